@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// Exit statuses, as with diff
+const (
+	exitSame    = 0
+	exitDiffer  = 1
+	exitFailure = 2
+)
+
+func main() {
+	semantic := flag.Bool("semantic", false, "Ignore comments and literal spelling as well, to confirm a change is formatting-only")
+	// Flags may follow the file names: gddiff old.gd new.gd --semantic
+	flags, files := splitArgs(os.Args[1:])
+	if err := flag.CommandLine.Parse(flags); err != nil {
+		os.Exit(exitFailure)
+	}
+
+	if len(files) != 2 {
+		fmt.Println("Usage: gddiff [--semantic] old.gd new.gd")
+		os.Exit(exitFailure)
+	}
+
+	differences, err := diffFiles(files[0], files[1], *semantic, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitFailure)
+	}
+	if differences > 0 {
+		os.Exit(exitDiffer)
+	}
+	os.Exit(exitSame)
+}
+
+// splitArgs separates flags from file names wherever they appear
+func splitArgs(args []string) (flags, files []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return flags, append(files, args[i+1:]...)
+		}
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			flags = append(flags, arg)
+		} else {
+			files = append(files, arg)
+		}
+	}
+	return flags, files
+}
+
+// diffFiles parses two scripts and writes the differences between their
+// trees to w, returning how many there are. Positions and whitespace never
+// count; with semantic, comments and the spelling of literals do not either.
+func diffFiles(oldPath, newPath string, semantic bool, w io.Writer) (int, error) {
+	oldTree, err := parseFile(oldPath)
+	if err != nil {
+		return 0, err
+	}
+	newTree, err := parseFile(newPath)
+	if err != nil {
+		return 0, err
+	}
+
+	// The root class is named after its file, which is not part of the code
+	if oldTree.RootClass != nil && newTree.RootClass != nil {
+		newTree.RootClass.Name = oldTree.RootClass.Name
+	}
+
+	var differences []ast.Difference
+	if semantic {
+		differences = ast.Compare(oldTree, newTree)
+	} else {
+		differences = ast.CompareSyntax(oldTree, newTree)
+	}
+
+	for _, d := range differences {
+		fmt.Fprintf(w, "%s:%d, %s:%d: %s\n", oldPath, d.A.Line, newPath, d.B.Line, d)
+	}
+	switch {
+	case len(differences) > 0:
+		fmt.Fprintf(w, "%d differences\n", len(differences))
+	case semantic:
+		fmt.Fprintln(w, "The scripts are equivalent, the change is formatting-only")
+	default:
+		fmt.Fprintln(w, "The scripts differ in whitespace only")
+	}
+	return len(differences), nil
+}
+
+// parseFile reads and parses a script, failing on parse errors: trees of
+// scripts that do not parse are not worth comparing
+func parseFile(path string) (*ast.AbstractSyntaxTree, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	tree, errors := parser.ParseFile(path, string(content))
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("%s: %d parsing errors, first: %v", path, len(errors), errors[0])
+	}
+	return tree, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	original := write("old.gd", "func foo(a):\n\treturn a+'x'\n")
+	reformatted := write("formatted.gd", "# adds x\nfunc foo(a):\n\treturn a + \"x\"\n")
+	changed := write("changed.gd", "func foo(a):\n\treturn a - \"x\"\n")
+
+	tests := []struct {
+		name        string
+		newPath     string
+		semantic    bool
+		differences int
+		output      string
+	}{
+		{"formatting_semantic", reformatted, true, 0, "formatting-only"},
+		{"formatting_syntax", reformatted, false, 3, "3 differences"},
+		{"change", changed, true, 1, `changed.gd:2: RootClass.Functions[0].Statements[0].Value.Operator: "+" != "-"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			differences, err := diffFiles(original, tt.newPath, tt.semantic, &out)
+			if err != nil {
+				t.Fatalf("diffFiles failed: %v", err)
+			}
+			if differences != tt.differences {
+				t.Errorf("Expected %d differences, got %d:\n%s", tt.differences, differences, out.String())
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.output, out.String())
+			}
+		})
+	}
+
+	if _, err := diffFiles(original, write("broken.gd", "func (:\n"), true, &strings.Builder{}); err == nil {
+		t.Error("Expected an error for a script that does not parse")
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	flags, files := splitArgs([]string{"old.gd", "new.gd", "--semantic", "--", "-odd.gd"})
+	if strings.Join(flags, " ") != "--semantic" || strings.Join(files, " ") != "old.gd new.gd -odd.gd" {
+		t.Errorf("Unexpected split: flags %v, files %v", flags, files)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/doc"
+)
+
+// options controls what documentation is written and where
+type options struct {
+	format string // markdown or json
+	output string // directory the pages are written to; standard output when empty
+}
+
+// page is the documentation of a script, with the path of its page relative
+// to the output directory
+type page struct {
+	path   string
+	script *doc.Script
+}
+
+func main() {
+	// Parse command-line flags
+	var opts options
+	flag.StringVar(&opts.format, "format", "markdown", "Format of the documentation: 'markdown' or 'json'")
+	flag.StringVar(&opts.output, "output", "", "Write one page per script to this directory, mirroring the project tree")
+	flag.Parse()
+
+	// Get the paths from the command-line arguments
+	args := flag.Args()
+	if len(args) == 0 || (opts.format != "markdown" && opts.format != "json") {
+		fmt.Println("Usage: gddoc [--format markdown|json] [--output dir] [file.gd|dir...]")
+		os.Exit(1)
+	}
+
+	var pages []page
+	failed := 0
+	for _, root := range args {
+		scripts, err := corpus.Load(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", root, err)
+			os.Exit(1)
+		}
+		found, errors := extractPages(root, scripts, os.Stderr)
+		pages = append(pages, found...)
+		failed += errors
+	}
+
+	if err := writePages(pages, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Exit with non-zero status if a script could not be documented
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// extractPages documents the scripts loaded from root, reporting those that
+// do not parse to w, and returns the pages with the number of failures
+func extractPages(root string, scripts *corpus.Corpus, w io.Writer) ([]page, int) {
+	var pages []page
+	failed := 0
+	for _, file := range scripts.Files {
+		tree, errors := parser.ParseFile(file.Path, file.Source)
+		if len(errors) > 0 {
+			failed++
+			fmt.Fprintf(w, "Parsing %s:\n", file.Path)
+			for _, err := range errors {
+				fmt.Fprintf(w, "  %v\n", err)
+			}
+			continue
+		}
+
+		// Pages mirror the tree under root; a script given by itself gets a
+		// page named after it
+		rel, err := filepath.Rel(root, file.Path)
+		if err != nil || rel == "." {
+			rel = filepath.Base(file.Path)
+		}
+		pages = append(pages, page{
+			path:   strings.TrimSuffix(rel, ".gd"),
+			script: doc.Extract(filepath.ToSlash(file.Path), tree),
+		})
+	}
+	return pages, failed
+}
+
+// writePages writes a file per page to the output directory, or all pages
+// to w when there is none: Markdown pages one after the other, and JSON as a
+// single array
+func writePages(pages []page, opts options, w io.Writer) error {
+	if opts.output == "" {
+		if opts.format == "json" {
+			scripts := []*doc.Script{}
+			for _, p := range pages {
+				scripts = append(scripts, p.script)
+			}
+			return writeJSON(w, scripts)
+		}
+		for i, p := range pages {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprint(w, doc.Markdown(p.script))
+		}
+		return nil
+	}
+
+	for _, p := range pages {
+		path := filepath.Join(opts.output, p.path+".md")
+		if opts.format == "json" {
+			path = filepath.Join(opts.output, p.path+".json")
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if opts.format == "json" {
+			err = writeJSON(f, p.script)
+		} else {
+			_, err = io.WriteString(f, doc.Markdown(p.script))
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/doc"
+)
+
+func TestGddoc(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"player.gd":        "class_name Player\n## Emitted when hit.\nsignal hit\n",
+		"enemies/slime.gd": "extends Node\n## Squishes.\nfunc squish():\n\tpass\n",
+		"broken.gd":        "func (:\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scripts, err := corpus.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errors strings.Builder
+	pages, failed := extractPages(root, scripts, &errors)
+	if failed != 1 || !strings.Contains(errors.String(), "broken.gd") {
+		t.Errorf("Expected broken.gd to fail, got %d failures:\n%s", failed, errors.String())
+	}
+	if len(pages) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(pages))
+	}
+
+	t.Run("markdown_to_directory", func(t *testing.T) {
+		output := t.TempDir()
+		if err := writePages(pages, options{format: "markdown", output: output}, &strings.Builder{}); err != nil {
+			t.Fatalf("writePages failed: %v", err)
+		}
+		page, err := os.ReadFile(filepath.Join(output, "enemies", "slime.md"))
+		if err != nil {
+			t.Fatalf("Expected a page mirroring the script path: %v", err)
+		}
+		if !strings.Contains(string(page), "### squish\n\n`func squish()`\n\nSquishes.\n") {
+			t.Errorf("Unexpected page:\n%s", page)
+		}
+		if _, err := os.Stat(filepath.Join(output, "player.md")); err != nil {
+			t.Errorf("Expected a page for player.gd: %v", err)
+		}
+	})
+
+	t.Run("json_to_stdout", func(t *testing.T) {
+		var out strings.Builder
+		if err := writePages(pages, options{format: "json"}, &out); err != nil {
+			t.Fatalf("writePages failed: %v", err)
+		}
+		var decoded []doc.Script
+		if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+			t.Fatalf("Output is not a JSON array of scripts: %v\n%s", err, out.String())
+		}
+		if len(decoded) != 2 || decoded[1].ClassName != "Player" || decoded[1].Signals[0].Description != "Emitted when hit." {
+			t.Errorf("Unexpected scripts %+v", decoded)
+		}
+	})
+}
+
+func TestExtractPages_SingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "player.gd")
+	if err := os.WriteFile(path, []byte("extends Node\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scripts, err := corpus.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, failed := extractPages(path, scripts, &strings.Builder{})
+	if failed != 0 || len(pages) != 1 || pages[0].path != "player" {
+		t.Errorf("Expected a page named after the script, got %+v", pages)
+	}
+}
@@ -1,118 +1,535 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
 	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/diagnostic"
+	"github.com/dzannotti/gdtoolkit/internal/scene"
+	"github.com/dzannotti/gdtoolkit/internal/textutil"
+	"github.com/dzannotti/gdtoolkit/internal/version"
 )
 
+// Exit statuses of a formatting run
+const (
+	exitClean    = 0 // every file was formatted, or is formatted with --check
+	exitReformat = 1 // --check found files that would be reformatted
+	exitErrors   = 2 // a script does not parse or cannot be formatted safely
+	exitFailure  = 3 // a file could not be read or written, or bad usage
+)
+
+// options controls how formatted files are written
+type options struct {
+	checkOnly             bool
+	dryRun                bool
+	backup                bool
+	lineLengthMode        formatter.LineLengthMode
+	normalizeStrings      bool
+	keepLineContinuations bool
+	trailingCommas        formatter.TrailingCommaPolicy
+	alignInlineComments   bool
+	// reorderMembers sorts class members into the order class-definitions-order expects
+	reorderMembers bool
+	// eol is the line ending to write; empty keeps that of each file
+	eol formatter.LineEnding
+	// ignoreEOL makes --check accept files differing only in line endings
+	ignoreEOL bool
+	// quiet leaves out the messages of files formatted successfully
+	quiet bool
+	// report collects the outcome of each file for --report json instead of
+	// printing it; nil prints it as text
+	report *report
+	// color colorizes the parse errors printed on stderr
+	color bool
+	// maxFileSize is the size in bytes of the largest script formatted; 0
+	// formats any
+	maxFileSize int64
+	// convertIndent reindents the scripts in this style instead of
+	// formatting them; nil formats them
+	convertIndent *formatter.IndentStyle
+}
+
+// summary counts what a formatting run did
+type summary struct {
+	files int
+	// reformatted counts the files formatting changed, or would change with --check
+	reformatted int
+	errors      int // scripts that do not parse or cannot be formatted safely
+	failed      bool
+}
+
 func main() {
 	// Parse command-line flags
-	checkOnly := flag.Bool("check", false, "Check if files are formatted without modifying them")
+	var opts options
+	flag.BoolVar(&opts.checkOnly, "check", false, "Check if files are formatted without modifying them")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "Print formatted code to stdout instead of writing files")
+	flag.BoolVar(&opts.backup, "backup", false, "Save the original file as file.gd.bak before reformatting it")
+	flag.BoolVar(&opts.normalizeStrings, "normalize-strings", true, "Quote strings with double quotes unless they contain one")
+	flag.BoolVar(&opts.quiet, "quiet", false, "Only print problems and the summary, not the files formatted successfully")
+	flag.BoolVar(&opts.ignoreEOL, "ignore-eol", false, "With --check, ignore differences in line endings")
+	flag.BoolVar(&opts.keepLineContinuations, "keep-line-continuations", false, "Keep backslash line continuations between operands instead of joining the lines")
+	flag.BoolVar(&opts.alignInlineComments, "align-inline-comments", false, "Start the inline comments of consecutive lines at a common column")
+	flag.BoolVar(&opts.reorderMembers, "reorder-members", false, "Sort class members into the order the class-definitions-order rule expects")
+	lineLengthMode := flag.String("line-length-mode", "runes", "Measure line length in 'runes' (code points) or 'bytes'")
+	trailingCommas := flag.String("trailing-commas", "always", "End lists split one element per line with a comma: 'always', 'never', or 'preserve' to keep the source's")
+	reportFormat := flag.String("report", "text", "Report the outcome of each file as 'text' or as a 'json' document on stdout")
+	convertIndent := flag.String("convert-indent", "", "Only reindent the scripts with 'tabs' or 'spaces:N', keeping the rest of the code and the alignment of continued lines as written")
+	eol := flag.String("eol", "auto", "End lines with 'lf', 'crlf', or 'auto' to keep the line endings of each file")
+	flag.Int64Var(&opts.maxFileSize, "max-file-size", corpus.DefaultMaxFileSize, "Fail on scripts larger than this many bytes instead of formatting them; 0 for no limit")
+	color := flag.String("color", "auto", "Colorize parse errors: 'auto' on terminals, 'always' or 'never'")
+	printVersion := flag.Bool("version", false, "Print the version of gdformat and of the GDScript grammar it formats")
 	flag.Parse()
 
+	if *printVersion {
+		fmt.Println(version.Get("gdformat"))
+		return
+	}
+
+	colorMode, err := diagnostic.ParseColorMode(*color)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitFailure)
+	}
+	opts.color = colorMode.Enabled(os.Stderr)
+
+	switch *lineLengthMode {
+	case "runes":
+		opts.lineLengthMode = formatter.LineLengthRunes
+	case "bytes":
+		opts.lineLengthMode = formatter.LineLengthBytes
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --line-length-mode %q, expected runes or bytes\n", *lineLengthMode)
+		os.Exit(exitFailure)
+	}
+
+	switch *trailingCommas {
+	case "always":
+		opts.trailingCommas = formatter.TrailingCommasAlways
+	case "never":
+		opts.trailingCommas = formatter.TrailingCommasNever
+	case "preserve":
+		opts.trailingCommas = formatter.TrailingCommasPreserve
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --trailing-commas %q, expected always, never or preserve\n", *trailingCommas)
+		os.Exit(exitFailure)
+	}
+
+	switch *eol {
+	case "auto":
+	case "lf":
+		opts.eol = formatter.LF
+	case "crlf":
+		opts.eol = formatter.CRLF
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --eol %q, expected auto, lf or crlf\n", *eol)
+		os.Exit(exitFailure)
+	}
+
+	if *convertIndent != "" {
+		style, err := formatter.ParseIndentStyle(*convertIndent)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --convert-indent: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		opts.convertIndent = &style
+	}
+
+	switch *reportFormat {
+	case "text":
+	case "json":
+		if opts.dryRun {
+			fmt.Fprintln(os.Stderr, "--report json cannot be combined with --dry-run, which prints the code on stdout")
+			os.Exit(exitFailure)
+		}
+		opts.report = &report{Tool: version.Get("gdformat"), Files: []fileReport{}}
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --report %q, expected text or json\n", *reportFormat)
+		os.Exit(exitFailure)
+	}
+
 	// Get the file paths from the command-line arguments
 	args := flag.Args()
 	if len(args) == 0 {
-		fmt.Println("Usage: gdformat [--check] [file.gd...]")
-		os.Exit(1)
+		fmt.Println("Usage: gdformat [--check] [--quiet] [--dry-run] [--backup] [--line-length-mode runes|bytes] [--trailing-commas always|never|preserve] [--eol auto|lf|crlf] [--ignore-eol] [--report text|json] [--color auto|always|never] [--normalize-strings=false] [--keep-line-continuations] [--align-inline-comments] [--reorder-members] [--convert-indent tabs|spaces:N] [--max-file-size bytes] [file.gd|dir...]")
+		fmt.Println("       gdformat --version")
+		os.Exit(exitFailure)
+	}
+
+	result := formatPaths(args, opts)
+	if opts.report != nil {
+		opts.report.Summary = reportSummary{Files: result.files, Reformatted: result.reformatted, Errors: result.errors}
+		if err := writeJSON(os.Stdout, opts.report); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		os.Exit(exitStatus(result, opts))
+	}
+
+	// With --dry-run the formatted code is written to stdout, so the summary
+	// goes to stderr
+	out := os.Stdout
+	if opts.dryRun {
+		out = os.Stderr
+	}
+	fmt.Fprintln(out, result)
+	os.Exit(exitStatus(result, opts))
+}
+
+// formatPaths formats each path, and the scripts under each directory as they
+// are found, and returns the summary of the run. A path that cannot be walked
+// counts as a file in error.
+func formatPaths(paths []string, opts options) summary {
+	var result summary
+	for _, arg := range paths {
+		err := corpus.Walk(arg, func(path string) error {
+			result.files++
+			processFile(path, opts, &result)
+			return nil
+		})
+		if err != nil {
+			result.files++
+			result.errors++
+			result.failed = true
+			if opts.report != nil {
+				opts.report.Files = append(opts.report.Files, newFileReport(arg, false, err, opts))
+			} else {
+				fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", arg, err)
+			}
+		}
+	}
+	return result
+}
+
+// String returns the end-of-run summary line, e.g. "3 files, 1 reformatted, 0 errors"
+func (s summary) String() string {
+	return fmt.Sprintf("%s, %d reformatted, %s", textutil.Pluralize(s.files, "file"), s.reformatted, textutil.Pluralize(s.errors, "error"))
+}
+
+// exitStatus returns the status a formatting run exits with
+func exitStatus(result summary, opts options) int {
+	switch {
+	case result.failed:
+		return exitFailure
+	case result.errors > 0:
+		return exitErrors
+	case opts.checkOnly && result.reformatted > 0:
+		return exitReformat
+	default:
+		return exitClean
+	}
+}
+
+// scriptError reports a script that cannot be formatted, as opposed to a file
+// that cannot be read or written
+type scriptError struct {
+	err error
+	// parseErrors holds the errors of a script that does not parse, and
+	// source its code
+	parseErrors []error
+	source      string
+}
+
+func (e *scriptError) Error() string {
+	return e.err.Error()
+}
+
+// Statuses of a file in a --report json report
+const (
+	statusFormatted       = "formatted"        // the file was already formatted
+	statusReformatted     = "reformatted"      // the file was formatted and written
+	statusNeedsFormatting = "needs-formatting" // --check found the file unformatted
+	statusParseError      = "parse-error"      // the script does not parse
+	statusError           = "error"            // the file could not be formatted, read or written
+)
+
+// report is the document --report json writes
+type report struct {
+	// Tool is the build of gdformat, so that CI can tell reports of other
+	// versions apart
+	Tool    version.Info  `json:"tool"`
+	Files   []fileReport  `json:"files"`
+	Summary reportSummary `json:"summary"`
+}
+
+// fileReport is the outcome of formatting a file
+type fileReport struct {
+	Path   string        `json:"path"`
+	Status string        `json:"status"`
+	Errors []reportError `json:"errors,omitempty"`
+}
+
+// reportError is an error of a file, positioned when it is a parse error
+type reportError struct {
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// reportSummary counts the files of a report as the summary line does
+type reportSummary struct {
+	Files       int `json:"files"`
+	Reformatted int `json:"reformatted"`
+	Errors      int `json:"errors"`
+}
+
+// processFile formats a GDScript file, reports the outcome and adds it to result
+func processFile(path string, opts options, result *summary) {
+	changed, err := formatFile(path, opts)
+	var scriptErr *scriptError
+	switch {
+	case errors.As(err, &scriptErr):
+		result.errors++
+	case err != nil:
+		result.errors++
+		result.failed = true
+	case changed:
+		result.reformatted++
 	}
 
-	// Process each file
-	hasErrors := false
-	for _, path := range args {
-		if err := processFile(path, *checkOnly); err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", path, err)
-			hasErrors = true
+	if opts.report != nil {
+		opts.report.Files = append(opts.report.Files, newFileReport(path, changed, err, opts))
+		return
+	}
+
+	switch {
+	case err != nil:
+		if scriptErr != nil {
+			for _, err := range scriptErr.parseErrors {
+				diagnostic.Write(os.Stderr, diagnostic.FromParseError(path, err), scriptErr.source, opts.color)
+			}
 		}
+		fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", path, err)
+	case opts.checkOnly && changed:
+		fmt.Printf("File %s would be reformatted\n", path)
+	case opts.quiet || opts.dryRun:
+	case opts.checkOnly:
+		fmt.Printf("File %s is correctly formatted\n", path)
+	default:
+		fmt.Printf("Successfully formatted %s\n", path)
 	}
+}
 
-	// Exit with non-zero status if there were errors
-	if hasErrors {
-		os.Exit(1)
+// newFileReport returns the report of formatting the file at path
+func newFileReport(path string, changed bool, err error, opts options) fileReport {
+	file := fileReport{Path: path, Status: statusFormatted}
+	var scriptErr *scriptError
+	switch {
+	case errors.As(err, &scriptErr) && len(scriptErr.parseErrors) > 0:
+		file.Status = statusParseError
+		for _, parseErr := range scriptErr.parseErrors {
+			e := reportError{Message: parseErr.Error()}
+			if positioned, ok := parseErr.(parser.Error); ok {
+				e = reportError{
+					Line:       positioned.Line,
+					Column:     positioned.Column,
+					Message:    positioned.Message,
+					Suggestion: positioned.Suggestion,
+				}
+			}
+			file.Errors = append(file.Errors, e)
+		}
+	case err != nil:
+		file.Status = statusError
+		file.Errors = []reportError{{Message: err.Error()}}
+	case changed && opts.checkOnly:
+		file.Status = statusNeedsFormatting
+	case changed:
+		file.Status = statusReformatted
 	}
+	return file
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
 }
 
-// processFile reads, parses, and formats a GDScript file
-func processFile(path string, checkOnly bool) error {
+// formatFile reads, parses, and formats a GDScript file, and reports whether
+// its formatting changes it
+func formatFile(path string, opts options) (bool, error) {
 	// Check if the file exists
 	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return false, fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	// Check if it's a directory
 	if info.IsDir() {
-		return fmt.Errorf("path is a directory, not a file")
+		return false, fmt.Errorf("path is a directory, not a file")
 	}
 
 	// Read the file
-	content, err := ioutil.ReadFile(path)
+	content, err := corpus.ReadFile(path, opts.maxFileSize)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return false, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Parse the file
-	ast, errors := parser.ParseFile(path, string(content))
-	if len(errors) > 0 {
-		fmt.Printf("Parsing %s:\n", path)
-		for _, err := range errors {
-			fmt.Printf("  %v\n", err)
-		}
-		return fmt.Errorf("%d parsing errors", len(errors))
+	// Format the file, or the scripts embedded in a scene file
+	var formattedCode string
+	if scene.IsScene(path) {
+		formattedCode, err = formatScene(path, content, opts)
+	} else {
+		formattedCode, err = formatScript(path, content, opts)
 	}
-
-	// Format the AST
-	config := formatter.DefaultConfig()
-	formattedCode, err := formatter.FormatCode(ast, config)
 	if err != nil {
-		return fmt.Errorf("formatting error: %w", err)
+		return false, err
 	}
 
-	// Ensure the formatted code ends with a newline
-	if !strings.HasSuffix(formattedCode, "\n") {
-		formattedCode += "\n"
-	}
-
-	if checkOnly {
-		// Check if the file is already formatted correctly
-		if string(content) == formattedCode {
-			fmt.Printf("File %s is correctly formatted\n", path)
-		} else {
-			fmt.Printf("File %s would be reformatted\n", path)
-			return fmt.Errorf("file needs formatting")
+	changed := !sameCode(content, formattedCode, opts.checkOnly && opts.ignoreEOL)
+	switch {
+	case opts.checkOnly:
+		// The file is only compared with its formatted code
+	case opts.dryRun:
+		fmt.Print(formattedCode)
+	case !changed:
+		// Neither the file nor its backup is written when formatting changes
+		// nothing
+	default:
+		if opts.backup {
+			if err := writeFileAtomic(path+".bak", []byte(content), info.Mode().Perm()); err != nil {
+				return false, fmt.Errorf("failed to write backup: %w", err)
+			}
 		}
-	} else {
+
 		// Write the formatted code back to the file
-		err = ioutil.WriteFile(path, []byte(formattedCode), 0644)
+		err = writeFileAtomic(path, []byte(formattedCode), info.Mode().Perm())
 		if err != nil {
-			return fmt.Errorf("failed to write formatted file: %w", err)
+			return false, fmt.Errorf("failed to write formatted file: %w", err)
 		}
-		fmt.Printf("Successfully formatted %s\n", path)
 	}
 
-	return nil
+	return changed, nil
 }
 
-// findGDScriptFiles finds all .gd files in a directory
-func findGDScriptFiles(dir string) ([]string, error) {
-	var files []string
+// formatScript formats content, the code of the script at path, failing
+// with a *scriptError when it does not parse or cannot be formatted safely
+func formatScript(path, content string, opts options) (string, error) {
+	// The file is formatted with \n line endings and without a byte order
+	// mark, which are restored when it is written back
+	source, style := formatter.NormalizeSource(content)
+	if opts.eol != "" {
+		style.LineEnding = opts.eol
+	}
+
+	// Parse the file
+	tree, errors := parser.ParseFile(path, source)
+	if len(errors) > 0 {
+		return "", &scriptError{err: fmt.Errorf("%d parsing errors", len(errors)), parseErrors: errors, source: source}
+	}
+
+	var formattedCode string
+	if opts.convertIndent != nil {
+		formattedCode = formatter.Reindent(source, *opts.convertIndent)
+	} else {
+		if opts.reorderMembers {
+			// The safety check then compares the output with the reordered tree
+			formatter.ReorderMembers(tree)
+		}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		// Format the AST
+		config := formatter.DefaultConfig()
+		config.LineLengthMode = opts.lineLengthMode
+		config.NormalizeStrings = opts.normalizeStrings
+		config.KeepLineContinuations = opts.keepLineContinuations
+		config.TrailingCommas = opts.trailingCommas
+		config.AlignInlineComments = opts.alignInlineComments
+		var err error
+		formattedCode, err = formatter.FormatCode(tree, config)
 		if err != nil {
-			return err
+			return "", &scriptError{err: fmt.Errorf("formatting error: %w", err)}
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".gd" {
-			files = append(files, path)
+	}
+
+	// Never write output that would no longer parse, or that would behave differently
+	formatted, errors := parser.ParseFile(path, formattedCode)
+	if len(errors) > 0 {
+		return "", &scriptError{err: fmt.Errorf("safety check failed, formatted code does not parse: %v", errors[0])}
+	}
+	if differences := ast.Compare(tree, formatted); len(differences) > 0 {
+		d := differences[0]
+		return "", &scriptError{err: fmt.Errorf("safety check failed, formatted code differs from the original at %s: %s",
+			d.A, d.Message)}
+	}
+	return style.Apply(formattedCode), nil
+}
+
+// formatScene formats the scripts embedded in content, the scene file at
+// path, and returns the scene file with the formatted scripts. Positions of
+// parse errors are in the scene file.
+func formatScene(path, content string, opts options) (string, error) {
+	// Godot writes embedded scripts with \n line endings whatever the scene's
+	opts.eol = formatter.LF
+	scripts := scene.Extract(content)
+	sources := make([]string, len(scripts))
+	for i, script := range scripts {
+		formatted, err := formatScript(path, script.Source, opts)
+		var scriptErr *scriptError
+		if errors.As(err, &scriptErr) && len(scriptErr.parseErrors) > 0 {
+			for j, e := range scriptErr.parseErrors {
+				if positioned, ok := e.(parser.Error); ok {
+					positioned.Line, positioned.Column, _ = script.Position(script.Offset(positioned.Line, positioned.Column), 0)
+					scriptErr.parseErrors[j] = positioned
+				}
+			}
+			scriptErr.source = content
 		}
-		return nil
-	})
+		if err != nil {
+			return "", err
+		}
+		sources[i] = formatted
+	}
+	return scene.Replace(content, scripts, sources), nil
+}
+
+// sameCode reports whether a and b are the same code, in any line endings if
+// ignoreEOL is set
+func sameCode(a, b string, ignoreEOL bool) bool {
+	if ignoreEOL {
+		a = strings.ReplaceAll(a, "\r\n", "\n")
+		b = strings.ReplaceAll(b, "\r\n", "\n")
+	}
+	return a == b
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so an interrupted write leaves either the old or the new content
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file unless it was renamed into place
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
 
-	return files, err
+	return os.Rename(tmpPath, path)
 }
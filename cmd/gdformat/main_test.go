@@ -0,0 +1,359 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestProcessFileWrites(t *testing.T) {
+	input := "func foo(a,b):\n\treturn a+b\n"
+	expected := "func foo(a, b):\n\treturn a + b\n"
+
+	t.Run("backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "script.gd")
+		if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := formatFile(path, options{backup: true}); err != nil {
+			t.Fatalf("formatFile failed: %v", err)
+		}
+
+		assertFile(t, path, expected, 0600)
+		assertFile(t, path+".bak", input, 0600)
+		assertNoTempFiles(t, filepath.Dir(path))
+	})
+
+	t.Run("backup_unchanged", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "script.gd")
+		if err := os.WriteFile(path, []byte(expected), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+
+		changed, err := formatFile(path, options{backup: true})
+		if err != nil {
+			t.Fatalf("formatFile failed: %v", err)
+		}
+		if changed {
+			t.Error("Expected a formatted file to be unchanged")
+		}
+
+		assertFile(t, path, expected, 0600)
+		if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(modified) {
+			t.Errorf("Expected the file not to be written")
+		}
+		if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+			t.Errorf("Expected no backup file for an unchanged file")
+		}
+	})
+
+	t.Run("dry_run", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "script.gd")
+		if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := formatFile(path, options{dryRun: true}); err != nil {
+			t.Fatalf("formatFile failed: %v", err)
+		}
+
+		assertFile(t, path, input, 0644)
+		if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+			t.Errorf("Expected no backup file in dry-run mode")
+		}
+	})
+}
+
+func TestFormatFileConvertIndent(t *testing.T) {
+	// Only the indentation changes, unlike formatting, which would join the
+	// call and space the operator
+	input := "func foo(a,b):\n\tif a:\n\t\treturn bar(a,\n\t\t           b)+1\n"
+	expected := "func foo(a,b):\n  if a:\n    return bar(a,\n               b)+1\n"
+	path := filepath.Join(t.TempDir(), "script.gd")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	style := formatter.IndentStyle{Size: 2}
+	changed, err := formatFile(path, options{convertIndent: &style})
+	if err != nil || !changed {
+		t.Fatalf("Expected the file to be reindented, got %v, %v", changed, err)
+	}
+	assertFile(t, path, expected, 0644)
+
+	// Converting back gives the script as it was
+	style = formatter.IndentStyle{Tabs: true, Size: 4}
+	if _, err := formatFile(path, options{convertIndent: &style}); err != nil {
+		t.Fatalf("formatFile failed: %v", err)
+	}
+	assertFile(t, path, input, 0644)
+}
+
+func TestProcessFileKeepsLineEndings(t *testing.T) {
+	input := "\uFEFFfunc foo(a,b):\r\n\treturn a+b\r\n"
+
+	tests := []struct {
+		name     string
+		eol      formatter.LineEnding
+		expected string
+	}{
+		{"auto", "", "\uFEFFfunc foo(a, b):\r\n\treturn a + b\r\n"},
+		{"lf", formatter.LF, "\uFEFFfunc foo(a, b):\n\treturn a + b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script.gd")
+			if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := formatFile(path, options{eol: tt.eol}); err != nil {
+				t.Fatalf("formatFile failed: %v", err)
+			}
+
+			assertFile(t, path, tt.expected, 0644)
+		})
+	}
+}
+
+func TestProcessFileCheckIgnoresLineEndings(t *testing.T) {
+	// Mixed line endings are written back as the ones most lines use
+	path := filepath.Join(t.TempDir(), "script.gd")
+	if err := os.WriteFile(path, []byte("func foo():\r\n\tpass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := formatFile(path, options{checkOnly: true}); err != nil || !changed {
+		t.Errorf("Expected the line endings to need formatting, got %v", err)
+	}
+	if changed, err := formatFile(path, options{checkOnly: true, ignoreEOL: true}); err != nil || changed {
+		t.Errorf("Expected --ignore-eol to accept the file, got %v", err)
+	}
+}
+
+func TestProcessFileCountsOutcomes(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"formatted.gd":   "func foo(a, b):\n\treturn a + b\n",
+		"unformatted.gd": "func foo(a,b):\n\treturn a+b\n",
+		"broken.gd":      "func foo(:\n",
+	}
+	for name, code := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file     string
+		expected summary
+		status   int
+	}{
+		{"formatted.gd", summary{}, exitClean},
+		{"unformatted.gd", summary{reformatted: 1}, exitReformat},
+		{"broken.gd", summary{errors: 1}, exitErrors},
+		{"missing.gd", summary{errors: 1, failed: true}, exitFailure},
+	}
+	for _, tt := range tests {
+		var result summary
+		opts := options{checkOnly: true, quiet: true}
+		processFile(filepath.Join(dir, tt.file), opts, &result)
+		if result != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.file, tt.expected, result)
+		}
+		if status := exitStatus(result, opts); status != tt.status {
+			t.Errorf("%s: expected exit status %d, got %d", tt.file, tt.status, status)
+		}
+	}
+
+	result := summary{files: 3, reformatted: 1, errors: 1}
+	if result.String() != "3 files, 1 reformatted, 1 error" {
+		t.Errorf("Unexpected summary %q", result)
+	}
+}
+
+func TestFormatPathsMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	opts := options{checkOnly: true}
+
+	var result summary
+	stdout, stderr := captureOutput(t, func() {
+		result = formatPaths([]string{missing}, opts)
+	})
+	if result.String() != "1 file, 0 reformatted, 1 error" {
+		t.Errorf("Expected the missing path to count as a file in error, got %q", result)
+	}
+	if status := exitStatus(result, opts); status != exitFailure {
+		t.Errorf("Expected exit status %d, got %d", exitFailure, status)
+	}
+	if stdout != "" || !strings.Contains(stderr, "Error processing "+missing) {
+		t.Errorf("Expected the error on stderr only, got stdout %q and stderr %q", stdout, stderr)
+	}
+}
+
+func TestProcessFilePrintsParseErrorsOnStderr(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.gd")
+	if err := os.WriteFile(path, []byte("func foo(:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := captureOutput(t, func() {
+		var result summary
+		processFile(path, options{checkOnly: true}, &result)
+	})
+	if stdout != "" {
+		t.Errorf("Expected nothing on stdout, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "broken.gd:1:") || !strings.Contains(stderr, "Error processing") {
+		t.Errorf("Expected the parse error on stderr, got %q", stderr)
+	}
+}
+
+// captureOutput runs f and returns what it wrote to stdout and stderr
+func captureOutput(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	savedStdout, savedStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	defer func() { os.Stdout, os.Stderr = savedStdout, savedStderr }()
+	f()
+
+	out, _ := os.ReadFile(stdout.Name())
+	errOut, _ := os.ReadFile(stderr.Name())
+	return string(out), string(errOut)
+}
+
+func TestFormatFileScene(t *testing.T) {
+	input := "[gd_scene format=3]\n\n[sub_resource type=\"GDScript\" id=\"GDScript_a1\"]\n" +
+		"script/source = \"extends Node\nfunc _ready():\n\tprint(\\\"a\\\"+\\\"b\\\")\n\"\n\n" +
+		"[node name=\"Root\" type=\"Node\"]\nscript = SubResource(\"GDScript_a1\")\n"
+	expected := strings.Replace(strings.Replace(input, "extends Node\n", "extends Node\n\n\n", 1), "+", " + ", 1)
+	path := filepath.Join(t.TempDir(), "main.tscn")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := formatFile(path, options{})
+	if err != nil || !changed {
+		t.Fatalf("Expected the embedded script to be formatted, got %v, %v", changed, err)
+	}
+	assertFile(t, path, expected, 0644)
+
+	// Parse errors are positioned in the scene file
+	broken := strings.Replace(input, "_ready():", "_ready()", 1)
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = formatFile(path, options{checkOnly: true})
+	var scriptErr *scriptError
+	if !errors.As(err, &scriptErr) || scriptErr.source != broken {
+		t.Fatalf("Expected a parse error in the scene, got %v", err)
+	}
+	if positioned, ok := scriptErr.parseErrors[0].(parser.Error); !ok || positioned.Line != 5 || positioned.Column != 14 {
+		t.Errorf("Expected the error at 5:14, got %v", scriptErr.parseErrors[0])
+	}
+}
+
+func TestProcessFileReportsJSON(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"formatted.gd":   "func foo(a, b):\n\treturn a + b\n",
+		"unformatted.gd": "func foo(a,b):\n\treturn a+b\n",
+		"broken.gd":      "func foo(:\n",
+	}
+	for name, code := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := options{checkOnly: true, report: &report{}}
+	var result summary
+	for _, name := range []string{"formatted.gd", "unformatted.gd", "broken.gd", "missing.gd"} {
+		processFile(filepath.Join(dir, name), opts, &result)
+	}
+
+	files := opts.report.Files
+	if len(files) != 4 {
+		t.Fatalf("Expected a report per file, got %+v", files)
+	}
+	for i, status := range []string{statusFormatted, statusNeedsFormatting, statusParseError, statusError} {
+		if files[i].Status != status {
+			t.Errorf("%s: expected status %s, got %s", filepath.Base(files[i].Path), status, files[i].Status)
+		}
+	}
+	if errs := files[2].Errors; len(errs) == 0 || errs[0].Line != 1 || errs[0].Column == 0 || errs[0].Message == "" {
+		t.Errorf("Expected positioned parse errors, got %+v", errs)
+	}
+	if errs := files[3].Errors; len(errs) != 1 || errs[0].Line != 0 {
+		t.Errorf("Expected an unpositioned error, got %+v", errs)
+	}
+
+	// So does a path that cannot be walked
+	opts.report = &report{}
+	missing := filepath.Join(dir, "missing")
+	result = formatPaths([]string{missing}, opts)
+	files = opts.report.Files
+	if len(files) != 1 || files[0].Path != missing || files[0].Status != statusError ||
+		len(files[0].Errors) != 1 || !strings.Contains(files[0].Errors[0].Message, "no such file") {
+		t.Errorf("Expected an error entry for the missing path, got %+v", files)
+	}
+	if result.files != 1 || result.errors != 1 {
+		t.Errorf("Expected the missing path to be counted, got %+v", result)
+	}
+}
+
+func assertFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if string(data) != content {
+		t.Errorf("%s: expected %q, got %q", filepath.Base(path), content, string(data))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != perm {
+		t.Errorf("%s: expected mode %v, got %v", filepath.Base(path), perm, info.Mode().Perm())
+	}
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("Temporary files left behind: %v", matches)
+	}
+}
@@ -1,99 +1,591 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/dzannotti/gdtoolkit/internal/baseline"
+	"github.com/dzannotti/gdtoolkit/internal/cache"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/diagnostic"
+	"github.com/dzannotti/gdtoolkit/internal/doctor"
+	"github.com/dzannotti/gdtoolkit/internal/project"
+	"github.com/dzannotti/gdtoolkit/internal/scene"
+	"github.com/dzannotti/gdtoolkit/internal/textutil"
+	"github.com/dzannotti/gdtoolkit/internal/version"
 )
 
+// Exit statuses of a lint run
+const (
+	exitClean    = 0 // no errors, and warnings within the budget
+	exitWarnings = 1 // more warnings than --max-warnings allows
+	exitErrors   = 2 // at least one error, including scripts that do not parse
+	exitFailure  = 3 // a file or config could not be read, or bad usage
+)
+
+// options controls which problems are reported and how they affect the exit status
+type options struct {
+	maxWarnings int // -1 allows any number of warnings
+	errorsOnly  bool
+	// baseline suppresses the problems it records
+	baseline *baseline.Baseline
+	// record collects every problem found instead of reporting it
+	record *baseline.Baseline
+	// cache holds the problems found in scripts by earlier runs; nil disables it
+	cache *cache.Cache
+	// quiet leaves out the messages of files without problems
+	quiet bool
+	// color and errColor colorize the diagnostics printed on stdout and stderr
+	color, errColor bool
+	// maxFileSize is the size in bytes of the largest script linted; 0 lints any
+	maxFileSize int64
+	// profile records the time rules take when --profile-rules is given
+	profile *linter.Profile
+	// projects are the projects of the paths linted with --project, whose
+	// scripts the project rules check each script against
+	projects []*project.Project
+	// fix rewrites the scripts with the fixes of the problems found applied
+	fix bool
+}
+
+// summary counts what a lint run found
+type summary struct {
+	files      int
+	errors     int
+	warnings   int
+	suppressed int // problems hidden by the baseline
+	fixed      int // problems fixed with --fix
+	failed     bool
+}
+
 func main() {
 	// Parse command-line flags
+	var opts options
+	flag.IntVar(&opts.maxWarnings, "max-warnings", 0, "Number of warnings allowed before exiting with status 1; -1 allows any number")
+	flag.BoolVar(&opts.errorsOnly, "errors-only", false, "Report errors only, ignoring warnings and infos")
+	flag.BoolVar(&opts.quiet, "quiet", false, "Only print problems and the summary, not the files without problems")
+	flag.BoolVar(&opts.fix, "fix", false, "Rewrite the scripts with the fixes of the problems found applied, then report the problems left")
+	listRulesFlag := flag.Bool("list-rules", false, "List every rule with its default severity and description")
+	explain := flag.String("explain", "", "Describe a rule and the settings it reads from rule_settings")
+	baselinePath := flag.String("baseline", "", "Suppress the problems recorded in this baseline file")
+	generateBaseline := flag.String("generate-baseline", "", "Record every problem found in this baseline file instead of reporting them")
+	noCache := flag.Bool("no-cache", false, "Lint every file instead of reusing the problems cached for unchanged files")
+	cacheDir := flag.String("cache-dir", cache.DefaultDir, "Directory the problems found are cached in")
+	flag.Int64Var(&opts.maxFileSize, "max-file-size", corpus.DefaultMaxFileSize, "Fail on scripts larger than this many bytes instead of linting them; 0 for no limit")
+	color := flag.String("color", "auto", "Colorize problems: 'auto' on terminals, 'always' or 'never'")
+	profileRules := flag.Bool("profile-rules", false, "Print how long each rule and the slowest files took; every file is linted, ignoring the cache")
+	projectMode := flag.Bool("project", false, "Load every script of the project of each path to run the project rules, such as unknown-class; every file is linted, ignoring the cache")
+	printVersion := flag.Bool("version", false, "Print the version of gdlint and of the GDScript grammar it checks")
 	flag.Parse()
 
+	if *printVersion {
+		fmt.Println(version.Get("gdlint"))
+		return
+	}
+
+	colorMode, err := diagnostic.ParseColorMode(*color)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitFailure)
+	}
+	opts.color = colorMode.Enabled(os.Stdout)
+	opts.errColor = colorMode.Enabled(os.Stderr)
+
+	if *listRulesFlag {
+		listRules(os.Stdout)
+		return
+	}
+	if *explain != "" {
+		if err := explainRule(os.Stdout, *explain); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitFailure)
+		}
+		return
+	}
+
 	// Get the file paths from the command-line arguments
 	args := flag.Args()
 	if len(args) == 0 {
-		fmt.Println("Usage: gdlint [file.gd...]")
-		os.Exit(1)
+		fmt.Println("Usage: gdlint [--max-warnings N] [--errors-only] [--quiet] [--color auto|always|never] [--max-file-size bytes] [--baseline file] [--no-cache] [--profile-rules] [--project] [--fix] [file.gd|dir...]")
+		fmt.Println("       gdlint --generate-baseline file [file.gd|dir...]")
+		fmt.Println("       gdlint doctor [dir]")
+		fmt.Println("       gdlint --list-rules")
+		fmt.Println("       gdlint --explain <rule>")
+		fmt.Println("       gdlint --version")
+		os.Exit(exitFailure)
+	}
+
+	if args[0] == "doctor" {
+		os.Exit(runDoctor(args[1:]))
 	}
 
-	// Process each file
-	hasErrors := false
-	for _, path := range args {
-		if err := processFile(path); err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", path, err)
-			hasErrors = true
+	if *baselinePath != "" {
+		b, err := baseline.Load(*baselinePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitFailure)
 		}
+		opts.baseline = b
+	}
+	if *generateBaseline != "" {
+		dir, err := filepath.Abs(filepath.Dir(*generateBaseline))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitFailure)
+		}
+		opts.record = baseline.New(dir)
+		opts.record.Tool = version.Get("gdlint").String()
+	}
+	if *projectMode {
+		projects, err := loadProjects(args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitFailure)
+		}
+		opts.projects = projects
+	}
+	// The problems project rules find depend on other files than the one linted
+	if *profileRules {
+		opts.profile = linter.NewProfile()
+	} else if !*noCache && !*projectMode {
+		opts.cache = cache.Open(*cacheDir, "lint")
 	}
 
-	// Exit with non-zero status if there were errors
-	if hasErrors {
-		os.Exit(1)
+	result := lintPaths(args, opts)
+	if opts.profile != nil {
+		printProfile(os.Stdout, opts.profile)
+	}
+
+	if opts.record != nil {
+		if err := opts.record.Write(*generateBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write baseline: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		fmt.Printf("Recorded %s in %s\n", textutil.Pluralize(len(opts.record.Entries), "problem"), *generateBaseline)
+		if result.failed {
+			os.Exit(exitFailure)
+		}
+		return
+	}
+
+	printSummary(result)
+	os.Exit(exitStatus(result, opts))
+}
+
+// printSummary prints the end-of-run summary, followed by the problems the
+// baseline suppressed and the problems fixed with --fix, if any
+func printSummary(result summary) {
+	fmt.Println(result)
+	if result.suppressed > 0 {
+		fmt.Printf("%s suppressed by the baseline\n", textutil.Pluralize(result.suppressed, "problem"))
+	}
+	if result.fixed > 0 {
+		fmt.Printf("%s fixed\n", textutil.Pluralize(result.fixed, "problem"))
 	}
 }
 
-// processFile reads and parses a GDScript file
-func processFile(path string) error {
+// lintPaths lints each path, and the scripts under each directory as they are
+// found, and returns the summary of the run. A path that cannot be walked
+// counts as a file in error.
+func lintPaths(paths []string, opts options) summary {
+	var result summary
+	lintScripts(paths, opts, func(s lintedScript) {
+		result.files++
+		if s.walkErr != nil {
+			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", s.path, s.walkErr)
+			result.errors++
+			result.failed = true
+			return
+		}
+		reportScript(s, opts, &result)
+	})
+	return result
+}
+
+// String returns the end-of-run summary line, e.g. "3 files, 2 warnings, 0 errors"
+func (s summary) String() string {
+	return fmt.Sprintf("%s, %s, %s", textutil.Pluralize(s.files, "file"), textutil.Pluralize(s.warnings, "warning"), textutil.Pluralize(s.errors, "error"))
+}
+
+// exitStatus returns the status a lint run exits with
+func exitStatus(result summary, opts options) int {
+	switch {
+	case result.failed:
+		return exitFailure
+	case result.errors > 0:
+		return exitErrors
+	case opts.maxWarnings >= 0 && result.warnings > opts.maxWarnings:
+		return exitWarnings
+	default:
+		return exitClean
+	}
+}
+
+// lintedScript is a script linted by lintFile
+type lintedScript struct {
+	path     string
+	problems []problem.Problem
+	source   string
+	err      error
+	// fixed is the number of problems fixed with --fix before problems were found
+	fixed int
+	// walkErr is the error of walking path when it is an argument that could
+	// not be walked rather than a script
+	walkErr error
+}
+
+// lintScripts lints the scripts of paths, and the scripts under directories
+// as they are found, with a worker per CPU, and reports each of them to
+// report, from the calling goroutine, in the order they were found
+func lintScripts(paths []string, opts options, report func(lintedScript)) {
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+	pending := make(chan chan lintedScript, cap(workers))
+	go func() {
+		defer close(pending)
+		for _, arg := range paths {
+			err := corpus.Walk(arg, func(path string) error {
+				done := make(chan lintedScript, 1)
+				pending <- done
+				workers <- struct{}{}
+				go func() {
+					defer func() { <-workers }()
+					done <- lintScript(path, opts)
+				}()
+				return nil
+			})
+			if err != nil {
+				done := make(chan lintedScript, 1)
+				done <- lintedScript{path: arg, walkErr: err}
+				pending <- done
+			}
+		}
+	}()
+	for done := range pending {
+		report(<-done)
+	}
+}
+
+// lintScript lints the script at path; with --fix, it applies the fixes of
+// the problems found and lints the fixed script again
+func lintScript(path string, opts options) lintedScript {
+	problems, source, err := lintFile(path, opts)
+	fixed := 0
+	if opts.fix && err == nil && !scene.IsScene(path) {
+		fixed, err = fixFile(path, source, problems)
+		if fixed > 0 && err == nil {
+			problems, source, err = lintFile(path, opts)
+		}
+	}
+	return lintedScript{path: path, problems: problems, source: source, err: err, fixed: fixed}
+}
+
+// fixFile rewrites the script at path, whose content is source, with the
+// fixes of problems applied, and returns the number of problems fixed
+func fixFile(path, source string, problems []problem.Problem) (int, error) {
+	fixedSource, fixed := problem.ApplyFixes(source, problems)
+	if fixed == 0 {
+		return 0, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(fixedSource), info.Mode().Perm()); err != nil {
+		return 0, fmt.Errorf("failed to write the fixes: %w", err)
+	}
+	return fixed, nil
+}
+
+// processFile lints a GDScript file, prints the problems found and adds them to result
+func processFile(path string, opts options, result *summary) {
+	reportScript(lintScript(path, opts), opts, result)
+}
+
+// reportScript prints the problems found in a linted script and adds them to result
+func reportScript(s lintedScript, opts options, result *summary) {
+	path, problems, source, err := s.path, s.problems, s.source, s.err
+	result.fixed += s.fixed
+	var parseErr *linter.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		for _, e := range parseErr.Errors {
+			diagnostic.Write(os.Stderr, diagnostic.FromParseError(path, e), source, opts.errColor)
+		}
+		result.errors++
+		return
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", path, err)
+		result.errors++
+		result.failed = true
+		return
+	}
+
+	if opts.record != nil {
+		opts.record.Add(path, source, problems)
+		return
+	}
+	if opts.baseline != nil {
+		var suppressed int
+		problems, suppressed = opts.baseline.Filter(path, source, problems)
+		result.suppressed += suppressed
+	}
+
+	var reported []problem.Problem
+	for _, p := range problems {
+		if opts.errorsOnly && p.Severity != problem.Error {
+			continue
+		}
+		reported = append(reported, p)
+		switch p.Severity {
+		case problem.Error:
+			result.errors++
+		case problem.Warning:
+			result.warnings++
+		}
+	}
+
+	// Print any problems found
+	if len(reported) > 0 {
+		for _, p := range reported {
+			diagnostic.Write(os.Stdout, diagnostic.FromProblem(path, p), source, opts.color)
+		}
+		return
+	}
+
+	if !opts.quiet {
+		fmt.Printf("Successfully linted %s (no problems found)\n", path)
+	}
+}
+
+// lintFile reads a GDScript file of at most opts.maxFileSize bytes, or of any
+// size if it is 0, and lints it with the config closest to it, returning the
+// problems found and the content of the file, which is also returned with the
+// *linter.ParseError of a script that does not parse. The problems are read
+// from opts.cache when the file, its config and gdlint are unchanged since
+// they were cached.
+func lintFile(path string, opts options) ([]problem.Problem, string, error) {
+	c := opts.cache
 	// Check if the file exists
 	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return nil, "", fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	// Check if it's a directory
 	if info.IsDir() {
-		return fmt.Errorf("path is a directory, not a file")
+		return nil, "", fmt.Errorf("path is a directory, not a file")
 	}
 
 	// Read the file
-	content, err := ioutil.ReadFile(path)
+	content, err := corpus.ReadFile(path, opts.maxFileSize)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Create a linter with all default rules
-	lint := linter.NewLinter(
-		rules.GetDefaultRules(),
-		linter.DefaultConfig(),
-	)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve path: %w", err)
+	}
 
-	// Lint the file
-	problems, err := lint.Lint(string(content))
+	config, err := loadConfig(absPath)
 	if err != nil {
-		return fmt.Errorf("failed to lint file: %w", err)
+		return nil, "", err
 	}
 
-	// Print any problems found
-	if len(problems) > 0 {
-		fmt.Printf("Linting %s:\n", path)
-		for _, p := range problems {
-			fmt.Printf("  %v\n", p)
+	var key string
+	if c != nil {
+		key = c.Key(absPath, config.Fingerprint(), content)
+		var problems []problem.Problem
+		if c.Get(key, &problems) {
+			return problems, content, nil
 		}
-		return fmt.Errorf("%d linting problems", len(problems))
 	}
 
-	fmt.Printf("Successfully linted %s (no problems found)\n", path)
+	// Create a linter with the rules the config enables and its external rules
+	lint := linter.NewLinterForConfig(rules.NewRegistry(), config)
+	lint.SetProfile(opts.profile)
+	lint.SetProject(projectOf(opts.projects, absPath))
+
+	// Lint the file, or the scripts embedded in a scene file
+	var problems []problem.Problem
+	if scene.IsScene(path) {
+		problems, err = lint.LintScene(absPath, content)
+	} else {
+		problems, err = lint.LintSource(absPath, content)
+	}
+	if err != nil {
+		return nil, content, fmt.Errorf("failed to lint file: %w", err)
+	}
+	if c != nil {
+		// The cache only saves time, so failing to write it is not an error
+		_ = c.Put(key, problems)
+	}
+	return problems, content, nil
+}
+
+// loadProjects loads the project of each path, once for paths of the same project
+func loadProjects(paths []string) ([]*project.Project, error) {
+	var projects []*project.Project
+	for _, path := range paths {
+		dir := path
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			dir = filepath.Dir(path)
+		}
+		if projectOf(projects, dir) != nil {
+			continue
+		}
+		p, err := project.Load(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the project of %s: %w", path, err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
+
+// projectOf returns the project of projects whose root contains path, or nil
+func projectOf(projects []*project.Project, path string) *project.Project {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for _, p := range projects {
+		if rel, err := filepath.Rel(p.Root, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return p
+		}
+	}
 	return nil
 }
 
-// findGDScriptFiles finds all .gd files in a directory
-func findGDScriptFiles(dir string) ([]string, error) {
-	var files []string
+// slowestFiles is the number of files printed by printProfile
+const slowestFiles = 10
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// printProfile prints the time each rule took, the slowest first, and the
+// files that took the longest to lint
+func printProfile(w io.Writer, profile *linter.Profile) {
+	timings := profile.Rules()
+	var total time.Duration
+	for _, timing := range timings {
+		total += timing.Total
+	}
+
+	fmt.Fprintln(w, "Rule timings:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tTOTAL\tSHARE\tFILES\tSLOWEST\tSLOWEST FILE")
+	for _, timing := range timings {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(timing.Total) / float64(total)
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".gd" {
-			files = append(files, path)
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%d\t%s\t%s\n", timing.Name, formatDuration(timing.Total), share,
+			timing.Files, formatDuration(timing.Slowest), timing.SlowestFile)
+	}
+	tw.Flush()
+
+	files := profile.Files()
+	if len(files) > slowestFiles {
+		files = files[:slowestFiles]
+	}
+	fmt.Fprintln(w, "\nSlowest files:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tPARSE\tLINT\tTOTAL")
+	for _, file := range files {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", file.Path, formatDuration(file.Parse), formatDuration(file.Lint), formatDuration(file.Total()))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// formatDuration formats d in milliseconds, e.g. "12.34ms"
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+// runDoctor diagnoses the project containing the given directory, or the
+// current directory, and returns the exit status
+func runDoctor(args []string) int {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	return doctor.Run(os.Stdout, dir)
+}
+
+// listRules prints every registered rule with whether it is enabled by
+// default, its default severity, its category and its description
+func listRules(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tDEFAULT\tSEVERITY\tCATEGORY\tDESCRIPTION")
+	for _, info := range rules.NewRegistry().Rules() {
+		state := "off"
+		if info.Default {
+			state = "on"
 		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			info.Rule.Name(), state, linter.DefaultSeverity(info.Rule), info.Category, info.Rule.Description())
+	}
+	tw.Flush()
+}
+
+// explainRule prints the description, default severity and settings of the rule called name
+func explainRule(w io.Writer, name string) error {
+	info, ok := rules.NewRegistry().Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown rule %q, run gdlint --list-rules to see every rule", name)
+	}
+	rule := info.Rule
+
+	enabled := "yes"
+	if !info.Default {
+		enabled = fmt.Sprintf("no, add it to enabled_rules: {\"enabled_rules\": [\"%s\"]}", rule.Name())
+	}
+
+	fmt.Fprintf(w, "%s\n  %s\n\n", rule.Name(), rule.Description())
+	fmt.Fprintf(w, "Category: %s\n", info.Category)
+	fmt.Fprintf(w, "Enabled by default: %s\n", enabled)
+	fmt.Fprintf(w, "Default severity: %s\n", linter.DefaultSeverity(rule))
+
+	settings := info.Settings()
+	if len(settings) == 0 {
+		fmt.Fprintln(w, "Settings: none")
 		return nil
-	})
+	}
+	fmt.Fprintln(w, "Settings:")
+	for _, setting := range settings {
+		fmt.Fprintf(w, "  %s (default %v)\n    %s\n", setting.Name, setting.Default, setting.Description)
+	}
+	fmt.Fprintf(w, "\nExample gdlintrc.json:\n  {\"rule_settings\": {\"%s\": {\"%s\": %s}}}\n",
+		rule.Name(), settings[0].Name, exampleValue(settings[0].Default))
+	return nil
+}
 
-	return files, err
+// exampleValue formats a setting default as a JSON value
+func exampleValue(value any) string {
+	if s, ok := value.(string); ok {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
+
+// loadConfig loads the gdlintrc closest to the file, or the default configuration if there is none
+func loadConfig(absPath string) (linter.Config, error) {
+	configPath := linter.FindConfigFileFrom(filepath.Dir(absPath))
+	if configPath == "" {
+		return linter.DefaultConfig(), nil
+	}
+	return linter.LoadConfig(configPath)
 }
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/baseline"
+	"github.com/dzannotti/gdtoolkit/internal/cache"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+func TestListRules(t *testing.T) {
+	var out bytes.Buffer
+	listRules(&out)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	all := rules.GetAllRules()
+	if len(lines) != len(all)+1 {
+		t.Fatalf("Expected a header and %d rules, got %d lines:\n%s", len(all), len(lines), out.String())
+	}
+	for i, rule := range all {
+		fields := strings.Fields(lines[i+1])
+		if fields[0] != rule.Name() {
+			t.Errorf("Line %d: expected rule %s, got %q", i+1, rule.Name(), lines[i+1])
+		}
+		if rule.Name() == "sub-class-before-parent-class" && (fields[1] != "on" || fields[2] != "error" || fields[3] != "class") {
+			t.Errorf("Expected %s to be an enabled class rule with error severity, got %q", rule.Name(), lines[i+1])
+		}
+	}
+}
+
+func TestExplainRule(t *testing.T) {
+	var out bytes.Buffer
+	if err := explainRule(&out, "max-locals"); err != nil {
+		t.Fatalf("explainRule failed: %v", err)
+	}
+	for _, expected := range []string{
+		"Checks for too many local variables in a function",
+		"Category: design",
+		`{"enabled_rules": ["max-locals"]}`,
+		"Default severity: warning",
+		"threshold (default 15)",
+		`{"rule_settings": {"max-locals": {"threshold": 15}}}`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected explanation to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := explainRule(&out, "unnecessary-pass"); err != nil {
+		t.Fatalf("explainRule failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Settings: none") {
+		t.Errorf("Expected a rule without settings, got:\n%s", out.String())
+	}
+
+	if err := explainRule(&out, "no-such-rule"); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   summary
+		opts     options
+		expected int
+	}{
+		{"clean", summary{}, options{}, exitClean},
+		{"warnings over budget", summary{warnings: 1}, options{}, exitWarnings},
+		{"warnings within budget", summary{warnings: 3}, options{maxWarnings: 3}, exitClean},
+		{"unlimited warnings", summary{warnings: 30}, options{maxWarnings: -1}, exitClean},
+		{"errors", summary{errors: 1, warnings: 5}, options{maxWarnings: -1}, exitErrors},
+		{"failure", summary{errors: 1, failed: true}, options{}, exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitStatus(tt.result, tt.opts); got != tt.expected {
+			t.Errorf("%s: expected exit status %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestProcessFileCountsSeverities(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		// unused-argument is a warning
+		"warning.gd": "func foo(unused):\n\tprint(1)\n",
+		// sub-class-before-parent-class is an error
+		"error.gd":  "class B extends A:\n\tpass\n\n\nclass A:\n\tpass\n",
+		"broken.gd": "func foo(:\n",
+	}
+	for name, code := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file     string
+		opts     options
+		expected summary
+	}{
+		{"warning.gd", options{}, summary{warnings: 1}},
+		{"warning.gd", options{errorsOnly: true}, summary{}},
+		{"error.gd", options{errorsOnly: true}, summary{errors: 1}},
+		{"broken.gd", options{}, summary{errors: 1}},
+		{"missing.gd", options{}, summary{errors: 1, failed: true}},
+		{"warning.gd", options{maxFileSize: 10}, summary{errors: 1, failed: true}},
+	}
+	for _, tt := range tests {
+		var result summary
+		processFile(filepath.Join(dir, tt.file), tt.opts, &result)
+		if result != tt.expected {
+			t.Errorf("%s (errors only: %v): expected %+v, got %+v", tt.file, tt.opts.errorsOnly, tt.expected, result)
+		}
+	}
+
+	result := summary{files: 1, warnings: 2, errors: 1}
+	if result.String() != "1 file, 2 warnings, 1 error" {
+		t.Errorf("Unexpected summary %q", result)
+	}
+}
+
+func TestLintPathsMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	opts := options{maxWarnings: -1}
+
+	var result summary
+	stdout, stderr := captureOutput(t, func() {
+		result = lintPaths([]string{missing}, opts)
+	})
+	if result.String() != "1 file, 0 warnings, 1 error" {
+		t.Errorf("Expected the missing path to count as a file in error, got %q", result)
+	}
+	if status := exitStatus(result, opts); status != exitFailure {
+		t.Errorf("Expected exit status %d, got %d", exitFailure, status)
+	}
+	if stdout != "" || !strings.Contains(stderr, "Error processing "+missing) {
+		t.Errorf("Expected the error on stderr only, got stdout %q and stderr %q", stdout, stderr)
+	}
+}
+
+// captureOutput runs f and returns what it wrote to stdout and stderr
+func captureOutput(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	savedStdout, savedStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	defer func() { os.Stdout, os.Stderr = savedStdout, savedStderr }()
+	f()
+
+	out, _ := os.ReadFile(stdout.Name())
+	errOut, _ := os.ReadFile(stderr.Name())
+	return string(out), string(errOut)
+}
+
+func TestProcessFileWithBaseline(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "player.gd")
+	if err := os.WriteFile(script, []byte("func foo(unused):\n\tprint(1)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	record := baseline.New(dir)
+	var result summary
+	processFile(script, options{record: record}, &result)
+	if len(record.Entries) != 1 || result != (summary{}) {
+		t.Fatalf("Expected one recorded problem and nothing reported, got %+v and %+v", record.Entries, result)
+	}
+	path := filepath.Join(dir, "baseline.json")
+	if err := record.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := baseline.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processFile(script, options{baseline: b}, &result)
+	if result != (summary{suppressed: 1}) {
+		t.Errorf("Expected the baselined problem to be suppressed, got %+v", result)
+	}
+
+	// Editing the line a problem was found on resurfaces it, with the new problem
+	if err := os.WriteFile(script, []byte("func foo(unused, other):\n\tprint(1)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = summary{}
+	processFile(script, options{baseline: b}, &result)
+	if result.warnings != 2 {
+		t.Errorf("Expected both problems of the changed line to be reported, got %+v", result)
+	}
+}
+
+func TestProcessFileWithCache(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "player.gd")
+	if err := os.WriteFile(script, []byte("func foo(unused):\n\tprint(1)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := cache.Open(filepath.Join(dir, cache.DefaultDir), "lint")
+
+	var result summary
+	processFile(script, options{cache: c}, &result)
+	if result != (summary{warnings: 1}) {
+		t.Fatalf("Expected the unused argument warning, got %+v", result)
+	}
+
+	// Replacing the cached problems shows that the unchanged file is not linted again
+	absPath, _ := filepath.Abs(script)
+	key := c.Key(absPath, linter.DefaultConfig().Fingerprint(), "func foo(unused):\n\tprint(1)\n")
+	if err := c.Put(key, []problem.Problem{problem.NewError(ast.Position{Line: 1}, "cached", "rule")}); err != nil {
+		t.Fatal(err)
+	}
+	result = summary{}
+	processFile(script, options{cache: c}, &result)
+	if result != (summary{errors: 1}) {
+		t.Errorf("Expected the cached problem, got %+v", result)
+	}
+
+	// A new config invalidates the cached problems
+	config := `{"disabled_rules": ["unused-argument"]}`
+	if err := os.WriteFile(filepath.Join(dir, "gdlintrc.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = summary{}
+	processFile(script, options{cache: c}, &result)
+	if result != (summary{}) {
+		t.Errorf("Expected the file to be linted again with the new config, got %+v", result)
+	}
+}
+
+func TestLintScriptsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	var expected []string
+	for i := 0; i < 20; i++ {
+		script := filepath.Join(dir, fmt.Sprintf("script_%02d.gd", i))
+		if err := os.WriteFile(script, []byte(fmt.Sprintf("func foo_%d(unused):\n\tpass\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, script)
+	}
+	missing := filepath.Join(dir, "missing")
+	expected = append(expected, missing)
+
+	var got []string
+	lintScripts([]string{dir, missing}, options{}, func(s lintedScript) {
+		got = append(got, s.path)
+		if s.path == missing && s.walkErr == nil {
+			t.Errorf("Expected an error walking %s", missing)
+		}
+		if s.path != missing && (s.err != nil || len(s.problems) != 1) {
+			t.Errorf("%s: expected the unused argument, got %v, %v", s.path, s.problems, s.err)
+		}
+	})
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the scripts in the order found, got %v", got)
+	}
+}
+
+func TestPrintProfile(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "player.gd")
+	if err := os.WriteFile(script, []byte("func foo(unused):\n\tpass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{profile: linter.NewProfile(), quiet: true}
+	var result summary
+	processFile(script, opts, &result)
+
+	var out bytes.Buffer
+	printProfile(&out, opts.profile)
+	lines := strings.Split(out.String(), "\n")
+	if lines[0] != "Rule timings:" || !strings.HasPrefix(lines[1], "RULE") {
+		t.Fatalf("Expected the rule timings first, got:\n%s", out.String())
+	}
+	for _, expected := range []string{"unused-argument ", linter.AnalysisTiming, "Slowest files:", "player.gd"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected the profile to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestLintFileScene(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.tscn")
+	content := "[gd_scene format=3]\n\n[sub_resource type=\"GDScript\" id=\"GDScript_a1\"]\n" +
+		"script/source = \"extends Node\n\nfunc _ready(unused):\n\tprint(\\\"ready\\\")\n\"\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, source, err := lintFile(path, options{})
+	if err != nil || source != content {
+		t.Fatalf("lintFile failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].RuleName != "unused-argument" {
+		t.Fatalf("Expected the unused argument of the embedded script, got %v", problems)
+	}
+	if pos := problems[0].Position; pos.Line != 6 || pos.Column != 13 || !strings.HasPrefix(content[pos.Offset:], "unused") {
+		t.Errorf("Expected the problem at 6:13 of the scene file, got %+v", pos)
+	}
+}
+
+func TestLintFileProject(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"project.godot": "config_version=5\n",
+		"a.gd":          "extends Node\nconst B = preload(\"b.gd\")\n",
+		"b.gd":          "extends Node\nconst A = preload(\"a.gd\")\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(dir, "a.gd")
+
+	problems, _, err := lintFile(script, options{})
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems without --project, got %v, %v", problems, err)
+	}
+
+	projects, err := loadProjects([]string{script, dir})
+	if err != nil || len(projects) != 1 {
+		t.Fatalf("Expected a single project for both paths, got %v, %v", projects, err)
+	}
+	problems, _, err = lintFile(script, options{projects: projects})
+	if err != nil || len(problems) != 1 || problems[0].RuleName != "cyclic-preload" || problems[0].Position.Line != 2 {
+		t.Errorf("Expected the preload cycle on line 2, got %v, %v", problems, err)
+	}
+}
+
+func TestLintScriptFix(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"gdlintrc.json": `{"enabled_rules": ["indentation-consistency"], "rule_settings": {"indentation-consistency": {"style": "spaces:2"}}}`,
+		"player.gd":     "func foo():\n\tif true:\n\t\tprint(\"\"\"a\n\tb\"\"\")\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(dir, "player.gd")
+
+	s := lintScript(script, options{})
+	if s.err != nil || len(s.problems) != 2 || s.fixed != 0 {
+		t.Fatalf("Expected two lines indented with tabs and nothing fixed, got %v, %d, %v", s.problems, s.fixed, s.err)
+	}
+
+	// The lines within the string are left as written
+	s = lintScript(script, options{fix: true})
+	if s.err != nil || len(s.problems) != 0 || s.fixed != 2 {
+		t.Fatalf("Expected both lines fixed and no problem left, got %v, %d, %v", s.problems, s.fixed, s.err)
+	}
+	content, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "func foo():\n  if true:\n    print(\"\"\"a\n\tb\"\"\")\n"; string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
+
+// TestLintPathsFix checks the output of a run with --fix, where the alignment
+// spaces indentation-consistency keeps past the tabs of a continued line are
+// not reported as mixing tabs and spaces
+func TestLintPathsFix(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"gdlintrc.json": `{"enabled_rules": ["indentation-consistency", "mixed-tabs-and-spaces"]}`,
+		"player.gd":     "func foo():\n    print(1,\n          2)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(dir, "player.gd")
+
+	stdout, stderr := captureOutput(t, func() {
+		printSummary(lintPaths([]string{script}, options{maxWarnings: -1, fix: true}))
+	})
+	expected := "Successfully linted " + script + " (no problems found)\n" +
+		"1 file, 0 warnings, 0 errors\n" +
+		"2 problems fixed\n"
+	if stdout != expected || stderr != "" {
+		t.Errorf("Expected stdout %q, got stdout %q and stderr %q", expected, stdout, stderr)
+	}
+	content, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "func foo():\n\tprint(1,\n\t      2)\n"; string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/tags"
+)
+
+// options controls how the scripts are parsed and profiled
+type options struct {
+	repeat     int    // number of times every script is parsed
+	profile    string // file the CPU profile is written to
+	memProfile string // file the heap profile is written to
+	tags       string // tags file indexing the declarations of the scripts
+	tagsFormat string // ctags or etags
+	verify     bool   // report the lines of code no syntax tree node covers
+}
+
+func main() {
+	// Parse command-line flags
+	var opts options
+	flag.IntVar(&opts.repeat, "repeat", 1, "Parse every script this many times, for steadier timings and profiles")
+	flag.StringVar(&opts.profile, "profile", "", "Write a CPU profile of the parsing to this file")
+	flag.StringVar(&opts.memProfile, "memprofile", "", "Write a heap profile to this file after parsing")
+	flag.StringVar(&opts.tags, "tags", "", "Write a tags file indexing the classes, functions, signals, variables and enums of the scripts")
+	flag.StringVar(&opts.tagsFormat, "tags-format", "ctags", "Format of the tags file: 'ctags' or 'etags' (Emacs)")
+	flag.BoolVar(&opts.verify, "verify", false, "Report the lines of code that produced no syntax tree node, i.e. that the parser dropped")
+	flag.Parse()
+
+	// Get the paths from the command-line arguments
+	args := flag.Args()
+	if len(args) == 0 || opts.repeat < 1 || (opts.tagsFormat != "ctags" && opts.tagsFormat != "etags") {
+		fmt.Println("Usage: gdparse [--repeat N] [--profile cpu.out] [--memprofile mem.out] [--tags tags [--tags-format ctags|etags]] [--verify] [file.gd|dir...]")
+		os.Exit(1)
+	}
+
+	// Load every script up front, so that profiles cover parsing only
+	var scripts corpus.Corpus
+	for _, path := range args {
+		c, err := corpus.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		scripts.Files = append(scripts.Files, c.Files...)
+		scripts.Bytes += c.Bytes
+		scripts.Lines += c.Lines
+	}
+
+	if opts.profile != "" {
+		f, err := os.Create(opts.profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create CPU profile: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start CPU profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var index []tags.Tag
+	dropped := 0
+	var collect func(corpus.File, *ast.AbstractSyntaxTree)
+	if opts.tags != "" || opts.verify {
+		collect = func(file corpus.File, tree *ast.AbstractSyntaxTree) {
+			if opts.tags != "" {
+				index = append(index, tags.Extract(tagPath(opts.tags, file.Path), file.Source, tree)...)
+			}
+			if opts.verify {
+				dropped += verifyTree(os.Stdout, file, tree)
+			}
+		}
+	}
+	failed := parseCorpus(&scripts, opts.repeat, os.Stdout, collect)
+	if dropped > 0 {
+		fmt.Printf("%d lines produced no syntax tree node\n", dropped)
+	}
+
+	if opts.profile != "" {
+		pprof.StopCPUProfile()
+	}
+	if opts.memProfile != "" {
+		if err := writeHeapProfile(opts.memProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write heap profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if opts.tags != "" {
+		if err := writeTags(opts.tags, opts.tagsFormat, index); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write tags: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Exit with non-zero status if a script did not parse or lost code
+	if failed > 0 || dropped > 0 {
+		os.Exit(1)
+	}
+}
+
+// parseCorpus parses every script repeat times, reports parse errors and a
+// timing summary to w, and returns the number of scripts that did not parse.
+// collect, when not nil, receives the tree of every script that parses, once.
+func parseCorpus(scripts *corpus.Corpus, repeat int, w io.Writer, collect func(corpus.File, *ast.AbstractSyntaxTree)) int {
+	failed := 0
+	start := time.Now()
+	for i := 0; i < repeat; i++ {
+		for _, file := range scripts.Files {
+			tree, errors := parser.ParseFile(file.Path, file.Source)
+			// Trees and errors are the same on every pass; use them once
+			if i > 0 {
+				continue
+			}
+			if len(errors) == 0 {
+				if collect != nil {
+					collect(file, tree)
+				}
+				continue
+			}
+			failed++
+			fmt.Fprintf(w, "Parsing %s:\n", file.Path)
+			for _, err := range errors {
+				fmt.Fprintf(w, "  %v\n", err)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+
+	lines := scripts.Lines * repeat
+	fmt.Fprintf(w, "Parsed %d files, %d lines in %v", len(scripts.Files), scripts.Lines, elapsed.Round(time.Microsecond))
+	if repeat > 1 {
+		fmt.Fprintf(w, " (%d times)", repeat)
+	}
+	if elapsed > 0 {
+		fmt.Fprintf(w, ", %.0f lines/s", float64(lines)/elapsed.Seconds())
+	}
+	fmt.Fprintln(w)
+	if failed > 0 {
+		fmt.Fprintf(w, "%d files failed to parse\n", failed)
+	}
+	return failed
+}
+
+// verifyTree reports to w the lines of code of file that no node of its tree
+// covers, and returns their number
+func verifyTree(w io.Writer, file corpus.File, tree *ast.AbstractSyntaxTree) int {
+	lines := parser.UncoveredLines(file.Source, tree)
+	if len(lines) == 0 {
+		return 0
+	}
+	source := strings.Split(file.Source, "\n")
+	fmt.Fprintf(w, "Verifying %s:\n", file.Path)
+	for _, line := range lines {
+		fmt.Fprintf(w, "  line %d produced no syntax tree node: %s\n", line, strings.TrimSpace(source[line-1]))
+	}
+	return len(lines)
+}
+
+// tagPath returns the path of a script as written in the tags file at
+// tagsFile: relative to the directory of the tags file, as editors expect
+func tagPath(tagsFile, script string) string {
+	dir, err := filepath.Abs(filepath.Dir(tagsFile))
+	if err != nil {
+		return script
+	}
+	abs, err := filepath.Abs(script)
+	if err != nil {
+		return script
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return script
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeTags writes the tags file at path in the given format
+func writeTags(path, format string, index []tags.Tag) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == "etags" {
+		err = tags.WriteEtags(f, index)
+	} else {
+		err = tags.WriteCtags(f, index)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeHeapProfile writes a profile of the memory still in use to path
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Collect garbage first, so that the profile shows live memory only
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/tags"
+)
+
+func TestParseCorpus(t *testing.T) {
+	scripts := &corpus.Corpus{
+		Files: []corpus.File{
+			{Path: "good.gd", Source: "func foo():\n\tpass\n"},
+			{Path: "bad.gd", Source: "func (:\n"},
+		},
+		Lines: 3,
+	}
+
+	var out strings.Builder
+	failed := parseCorpus(scripts, 3, &out, nil)
+
+	if failed != 1 {
+		t.Errorf("Expected 1 failed script, got %d", failed)
+	}
+	output := out.String()
+	if strings.Count(output, "Parsing bad.gd:") != 1 {
+		t.Errorf("Expected the errors of bad.gd to be reported once, got:\n%s", output)
+	}
+	if strings.Contains(output, "good.gd") {
+		t.Errorf("Expected no report for good.gd, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Parsed 2 files, 3 lines in ") || !strings.Contains(output, "(3 times)") {
+		t.Errorf("Expected a timing summary, got:\n%s", output)
+	}
+}
+
+func TestTags(t *testing.T) {
+	dir := t.TempDir()
+	scripts := &corpus.Corpus{
+		Files: []corpus.File{
+			{Path: filepath.Join(dir, "scripts", "player.gd"), Source: "func jump():\n\tpass\n"},
+			{Path: filepath.Join(dir, "bad.gd"), Source: "func (:\n"},
+		},
+	}
+
+	tagsFile := filepath.Join(dir, "tags")
+	var index []tags.Tag
+	parseCorpus(scripts, 2, &strings.Builder{}, func(file corpus.File, tree *ast.AbstractSyntaxTree) {
+		index = append(index, tags.Extract(tagPath(tagsFile, file.Path), file.Source, tree)...)
+	})
+	if len(index) != 1 {
+		t.Fatalf("Expected the tag of jump collected once, got %+v", index)
+	}
+
+	if err := writeTags(tagsFile, "ctags", index); err != nil {
+		t.Fatalf("writeTags failed: %v", err)
+	}
+	content, err := os.ReadFile(tagsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Paths are relative to the tags file
+	if !strings.Contains(string(content), "jump\tscripts/player.gd\t/^func jump():$/;\"\tm\tline:1\n") {
+		t.Errorf("Unexpected tags file:\n%s", content)
+	}
+}
+
+func TestWriteHeapProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mem.out")
+	if err := writeHeapProfile(path); err != nil {
+		t.Fatalf("writeHeapProfile failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Errorf("Expected a non-empty heap profile, got %v", err)
+	}
+}
+
+func TestVerifyTree(t *testing.T) {
+	source := "func foo():\n\tpass\n\treturn 1\n"
+	tree, _ := parser.ParseFile("foo.gd", source)
+	var out strings.Builder
+	if dropped := verifyTree(&out, corpus.File{Path: "foo.gd", Source: source}, tree); dropped != 0 {
+		t.Errorf("Expected no dropped lines, got %d:\n%s", dropped, out.String())
+	}
+
+	function := tree.RootClass.Functions[0]
+	function.Statements = function.Statements[:1]
+	out.Reset()
+	if dropped := verifyTree(&out, corpus.File{Path: "foo.gd", Source: source}, tree); dropped != 1 {
+		t.Errorf("Expected 1 dropped line, got %d", dropped)
+	}
+	if !strings.Contains(out.String(), "line 3 produced no syntax tree node: return 1") {
+		t.Errorf("Expected the dropped return reported, got:\n%s", out.String())
+	}
+}
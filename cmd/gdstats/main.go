@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/metrics"
+)
+
+// report is the measure of the scripts given to gdstats
+type report struct {
+	Files []metrics.Summary `json:"files"`
+	Total metrics.Summary   `json:"total"`
+}
+
+func main() {
+	// Parse command-line flags
+	format := flag.String("format", "table", "Format of the report: 'table', 'json' or 'csv'")
+	flag.Parse()
+
+	// Get the paths from the command-line arguments
+	args := flag.Args()
+	if len(args) == 0 || (*format != "table" && *format != "json" && *format != "csv") {
+		fmt.Println("Usage: gdstats [--format table|json|csv] [file.gd|dir...]")
+		os.Exit(1)
+	}
+
+	var summaries []metrics.Summary
+	var functions [][]metrics.Function
+	failed := 0
+	for _, root := range args {
+		scripts, err := corpus.Load(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", root, err)
+			os.Exit(1)
+		}
+		files, measured, errors := measureScripts(scripts, os.Stderr)
+		summaries = append(summaries, files...)
+		functions = append(functions, measured...)
+		failed += errors
+	}
+
+	r := report{Files: summaries, Total: metrics.Total(summaries, functions)}
+	if r.Files == nil {
+		r.Files = []metrics.Summary{}
+	}
+	if err := writeReport(os.Stdout, r, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Exit with non-zero status if a script could not be measured
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// measureScripts measures the scripts that parse, reporting the others to
+// w, and returns their summaries and functions with the number of failures
+func measureScripts(scripts *corpus.Corpus, w io.Writer) ([]metrics.Summary, [][]metrics.Function, int) {
+	var summaries []metrics.Summary
+	var functions [][]metrics.Function
+	failed := 0
+	for _, file := range scripts.Files {
+		tree, errors := parser.ParseFile(file.Path, file.Source)
+		if len(errors) > 0 {
+			failed++
+			fmt.Fprintf(w, "Parsing %s:\n", file.Path)
+			for _, err := range errors {
+				fmt.Fprintf(w, "  %v\n", err)
+			}
+			continue
+		}
+		summary, measured := metrics.Measure(file.Path, file.Source, tree)
+		summaries = append(summaries, summary)
+		functions = append(functions, measured)
+	}
+	return summaries, functions, failed
+}
+
+// columns are the headers of the table and CSV reports
+var columns = []string{
+	"path", "lines", "code_lines", "comment_lines", "blank_lines", "classes", "functions",
+	"average_function_lines", "longest_function", "longest_function_lines", "max_nesting_depth", "comment_density",
+}
+
+// headers are the headers of the table report, in the order of columns
+var headers = []string{
+	"PATH", "LINES", "CODE LINES", "COMMENT LINES", "BLANK LINES", "CLASSES", "FUNCS",
+	"AVG FUNC LINES", "LONGEST FUNC", "LONGEST FUNC LINES", "MAX DEPTH", "COMMENT %",
+}
+
+// row returns the cells of summary in the order of columns
+func row(s metrics.Summary) []string {
+	return []string{
+		s.Path, strconv.Itoa(s.Lines), strconv.Itoa(s.CodeLines), strconv.Itoa(s.CommentLines),
+		strconv.Itoa(s.BlankLines), strconv.Itoa(s.Classes), strconv.Itoa(s.Functions),
+		strconv.FormatFloat(s.AverageFunctionLines, 'f', 1, 64), s.LongestFunction,
+		strconv.Itoa(s.LongestFunctionLines), strconv.Itoa(s.MaxNestingDepth),
+		strconv.FormatFloat(s.CommentDensity, 'f', 2, 64),
+	}
+}
+
+// writeReport writes r to w: as a table with a row per script and the
+// total last, as CSV with the same rows, or as JSON
+func writeReport(w io.Writer, r report, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write(columns)
+		for _, summary := range r.Files {
+			writer.Write(row(summary))
+		}
+		writer.Write(row(r.Total))
+		writer.Flush()
+		return writer.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	rows := append(append([]metrics.Summary{}, r.Files...), r.Total)
+	for _, summary := range rows {
+		cells := row(summary)
+		// The longest function is empty when there are no functions
+		if cells[8] == "" {
+			cells[8] = "-"
+		}
+		cells[11] = strconv.FormatFloat(summary.CommentDensity*100, 'f', 0, 64) + "%"
+		for i, cell := range cells {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
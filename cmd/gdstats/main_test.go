@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/metrics"
+)
+
+func TestGdstats(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"player.gd": "extends Node\n# Moves\nfunc move():\n\tif true:\n\t\tpass\n",
+		"empty.gd":  "extends Node\n",
+		"broken.gd": "func (:\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scripts, err := corpus.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errors strings.Builder
+	summaries, functions, failed := measureScripts(scripts, &errors)
+	if failed != 1 || !strings.Contains(errors.String(), "broken.gd") {
+		t.Errorf("Expected broken.gd to fail, got %d failures:\n%s", failed, errors.String())
+	}
+	r := report{Files: summaries, Total: metrics.Total(summaries, functions)}
+
+	t.Run("table", func(t *testing.T) {
+		var out strings.Builder
+		if err := writeReport(&out, r, "table"); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if len(lines) != 4 || !strings.HasPrefix(lines[0], "PATH") || !strings.HasPrefix(lines[3], "total") {
+			t.Fatalf("Expected a header, a row per script and the total, got:\n%s", out.String())
+		}
+		// The cells of the header are apart by at least the padding of the table
+		header := regexp.MustCompile(`\s{2,}`).Split(strings.TrimSpace(lines[0]), -1)
+		expected := []string{
+			"PATH", "LINES", "CODE LINES", "COMMENT LINES", "BLANK LINES", "CLASSES", "FUNCS",
+			"AVG FUNC LINES", "LONGEST FUNC", "LONGEST FUNC LINES", "MAX DEPTH", "COMMENT %",
+		}
+		if strings.Join(header, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected the header %q, got %q", expected, header)
+		}
+		if fields := strings.Fields(lines[1]); fields[len(fields)-4] != "-" || fields[len(fields)-1] != "0%" {
+			t.Errorf("Expected no longest function for empty.gd, got %q", lines[1])
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var out strings.Builder
+		if err := writeReport(&out, r, "csv"); err != nil {
+			t.Fatal(err)
+		}
+		expected := filepath.Join(root, "player.gd") + ",5,4,1,0,1,1,3.0,move,3,1,0.20\n"
+		if !strings.HasPrefix(out.String(), "path,lines,") || !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected the row %q, got:\n%s", expected, out.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out strings.Builder
+		if err := writeReport(&out, r, "json"); err != nil {
+			t.Fatal(err)
+		}
+		var decoded report
+		if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+			t.Fatalf("Output is not a JSON report: %v\n%s", err, out.String())
+		}
+		if len(decoded.Files) != 2 || decoded.Total.Lines != 6 || decoded.Total.LongestFunction != filepath.Join(root, "player.gd")+":move" {
+			t.Errorf("Unexpected report %+v", decoded)
+		}
+	})
+}
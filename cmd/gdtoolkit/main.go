@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/doctor"
+	"github.com/dzannotti/gdtoolkit/internal/project"
+	"github.com/dzannotti/gdtoolkit/internal/translation"
+)
+
+// commands are the subcommands of gdtoolkit, each taking the arguments
+// that follow its name and returning the exit status
+var commands = map[string]func(args []string) int{
+	"extract-translations": extractTranslations,
+	"doctor":               runDoctor,
+}
+
+func usage() {
+	fmt.Println("Usage: gdtoolkit <command> [arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  extract-translations [--format pot|csv] [--locale en] [--output file] [file.gd|dir...]")
+	fmt.Println("        Write the strings translated with tr(), tr_n(), atr() and atr_n() as a POT or CSV file")
+	fmt.Println("  doctor [dir]")
+	fmt.Println("        Check the gdlintrc, project.godot and scripts of the project containing dir")
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		usage()
+		os.Exit(1)
+	}
+	os.Exit(commands[os.Args[1]](os.Args[2:]))
+}
+
+// runDoctor checks the setup of the project containing the given
+// directory, or the current one, as gdlint doctor does
+func runDoctor(args []string) int {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	return doctor.Run(os.Stdout, dir)
+}
+
+// translationOptions controls how the extracted strings are written
+type translationOptions struct {
+	format string // pot or csv
+	locale string // language of the strings, the column of the CSV file
+	output string // file written; standard output when empty
+}
+
+func extractTranslations(args []string) int {
+	var opts translationOptions
+	flags := flag.NewFlagSet("extract-translations", flag.ExitOnError)
+	flags.StringVar(&opts.format, "format", "pot", "Format of the output: 'pot' or 'csv'")
+	flags.StringVar(&opts.locale, "locale", "en", "Locale of the strings, the translation column of the CSV file")
+	flags.StringVar(&opts.output, "output", "", "Write to this file instead of the standard output")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 || (opts.format != "pot" && opts.format != "csv") {
+		usage()
+		return 1
+	}
+
+	catalog := translation.NewCatalog()
+	failed := 0
+	for _, root := range paths {
+		scripts, err := corpus.Load(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", root, err)
+			return 1
+		}
+		failed += extractCatalog(catalog, scripts, os.Stderr)
+	}
+
+	var err error
+	if opts.output == "" {
+		err = writeCatalog(os.Stdout, catalog, opts)
+	} else {
+		var file *os.File
+		if file, err = os.Create(opts.output); err == nil {
+			err = writeCatalog(file, catalog, opts)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Exit with non-zero status if a script could not be read
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// extractCatalog adds the strings of scripts to catalog, reporting the
+// scripts that do not parse to w, and returns the number of failures
+func extractCatalog(catalog *translation.Catalog, scripts *corpus.Corpus, w io.Writer) int {
+	failed := 0
+	for _, file := range scripts.Files {
+		tree, errors := parser.ParseFile(file.Path, file.Source)
+		if len(errors) > 0 {
+			failed++
+			fmt.Fprintf(w, "Parsing %s:\n", file.Path)
+			for _, err := range errors {
+				fmt.Fprintf(w, "  %v\n", err)
+			}
+			continue
+		}
+		catalog.Extract(sourcePath(file.Path), tree)
+	}
+	return failed
+}
+
+// sourcePath returns the path the sources of the strings of the script at
+// path are written with: its res:// path in a project, as Godot writes
+// them, or else path itself
+func sourcePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	root := project.FindRoot(filepath.Dir(abs))
+	if root == "" {
+		return filepath.ToSlash(path)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return "res://" + filepath.ToSlash(rel)
+}
+
+// writeCatalog writes the strings of catalog to w in the format of opts
+func writeCatalog(w io.Writer, catalog *translation.Catalog, opts translationOptions) error {
+	if opts.format == "csv" {
+		return translation.WriteCSV(w, catalog.Messages, opts.locale)
+	}
+	return translation.WritePOT(w, catalog.Messages)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/translation"
+)
+
+func TestExtractTranslations(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"project.godot": "config_version=5\n",
+		"ui/hud.gd":     "extends Control\nfunc _ready():\n\t$Label.text = tr(\"Score\")\n",
+		"broken.gd":     "func (:\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scripts, err := corpus.Load(filepath.Join(root, "ui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := translation.NewCatalog()
+	var errors strings.Builder
+	if failed := extractCatalog(catalog, scripts, &errors); failed != 0 {
+		t.Fatalf("Expected no failures, got %d:\n%s", failed, errors.String())
+	}
+
+	// Sources are res:// paths, even when only part of the project is read
+	var out strings.Builder
+	if err := writeCatalog(&out, catalog, translationOptions{format: "pot"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "#: res://ui/hud.gd:3\nmsgid \"Score\"\n") {
+		t.Errorf("Unexpected template:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := writeCatalog(&out, catalog, translationOptions{format: "csv", locale: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "keys,en,_sources\nScore,Score,res://ui/hud.gd:3\n" {
+		t.Errorf("Unexpected CSV:\n%s", out.String())
+	}
+
+	scripts, err = corpus.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errors.Reset()
+	if failed := extractCatalog(translation.NewCatalog(), scripts, &errors); failed != 1 || !strings.Contains(errors.String(), "broken.gd") {
+		t.Errorf("Expected broken.gd to fail, got %d failures:\n%s", failed, errors.String())
+	}
+}
@@ -0,0 +1,156 @@
+// Package baseline records the problems a project already has, so that gdlint
+// can be adopted gradually: baselined problems are suppressed and only new
+// ones are reported
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// Version is the version of the baseline file format
+const Version = 1
+
+// Entry is a problem recorded in the baseline
+type Entry struct {
+	// File is the path of the script, slash-separated and relative to the baseline file
+	File string `json:"file"`
+	Rule string `json:"rule"`
+	// Fingerprint identifies the problem independently of its line number, so
+	// that editing other parts of the file does not resurface it
+	Fingerprint string `json:"fingerprint"`
+	Message     string `json:"message"`
+}
+
+// Baseline is a set of known problems. Each entry suppresses one problem, so
+// a script that gains a second identical problem reports the new one.
+type Baseline struct {
+	Version int `json:"version"`
+	// Tool is the version of gdlint that recorded the baseline, e.g.
+	// "gdlint 1.2.0 (GDScript 2.0)", telling baselines to regenerate after
+	// an upgrade apart
+	Tool    string  `json:"tool,omitempty"`
+	Entries []Entry `json:"entries"`
+
+	// dir is the directory file paths are relative to
+	dir string
+}
+
+// New creates an empty baseline to be written to a file in dir
+func New(dir string) *Baseline {
+	return &Baseline{Version: Version, Entries: []Entry{}, dir: dir}
+}
+
+// Load reads a baseline file
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s, regenerate it with --generate-baseline", b.Version, path)
+	}
+	if b.dir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Write saves the baseline to path, with entries sorted so that regenerating
+// an unchanged project gives an identical file
+func (b *Baseline) Write(path string) error {
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		a, c := b.Entries[i], b.Entries[j]
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		if a.Rule != c.Rule {
+			return a.Rule < c.Rule
+		}
+		return a.Fingerprint < c.Fingerprint
+	})
+	data, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Add records the problems found in the script at path, whose content is source
+func (b *Baseline) Add(path, source string, problems []problem.Problem) {
+	file := b.relative(path)
+	for _, p := range problems {
+		b.Entries = append(b.Entries, Entry{
+			File:        file,
+			Rule:        p.RuleName,
+			Fingerprint: Fingerprint(p, source),
+			Message:     p.Message,
+		})
+	}
+}
+
+// Filter returns the problems of the script at path that the baseline does
+// not record, and the number of problems it suppressed
+func (b *Baseline) Filter(path, source string, problems []problem.Problem) ([]problem.Problem, int) {
+	file := b.relative(path)
+	known := make(map[Entry]int)
+	for _, entry := range b.Entries {
+		if entry.File == file {
+			known[entryKey(entry)]++
+		}
+	}
+
+	var remaining []problem.Problem
+	suppressed := 0
+	for _, p := range problems {
+		key := Entry{File: file, Rule: p.RuleName, Fingerprint: Fingerprint(p, source)}
+		if known[key] > 0 {
+			known[key]--
+			suppressed++
+			continue
+		}
+		remaining = append(remaining, p)
+	}
+	return remaining, suppressed
+}
+
+// entryKey returns the fields of entry that identify a problem
+func entryKey(entry Entry) Entry {
+	return Entry{File: entry.File, Rule: entry.Rule, Fingerprint: entry.Fingerprint}
+}
+
+// relative returns path relative to the baseline directory, slash-separated
+func (b *Baseline) relative(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if b.dir != "" {
+		if rel, err := filepath.Rel(b.dir, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// Fingerprint identifies a problem by its rule, its message and the trimmed
+// text of the line it was found on, leaving out the line number
+func Fingerprint(p problem.Problem, source string) string {
+	line := ""
+	if lines := strings.Split(source, "\n"); p.Position.Line >= 1 && p.Position.Line <= len(lines) {
+		line = strings.TrimSpace(lines[p.Position.Line-1])
+	}
+	sum := sha256.Sum256([]byte(p.RuleName + "\x00" + p.Message + "\x00" + line))
+	return hex.EncodeToString(sum[:8])
+}
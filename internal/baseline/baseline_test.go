@@ -0,0 +1,86 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+func warningAt(line int, message, rule string) problem.Problem {
+	return problem.NewWarning(ast.Position{Line: line, Column: 1}, message, rule)
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "src", "player.gd")
+	source := "func foo(unused):\n\tpass\n\tpass\n"
+	problems := []problem.Problem{
+		warningAt(1, "Unused argument 'unused'", "unused-argument"),
+		warningAt(2, "Unnecessary pass", "unnecessary-pass"),
+		warningAt(3, "Unnecessary pass", "unnecessary-pass"),
+	}
+
+	recorded := New(dir)
+	recorded.Add(script, source, problems)
+	path := filepath.Join(dir, "baseline.json")
+	if err := recorded.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	b, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(b.Entries) != 3 || b.Entries[0].File != "src/player.gd" {
+		t.Fatalf("Expected 3 entries for src/player.gd, got %+v", b.Entries)
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		remaining, suppressed := b.Filter(script, source, problems)
+		if len(remaining) != 0 || suppressed != 3 {
+			t.Errorf("Expected every problem suppressed, got %v (%d suppressed)", remaining, suppressed)
+		}
+	})
+
+	t.Run("lines_shifted", func(t *testing.T) {
+		shifted := "# header\n\n" + source
+		moved := []problem.Problem{
+			warningAt(3, "Unused argument 'unused'", "unused-argument"),
+			warningAt(4, "Unnecessary pass", "unnecessary-pass"),
+			warningAt(5, "Unnecessary pass", "unnecessary-pass"),
+		}
+		remaining, suppressed := b.Filter(script, shifted, moved)
+		if len(remaining) != 0 || suppressed != 3 {
+			t.Errorf("Expected moved problems to stay suppressed, got %v (%d suppressed)", remaining, suppressed)
+		}
+	})
+
+	t.Run("new_problems", func(t *testing.T) {
+		grown := source + "\tpass\n"
+		more := append(append([]problem.Problem{}, problems...),
+			warningAt(4, "Unnecessary pass", "unnecessary-pass"))
+		remaining, suppressed := b.Filter(script, grown, more)
+		if len(remaining) != 1 || suppressed != 3 {
+			t.Errorf("Expected one new problem, got %v (%d suppressed)", remaining, suppressed)
+		}
+
+		other := filepath.Join(dir, "src", "enemy.gd")
+		remaining, suppressed = b.Filter(other, source, problems)
+		if len(remaining) != 3 || suppressed != 0 {
+			t.Errorf("Expected problems of another file to be reported, got %v (%d suppressed)", remaining, suppressed)
+		}
+	})
+}
+
+func TestLoadRejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "entries": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for an unknown baseline version")
+	}
+}
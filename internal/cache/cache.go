@@ -0,0 +1,154 @@
+// Package cache keeps the results of linting and parsing scripts on disk,
+// keyed by a hash of everything they depend on, so that repeated runs skip
+// the scripts that did not change
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// Version is the version of the layout of cache entries; changing it
+// invalidates every entry
+const Version = 1
+
+// DefaultDir is the directory the tools keep their cache in, relative to the
+// current directory
+const DefaultDir = ".gdtoolkit-cache"
+
+// Cache stores JSON entries under a directory
+type Cache struct {
+	dir string
+	// salt is hashed into every key, so that entries written by another
+	// build of the tools are never read
+	salt string
+}
+
+// Open returns the cache of namespace, e.g. "lint", kept under dir. The
+// directory is created when the first entry is written.
+func Open(dir, namespace string) *Cache {
+	return &Cache{
+		dir:  filepath.Join(dir, namespace),
+		salt: strconv.Itoa(Version) + "\x00" + namespace + "\x00" + ToolVersion(),
+	}
+}
+
+// Key hashes parts, such as the path, content and config of a script, into
+// the key of its entry
+func (c *Cache) Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{c.salt}, parts...) {
+		// Length prefixes keep parts from running into each other
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the file of the entry for key, spread over subdirectories
+// named after its first two characters
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get decodes the entry for key into v and reports whether there was one.
+// Unreadable entries count as missing.
+func (c *Cache) Get(key string, v any) bool {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// Put stores v as the entry for key. The entry is written to a temporary file
+// and renamed, so that concurrent runs never read a partial entry.
+func (c *Cache) Put(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := c.init(); err != nil {
+		return err
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// init creates the root of the cache with a .gitignore, so that it is never
+// committed, and a CACHEDIR.TAG, so that backup tools skip it
+func (c *Cache) init() error {
+	root := filepath.Dir(c.dir)
+	if _, err := os.Stat(filepath.Join(root, "CACHEDIR.TAG")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("*\n"), 0644); err != nil {
+		return err
+	}
+	tag := "Signature: 8a477f597d28d172789f06886806bc55\n# This directory is a cache of gdtoolkit; see https://bford.info/cachedir/\n"
+	return os.WriteFile(filepath.Join(root, "CACHEDIR.TAG"), []byte(tag), 0644)
+}
+
+var (
+	toolVersion     string
+	toolVersionOnce sync.Once
+)
+
+// ToolVersion identifies the build of the running tool: the hash of its
+// executable, so that rebuilding it with other rules invalidates the cache
+// even when its version number did not change
+func ToolVersion() string {
+	toolVersionOnce.Do(func() {
+		toolVersion = hashExecutable()
+		if toolVersion == "" {
+			if info, ok := debug.ReadBuildInfo(); ok {
+				toolVersion = info.Main.Version
+			}
+		}
+	})
+	return toolVersion
+}
+
+func hashExecutable() string {
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
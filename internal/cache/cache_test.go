@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), DefaultDir)
+	c := Open(dir, "lint")
+
+	key := c.Key("player.gd", "extends Node\n")
+	var got []string
+	if c.Get(key, &got) {
+		t.Fatal("Expected no entry in an empty cache")
+	}
+	if err := c.Put(key, []string{"a", "b"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if !c.Get(key, &got) || len(got) != 2 || got[1] != "b" {
+		t.Errorf("Expected the stored entry, got %v", got)
+	}
+
+	// Another cache over the same directory sees the entry
+	if !Open(dir, "lint").Get(key, &got) {
+		t.Error("Expected the entry to persist")
+	}
+	if Open(dir, "format").Get(key, &got) {
+		t.Error("Expected namespaces not to share entries")
+	}
+
+	for _, name := range []string{".gitignore", "CACHEDIR.TAG"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s at the root of the cache: %v", name, err)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	c := Open(t.TempDir(), "lint")
+	if c.Key("ab", "c") == c.Key("a", "bc") {
+		t.Error("Expected parts not to run into each other")
+	}
+	if c.Key("a") != c.Key("a") {
+		t.Error("Expected keys to be stable")
+	}
+}
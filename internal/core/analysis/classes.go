@@ -0,0 +1,32 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/godotapi"
+
+// builtinTypes lists the Variant types built into GDScript
+var builtinTypes = map[string]bool{
+	"bool": true, "int": true, "float": true, "String": true, "StringName": true, "NodePath": true,
+	"Vector2": true, "Vector2i": true, "Vector3": true, "Vector3i": true, "Vector4": true, "Vector4i": true,
+	"Rect2": true, "Rect2i": true, "Transform2D": true, "Transform3D": true, "Plane": true,
+	"Quaternion": true, "AABB": true, "Basis": true, "Projection": true, "Color": true, "RID": true,
+	"Object": true, "Callable": true, "Signal": true, "Dictionary": true, "Array": true,
+	"PackedByteArray": true, "PackedInt32Array": true, "PackedInt64Array": true,
+	"PackedFloat32Array": true, "PackedFloat64Array": true, "PackedStringArray": true,
+	"PackedVector2Array": true, "PackedVector3Array": true, "PackedColorArray": true,
+	"PackedVector4Array": true,
+}
+
+// IsBuiltinType reports whether name is a Variant type built into GDScript
+func IsBuiltinType(name string) bool {
+	return builtinTypes[name]
+}
+
+// IsCoreClass reports whether name is an engine class of the embedded API database
+func IsCoreClass(name string) bool {
+	return godotapi.Default().HasClass(name)
+}
+
+// IsSubclassOf reports whether class is ancestor or inherits from it,
+// according to the embedded API database
+func IsSubclassOf(class, ancestor string) bool {
+	return godotapi.Default().IsSubclassOf(class, ancestor)
+}
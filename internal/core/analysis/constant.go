@@ -0,0 +1,388 @@
+package analysis
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// ConstantKind is the type of a Constant
+type ConstantKind int
+
+const (
+	ConstantBool ConstantKind = iota
+	ConstantInt
+	ConstantFloat
+	ConstantString
+)
+
+// Constant is the value of an expression known without running the script
+type Constant struct {
+	Kind   ConstantKind
+	Bool   bool
+	Int    int64
+	Float  float64
+	String string
+}
+
+// IsNumber reports whether c is an int or a float
+func (c Constant) IsNumber() bool {
+	return c.Kind == ConstantInt || c.Kind == ConstantFloat
+}
+
+// Number returns the value of an int or float constant as a float
+func (c Constant) Number() float64 {
+	if c.Kind == ConstantInt {
+		return float64(c.Int)
+	}
+	return c.Float
+}
+
+// Truthy reports whether c counts as true in a condition: false, 0, 0.0 and
+// the empty string do not
+func (c Constant) Truthy() bool {
+	switch c.Kind {
+	case ConstantBool:
+		return c.Bool
+	case ConstantInt:
+		return c.Int != 0
+	case ConstantFloat:
+		return c.Float != 0
+	default:
+		return c.String != ""
+	}
+}
+
+// Source returns c as GDScript would write it
+func (c Constant) Source() string {
+	switch c.Kind {
+	case ConstantBool:
+		return strconv.FormatBool(c.Bool)
+	case ConstantInt:
+		return strconv.FormatInt(c.Int, 10)
+	case ConstantFloat:
+		switch {
+		case math.IsInf(c.Float, 1):
+			return "INF"
+		case math.IsInf(c.Float, -1):
+			return "-INF"
+		case math.IsNaN(c.Float):
+			return "NAN"
+		}
+		s := strconv.FormatFloat(c.Float, 'g', -1, 64)
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			s += ".0"
+		}
+		return s
+	default:
+		return strconv.Quote(c.String)
+	}
+}
+
+// Fold evaluates expr when its value is known without running the script:
+// literals of bools, numbers and strings, and the operators of GDScript
+// applied to them. An expression subtracted from or compared with itself
+// also folds, whatever its value, as long as evaluating it has no side
+// effects: x - x is 0 and x <= x is true. Fold reports false for any other
+// expression, and for operations that fail at runtime, such as an integer
+// division by zero.
+func Fold(expr ast.Expression) (Constant, bool) {
+	switch e := expr.(type) {
+	case *ast.BooleanLiteral:
+		return Constant{Kind: ConstantBool, Bool: e.Value}, true
+	case *ast.NumberLiteral:
+		if e.IsInt {
+			return Constant{Kind: ConstantInt, Int: int64(e.Value)}, true
+		}
+		return Constant{Kind: ConstantFloat, Float: e.Value}, true
+	case *ast.StringLiteral:
+		return Constant{Kind: ConstantString, String: e.Value}, true
+	case *ast.PrefixExpression:
+		right, ok := Fold(e.Right)
+		if !ok {
+			return Constant{}, false
+		}
+		return foldPrefix(e.Operator, right)
+	case *ast.InfixExpression:
+		if c, ok := foldSameOperands(e); ok {
+			return c, true
+		}
+		left, ok := Fold(e.Left)
+		if !ok {
+			return Constant{}, false
+		}
+		// and and or only evaluate their right operand when they need it
+		switch e.Operator {
+		case "and", "&&":
+			if !left.Truthy() {
+				return Constant{Kind: ConstantBool}, true
+			}
+		case "or", "||":
+			if left.Truthy() {
+				return Constant{Kind: ConstantBool, Bool: true}, true
+			}
+		}
+		right, ok := Fold(e.Right)
+		if !ok {
+			return Constant{}, false
+		}
+		return foldInfix(e.Operator, left, right)
+	case *ast.ConditionalExpression:
+		condition, ok := Fold(e.Condition)
+		if !ok {
+			return Constant{}, false
+		}
+		if condition.Truthy() {
+			return Fold(e.ValueIfTrue)
+		}
+		return Fold(e.ValueIfFalse)
+	}
+	return Constant{}, false
+}
+
+// IsLiteral reports whether expr is made of literals and operators only, so
+// that its value is written out in the source rather than derived
+func IsLiteral(expr ast.Expression) bool {
+	literal := true
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.BooleanLiteral, *ast.NumberLiteral, *ast.StringLiteral, *ast.NullLiteral,
+			*ast.PrefixExpression, *ast.InfixExpression, *ast.ConditionalExpression:
+		default:
+			literal = false
+		}
+		return literal
+	})
+	return literal
+}
+
+// SameValue reports whether a and b are the same expression, and so have the
+// same value, when evaluating them has no side effects. Calls, await and
+// get_node shorthands never have the same value.
+func SameValue(a, b ast.Expression) bool {
+	switch x := a.(type) {
+	case *ast.Identifier:
+		y, ok := b.(*ast.Identifier)
+		return ok && x.Value == y.Value
+	case *ast.SelfExpression:
+		_, ok := b.(*ast.SelfExpression)
+		return ok
+	case *ast.NullLiteral:
+		_, ok := b.(*ast.NullLiteral)
+		return ok
+	case *ast.BooleanLiteral:
+		y, ok := b.(*ast.BooleanLiteral)
+		return ok && x.Value == y.Value
+	case *ast.NumberLiteral:
+		y, ok := b.(*ast.NumberLiteral)
+		return ok && x.IsInt == y.IsInt && x.Value == y.Value
+	case *ast.StringLiteral:
+		y, ok := b.(*ast.StringLiteral)
+		return ok && x.Value == y.Value
+	case *ast.DotExpression:
+		y, ok := b.(*ast.DotExpression)
+		return ok && x.Property == y.Property && SameValue(x.Left, y.Left)
+	case *ast.IndexExpression:
+		y, ok := b.(*ast.IndexExpression)
+		return ok && SameValue(x.Left, y.Left) && SameValue(x.Index, y.Index)
+	case *ast.PrefixExpression:
+		y, ok := b.(*ast.PrefixExpression)
+		return ok && x.Operator == y.Operator && SameValue(x.Right, y.Right)
+	case *ast.InfixExpression:
+		y, ok := b.(*ast.InfixExpression)
+		return ok && x.Operator == y.Operator && SameValue(x.Left, y.Left) && SameValue(x.Right, y.Right)
+	}
+	return false
+}
+
+// foldSameOperands folds the operators whose result does not depend on the
+// value of their operands when both are the same
+func foldSameOperands(e *ast.InfixExpression) (Constant, bool) {
+	switch e.Operator {
+	case "-", "==", "!=", "<", ">", "<=", ">=":
+	default:
+		return Constant{}, false
+	}
+	if !SameValue(e.Left, e.Right) {
+		return Constant{}, false
+	}
+	switch e.Operator {
+	case "-":
+		return Constant{Kind: ConstantInt}, true
+	case "==", "<=", ">=":
+		return Constant{Kind: ConstantBool, Bool: true}, true
+	default:
+		return Constant{Kind: ConstantBool}, true
+	}
+}
+
+func foldPrefix(operator string, c Constant) (Constant, bool) {
+	switch operator {
+	case "not", "!":
+		return Constant{Kind: ConstantBool, Bool: !c.Truthy()}, true
+	case "-":
+		switch c.Kind {
+		case ConstantInt:
+			return Constant{Kind: ConstantInt, Int: -c.Int}, true
+		case ConstantFloat:
+			return Constant{Kind: ConstantFloat, Float: -c.Float}, true
+		}
+	case "+":
+		if c.IsNumber() {
+			return c, true
+		}
+	case "~":
+		if c.Kind == ConstantInt {
+			return Constant{Kind: ConstantInt, Int: ^c.Int}, true
+		}
+	}
+	return Constant{}, false
+}
+
+func foldInfix(operator string, left, right Constant) (Constant, bool) {
+	switch operator {
+	case "and", "&&", "or", "||":
+		// The left operand did not decide the result
+		return Constant{Kind: ConstantBool, Bool: right.Truthy()}, true
+	case "==", "!=", "<", ">", "<=", ">=":
+		return foldComparison(operator, left, right)
+	}
+
+	if left.Kind == ConstantString && right.Kind == ConstantString && operator == "+" {
+		return Constant{Kind: ConstantString, String: left.String + right.String}, true
+	}
+	if left.Kind == ConstantInt && right.Kind == ConstantInt {
+		return foldInt(operator, left.Int, right.Int)
+	}
+	if !left.IsNumber() || !right.IsNumber() {
+		return Constant{}, false
+	}
+	// Mixing ints and floats gives a float
+	x, y := left.Number(), right.Number()
+	var result float64
+	switch operator {
+	case "+":
+		result = x + y
+	case "-":
+		result = x - y
+	case "*":
+		result = x * y
+	case "/":
+		result = x / y
+	case "**":
+		result = math.Pow(x, y)
+	default:
+		return Constant{}, false
+	}
+	return Constant{Kind: ConstantFloat, Float: result}, true
+}
+
+func foldInt(operator string, x, y int64) (Constant, bool) {
+	var result int64
+	switch operator {
+	case "+":
+		result = x + y
+	case "-":
+		result = x - y
+	case "*":
+		result = x * y
+	case "/", "%":
+		if y == 0 {
+			return Constant{}, false
+		}
+		if operator == "/" {
+			result = x / y
+		} else {
+			result = x % y
+		}
+	case "**":
+		if y < 0 {
+			return Constant{Kind: ConstantFloat, Float: math.Pow(float64(x), float64(y))}, true
+		}
+		// Overflows wrap around, as in GDScript
+		result = 1
+		for ; y > 0; y >>= 1 {
+			if y&1 == 1 {
+				result *= x
+			}
+			x *= x
+		}
+	case "&":
+		result = x & y
+	case "|":
+		result = x | y
+	case "^":
+		result = x ^ y
+	case "<<", ">>":
+		if y < 0 || y > 63 {
+			return Constant{}, false
+		}
+		if operator == "<<" {
+			result = x << y
+		} else {
+			result = x >> y
+		}
+	default:
+		return Constant{}, false
+	}
+	return Constant{Kind: ConstantInt, Int: result}, true
+}
+
+func foldComparison(operator string, left, right Constant) (Constant, bool) {
+	var cmp int
+	switch {
+	case left.Kind == ConstantInt && right.Kind == ConstantInt:
+		switch {
+		case left.Int < right.Int:
+			cmp = -1
+		case left.Int > right.Int:
+			cmp = 1
+		}
+	case left.IsNumber() && right.IsNumber():
+		switch x, y := left.Number(), right.Number(); {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	case left.Kind == ConstantString && right.Kind == ConstantString:
+		switch {
+		case left.String < right.String:
+			cmp = -1
+		case left.String > right.String:
+			cmp = 1
+		}
+	case left.Kind == ConstantBool && right.Kind == ConstantBool:
+		if operator != "==" && operator != "!=" {
+			return Constant{}, false
+		}
+		if left.Bool != right.Bool {
+			cmp = 1
+		}
+	default:
+		// Comparing values of different types is an error, except for
+		// equality, which is then false
+		if operator != "==" && operator != "!=" {
+			return Constant{}, false
+		}
+		cmp = 1
+	}
+
+	var result bool
+	switch operator {
+	case "==":
+		result = cmp == 0
+	case "!=":
+		result = cmp != 0
+	case "<":
+		result = cmp < 0
+	case ">":
+		result = cmp > 0
+	case "<=":
+		result = cmp <= 0
+	case ">=":
+		result = cmp >= 0
+	}
+	return Constant{Kind: ConstantBool, Bool: result}, true
+}
@@ -0,0 +1,83 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string // the folded value, or "" when it is not known
+	}{
+		{"1 + 2 * 3", "7"},
+		{"7 / 2", "3"},
+		{"-7 % 3", "-1"},
+		{"7 / 2.0", "3.5"},
+		{"2 ** 10", "1024"},
+		{"2 ** -1", "0.5"},
+		{"1 << 4 | 1", "17"},
+		{"~0", "-1"},
+		{"1.5 + 1.5", "3.0"},
+		{`"a" + "b"`, `"ab"`},
+		{"1 + 1 == 2", "true"},
+		{"1 == 1.0", "true"},
+		{`"a" < "b"`, "true"},
+		{`1 == "1"`, "false"},
+		{"not 0", "true"},
+		{"false and x", "false"},
+		{"true or x", "true"},
+		{"true and 1", "true"},
+		{"x and true", ""},
+		{"1 if false else 2", "2"},
+		{"x - x", "0"},
+		{"x.y[0] >= x.y[0]", "true"},
+		{"x - x == 0", "true"},
+		{"x != x", "false"},
+		{"f() - f()", ""},
+		{"x - y", ""},
+		{"1 / 0", ""},
+		{"1 % 0", ""},
+		{"1.0 / 0", "INF"},
+		{`1 < "a"`, ""},
+		{"[1] == [1]", ""},
+	}
+	for _, tt := range tests {
+		tree, errors := parser.ParseFile("test.gd", "var v = "+tt.expr+"\n")
+		if len(errors) > 0 {
+			t.Fatalf("%s: parse errors: %v", tt.expr, errors)
+		}
+		expr := tree.RootClass.Statements[0].(*ast.VarStatement).Value
+		c, ok := Fold(expr)
+		got := ""
+		if ok {
+			got = c.Source()
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.expr, tt.expected, got)
+		}
+	}
+}
+
+func TestIsLiteral(t *testing.T) {
+	tests := map[string]bool{
+		"1 + 1 == 2":    true,
+		`-1.5 * "a"`:    true,
+		"x - x == 0":    false,
+		"len([]) == 0":  false,
+		"self == null":  false,
+		"1 if x else 2": false,
+	}
+	for expr, expected := range tests {
+		tree, errors := parser.ParseFile("test.gd", "var v = "+expr+"\n")
+		if len(errors) > 0 {
+			t.Fatalf("%s: parse errors: %v", expr, errors)
+		}
+		value := tree.RootClass.Statements[0].(*ast.VarStatement).Value
+		if got := IsLiteral(value); got != expected {
+			t.Errorf("%s: expected %v, got %v", expr, expected, got)
+		}
+	}
+}
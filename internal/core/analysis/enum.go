@@ -0,0 +1,30 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// EnumValue is the value of an element of an enum
+type EnumValue struct {
+	Element *ast.EnumElement
+	Value   int64
+	// Known is false when the value of the element does not fold to an
+	// int, or the element has no value of its own and follows one whose
+	// value is not known
+	Known bool
+}
+
+// EnumValues returns the values of the elements of enum, in order: the
+// value written for an element, or the value of the element before it plus
+// one, starting from 0
+func EnumValues(enum *ast.EnumStatement) []EnumValue {
+	values := make([]EnumValue, 0, len(enum.Elements))
+	next, known := int64(0), true
+	for _, element := range enum.Elements {
+		if element.Value != nil {
+			value, ok := Fold(element.Value)
+			next, known = value.Int, ok && value.Kind == ConstantInt
+		}
+		values = append(values, EnumValue{Element: element, Value: next, Known: known})
+		next++
+	}
+	return values
+}
@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestEnumValues(t *testing.T) {
+	tests := []struct {
+		enum     string
+		expected string // name=value for each element, name=? when it is not known
+	}{
+		{"enum E {A, B, C}", "A=0 B=1 C=2"},
+		{"enum E {A = 5, B, C = 1 << 4, D}", "A=5 B=6 C=16 D=17"},
+		{"enum E {A = -1, B}", "A=-1 B=0"},
+		{"enum E {A = X, B, C = 2}", "A=? B=? C=2"},
+		{"enum E {A = 1.5}", "A=?"},
+	}
+	for _, tt := range tests {
+		tree, errors := parser.ParseFile("test.gd", tt.enum+"\n")
+		if len(errors) > 0 {
+			t.Fatalf("%s: parse errors: %v", tt.enum, errors)
+		}
+		var got []string
+		for _, value := range EnumValues(tree.RootClass.Statements[0].(*ast.EnumStatement)) {
+			if value.Known {
+				got = append(got, fmt.Sprintf("%s=%d", value.Element.Name, value.Value))
+			} else {
+				got = append(got, value.Element.Name+"=?")
+			}
+		}
+		if strings.Join(got, " ") != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.enum, tt.expected, strings.Join(got, " "))
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// Flow is the control flow of a function body at block level: for each
+// statement, whether control can fall through to the statement after it.
+//
+// A statement terminates when it is a return, break or continue, or when
+// every path through it ends in one: an if with an else whose branches all
+// terminate, or a match whose branches all terminate and one of which
+// catches every value. Loops never terminate, since their body may not run
+// and a break inside them only leaves the loop.
+type Flow struct {
+	terminates map[ast.Statement]bool
+	returns    map[ast.Statement]bool
+}
+
+// AnalyzeFlow computes the control flow of function
+func AnalyzeFlow(function *ast.Function) *Flow {
+	f := &Flow{
+		terminates: make(map[ast.Statement]bool),
+		returns:    make(map[ast.Statement]bool),
+	}
+	f.block(function.Statements)
+	return f
+}
+
+// Terminates reports whether control never reaches the statement after stmt
+func (f *Flow) Terminates(stmt ast.Statement) bool {
+	return f.terminates[stmt]
+}
+
+// Returns reports whether every path through stmt ends in a return
+func (f *Flow) Returns(stmt ast.Statement) bool {
+	return f.returns[stmt]
+}
+
+// BlockTerminates reports whether control never reaches the end of block
+func (f *Flow) BlockTerminates(block []ast.Statement) bool {
+	for _, stmt := range block {
+		if f.terminates[stmt] {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockReturns reports whether every path through block ends in a return
+func (f *Flow) BlockReturns(block []ast.Statement) bool {
+	for _, stmt := range block {
+		if f.returns[stmt] {
+			return true
+		}
+		if f.terminates[stmt] {
+			return false
+		}
+	}
+	return false
+}
+
+// block analyzes the statements of a block, nested blocks first
+func (f *Flow) block(statements []ast.Statement) {
+	for _, stmt := range statements {
+		f.statement(stmt)
+	}
+}
+
+func (f *Flow) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement:
+		f.terminates[s] = true
+		f.returns[s] = true
+
+	case *ast.BreakStatement, *ast.ContinueStatement:
+		f.terminates[s] = true
+
+	case *ast.IfStatement:
+		branches := append([][]ast.Statement{s.Consequence}, s.ElseBranches...)
+		branches = append(branches, s.Alternative)
+		for _, branch := range branches {
+			f.block(branch)
+		}
+		if len(s.Alternative) > 0 {
+			f.terminates[s], f.returns[s] = f.allBranches(branches)
+		}
+
+	case *ast.MatchStatement:
+		var branches [][]ast.Statement
+		exhaustive := false
+		for _, branch := range s.Branches {
+			f.block(branch.Body)
+			branches = append(branches, branch.Body)
+			if CatchesAll(branch) {
+				exhaustive = true
+			}
+		}
+		if exhaustive {
+			f.terminates[s], f.returns[s] = f.allBranches(branches)
+		}
+
+	case *ast.ForStatement:
+		f.block(s.Body)
+	case *ast.WhileStatement:
+		f.block(s.Body)
+	}
+}
+
+// allBranches reports whether every branch terminates and whether every branch returns
+func (f *Flow) allBranches(branches [][]ast.Statement) (terminates, returns bool) {
+	terminates, returns = true, true
+	for _, branch := range branches {
+		terminates = terminates && f.BlockTerminates(branch)
+		returns = returns && f.BlockReturns(branch)
+	}
+	return terminates, returns
+}
+
+// CatchesAll reports whether a match branch matches every value: it has no
+// guard, and a wildcard or a binding among its patterns
+func CatchesAll(branch *ast.MatchBranch) bool {
+	if branch.IsGuarded {
+		return false
+	}
+	for _, pattern := range branch.Patterns {
+		switch pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+			return true
+		}
+	}
+	return false
+}
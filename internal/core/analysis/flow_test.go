@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestAnalyzeFlow(t *testing.T) {
+	input := `func f(x):
+	for i in x:
+		break
+	if x > 0:
+		return 1
+	else:
+		return 0
+	match x:
+		1:
+			return 1
+		_:
+			return 2
+	match x:
+		1:
+			return 1
+	if x:
+		return 1
+	else:
+		pass
+	while true:
+		continue
+	if x:
+		return 1
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	function := tree.RootClass.Functions[0]
+	flow := AnalyzeFlow(function)
+
+	expected := []bool{
+		false, // for
+		true,  // if/else both returning
+		true,  // match with a wildcard branch
+		false, // match without a wildcard branch
+		false, // else branch falls through
+		false, // while
+		false, // if without else
+	}
+	if len(function.Statements) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d", len(expected), len(function.Statements))
+	}
+	for i, stmt := range function.Statements {
+		if flow.Terminates(stmt) != expected[i] {
+			t.Errorf("Statement %d (line %d): expected Terminates %v", i, stmt.Position().Line, expected[i])
+		}
+		if flow.Returns(stmt) != expected[i] {
+			t.Errorf("Statement %d (line %d): expected Returns %v", i, stmt.Position().Line, expected[i])
+		}
+	}
+	if !flow.BlockReturns(function.Statements[:2]) {
+		t.Error("Expected a block ending with if/else returns to always return")
+	}
+
+	loop := function.Statements[0].(*ast.ForStatement)
+	if !flow.BlockTerminates(loop.Body) || flow.BlockReturns(loop.Body) {
+		t.Error("Expected the loop body to terminate with a break, without returning")
+	}
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// LoadCall is a call to load() or preload() with a constant path; Path has no quotes
+type LoadCall struct {
+	Call    *ast.CallExpression
+	Path    string
+	Preload bool
+	// Decl is the variable or constant the call initializes, or nil when the
+	// call is used in any other way
+	Decl *ast.VarStatement
+	// Class is the innermost class containing the call
+	Class *ast.Class
+	// Function is the function containing the call, or nil for a class member
+	Function *ast.Function
+}
+
+// FindLoadCalls returns the load and preload calls of tree in source order
+func FindLoadCalls(tree *ast.AbstractSyntaxTree) []LoadCall {
+	var calls []LoadCall
+	decls := make(map[*ast.CallExpression]*ast.VarStatement)
+
+	ast.WalkWithAncestors(tree, func(node ast.Node, ancestors ast.NodeStack) bool {
+		switch n := node.(type) {
+		case *ast.VarStatement:
+			if call, ok := n.Value.(*ast.CallExpression); ok {
+				decls[call] = n
+			}
+		case *ast.CallExpression:
+			function, ok := n.Function.(*ast.Identifier)
+			if !ok || (function.Value != "load" && function.Value != "preload") || len(n.Arguments) == 0 {
+				return true
+			}
+			if path, ok := n.Arguments[0].(*ast.StringLiteral); ok {
+				calls = append(calls, LoadCall{
+					Call:     n,
+					Path:     path.Value,
+					Preload:  function.Value == "preload",
+					Decl:     decls[n],
+					Class:    ancestors.EnclosingClass(),
+					Function: ancestors.EnclosingFunction(),
+				})
+			}
+		}
+		return true
+	})
+	return calls
+}
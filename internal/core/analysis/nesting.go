@@ -0,0 +1,51 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// WalkNesting calls visit for each if, for, while and match statement within
+// statements, the body of a function, with the depth of the blocks it opens:
+// 1 for the statements of the body, 2 for those nested in their blocks, and
+// so on. The elif and else branches of an if, and the branches of a match,
+// are at the depth of the if or match. Lambdas are functions of their own
+// and are not entered.
+func WalkNesting(statements []ast.Statement, visit func(stmt ast.Statement, depth int)) {
+	walkNesting(statements, 1, visit)
+}
+
+func walkNesting(statements []ast.Statement, depth int, visit func(ast.Statement, int)) {
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *ast.IfStatement:
+			visit(s, depth)
+			walkNesting(s.Consequence, depth+1, visit)
+			for _, branch := range s.ElseBranches {
+				walkNesting(branch, depth+1, visit)
+			}
+			walkNesting(s.Alternative, depth+1, visit)
+		case *ast.ForStatement:
+			visit(s, depth)
+			walkNesting(s.Body, depth+1, visit)
+		case *ast.WhileStatement:
+			visit(s, depth)
+			walkNesting(s.Body, depth+1, visit)
+		case *ast.MatchStatement:
+			visit(s, depth)
+			for _, branch := range s.Branches {
+				walkNesting(branch.Body, depth+1, visit)
+			}
+		}
+	}
+}
+
+// NestingDepth returns the deepest nesting of blocks within statements, as
+// WalkNesting counts it, and the first statement opening a block that deep;
+// it is 0 and nil when statements open no block
+func NestingDepth(statements []ast.Statement) (int, ast.Statement) {
+	deepest, opener := 0, ast.Statement(nil)
+	WalkNesting(statements, func(stmt ast.Statement, depth int) {
+		if depth > deepest {
+			deepest, opener = depth, stmt
+		}
+	})
+	return deepest, opener
+}
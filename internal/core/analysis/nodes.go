@@ -0,0 +1,50 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// NodePathUse is a node path a script looks up on the node it is attached
+// to: a $Path or %Name shorthand, or a get_node() call with a constant path
+type NodePathUse struct {
+	// Expr is the shorthand or the call
+	Expr ast.Expression
+	// Path is the path looked up, e.g. ../HUD or %Name/Label
+	Path string
+	// Class is the innermost class containing the lookup
+	Class *ast.Class
+}
+
+// FindNodePaths returns the node paths tree looks up, in source order.
+// Calls of get_node on other objects than self are left out, as they look
+// up paths from other nodes.
+func FindNodePaths(tree *ast.AbstractSyntaxTree) []NodePathUse {
+	var uses []NodePathUse
+
+	ast.WalkWithAncestors(tree, func(node ast.Node, ancestors ast.NodeStack) bool {
+		switch n := node.(type) {
+		case *ast.GetNodeExpression:
+			uses = append(uses, NodePathUse{Expr: n, Path: n.Path, Class: ancestors.EnclosingClass()})
+		case *ast.CallExpression:
+			if !isSelfCall(n, "get_node") || len(n.Arguments) != 1 {
+				return true
+			}
+			if path, ok := n.Arguments[0].(*ast.StringLiteral); ok {
+				uses = append(uses, NodePathUse{Expr: n, Path: path.Value, Class: ancestors.EnclosingClass()})
+			}
+		}
+		return true
+	})
+	return uses
+}
+
+// isSelfCall reports whether call calls the method name of self, as name()
+// or self.name()
+func isSelfCall(call *ast.CallExpression, name string) bool {
+	switch function := call.Function.(type) {
+	case *ast.Identifier:
+		return function.Value == name
+	case *ast.DotExpression:
+		_, self := function.Left.(*ast.SelfExpression)
+		return self && function.Property == name
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package analysis
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// Pass is an analysis computed over a whole file
+type Pass int
+
+const (
+	// PassScopes collects the members of every class and resolves the names they use
+	PassScopes Pass = iota
+	// PassTypes infers the static types of expressions and declarations
+	PassTypes
+	// PassControlFlow computes the control flow of every function
+	PassControlFlow
+	// PassLoadCalls collects the load and preload calls
+	PassLoadCalls
+)
+
+// String returns the name of the pass
+func (p Pass) String() string {
+	switch p {
+	case PassScopes:
+		return "scopes"
+	case PassTypes:
+		return "types"
+	case PassControlFlow:
+		return "control-flow"
+	case PassLoadCalls:
+		return "load-calls"
+	default:
+		return "unknown"
+	}
+}
+
+// passDependencies lists the passes whose results each pass reuses
+var passDependencies = map[Pass][]Pass{
+	PassTypes: {PassScopes},
+}
+
+// Results caches the analyses of one file, so that rules sharing an
+// analysis compute it once. Each pass runs on first use, after the passes it
+// depends on. Results are not safe for concurrent use.
+type Results struct {
+	Tree *ast.AbstractSyntaxTree
+
+	done       map[Pass]bool
+	members    map[*ast.Class]map[string]ast.Node
+	references map[*ast.Class][]Reference
+	types      *Types
+	flows      map[*ast.Function]*Flow
+	loads      []LoadCall
+}
+
+// NewResults creates an empty result cache for tree
+func NewResults(tree *ast.AbstractSyntaxTree) *Results {
+	return &Results{
+		Tree: tree,
+		done: make(map[Pass]bool),
+	}
+}
+
+// Require runs the given passes and the passes they depend on, unless they already ran
+func (r *Results) Require(passes ...Pass) {
+	for _, pass := range passes {
+		if r.done[pass] {
+			continue
+		}
+		r.Require(passDependencies[pass]...)
+		r.run(pass)
+		r.done[pass] = true
+	}
+}
+
+func (r *Results) run(pass Pass) {
+	switch pass {
+	case PassScopes:
+		r.members = make(map[*ast.Class]map[string]ast.Node)
+		r.references = make(map[*ast.Class][]Reference)
+		ast.Inspect(r.Tree, func(node ast.Node) bool {
+			if class, ok := node.(*ast.Class); ok {
+				r.members[class] = ClassMembers(class)
+				r.references[class] = resolveClassReferences(class, r.members[class])
+			}
+			return true
+		})
+
+	case PassTypes:
+		r.types = inferTypes(r.Tree, r.Members)
+
+	case PassControlFlow:
+		r.flows = make(map[*ast.Function]*Flow)
+		ast.Inspect(r.Tree, func(node ast.Node) bool {
+			if function, ok := node.(*ast.Function); ok {
+				r.flows[function] = AnalyzeFlow(function)
+			}
+			return true
+		})
+
+	case PassLoadCalls:
+		r.loads = FindLoadCalls(r.Tree)
+	}
+}
+
+// Members returns the members declared directly in class, by name
+func (r *Results) Members(class *ast.Class) map[string]ast.Node {
+	r.Require(PassScopes)
+	if members, ok := r.members[class]; ok {
+		return members
+	}
+	return ClassMembers(class)
+}
+
+// References returns the names used by class, as ResolveClassReferences does
+func (r *Results) References(class *ast.Class) []Reference {
+	r.Require(PassScopes)
+	if references, ok := r.references[class]; ok {
+		return references
+	}
+	return ResolveClassReferences(class)
+}
+
+// Types returns the types inferred for the file
+func (r *Results) Types() *Types {
+	r.Require(PassTypes)
+	return r.types
+}
+
+// Flow returns the control flow of function
+func (r *Results) Flow(function *ast.Function) *Flow {
+	r.Require(PassControlFlow)
+	if flow, ok := r.flows[function]; ok {
+		return flow
+	}
+	return AnalyzeFlow(function)
+}
+
+// LoadCalls returns the load and preload calls of the file in source order
+func (r *Results) LoadCalls() []LoadCall {
+	r.Require(PassLoadCalls)
+	return r.loads
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestResults(t *testing.T) {
+	input := `extends Node
+const Scene = preload("res://scene.tscn")
+var health := 10
+func heal(amount):
+	var texture = load("res://icon.png")
+	print(load("res://icon.png"))
+	health += amount
+	return texture
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	results := NewResults(tree)
+	class := tree.RootClass
+
+	// Types reuse the members collected by the scopes pass
+	results.Require(PassTypes)
+	if !results.done[PassScopes] || !results.done[PassTypes] {
+		t.Fatalf("Expected the types pass and its scopes dependency to run, got %v", results.done)
+	}
+	if results.Types() != results.Types() {
+		t.Error("Expected types to be computed once")
+	}
+	if got := results.Types().TypeOf(class.Statements[1]); got != "int" {
+		t.Errorf("Expected health to be an int, got %q", got)
+	}
+
+	references := results.References(class)
+	if len(references) == 0 || &references[0] != &results.References(class)[0] {
+		t.Error("Expected references to be resolved once")
+	}
+	if len(references) != len(ResolveClassReferences(class)) {
+		t.Errorf("Expected the cached references to match ResolveClassReferences")
+	}
+
+	loads := results.LoadCalls()
+	if len(loads) != 3 {
+		t.Fatalf("Expected 3 load calls, got %d", len(loads))
+	}
+	if !loads[0].Preload || loads[0].Path != "res://scene.tscn" || loads[0].Decl == nil {
+		t.Errorf("Expected a preload of the scene kept in a constant, got %+v", loads[0])
+	}
+	if loads[1].Preload || loads[1].Decl == nil || loads[1].Decl.Name != "texture" {
+		t.Errorf("Expected a load kept in texture, got %+v", loads[1])
+	}
+	if loads[2].Decl != nil {
+		t.Errorf("Expected a load passed as an argument, got %+v", loads[2])
+	}
+
+	if flow := results.Flow(class.Functions[0]); flow != results.Flow(class.Functions[0]) {
+		t.Error("Expected control flow to be computed once")
+	}
+}
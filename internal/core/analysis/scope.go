@@ -0,0 +1,283 @@
+// Package analysis provides semantic analysis of GDScript ASTs shared by linter rules
+package analysis
+
+import (
+	"sort"
+	"unicode"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// SymbolKind classifies what a referenced name resolves to
+type SymbolKind int
+
+const (
+	// SymbolUnresolved means the name is not declared anywhere visible
+	SymbolUnresolved SymbolKind = iota
+	// SymbolLocal is a function variable, loop variable or match binding
+	SymbolLocal
+	// SymbolParameter is an argument of the enclosing function
+	SymbolParameter
+	// SymbolMember is a variable, constant, function, signal, enum or inner class of the enclosing class
+	SymbolMember
+	// SymbolGlobal is a builtin function, global constant or type name
+	SymbolGlobal
+)
+
+// String returns a string representation of the symbol kind
+func (k SymbolKind) String() string {
+	switch k {
+	case SymbolLocal:
+		return "local"
+	case SymbolParameter:
+		return "parameter"
+	case SymbolMember:
+		return "member"
+	case SymbolGlobal:
+		return "global"
+	default:
+		return "unresolved"
+	}
+}
+
+// Reference is a use of a name inside a class or function
+type Reference struct {
+	Name     string
+	Pos      ast.Position
+	Kind     SymbolKind
+	Explicit bool     // accessed as self.name rather than as a bare name
+	Decl     ast.Node // declaration the name resolves to; nil for globals and unresolved names
+}
+
+// Declaration is a member declared directly in a class
+type Declaration struct {
+	Name string
+	// Node is the VarStatement, Function, SignalStatement, EnumStatement or
+	// Class declaring the member, or the EnumElement of an unnamed enum
+	Node ast.Node
+}
+
+// ClassDeclarations returns the members declared directly in a class, in
+// source order. A name declared twice appears twice.
+func ClassDeclarations(class *ast.Class) []Declaration {
+	if class == nil {
+		return nil
+	}
+
+	var declarations []Declaration
+	for _, stmt := range class.Statements {
+		switch s := stmt.(type) {
+		case *ast.VarStatement:
+			declarations = append(declarations, Declaration{s.Name, s})
+		case *ast.SignalStatement:
+			declarations = append(declarations, Declaration{s.Name, s})
+		case *ast.EnumStatement:
+			if s.Name != "" {
+				declarations = append(declarations, Declaration{s.Name, s})
+				continue
+			}
+			// The elements of an unnamed enum are constants of the class
+			for _, element := range s.Elements {
+				declarations = append(declarations, Declaration{element.Name, element})
+			}
+		}
+	}
+	for _, function := range class.Functions {
+		declarations = append(declarations, Declaration{function.Name, function})
+	}
+	for _, subClass := range class.SubClasses {
+		declarations = append(declarations, Declaration{subClass.Name, subClass})
+	}
+
+	sort.SliceStable(declarations, func(i, j int) bool {
+		a, b := declarations[i].Node.Position(), declarations[j].Node.Position()
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return declarations
+}
+
+// ClassMembers returns the members declared directly in a class, by name.
+// A name declared twice maps to its first declaration.
+func ClassMembers(class *ast.Class) map[string]ast.Node {
+	members := make(map[string]ast.Node)
+	for _, decl := range ClassDeclarations(class) {
+		if _, ok := members[decl.Name]; !ok {
+			members[decl.Name] = decl.Node
+		}
+	}
+	return members
+}
+
+// ResolveReferences resolves every name used in the body of a function that
+// belongs to class. Explicit member access (self.foo) and bare names (foo)
+// go through the same member lookup, so both resolve to the same declaration;
+// a bare name is only looked up among the members when no local variable or
+// parameter shadows it.
+//
+// Locals stay visible from their declaration to the end of the function;
+// GDScript's block scoping is not modelled.
+func ResolveReferences(function *ast.Function, class *ast.Class) []Reference {
+	return resolveReferences(function, ClassMembers(class))
+}
+
+func resolveReferences(function *ast.Function, members map[string]ast.Node) []Reference {
+	r := &resolver{
+		members:    members,
+		parameters: make(map[string]ast.Node),
+		locals:     make(map[string]ast.Node),
+	}
+	for _, param := range function.Parameters {
+		r.parameters[param.Name] = param
+		if param.Default != nil {
+			ast.Inspect(param.Default, r.inspect)
+		}
+	}
+	for _, stmt := range function.Statements {
+		ast.Inspect(stmt, r.inspect)
+	}
+	return r.references
+}
+
+// ResolveClassReferences resolves the names used by a class: in the
+// initializers of its member declarations and in the bodies of its functions.
+// Inner classes are not included.
+func ResolveClassReferences(class *ast.Class) []Reference {
+	return resolveClassReferences(class, ClassMembers(class))
+}
+
+func resolveClassReferences(class *ast.Class, members map[string]ast.Node) []Reference {
+	r := &resolver{
+		members:    members,
+		parameters: make(map[string]ast.Node),
+		locals:     make(map[string]ast.Node),
+	}
+	for _, stmt := range class.Statements {
+		if v, ok := stmt.(*ast.VarStatement); ok && v.Value != nil {
+			ast.Inspect(v.Value, r.inspect)
+		}
+	}
+
+	references := r.references
+	for _, function := range class.Functions {
+		references = append(references, resolveReferences(function, members)...)
+	}
+	return references
+}
+
+type resolver struct {
+	members    map[string]ast.Node
+	parameters map[string]ast.Node
+	locals     map[string]ast.Node
+	references []Reference
+}
+
+func (r *resolver) inspect(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.VarStatement:
+		// The initializer is evaluated before the name comes into scope
+		if n.Value != nil {
+			ast.Inspect(n.Value, r.inspect)
+		}
+		r.locals[n.Name] = n
+		return false
+
+	case *ast.ForStatement:
+		ast.Inspect(n.Collection, r.inspect)
+		r.locals[n.Iterator] = n
+		for _, stmt := range n.Body {
+			ast.Inspect(stmt, r.inspect)
+		}
+		return false
+
+	case *ast.BindingPattern:
+		r.locals[n.Name] = n
+
+	case *ast.DotExpression:
+		if _, ok := n.Left.(*ast.SelfExpression); ok {
+			r.resolveMember(n.Property, n.Position())
+			return false
+		}
+		// The property name belongs to the left operand's type, not to this scope
+		ast.Inspect(n.Left, r.inspect)
+		return false
+
+	case *ast.DictionaryLiteral:
+		// The keys of {name = value} are strings spelled as names
+		if n.LuaStyle {
+			for _, value := range n.Values {
+				ast.Inspect(value, r.inspect)
+			}
+			return false
+		}
+
+	case *ast.Identifier:
+		r.resolveName(n.Value, n.Position())
+	}
+	return true
+}
+
+func (r *resolver) resolveMember(name string, pos ast.Position) {
+	ref := Reference{Name: name, Pos: pos, Explicit: true}
+	if decl, ok := r.members[name]; ok {
+		ref.Kind = SymbolMember
+		ref.Decl = decl
+	}
+	r.references = append(r.references, ref)
+}
+
+func (r *resolver) resolveName(name string, pos ast.Position) {
+	ref := Reference{Name: name, Pos: pos}
+	if decl, ok := r.locals[name]; ok {
+		ref.Kind, ref.Decl = SymbolLocal, decl
+	} else if decl, ok := r.parameters[name]; ok {
+		ref.Kind, ref.Decl = SymbolParameter, decl
+	} else if decl, ok := r.members[name]; ok {
+		ref.Kind, ref.Decl = SymbolMember, decl
+	} else if IsGlobalName(name) {
+		ref.Kind = SymbolGlobal
+	}
+	r.references = append(r.references, ref)
+}
+
+// IsGlobalName reports whether name is available in every script: builtin
+// functions and constants, plus capitalized names, which denote types,
+// singletons and global classes
+func IsGlobalName(name string) bool {
+	if name == "" {
+		return false
+	}
+	if unicode.IsUpper([]rune(name)[0]) {
+		return true
+	}
+	return globalNames[name]
+}
+
+// globalNames lists the builtin functions and constants of GDScript and @GlobalScope
+var globalNames = map[string]bool{
+	// GDScript builtins
+	"assert": true, "char": true, "convert": true, "dict_to_inst": true, "get_stack": true,
+	"inst_to_dict": true, "is_instance_of": true, "len": true, "load": true, "preload": true,
+	"print_debug": true, "print_stack": true, "range": true, "type_exists": true,
+	// @GlobalScope functions
+	"abs": true, "absf": true, "absi": true, "acos": true, "asin": true, "atan": true, "atan2": true,
+	"bytes_to_var": true, "ceil": true, "ceilf": true, "ceili": true, "clamp": true, "clampf": true,
+	"clampi": true, "cos": true, "cosh": true, "db_to_linear": true, "deg_to_rad": true, "ease": true,
+	"error_string": true, "exp": true, "floor": true, "floorf": true, "floori": true, "fmod": true,
+	"fposmod": true, "hash": true, "instance_from_id": true, "inverse_lerp": true,
+	"is_equal_approx": true, "is_finite": true, "is_inf": true, "is_instance_id_valid": true,
+	"is_instance_valid": true, "is_nan": true, "is_same": true, "is_zero_approx": true, "lerp": true,
+	"lerp_angle": true, "lerpf": true, "linear_to_db": true, "log": true, "max": true, "maxf": true,
+	"maxi": true, "min": true, "minf": true, "mini": true, "move_toward": true, "nearest_po2": true,
+	"pingpong": true, "posmod": true, "pow": true, "print": true, "print_rich": true,
+	"print_verbose": true, "printerr": true, "printraw": true, "prints": true, "printt": true,
+	"push_error": true, "push_warning": true, "rad_to_deg": true, "rand_from_seed": true,
+	"randf": true, "randf_range": true, "randfn": true, "randi": true, "randi_range": true,
+	"randomize": true, "remap": true, "rotate_toward": true, "round": true, "roundf": true,
+	"roundi": true, "seed": true, "sign": true, "signf": true, "signi": true, "sin": true,
+	"sinh": true, "smoothstep": true, "snapped": true, "snappedf": true, "snappedi": true,
+	"sqrt": true, "step_decimals": true, "str": true, "str_to_var": true, "tan": true, "tanh": true,
+	"type_convert": true, "type_string": true, "typeof": true, "var_to_bytes": true,
+	"var_to_str": true, "weakref": true, "wrap": true, "wrapf": true, "wrapi": true,
+	// Builtin types usable as conversion functions
+	"bool": true, "int": true, "float": true,
+}
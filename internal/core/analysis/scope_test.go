@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestResolveReferencesMemberAccess(t *testing.T) {
+	input := `var health = 10
+func heal(amount):
+	health += amount
+	self.health += amount
+	var health = 0
+	print(health, self.health, missing, self.missing)
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	class := tree.RootClass
+	member := ClassMembers(class)["health"]
+	if member == nil {
+		t.Fatalf("Expected 'health' to be a class member")
+	}
+
+	expected := []struct {
+		name     string
+		kind     SymbolKind
+		explicit bool
+		member   bool // resolves to the class member declaration
+	}{
+		{"health", SymbolMember, false, true},
+		{"amount", SymbolParameter, false, false},
+		{"health", SymbolMember, true, true},
+		{"amount", SymbolParameter, false, false},
+		{"print", SymbolGlobal, false, false},
+		{"health", SymbolLocal, false, false}, // shadowed by the local variable
+		{"health", SymbolMember, true, true},  // self.health still reaches the member
+		{"missing", SymbolUnresolved, false, false},
+		{"missing", SymbolUnresolved, true, false},
+	}
+
+	refs := ResolveReferences(class.Functions[0], class)
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d references, got %d: %+v", len(expected), len(refs), refs)
+	}
+
+	for i, want := range expected {
+		got := refs[i]
+		if got.Name != want.name || got.Kind != want.kind || got.Explicit != want.explicit {
+			t.Errorf("reference %d: expected %s (%s, explicit=%v), got %s (%s, explicit=%v)",
+				i, want.name, want.kind, want.explicit, got.Name, got.Kind, got.Explicit)
+		}
+		if (got.Decl == ast.Node(member)) != want.member {
+			t.Errorf("reference %d: expected resolves-to-member=%v", i, want.member)
+		}
+	}
+}
+
+func TestClassDeclarations(t *testing.T) {
+	input := `signal hit
+enum State {IDLE}
+enum {LEFT, RIGHT}
+var speed = 1
+func hit():
+	pass
+class Inner:
+	pass
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	var names []string
+	for _, decl := range ClassDeclarations(tree.RootClass) {
+		names = append(names, decl.Name)
+	}
+	expected := "hit State LEFT RIGHT speed hit Inner"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("Expected declarations %q in source order, got %q", expected, got)
+	}
+
+	// The first declaration of a name is the member
+	if _, ok := ClassMembers(tree.RootClass)["hit"].(*ast.SignalStatement); !ok {
+		t.Errorf("Expected hit to be the signal, got %T", ClassMembers(tree.RootClass)["hit"])
+	}
+}
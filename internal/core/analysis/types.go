@@ -0,0 +1,381 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// Type is the name of a GDScript type, such as "int", "Node2D" or "Array[int]".
+// The zero value is Variant: nothing is known statically about the value.
+type Type string
+
+// Variant is the type of values whose type is not known statically
+const Variant Type = ""
+
+// Known reports whether anything is known about the type
+func (t Type) Known() bool {
+	return t != Variant
+}
+
+// ElementType returns the element type of a typed array, e.g. int for Array[int]
+func (t Type) ElementType() Type {
+	inner, found := strings.CutPrefix(string(t), "Array[")
+	if !found || !strings.HasSuffix(inner, "]") {
+		return Variant
+	}
+	return Type(strings.TrimSuffix(inner, "]"))
+}
+
+// Types is a type annotation layer over a tree: the static type of its
+// expressions and declarations, kept beside the AST rather than in it.
+//
+// Declarations are annotated with the type a reader of the name sees: the
+// declared type, or for `:=` variables and constants the type of the
+// initializer. Plain `var x = 1` is a Variant. Functions are annotated with
+// their return type.
+type Types struct {
+	types map[ast.Node]Type
+}
+
+// TypeOf returns the type of an expression or declaration, or Variant when
+// it is unknown
+func (t *Types) TypeOf(node ast.Node) Type {
+	return t.types[node]
+}
+
+// InferTypes runs type inference over every class of tree
+func InferTypes(tree *ast.AbstractSyntaxTree) *Types {
+	return inferTypes(tree, ClassMembers)
+}
+
+// inferTypes runs type inference, looking up the members of each class with members
+func inferTypes(tree *ast.AbstractSyntaxTree, members func(*ast.Class) map[string]ast.Node) *Types {
+	t := &Types{types: make(map[ast.Node]Type)}
+	ast.Inspect(tree, func(node ast.Node) bool {
+		if class, ok := node.(*ast.Class); ok {
+			t.inferClass(class, members(class))
+		}
+		return true
+	})
+	return t
+}
+
+func (t *Types) inferClass(class *ast.Class, members map[string]ast.Node) {
+	scope := &typeScope{types: t, members: members, locals: make(map[string]ast.Node)}
+
+	// Functions are typed up front so that calls can use their return type
+	// regardless of declaration order
+	for _, function := range class.Functions {
+		t.set(function, Type(function.ReturnType))
+	}
+	for _, stmt := range class.Statements {
+		if v, ok := stmt.(*ast.VarStatement); ok {
+			scope.declare(v)
+		}
+	}
+
+	for _, function := range class.Functions {
+		local := &typeScope{types: t, members: members, locals: make(map[string]ast.Node)}
+		for _, param := range function.Parameters {
+			t.set(param, Type(param.TypeHint))
+			if param.Default != nil {
+				local.infer(param.Default)
+			}
+			local.locals[param.Name] = param
+		}
+		for _, stmt := range function.Statements {
+			local.statement(stmt)
+		}
+	}
+}
+
+func (t *Types) set(node ast.Node, typ Type) Type {
+	if typ.Known() {
+		t.types[node] = typ
+	}
+	return typ
+}
+
+// typeScope infers types within one function or class body. Like the
+// resolver, it keeps locals visible to the end of the function.
+type typeScope struct {
+	types   *Types
+	members map[string]ast.Node
+	locals  map[string]ast.Node
+}
+
+// declare annotates a variable declaration and brings it into scope
+func (s *typeScope) declare(v *ast.VarStatement) {
+	var value Type
+	if v.Value != nil {
+		value = s.infer(v.Value)
+	}
+
+	switch {
+	case v.TypeHint != "":
+		s.types.set(v, Type(v.TypeHint))
+	case v.IsInferred || v.IsConst:
+		s.types.set(v, value)
+	}
+	s.locals[v.Name] = v
+}
+
+// statement infers the expressions of a statement and of its nested blocks
+func (s *typeScope) statement(stmt ast.Statement) {
+	switch n := stmt.(type) {
+	case *ast.VarStatement:
+		s.declare(n)
+	case *ast.ForStatement:
+		collection := s.infer(n.Collection)
+		if element := collection.ElementType(); element.Known() {
+			s.types.set(n, element)
+		} else if collection == "int" {
+			s.types.set(n, "int")
+		}
+		s.locals[n.Iterator] = n
+		s.block(n.Body)
+	default:
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case ast.Expression:
+				s.infer(n)
+				return false
+			case *ast.VarStatement, *ast.ForStatement:
+				s.statement(n.(ast.Statement))
+				return false
+			}
+			return true
+		})
+	}
+}
+
+func (s *typeScope) block(statements []ast.Statement) {
+	for _, stmt := range statements {
+		s.statement(stmt)
+	}
+}
+
+// infer annotates expr and its sub-expressions and returns the type of expr
+func (s *typeScope) infer(expr ast.Expression) Type {
+	if expr == nil {
+		return Variant
+	}
+	return s.types.set(expr, s.typeOf(expr))
+}
+
+func (s *typeScope) typeOf(expr ast.Expression) Type {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		if e.IsInt {
+			return "int"
+		}
+		return "float"
+	case *ast.StringLiteral:
+		return "String"
+	case *ast.BooleanLiteral:
+		return "bool"
+	case *ast.ArrayLiteral:
+		for _, element := range e.Elements {
+			s.infer(element)
+		}
+		return "Array"
+	case *ast.DictionaryLiteral:
+		for i, key := range e.Keys {
+			if !e.LuaStyle {
+				s.infer(key)
+			}
+			s.infer(e.Values[i])
+		}
+		return "Dictionary"
+
+	case *ast.Identifier:
+		return s.lookup(e.Value)
+
+	case *ast.PrefixExpression:
+		operand := s.infer(e.Right)
+		switch e.Operator {
+		case "!", "not":
+			return "bool"
+		case "~":
+			return "int"
+		}
+		return operand
+
+	case *ast.InfixExpression:
+		if e.Operator == "as" {
+			// The right operand names a type rather than holding a value
+			s.infer(e.Left)
+			if name, ok := e.Right.(*ast.Identifier); ok {
+				return Type(name.Value)
+			}
+			return Variant
+		}
+		return binaryType(e.Operator, s.infer(e.Left), s.infer(e.Right))
+
+	case *ast.ConditionalExpression:
+		s.infer(e.Condition)
+		whenTrue, whenFalse := s.infer(e.ValueIfTrue), s.infer(e.ValueIfFalse)
+		if whenTrue == whenFalse {
+			return whenTrue
+		}
+		return Variant
+
+	case *ast.CallExpression:
+		for _, arg := range e.Arguments {
+			s.infer(arg)
+		}
+		return s.callType(e)
+
+	case *ast.IndexExpression:
+		container := s.infer(e.Left)
+		s.infer(e.Index)
+		if container == "String" {
+			return "String"
+		}
+		return container.ElementType()
+
+	case *ast.DotExpression:
+		s.infer(e.Left)
+		if _, ok := e.Left.(*ast.SelfExpression); ok {
+			return s.memberType(e.Property)
+		}
+		return Variant
+
+	case *ast.AssignmentExpression:
+		s.infer(e.Left)
+		s.infer(e.Right)
+		return Variant
+
+	case *ast.AwaitExpression:
+		// The result of a coroutine or the arguments of a signal
+		s.infer(e.Operand)
+		return Variant
+	}
+	return Variant
+}
+
+// lookup returns the type of a name as seen from the current scope
+func (s *typeScope) lookup(name string) Type {
+	if decl, ok := s.locals[name]; ok {
+		return s.types.TypeOf(decl)
+	}
+	return s.memberType(name)
+}
+
+func (s *typeScope) memberType(name string) Type {
+	switch decl := s.members[name].(type) {
+	case *ast.VarStatement:
+		return s.types.TypeOf(decl)
+	case *ast.Function:
+		// A bare method name is a Callable; calls are typed by callType
+		return "Callable"
+	}
+	return Variant
+}
+
+// callType returns the type of the value returned by a call
+func (s *typeScope) callType(call *ast.CallExpression) Type {
+	switch callee := call.Function.(type) {
+	case *ast.Identifier:
+		if _, local := s.locals[callee.Value]; local {
+			return Variant
+		}
+		if function, ok := s.members[callee.Value].(*ast.Function); ok {
+			return Type(function.ReturnType)
+		}
+		if IsBuiltinType(callee.Value) {
+			return Type(callee.Value)
+		}
+		return globalReturnTypes[callee.Value]
+
+	case *ast.DotExpression:
+		s.infer(callee.Left)
+		switch receiver := callee.Left.(type) {
+		case *ast.SelfExpression:
+			if function, ok := s.members[callee.Property].(*ast.Function); ok {
+				return Type(function.ReturnType)
+			}
+		case *ast.Identifier:
+			// Class.new() constructs an instance of Class
+			if callee.Property == "new" && s.isClassName(receiver.Value) {
+				return Type(receiver.Value)
+			}
+		}
+	}
+	return Variant
+}
+
+// isClassName reports whether name refers to an inner class or an engine class
+func (s *typeScope) isClassName(name string) bool {
+	if _, shadowed := s.locals[name]; shadowed {
+		return false
+	}
+	if _, ok := s.members[name].(*ast.Class); ok {
+		return true
+	}
+	return IsCoreClass(name)
+}
+
+// binaryType returns the type of a binary operation on operands of the given types
+func binaryType(operator string, left, right Type) Type {
+	switch operator {
+	case "==", "!=", "<", ">", "<=", ">=", "and", "or", "&&", "||", "in", "not in", "is", "is not":
+		return "bool"
+	case "&", "|", "^", "<<", ">>":
+		if left == "int" && right == "int" {
+			return "int"
+		}
+	case "+", "-", "*", "/", "%", "**":
+		switch {
+		case isNumeric(left) && isNumeric(right):
+			if left == "float" || right == "float" {
+				return "float"
+			}
+			return "int"
+		case left == "String" && (operator == "%" || operator == "+" && right == "String"):
+			return "String"
+		case operator == "+" && left == right && (left == "Array" || left.ElementType().Known()):
+			return left
+		case isVector(left) && (right == left || isNumeric(right)):
+			return left
+		case isVector(right) && isNumeric(left) && operator == "*":
+			return right
+		}
+	}
+	return Variant
+}
+
+func isNumeric(t Type) bool {
+	return t == "int" || t == "float"
+}
+
+func isVector(t Type) bool {
+	switch t {
+	case "Vector2", "Vector2i", "Vector3", "Vector3i", "Vector4", "Vector4i", "Color", "Quaternion":
+		return true
+	}
+	return false
+}
+
+// globalReturnTypes gives the return type of builtin functions whose result
+// type does not depend on their arguments
+var globalReturnTypes = map[string]Type{
+	"str": "String", "len": "int", "range": "Array", "typeof": "int", "type_string": "String",
+	"var_to_str": "String", "error_string": "String", "char": "String", "hash": "int",
+	"is_instance_valid": "bool", "is_same": "bool", "is_equal_approx": "bool", "is_zero_approx": "bool",
+	"is_nan": "bool", "is_inf": "bool", "is_finite": "bool", "type_exists": "bool", "is_instance_of": "bool",
+	"randi": "int", "randi_range": "int", "randf": "float", "randf_range": "float", "randfn": "float",
+	"absi": "int", "absf": "float", "signi": "int", "signf": "float", "clampi": "int", "clampf": "float",
+	"mini": "int", "maxi": "int", "minf": "float", "maxf": "float", "floori": "int", "floorf": "float",
+	"ceili": "int", "ceilf": "float", "roundi": "int", "roundf": "float", "snappedi": "int", "snappedf": "float",
+	"wrapi": "int", "wrapf": "float", "posmod": "int", "fposmod": "float", "fmod": "float",
+	"sqrt": "float", "pow": "float", "exp": "float", "log": "float", "sin": "float", "cos": "float",
+	"tan": "float", "asin": "float", "acos": "float", "atan": "float", "atan2": "float",
+	"sinh": "float", "cosh": "float", "tanh": "float", "deg_to_rad": "float", "rad_to_deg": "float",
+	"lerpf": "float", "inverse_lerp": "float", "remap": "float", "smoothstep": "float",
+	"move_toward": "float", "lerp_angle": "float", "ease": "float", "db_to_linear": "float",
+	"linear_to_db": "float", "nearest_po2": "int", "step_decimals": "int",
+	"weakref": "WeakRef", "get_stack": "Array", "inst_to_dict": "Dictionary",
+	"var_to_bytes": "PackedByteArray", "instance_from_id": "Object",
+}
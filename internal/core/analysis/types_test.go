@@ -0,0 +1,135 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestInferTypes(t *testing.T) {
+	input := `extends Node
+const SPEED = 4.5
+var health: int = 10
+var untyped = 10
+var items: Array = []
+func damage(amount: int, factor) -> float:
+	var scaled := amount * SPEED
+	var label := "hp: %d" % health
+	var alive := health > 0 and not dead()
+	var sprite := Sprite2D.new()
+	var first := label[0]
+	var pick := amount if alive else health
+	var mixed := amount if alive else scaled
+	var total := len(items) + untyped
+	var position := Vector2(1, 2) * 2.0
+	for i in 3:
+		print(i)
+	return self.ratio() * factor
+func ratio() -> float:
+	return 0.5
+func dead() -> bool:
+	return health <= 0
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	types := InferTypes(tree)
+
+	class := tree.RootClass
+	members := ClassMembers(class)
+	expected := map[string]Type{
+		"SPEED":   "float",
+		"health":  "int",
+		"untyped": Variant, // plain var without type hint or :=
+		"items":   "Array",
+	}
+	for name, want := range expected {
+		if got := types.TypeOf(members[name]); got != want {
+			t.Errorf("member %s: expected %q, got %q", name, want, got)
+		}
+	}
+
+	function := class.Functions[0]
+	if got := types.TypeOf(function); got != "float" {
+		t.Errorf("function damage: expected return type float, got %q", got)
+	}
+	if got := types.TypeOf(function.Parameters[0]); got != "int" {
+		t.Errorf("parameter amount: expected int, got %q", got)
+	}
+	if got := types.TypeOf(function.Parameters[1]); got.Known() {
+		t.Errorf("parameter factor: expected Variant, got %q", got)
+	}
+
+	locals := map[string]Type{
+		"scaled":   "float",
+		"label":    "String",
+		"alive":    "bool",
+		"sprite":   "Sprite2D",
+		"first":    "String",
+		"pick":     "int",
+		"mixed":    Variant,
+		"total":    Variant, // int + Variant
+		"position": "Vector2",
+	}
+	for _, stmt := range function.Statements {
+		switch s := stmt.(type) {
+		case *ast.VarStatement:
+			want, ok := locals[s.Name]
+			if !ok {
+				t.Fatalf("unexpected local %s", s.Name)
+			}
+			if got := types.TypeOf(s); got != want {
+				t.Errorf("local %s: expected %q, got %q", s.Name, want, got)
+			}
+		case *ast.ForStatement:
+			if got := types.TypeOf(s); got != "int" {
+				t.Errorf("loop variable %s: expected int, got %q", s.Iterator, got)
+			}
+		case *ast.ReturnStatement:
+			infix := s.Value.(*ast.InfixExpression)
+			if got := types.TypeOf(infix.Left); got != "float" {
+				t.Errorf("self.ratio(): expected float, got %q", got)
+			}
+			if got := types.TypeOf(s.Value); got.Known() {
+				t.Errorf("float * Variant: expected Variant, got %q", got)
+			}
+		}
+	}
+}
+
+func TestTypeElementType(t *testing.T) {
+	tests := map[Type]Type{
+		"Array[int]":          "int",
+		"Array[Array[float]]": "Array[float]",
+		"Array":               Variant,
+		"PackedInt32Array":    Variant,
+		Variant:               Variant,
+	}
+	for typ, want := range tests {
+		if got := typ.ElementType(); got != want {
+			t.Errorf("%q.ElementType(): expected %q, got %q", typ, want, got)
+		}
+	}
+}
+
+func TestIsSubclassOf(t *testing.T) {
+	tests := []struct {
+		class, ancestor string
+		expected        bool
+	}{
+		{"CharacterBody2D", "Node2D", true},
+		{"CharacterBody2D", "Node", true},
+		{"Node2D", "Node2D", true},
+		{"Node2D", "Control", false},
+		{"Resource", "Node", false},
+		{"MyCustomNode", "Node", false},
+	}
+	for _, tt := range tests {
+		if got := IsSubclassOf(tt.class, tt.ancestor); got != tt.expected {
+			t.Errorf("IsSubclassOf(%s, %s): expected %v, got %v", tt.class, tt.ancestor, tt.expected, got)
+		}
+	}
+}
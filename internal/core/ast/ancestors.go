@@ -0,0 +1,69 @@
+package ast
+
+// NodeStack holds the ancestors of a node during traversal, outermost first.
+// The node being visited is not part of its own stack.
+type NodeStack []Node
+
+// push returns a new stack with node appended, leaving s untouched so that
+// sibling subtrees never observe each other's ancestors
+func (s NodeStack) push(node Node) NodeStack {
+	return append(s[:len(s):len(s)], node)
+}
+
+// Parent returns the direct parent of the visited node, or nil at the root
+func (s NodeStack) Parent() Node {
+	if len(s) == 0 {
+		return nil
+	}
+	return s[len(s)-1]
+}
+
+// EnclosingFunction returns the innermost function containing the visited node, or nil
+func (s NodeStack) EnclosingFunction() *Function {
+	for i := len(s) - 1; i >= 0; i-- {
+		switch n := s[i].(type) {
+		case *Function:
+			return n
+		case *Class:
+			return nil
+		}
+	}
+	return nil
+}
+
+// EnclosingClass returns the innermost class containing the visited node, or nil
+func (s NodeStack) EnclosingClass() *Class {
+	for i := len(s) - 1; i >= 0; i-- {
+		if class, ok := s[i].(*Class); ok {
+			return class
+		}
+	}
+	return nil
+}
+
+// InFunction reports whether the visited node is inside a function body or signature
+func (s NodeStack) InFunction() bool {
+	return s.EnclosingFunction() != nil
+}
+
+// AncestorInspector is called for each node together with its ancestors.
+// If it returns false, the children of the node will not be visited.
+type AncestorInspector func(node Node, ancestors NodeStack) bool
+
+// WalkWithAncestors traverses the AST like Inspect, additionally passing the
+// chain of ancestors of each visited node
+func WalkWithAncestors(node Node, f AncestorInspector) {
+	Walk(ancestorVisitor{inspect: f}, node)
+}
+
+type ancestorVisitor struct {
+	inspect   AncestorInspector
+	ancestors NodeStack
+}
+
+func (v ancestorVisitor) Visit(node Node) Visitor {
+	if !v.inspect(node, v.ancestors) {
+		return nil
+	}
+	return ancestorVisitor{inspect: v.inspect, ancestors: v.ancestors.push(node)}
+}
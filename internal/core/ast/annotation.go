@@ -30,3 +30,16 @@ func NewAnnotation(name string, pos Position) *Annotation {
 func (a *Annotation) AddArg(arg Expression) {
 	a.Args = append(a.Args, arg)
 }
+
+// AnnotationsOf returns the annotations applied to a statement, a function or a class
+func AnnotationsOf(node Node) []*Annotation {
+	switch n := node.(type) {
+	case *Function:
+		return n.Annotations
+	case *Class:
+		return n.Annotations
+	case interface{ base() *BaseStatement }:
+		return n.base().Annotations
+	}
+	return nil
+}
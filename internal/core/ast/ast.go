@@ -43,6 +43,15 @@ type AbstractSyntaxTree struct {
 	RootClass *Class
 	Classes   []*Class
 	Functions []*Function
+	Comments  []*Comment // every comment in the source, in order
+	// BlankLines lists the line numbers of the empty lines in the source, in order
+	BlankLines []int
+	// LineContinuations lists the line numbers of the lines ending with a
+	// backslash, in order
+	LineContinuations []int
+	// Unformatted lists the regions from a "# gdformat: off" comment to the
+	// next "# gdformat: on" comment, or to the end of the script, in order
+	Unformatted []SourceRegion
 }
 
 // Position returns the position of the AST in the source code
@@ -2,13 +2,16 @@ package ast
 
 // Class represents a GDScript class
 type Class struct {
-	Pos         Position
-	Name        string
-	Extends     string
-	SubClasses  []*Class
-	Functions   []*Function
-	Statements  []Statement
-	Annotations []*Annotation
+	Pos          Position
+	Name         string
+	Extends      string
+	ExtendsPos   Position // position of the extends clause; zero when it has none
+	ClassName    string   // global name given by class_name; only the script itself has one
+	ClassNamePos Position // position of the class_name statement; zero when it has none
+	SubClasses   []*Class
+	Functions    []*Function
+	Statements   []Statement
+	Annotations  []*Annotation
 }
 
 // Position returns the position of the class in the source code
@@ -23,6 +26,22 @@ func (c *Class) TokenLiteral() string {
 
 func (c *Class) statementNode() {}
 
+// HeaderPosition returns the position of the first line of the script header,
+// made of its annotations, class_name and extends; zero when it has none
+func (c *Class) HeaderPosition() Position {
+	var header Position
+	positions := []Position{c.ExtendsPos, c.ClassNamePos}
+	for _, annotation := range c.Annotations {
+		positions = append(positions, annotation.Pos)
+	}
+	for _, pos := range positions {
+		if pos.Line > 0 && (header.Line == 0 || before(pos, header)) {
+			header = pos
+		}
+	}
+	return header
+}
+
 // NewClass creates a new class
 func NewClass(name string, pos Position) *Class {
 	return &Class{
@@ -54,3 +73,16 @@ func (c *Class) AddStatement(statement Statement) {
 func (c *Class) AddAnnotation(annotation *Annotation) {
 	c.Annotations = append(c.Annotations, annotation)
 }
+
+// HasSubClass reports whether class is a direct subclass of c
+func (c *Class) HasSubClass(class *Class) bool {
+	if c == nil {
+		return false
+	}
+	for _, subClass := range c.SubClasses {
+		if subClass == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package ast
+
+import "sort"
+
+// Comment represents a '#' comment in the source code
+type Comment struct {
+	Pos    Position
+	Text   string // comment text including the leading '#'
+	Inline bool   // comment follows code on the same line
+}
+
+// NewComment creates a new comment
+func NewComment(text string, pos Position, inline bool) *Comment {
+	return &Comment{
+		Pos:    pos,
+		Text:   text,
+		Inline: inline,
+	}
+}
+
+// SourceRegion is a span of whole lines of the source, kept with their text
+type SourceRegion struct {
+	StartLine int // first line of the region
+	EndLine   int // last line of the region, included
+	Text      string
+}
+
+// Contains reports whether line is within the region
+func (r SourceRegion) Contains(line int) bool {
+	return line >= r.StartLine && line <= r.EndLine
+}
+
+// Clause stands for the header of an elif or else branch in a CommentMap:
+// the branches are no nodes of their own, yet comments on their header line
+// and above it belong to them
+type Clause struct {
+	Pos     Position
+	Keyword string // "elif" or "else"
+}
+
+// Position returns the position of the keyword of the clause
+func (c *Clause) Position() Position {
+	return c.Pos
+}
+
+// TokenLiteral returns the keyword of the clause
+func (c *Clause) TokenLiteral() string {
+	return c.Keyword
+}
+
+// CommentMap associates the comments of a script with the statements they belong to.
+//
+// A standalone comment leads the first statement after it, unless it is
+// indented deeper than that statement: then it closes the block it is
+// indented for and follows the enclosing statement at its column. When there
+// is no such block, as for a comment opening a block, it leads the next
+// statement however deep it is. A comment is taken to be indented no deeper
+// than the standalone comments before it since the last statement, as a block
+// they closed cannot be reopened. An inline comment trails the last statement
+// starting before it, which is the statement whose line it ends unless that
+// statement spans several lines. Other standalone comments after the last
+// statement are trailing comments of the script. The elif and else headers
+// of if statements take comments as statements do, through their Clause.
+type CommentMap struct {
+	Leading  map[Node][]*Comment
+	Inline   map[Node][]*Comment
+	After    map[Node][]*Comment // standalone comments closing the node's block
+	Trailing []*Comment
+	// Clauses are the elif and else headers, by the position of their keyword
+	Clauses map[Position]*Clause
+}
+
+// NewCommentMap attaches the comments of tree to its statements, functions,
+// classes and match branches. When the script has a header, the root class
+// itself stands for it.
+func NewCommentMap(tree *AbstractSyntaxTree) *CommentMap {
+	m := &CommentMap{
+		Leading: make(map[Node][]*Comment),
+		Inline:  make(map[Node][]*Comment),
+		After:   make(map[Node][]*Comment),
+		Clauses: make(map[Position]*Clause),
+	}
+	if len(tree.Comments) == 0 {
+		return m
+	}
+
+	var targets []Node
+	// parents maps each target to the closest target enclosing it
+	parents := make(map[Node]Node)
+	var header Position
+	if tree.RootClass != nil {
+		header = tree.RootClass.HeaderPosition()
+	}
+	if header.Line > 0 {
+		targets = append(targets, tree.RootClass)
+	}
+	Walk(&commentTargets{root: tree.RootClass, targets: &targets, parents: parents, clauses: m.Clauses}, tree)
+
+	position := func(node Node) Position {
+		if node == tree.RootClass {
+			return header
+		}
+		return node.Position()
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		return before(position(targets[i]), position(targets[j]))
+	})
+
+	// gap is the next target of the last standalone comment, and column
+	// the column that comment was taken at
+	gap, column := -1, 0
+	for _, comment := range tree.Comments {
+		// Index of the first target starting after the comment
+		next := sort.Search(len(targets), func(i int) bool {
+			return before(comment.Pos, position(targets[i]))
+		})
+		if !comment.Inline {
+			if next != gap || comment.Pos.Column < column {
+				column = comment.Pos.Column
+			}
+			gap = next
+		}
+
+		switch {
+		case comment.Inline && next > 0:
+			target := targets[next-1]
+			m.Inline[target] = append(m.Inline[target], comment)
+		case next > 0 && (next == len(targets) || column > position(targets[next]).Column):
+			// Indented deeper than what follows: find the statement whose
+			// column the comment is aligned with among the previous one and its parents
+			target := targets[next-1]
+			for position(target).Column > column && parents[target] != nil {
+				target = parents[target]
+			}
+			if next < len(targets) && (parents[targets[next]] == target || parents[targets[next]] == parents[target]) {
+				// No block to close, as when the comment opens one or is
+				// merely indented differently from the statements around it
+				m.Leading[targets[next]] = append(m.Leading[targets[next]], comment)
+				break
+			}
+			if position(target).Column > column || (next == len(targets) && parents[target] == nil) {
+				m.Trailing = append(m.Trailing, comment)
+				break
+			}
+			m.After[target] = append(m.After[target], comment)
+		case next < len(targets):
+			target := targets[next]
+			m.Leading[target] = append(m.Leading[target], comment)
+		default:
+			m.Trailing = append(m.Trailing, comment)
+		}
+	}
+	return m
+}
+
+// commentTargets collects the statements, match branches and elif and else
+// clauses comments attach to, along with the closest statement enclosing
+// each. It tracks that statement alone rather than every ancestor, and is
+// only copied when entering a statement, as NewCommentMap runs on each file
+// formatted.
+type commentTargets struct {
+	root    *Class
+	targets *[]Node
+	parents map[Node]Node
+	parent  Node
+	clauses map[Position]*Clause
+}
+
+func (v *commentTargets) Visit(node Node) Visitor {
+	if _, ok := node.(Statement); !ok || node == v.root {
+		return v
+	}
+	*v.targets = append(*v.targets, node)
+	if v.parent != nil {
+		v.parents[node] = v.parent
+	}
+	if match, ok := node.(*MatchStatement); ok {
+		for _, branch := range match.Branches {
+			*v.targets = append(*v.targets, branch)
+			v.parents[branch] = match
+		}
+	}
+	if ifStmt, ok := node.(*IfStatement); ok {
+		// A clause is at the level of its if statement, which the comments
+		// indented deeper before it are within
+		positions := ifStmt.ElifPos
+		if len(ifStmt.Alternative) > 0 {
+			positions = append(positions[:len(positions):len(positions)], ifStmt.ElsePos)
+		}
+		for i, pos := range positions {
+			keyword := "elif"
+			if i == len(ifStmt.ElifPos) {
+				keyword = "else"
+			}
+			clause := &Clause{Pos: pos, Keyword: keyword}
+			v.clauses[pos] = clause
+			*v.targets = append(*v.targets, clause)
+			if v.parent != nil {
+				v.parents[clause] = v.parent
+			}
+		}
+	}
+	inner := *v
+	inner.parent = node
+	return &inner
+}
+
+// before reports whether position a comes before position b
+func before(a, b Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
@@ -0,0 +1,171 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Difference is a place where two trees differ
+type Difference struct {
+	// Path leads from the compared roots to the differing field, e.g.
+	// RootClass.Functions[0].Statements[1].Value.Right
+	Path string
+	// A and B are the positions of the innermost nodes holding the difference
+	A, B    Position
+	Message string
+}
+
+// String returns a one-line description of the difference
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// Compare returns the differences between two trees that can change what the
+// code does. Positions, whitespace and comments are ignored, and so is the
+// way literals are spelled: 'a' equals "a", and 0xFF equals 255.
+func Compare(a, b Node) []Difference {
+	c := &comparer{semantic: true}
+	c.compare("", reflect.ValueOf(a), reflect.ValueOf(b), Position{}, Position{})
+	return c.differences
+}
+
+// CompareSyntax returns the differences between two trees other than
+// positions and whitespace: unlike Compare, comments and the spelling of
+// literals count
+func CompareSyntax(a, b Node) []Difference {
+	c := &comparer{}
+	c.compare("", reflect.ValueOf(a), reflect.ValueOf(b), Position{}, Position{})
+	return c.differences
+}
+
+// ignoredFields are the fields that never affect a comparison. Classes and
+// Functions of a tree only index what RootClass holds, and SubStatements of
+// a function repeat its statements.
+var ignoredFields = map[reflect.Type]map[string]bool{
+	reflect.TypeOf(AbstractSyntaxTree{}): {
+		"Classes": true, "Functions": true, "BlankLines": true, "LineContinuations": true, "Unformatted": true,
+	},
+	reflect.TypeOf(Function{}): {"SubStatements": true},
+}
+
+// spellingFields are the fields that only record how code is written, which
+// Compare ignores
+var spellingFields = map[reflect.Type]map[string]bool{
+	reflect.TypeOf(AbstractSyntaxTree{}): {"Comments": true},
+	reflect.TypeOf(StringLiteral{}):      {"Raw": true, "Quote": true},
+	reflect.TypeOf(NumberLiteral{}):      {"Original": true},
+	reflect.TypeOf(GetNodeExpression{}):  {"Raw": true},
+	reflect.TypeOf(ArrayLiteral{}):       {"TrailingComma": true},
+	reflect.TypeOf(DictionaryLiteral{}):  {"TrailingComma": true},
+	reflect.TypeOf(CallExpression{}):     {"TrailingComma": true},
+	reflect.TypeOf(Function{}):           {"TrailingComma": true},
+}
+
+var (
+	positionType = reflect.TypeOf(Position{})
+	nodeType     = reflect.TypeOf((*Node)(nil)).Elem()
+)
+
+type comparer struct {
+	semantic    bool
+	differences []Difference
+}
+
+func (c *comparer) report(path string, posA, posB Position, format string, args ...any) {
+	c.differences = append(c.differences, Difference{
+		Path:    path,
+		A:       posA,
+		B:       posB,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// compare walks a and b in parallel; posA and posB are the positions of the
+// innermost nodes containing them
+func (c *comparer) compare(path string, a, b reflect.Value, posA, posB Position) {
+	if a.IsValid() != b.IsValid() {
+		c.report(path, posA, posB, "%s != %s", describe(a), describe(b))
+		return
+	}
+	if !a.IsValid() {
+		return
+	}
+	if a.Type() != b.Type() {
+		c.report(path, posA, posB, "%s != %s", describe(a), describe(b))
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				c.report(path, posA, posB, "%s != %s", describe(a), describe(b))
+			}
+			return
+		}
+		if a.Type().Implements(nodeType) && a.Kind() == reflect.Pointer {
+			posA = a.Interface().(Node).Position()
+			posB = b.Interface().(Node).Position()
+		}
+		c.compare(path, a.Elem(), b.Elem(), posA, posB)
+
+	case reflect.Struct:
+		if a.Type() == positionType {
+			return
+		}
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() || ignoredFields[a.Type()][field.Name] ||
+				(c.semantic && spellingFields[a.Type()][field.Name]) {
+				continue
+			}
+			fieldPath := path
+			if !field.Anonymous {
+				fieldPath = join(path, field.Name)
+			}
+			c.compare(fieldPath, a.Field(i), b.Field(i), posA, posB)
+		}
+
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			c.report(path, posA, posB, "%d elements != %d elements", a.Len(), b.Len())
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			c.compare(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i), posA, posB)
+		}
+
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			c.report(path, posA, posB, "%s != %s", describe(a), describe(b))
+		}
+	}
+}
+
+// describe formats a value for a difference message
+func describe(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nothing"
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return "nothing"
+		}
+		if v.Kind() == reflect.Interface {
+			return describe(v.Elem())
+		}
+		return v.Type().String()
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
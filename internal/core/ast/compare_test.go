@@ -0,0 +1,103 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func parseForCompare(t *testing.T, code string) *ast.AbstractSyntaxTree {
+	t.Helper()
+	tree, errors := parser.ParseFile("test.gd", code)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors in %q: %v", code, errors)
+	}
+	return tree
+}
+
+func TestCompare(t *testing.T) {
+	original := "extends Node\n\nfunc foo(a, b = 1):\n\tvar s = 'text'\n\treturn a + b * 0xFF\n"
+
+	tests := []struct {
+		name     string
+		code     string
+		semantic []string // paths of the differences Compare reports
+		syntax   int      // number of differences CompareSyntax reports
+	}{
+		{
+			name: "whitespace",
+			code: "extends Node\nfunc foo(a,b=1):\n    var s = 'text'\n    return a+b*0xFF\n",
+		},
+		{
+			name:   "spelling",
+			code:   "extends Node\n# why\nfunc foo(a, b = 1):\n\tvar s = \"text\"\n\treturn a + b * 255\n",
+			syntax: 4, // comments, string value and quote, number spelling
+		},
+		{
+			name:     "operator",
+			code:     "extends Node\nfunc foo(a, b = 1):\n\tvar s = 'text'\n\treturn a - b * 0xFF\n",
+			semantic: []string{"RootClass.Functions[0].Statements[1].Value.Operator"},
+			syntax:   1,
+		},
+		{
+			name:     "string_content",
+			code:     "extends Node\nfunc foo(a, b = 1):\n\tvar s = 'other'\n\treturn a + b * 0xFF\n",
+			semantic: []string{"RootClass.Functions[0].Statements[0].Value.Value"},
+			syntax:   2, // decoded value and literal
+		},
+		{
+			name: "structure",
+			code: "extends Node\nfunc foo(a, b = 1):\n\tvar s = 'text'\n\treturn (a + b) * 0xFF\n",
+			semantic: []string{
+				"RootClass.Functions[0].Statements[1].Value.Left",
+				"RootClass.Functions[0].Statements[1].Value.Operator",
+				"RootClass.Functions[0].Statements[1].Value.Right",
+			},
+			syntax: 3,
+		},
+		{
+			name:     "statements",
+			code:     "extends Node\nfunc foo(a, b = 1):\n\tvar s = 'text'\n\tprint(s)\n\treturn a + b * 0xFF\n",
+			semantic: []string{"RootClass.Functions[0].Statements"},
+			syntax:   1,
+		},
+	}
+
+	a := parseForCompare(t, original)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := parseForCompare(t, tt.code)
+
+			differences := ast.Compare(a, b)
+			var paths []string
+			for _, d := range differences {
+				paths = append(paths, d.Path)
+			}
+			if strings.Join(paths, "\n") != strings.Join(tt.semantic, "\n") {
+				t.Errorf("Compare: expected differences at %v, got %v", tt.semantic, differences)
+			}
+			if syntax := ast.CompareSyntax(a, b); len(syntax) != tt.syntax {
+				t.Errorf("CompareSyntax: expected %d differences, got %v", tt.syntax, syntax)
+			}
+		})
+	}
+}
+
+func TestCompareReportsPositions(t *testing.T) {
+	a := parseForCompare(t, "func foo():\n\treturn 1\n")
+	b := parseForCompare(t, "\n\nfunc foo():\n\treturn 2\n")
+
+	differences := ast.Compare(a, b)
+	if len(differences) != 1 {
+		t.Fatalf("Expected one difference, got %v", differences)
+	}
+	d := differences[0]
+	if d.A.Line != 2 || d.B.Line != 4 {
+		t.Errorf("Expected the difference on lines 2 and 4, got %d and %d", d.A.Line, d.B.Line)
+	}
+	if d.String() != "RootClass.Functions[0].Statements[0].Value.Value: 1 != 2" {
+		t.Errorf("Unexpected description %q", d.String())
+	}
+}
@@ -7,7 +7,11 @@ type IfStatement struct {
 	Consequence   []Statement
 	ElseCondition []Expression
 	ElseBranches  [][]Statement
-	Alternative   []Statement
+	// ElifPos are the positions of the elif keywords, one per ElseCondition
+	ElifPos     []Position
+	Alternative []Statement
+	// ElsePos is the position of the else keyword, when there is an else branch
+	ElsePos Position
 }
 
 // NewIfStatement creates a new if statement
@@ -31,8 +35,9 @@ func (i *IfStatement) AddConsequenceStatement(statement Statement) {
 	i.Consequence = append(i.Consequence, statement)
 }
 
-// AddElseIfBranch adds an else-if branch
-func (i *IfStatement) AddElseIfBranch(condition Expression, statements []Statement) {
+// AddElseIfBranch adds an else-if branch whose elif keyword is at pos
+func (i *IfStatement) AddElseIfBranch(pos Position, condition Expression, statements []Statement) {
+	i.ElifPos = append(i.ElifPos, pos)
 	i.ElseCondition = append(i.ElseCondition, condition)
 	i.ElseBranches = append(i.ElseBranches, statements)
 }
@@ -137,9 +142,11 @@ func (m *MatchStatement) AddBranch(branch *MatchBranch) {
 }
 
 // MatchBranch represents a branch in a match statement
+// Pattern holds the first pattern; Patterns holds all comma-separated patterns
 type MatchBranch struct {
 	Pos       Position
 	Pattern   Expression
+	Patterns  []Expression
 	Guard     Expression
 	Body      []Statement
 	IsGuarded bool
@@ -148,12 +155,18 @@ type MatchBranch struct {
 // NewMatchBranch creates a new match branch
 func NewMatchBranch(pos Position, pattern Expression) *MatchBranch {
 	return &MatchBranch{
-		Pos:     pos,
-		Pattern: pattern,
-		Body:    make([]Statement, 0),
+		Pos:      pos,
+		Pattern:  pattern,
+		Patterns: []Expression{pattern},
+		Body:     make([]Statement, 0),
 	}
 }
 
+// AddPattern adds an alternative pattern to the branch (multipattern)
+func (m *MatchBranch) AddPattern(pattern Expression) {
+	m.Patterns = append(m.Patterns, pattern)
+}
+
 // SetGuard sets the guard condition for the branch
 func (m *MatchBranch) SetGuard(guard Expression) {
 	m.Guard = guard
@@ -169,3 +182,8 @@ func (m *MatchBranch) AddBodyStatement(statement Statement) {
 func (m *MatchBranch) Position() Position {
 	return m.Pos
 }
+
+// TokenLiteral returns the literal value of the token
+func (m *MatchBranch) TokenLiteral() string {
+	return "match_branch"
+}
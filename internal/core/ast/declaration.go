@@ -0,0 +1,72 @@
+package ast
+
+// SignalStatement represents a signal declaration
+type SignalStatement struct {
+	BaseStatement
+	Name       string
+	Parameters []*Parameter
+}
+
+// NewSignalStatement creates a new signal declaration
+func NewSignalStatement(pos Position, name string) *SignalStatement {
+	return &SignalStatement{
+		BaseStatement: BaseStatement{
+			Pos:         pos,
+			Kind:        "signal_stmt",
+			Annotations: make([]*Annotation, 0),
+		},
+		Name:       name,
+		Parameters: make([]*Parameter, 0),
+	}
+}
+
+// AddParameter adds a parameter to the signal
+func (s *SignalStatement) AddParameter(param *Parameter) {
+	s.Parameters = append(s.Parameters, param)
+}
+
+// EnumStatement represents an enum declaration; Name is empty for an unnamed enum
+type EnumStatement struct {
+	BaseStatement
+	Name     string
+	Elements []*EnumElement
+}
+
+// NewEnumStatement creates a new enum declaration
+func NewEnumStatement(pos Position, name string) *EnumStatement {
+	kind := "enum_stmt"
+	if name == "" {
+		kind = "enum_unnamed"
+	}
+	return &EnumStatement{
+		BaseStatement: BaseStatement{
+			Pos:         pos,
+			Kind:        kind,
+			Annotations: make([]*Annotation, 0),
+		},
+		Name:     name,
+		Elements: make([]*EnumElement, 0),
+	}
+}
+
+// AddElement adds an element to the enum
+func (e *EnumStatement) AddElement(element *EnumElement) {
+	e.Elements = append(e.Elements, element)
+}
+
+// EnumElement represents an element of an enum; Value is nil when it is implicit
+type EnumElement struct {
+	Pos   Position
+	Name  string
+	Value Expression
+}
+
+// Position returns the position of the element in the source code
+func (e *EnumElement) Position() Position {
+	return e.Pos
+}
+
+// TokenLiteral returns the literal value of the token
+func (e *EnumElement) TokenLiteral() string {
+	return e.Name
+}
@@ -0,0 +1,64 @@
+package ast
+
+import (
+	"reflect"
+)
+
+// Encode converts a tree into values that encoding/json writes as plain
+// objects, for tools outside gdtoolkit. Each struct becomes an object with a
+// "node" member naming its type, e.g. "VarStatement", and a member per
+// exported field, named as in this package; the fields of embedded structs
+// such as BaseStatement are flattened into it. Positions are objects with
+// "line", "column" and "offset" members. The Classes and Functions of a tree
+// and the SubStatements of a function are left out, as they repeat what
+// RootClass holds.
+func Encode(node Node) any {
+	return encode(reflect.ValueOf(node))
+}
+
+func encode(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return encode(v.Elem())
+
+	case reflect.Struct:
+		if v.Type() == positionType {
+			pos := v.Interface().(Position)
+			return map[string]any{"line": pos.Line, "column": pos.Column, "offset": pos.Offset}
+		}
+		object := map[string]any{"node": v.Type().Name()}
+		encodeFields(v, object)
+		return object
+
+	case reflect.Slice:
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = encode(v.Index(i))
+		}
+		return values
+
+	default:
+		return v.Interface()
+	}
+}
+
+// encodeFields adds the exported fields of the struct v to object
+func encodeFields(v reflect.Value, object map[string]any) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || ignoredFields[v.Type()][field.Name] {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			encodeFields(v.Field(i), object)
+			continue
+		}
+		object[field.Name] = encode(v.Field(i))
+	}
+}
@@ -0,0 +1,56 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+func TestEncode(t *testing.T) {
+	tree := parseForCompare(t, "extends Node\n\nfunc foo(a):\n\treturn a + 1\n")
+
+	data, err := json.Marshal(ast.Encode(tree))
+	if err != nil {
+		t.Fatalf("Encoded tree is not JSON: %v", err)
+	}
+	var decoded struct {
+		Node      string
+		RootClass struct {
+			Extends   string
+			Functions []struct {
+				Node       string
+				Name       string
+				Pos        map[string]int
+				Statements []struct {
+					Node  string
+					Kind  string
+					Value struct {
+						Node     string
+						Operator string
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Node != "AbstractSyntaxTree" || decoded.RootClass.Extends != "Node" || len(decoded.RootClass.Functions) != 1 {
+		t.Fatalf("Unexpected tree:\n%s", data)
+	}
+	function := decoded.RootClass.Functions[0]
+	if function.Node != "Function" || function.Name != "foo" || function.Pos["line"] != 3 {
+		t.Errorf("Unexpected function %+v", function)
+	}
+	// Fields of BaseStatement are flattened into the statement
+	if len(function.Statements) != 1 || function.Statements[0].Kind != "return_stmt" ||
+		function.Statements[0].Value.Node != "InfixExpression" || function.Statements[0].Value.Operator != "+" {
+		t.Errorf("Unexpected statements %+v", function.Statements)
+	}
+	if strings.Contains(string(data), "SubStatements") || strings.Contains(string(data), `"Classes"`) {
+		t.Errorf("Expected the fields repeating RootClass to be left out:\n%s", data)
+	}
+}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"strconv"
+	"strings"
 )
 
 // BaseExpression provides common functionality for all expressions
@@ -38,19 +39,46 @@ func NewIdentifier(value string, pos Position) *Identifier {
 // StringLiteral represents a string literal
 type StringLiteral struct {
 	BaseExpression
-	Value string
+	Value string // the string the literal stands for, with its escapes decoded
+	Raw   string // the literal as written, with its r prefix, quotes and escapes
+	Quote string // delimiter: ", ', """ or '''
+	IsRaw bool   // r"..." literal, where backslashes are not escapes
 }
 
 // TokenLiteral returns the literal value of the token
 func (s *StringLiteral) TokenLiteral() string {
-	return s.Value
+	return s.Raw
 }
 
-// NewStringLiteral creates a new string literal
-func NewStringLiteral(value string, pos Position) *StringLiteral {
+// Content returns the text between the quotes, with escapes as written
+func (s *StringLiteral) Content() string {
+	content := strings.TrimPrefix(s.Raw, "r")
+	content = strings.TrimPrefix(content, s.Quote)
+	// An unterminated string has no closing quotes
+	if len(content) >= len(s.Quote) {
+		content = strings.TrimSuffix(content, s.Quote)
+	}
+	return content
+}
+
+// NewStringLiteral creates a new string literal from its source text and the
+// string it decodes to
+func NewStringLiteral(raw, value string, pos Position) *StringLiteral {
+	isRaw := strings.HasPrefix(raw, "r")
+	quoted := strings.TrimPrefix(raw, "r")
+	quote := ""
+	if quoted != "" {
+		quote = quoted[:1]
+		if triple := strings.Repeat(quote, 3); len(quoted) >= 6 && strings.HasPrefix(quoted, triple) {
+			quote = triple
+		}
+	}
 	return &StringLiteral{
 		BaseExpression: BaseExpression{Pos: pos},
 		Value:          value,
+		Raw:            raw,
+		Quote:          quote,
+		IsRaw:          isRaw,
 	}
 }
 
@@ -104,6 +132,23 @@ func NewNullLiteral(pos Position) *NullLiteral {
 	}
 }
 
+// SelfExpression represents the 'self' reference to the current instance
+type SelfExpression struct {
+	BaseExpression
+}
+
+// TokenLiteral returns the literal value of the token
+func (s *SelfExpression) TokenLiteral() string {
+	return "self"
+}
+
+// NewSelfExpression creates a new self expression
+func NewSelfExpression(pos Position) *SelfExpression {
+	return &SelfExpression{
+		BaseExpression: BaseExpression{Pos: pos},
+	}
+}
+
 type BooleanLiteral struct {
 	BaseExpression
 	Value bool
@@ -126,6 +171,8 @@ func NewBooleanLiteral(value bool, pos Position) *BooleanLiteral {
 type ArrayLiteral struct {
 	BaseExpression
 	Elements []Expression
+	// TrailingComma is set when a comma follows the last element
+	TrailingComma bool
 }
 
 // TokenLiteral returns the literal value of the token
@@ -146,10 +193,16 @@ func (a *ArrayLiteral) AddElement(element Expression) {
 	a.Elements = append(a.Elements, element)
 }
 
-// DictionaryLiteral represents a dictionary literal
+// DictionaryLiteral represents a dictionary literal; Keys and Values are in source order
 type DictionaryLiteral struct {
 	BaseExpression
-	Pairs map[Expression]Expression
+	Keys   []Expression
+	Values []Expression
+	// LuaStyle is set for {name = value} dictionaries, whose keys are
+	// identifiers standing for the string of their name
+	LuaStyle bool
+	// TrailingComma is set when a comma follows the last pair
+	TrailingComma bool
 }
 
 // TokenLiteral returns the literal value of the token
@@ -161,13 +214,15 @@ func (d *DictionaryLiteral) TokenLiteral() string {
 func NewDictionaryLiteral(pos Position) *DictionaryLiteral {
 	return &DictionaryLiteral{
 		BaseExpression: BaseExpression{Pos: pos},
-		Pairs:          make(map[Expression]Expression),
+		Keys:           make([]Expression, 0),
+		Values:         make([]Expression, 0),
 	}
 }
 
 // AddPair adds a key-value pair to the dictionary
 func (d *DictionaryLiteral) AddPair(key, value Expression) {
-	d.Pairs[key] = value
+	d.Keys = append(d.Keys, key)
+	d.Values = append(d.Values, value)
 }
 
 // PrefixExpression represents a prefix operator expression
@@ -219,6 +274,8 @@ type CallExpression struct {
 	BaseExpression
 	Function  Expression
 	Arguments []Expression
+	// TrailingComma is set when a comma follows the last argument
+	TrailingComma bool
 }
 
 // TokenLiteral returns the literal value of the token
@@ -328,3 +385,47 @@ func NewConditionalExpression(condition, valueIfTrue, valueIfFalse Expression, p
 		ValueIfFalse:   valueIfFalse,
 	}
 }
+
+// AwaitExpression represents waiting for a signal or a coroutine: await operand
+type AwaitExpression struct {
+	BaseExpression
+	Operand Expression
+}
+
+// TokenLiteral returns the literal value of the token
+func (a *AwaitExpression) TokenLiteral() string {
+	return "await"
+}
+
+// NewAwaitExpression creates a new await expression
+func NewAwaitExpression(operand Expression, pos Position) *AwaitExpression {
+	return &AwaitExpression{
+		BaseExpression: BaseExpression{Pos: pos},
+		Operand:        operand,
+	}
+}
+
+// GetNodeExpression represents the get_node() shorthands $Path, $"Path" and
+// %Name
+type GetNodeExpression struct {
+	BaseExpression
+	// Raw is the expression as written, e.g. $"../HUD"
+	Raw string
+	// Path is the node path looked up, e.g. ../HUD, or %Name for a scene
+	// unique node
+	Path string
+}
+
+// TokenLiteral returns the literal value of the token
+func (g *GetNodeExpression) TokenLiteral() string {
+	return g.Raw
+}
+
+// NewGetNodeExpression creates a new get_node shorthand expression
+func NewGetNodeExpression(raw, path string, pos Position) *GetNodeExpression {
+	return &GetNodeExpression{
+		BaseExpression: BaseExpression{Pos: pos},
+		Raw:            raw,
+		Path:           path,
+	}
+}
@@ -20,7 +20,10 @@ func (p *Parameter) TokenLiteral() string {
 
 // Function represents a GDScript function
 type Function struct {
-	Pos           Position
+	Pos Position
+	// End is the position right after the last token of the function, on
+	// the last line of its body
+	End           Position
 	Name          string
 	Parameters    []*Parameter
 	ReturnType    string
@@ -28,6 +31,8 @@ type Function struct {
 	SubStatements []Statement
 	Annotations   []*Annotation
 	IsStatic      bool
+	// TrailingComma is set when a comma follows the last parameter
+	TrailingComma bool
 }
 
 // Position returns the position of the function in the source code
@@ -0,0 +1,112 @@
+//go:build ignore
+
+// gen_typed_visitor generates typed_visitor.go, which dispatches walked nodes
+// to per-type callbacks. Run it with `go generate ./internal/core/ast`.
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"log"
+	"os"
+	"text/template"
+)
+
+// nodeTypes maps each callback name to the node type it receives, in the
+// order they appear in the generated struct
+var nodeTypes = []struct {
+	Callback string
+	Type     string
+}{
+	{"Class", "Class"},
+	{"Function", "Function"},
+	{"Parameter", "Parameter"},
+	{"Annotation", "Annotation"},
+
+	{"Pass", "PassStatement"},
+	{"Break", "BreakStatement"},
+	{"Continue", "ContinueStatement"},
+	{"Breakpoint", "BreakpointStatement"},
+	{"Assert", "AssertStatement"},
+	{"Return", "ReturnStatement"},
+	{"ExpressionStatement", "ExpressionStatement"},
+	{"Var", "VarStatement"},
+	{"Signal", "SignalStatement"},
+	{"Enum", "EnumStatement"},
+	{"EnumElement", "EnumElement"},
+	{"If", "IfStatement"},
+	{"For", "ForStatement"},
+	{"While", "WhileStatement"},
+	{"Match", "MatchStatement"},
+
+	{"Identifier", "Identifier"},
+	{"StringLiteral", "StringLiteral"},
+	{"NumberLiteral", "NumberLiteral"},
+	{"BooleanLiteral", "BooleanLiteral"},
+	{"NullLiteral", "NullLiteral"},
+	{"Self", "SelfExpression"},
+	{"ArrayLiteral", "ArrayLiteral"},
+	{"DictionaryLiteral", "DictionaryLiteral"},
+	{"Prefix", "PrefixExpression"},
+	{"Infix", "InfixExpression"},
+	{"Call", "CallExpression"},
+	{"Index", "IndexExpression"},
+	{"Dot", "DotExpression"},
+	{"Assignment", "AssignmentExpression"},
+	{"Conditional", "ConditionalExpression"},
+	{"Await", "AwaitExpression"},
+	{"GetNode", "GetNodeExpression"},
+
+	{"WildcardPattern", "WildcardPattern"},
+	{"BindingPattern", "BindingPattern"},
+	{"RestPattern", "RestPattern"},
+	{"ArrayPattern", "ArrayPattern"},
+	{"DictionaryPattern", "DictionaryPattern"},
+}
+
+var source = template.Must(template.New("typed_visitor").Parse(`// Code generated by gen_typed_visitor.go; DO NOT EDIT.
+
+package ast
+
+// TypedVisitor dispatches each walked node to the callback registered for its
+// type, together with the node's ancestors. Nil callbacks are skipped and the
+// traversal always continues into children.
+type TypedVisitor struct {
+{{- range .}}
+	Visit{{.Callback}} func(node *{{.Type}}, ancestors NodeStack)
+{{- end}}
+}
+
+// Walk traverses the AST starting from node, calling the matching callback for each node
+func (t *TypedVisitor) Walk(node Node) {
+	WalkWithAncestors(node, t.visit)
+}
+
+func (t *TypedVisitor) visit(node Node, ancestors NodeStack) bool {
+	switch n := node.(type) {
+{{- range .}}
+	case *{{.Type}}:
+		if t.Visit{{.Callback}} != nil {
+			t.Visit{{.Callback}}(n, ancestors)
+		}
+{{- end}}
+	}
+	return true
+}
+`))
+
+func main() {
+	var buf bytes.Buffer
+	if err := source.Execute(&buf, nodeTypes); err != nil {
+		log.Fatal(err)
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile("typed_visitor.go", formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
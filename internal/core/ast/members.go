@@ -0,0 +1,94 @@
+package ast
+
+import "strings"
+
+// MemberKind is the category of a class member. Categories are declared in
+// the order gdlint expects class members in.
+type MemberKind int
+
+const (
+	MemberPass MemberKind = iota
+	MemberClassName
+	MemberExtends
+	MemberDocstring
+	MemberSignal
+	MemberEnum
+	MemberConst
+	MemberStaticVar
+	MemberExportGroup
+	MemberExportVar
+	MemberVar
+	MemberPrivateVar
+	MemberOnreadyVar
+	MemberPrivateOnreadyVar
+	MemberInnerClass
+	MemberFunc
+	MemberStaticFunc
+)
+
+// MemberKindOf returns the category of a class member
+func MemberKindOf(stmt Statement) MemberKind {
+	switch s := stmt.(type) {
+	case *PassStatement:
+		return MemberPass
+	case *VarStatement:
+		if s.IsConst {
+			return MemberConst
+		}
+		if s.IsStatic {
+			return MemberStaticVar
+		}
+
+		// Check annotations for @export, @onready, etc.
+		hasExport := false
+		hasOnready := false
+		for _, annotation := range s.Annotations {
+			switch {
+			case annotation.Name == "export_group":
+				return MemberExportGroup
+			case strings.HasPrefix(annotation.Name, "export"):
+				hasExport = true
+			case annotation.Name == "onready":
+				hasOnready = true
+			}
+		}
+
+		// Check if variable name starts with underscore (private)
+		isPrivate := len(s.Name) > 0 && s.Name[0] == '_'
+
+		if hasExport {
+			return MemberExportVar
+		} else if hasOnready {
+			if isPrivate {
+				return MemberPrivateOnreadyVar
+			}
+			return MemberOnreadyVar
+		} else if isPrivate {
+			return MemberPrivateVar
+		}
+		return MemberVar
+	case *ExpressionStatement:
+		// Check for string literals (docstrings)
+		if _, ok := s.Expression.(*StringLiteral); ok {
+			return MemberDocstring
+		}
+	case *Class:
+		return MemberInnerClass
+	case *Function:
+		if s.IsStatic {
+			return MemberStaticFunc
+		}
+		for _, annotation := range s.Annotations {
+			if annotation.Name == "static" {
+				return MemberStaticFunc
+			}
+		}
+		return MemberFunc
+	case *SignalStatement:
+		return MemberSignal
+	case *EnumStatement:
+		return MemberEnum
+	}
+
+	return MemberVar // Default fallback
+}
@@ -0,0 +1,107 @@
+package ast
+
+// WildcardPattern represents the '_' pattern matching any value
+type WildcardPattern struct {
+	BaseExpression
+}
+
+// TokenLiteral returns the literal value of the token
+func (w *WildcardPattern) TokenLiteral() string {
+	return "_"
+}
+
+// NewWildcardPattern creates a new wildcard pattern
+func NewWildcardPattern(pos Position) *WildcardPattern {
+	return &WildcardPattern{
+		BaseExpression: BaseExpression{Pos: pos},
+	}
+}
+
+// BindingPattern represents a 'var name' pattern binding the matched value
+type BindingPattern struct {
+	BaseExpression
+	Name string
+}
+
+// TokenLiteral returns the literal value of the token
+func (b *BindingPattern) TokenLiteral() string {
+	return b.Name
+}
+
+// NewBindingPattern creates a new binding pattern
+func NewBindingPattern(name string, pos Position) *BindingPattern {
+	return &BindingPattern{
+		BaseExpression: BaseExpression{Pos: pos},
+		Name:           name,
+	}
+}
+
+// RestPattern represents the '..' pattern for open-ended arrays and dictionaries
+type RestPattern struct {
+	BaseExpression
+}
+
+// TokenLiteral returns the literal value of the token
+func (r *RestPattern) TokenLiteral() string {
+	return ".."
+}
+
+// NewRestPattern creates a new rest pattern
+func NewRestPattern(pos Position) *RestPattern {
+	return &RestPattern{
+		BaseExpression: BaseExpression{Pos: pos},
+	}
+}
+
+// ArrayPattern represents an array pattern ([a, b, ..])
+type ArrayPattern struct {
+	BaseExpression
+	Elements []Expression
+}
+
+// TokenLiteral returns the literal value of the token
+func (a *ArrayPattern) TokenLiteral() string {
+	return "array_pattern"
+}
+
+// NewArrayPattern creates a new array pattern
+func NewArrayPattern(pos Position) *ArrayPattern {
+	return &ArrayPattern{
+		BaseExpression: BaseExpression{Pos: pos},
+		Elements:       make([]Expression, 0),
+	}
+}
+
+// AddElement adds an element to the array pattern
+func (a *ArrayPattern) AddElement(element Expression) {
+	a.Elements = append(a.Elements, element)
+}
+
+// DictionaryPattern represents a dictionary pattern ({"key": value, ..})
+// Keys and Values are parallel slices; a nil value means the key is matched
+// without a value pattern, and a RestPattern key marks an open-ended pattern.
+type DictionaryPattern struct {
+	BaseExpression
+	Keys   []Expression
+	Values []Expression
+}
+
+// TokenLiteral returns the literal value of the token
+func (d *DictionaryPattern) TokenLiteral() string {
+	return "dict_pattern"
+}
+
+// NewDictionaryPattern creates a new dictionary pattern
+func NewDictionaryPattern(pos Position) *DictionaryPattern {
+	return &DictionaryPattern{
+		BaseExpression: BaseExpression{Pos: pos},
+		Keys:           make([]Expression, 0),
+		Values:         make([]Expression, 0),
+	}
+}
+
+// AddPair adds a key and its optional value pattern to the dictionary pattern
+func (d *DictionaryPattern) AddPair(key, value Expression) {
+	d.Keys = append(d.Keys, key)
+	d.Values = append(d.Values, value)
+}
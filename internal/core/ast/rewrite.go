@@ -0,0 +1,394 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// ApplyFunc is called by Apply for each node, with a cursor on it
+type ApplyFunc func(*Cursor) bool
+
+// Cursor describes a node met by Apply: the node, its ancestors, and the
+// field of its parent holding it, through which the node can be replaced,
+// deleted, or given siblings. A cursor is only valid during the call it is
+// passed to.
+type Cursor struct {
+	node      Node
+	ancestors NodeStack
+	deleted   bool
+
+	// replace and remove update the field holding the node; remove is nil
+	// when the field cannot be left without a node, and insert when the
+	// node is not in a list
+	replace func(Node)
+	remove  func()
+	insert  func(node Node, before bool)
+}
+
+// Node returns the current node, or nil once it is deleted
+func (c *Cursor) Node() Node {
+	if c.deleted {
+		return nil
+	}
+	return c.node
+}
+
+// Parent returns the parent of the current node, or nil at the root
+func (c *Cursor) Parent() Node {
+	return c.ancestors.Parent()
+}
+
+// Ancestors returns the ancestors of the current node, outermost first
+func (c *Cursor) Ancestors() NodeStack {
+	return c.ancestors
+}
+
+// Replace puts node in place of the current node. The children of node are
+// the ones visited when it replaces the node before them.
+func (c *Cursor) Replace(node Node) {
+	c.check("replace")
+	if isNil(node) {
+		panic("ast: Replace with a nil node, use Delete")
+	}
+	c.replace(node)
+	c.node = node
+}
+
+// Delete removes the current node from its list, or clears the optional
+// field holding it; it panics for the nodes a parent cannot do without. A
+// node deleted before its children is not visited further.
+func (c *Cursor) Delete() {
+	c.check("delete")
+	if c.remove == nil {
+		panic(fmt.Sprintf("ast: cannot delete the %T held by %T", c.node, c.Parent()))
+	}
+	c.remove()
+	c.deleted = true
+}
+
+// InsertBefore inserts node before the current node in its list; Apply
+// does not visit node
+func (c *Cursor) InsertBefore(node Node) {
+	c.checkInsert(node)
+	c.insert(node, true)
+}
+
+// InsertAfter inserts node after the current node in its list; Apply does
+// not visit node
+func (c *Cursor) InsertAfter(node Node) {
+	c.checkInsert(node)
+	c.insert(node, false)
+}
+
+func (c *Cursor) check(action string) {
+	if c.deleted {
+		panic(fmt.Sprintf("ast: cannot %s a deleted node", action))
+	}
+}
+
+func (c *Cursor) checkInsert(node Node) {
+	c.check("insert next to")
+	if c.insert == nil {
+		panic(fmt.Sprintf("ast: the %T held by %T is not in a list", c.node, c.Parent()))
+	}
+	if isNil(node) {
+		panic("ast: cannot insert a nil node")
+	}
+}
+
+// Apply traverses root in the order of Walk, also visiting the branches of
+// match statements, and returns it rewritten. For each node, pre is called
+// before the children and post after them, either being nil to skip it.
+// When pre returns false, the children and post are skipped; when post
+// returns false, the traversal stops. The returned node is root, or what
+// replaced it, or nil when it was deleted.
+//
+// Deleting the condition of an elif deletes its branch, and deleting a key
+// or a value of a dictionary deletes the entry, but the value of an entry
+// of a dictionary pattern is optional. The entries of a dictionary and the
+// elif branches cannot be inserted. Applied to a tree, Apply updates its
+// Classes after the rewrite.
+func Apply(root Node, pre, post ApplyFunc) Node {
+	a := &applier{pre: pre, post: post}
+	c := &Cursor{node: root}
+	c.replace = func(node Node) { root = node }
+	c.remove = func() { root = nil }
+	a.apply(c)
+	return root
+}
+
+// Rewrite replaces each node within node by what f returns for it, once
+// its children are rewritten, and returns node rewritten. When f returns
+// nil, the node is deleted, as with Cursor.Delete.
+func Rewrite(node Node, f func(Node) Node) Node {
+	return Apply(node, nil, func(c *Cursor) bool {
+		switch replacement := f(c.Node()); {
+		case isNil(replacement):
+			c.Delete()
+		case replacement != c.Node():
+			c.Replace(replacement)
+		}
+		return true
+	})
+}
+
+type applier struct {
+	pre, post ApplyFunc
+	stopped   bool
+}
+
+// apply visits the node of c and its children, and reports whether the
+// node was deleted
+func (a *applier) apply(c *Cursor) bool {
+	if a.pre != nil && !a.pre(c) || c.deleted {
+		return c.deleted
+	}
+	a.children(c.node, c.ancestors.push(c.node))
+	if !a.stopped && a.post != nil && !a.post(c) {
+		a.stopped = true
+	}
+	return c.deleted
+}
+
+// children applies to the children of node, whose ancestors are stack
+func (a *applier) children(node Node, stack NodeStack) {
+	switch n := node.(type) {
+	case *AbstractSyntaxTree:
+		var others []*Class
+		for _, class := range n.Classes {
+			if class != n.RootClass && !n.RootClass.HasSubClass(class) {
+				others = append(others, class)
+			}
+		}
+		applyField(a, stack, &n.RootClass, false)
+		applyList(a, stack, &others)
+		applyList(a, stack, &n.Functions)
+		// Classes lists the root and its sub-classes, which may have changed
+		n.Classes = make([]*Class, 0, len(n.Classes))
+		if n.RootClass != nil {
+			n.Classes = append(append(n.Classes, n.RootClass), n.RootClass.SubClasses...)
+		}
+		n.Classes = append(n.Classes, others...)
+
+	case *Class:
+		applyList(a, stack, &n.Annotations)
+		applyList(a, stack, &n.Statements)
+		applyList(a, stack, &n.Functions)
+		applyList(a, stack, &n.SubClasses)
+
+	case *Function:
+		applyList(a, stack, &n.Annotations)
+		applyList(a, stack, &n.Parameters)
+		applyList(a, stack, &n.Statements)
+
+	case *Parameter:
+		applyField(a, stack, &n.Default, true)
+
+	case *Annotation:
+		applyList(a, stack, &n.Args)
+
+	case *AssertStatement:
+		applyField(a, stack, &n.Condition, false)
+		applyField(a, stack, &n.Message, true)
+
+	case *ReturnStatement:
+		applyField(a, stack, &n.Value, true)
+
+	case *ExpressionStatement:
+		applyField(a, stack, &n.Expression, false)
+
+	case *VarStatement:
+		applyList(a, stack, &n.Annotations)
+		applyField(a, stack, &n.Value, true)
+		n.updateKind()
+
+	case *SignalStatement:
+		applyList(a, stack, &n.Annotations)
+		applyList(a, stack, &n.Parameters)
+
+	case *EnumStatement:
+		applyList(a, stack, &n.Annotations)
+		applyList(a, stack, &n.Elements)
+
+	case *EnumElement:
+		applyField(a, stack, &n.Value, true)
+
+	case *IfStatement:
+		applyField(a, stack, &n.Condition, false)
+		applyList(a, stack, &n.Consequence)
+		for i := 0; i < len(n.ElseCondition) && !a.stopped; {
+			index := i
+			c := &Cursor{node: n.ElseCondition[i], ancestors: stack}
+			c.replace = func(node Node) { n.ElseCondition[index] = convert[Expression](node) }
+			c.remove = func() {
+				n.ElseCondition = slices.Delete(n.ElseCondition, index, index+1)
+				n.ElseBranches = slices.Delete(n.ElseBranches, index, index+1)
+				if index < len(n.ElifPos) {
+					n.ElifPos = slices.Delete(n.ElifPos, index, index+1)
+				}
+			}
+			if a.apply(c) {
+				continue
+			}
+			applyList(a, stack, &n.ElseBranches[i])
+			i++
+		}
+		applyList(a, stack, &n.Alternative)
+
+	case *ForStatement:
+		applyField(a, stack, &n.Collection, false)
+		applyList(a, stack, &n.Body)
+
+	case *WhileStatement:
+		applyField(a, stack, &n.Condition, false)
+		applyList(a, stack, &n.Body)
+
+	case *MatchStatement:
+		applyField(a, stack, &n.Value, false)
+		applyList(a, stack, &n.Branches)
+
+	case *MatchBranch:
+		applyList(a, stack, &n.Patterns)
+		applyField(a, stack, &n.Guard, true)
+		applyList(a, stack, &n.Body)
+		// Pattern repeats the first of the patterns
+		n.Pattern = nil
+		if len(n.Patterns) > 0 {
+			n.Pattern = n.Patterns[0]
+		}
+		n.IsGuarded = n.Guard != nil
+
+	case *ArrayPattern:
+		applyList(a, stack, &n.Elements)
+
+	case *DictionaryPattern:
+		applyEntries(a, stack, &n.Keys, &n.Values, true)
+
+	case *ArrayLiteral:
+		applyList(a, stack, &n.Elements)
+
+	case *DictionaryLiteral:
+		applyEntries(a, stack, &n.Keys, &n.Values, false)
+
+	case *PrefixExpression:
+		applyField(a, stack, &n.Right, false)
+
+	case *AwaitExpression:
+		applyField(a, stack, &n.Operand, false)
+
+	case *InfixExpression:
+		applyField(a, stack, &n.Left, false)
+		applyField(a, stack, &n.Right, false)
+
+	case *CallExpression:
+		applyField(a, stack, &n.Function, false)
+		applyList(a, stack, &n.Arguments)
+
+	case *IndexExpression:
+		applyField(a, stack, &n.Left, false)
+		applyField(a, stack, &n.Index, false)
+
+	case *DotExpression:
+		applyField(a, stack, &n.Left, false)
+
+	case *AssignmentExpression:
+		applyField(a, stack, &n.Left, false)
+		applyField(a, stack, &n.Right, false)
+
+	case *ConditionalExpression:
+		applyField(a, stack, &n.ValueIfTrue, false)
+		applyField(a, stack, &n.Condition, false)
+		applyField(a, stack, &n.ValueIfFalse, false)
+	}
+}
+
+// applyField applies to the node held by field, if any, which can be
+// deleted when optional
+func applyField[T Node](a *applier, stack NodeStack, field *T, optional bool) {
+	if a.stopped || isNil(*field) {
+		return
+	}
+	c := &Cursor{node: *field, ancestors: stack}
+	c.replace = func(node Node) { *field = convert[T](node) }
+	if optional {
+		c.remove = func() {
+			var zero T
+			*field = zero
+		}
+	}
+	a.apply(c)
+}
+
+// applyList applies to each node of list, skipping the nodes inserted in it
+func applyList[T Node](a *applier, stack NodeStack, list *[]T) {
+	for i := 0; i < len(*list) && !a.stopped; {
+		index, inserted := i, 0
+		c := &Cursor{node: (*list)[i], ancestors: stack}
+		c.replace = func(node Node) { (*list)[index] = convert[T](node) }
+		c.remove = func() { *list = slices.Delete(*list, index, index+1) }
+		c.insert = func(node Node, before bool) {
+			if before {
+				*list = slices.Insert(*list, index, convert[T](node))
+				index++
+				return
+			}
+			*list = slices.Insert(*list, index+1, convert[T](node))
+			inserted++
+		}
+		deleted := a.apply(c)
+		i = index + inserted + 1
+		if deleted {
+			i--
+		}
+	}
+}
+
+// applyEntries applies to the entries of a dictionary, each key followed by
+// its value; deleting a key deletes the entry, and so does deleting a value
+// unless the values are optional
+func applyEntries(a *applier, stack NodeStack, keys, values *[]Expression, optional bool) {
+	for i := 0; i < len(*keys) && !a.stopped; {
+		index := i
+		remove := func() {
+			*keys = slices.Delete(*keys, index, index+1)
+			*values = slices.Delete(*values, index, index+1)
+		}
+		c := &Cursor{node: (*keys)[i], ancestors: stack, remove: remove}
+		c.replace = func(node Node) { (*keys)[index] = convert[Expression](node) }
+		if a.apply(c) {
+			continue
+		}
+		i++
+		if a.stopped || (*values)[index] == nil {
+			continue
+		}
+		c = &Cursor{node: (*values)[index], ancestors: stack, remove: remove}
+		c.replace = func(node Node) { (*values)[index] = convert[Expression](node) }
+		if optional {
+			c.remove = func() { (*values)[index] = nil }
+		}
+		if a.apply(c) && !optional {
+			i--
+		}
+	}
+}
+
+// convert returns node as the type of the field it is put in, panicking
+// when it does not fit there
+func convert[T Node](node Node) T {
+	converted, ok := node.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast: cannot put %T in place of %v", node, reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	return converted
+}
+
+// isNil reports whether node is nil, or a nil pointer to a node
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
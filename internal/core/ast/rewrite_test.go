@@ -0,0 +1,181 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		rewrite  func(ast.Node) ast.Node
+		expected string
+	}{
+		{
+			name: "replace",
+			code: "func foo(old):\n\tprint(old, {old: [old]})\n",
+			rewrite: func(node ast.Node) ast.Node {
+				if ident, ok := node.(*ast.Identifier); ok && ident.Value == "old" {
+					return ast.NewIdentifier("renamed", ident.Pos)
+				}
+				return node
+			},
+			expected: "func foo(old):\n\tprint(renamed, {renamed: [renamed]})\n",
+		},
+		{
+			name: "delete statements",
+			code: "func foo():\n\tpass\n\tif true:\n\t\tpass\n\t\tprint(1)\n\telif false:\n\t\tprint(2)\n\tpass\n",
+			rewrite: func(node ast.Node) ast.Node {
+				if _, ok := node.(*ast.PassStatement); ok {
+					return nil
+				}
+				return node
+			},
+			expected: "func foo():\n\tif true:\n\t\tprint(1)\n\telif false:\n\t\tprint(2)\n",
+		},
+		{
+			name: "delete optional fields",
+			code: "func foo(a = 1):\n\tvar b = 2\n\treturn 3\n",
+			rewrite: func(node ast.Node) ast.Node {
+				if _, ok := node.(*ast.NumberLiteral); ok {
+					return nil
+				}
+				return node
+			},
+			expected: "func foo(a):\n\tvar b\n\treturn\n",
+		},
+		{
+			name: "delete dictionary entries",
+			code: "func foo():\n\tprint({1: 'a', 'b': 2, 'c': 'd'})\n",
+			rewrite: func(node ast.Node) ast.Node {
+				if _, ok := node.(*ast.NumberLiteral); ok {
+					return nil
+				}
+				return node
+			},
+			expected: "func foo():\n\tprint({'c': 'd'})\n",
+		},
+		{
+			name: "delete elif",
+			code: "func foo(a):\n\tif a == 1:\n\t\tprint(1)\n\telif a == 2:\n\t\tprint(2)\n\telif a == 3:\n\t\tprint(3)\n",
+			rewrite: func(node ast.Node) ast.Node {
+				if infix, ok := node.(*ast.InfixExpression); ok {
+					if number, ok := infix.Right.(*ast.NumberLiteral); ok && number.Original == "2" {
+						return nil
+					}
+				}
+				return node
+			},
+			expected: "func foo(a):\n\tif a == 1:\n\t\tprint(1)\n\telif a == 3:\n\t\tprint(3)\n",
+		},
+		{
+			name: "delete match patterns",
+			code: "func foo(a):\n\tmatch a:\n\t\t1, 2:\n\t\t\tpass\n\t\t3:\n\t\t\tpass\n",
+			rewrite: func(node ast.Node) ast.Node {
+				if number, ok := node.(*ast.NumberLiteral); ok && number.Original == "1" {
+					return nil
+				}
+				if branch, ok := node.(*ast.MatchBranch); ok && branch.Pattern.(*ast.NumberLiteral).Original == "3" {
+					return nil
+				}
+				return node
+			},
+			expected: "func foo(a):\n\tmatch a:\n\t\t2:\n\t\t\tpass\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := parseForCompare(t, tt.code)
+			if rewritten := ast.Rewrite(tree, tt.rewrite); rewritten != tree {
+				t.Fatalf("Expected the tree itself back, got %T", rewritten)
+			}
+			expected := parseForCompare(t, tt.expected)
+			if differences := ast.Compare(tree, expected); len(differences) > 0 {
+				t.Errorf("Expected the rewrite to give %q, differences: %v", tt.expected, differences)
+			}
+		})
+	}
+}
+
+func TestRewriteMatchBranchPattern(t *testing.T) {
+	tree := parseForCompare(t, "func foo(a):\n\tmatch a:\n\t\t1, 2:\n\t\t\tpass\n")
+	ast.Rewrite(tree, func(node ast.Node) ast.Node {
+		if number, ok := node.(*ast.NumberLiteral); ok && number.Original == "1" {
+			return nil
+		}
+		return node
+	})
+
+	match := tree.RootClass.Functions[0].Statements[0].(*ast.MatchStatement)
+	branch := match.Branches[0]
+	if len(branch.Patterns) != 1 || branch.Pattern != branch.Patterns[0] {
+		t.Errorf("Expected Pattern to be the one pattern left, got %v of %v", branch.Pattern, branch.Patterns)
+	}
+}
+
+func TestApplyInsert(t *testing.T) {
+	tree := parseForCompare(t, "func foo():\n\tprint(1)\n\tprint(2)\n")
+
+	var parents []string
+	ast.Apply(tree, func(c *ast.Cursor) bool {
+		stmt, ok := c.Node().(*ast.ExpressionStatement)
+		if !ok {
+			return true
+		}
+		if function, ok := c.Parent().(*ast.Function); ok {
+			parents = append(parents, function.Name)
+		}
+		before := ast.NewExpressionStatement(stmt.Pos, ast.NewIdentifier("before", stmt.Pos))
+		after := ast.NewExpressionStatement(stmt.Pos, ast.NewIdentifier("after", stmt.Pos))
+		c.InsertBefore(before)
+		c.InsertAfter(after)
+		return false
+	}, nil)
+
+	expected := parseForCompare(t, "func foo():\n\tbefore\n\tprint(1)\n\tafter\n\tbefore\n\tprint(2)\n\tafter\n")
+	if differences := ast.Compare(tree, expected); len(differences) > 0 {
+		t.Errorf("Unexpected differences after inserting: %v", differences)
+	}
+	if strings.Join(parents, ",") != "foo,foo" {
+		t.Errorf("Expected the inserted statements not to be visited, got parents %v", parents)
+	}
+}
+
+func TestApplyStop(t *testing.T) {
+	tree := parseForCompare(t, "func foo():\n\tprint(1)\n\tprint(2)\n")
+
+	var numbers []string
+	ast.Apply(tree, nil, func(c *ast.Cursor) bool {
+		if number, ok := c.Node().(*ast.NumberLiteral); ok {
+			numbers = append(numbers, number.Original)
+			return false
+		}
+		return true
+	})
+
+	if strings.Join(numbers, ",") != "1" {
+		t.Errorf("Expected the traversal to stop after the first number, got %v", numbers)
+	}
+}
+
+func TestApplyDeleteRequired(t *testing.T) {
+	tree := parseForCompare(t, "func foo(a):\n\twhile a:\n\t\tpass\n")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected deleting the condition of a while to panic")
+		}
+	}()
+	ast.Apply(tree, func(c *ast.Cursor) bool {
+		if _, ok := c.Parent().(*ast.WhileStatement); ok && c.Node() != nil {
+			if _, ok := c.Node().(*ast.Identifier); ok {
+				c.Delete()
+			}
+		}
+		return true
+	}, nil)
+}
@@ -19,6 +19,15 @@ func (s *BaseStatement) TokenLiteral() string {
 
 func (s *BaseStatement) statementNode() {}
 
+func (s *BaseStatement) base() *BaseStatement {
+	return s
+}
+
+// AddAnnotation adds an annotation to the statement
+func (s *BaseStatement) AddAnnotation(annotation *Annotation) {
+	s.Annotations = append(s.Annotations, annotation)
+}
+
 // PassStatement represents a 'pass' statement
 type PassStatement struct {
 	BaseStatement
@@ -85,6 +94,44 @@ func NewContinueStatement(pos Position) *ContinueStatement {
 	}
 }
 
+// BreakpointStatement represents a 'breakpoint' statement, which pauses the
+// script in the debugger
+type BreakpointStatement struct {
+	BaseStatement
+}
+
+// NewBreakpointStatement creates a new breakpoint statement
+func NewBreakpointStatement(pos Position) *BreakpointStatement {
+	return &BreakpointStatement{
+		BaseStatement: BaseStatement{
+			Pos:         pos,
+			Kind:        "breakpoint_stmt",
+			Annotations: make([]*Annotation, 0),
+		},
+	}
+}
+
+// AssertStatement represents an assert(condition, message) statement. Message
+// is nil when left out.
+type AssertStatement struct {
+	BaseStatement
+	Condition Expression
+	Message   Expression
+}
+
+// NewAssertStatement creates a new assert statement
+func NewAssertStatement(pos Position, condition, message Expression) *AssertStatement {
+	return &AssertStatement{
+		BaseStatement: BaseStatement{
+			Pos:         pos,
+			Kind:        "assert_stmt",
+			Annotations: make([]*Annotation, 0),
+		},
+		Condition: condition,
+		Message:   message,
+	}
+}
+
 // ExpressionStatement represents an expression used as a statement
 type ExpressionStatement struct {
 	BaseStatement
@@ -112,88 +159,79 @@ type VarStatement struct {
 	IsTyped    bool
 	IsConst    bool
 	IsInferred bool
+	// IsClassLevel is set for the members of a class, as opposed to the
+	// variables declared in a function body
+	IsClassLevel bool
+	// IsStatic is set for static variables, shared by the instances of the class
+	IsStatic bool
 }
 
 // NewVarStatement creates a new variable declaration statement
 func NewVarStatement(pos Position, name string, isTyped bool) *VarStatement {
-	kind := "func_var_stmt"
-	if isTyped {
-		kind = "func_var_typed"
-	}
-
-	return &VarStatement{
+	stmt := &VarStatement{
 		BaseStatement: BaseStatement{
 			Pos:         pos,
-			Kind:        kind,
 			Annotations: make([]*Annotation, 0),
 		},
 		Name:    name,
 		IsTyped: isTyped,
 	}
+	stmt.updateKind()
+	return stmt
 }
 
 // SetValue sets the value of the variable
 func (v *VarStatement) SetValue(value Expression) {
 	v.Value = value
-	if v.IsConst {
-		if v.IsTyped {
-			v.Kind = "const_typed_assgnd"
-		} else {
-			v.Kind = "const_assigned"
-		}
-	} else {
-		if v.IsTyped {
-			v.Kind = "func_var_typed_assgnd"
-		} else {
-			v.Kind = "func_var_assigned"
-		}
-	}
+	v.updateKind()
 }
 
 // SetTypeHint sets the type hint of the variable
 func (v *VarStatement) SetTypeHint(typeHint string) {
 	v.TypeHint = typeHint
 	v.IsTyped = true
-	if v.Value != nil {
-		if v.IsConst {
-			v.Kind = "const_typed_assgnd"
-		} else {
-			v.Kind = "func_var_typed_assgnd"
-		}
-	} else {
-		if v.IsConst {
-			v.Kind = "const_typed"
-		} else {
-			v.Kind = "func_var_typed"
-		}
-	}
+	v.updateKind()
 }
 
 // SetConst marks the variable as a constant
 func (v *VarStatement) SetConst() {
 	v.IsConst = true
-	if v.IsTyped {
-		if v.Value != nil {
-			v.Kind = "const_typed_assgnd"
-		} else {
-			v.Kind = "const_typed"
-		}
-	} else {
-		if v.Value != nil {
-			v.Kind = "const_assigned"
-		} else {
-			v.Kind = "const_stmt"
-		}
-	}
+	v.updateKind()
 }
 
 // SetInferred marks the variable as type-inferred (:=)
 func (v *VarStatement) SetInferred() {
 	v.IsInferred = true
 	v.IsTyped = true
+	v.updateKind()
+}
+
+// SetClassLevel marks the variable as a member of a class
+func (v *VarStatement) SetClassLevel() {
+	v.IsClassLevel = true
+	v.updateKind()
+}
+
+// updateKind sets the kind of the statement from the declaration context,
+// whether it is a constant, typed and assigned
+func (v *VarStatement) updateKind() {
+	prefix := "func_var"
 	if v.IsConst {
-		v.Kind = "const_typed_assgnd"
-	} else {
-		v.Kind = "func_var_assigned"
+		prefix = "const"
+	} else if v.IsClassLevel {
+		prefix = "class_var"
+	}
+
+	switch {
+	case v.IsTyped && v.Value != nil:
+		v.Kind = prefix + "_typed_assgnd"
+	case v.IsTyped:
+		v.Kind = prefix + "_typed"
+	case v.Value != nil:
+		v.Kind = prefix + "_assigned"
+	case v.IsConst:
+		v.Kind = "const_stmt"
+	default:
+		v.Kind = prefix + "_stmt"
 	}
 }
@@ -0,0 +1,225 @@
+// Code generated by gen_typed_visitor.go; DO NOT EDIT.
+
+package ast
+
+// TypedVisitor dispatches each walked node to the callback registered for its
+// type, together with the node's ancestors. Nil callbacks are skipped and the
+// traversal always continues into children.
+type TypedVisitor struct {
+	VisitClass               func(node *Class, ancestors NodeStack)
+	VisitFunction            func(node *Function, ancestors NodeStack)
+	VisitParameter           func(node *Parameter, ancestors NodeStack)
+	VisitAnnotation          func(node *Annotation, ancestors NodeStack)
+	VisitPass                func(node *PassStatement, ancestors NodeStack)
+	VisitBreak               func(node *BreakStatement, ancestors NodeStack)
+	VisitContinue            func(node *ContinueStatement, ancestors NodeStack)
+	VisitBreakpoint          func(node *BreakpointStatement, ancestors NodeStack)
+	VisitAssert              func(node *AssertStatement, ancestors NodeStack)
+	VisitReturn              func(node *ReturnStatement, ancestors NodeStack)
+	VisitExpressionStatement func(node *ExpressionStatement, ancestors NodeStack)
+	VisitVar                 func(node *VarStatement, ancestors NodeStack)
+	VisitSignal              func(node *SignalStatement, ancestors NodeStack)
+	VisitEnum                func(node *EnumStatement, ancestors NodeStack)
+	VisitEnumElement         func(node *EnumElement, ancestors NodeStack)
+	VisitIf                  func(node *IfStatement, ancestors NodeStack)
+	VisitFor                 func(node *ForStatement, ancestors NodeStack)
+	VisitWhile               func(node *WhileStatement, ancestors NodeStack)
+	VisitMatch               func(node *MatchStatement, ancestors NodeStack)
+	VisitIdentifier          func(node *Identifier, ancestors NodeStack)
+	VisitStringLiteral       func(node *StringLiteral, ancestors NodeStack)
+	VisitNumberLiteral       func(node *NumberLiteral, ancestors NodeStack)
+	VisitBooleanLiteral      func(node *BooleanLiteral, ancestors NodeStack)
+	VisitNullLiteral         func(node *NullLiteral, ancestors NodeStack)
+	VisitSelf                func(node *SelfExpression, ancestors NodeStack)
+	VisitArrayLiteral        func(node *ArrayLiteral, ancestors NodeStack)
+	VisitDictionaryLiteral   func(node *DictionaryLiteral, ancestors NodeStack)
+	VisitPrefix              func(node *PrefixExpression, ancestors NodeStack)
+	VisitInfix               func(node *InfixExpression, ancestors NodeStack)
+	VisitCall                func(node *CallExpression, ancestors NodeStack)
+	VisitIndex               func(node *IndexExpression, ancestors NodeStack)
+	VisitDot                 func(node *DotExpression, ancestors NodeStack)
+	VisitAssignment          func(node *AssignmentExpression, ancestors NodeStack)
+	VisitConditional         func(node *ConditionalExpression, ancestors NodeStack)
+	VisitAwait               func(node *AwaitExpression, ancestors NodeStack)
+	VisitGetNode             func(node *GetNodeExpression, ancestors NodeStack)
+	VisitWildcardPattern     func(node *WildcardPattern, ancestors NodeStack)
+	VisitBindingPattern      func(node *BindingPattern, ancestors NodeStack)
+	VisitRestPattern         func(node *RestPattern, ancestors NodeStack)
+	VisitArrayPattern        func(node *ArrayPattern, ancestors NodeStack)
+	VisitDictionaryPattern   func(node *DictionaryPattern, ancestors NodeStack)
+}
+
+// Walk traverses the AST starting from node, calling the matching callback for each node
+func (t *TypedVisitor) Walk(node Node) {
+	WalkWithAncestors(node, t.visit)
+}
+
+func (t *TypedVisitor) visit(node Node, ancestors NodeStack) bool {
+	switch n := node.(type) {
+	case *Class:
+		if t.VisitClass != nil {
+			t.VisitClass(n, ancestors)
+		}
+	case *Function:
+		if t.VisitFunction != nil {
+			t.VisitFunction(n, ancestors)
+		}
+	case *Parameter:
+		if t.VisitParameter != nil {
+			t.VisitParameter(n, ancestors)
+		}
+	case *Annotation:
+		if t.VisitAnnotation != nil {
+			t.VisitAnnotation(n, ancestors)
+		}
+	case *PassStatement:
+		if t.VisitPass != nil {
+			t.VisitPass(n, ancestors)
+		}
+	case *BreakStatement:
+		if t.VisitBreak != nil {
+			t.VisitBreak(n, ancestors)
+		}
+	case *ContinueStatement:
+		if t.VisitContinue != nil {
+			t.VisitContinue(n, ancestors)
+		}
+	case *BreakpointStatement:
+		if t.VisitBreakpoint != nil {
+			t.VisitBreakpoint(n, ancestors)
+		}
+	case *AssertStatement:
+		if t.VisitAssert != nil {
+			t.VisitAssert(n, ancestors)
+		}
+	case *ReturnStatement:
+		if t.VisitReturn != nil {
+			t.VisitReturn(n, ancestors)
+		}
+	case *ExpressionStatement:
+		if t.VisitExpressionStatement != nil {
+			t.VisitExpressionStatement(n, ancestors)
+		}
+	case *VarStatement:
+		if t.VisitVar != nil {
+			t.VisitVar(n, ancestors)
+		}
+	case *SignalStatement:
+		if t.VisitSignal != nil {
+			t.VisitSignal(n, ancestors)
+		}
+	case *EnumStatement:
+		if t.VisitEnum != nil {
+			t.VisitEnum(n, ancestors)
+		}
+	case *EnumElement:
+		if t.VisitEnumElement != nil {
+			t.VisitEnumElement(n, ancestors)
+		}
+	case *IfStatement:
+		if t.VisitIf != nil {
+			t.VisitIf(n, ancestors)
+		}
+	case *ForStatement:
+		if t.VisitFor != nil {
+			t.VisitFor(n, ancestors)
+		}
+	case *WhileStatement:
+		if t.VisitWhile != nil {
+			t.VisitWhile(n, ancestors)
+		}
+	case *MatchStatement:
+		if t.VisitMatch != nil {
+			t.VisitMatch(n, ancestors)
+		}
+	case *Identifier:
+		if t.VisitIdentifier != nil {
+			t.VisitIdentifier(n, ancestors)
+		}
+	case *StringLiteral:
+		if t.VisitStringLiteral != nil {
+			t.VisitStringLiteral(n, ancestors)
+		}
+	case *NumberLiteral:
+		if t.VisitNumberLiteral != nil {
+			t.VisitNumberLiteral(n, ancestors)
+		}
+	case *BooleanLiteral:
+		if t.VisitBooleanLiteral != nil {
+			t.VisitBooleanLiteral(n, ancestors)
+		}
+	case *NullLiteral:
+		if t.VisitNullLiteral != nil {
+			t.VisitNullLiteral(n, ancestors)
+		}
+	case *SelfExpression:
+		if t.VisitSelf != nil {
+			t.VisitSelf(n, ancestors)
+		}
+	case *ArrayLiteral:
+		if t.VisitArrayLiteral != nil {
+			t.VisitArrayLiteral(n, ancestors)
+		}
+	case *DictionaryLiteral:
+		if t.VisitDictionaryLiteral != nil {
+			t.VisitDictionaryLiteral(n, ancestors)
+		}
+	case *PrefixExpression:
+		if t.VisitPrefix != nil {
+			t.VisitPrefix(n, ancestors)
+		}
+	case *InfixExpression:
+		if t.VisitInfix != nil {
+			t.VisitInfix(n, ancestors)
+		}
+	case *CallExpression:
+		if t.VisitCall != nil {
+			t.VisitCall(n, ancestors)
+		}
+	case *IndexExpression:
+		if t.VisitIndex != nil {
+			t.VisitIndex(n, ancestors)
+		}
+	case *DotExpression:
+		if t.VisitDot != nil {
+			t.VisitDot(n, ancestors)
+		}
+	case *AssignmentExpression:
+		if t.VisitAssignment != nil {
+			t.VisitAssignment(n, ancestors)
+		}
+	case *ConditionalExpression:
+		if t.VisitConditional != nil {
+			t.VisitConditional(n, ancestors)
+		}
+	case *AwaitExpression:
+		if t.VisitAwait != nil {
+			t.VisitAwait(n, ancestors)
+		}
+	case *GetNodeExpression:
+		if t.VisitGetNode != nil {
+			t.VisitGetNode(n, ancestors)
+		}
+	case *WildcardPattern:
+		if t.VisitWildcardPattern != nil {
+			t.VisitWildcardPattern(n, ancestors)
+		}
+	case *BindingPattern:
+		if t.VisitBindingPattern != nil {
+			t.VisitBindingPattern(n, ancestors)
+		}
+	case *RestPattern:
+		if t.VisitRestPattern != nil {
+			t.VisitRestPattern(n, ancestors)
+		}
+	case *ArrayPattern:
+		if t.VisitArrayPattern != nil {
+			t.VisitArrayPattern(n, ancestors)
+		}
+	case *DictionaryPattern:
+		if t.VisitDictionaryPattern != nil {
+			t.VisitDictionaryPattern(n, ancestors)
+		}
+	}
+	return true
+}
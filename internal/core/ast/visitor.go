@@ -1,5 +1,7 @@
 package ast
 
+//go:generate go run gen_typed_visitor.go
+
 // Visitor is the interface for the visitor pattern
 type Visitor interface {
 	// Visit is called for each node in the AST
@@ -19,8 +21,10 @@ func Walk(v Visitor, node Node) {
 		if n.RootClass != nil {
 			Walk(v, n.RootClass)
 		}
+		// Classes also lists the root's sub-classes; those are reached through
+		// the root so that they are visited once and with the right ancestors
 		for _, class := range n.Classes {
-			if class != n.RootClass {
+			if class != n.RootClass && !n.RootClass.HasSubClass(class) {
 				Walk(v, class)
 			}
 		}
@@ -63,9 +67,15 @@ func Walk(v Visitor, node Node) {
 			Walk(v, arg)
 		}
 
-	case *PassStatement, *BreakStatement, *ContinueStatement:
+	case *PassStatement, *BreakStatement, *ContinueStatement, *BreakpointStatement:
 		// These statements have no children
 
+	case *AssertStatement:
+		Walk(v, n.Condition)
+		if n.Message != nil {
+			Walk(v, n.Message)
+		}
+
 	case *ReturnStatement:
 		if n.Value != nil {
 			Walk(v, n.Value)
@@ -75,6 +85,30 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Expression)
 
 	case *VarStatement:
+		for _, annotation := range n.Annotations {
+			Walk(v, annotation)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *SignalStatement:
+		for _, annotation := range n.Annotations {
+			Walk(v, annotation)
+		}
+		for _, param := range n.Parameters {
+			Walk(v, param)
+		}
+
+	case *EnumStatement:
+		for _, annotation := range n.Annotations {
+			Walk(v, annotation)
+		}
+		for _, element := range n.Elements {
+			Walk(v, element)
+		}
+
+	case *EnumElement:
 		if n.Value != nil {
 			Walk(v, n.Value)
 		}
@@ -109,7 +143,9 @@ func Walk(v Visitor, node Node) {
 	case *MatchStatement:
 		Walk(v, n.Value)
 		for _, branch := range n.Branches {
-			Walk(v, branch.Pattern)
+			for _, pattern := range branch.Patterns {
+				Walk(v, pattern)
+			}
 			if branch.Guard != nil {
 				Walk(v, branch.Guard)
 			}
@@ -118,23 +154,42 @@ func Walk(v Visitor, node Node) {
 			}
 		}
 
-	case *Identifier, *StringLiteral, *NumberLiteral, *BooleanLiteral:
+	case *Identifier, *StringLiteral, *NumberLiteral, *BooleanLiteral, *NullLiteral, *SelfExpression, *GetNodeExpression:
 		// These expressions have no children
 
+	case *WildcardPattern, *BindingPattern, *RestPattern:
+		// These patterns have no children
+
+	case *ArrayPattern:
+		for _, element := range n.Elements {
+			Walk(v, element)
+		}
+
+	case *DictionaryPattern:
+		for i, key := range n.Keys {
+			Walk(v, key)
+			if n.Values[i] != nil {
+				Walk(v, n.Values[i])
+			}
+		}
+
 	case *ArrayLiteral:
 		for _, element := range n.Elements {
 			Walk(v, element)
 		}
 
 	case *DictionaryLiteral:
-		for key, value := range n.Pairs {
+		for i, key := range n.Keys {
 			Walk(v, key)
-			Walk(v, value)
+			Walk(v, n.Values[i])
 		}
 
 	case *PrefixExpression:
 		Walk(v, n.Right)
 
+	case *AwaitExpression:
+		Walk(v, n.Operand)
+
 	case *InfixExpression:
 		Walk(v, n.Left)
 		Walk(v, n.Right)
@@ -155,6 +210,11 @@ func Walk(v Visitor, node Node) {
 	case *AssignmentExpression:
 		Walk(v, n.Left)
 		Walk(v, n.Right)
+
+	case *ConditionalExpression:
+		Walk(v, n.ValueIfTrue)
+		Walk(v, n.Condition)
+		Walk(v, n.ValueIfFalse)
 	}
 }
 
@@ -0,0 +1,104 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestWalkWithAncestors(t *testing.T) {
+	input := `var top = 1
+
+class Inner:
+	var member = 2
+
+	func method():
+		var local = 3
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	type scope struct {
+		function string
+		class    string
+	}
+	scopes := map[string]scope{}
+	visits := map[string]int{}
+
+	ast.WalkWithAncestors(tree, func(node ast.Node, ancestors ast.NodeStack) bool {
+		if class, ok := node.(*ast.Class); ok {
+			visits[class.Name]++
+		}
+		if v, ok := node.(*ast.VarStatement); ok {
+			var s scope
+			if function := ancestors.EnclosingFunction(); function != nil {
+				s.function = function.Name
+			}
+			if class := ancestors.EnclosingClass(); class != nil {
+				s.class = class.Name
+			}
+			scopes[v.Name] = s
+		}
+		return true
+	})
+
+	expected := map[string]scope{
+		"top":    {class: "test.gd"},
+		"member": {class: "Inner"},
+		"local":  {function: "method", class: "Inner"},
+	}
+	for name, want := range expected {
+		if got, ok := scopes[name]; !ok || got != want {
+			t.Errorf("var %s: expected scope %+v, got %+v (found: %v)", name, want, got, ok)
+		}
+	}
+
+	if visits["Inner"] != 1 {
+		t.Errorf("Expected inner class to be visited once, got %d", visits["Inner"])
+	}
+}
+
+func TestTypedVisitor(t *testing.T) {
+	input := `func foo(a):
+	for i in range(a):
+		print(i)
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	var functions, loops, calls []string
+	visitor := &ast.TypedVisitor{
+		VisitFunction: func(node *ast.Function, ancestors ast.NodeStack) {
+			functions = append(functions, node.Name)
+		},
+		VisitFor: func(node *ast.ForStatement, ancestors ast.NodeStack) {
+			if !ancestors.InFunction() {
+				t.Errorf("Expected loop %s to be inside a function", node.Iterator)
+			}
+			loops = append(loops, node.Iterator)
+		},
+		VisitCall: func(node *ast.CallExpression, ancestors ast.NodeStack) {
+			if ident, ok := node.Function.(*ast.Identifier); ok {
+				calls = append(calls, ident.Value)
+			}
+		},
+	}
+	visitor.Walk(tree)
+
+	if len(functions) != 1 || functions[0] != "foo" {
+		t.Errorf("Expected functions [foo], got %v", functions)
+	}
+	if len(loops) != 1 || loops[0] != "i" {
+		t.Errorf("Expected loops [i], got %v", loops)
+	}
+	if len(calls) != 2 || calls[0] != "range" || calls[1] != "print" {
+		t.Errorf("Expected calls [range print], got %v", calls)
+	}
+}
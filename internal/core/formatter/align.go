@@ -0,0 +1,107 @@
+package formatter
+
+import "strings"
+
+// physicalLine is a line of output with the position of its inline comment
+type physicalLine struct {
+	text    string
+	codeEnd int // offset where the code followed by an inline comment ends, or 0
+}
+
+// alignInlineComments starts the inline comments of consecutive lines with
+// the same indentation at a common column, at least INLINE_COMMENT_OFFSET
+// spaces after the longest code of the run. Lines that would then exceed the
+// maximum line length keep their comment where it was and do not count
+// towards the column.
+func alignInlineComments(lines []FormattedLine, context *Context) []FormattedLine {
+	// Lines are split into physical lines, aligned, then joined back
+	var physical []physicalLine
+	var counts []int
+	for _, line := range lines {
+		texts := strings.Split(line.Content, "\n")
+		offset := 0
+		for _, text := range texts {
+			p := physicalLine{text: text}
+			if line.InlineComment > offset && line.InlineComment <= offset+len(text) {
+				p.codeEnd = line.InlineComment - offset
+			}
+			physical = append(physical, p)
+			offset += len(text) + 1
+		}
+		counts = append(counts, len(texts))
+	}
+
+	for start := 0; start < len(physical); {
+		end := start + 1
+		if physical[start].codeEnd > 0 {
+			indent := leadingWhitespace(physical[start].text)
+			for end < len(physical) && physical[end].codeEnd > 0 && leadingWhitespace(physical[end].text) == indent {
+				end++
+			}
+			alignRun(physical[start:end], context)
+		}
+		start = end
+	}
+
+	i := 0
+	for n, count := range counts {
+		var texts []string
+		for _, p := range physical[i : i+count] {
+			texts = append(texts, p.text)
+		}
+		lines[n].Content = strings.Join(texts, "\n")
+		i += count
+	}
+	return lines
+}
+
+// alignRun moves the inline comments of run to a common column
+func alignRun(run []physicalLine, context *Context) {
+	if len(run) < 2 {
+		return
+	}
+	aligned := make([]bool, len(run))
+	for i := range aligned {
+		aligned[i] = true
+	}
+
+	for {
+		// column is where the comments start, in the line length unit
+		column, widest := 0, -1
+		for i, p := range run {
+			if width := context.LineLength(p.text[:p.codeEnd]); aligned[i] && width > column {
+				column, widest = width, i
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		column += INLINE_COMMENT_OFFSET
+
+		fits := true
+		for i, p := range run {
+			comment := strings.TrimLeft(p.text[p.codeEnd:], " ")
+			if aligned[i] && column+context.LineLength(comment) > context.MaxLineLength {
+				fits = false
+			}
+		}
+		if fits {
+			for i := range run {
+				if !aligned[i] {
+					continue
+				}
+				code := run[i].text[:run[i].codeEnd]
+				comment := strings.TrimLeft(run[i].text[run[i].codeEnd:], " ")
+				run[i].text = code + strings.Repeat(" ", column-context.LineLength(code)) + comment
+			}
+			return
+		}
+		// The longest code pushes a comment past the limit: leave it out
+		aligned[widest] = false
+	}
+}
+
+// leadingWhitespace returns the indentation of a line
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
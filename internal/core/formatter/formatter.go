@@ -1,8 +1,12 @@
 package formatter
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 )
@@ -22,8 +26,44 @@ type Config struct {
 	SpacesForIndent  *int // nil means use tabs
 	UseSpaces        bool
 	SingleIndentSize int
+	SplitDotChains   bool // break over-long call chains after each '.'
+	LineLengthMode   LineLengthMode
+	NormalizeStrings bool // quote strings with double quotes unless they contain one
+	// KeepLineContinuations keeps backslash line continuations between binary
+	// operands; otherwise continued lines are joined
+	KeepLineContinuations bool
+	// TrailingCommas decides whether arrays, dictionaries, call arguments and
+	// parameter lists split one element per line end with a comma
+	TrailingCommas TrailingCommaPolicy
+	// AlignInlineComments starts the inline comments of consecutive lines
+	// at a common column
+	AlignInlineComments bool
 }
 
+// TrailingCommaPolicy selects when a list split over several lines ends with a comma
+type TrailingCommaPolicy int
+
+const (
+	// TrailingCommasAlways puts a comma after the last element
+	TrailingCommasAlways TrailingCommaPolicy = iota
+	// TrailingCommasNever leaves the comma out after the last element
+	TrailingCommasNever
+	// TrailingCommasPreserve keeps the comma after the last element where
+	// the source has one
+	TrailingCommasPreserve
+)
+
+// LineLengthMode selects the unit lines are measured in against MaxLineLength
+type LineLengthMode int
+
+const (
+	// LineLengthRunes counts Unicode code points, so a CJK character or an
+	// emoji counts as one, like Python's len()
+	LineLengthRunes LineLengthMode = iota
+	// LineLengthBytes counts UTF-8 encoded bytes
+	LineLengthBytes
+)
+
 // DefaultConfig returns the default formatter configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -31,6 +71,8 @@ func DefaultConfig() *Config {
 		SpacesForIndent:  nil,
 		UseSpaces:        false,
 		SingleIndentSize: TAB_INDENT_SIZE,
+		SplitDotChains:   true,
+		NormalizeStrings: true,
 	}
 }
 
@@ -44,8 +86,16 @@ type Context struct {
 	IndentRegex         *regexp.Regexp
 	StandaloneComments  []string
 	InlineComments      []string
+	// indents caches the indentation string of each level
+	indents []string
 }
 
+// Patterns matching the indentation of a line
+var (
+	spaceIndentRegex = regexp.MustCompile(`^[ ]*`)
+	tabIndentRegex   = regexp.MustCompile(`^[\t]*`)
+)
+
 // NewContext creates a new formatting context
 func NewContext(config *Config) *Context {
 	var singleIndentString string
@@ -53,10 +103,10 @@ func NewContext(config *Config) *Context {
 
 	if config.SpacesForIndent != nil {
 		singleIndentString = strings.Repeat(" ", *config.SpacesForIndent)
-		indentRegex = regexp.MustCompile(`^[ ]*`)
+		indentRegex = spaceIndentRegex
 	} else {
 		singleIndentString = "\t"
-		indentRegex = regexp.MustCompile(`^[\t]*`)
+		indentRegex = tabIndentRegex
 	}
 
 	return &Context{
@@ -70,7 +120,23 @@ func NewContext(config *Config) *Context {
 
 // GetIndent returns the indentation string for the current level
 func (c *Context) GetIndent() string {
-	return strings.Repeat(c.SingleIndentString, c.IndentLevel)
+	return c.indent(c.IndentLevel)
+}
+
+// indent returns the indentation string for level
+func (c *Context) indent(level int) string {
+	for len(c.indents) <= level {
+		c.indents = append(c.indents, strings.Repeat(c.SingleIndentString, len(c.indents)))
+	}
+	return c.indents[level]
+}
+
+// LineLength returns the length of s in the configured line length unit
+func (c *Context) LineLength(s string) int {
+	if c.Config.LineLengthMode == LineLengthBytes {
+		return len(s)
+	}
+	return utf8.RuneCountInString(s)
 }
 
 // IncreaseIndent increases the indentation level
@@ -87,124 +153,574 @@ func (c *Context) DecreaseIndent() {
 
 // FormattedLine represents a formatted line with optional line number
 type FormattedLine struct {
-	LineNumber *int
+	// LineNumber is the source line the statement starting at this line
+	// starts on, including its leading comments and annotations; 0 for lines
+	// that do not start a statement
+	LineNumber int
 	Content    string
+	Level      int  // indentation level the line was emitted at
+	Definition bool // line opens a function or class definition
+	// InlineComment is the offset in Content where the code followed by an
+	// inline comment ends, or 0 when there is none
+	InlineComment int
+	// codeStart is the offset in Content after the annotations written on
+	// lines of their own above the statement
+	codeStart int
+	// endsDefinition reports whether the lines following this one step out
+	// of a definition's body: a definition's header line, or a copied region
+	// whose last statement is a definition
+	endsDefinition bool
 }
 
-// FormatCode formats GDScript code using the provided AST
-func FormatCode(ast *ast.AbstractSyntaxTree, config *Config) (string, error) {
+// InternalError reports a failure of the formatter itself on a tree
+type InternalError struct {
+	// Pos is the position of the statement being formatted
+	Pos   ast.Position
+	Cause any
+}
+
+func (e *InternalError) Error() string {
+	return fmt.Sprintf("internal formatter error at line %d, column %d: %v", e.Pos.Line, e.Pos.Column, e.Cause)
+}
+
+// FormatCode formats GDScript code using the provided AST. The code ends with
+// exactly one newline, unless it is empty. A failure of the formatter is
+// returned as an *InternalError.
+func FormatCode(tree *ast.AbstractSyntaxTree, config *Config) (code string, err error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
 
-	context := NewContext(config)
-	formatter := &Formatter{context: context}
-
-	lines := formatter.FormatAST(ast)
+	lines, err := formatLines(tree, config, false)
+	if err != nil {
+		return "", err
+	}
 
-	// Join lines with newlines
+	// Every line ends with a newline, the last one included
+	size := 0
+	for _, line := range lines {
+		size += len(line.Content) + 1
+	}
 	var result strings.Builder
-	for i, line := range lines {
-		if i > 0 {
-			result.WriteString("\n")
-		}
+	result.Grow(size)
+	for _, line := range lines {
 		result.WriteString(line.Content)
+		result.WriteString("\n")
 	}
 
 	return result.String(), nil
 }
 
+// formatLines formats tree into lines without trailing blank lines, with the
+// members of classes in source order when sourceOrder is set. A failure of
+// the formatter is returned as an *InternalError.
+func formatLines(tree *ast.AbstractSyntaxTree, config *Config, sourceOrder bool) (lines []FormattedLine, err error) {
+	context := NewContext(config)
+	formatter := &Formatter{context: context, sourceOrder: sourceOrder}
+
+	defer func() {
+		if r := recover(); r != nil {
+			lines, err = nil, &InternalError{Pos: formatter.current, Cause: r}
+		}
+	}()
+	lines = formatter.FormatAST(tree)
+	if config.AlignInlineComments {
+		lines = alignInlineComments(lines, context)
+	}
+
+	// Blank lines at the end of the file are dropped
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1].Content) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
+
+// FormatExpression formats a single expression the way FormatCode writes it
+// within a line
+func FormatExpression(expr ast.Expression, config *Config) string {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	formatter := &Formatter{context: NewContext(config)}
+	return formatter.formatExpression(expr)
+}
+
 // Formatter implements the visitor pattern for formatting
 type Formatter struct {
-	context *Context
-	lines   []FormattedLine
+	context       *Context
+	lines         []FormattedLine
+	comments      *ast.CommentMap
+	blankLines    []int
+	continuations []int
+	// unformatted holds the gdformat: off regions containing statements,
+	// which are copied from the source instead of formatted
+	unformatted []ast.SourceRegion
+	copied      map[int]int // line emitted for each region copied
+	// inSourceOrder keeps the members of classes in source order, for trees
+	// with gdformat: off regions: moving members would move the comments
+	// bounding the regions, which would then pair differently
+	inSourceOrder bool
+	// sourceOrder keeps the members of classes in source order in every tree
+	sourceOrder bool
+	// current is the position of the statement being formatted
+	current ast.Position
 }
 
 // FormatAST formats the entire AST
 func (f *Formatter) FormatAST(node *ast.AbstractSyntaxTree) []FormattedLine {
 	f.lines = []FormattedLine{}
+	f.unformatted = f.regionsWithStatements(node)
+	f.inSourceOrder = f.sourceOrder || len(node.Unformatted) > 0
+	f.copied = make(map[int]int)
+	// Comments within unformatted regions are copied along with them
+	tree := *node
+	tree.Comments = nil
+	for _, comment := range node.Comments {
+		if f.unformattedRegion(comment.Pos.Line) < 0 {
+			tree.Comments = append(tree.Comments, comment)
+		}
+	}
+	f.comments = ast.NewCommentMap(&tree)
+	f.blankLines = node.BlankLines
+	f.continuations = node.LineContinuations
 	f.visitAST(node)
+	for _, comment := range f.comments.Trailing {
+		f.addLine(comment.Text)
+	}
+	f.lines = normalizeBlankLines(f.lines)
 	return f.lines
 }
 
+// regionsWithStatements returns the unformatted regions of tree in which a
+// statement starts. Other regions hold nothing but comments and blank lines,
+// which are formatted as usual, and so are regions that do not hold whole
+// statements of a single block: those starting statements of different
+// blocks, starting within the block before their statements, or ending
+// within a statement they start, as copying them would drop or move code. Regions indented unlike the formatted code would not
+// parse once copied, and are formatted too.
+func (f *Formatter) regionsWithStatements(tree *ast.AbstractSyntaxTree) []ast.SourceRegion {
+	var regions []ast.SourceRegion
+	for _, region := range tree.Unformatted {
+		var statements []ast.Node
+		var parent ast.Node
+		depth := 0
+		cut := false
+		ast.WalkWithAncestors(tree, func(node ast.Node, ancestors ast.NodeStack) bool {
+			if _, ok := node.(ast.Statement); ok && node != tree.RootClass && region.Contains(node.Position().Line) {
+				cut = cut || lastLine(node) > region.EndLine || (statements != nil && ancestors.Parent() != parent)
+				if statements == nil {
+					// The tree and its root class enclose the statements of the script
+					parent, depth = ancestors.Parent(), len(ancestors)-2
+				}
+				statements = append(statements, node)
+				return false
+			}
+			return !cut
+		})
+		if statements == nil || cut {
+			continue
+		}
+		first := statements[0].Position()
+		// A directive indented deeper than the statements is in the block
+		// before them, which the region would then start within
+		if directive := standaloneComment(tree, region.StartLine); directive != nil && directive.Pos.Column > first.Column {
+			continue
+		}
+
+		// A last comment indented deeper than the statements ends the region
+		// within the block of the last of them, while the comments and blank
+		// lines ending it less indented are formatted with the code after it
+		lines := strings.Split(region.Text, "\n")
+		for end := region.EndLine; end > first.Line; end-- {
+			comment := standaloneComment(tree, end)
+			if comment != nil && comment.Pos.Column > first.Column && end == region.EndLine {
+				cut = true
+			}
+			if strings.TrimSpace(lines[end-region.StartLine]) != "" && (comment == nil || comment.Pos.Column >= first.Column) {
+				break
+			}
+			region.EndLine = end - 1
+			region.Text = strings.Join(lines[:end-region.StartLine], "\n")
+		}
+
+		unit := f.context.SingleIndentString
+		for _, stmt := range statements {
+			ast.Inspect(stmt, func(node ast.Node) bool {
+				if _, ok := node.(ast.Statement); ok {
+					line := lines[node.Position().Line-region.StartLine]
+					indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+					cut = cut || strings.Trim(indent, unit[:1]) != "" || len(indent)%len(unit) != 0 ||
+						(node == stmt && indent != strings.Repeat(unit, depth))
+				}
+				return !cut
+			})
+		}
+		if !cut {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// standaloneComment returns the comment of tree on a line of its own at line,
+// or nil
+func standaloneComment(tree *ast.AbstractSyntaxTree, line int) *ast.Comment {
+	for _, comment := range tree.Comments {
+		if comment.Pos.Line == line && !comment.Inline {
+			return comment
+		}
+	}
+	return nil
+}
+
+// unformattedRegion returns the index of the unformatted region containing
+// line, or -1
+func (f *Formatter) unformattedRegion(line int) int {
+	for i, region := range f.unformatted {
+		if region.Contains(line) {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyUnformatted emits the source of the unformatted region stmt starts in,
+// the first time one of its statements is visited, and reports whether stmt
+// is in such a region. The comments of stmt outside the region, before or
+// after it, are emitted around it as usual.
+func (f *Formatter) copyUnformatted(stmt ast.Statement) bool {
+	i := f.unformattedRegion(stmt.Position().Line)
+	if i < 0 {
+		return false
+	}
+	_, isDefinition := stmt.(*ast.Function)
+	if _, ok := stmt.(*ast.Class); ok {
+		isDefinition = true
+	}
+	if line, ok := f.copied[i]; ok {
+		f.lines[line].endsDefinition = isDefinition
+	} else {
+		f.copied[i] = len(f.lines)
+		// The comments go in the region's line to keep with it, as
+		// attachComments does
+		text := f.unformatted[i].Text
+		leading := f.comments.Leading[stmt]
+		for j := len(leading) - 1; j >= 0; j-- {
+			text = f.context.GetIndent() + leading[j].Text + "\n" + text
+		}
+		f.addLine(text)
+		// The region is spaced from its neighbours like its first and last
+		// statements would be once formatted
+		f.lines[len(f.lines)-1].Definition = isDefinition
+		f.lines[len(f.lines)-1].endsDefinition = isDefinition
+	}
+	f.addNestedCommentsAfter(stmt)
+	f.addCommentsAfter(stmt)
+	return true
+}
+
+// addNestedCommentsAfter emits the comments closing the blocks nested in
+// block, a copied statement or one of its match branches, which are past the region when its end is not indented
+// for them
+func (f *Formatter) addNestedCommentsAfter(block ast.Node) {
+	ast.Inspect(block, func(node ast.Node) bool {
+		_, isBranch := node.(*ast.MatchBranch)
+		if _, ok := node.(ast.Statement); !ok && !isBranch || node == block {
+			return true
+		}
+		f.context.IncreaseIndent()
+		f.addNestedCommentsAfter(node)
+		f.addCommentsAfter(node)
+		f.context.DecreaseIndent()
+		return false
+	})
+}
+
 // addLine adds a formatted line
 func (f *Formatter) addLine(content string) {
 	f.lines = append(f.lines, FormattedLine{
 		Content: content,
+		Level:   f.context.IndentLevel,
 	})
 }
 
+// addDefinitionLine adds the header line of a function or class definition
+func (f *Formatter) addDefinitionLine(content string) {
+	f.addLine(content)
+	f.lines[len(f.lines)-1].Definition = true
+	f.lines[len(f.lines)-1].endsDefinition = true
+}
+
 // addEmptyLine adds an empty line
 func (f *Formatter) addEmptyLine() {
 	f.addLine("")
 }
 
+// attachComments adds the comments of node to the first line emitted for it:
+// leading comments go above it at the current indentation, inline comments
+// at the end of its first physical line below the annotations written above
+// it, or above it too when a string spans its lines. Keeping them in the same
+// FormattedLine moves them together with the node under blank line normalization.
+func (f *Formatter) attachComments(node ast.Node, start int) {
+	leading, inline := f.comments.Leading[node], f.comments.Inline[node]
+	if len(leading) == 0 && len(inline) == 0 {
+		return
+	}
+	if start >= len(f.lines) {
+		// Nothing was emitted for the node; keep its comments in place anyway
+		for _, comment := range append(leading, inline...) {
+			f.addLine(f.context.GetIndent() + comment.Text)
+		}
+		return
+	}
+	line := &f.lines[start]
+	if len(inline) > 0 && holdsMultilineString(node) {
+		// The end of the first line may be inside the string
+		leading, inline = append(leading[:len(leading):len(leading)], inline...), nil
+	}
+
+	codeEnd := 0
+	if len(inline) > 0 {
+		var texts []string
+		for _, comment := range inline {
+			texts = append(texts, comment.Text)
+		}
+		annotations := line.Content[:line.codeStart]
+		first, rest, multiline := strings.Cut(line.Content[line.codeStart:], "\n")
+		codeEnd = len(annotations) + len(first)
+		first += strings.Repeat(" ", INLINE_COMMENT_OFFSET) + strings.Join(texts, " ")
+		if multiline {
+			first += "\n" + rest
+		}
+		line.Content = annotations + first
+	}
+
+	indent := f.context.GetIndent()
+	length := len(line.Content)
+	for i := len(leading) - 1; i >= 0; i-- {
+		line.Content = indent + leading[i].Text + "\n" + line.Content
+	}
+	if codeEnd > 0 {
+		line.InlineComment = len(line.Content) - length + codeEnd
+	}
+}
+
+// attachClauseComments adds the comments of the elif or else header whose
+// keyword is at pos to the line just emitted for it
+func (f *Formatter) attachClauseComments(pos ast.Position) {
+	if clause := f.comments.Clauses[pos]; clause != nil {
+		f.attachComments(clause, len(f.lines)-1)
+	}
+}
+
+// holdsMultilineString reports whether a string literal of node, outside
+// the statements nested in it, spans several lines
+func holdsMultilineString(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if _, ok := n.(ast.Statement); ok && n != node {
+			return false
+		}
+		if str, ok := n.(*ast.StringLiteral); ok && strings.Contains(str.Raw, "\n") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// addCommentsAfter emits the comments closing the block of node, at the node's indentation
+func (f *Formatter) addCommentsAfter(node ast.Node) {
+	for _, comment := range f.comments.After[node] {
+		f.addLine(f.context.GetIndent() + comment.Text)
+	}
+}
+
+// normalizeBlankLines applies gdformat's blank line policy: definitions are
+// separated from their neighbours by two blank lines at the top level and one
+// inside classes, blocks never start with a blank line, other runs of blank
+// lines collapse to one, and leading and trailing blank lines are dropped.
+func normalizeBlankLines(lines []FormattedLine) []FormattedLine {
+	result := make([]FormattedLine, 0, len(lines))
+	// lastWasDefinition[level] reports whether the most recent line at that
+	// level opened a definition, i.e. whether we are stepping out of its body
+	var lastWasDefinition []bool
+	pendingBlanks := 0
+
+	for _, line := range lines {
+		if strings.TrimSpace(line.Content) == "" {
+			pendingBlanks++
+			continue
+		}
+
+		for len(lastWasDefinition) <= line.Level {
+			lastWasDefinition = append(lastWasDefinition, false)
+		}
+
+		blanks := 0
+		if len(result) > 0 && result[len(result)-1].Level >= line.Level {
+			if line.Definition || lastWasDefinition[line.Level] {
+				blanks = definitionSpacing(line.Level)
+			} else if pendingBlanks > 0 {
+				blanks = 1
+			}
+		}
+
+		for i := 0; i < blanks; i++ {
+			result = append(result, FormattedLine{Level: line.Level})
+		}
+		result = append(result, line)
+
+		lastWasDefinition[line.Level] = line.endsDefinition
+		lastWasDefinition = lastWasDefinition[:line.Level+1]
+		pendingBlanks = 0
+	}
+
+	return result
+}
+
+// definitionSpacing returns the number of blank lines surrounding a definition at the given level
+func definitionSpacing(level int) int {
+	if level == 0 {
+		return 2
+	}
+	return 1
+}
+
 // visitAST visits the root AST node
 func (f *Formatter) visitAST(node *ast.AbstractSyntaxTree) {
-	// Check if root class is just a wrapper (has default name from parser)
-	hasRealRootClass := node.RootClass != nil && node.RootClass.Name != "" && node.RootClass.Name != "test.gd"
-
-	// Format real root class if present
-	if hasRealRootClass {
-		f.visitClass(node.RootClass)
-	} else if node.RootClass != nil {
-		// If root class is just a wrapper, format its contents directly
-		f.visitClassContents(node.RootClass)
+	// The root class is the script itself (named after the file), so only its
+	// contents are formatted
+	if node.RootClass != nil {
+		if header := f.formatScriptHeader(node.RootClass); header != "" {
+			f.addLine(header)
+			f.attachComments(node.RootClass, len(f.lines)-1)
+		}
+		f.visitClassContents(node.RootClass, headerEnd(node.RootClass))
 	}
 
-	// Format other classes with proper spacing
-	previousWasClass := hasRealRootClass
+	// Format other classes; the parser also lists the root's sub-classes here,
+	// and those have already been emitted with the root's contents
 	for _, class := range node.Classes {
-		if class != node.RootClass {
-			if previousWasClass {
-				f.addEmptyLine()
-				f.addEmptyLine()
-			}
-			f.visitClass(class)
-			previousWasClass = true
+		if class != node.RootClass && !node.RootClass.HasSubClass(class) {
+			f.visitStatement(class)
 		}
 	}
 
 	// Format top-level functions
 	for _, function := range node.Functions {
-		if previousWasClass {
-			f.addEmptyLine()
-			f.addEmptyLine()
-		}
-		f.visitFunction(function)
-		previousWasClass = false
+		f.visitStatement(function)
 	}
 }
 
-// visitClassContents formats the contents of a class without the class declaration
-func (f *Formatter) visitClassContents(node *ast.Class) {
-	// Format statements
-	previousWasStatement := false
-	for _, stmt := range node.Statements {
-		if previousWasStatement {
-			// Add spacing between different types of statements
-		}
-		f.visitStatement(stmt)
-		previousWasStatement = true
+// formatScriptHeader formats the script annotations, each on its own line,
+// followed by class_name and extends. Those two share a line when they do in
+// the source, and otherwise keep their order.
+func (f *Formatter) formatScriptHeader(root *ast.Class) string {
+	var lines []string
+	for _, annotation := range root.Annotations {
+		lines = append(lines, f.formatAnnotation(annotation, ""))
 	}
 
-	// Format functions with proper spacing
-	for _, function := range node.Functions {
-		if previousWasStatement {
-			f.addEmptyLine()
+	className := "class_name " + root.ClassName
+	extends := "extends " + root.Extends
+	switch {
+	case root.ClassName != "" && root.Extends != "":
+		if root.ClassNamePos.Line == root.ExtendsPos.Line {
+			lines = append(lines, className+" "+extends)
+		} else if root.ExtendsPos.Line < root.ClassNamePos.Line {
+			lines = append(lines, extends, className)
+		} else {
+			lines = append(lines, className, extends)
 		}
-		f.visitFunction(function)
-		previousWasStatement = true
+	case root.ClassName != "":
+		lines = append(lines, className)
+	case root.Extends != "":
+		lines = append(lines, extends)
 	}
+	return strings.Join(lines, "\n")
+}
+
+// headerEnd returns the last line of the script header of root, 0 when it
+// has none
+func headerEnd(root *ast.Class) int {
+	end := max(root.ClassNamePos.Line, root.ExtendsPos.Line)
+	for _, annotation := range root.Annotations {
+		end = max(end, lastLine(annotation))
+	}
+	return end
+}
+
+// standaloneAnnotations are the annotations Python gdformat always puts on
+// a line of their own, even when they precede a statement
+var standaloneAnnotations = map[string]bool{
+	"tool": true, "icon": true, "static_unload": true,
+	"export_category": true, "export_group": true, "export_subgroup": true,
+}
+
+// formatAnnotation formats an annotation with its arguments, if any, on one
+// line when it fits after indent, otherwise with one argument per line
+func (f *Formatter) formatAnnotation(annotation *ast.Annotation, indent string) string {
+	if len(annotation.Args) == 0 {
+		return "@" + annotation.Name
+	}
+	single := "@" + annotation.Name + f.formatArguments(annotation.Args)
+	if f.context.LineLength(indent+single) <= f.context.MaxLineLength {
+		return single
+	}
+	argIndent := indent + f.context.SingleIndentString
+	result := "@" + annotation.Name + "(\n"
+	for _, arg := range annotation.Args {
+		result += argIndent + f.formatExpression(arg) + ",\n"
+	}
+	return result + indent + ")"
+}
 
-	// Format sub-classes
+// visitClassContents formats the contents of a class without the class
+// declaration: its statements, then its functions and inner classes, or all
+// of them in source order when the script has gdformat: off regions. A single
+// blank line is kept wherever the source separates two members that stay
+// next to each other with empty lines, or its first member from headerEnd,
+// the last line of the header emitted before the contents (0 for none).
+func (f *Formatter) visitClassContents(node *ast.Class, headerEnd int) {
+	members := make([]ast.Statement, 0, len(node.Statements)+len(node.Functions)+len(node.SubClasses))
+	members = append(members, node.Statements...)
+	for _, function := range node.Functions {
+		members = append(members, function)
+	}
 	for _, subClass := range node.SubClasses {
-		if previousWasStatement {
-			f.addEmptyLine()
+		members = append(members, subClass)
+	}
+	if f.inSourceOrder {
+		sort.SliceStable(members, func(i, j int) bool {
+			return members[i].Position().Line < members[j].Position().Line
+		})
+	}
+	if len(members) == 0 {
+		return
+	}
+
+	// Blank lines are only kept between members that follow each other both
+	// in the source and in the output
+	inSource := slices.Clone(members)
+	sort.SliceStable(inSource, func(i, j int) bool {
+		return inSource[i].Position().Line < inSource[j].Position().Line
+	})
+	previous := make(map[ast.Statement]ast.Statement, len(members))
+	for i := 1; i < len(inSource); i++ {
+		previous[inSource[i]] = inSource[i-1]
+	}
+	for i, member := range members {
+		blank := false
+		if i > 0 {
+			blank = previous[member] == members[i-1] && f.hasBlankLineBetween(members[i-1], member)
+		} else if headerEnd > 0 {
+			blank = member == inSource[0] && f.hasBlankLineAfter(headerEnd, member)
+		}
+		if blank {
 			f.addEmptyLine()
 		}
-		f.visitClass(subClass)
-		previousWasStatement = true
+		f.visitStatement(member)
 	}
 }
 
@@ -216,46 +732,32 @@ func (f *Formatter) visitClass(node *ast.Class) {
 		classLine += " " + node.Name
 	}
 
-	// Format extends clause
-	if node.Extends != "" {
-		classLine += ":\n" + f.context.GetIndent() + "\textends " + node.Extends
+	// The parent class stays in the header unless the source gives it by an
+	// extends statement in the body
+	extendsStatement := node.Extends != "" && node.ExtendsPos.Line > node.Pos.Line
+	if node.Extends != "" && !extendsStatement {
+		classLine += " extends " + node.Extends
 	}
 	classLine += ":"
 
-	f.addLine(classLine)
+	f.addDefinitionLine(classLine)
 
 	// Increase indentation for class body
 	f.context.IncreaseIndent()
 
+	if extendsStatement {
+		f.addLine(f.context.GetIndent() + "extends " + node.Extends)
+	}
+
 	// Check if class has any content
-	hasContent := len(node.Statements) > 0 || len(node.Functions) > 0 || len(node.SubClasses) > 0
+	hasContent := extendsStatement || len(node.Statements) > 0 || len(node.Functions) > 0 || len(node.SubClasses) > 0
 
 	if !hasContent {
 		f.addLine(f.context.GetIndent() + "pass")
+	} else if extendsStatement {
+		f.visitClassContents(node, node.ExtendsPos.Line)
 	} else {
-		// Format statements
-		for _, stmt := range node.Statements {
-			f.visitStatement(stmt)
-		}
-
-		// Format functions with proper spacing
-		previousWasStatement := len(node.Statements) > 0
-		for _, function := range node.Functions {
-			if previousWasStatement {
-				f.addEmptyLine()
-			}
-			f.visitFunction(function)
-			previousWasStatement = true
-		}
-
-		// Format sub-classes
-		for _, subClass := range node.SubClasses {
-			if previousWasStatement {
-				f.addEmptyLine()
-			}
-			f.visitClass(subClass)
-			previousWasStatement = true
-		}
+		f.visitClassContents(node, 0)
 	}
 
 	// Decrease indentation
@@ -266,31 +768,26 @@ func (f *Formatter) visitClass(node *ast.Class) {
 func (f *Formatter) visitFunction(node *ast.Function) {
 	// Build function signature
 	funcLine := f.context.GetIndent() + "func " + node.Name + "("
+	if node.IsStatic {
+		funcLine = f.context.GetIndent() + "static func " + node.Name + "("
+	}
 
 	// Format parameters
 	if len(node.Parameters) > 0 {
 		// Check if parameters should be multiline
 		paramStr := f.formatParameters(node.Parameters)
-		totalLength := len(funcLine) + len(paramStr) + 1 // +1 for closing paren
+		totalLength := f.context.LineLength(funcLine) + f.context.LineLength(paramStr) + 1 // +1 for closing paren
 		if node.ReturnType != "" {
-			totalLength += len(" -> " + node.ReturnType)
+			totalLength += f.context.LineLength(" -> " + node.ReturnType)
 		}
 
 		if totalLength > f.context.MaxLineLength {
 			// Multi-line parameters
-			funcLine += "\n"
-			f.context.IncreaseIndent()
+			params := make([]string, len(node.Parameters))
 			for i, param := range node.Parameters {
-				paramLine := f.context.GetIndent() + f.formatParameter(param)
-				if i < len(node.Parameters)-1 {
-					paramLine += ","
-				} else {
-					paramLine += ","
-				}
-				funcLine += paramLine + "\n"
+				params[i] = f.formatParameter(param)
 			}
-			f.context.DecreaseIndent()
-			funcLine += f.context.GetIndent() + ")"
+			funcLine = f.splitLines(funcLine, params, ",", f.trailingComma(node.TrailingComma), f.context.IndentLevel, ")")
 		} else {
 			// Single line parameters
 			funcLine += paramStr + ")"
@@ -305,16 +802,14 @@ func (f *Formatter) visitFunction(node *ast.Function) {
 	}
 
 	funcLine += ":"
-	f.addLine(funcLine)
+	f.addDefinitionLine(funcLine)
 
 	// Format function body
 	f.context.IncreaseIndent()
 	if len(node.Statements) == 0 {
 		f.addLine(f.context.GetIndent() + "pass")
 	} else {
-		for _, stmt := range node.Statements {
-			f.visitStatement(stmt)
-		}
+		f.visitBlock(node.Statements)
 	}
 	f.context.DecreaseIndent()
 }
@@ -347,11 +842,26 @@ func (f *Formatter) formatParameter(param *ast.Parameter) string {
 	return result
 }
 
-// visitStatement formats a statement
+// visitStatement formats a statement together with its comments
 func (f *Formatter) visitStatement(stmt ast.Statement) {
+	if stmt != nil {
+		f.current = stmt.Position()
+		if f.copyUnformatted(stmt) {
+			return
+		}
+	}
+	defer f.markSourceLine(stmt, len(f.lines))
+	defer f.attachComments(stmt, len(f.lines))
+	defer f.addCommentsAfter(stmt)
+	defer f.attachAnnotations(stmt, len(f.lines))
+
 	switch s := stmt.(type) {
 	case *ast.VarStatement:
 		f.visitVarStatement(s)
+	case *ast.SignalStatement:
+		f.visitSignalStatement(s)
+	case *ast.EnumStatement:
+		f.visitEnumStatement(s)
 	case *ast.ReturnStatement:
 		f.visitReturnStatement(s)
 	case *ast.ExpressionStatement:
@@ -362,6 +872,10 @@ func (f *Formatter) visitStatement(stmt ast.Statement) {
 		f.addLine(f.context.GetIndent() + "break")
 	case *ast.ContinueStatement:
 		f.addLine(f.context.GetIndent() + "continue")
+	case *ast.BreakpointStatement:
+		f.addLine(f.context.GetIndent() + "breakpoint")
+	case *ast.AssertStatement:
+		f.visitAssertStatement(s)
 	case *ast.IfStatement:
 		f.visitIfStatement(s)
 	case *ast.ForStatement:
@@ -386,12 +900,141 @@ func (f *Formatter) visitStatement(stmt ast.Statement) {
 	}
 }
 
+// markSourceLine records on the first line emitted for stmt, if any, the
+// source line stmt starts on with its leading comments and annotations
+func (f *Formatter) markSourceLine(stmt ast.Statement, start int) {
+	if stmt == nil || start >= len(f.lines) {
+		return
+	}
+	line := stmt.Position().Line
+	for _, annotation := range ast.AnnotationsOf(stmt) {
+		line = min(line, annotation.Pos.Line)
+	}
+	for _, comment := range f.comments.Leading[stmt] {
+		line = min(line, comment.Pos.Line)
+	}
+	f.lines[start].LineNumber = line
+}
+
+// attachAnnotations writes the annotations of stmt into the first line
+// emitted for it: on lines of their own above functions and classes, and
+// before the statement on its line otherwise, as in @export var speed = 10.
+// Standalone annotations, annotations that do not fit on one line and those
+// that would make the line of the statement too long go on lines of their own.
+func (f *Formatter) attachAnnotations(stmt ast.Statement, start int) {
+	annotations := ast.AnnotationsOf(stmt)
+	if len(annotations) == 0 || start >= len(f.lines) {
+		return
+	}
+	indent := f.context.GetIndent()
+	line := &f.lines[start]
+
+	var above, inline []string
+	for _, annotation := range annotations {
+		text := f.formatAnnotation(annotation, indent)
+		if standaloneAnnotations[annotation.Name] || strings.Contains(text, "\n") {
+			above = append(above, text)
+		} else {
+			inline = append(inline, text)
+		}
+	}
+	switch stmt.(type) {
+	case *ast.Function, *ast.Class, *ast.ExpressionStatement:
+		// An expression after an annotation on its line could read as its argument
+		above, inline = append(above, inline...), nil
+	}
+
+	content := strings.TrimPrefix(line.Content, indent)
+	if len(inline) > 0 {
+		joined := indent + strings.Join(inline, " ") + " " + content
+		first, _, _ := strings.Cut(joined, "\n")
+		if f.context.LineLength(first) <= f.context.MaxLineLength {
+			content = strings.TrimPrefix(joined, indent)
+		} else {
+			above = append(above, inline...)
+		}
+	}
+	code := len(content)
+	for i := len(above) - 1; i >= 0; i-- {
+		content = above[i] + "\n" + indent + content
+	}
+	line.Content = indent + content
+	line.codeStart = len(line.Content) - len(indent) - code
+}
+
+// visitBlock formats the statements of a function body or a nested block,
+// keeping a single blank line wherever the source separates two of them with
+// empty lines
+func (f *Formatter) visitBlock(statements []ast.Statement) {
+	for i, stmt := range statements {
+		if i > 0 && f.hasBlankLineBetween(statements[i-1], stmt) {
+			f.addEmptyLine()
+		}
+		f.visitStatement(stmt)
+	}
+}
+
+// hasBlankLineBetween reports whether the source has an empty line after the
+// last line of prev that starts a node and before next, outside of the
+// unformatted regions copied for them
+func (f *Formatter) hasBlankLineBetween(prev, next ast.Statement) bool {
+	end := lastLine(prev)
+	if region := f.unformattedRegion(prev.Position().Line); region >= 0 {
+		end = max(end, f.unformatted[region].EndLine)
+	}
+	return f.hasBlankLineAfter(end, next)
+}
+
+// hasBlankLineAfter reports whether the source has an empty line after line
+// end and before next, outside of the unformatted region copied for next
+func (f *Formatter) hasBlankLineAfter(end int, next ast.Statement) bool {
+	start := next.Position().Line
+	if region := f.unformattedRegion(start); region >= 0 {
+		start = f.unformatted[region].StartLine
+	}
+	i := sort.SearchInts(f.blankLines, end+1)
+	return i < len(f.blankLines) && f.blankLines[i] < start
+}
+
+// hasContinuationBetween reports whether a line ending with a backslash
+// separates the operands left and right
+func (f *Formatter) hasContinuationBetween(left, right ast.Node) bool {
+	i := sort.SearchInts(f.continuations, lastLine(left))
+	return i < len(f.continuations) && f.continuations[i] < firstLine(right)
+}
+
+// lastLine returns the last line on which a node within node starts
+func lastLine(node ast.Node) int {
+	last := node.Position().Line
+	ast.Inspect(node, func(n ast.Node) bool {
+		if line := n.Position().Line; line > last {
+			last = line
+		}
+		return true
+	})
+	return last
+}
+
+// firstLine returns the first line on which a node within node starts
+func firstLine(node ast.Node) int {
+	first := node.Position().Line
+	ast.Inspect(node, func(n ast.Node) bool {
+		if line := n.Position().Line; line > 0 && line < first {
+			first = line
+		}
+		return true
+	})
+	return first
+}
+
 // visitVarStatement formats a variable declaration
 func (f *Formatter) visitVarStatement(stmt *ast.VarStatement) {
 	line := f.context.GetIndent()
 
 	if stmt.IsConst {
 		line += "const " + stmt.Name
+	} else if stmt.IsStatic {
+		line += "static var " + stmt.Name
 	} else {
 		line += "var " + stmt.Name
 	}
@@ -400,28 +1043,81 @@ func (f *Formatter) visitVarStatement(stmt *ast.VarStatement) {
 		line += ": " + stmt.TypeHint
 	}
 
-	if stmt.Value != nil {
-		line += " = " + f.formatExpression(stmt.Value)
+	if stmt.Value != nil && stmt.IsInferred && stmt.TypeHint == "" {
+		line = f.formatWrapped(line+" := ", stmt.Value)
+	} else if stmt.Value != nil {
+		line = f.formatWrapped(line+" = ", stmt.Value)
 	}
 
 	f.addLine(line)
 }
 
+// visitSignalStatement formats a signal declaration
+func (f *Formatter) visitSignalStatement(stmt *ast.SignalStatement) {
+	line := f.context.GetIndent() + "signal " + stmt.Name
+	if len(stmt.Parameters) > 0 {
+		line += "(" + f.formatParameters(stmt.Parameters) + ")"
+	}
+	f.addLine(line)
+}
+
+// visitEnumStatement formats an enum declaration on one line, or with one
+// element per line when it does not fit
+func (f *Formatter) visitEnumStatement(stmt *ast.EnumStatement) {
+	indent := f.context.GetIndent()
+	line := indent + "enum "
+	if stmt.Name != "" {
+		line += stmt.Name + " "
+	}
+
+	var elements []string
+	for _, element := range stmt.Elements {
+		text := element.Name
+		if element.Value != nil {
+			text += " = " + f.formatExpression(element.Value)
+		}
+		elements = append(elements, text)
+	}
+
+	single := line + "{" + strings.Join(elements, ", ") + "}"
+	if len(elements) == 0 || f.context.LineLength(single) <= f.context.MaxLineLength {
+		f.addLine(single)
+		return
+	}
+	f.addLine(f.splitLines(line+"{", elements, ",", true, f.context.IndentLevel, "}"))
+}
+
 // visitReturnStatement formats a return statement
 func (f *Formatter) visitReturnStatement(stmt *ast.ReturnStatement) {
 	line := f.context.GetIndent() + "return"
 	if stmt.Value != nil {
-		line += " " + f.formatExpression(stmt.Value)
+		line = f.formatWrapped(line+" ", stmt.Value)
 	}
 	f.addLine(line)
 }
 
-// visitExpressionStatement formats an expression statement
+// visitExpressionStatement formats an expression statement; the value of an
+// assignment wraps like that of a variable
 func (f *Formatter) visitExpressionStatement(stmt *ast.ExpressionStatement) {
-	line := f.context.GetIndent() + f.formatExpression(stmt.Expression)
+	if assignment, ok := stmt.Expression.(*ast.AssignmentExpression); ok {
+		prefix := f.context.GetIndent() + f.formatExpression(assignment.Left) + " " + assignment.Operator + " "
+		f.addLine(f.formatWrapped(prefix, assignment.Right))
+		return
+	}
+	line := f.formatWrapped(f.context.GetIndent(), stmt.Expression)
 	f.addLine(line)
 }
 
+// visitAssertStatement formats an assert statement like a call of assert
+func (f *Formatter) visitAssertStatement(stmt *ast.AssertStatement) {
+	call := ast.NewCallExpression(ast.NewIdentifier("assert", stmt.Pos), stmt.Pos)
+	call.AddArgument(stmt.Condition)
+	if stmt.Message != nil {
+		call.AddArgument(stmt.Message)
+	}
+	f.addLine(f.formatWrapped(f.context.GetIndent(), call))
+}
+
 // visitIfStatement formats an if statement
 func (f *Formatter) visitIfStatement(stmt *ast.IfStatement) {
 	// Format if condition
@@ -433,9 +1129,7 @@ func (f *Formatter) visitIfStatement(stmt *ast.IfStatement) {
 	if len(stmt.Consequence) == 0 {
 		f.addLine(f.context.GetIndent() + "pass")
 	} else {
-		for _, s := range stmt.Consequence {
-			f.visitStatement(s)
-		}
+		f.visitBlock(stmt.Consequence)
 	}
 	f.context.DecreaseIndent()
 
@@ -443,14 +1137,15 @@ func (f *Formatter) visitIfStatement(stmt *ast.IfStatement) {
 	for i, condition := range stmt.ElseCondition {
 		elifLine := f.context.GetIndent() + "elif " + f.formatExpression(condition) + ":"
 		f.addLine(elifLine)
+		if i < len(stmt.ElifPos) {
+			f.attachClauseComments(stmt.ElifPos[i])
+		}
 
 		f.context.IncreaseIndent()
 		if len(stmt.ElseBranches[i]) == 0 {
 			f.addLine(f.context.GetIndent() + "pass")
 		} else {
-			for _, s := range stmt.ElseBranches[i] {
-				f.visitStatement(s)
-			}
+			f.visitBlock(stmt.ElseBranches[i])
 		}
 		f.context.DecreaseIndent()
 	}
@@ -458,10 +1153,9 @@ func (f *Formatter) visitIfStatement(stmt *ast.IfStatement) {
 	// Format else clause
 	if len(stmt.Alternative) > 0 {
 		f.addLine(f.context.GetIndent() + "else:")
+		f.attachClauseComments(stmt.ElsePos)
 		f.context.IncreaseIndent()
-		for _, s := range stmt.Alternative {
-			f.visitStatement(s)
-		}
+		f.visitBlock(stmt.Alternative)
 		f.context.DecreaseIndent()
 	}
 }
@@ -479,9 +1173,7 @@ func (f *Formatter) visitForStatement(stmt *ast.ForStatement) {
 	if len(stmt.Body) == 0 {
 		f.addLine(f.context.GetIndent() + "pass")
 	} else {
-		for _, s := range stmt.Body {
-			f.visitStatement(s)
-		}
+		f.visitBlock(stmt.Body)
 	}
 	f.context.DecreaseIndent()
 }
@@ -495,9 +1187,7 @@ func (f *Formatter) visitWhileStatement(stmt *ast.WhileStatement) {
 	if len(stmt.Body) == 0 {
 		f.addLine(f.context.GetIndent() + "pass")
 	} else {
-		for _, s := range stmt.Body {
-			f.visitStatement(s)
-		}
+		f.visitBlock(stmt.Body)
 	}
 	f.context.DecreaseIndent()
 }
@@ -509,26 +1199,263 @@ func (f *Formatter) visitMatchStatement(stmt *ast.MatchStatement) {
 
 	f.context.IncreaseIndent()
 	for _, branch := range stmt.Branches {
-		patternLine := f.context.GetIndent() + f.formatExpression(branch.Pattern)
-		if branch.Guard != nil {
-			patternLine += " when " + f.formatExpression(branch.Guard)
-		}
-		patternLine += ":"
-		f.addLine(patternLine)
+		f.addLine(f.formatMatchBranchHeader(branch))
+		f.attachComments(branch, len(f.lines)-1)
 
 		f.context.IncreaseIndent()
 		if len(branch.Body) == 0 {
 			f.addLine(f.context.GetIndent() + "pass")
 		} else {
-			for _, s := range branch.Body {
-				f.visitStatement(s)
-			}
+			f.visitBlock(branch.Body)
 		}
 		f.context.DecreaseIndent()
 	}
 	f.context.DecreaseIndent()
 }
 
+// formatMatchBranchHeader formats the patterns and guard of a match branch,
+// wrapping bracketed patterns one element per line when the header is too long
+func (f *Formatter) formatMatchBranchHeader(branch *ast.MatchBranch) string {
+	guard := ""
+	if branch.Guard != nil {
+		guard = " when " + f.formatExpression(branch.Guard)
+	}
+
+	var patterns []string
+	for _, pattern := range branch.Patterns {
+		patterns = append(patterns, f.formatPattern(pattern))
+	}
+
+	indent := f.context.GetIndent()
+	line := indent + strings.Join(patterns, ", ") + guard + ":"
+	if f.context.LineLength(line) <= f.context.MaxLineLength {
+		return line
+	}
+
+	patterns = patterns[:0]
+	for _, pattern := range branch.Patterns {
+		patterns = append(patterns, f.formatPatternMultiline(pattern, f.context.IndentLevel))
+	}
+	return indent + strings.Join(patterns, ", ") + guard + ":"
+}
+
+// formatPattern formats a match pattern on a single line
+func (f *Formatter) formatPattern(pattern ast.Expression) string {
+	switch p := pattern.(type) {
+	case *ast.WildcardPattern:
+		return "_"
+	case *ast.BindingPattern:
+		return "var " + p.Name
+	case *ast.RestPattern:
+		return ".."
+	case *ast.ArrayPattern:
+		var elements []string
+		for _, element := range p.Elements {
+			elements = append(elements, f.formatPattern(element))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *ast.DictionaryPattern:
+		var pairs []string
+		for i, key := range p.Keys {
+			pairs = append(pairs, f.formatDictionaryPatternPair(key, p.Values[i]))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	default:
+		return f.formatExpression(pattern)
+	}
+}
+
+// formatDictionaryPatternPair formats a single key (and optional value) of a dictionary pattern
+func (f *Formatter) formatDictionaryPatternPair(key, value ast.Expression) string {
+	result := f.formatPattern(key)
+	if value != nil {
+		result += ": " + f.formatPattern(value)
+	}
+	return result
+}
+
+// formatPatternMultiline formats a bracketed pattern with one element per line
+// at the given indentation level; nested elements are wrapped only if they still don't fit
+func (f *Formatter) formatPatternMultiline(pattern ast.Expression, level int) string {
+	var elements []string
+	var open, close string
+
+	switch p := pattern.(type) {
+	case *ast.ArrayPattern:
+		open, close = "[", "]"
+		for _, element := range p.Elements {
+			elements = append(elements, f.wrapPatternElement(element, level+1))
+		}
+	case *ast.DictionaryPattern:
+		open, close = "{", "}"
+		for i, key := range p.Keys {
+			if p.Values[i] == nil {
+				elements = append(elements, f.formatPattern(key))
+				continue
+			}
+			elements = append(elements, f.formatPattern(key)+": "+f.wrapPatternElement(p.Values[i], level+1))
+		}
+	default:
+		return f.formatPattern(pattern)
+	}
+
+	if len(elements) == 0 {
+		return open + close
+	}
+
+	return f.splitLines(open, elements, ",", true, level, close)
+}
+
+// wrapPatternElement formats a pattern element on one line when it fits, otherwise multi-line
+func (f *Formatter) wrapPatternElement(pattern ast.Expression, level int) string {
+	single := f.formatPattern(pattern)
+	if len(f.context.indent(level))+f.context.LineLength(single)+1 <= f.context.MaxLineLength {
+		return single
+	}
+	return f.formatPatternMultiline(pattern, level)
+}
+
+// formatWrapped appends expr to prefix, wrapping the expression in parentheses
+// over several lines when the result would exceed the maximum line length
+func (f *Formatter) formatWrapped(prefix string, expr ast.Expression) string {
+	line := prefix + f.formatExpression(expr)
+	if f.context.LineLength(line) <= f.context.MaxLineLength {
+		return line
+	}
+
+	if ternary, ok := expr.(*ast.ConditionalExpression); ok {
+		return f.formatTernarySplit(prefix, ternary)
+	}
+	if f.context.Config.SplitDotChains {
+		if links := f.chainLinks(expr); len(links) >= 2 {
+			return f.formatChainSplit(prefix, links)
+		}
+	}
+	if split, ok := f.formatBracketSplit(prefix, expr); ok {
+		return split
+	}
+	return line
+}
+
+// formatBracketSplit puts the elements of an array, the pairs of a
+// dictionary or the arguments of a call on lines of their own:
+//
+//	var x = [
+//		first,
+//		second,
+//	]
+func (f *Formatter) formatBracketSplit(prefix string, expr ast.Expression) (string, bool) {
+	var open, close string
+	var elements []string
+	var trailingComma bool
+	switch e := expr.(type) {
+	case *ast.ArrayLiteral:
+		open, close, trailingComma = "[", "]", e.TrailingComma
+		for _, element := range e.Elements {
+			elements = append(elements, f.formatExpression(element))
+		}
+	case *ast.DictionaryLiteral:
+		open, close, trailingComma = "{", "}", e.TrailingComma
+		for i, key := range e.Keys {
+			elements = append(elements, f.formatPair(e, key, e.Values[i]))
+		}
+	case *ast.CallExpression:
+		open, close, trailingComma = f.formatOperand(e.Function, precAtom)+"(", ")", e.TrailingComma
+		for _, arg := range e.Arguments {
+			elements = append(elements, f.formatExpression(arg))
+		}
+	}
+	if len(elements) == 0 {
+		return "", false
+	}
+
+	return f.splitLines(prefix+open, elements, ",", f.trailingComma(trailingComma), f.context.IndentLevel, close), true
+}
+
+// trailingComma reports whether a list split over several lines ends with a
+// comma, given whether it does in the source
+func (f *Formatter) trailingComma(inSource bool) bool {
+	switch f.context.Config.TrailingCommas {
+	case TrailingCommasNever:
+		return false
+	case TrailingCommasPreserve:
+		return inSource
+	}
+	return true
+}
+
+// formatTernarySplit breaks a ternary before 'if' and 'else':
+//
+//	var x = (
+//		value
+//		if condition
+//		else other
+//	)
+func (f *Formatter) formatTernarySplit(prefix string, expr *ast.ConditionalExpression) string {
+	indent := f.context.GetIndent()
+	partIndent := indent + f.context.SingleIndentString
+	return prefix + "(\n" +
+		partIndent + f.formatOperand(expr.ValueIfTrue, precTernary+1) + "\n" +
+		partIndent + "if " + f.formatOperand(expr.Condition, precTernary+1) + "\n" +
+		partIndent + "else " + f.formatOperand(expr.ValueIfFalse, precTernary) + "\n" +
+		indent + ")"
+}
+
+// formatChainSplit wraps a dot chain in parentheses and breaks it after each '.'
+func (f *Formatter) formatChainSplit(prefix string, links []string) string {
+	return f.splitLines(prefix+"(", links, ".", false, f.context.IndentLevel, ")")
+}
+
+// splitLines returns open followed by each item on a line of its own, one
+// level deeper than level, and close on a line at level. Items are followed by
+// separator, the last one only if lastSeparator is set. Long lines of large
+// scripts are split this way, so the result is built in a single allocation.
+func (f *Formatter) splitLines(open string, items []string, separator string, lastSeparator bool, level int, close string) string {
+	indent, itemIndent := f.context.indent(level), f.context.indent(level+1)
+	size := len(open) + 1 + len(indent) + len(close)
+	for _, item := range items {
+		size += len(itemIndent) + len(item) + len(separator) + 1
+	}
+
+	var result strings.Builder
+	result.Grow(size)
+	result.WriteString(open)
+	result.WriteString("\n")
+	for i, item := range items {
+		result.WriteString(itemIndent)
+		result.WriteString(item)
+		if i < len(items)-1 || lastSeparator {
+			result.WriteString(separator)
+		}
+		result.WriteString("\n")
+	}
+	result.WriteString(indent)
+	result.WriteString(close)
+	return result.String()
+}
+
+// chainLinks flattens a dot chain such as a.b(c).d into its links ["a", "b(c)", "d"]
+func (f *Formatter) chainLinks(expr ast.Expression) []string {
+	switch e := expr.(type) {
+	case *ast.CallExpression:
+		if dot, ok := e.Function.(*ast.DotExpression); ok {
+			return append(f.chainLinks(dot.Left), dot.Property+f.formatArguments(e.Arguments))
+		}
+	case *ast.DotExpression:
+		return append(f.chainLinks(e.Left), e.Property)
+	}
+	return []string{f.formatExpression(expr)}
+}
+
+// formatArguments formats a parenthesized call argument list
+func (f *Formatter) formatArguments(arguments []ast.Expression) string {
+	var args []string
+	for _, arg := range arguments {
+		args = append(args, f.formatExpression(arg))
+	}
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
 // formatExpression formats an expression
 func (f *Formatter) formatExpression(expr ast.Expression) string {
 	switch e := expr.(type) {
@@ -536,7 +1463,10 @@ func (f *Formatter) formatExpression(expr ast.Expression) string {
 		return e.Value
 	case *ast.StringLiteral:
 		// String literals already include quotes, don't add extra ones
-		return e.Value
+		if f.context.Config.NormalizeStrings {
+			return normalizeQuotes(e)
+		}
+		return e.Raw
 	case *ast.NumberLiteral:
 		return e.Original
 	case *ast.BooleanLiteral:
@@ -546,6 +1476,8 @@ func (f *Formatter) formatExpression(expr ast.Expression) string {
 		return "false"
 	case *ast.NullLiteral:
 		return "null"
+	case *ast.SelfExpression:
+		return "self"
 	case *ast.ArrayLiteral:
 		if len(e.Elements) == 0 {
 			return "[]"
@@ -556,35 +1488,169 @@ func (f *Formatter) formatExpression(expr ast.Expression) string {
 		}
 		return "[" + strings.Join(elements, ", ") + "]"
 	case *ast.DictionaryLiteral:
-		if len(e.Pairs) == 0 {
+		if len(e.Keys) == 0 {
 			return "{}"
 		}
 		var pairs []string
-		for key, value := range e.Pairs {
-			pairs = append(pairs, f.formatExpression(key)+": "+f.formatExpression(value))
+		for i, key := range e.Keys {
+			pairs = append(pairs, f.formatPair(e, key, e.Values[i]))
 		}
 		return "{" + strings.Join(pairs, ", ") + "}"
 	case *ast.PrefixExpression:
-		return e.Operator + f.formatExpression(e.Right)
-	case *ast.InfixExpression:
-		return f.formatExpression(e.Left) + " " + e.Operator + " " + f.formatExpression(e.Right)
-	case *ast.CallExpression:
-		funcStr := f.formatExpression(e.Function)
-		if len(e.Arguments) == 0 {
-			return funcStr + "()"
+		if e.Operator == "not" {
+			return "not " + f.formatOperand(e.Right, precNot)
 		}
-		var args []string
-		for _, arg := range e.Arguments {
-			args = append(args, f.formatExpression(arg))
+		return e.Operator + f.formatOperand(e.Right, prefixPrecedence(e.Operator))
+	case *ast.InfixExpression:
+		// Operators are left-associative: only a right operand of the same
+		// precedence needs parentheses
+		prec := infixPrecedence(e.Operator)
+		separator := " "
+		if f.context.Config.KeepLineContinuations && f.hasContinuationBetween(e.Left, e.Right) {
+			// Continuation lines take two indentation levels, as in the style
+			// guide, so that they stand apart from a block that follows
+			separator = " \\\n" + f.context.GetIndent() + strings.Repeat(f.context.SingleIndentString, 2)
 		}
-		return funcStr + "(" + strings.Join(args, ", ") + ")"
+		return f.formatOperand(e.Left, prec) + " " + e.Operator + separator + f.formatOperand(e.Right, prec+1)
+	case *ast.ConditionalExpression:
+		// Ternaries chain to the right: only the false value may be a bare ternary
+		return f.formatOperand(e.ValueIfTrue, precTernary+1) + " if " +
+			f.formatOperand(e.Condition, precTernary+1) + " else " +
+			f.formatOperand(e.ValueIfFalse, precTernary)
+	case *ast.CallExpression:
+		return f.formatOperand(e.Function, precAtom) + f.formatArguments(e.Arguments)
 	case *ast.IndexExpression:
-		return f.formatExpression(e.Left) + "[" + f.formatExpression(e.Index) + "]"
+		return f.formatOperand(e.Left, precAtom) + "[" + f.formatExpression(e.Index) + "]"
 	case *ast.DotExpression:
-		return f.formatExpression(e.Left) + "." + e.Property
+		return f.formatOperand(e.Left, precAtom) + "." + e.Property
 	case *ast.AssignmentExpression:
-		return f.formatExpression(e.Left) + " = " + f.formatExpression(e.Right)
+		return f.formatExpression(e.Left) + " " + e.Operator + " " + f.formatExpression(e.Right)
+	case *ast.AwaitExpression:
+		return "await " + f.formatOperand(e.Operand, precAwait)
+	case *ast.GetNodeExpression:
+		return e.Raw
 	default:
 		return "# Unknown expression"
 	}
 }
+
+// formatPair formats a key-value pair of dictionary
+func (f *Formatter) formatPair(dictionary *ast.DictionaryLiteral, key, value ast.Expression) string {
+	if dictionary.LuaStyle {
+		return f.formatExpression(key) + " = " + f.formatExpression(value)
+	}
+	return f.formatExpression(key) + ": " + f.formatExpression(value)
+}
+
+// normalizeQuotes rewrites a single-quoted string with double quotes, like
+// gdformat, unless its content holds a double quote. Escapes are kept as
+// written, and raw and triple-quoted strings keep their prefix and form.
+func normalizeQuotes(str *ast.StringLiteral) string {
+	if !strings.HasPrefix(str.Quote, "'") || strings.Contains(str.Content(), `"`) {
+		return str.Raw
+	}
+	quote := strings.Repeat(`"`, len(str.Quote))
+	prefix := ""
+	if str.IsRaw {
+		prefix = "r"
+	}
+	return prefix + quote + str.Content() + quote
+}
+
+// Expression precedence levels, mirroring the parser's. The parser does not keep
+// grouping parentheses in the AST, so the formatter re-inserts them wherever an
+// operand binds looser than its position requires.
+const (
+	precAssign = iota
+	precCast
+	precTernary
+	precOr
+	precAnd
+	precNot
+	precContentTest
+	precComparison
+	precBitOr
+	precBitXor
+	precBitAnd
+	precShift
+	precSum
+	precProduct
+	precSign
+	precBitNot
+	precPower
+	precTypeTest
+	precAwait
+	precAtom
+)
+
+// infixPrecedence returns the precedence level of a binary operator
+func infixPrecedence(operator string) int {
+	switch operator {
+	case "as":
+		return precCast
+	case "or", "||":
+		return precOr
+	case "and", "&&":
+		return precAnd
+	case "in", "not in":
+		return precContentTest
+	case "==", "!=", "<", ">", "<=", ">=":
+		return precComparison
+	case "|":
+		return precBitOr
+	case "^":
+		return precBitXor
+	case "&":
+		return precBitAnd
+	case "<<", ">>":
+		return precShift
+	case "+", "-":
+		return precSum
+	case "*", "/", "%":
+		return precProduct
+	case "**":
+		return precPower
+	case "is", "is not":
+		return precTypeTest
+	default:
+		return precAssign
+	}
+}
+
+// prefixPrecedence returns the precedence level of a unary operator
+func prefixPrecedence(operator string) int {
+	switch operator {
+	case "not", "!":
+		return precNot
+	case "~":
+		return precBitNot
+	default:
+		return precSign
+	}
+}
+
+// expressionPrecedence returns how tightly expr binds when printed without parentheses
+func expressionPrecedence(expr ast.Expression) int {
+	switch e := expr.(type) {
+	case *ast.InfixExpression:
+		return infixPrecedence(e.Operator)
+	case *ast.ConditionalExpression:
+		return precTernary
+	case *ast.PrefixExpression:
+		return prefixPrecedence(e.Operator)
+	case *ast.AssignmentExpression:
+		return precAssign
+	case *ast.AwaitExpression:
+		return precAwait
+	default:
+		return precAtom
+	}
+}
+
+// formatOperand formats expr, parenthesized when it binds looser than minPrec
+func (f *Formatter) formatOperand(expr ast.Expression, minPrec int) string {
+	if expressionPrecedence(expr) < minPrec {
+		return "(" + f.formatExpression(expr) + ")"
+	}
+	return f.formatExpression(expr)
+}
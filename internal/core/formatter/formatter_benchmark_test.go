@@ -0,0 +1,115 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// benchmarkSection is repeated to build large scripts: it has the members,
+// statements and expressions of a typical game script, some of them written
+// unformatted or too long for a line so that every path of the formatter runs
+const benchmarkSection = `
+signal changed_%[1]d(old_value: int, new_value: int)
+
+enum State%[1]d { IDLE, RUNNING, JUMPING = 4 }
+enum Weapon%[1]d { SWORD, SHIELD, BOW, CROSSBOW, SPEAR, AXE, HAMMER, DAGGER, STAFF, WAND, SLING, WHIP, MACE, FLAIL, HALBERD }
+
+const SPEED_%[1]d = 300.0
+@export var health_%[1]d: int = 100 # current health
+var items_%[1]d: Array[String] = ["sword", "shield", "potion", "scroll", "map", "lantern", "rope", "torch"]
+var table_%[1]d = {"a": 1, "b": 2,
+	"c": 3}
+
+
+func update_%[1]d(delta: float, target: Node2D = null) -> void:
+	# Move towards the target
+	var direction=(target.global_position-global_position).normalized()
+	velocity = direction * SPEED_%[1]d * delta if target != null else Vector2.ZERO
+	if health_%[1]d <= 0 and not is_queued_for_deletion():
+		queue_free()
+	elif health_%[1]d < 20:
+		emit_signal("changed_%[1]d", health_%[1]d, health_%[1]d - 1)
+	else:
+		pass
+	for i in range(items_%[1]d.size()):
+		print(items_%[1]d[i], table_%[1]d.get(items_%[1]d[i], 0))
+	while velocity.length() > 1.0:
+		velocity *= 0.5
+	match State%[1]d.IDLE:
+		State%[1]d.IDLE, State%[1]d.RUNNING:
+			return
+		_:
+			pass
+	$AnimationPlayer.get_animation("run").track_get_key_value(0, 1).some_property.another_property.call_deferred("x")
+
+
+func configure_%[1]d(first_parameter: int, second_parameter: String, third_parameter: Dictionary = {}) -> bool:
+	return first_parameter > 0
+
+
+class Inner%[1]d extends Node:
+	var value := 0
+
+	func _ready():
+		value = max(value, 1)
+`
+
+// benchmarkScript returns a script of n sections
+func benchmarkScript(n int) string {
+	var script strings.Builder
+	script.WriteString("class_name Benchmark\nextends CharacterBody2D\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&script, benchmarkSection, i)
+	}
+	return script.String()
+}
+
+// parseBenchmarkScript parses a script of n sections
+func parseBenchmarkScript(tb testing.TB, n int) *ast.AbstractSyntaxTree {
+	tb.Helper()
+	tree, errors := parser.ParseFile("benchmark.gd", benchmarkScript(n))
+	if len(errors) > 0 {
+		tb.Fatalf("Parse errors: %v", errors)
+	}
+	return tree
+}
+
+// BenchmarkFormatCode benchmarks formatting scripts of about 500 to 10,000
+// lines, excluding parsing
+func BenchmarkFormatCode(b *testing.B) {
+	for _, sections := range []int{10, 200} {
+		b.Run(fmt.Sprintf("%d_sections", sections), func(b *testing.B) {
+			tree := parseBenchmarkScript(b, sections)
+			b.SetBytes(int64(len(benchmarkScript(sections))))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := FormatCode(tree, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// formatAllocsBudget is the number of allocations formatting a script of 10
+// benchmark sections may take, about 20% over what it takes. Raise it only
+// for a change that is worth the slowdown on large scripts.
+const formatAllocsBudget = 2700
+
+func TestFormatCodeAllocations(t *testing.T) {
+	tree := parseBenchmarkScript(t, 10)
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, err := FormatCode(tree, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > formatAllocsBudget {
+		t.Errorf("Formatting took %.0f allocations, over the budget of %d", allocs, formatAllocsBudget)
+	}
+	t.Logf("Formatting took %.0f allocations", allocs)
+}
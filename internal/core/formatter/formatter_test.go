@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 	"github.com/dzannotti/gdtoolkit/internal/core/parser"
 )
 
@@ -49,6 +50,41 @@ func TestBasicFormatting(t *testing.T) {
 	pass`,
 			expected: `func foo(a = 1, b: int = 2):
 	pass`,
+		},
+		{
+			name: "static_members",
+			input: `static var  counter:=0
+static   func _static_init():
+	counter=1`,
+			expected: `static var counter := 0
+
+
+static func _static_init():
+	counter = 1`,
+		},
+		{
+			name: "debugging_statements",
+			input: `func foo(x):
+	breakpoint
+	assert( x>0 ,"x must be positive")`,
+			expected: `func foo(x):
+	breakpoint
+	assert(x > 0, "x must be positive")`,
+		},
+		{
+			name: "await_and_node_paths",
+			input: `func foo(x):
+	await  get_tree().process_frame
+	var a=(await x.done)+1
+	$Sprite/Label.queue_free( )
+	%Button.pressed.connect(foo)
+	not x`,
+			expected: `func foo(x):
+	await get_tree().process_frame
+	var a = await x.done + 1
+	$Sprite/Label.queue_free()
+	%Button.pressed.connect(foo)
+	not x`,
 		},
 		{
 			name: "function_with_return_type",
@@ -351,6 +387,11 @@ func TestExpressionFormatting(t *testing.T) {
 			input:    `var dict = {"key":"value","num":123}`,
 			expected: `var dict = {"key": "value", "num": 123}`,
 		},
+		{
+			name:     "lua_style_dictionary_literals",
+			input:    `var dict = {key="value",num=123}`,
+			expected: `var dict = {key = "value", num = 123}`,
+		},
 		{
 			name:     "dot_notation",
 			input:    `player.position.x = 100`,
@@ -361,6 +402,75 @@ func TestExpressionFormatting(t *testing.T) {
 			input:    `var item = array[0]`,
 			expected: `var item = array[0]`,
 		},
+		{
+			name:     "hex_and_binary_literals",
+			input:    `var mask = 0b1010+0xFF_FF`,
+			expected: `var mask = 0b1010 + 0xFF_FF`,
+		},
+		{
+			name:     "underscore_literals",
+			input:    `var big = 1_000_000+1_0.5e1_0`,
+			expected: `var big = 1_000_000 + 1_0.5e1_0`,
+		},
+		{
+			name:     "not_operator",
+			input:    `var ok = a and not b==c`,
+			expected: `var ok = a and not b == c`,
+		},
+		{
+			name:     "not_operand_of_comparison",
+			input:    `var ok = (not a)==b`,
+			expected: `var ok = (not a) == b`,
+		},
+		{
+			name:     "content_and_type_tests",
+			input:    `var ok = a not in b and c is not Node or d in e`,
+			expected: `var ok = a not in b and c is not Node or d in e`,
+		},
+		{
+			name:     "bitwise_operators",
+			input:    `var bits = ~a&b|c^d<<1`,
+			expected: `var bits = ~a & b | c ^ d << 1`,
+		},
+		{
+			name:     "grouping_kept_against_precedence",
+			input:    `var x = (a or b) and (c | d) & e`,
+			expected: `var x = (a or b) and (c | d) & e`,
+		},
+		{
+			name:     "grouped_arithmetic",
+			input:    `var x = (a+b)*c - (d-e)/-(f+g) + a-(b==c)`,
+			expected: `var x = (a + b) * c - (d - e) / -(f + g) + a - (b == c)`,
+		},
+		{
+			name:     "grouped_operands_of_attributes_and_subscripts",
+			input:    `var x = (a+b).c + (a+b)[0] + (a if b else c).d`,
+			expected: `var x = (a + b).c + (a + b)[0] + (a if b else c).d`,
+		},
+		{
+			name:     "conditional_in_conditional",
+			input:    `var x = (a if b else c) if (d if e else f) else g`,
+			expected: `var x = (a if b else c) if (d if e else f) else g`,
+		},
+		{
+			name:     "power_and_signs",
+			input:    `var x = (-a)**2 + -b**2 + (a**b)**c + a**(b**c)`,
+			expected: `var x = (-a) ** 2 + -b ** 2 + a ** b ** c + a ** (b ** c)`,
+		},
+		{
+			name:     "casts",
+			input:    `var node = (get_node("A") as Sprite2D).texture if (a as int) else b as Node`,
+			expected: `var node = (get_node("A") as Sprite2D).texture if (a as int) else b as Node`,
+		},
+		{
+			name: "numeric_literals_in_match_patterns",
+			input: `match flags:
+	0x0F, 0b11:
+		pass`,
+			expected: `match flags:
+	0x0F, 0b11:
+		pass`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -507,3 +617,1241 @@ func TestConfigurationOptions(t *testing.T) {
 		}
 	})
 }
+
+func TestMatchPatternFormatting(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "multipattern",
+			input: `match x:
+	1,2,  3:
+		pass`,
+			expected: `match x:
+	1, 2, 3:
+		pass`,
+		},
+		{
+			name: "binding_and_wildcard",
+			input: `match x:
+	[var a,_]:
+		pass
+	_:
+		pass`,
+			expected: `match x:
+	[var a, _]:
+		pass
+	_:
+		pass`,
+		},
+		{
+			name: "open_ended_patterns",
+			input: `match x:
+	[1,..]:
+		pass
+	{"name":var n,"hp",..}:
+		pass`,
+			expected: `match x:
+	[1, ..]:
+		pass
+	{"name": var n, "hp", ..}:
+		pass`,
+		},
+		{
+			name: "guarded_multipattern",
+			input: `match x:
+	1,2 when y:
+		pass`,
+			expected: `match x:
+	1, 2 when y:
+		pass`,
+		},
+		{
+			name: "long_pattern_wraps",
+			input: `match x:
+	["aaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbb", "cccccccccccccccccccc", "dddddddddddddddddddd", ..]:
+		pass`,
+			expected: `match x:
+	[
+		"aaaaaaaaaaaaaaaaaaaa",
+		"bbbbbbbbbbbbbbbbbbbb",
+		"cccccccccccccccccccc",
+		"dddddddddddddddddddd",
+		..,
+	]:
+		pass`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, errors := parser.ParseFile("test.gd", tt.input)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+
+			result, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+
+			expected := strings.TrimSpace(tt.expected)
+			actual := strings.TrimSpace(result)
+
+			if actual != expected {
+				t.Errorf("Match pattern formatting mismatch:\nExpected:\n%s\n\nActual:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestDotChainSplitting(t *testing.T) {
+	input := `func foo():
+	get_node("A").get_node("B").call_deferred("x", very_long_argument_name_that_pushes_the_line_over_the_limit)
+	var short = get_node("A").get_node("B")`
+
+	expected := `func foo():
+	(
+		get_node("A").
+		get_node("B").
+		call_deferred("x", very_long_argument_name_that_pushes_the_line_over_the_limit)
+	)
+	var short = get_node("A").get_node("B")`
+
+	ast, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	result, err := FormatCode(ast, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+
+	if strings.TrimSpace(result) != strings.TrimSpace(expected) {
+		t.Errorf("Dot chain splitting mismatch:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+	}
+
+	// The split output must parse back to the same formatting
+	reparsed, errors := parser.ParseFile("test.gd", result)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors on formatted output: %v", errors)
+	}
+	again, err := FormatCode(reparsed, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if again != result {
+		t.Errorf("Dot chain splitting is not idempotent:\nFirst:\n%s\n\nSecond:\n%s", result, again)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		config := DefaultConfig()
+		config.SplitDotChains = false
+		result, err := FormatCode(ast, config)
+		if err != nil {
+			t.Fatalf("Format error: %v", err)
+		}
+		// The arguments may still be split, but not the chain
+		if !strings.Contains(result, "\tget_node(\"A\").get_node(\"B\").call_deferred(") {
+			t.Errorf("Expected chain to stay on one line, got:\n%s", result)
+		}
+	})
+}
+
+func TestStringNormalization(t *testing.T) {
+	input := `func foo():
+	var a = 'single'
+	var b = 'say "hi"'
+	var c = 'it\'s'
+	var d = "double"
+	var e = r'C:\path'
+	var f = '''multi
+line'''
+	var g = '''has "quote"'''
+	print('tab\t', "mixed 'quotes'")`
+
+	expected := `func foo():
+	var a = "single"
+	var b = 'say "hi"'
+	var c = "it\'s"
+	var d = "double"
+	var e = r"C:\path"
+	var f = """multi
+line"""
+	var g = '''has "quote"'''
+	print("tab\t", "mixed 'quotes'")`
+
+	ast, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	result, err := FormatCode(ast, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if result != expected+"\n" {
+		t.Errorf("String normalization mismatch:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		config := DefaultConfig()
+		config.NormalizeStrings = false
+		result, err := FormatCode(ast, config)
+		if err != nil {
+			t.Fatalf("Format error: %v", err)
+		}
+		if result != input+"\n" {
+			t.Errorf("Expected strings to keep their quotes, got:\n%s", result)
+		}
+	})
+}
+
+func TestLineContinuations(t *testing.T) {
+	input := "func foo():\n\tvar ok = a and \\\n\t\tb and \\\n c\n\tif ok or \\\n\t\tdone:\n\t\tpass"
+
+	ast, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	t.Run("joined", func(t *testing.T) {
+		expected := "func foo():\n\tvar ok = a and b and c\n\tif ok or done:\n\t\tpass"
+		result, err := FormatCode(ast, DefaultConfig())
+		if err != nil {
+			t.Fatalf("Format error: %v", err)
+		}
+		if result != expected+"\n" {
+			t.Errorf("Expected continued lines to be joined:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+		}
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		expected := "func foo():\n\tvar ok = a and \\\n\t\t\tb and \\\n\t\t\tc\n\tif ok or \\\n\t\t\tdone:\n\t\tpass"
+		config := DefaultConfig()
+		config.KeepLineContinuations = true
+		result, err := FormatCode(ast, config)
+		if err != nil {
+			t.Fatalf("Format error: %v", err)
+		}
+		if result != expected+"\n" {
+			t.Errorf("Expected continuations to be kept:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+		}
+	})
+}
+
+func TestConditionalExpressionFormatting(t *testing.T) {
+	input := `func foo():
+	var a = x if y else z
+	var b = (x + 1) * 2 if ready and not_empty else fallback if other else 0
+	var c = (x if y else z) + 1
+	return first_really_long_value_name if some_condition_that_is_long else second_really_long_value_name`
+
+	expected := `func foo():
+	var a = x if y else z
+	var b = (x + 1) * 2 if ready and not_empty else fallback if other else 0
+	var c = (x if y else z) + 1
+	return (
+		first_really_long_value_name
+		if some_condition_that_is_long
+		else second_really_long_value_name
+	)`
+
+	ast, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	result, err := FormatCode(ast, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+
+	if strings.TrimSpace(result) != strings.TrimSpace(expected) {
+		t.Errorf("Conditional expression formatting mismatch:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+	}
+
+	// The wrapped output must parse back to the same formatting
+	reparsed, errors := parser.ParseFile("test.gd", result)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors on formatted output: %v", errors)
+	}
+	again, err := FormatCode(reparsed, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if again != result {
+		t.Errorf("Conditional expression formatting is not idempotent:\nFirst:\n%s\n\nSecond:\n%s", result, again)
+	}
+}
+
+func TestLineLengthMode(t *testing.T) {
+	// 62 characters but 128 bytes: only the byte count exceeds the limit
+	input := "func foo():\n\treturn get_node(\"ノード\").call(\"" + strings.Repeat("あ", 30) + "\")"
+
+	ast, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	config := DefaultConfig()
+	config.MaxLineLength = 80
+
+	result, err := FormatCode(ast, config)
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if strings.TrimSpace(result) != strings.TrimSpace(input) {
+		t.Errorf("Expected line to fit when counting runes, got:\n%s", result)
+	}
+
+	config.LineLengthMode = LineLengthBytes
+	result, err = FormatCode(ast, config)
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if !strings.Contains(result, "return (\n") {
+		t.Errorf("Expected line to be split when counting bytes, got:\n%s", result)
+	}
+}
+
+func TestBlankLineNormalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "missing_blank_lines_between_functions",
+			input: `func foo():
+	pass
+func bar():
+	pass`,
+			expected: `func foo():
+	pass
+
+
+func bar():
+	pass`,
+		},
+		{
+			name: "excess_blank_lines_between_functions",
+			input: `func foo():
+	pass
+
+
+
+
+
+func bar():
+	pass`,
+			expected: `func foo():
+	pass
+
+
+func bar():
+	pass`,
+		},
+		{
+			name: "variables_before_function",
+			input: `var a = 1
+var b = 2
+func foo():
+	pass`,
+			expected: `var a = 1
+var b = 2
+
+
+func foo():
+	pass`,
+		},
+		{
+			name: "declaration_groups",
+			input: `extends Node
+
+signal died
+signal hit
+
+
+const MAX = 3
+var a = 1
+
+
+
+var b = 2
+class Inner:
+	extends Object
+
+	var x
+	const Y = 1
+
+	var z`,
+			expected: `extends Node
+
+signal died
+signal hit
+
+const MAX = 3
+var a = 1
+
+var b = 2
+
+
+class Inner:
+	extends Object
+
+	var x
+	const Y = 1
+
+	var z`,
+		},
+		{
+			name: "methods_inside_class",
+			input: `func baz():
+	pass
+class X:
+	func foo():
+		pass
+	func bar():
+		pass`,
+			expected: `func baz():
+	pass
+
+
+class X:
+	func foo():
+		pass
+
+	func bar():
+		pass`,
+		},
+		{
+			name: "statement_groups_in_function",
+			input: `func foo():
+	var a = 1
+	var b = 2
+
+
+
+	print(a)
+	# then b
+	print(b)
+
+	return a + b`,
+			expected: `func foo():
+	var a = 1
+	var b = 2
+
+	print(a)
+	# then b
+	print(b)
+
+	return a + b`,
+		},
+		{
+			name: "statement_groups_in_nested_blocks",
+			input: `func foo(items):
+	for item in items:
+
+		print(item)
+
+		print(item)
+
+	while true:
+		var x = 1
+
+		if x:
+			break`,
+			expected: `func foo(items):
+	for item in items:
+		print(item)
+
+		print(item)
+
+	while true:
+		var x = 1
+
+		if x:
+			break`,
+		},
+		{
+			name: "blank_lines_inside_previous_block_do_not_separate",
+			input: `func foo(items):
+	for item in items:
+		print(item)
+
+		print(item)
+	print(items)`,
+			expected: `func foo(items):
+	for item in items:
+		print(item)
+
+		print(item)
+	print(items)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, errors := parser.ParseFile("test.gd", tt.input)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+
+			result, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+
+			if result != tt.expected+"\n" {
+				t.Errorf("Formatting mismatch:\nExpected:\n%s\n\nActual:\n%s", tt.expected, result)
+			}
+
+			// Formatting the output again must not change it
+			ast, errors = parser.ParseFile("test.gd", result)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors on formatted output: %v", errors)
+			}
+			again, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error on formatted output: %v", err)
+			}
+			if again != result {
+				t.Errorf("Formatting is not idempotent:\nFirst:\n%s\n\nSecond:\n%s", result, again)
+			}
+		})
+	}
+
+	t.Run("end_of_file", func(t *testing.T) {
+		for _, input := range []string{"var a = 1", "var a = 1\n", "var a = 1\n\n\n", "var a = 1\n\t\n  \n"} {
+			ast, errors := parser.ParseFile("test.gd", input)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+			result, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+			if result != "var a = 1\n" {
+				t.Errorf("Expected %q to end with one newline, got %q", input, result)
+			}
+		}
+	})
+
+	t.Run("runs_collapse_and_edges_trim", func(t *testing.T) {
+		lines := []FormattedLine{
+			{Content: ""},
+			{Content: "var a = 1"},
+			{Content: ""},
+			{Content: ""},
+			{Content: ""},
+			{Content: ""},
+			{Content: ""},
+			{Content: "var b = 2"},
+			{Content: ""},
+		}
+
+		var actual []string
+		for _, line := range normalizeBlankLines(lines) {
+			actual = append(actual, line.Content)
+		}
+
+		expected := []string{"var a = 1", "", "var b = 2"}
+		if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("Expected %q, got %q", expected, actual)
+		}
+	})
+}
+
+func TestAnnotationPlacement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "annotations_join_their_statement",
+			input: `@export @onready
+var a = 1
+@export_range(0,10) var b = 2`,
+			expected: `@export @onready var a = 1
+@export_range(0, 10) var b = 2`,
+		},
+		{
+			name: "standalone_annotations_on_their_own_line",
+			input: `@export_group("Stats") @export var health = 10
+@export_subgroup("Sub")
+@export var mana = 5`,
+			expected: `@export_group("Stats")
+@export var health = 10
+@export_subgroup("Sub")
+@export var mana = 5`,
+		},
+		{
+			name:  "annotations_of_a_long_statement_on_their_own_line",
+			input: `@export_range(0, 100) @export_storage var a_long_variable_name_that_goes_on: int = 50 + 20 + 30 + 40 + 50`,
+			expected: `@export_range(0, 100)
+@export_storage
+var a_long_variable_name_that_goes_on: int = 50 + 20 + 30 + 40 + 50`,
+		},
+		{
+			name: "long_arguments_wrapped",
+			input: `class X:
+	@export_enum("Warrior", "Magician", "Thief", "Paladin", "Ranger", "Bard", "Cleric", "Druid", "Monk") var c = 0`,
+			expected: `class X:
+	@export_enum(
+		"Warrior",
+		"Magician",
+		"Thief",
+		"Paladin",
+		"Ranger",
+		"Bard",
+		"Cleric",
+		"Druid",
+		"Monk",
+	)
+	var c = 0`,
+		},
+		{
+			name: "function_annotations_on_their_own_line",
+			input: `@rpc("any_peer") func sync():
+	pass`,
+			expected: `@rpc("any_peer")
+func sync():
+	pass`,
+		},
+		{
+			name: "inline_comment_below_annotations",
+			input: `@rpc
+func sync(): # called remotely
+	pass`,
+			expected: `@rpc
+func sync():  # called remotely
+	pass`,
+		},
+		{
+			// The expression would read as the argument of the annotation
+			name: "expression_annotations_on_their_own_line",
+			input: `func f():
+	@warning_ignore("standalone_expression") 1 + 1`,
+			expected: `func f():
+	@warning_ignore("standalone_expression")
+	1 + 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, errors := parser.ParseFile("test.gd", tt.input)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+
+			result, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+
+			if strings.TrimSpace(result) != tt.expected {
+				t.Errorf("Formatting mismatch:\nExpected:\n%s\n\nActual:\n%s", tt.expected, result)
+			}
+
+			// Formatting the output again must not change it
+			ast, errors = parser.ParseFile("test.gd", result)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors on formatted output: %v", errors)
+			}
+			again, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error on formatted output: %v", err)
+			}
+			if again != result {
+				t.Errorf("Formatting is not idempotent:\nFirst:\n%s\n\nSecond:\n%s", result, again)
+			}
+		})
+	}
+}
+
+func TestCommentPlacement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "comment_opening_a_block_deeper_than_it",
+			input: `func foo():
+		# first
+	pass
+func bar():
+	pass`,
+			expected: `func foo():
+	# first
+	pass
+
+
+func bar():
+	pass`,
+		},
+		{
+			name: "comment_indented_deeper_after_a_closed_block",
+			input: `func foo():
+	pass
+# closes foo
+	# stays after it
+var a = 1`,
+			expected: `# closes foo
+# stays after it
+var a = 1
+
+
+func foo():
+	pass`,
+		},
+		{
+			name: "comment_between_statements_indented_deeper",
+			input: `func foo():
+	var a = 1
+
+	  # about b
+	var b = 2`,
+			expected: `func foo():
+	var a = 1
+
+	# about b
+	var b = 2`,
+		},
+		{
+			name: "comment_closing_a_block",
+			input: `func foo(a):
+	if a:
+		pass
+		# end of if
+	return a`,
+			expected: `func foo(a):
+	if a:
+		pass
+		# end of if
+	return a`,
+		},
+		{
+			name: "indented_comment_after_the_last_statement",
+			input: `var a = 1
+func foo():
+	pass
+var b = 2
+  # end`,
+			expected: `var a = 1
+var b = 2
+
+
+func foo():
+	pass
+
+
+# end`,
+		},
+		{
+			name: "inline_comment_on_else",
+			input: `func foo(a):
+	if a:
+		pass
+	else:  # trailing
+		pass`,
+			expected: `func foo(a):
+	if a:
+		pass
+	else:  # trailing
+		pass`,
+		},
+		{
+			name: "inline_comment_on_elif",
+			input: `func foo(a, x):
+	if a:
+		pass
+	elif x:   # c
+		pass
+	elif not x:#d
+		pass`,
+			expected: `func foo(a, x):
+	if a:
+		pass
+	elif x:  # c
+		pass
+	elif not x:  #d
+		pass`,
+		},
+		{
+			name: "comment_before_else",
+			input: `func foo(a):
+	if a:
+		pass
+		# end of if
+	# about else
+	else:
+		# opens else
+		pass`,
+			expected: `func foo(a):
+	if a:
+		pass
+		# end of if
+	# about else
+	else:
+		# opens else
+		pass`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, errors := parser.ParseFile("test.gd", tt.input)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+			result, err := FormatCode(tree, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+			if strings.TrimSpace(result) != tt.expected {
+				t.Errorf("Formatting mismatch:\nExpected:\n%s\n\nActual:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestInnerClassExtends(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "extends_in_the_header",
+			input: `class A   extends   Node2D:
+	var x = 1`,
+			expected: `class A extends Node2D:
+	var x = 1`,
+		},
+		{
+			name: "extends_statement_in_the_body",
+			input: `class B:
+	extends "res://b.gd"
+	var y = 2`,
+			expected: `class B:
+	extends "res://b.gd"
+	var y = 2`,
+		},
+		{
+			name: "extends_statement_alone",
+			input: `class C:
+	extends Sprite2D`,
+			expected: `class C:
+	extends Sprite2D`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, errors := parser.ParseFile("test.gd", tt.input)
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+
+			result, err := FormatCode(ast, DefaultConfig())
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+
+			if strings.TrimSpace(result) != tt.expected {
+				t.Errorf("Formatting mismatch:\nExpected:\n%s\n\nActual:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatCodeReportsInternalErrors(t *testing.T) {
+	tree, errors := parser.ParseFile("test.gd", "func foo():\n\tpass\n\tbar()\n")
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	// A tree the parser never builds makes the formatter fail
+	stmt := tree.RootClass.Functions[0].Statements[1].(*ast.ExpressionStatement)
+	stmt.Expression = (*ast.CallExpression)(nil)
+
+	result, err := FormatCode(tree, DefaultConfig())
+	internal, ok := err.(*InternalError)
+	if !ok {
+		t.Fatalf("Expected an internal error, got %v with result %q", err, result)
+	}
+	if internal.Pos.Line != 3 {
+		t.Errorf("Expected the error at line 3, got %v", internal)
+	}
+}
+
+func TestReorderMembers(t *testing.T) {
+	input := `extends Node
+
+func _private():
+	pass
+
+# Called when ready
+func _ready():
+	pass
+
+func public():
+	pass
+
+var _hidden = 1
+@onready var label = $Label
+@export var speed = 10  # pixels per second
+const MAX = 3
+signal hit
+
+class Inner:
+	func b():
+		pass
+	var x
+	func _init():
+		pass
+`
+
+	expected := `extends Node
+signal hit
+const MAX = 3
+@export var speed = 10  # pixels per second
+var _hidden = 1
+@onready var label = $Label
+
+
+# Called when ready
+func _ready():
+	pass
+
+
+func public():
+	pass
+
+
+func _private():
+	pass
+
+
+class Inner:
+	var x
+
+	func _init():
+		pass
+
+	func b():
+		pass
+`
+
+	tree, errors := parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	ReorderMembers(tree)
+	result, err := FormatCode(tree, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected members in canonical order:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+	}
+
+	// Members in a gdformat: off region stay where they are written
+	input = "var b\n# gdformat: off\nconst A = 1\n# gdformat: on\n"
+	tree, errors = parser.ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	ReorderMembers(tree)
+	if _, ok := tree.RootClass.Statements[0].(*ast.VarStatement); !ok || tree.RootClass.Statements[0].(*ast.VarStatement).IsConst {
+		t.Errorf("Expected the members of a class with an unformatted region kept in order")
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	input := `func foo(first_parameter_name, second_parameter_name, third_parameter_name, fourth_one,):
+	var values = [first_parameter_name, second_parameter_name, third_parameter_name, fourth_one]
+	var table = {"first": first_parameter_name, "second": second_parameter_name, "third": 3,}
+	print(first_parameter_name, second_parameter_name, third_parameter_name, fourth_one, 5)`
+
+	tests := []struct {
+		policy   TrailingCommaPolicy
+		expected string
+	}{
+		{TrailingCommasAlways, `func foo(
+	first_parameter_name,
+	second_parameter_name,
+	third_parameter_name,
+	fourth_one,
+):
+	var values = [
+		first_parameter_name,
+		second_parameter_name,
+		third_parameter_name,
+		fourth_one,
+	]
+	var table = {
+		"first": first_parameter_name,
+		"second": second_parameter_name,
+		"third": 3,
+	}
+	print(
+		first_parameter_name,
+		second_parameter_name,
+		third_parameter_name,
+		fourth_one,
+		5,
+	)
+`},
+		{TrailingCommasNever, `func foo(
+	first_parameter_name,
+	second_parameter_name,
+	third_parameter_name,
+	fourth_one
+):
+	var values = [
+		first_parameter_name,
+		second_parameter_name,
+		third_parameter_name,
+		fourth_one
+	]
+	var table = {
+		"first": first_parameter_name,
+		"second": second_parameter_name,
+		"third": 3
+	}
+	print(
+		first_parameter_name,
+		second_parameter_name,
+		third_parameter_name,
+		fourth_one,
+		5
+	)
+`},
+		{TrailingCommasPreserve, `func foo(
+	first_parameter_name,
+	second_parameter_name,
+	third_parameter_name,
+	fourth_one,
+):
+	var values = [
+		first_parameter_name,
+		second_parameter_name,
+		third_parameter_name,
+		fourth_one
+	]
+	var table = {
+		"first": first_parameter_name,
+		"second": second_parameter_name,
+		"third": 3,
+	}
+	print(
+		first_parameter_name,
+		second_parameter_name,
+		third_parameter_name,
+		fourth_one,
+		5
+	)
+`},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.MaxLineLength = 60
+		config.TrailingCommas = tt.policy
+		result := formatWithConfig(t, input, config)
+		if result != tt.expected {
+			t.Errorf("Policy %d:\nExpected:\n%s\n\nActual:\n%s", tt.policy, tt.expected, result)
+		}
+		if again := formatWithConfig(t, result, config); again != result {
+			t.Errorf("Policy %d is not idempotent:\nFirst:\n%s\n\nSecond:\n%s", tt.policy, result, again)
+		}
+	}
+}
+
+func formatWithConfig(t *testing.T, code string, config *Config) string {
+	t.Helper()
+	tree, errors := parser.ParseFile("test.gd", code)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	result, err := FormatCode(tree, config)
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	return result
+}
+
+func TestAlignInlineComments(t *testing.T) {
+	input := `const A = 1 # first
+const LONGER_NAME = 2 # second
+const C = 3
+
+
+func foo():
+	var x = 1 # a
+	var yy = [1, 2] # b
+	if x:
+		pass # c
+	return x    # d
+`
+
+	expected := `const A = 1            # first
+const LONGER_NAME = 2  # second
+const C = 3
+
+
+func foo():
+	var x = 1        # a
+	var yy = [1, 2]  # b
+	if x:
+		pass  # c
+	return x  # d
+`
+
+	config := DefaultConfig()
+	config.AlignInlineComments = true
+	if result := formatWithConfig(t, input, config); result != expected {
+		t.Errorf("Expected aligned comments:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+	}
+
+	// A line the common column would make too long keeps its comment in place
+	config.MaxLineLength = 30
+	expected = `const A = 1   # first
+const BB = 2  # second
+const A_MUCH_LONGER_NAME = 3  # third
+`
+	input = "const A = 1 # first\nconst BB = 2 # second\nconst A_MUCH_LONGER_NAME = 3 # third\n"
+	if result := formatWithConfig(t, input, config); result != expected {
+		t.Errorf("Expected the long line left out:\nExpected:\n%s\n\nActual:\n%s", expected, result)
+	}
+}
+
+func TestSingleLineBodies(t *testing.T) {
+	input := `class Inner extends Node: var y = 1
+func f(x): a(); return x
+static func s(): pass
+func g(x):
+	if x: return 1 # one
+	elif x > 2: pass
+	else: a(); b()
+	while x: x -= 1
+	for i in x: print(i)
+	match x:
+		1: return 3
+		_: pass
+`
+
+	expected := `func f(x):
+	a()
+	return x
+
+
+static func s():
+	pass
+
+
+func g(x):
+	if x:
+		return 1  # one
+	elif x > 2:
+		pass
+	else:
+		a()
+		b()
+	while x:
+		x -= 1
+	for i in x:
+		print(i)
+	match x:
+		1:
+			return 3
+		_:
+			pass
+
+
+class Inner extends Node:
+	var y = 1
+`
+
+	result := formatWithConfig(t, input, DefaultConfig())
+	if result != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, result)
+	}
+	if again := formatWithConfig(t, result, DefaultConfig()); again != result {
+		t.Errorf("Expected the expanded bodies to be formatted already, got:\n%s", again)
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	input := "extends Node\nvar a=1\nvar b=2\n\n\n\n# about c\nfunc c(x,y):\n\tvar z=x+y\n\tif z>1:\n\t\treturn  z\n\treturn 0\nfunc d():\n\tpass\n"
+	tree, errors := parser.ParseFile("range.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse failed: %v", errors)
+	}
+
+	tests := []struct {
+		start, end int
+		line       int
+		changed    string
+	}{
+		{2, 2, 2, "var a = 1"},
+		{9, 9, 9, "\tvar z = x + y"},
+		{11, 11, 11, "\t\treturn z"},
+		{7, 8, 8, "func c(x, y):"},
+	}
+	for _, tt := range tests {
+		result, err := FormatRange(tree, input, tt.start, tt.end, DefaultConfig())
+		if err != nil {
+			t.Fatalf("Lines %d to %d: %v", tt.start, tt.end, err)
+		}
+		// Only the selected statement is formatted; every other line is kept
+		want := strings.Split(input, "\n")
+		want[tt.line-1] = tt.changed
+		if expected := strings.Join(want, "\n"); result != expected {
+			t.Errorf("Lines %d to %d: expected:\n%s\nGot:\n%s", tt.start, tt.end, expected, result)
+		}
+	}
+
+	all, err := FormatRange(tree, input, 1, 100, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whole := formatWithConfig(t, input, DefaultConfig()); all != whole {
+		t.Errorf("Expected a range over the whole file to format it all, got:\n%s\nwant:\n%s", all, whole)
+	}
+}
+
+func TestFormatRangeInterleavedMembers(t *testing.T) {
+	// The formatter writes variables before functions; a range keeps the
+	// members where they are, with the blank lines gdformat puts around
+	// functions
+	input := "extends Node\nfunc a():\n\treturn  1\nvar x=1\nfunc b():\n\tpass\n"
+	tree, errors := parser.ParseFile("range.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("Parse failed: %v", errors)
+	}
+
+	tests := []struct {
+		start, end int
+		expected   string
+	}{
+		{4, 4, "extends Node\nfunc a():\n\treturn  1\nvar x = 1\n\n\nfunc b():\n\tpass\n"},
+		{3, 3, "extends Node\nfunc a():\n\treturn 1\n\n\nvar x=1\nfunc b():\n\tpass\n"},
+	}
+	for _, tt := range tests {
+		result, err := FormatRange(tree, input, tt.start, tt.end, DefaultConfig())
+		if err != nil {
+			t.Fatalf("Lines %d to %d: %v", tt.start, tt.end, err)
+		}
+		if result != tt.expected {
+			t.Errorf("Lines %d to %d: expected:\n%s\nGot:\n%s", tt.start, tt.end, tt.expected, result)
+		}
+	}
+}
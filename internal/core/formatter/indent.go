@@ -0,0 +1,207 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// IndentStyle is the indentation of a project: a tab per level, or Size
+// spaces per level
+type IndentStyle struct {
+	Tabs bool
+	// Size is the number of spaces of a level; with tabs, the number of
+	// spaces a tab stands for
+	Size int
+}
+
+// ParseIndentStyle parses an indentation style written as "tabs", or as
+// "spaces:N" for N spaces per level; "spaces" alone is 4 spaces per level
+func ParseIndentStyle(s string) (IndentStyle, error) {
+	switch {
+	case s == "tabs":
+		return IndentStyle{Tabs: true, Size: TAB_INDENT_SIZE}, nil
+	case s == "spaces":
+		return IndentStyle{Size: TAB_INDENT_SIZE}, nil
+	case strings.HasPrefix(s, "spaces:"):
+		size, err := strconv.Atoi(strings.TrimPrefix(s, "spaces:"))
+		if err != nil || size < 1 {
+			return IndentStyle{}, fmt.Errorf("invalid indentation %q, expected a positive number of spaces", s)
+		}
+		return IndentStyle{Size: size}, nil
+	}
+	return IndentStyle{}, fmt.Errorf("invalid indentation %q, expected 'tabs' or 'spaces:N'", s)
+}
+
+// String returns the style as ParseIndentStyle reads it
+func (s IndentStyle) String() string {
+	if s.Tabs {
+		return "tabs"
+	}
+	return fmt.Sprintf("spaces:%d", s.Size)
+}
+
+// Convert returns indent, the leading whitespace of a line, in the style:
+// a tab and Size spaces are a level each, and the spaces left over after
+// the last whole level stay spaces
+func (s IndentStyle) Convert(indent string) string {
+	width := 0
+	for _, ch := range indent {
+		if ch == '\t' {
+			width += s.Size
+		} else {
+			width++
+		}
+	}
+	if !s.Tabs {
+		return strings.Repeat(" ", width)
+	}
+	return strings.Repeat("\t", width/s.Size) + strings.Repeat(" ", width%s.Size)
+}
+
+// Kinds of lines in the lexer's indentation model
+const (
+	otherLine     = iota // blank, or within a string spanning several lines
+	blockLine            // starts a statement, at the level of its block
+	continuedLine        // continues a statement, within brackets or after a backslash
+	commentLine          // holds nothing but a comment
+)
+
+// lexerTabWidth is the width the lexer counts a tab of indentation as
+const lexerTabWidth = 4
+
+// Reindent returns src with every line indented in the style, leaving the
+// rest of the lines, and the lines within strings, as written. The lines
+// starting a statement are indented by the level of their block, as the
+// lexer counts it. A continued line is indented past its statement by
+// levels when it hangs, after a backslash or within a bracket ending its
+// line, the width of a level being that of the first indented block of
+// src; within a bracket followed by code, it keeps the alignment spaces
+// past the indentation of its statement, and only its tabs are levels.
+// Comment lines take the level of the statements indented like them, or
+// else the width of their indentation.
+func Reindent(src string, style IndentStyle) string {
+	unit := "\t"
+	if !style.Tabs {
+		unit = strings.Repeat(" ", style.Size)
+	}
+	lines := strings.Split(src, "\n")
+	indents := make([]string, len(lines))
+	for i, line := range lines {
+		indents[i] = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	}
+
+	// Each line is classified by the first token on it
+	kinds := make([]int, len(lines))
+	levels := make([]int, len(lines))
+	statements := make([]int, len(lines)) // the line a continued line continues
+	hanging := make([]bool, len(lines))   // whether a continued line hangs
+	inString := make(map[int]bool)
+	depth, atStart, statement := 0, true, 0
+	// brackets holds whether each open bracket ends its line, the last one
+	// being undecided until the token after it
+	var brackets []bool
+	openLine := 0
+	lexer := parser.NewLexer(src)
+	for tok := lexer.NextToken(); tok.Type != parser.EOF; tok = lexer.NextToken() {
+		switch tok.Type {
+		case parser.INDENT:
+			depth++
+			continue
+		case parser.DEDENT:
+			depth--
+			continue
+		case parser.NL:
+			atStart = true
+			continue
+		}
+		if openLine > 0 && tok.Type != parser.COMMENT {
+			brackets[len(brackets)-1] = tok.Line > openLine
+			openLine = 0
+		}
+		i := tok.Line - 1
+		if i >= 0 && i < len(lines) && kinds[i] == otherLine && !inString[i] && tok.Column == len(indents[i])+1 {
+			switch {
+			case tok.Type == parser.COMMENT:
+				kinds[i] = commentLine
+			case atStart:
+				kinds[i], levels[i], statement = blockLine, depth, i
+			default:
+				kinds[i], statements[i] = continuedLine, statement
+				hanging[i] = len(brackets) == 0 || brackets[len(brackets)-1]
+			}
+		}
+		switch tok.Type {
+		case parser.COMMENT:
+		case parser.LPAREN, parser.LBRACKET, parser.LBRACE:
+			brackets = append(brackets, false)
+			openLine = tok.Line
+		case parser.RPAREN, parser.RBRACKET, parser.RBRACE:
+			if len(brackets) > 0 {
+				brackets = brackets[:len(brackets)-1]
+			}
+			fallthrough
+		default:
+			atStart = false
+		}
+		for j := 1; j <= strings.Count(tok.Literal, "\n"); j++ {
+			inString[i+j] = true
+		}
+	}
+
+	// The level of the statements indented like each comment, and the width
+	// of a level
+	levelOf := make(map[string]int)
+	levelWidth := 0
+	for i, kind := range kinds {
+		if _, ok := levelOf[indents[i]]; kind == blockLine && !ok {
+			levelOf[indents[i]] = levels[i]
+		}
+		if kind == blockLine && levels[i] == 1 && levelWidth == 0 {
+			levelWidth = indentWidth(indents[i])
+		}
+	}
+	if levelWidth == 0 {
+		levelWidth = lexerTabWidth
+	}
+
+	for i, line := range lines {
+		var indent string
+		switch kinds[i] {
+		case blockLine:
+			indent = strings.Repeat(unit, levels[i])
+		case commentLine:
+			level, ok := levelOf[indents[i]]
+			if !ok {
+				indent = style.Convert(indents[i])
+				break
+			}
+			indent = strings.Repeat(unit, level)
+		case continuedLine:
+			base := indents[statements[i]]
+			if !strings.HasPrefix(indents[i], base) {
+				indent = style.Convert(indents[i])
+				break
+			}
+			extra := indents[i][len(base):]
+			if hanging[i] {
+				width := indentWidth(extra)
+				indent = strings.Repeat(unit, levels[statements[i]]+width/levelWidth) + strings.Repeat(" ", width%levelWidth)
+				break
+			}
+			alignment := strings.TrimLeft(extra, "\t")
+			indent = strings.Repeat(unit, levels[statements[i]]+len(extra)-len(alignment)) + alignment
+		default:
+			continue
+		}
+		lines[i] = indent + line[len(indents[i]):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentWidth returns the width of indent as the lexer counts it
+func indentWidth(indent string) int {
+	return len(indent) + (lexerTabWidth-1)*strings.Count(indent, "\t")
+}
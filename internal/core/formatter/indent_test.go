@@ -0,0 +1,87 @@
+package formatter
+
+import "testing"
+
+func TestParseIndentStyle(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected IndentStyle
+		valid    bool
+	}{
+		{"tabs", IndentStyle{Tabs: true, Size: 4}, true},
+		{"spaces", IndentStyle{Size: 4}, true},
+		{"spaces:2", IndentStyle{Size: 2}, true},
+		{"spaces:0", IndentStyle{}, false},
+		{"spaces:x", IndentStyle{}, false},
+		{"tab", IndentStyle{}, false},
+	}
+	for _, tt := range tests {
+		style, err := ParseIndentStyle(tt.input)
+		if (err == nil) != tt.valid || style != tt.expected {
+			t.Errorf("%q: expected %+v (valid: %v), got %+v, %v", tt.input, tt.expected, tt.valid, style, err)
+		}
+		if tt.valid && tt.input != "spaces" && style.String() != tt.input {
+			t.Errorf("%q: expected the style to print as it is written, got %q", tt.input, style)
+		}
+	}
+}
+
+func TestIndentStyleConvert(t *testing.T) {
+	tabs := IndentStyle{Tabs: true, Size: 4}
+	spaces := IndentStyle{Size: 2}
+	tests := []struct {
+		style    IndentStyle
+		indent   string
+		expected string
+	}{
+		{tabs, "\t\t", "\t\t"},
+		{tabs, "        ", "\t\t"},
+		{tabs, "\t  ", "\t  "},
+		{tabs, "  \t   ", "\t\t "},
+		{spaces, "    ", "    "},
+		{spaces, "\t\t", "    "},
+		{spaces, " \t", "   "},
+	}
+	for _, tt := range tests {
+		if converted := tt.style.Convert(tt.indent); converted != tt.expected {
+			t.Errorf("%s %q: expected %q, got %q", tt.style, tt.indent, tt.expected, converted)
+		}
+	}
+}
+
+func TestReindent(t *testing.T) {
+	tabs := "func foo():\n" +
+		"\tif bar(1,\n" +
+		"\t       2):\n" +
+		"\t\tvar s = \"\"\"a\n" +
+		"\tb\"\"\"\n" +
+		"\t# comment\n" +
+		"\t\treturn [\n" +
+		"\t\t\t1,  # aligned\n" +
+		"\t\t]\n" +
+		"\n" +
+		"  \n" +
+		"var x = 1 + \\\n" +
+		"\t2\n"
+	spaces := "func foo():\n" +
+		"  if bar(1,\n" +
+		"         2):\n" +
+		"    var s = \"\"\"a\n" +
+		"\tb\"\"\"\n" +
+		"  # comment\n" +
+		"    return [\n" +
+		"      1,  # aligned\n" +
+		"    ]\n" +
+		"\n" +
+		"  \n" +
+		"var x = 1 + \\\n" +
+		"  2\n"
+
+	// The alignment spaces of continued lines stay spaces both ways
+	if reindented := Reindent(tabs, IndentStyle{Size: 2}); reindented != spaces {
+		t.Errorf("Expected tabs reindented as\n%s\ngot\n%s", spaces, reindented)
+	}
+	if reindented := Reindent(spaces, IndentStyle{Tabs: true, Size: 4}); reindented != tabs {
+		t.Errorf("Expected spaces reindented as\n%s\ngot\n%s", tabs, reindented)
+	}
+}
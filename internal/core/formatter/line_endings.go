@@ -0,0 +1,55 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// LineEnding is the sequence ending the lines of a file
+type LineEnding string
+
+const (
+	// LF ends lines with \n, as on Linux and macOS
+	LF LineEnding = "\n"
+	// CRLF ends lines with \r\n, as Windows editors do
+	CRLF LineEnding = "\r\n"
+)
+
+// SourceStyle records how a file is encoded beyond its code, so that the
+// formatted code can be written back the same way
+type SourceStyle struct {
+	LineEnding LineEnding
+	// BOM reports whether the file starts with a UTF-8 byte order mark
+	BOM bool
+}
+
+// NormalizeSource returns source without a byte order mark and with \n line
+// endings, which the formatter works with, along with the style of source.
+// A file mixing line endings is given the one most of its lines use, LF on a
+// tie.
+func NormalizeSource(source string) (string, SourceStyle) {
+	var style SourceStyle
+	if strings.HasPrefix(source, parser.ByteOrderMark) {
+		style.BOM = true
+		source = source[len(parser.ByteOrderMark):]
+	}
+
+	crlf := strings.Count(source, "\r\n")
+	style.LineEnding = LF
+	if crlf > strings.Count(source, "\n")-crlf {
+		style.LineEnding = CRLF
+	}
+	return strings.ReplaceAll(source, "\r\n", "\n"), style
+}
+
+// Apply writes code, whose lines end with \n, in style
+func (s SourceStyle) Apply(code string) string {
+	if s.LineEnding == CRLF {
+		code = strings.ReplaceAll(code, "\n", "\r\n")
+	}
+	if s.BOM {
+		code = parser.ByteOrderMark + code
+	}
+	return code
+}
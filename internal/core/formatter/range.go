@@ -0,0 +1,83 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// FormatRange formats the statements of src, the source tree was parsed
+// from, that intersect the lines startLine to endLine, counted from 1 and
+// included, as editors do to format a selection. Other lines are kept as
+// written. A statement spans its leading comments and annotations, and the
+// lines up to the next statement, so the blank lines after it are formatted
+// with it; the header of an if, a loop or a definition spans the lines up to
+// its first nested statement. Members of classes are kept in source order,
+// as moving a member would move lines outside the range.
+//
+// The result is checked like the output of gdformat: when it would not
+// parse, or would not behave as src does, e.g. because the selection is
+// indented with tabs and the lines around it with spaces, FormatRange fails.
+func FormatRange(tree *ast.AbstractSyntaxTree, src string, startLine, endLine int, config *Config) (string, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	lines, err := formatLines(tree, config, true)
+	if err != nil {
+		return "", err
+	}
+
+	// The formatted lines of each statement start at its source line; both
+	// must increase for the statements to pair up
+	var formatted []string
+	type anchor struct{ source, formatted int }
+	anchors := []anchor{{1, 0}}
+	for _, line := range lines {
+		last := anchors[len(anchors)-1]
+		if line.LineNumber > last.source && len(formatted) > last.formatted {
+			anchors = append(anchors, anchor{line.LineNumber, len(formatted)})
+		}
+		formatted = append(formatted, strings.Split(line.Content, "\n")...)
+	}
+
+	source := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	if src == "" {
+		source = nil
+	}
+	anchors = append(anchors, anchor{len(source) + 1, len(formatted)})
+
+	// Each statement is replaced by its formatted lines when it intersects the range
+	var result []string
+	for i, a := range anchors[:len(anchors)-1] {
+		next := anchors[i+1]
+		if next.source > len(source)+1 {
+			next.source = len(source) + 1
+		}
+		if a.source <= endLine && next.source-1 >= startLine {
+			result = append(result, formatted[a.formatted:next.formatted]...)
+		} else if a.source <= len(source) {
+			result = append(result, source[a.source-1:next.source-1]...)
+		}
+	}
+	code := strings.Join(result, "\n")
+	if len(result) > 0 {
+		code += "\n"
+	}
+
+	// Never return code that would no longer parse, or that would behave differently
+	name := ""
+	if tree.RootClass != nil {
+		name = tree.RootClass.Name
+	}
+	check, errors := parser.ParseFile(name, code)
+	if len(errors) > 0 {
+		return "", fmt.Errorf("formatting lines %d to %d alone gives code that does not parse: %v", startLine, endLine, errors[0])
+	}
+	if differences := ast.Compare(tree, check); len(differences) > 0 {
+		return "", fmt.Errorf("formatting lines %d to %d alone changes the code at %s: %s",
+			startLine, endLine, differences[0].A, differences[0].Message)
+	}
+	return code, nil
+}
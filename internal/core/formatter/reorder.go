@@ -0,0 +1,61 @@
+package formatter
+
+import (
+	"sort"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// ReorderMembers sorts the members of every class of tree into the order
+// class-definitions-order expects: signals, enums, constants, exported,
+// public, private and @onready variables, then _init, _ready, public and
+// private functions. Members of a category keep their relative order, and
+// their comments and annotations move with them. Classes with a member in a
+// gdformat: off region are left as written.
+func ReorderMembers(tree *ast.AbstractSyntaxTree) {
+	if tree.RootClass != nil {
+		reorderClass(tree.RootClass, tree.Unformatted)
+	}
+}
+
+// reorderClass sorts the members of class and of its inner classes
+func reorderClass(class *ast.Class, unformatted []ast.SourceRegion) {
+	for _, subClass := range class.SubClasses {
+		reorderClass(subClass, unformatted)
+	}
+	if inRegions(class.Statements, unformatted) || inRegions(class.Functions, unformatted) {
+		return
+	}
+
+	sort.SliceStable(class.Statements, func(i, j int) bool {
+		return ast.MemberKindOf(class.Statements[i]) < ast.MemberKindOf(class.Statements[j])
+	})
+	sort.SliceStable(class.Functions, func(i, j int) bool {
+		return functionRank(class.Functions[i]) < functionRank(class.Functions[j])
+	})
+}
+
+// functionRank returns the place of a function among the functions of a class
+func functionRank(function *ast.Function) int {
+	switch {
+	case function.Name == "_static_init" || function.Name == "_init":
+		return 0
+	case function.Name == "_ready":
+		return 1
+	case len(function.Name) > 0 && function.Name[0] == '_':
+		return 3
+	}
+	return 2
+}
+
+// inRegions reports whether one of members starts in one of regions
+func inRegions[T ast.Statement](members []T, regions []ast.SourceRegion) bool {
+	for _, member := range members {
+		for _, region := range regions {
+			if region.Contains(member.Position().Line) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+// Package godotapi is a database of the Godot engine classes, with their
+// methods, properties and signals, read from the JSON API dump the engine
+// produces with `godot --dump-extension-api`.
+//
+// An embedded database is available through Default. It is generated with
+// gen.go and covers the core classes scripts extend and call most; a full
+// dump can be loaded with LoadFile for projects that need the whole API.
+package godotapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+//go:generate go run gen.go -o api.json extension_api.json
+
+//go:embed api.json
+var embeddedAPI []byte
+
+// API is a database of engine classes
+type API struct {
+	Header  Header
+	Classes map[string]*Class
+	// GlobalEnums are the enums of @GlobalScope, such as Error and Key,
+	// which scripts use as types; the embedded database has none
+	GlobalEnums map[string]bool
+}
+
+// Header identifies the engine version an API was dumped from
+type Header struct {
+	VersionMajor int `json:"version_major"`
+	VersionMinor int `json:"version_minor"`
+}
+
+// Class is an engine class
+type Class struct {
+	Name       string      `json:"name"`
+	Inherits   string      `json:"inherits,omitempty"`
+	Methods    []*Method   `json:"methods,omitempty"`
+	Properties []*Property `json:"properties,omitempty"`
+	Signals    []*Signal   `json:"signals,omitempty"`
+}
+
+// Method is a method of an engine class. Virtual methods are the ones
+// scripts override, such as Node._ready.
+type Method struct {
+	Name        string       `json:"name"`
+	IsVirtual   bool         `json:"is_virtual,omitempty"`
+	IsStatic    bool         `json:"is_static,omitempty"`
+	IsVararg    bool         `json:"is_vararg,omitempty"`
+	Arguments   []*Argument  `json:"arguments,omitempty"`
+	ReturnValue *ReturnValue `json:"return_value,omitempty"`
+}
+
+// Argument is an argument of a method or signal
+type Argument struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	DefaultValue string `json:"default_value,omitempty"`
+}
+
+// ReturnValue is the value a method returns
+type ReturnValue struct {
+	Type string `json:"type"`
+}
+
+// Property is a property of an engine class
+type Property struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Signal is a signal of an engine class
+type Signal struct {
+	Name      string      `json:"name"`
+	Arguments []*Argument `json:"arguments,omitempty"`
+}
+
+// ReturnType returns the type the method returns, or "void"
+func (m *Method) ReturnType() string {
+	if m.ReturnValue == nil {
+		return "void"
+	}
+	return m.ReturnValue.Type
+}
+
+// MinArguments returns the number of arguments a call must pass
+func (m *Method) MinArguments() int {
+	count := 0
+	for _, arg := range m.Arguments {
+		if arg.DefaultValue == "" {
+			count++
+		}
+	}
+	return count
+}
+
+// MaxArguments returns the number of arguments a call may pass, or -1 when
+// the method takes any number of extra arguments
+func (m *Method) MaxArguments() int {
+	if m.IsVararg {
+		return -1
+	}
+	return len(m.Arguments)
+}
+
+// dump is the layout of the JSON API dump; only the fields the database uses are read
+type dump struct {
+	Header      Header   `json:"header"`
+	Classes     []*Class `json:"classes"`
+	GlobalEnums []struct {
+		Name string `json:"name"`
+	} `json:"global_enums"`
+}
+
+// Load reads an API from a JSON API dump
+func Load(r io.Reader) (*API, error) {
+	var d dump
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("failed to parse API dump: %w", err)
+	}
+
+	api := &API{Header: d.Header, Classes: make(map[string]*Class, len(d.Classes))}
+	for _, class := range d.Classes {
+		api.Classes[class.Name] = class
+	}
+	if len(d.GlobalEnums) > 0 {
+		api.GlobalEnums = make(map[string]bool, len(d.GlobalEnums))
+		for _, enum := range d.GlobalEnums {
+			api.GlobalEnums[enum.Name] = true
+		}
+	}
+	return api, nil
+}
+
+// LoadFile reads an API from a JSON API dump file
+func LoadFile(path string) (*API, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API dump: %w", err)
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+var (
+	defaultOnce sync.Once
+	defaultAPI  *API
+)
+
+// Default returns the embedded API database
+func Default() *API {
+	defaultOnce.Do(func() {
+		api, err := Load(bytes.NewReader(embeddedAPI))
+		if err != nil {
+			panic("godotapi: embedded API is invalid: " + err.Error())
+		}
+		defaultAPI = api
+	})
+	return defaultAPI
+}
+
+// Class returns the class named name, or nil when the API has no such class
+func (a *API) Class(name string) *Class {
+	return a.Classes[name]
+}
+
+// HasClass reports whether the API has a class named name
+func (a *API) HasClass(name string) bool {
+	_, ok := a.Classes[name]
+	return ok
+}
+
+// HasGlobalEnum reports whether the API has a global enum named name
+func (a *API) HasGlobalEnum(name string) bool {
+	return a.GlobalEnums[name]
+}
+
+// ClassNames returns the names of every class, sorted
+func (a *API) ClassNames() []string {
+	names := make([]string, 0, len(a.Classes))
+	for name := range a.Classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ancestors returns the classes class inherits from, closest first
+func (a *API) Ancestors(class string) []string {
+	var ancestors []string
+	for c := a.Classes[class]; c != nil && c.Inherits != ""; c = a.Classes[c.Inherits] {
+		ancestors = append(ancestors, c.Inherits)
+	}
+	return ancestors
+}
+
+// IsSubclassOf reports whether class is ancestor or inherits from it
+func (a *API) IsSubclassOf(class, ancestor string) bool {
+	if !a.HasClass(class) {
+		return false
+	}
+	if class == ancestor {
+		return true
+	}
+	for _, c := range a.Ancestors(class) {
+		if c == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Method looks up a method of class or of the classes it inherits from
+func (a *API) Method(class, name string) *Method {
+	for c := a.Classes[class]; c != nil; c = a.Classes[c.Inherits] {
+		for _, method := range c.Methods {
+			if method.Name == name {
+				return method
+			}
+		}
+	}
+	return nil
+}
+
+// Property looks up a property of class or of the classes it inherits from
+func (a *API) Property(class, name string) *Property {
+	for c := a.Classes[class]; c != nil; c = a.Classes[c.Inherits] {
+		for _, property := range c.Properties {
+			if property.Name == name {
+				return property
+			}
+		}
+	}
+	return nil
+}
+
+// Signal looks up a signal of class or of the classes it inherits from
+func (a *API) Signal(class, name string) *Signal {
+	for c := a.Classes[class]; c != nil; c = a.Classes[c.Inherits] {
+		for _, signal := range c.Signals {
+			if signal.Name == name {
+				return signal
+			}
+		}
+	}
+	return nil
+}
+
+// VirtualMethods returns the virtual methods a script extending class can
+// override, by name. Overrides in subclasses take precedence.
+func (a *API) VirtualMethods(class string) map[string]*Method {
+	virtuals := make(map[string]*Method)
+	for c := a.Classes[class]; c != nil; c = a.Classes[c.Inherits] {
+		for _, method := range c.Methods {
+			if _, overridden := virtuals[method.Name]; method.IsVirtual && !overridden {
+				virtuals[method.Name] = method
+			}
+		}
+	}
+	return virtuals
+}
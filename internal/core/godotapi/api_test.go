@@ -0,0 +1,119 @@
+package godotapi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	api := Default()
+
+	for _, name := range []string{"Object", "Node", "Node2D", "CharacterBody2D", "Control", "Resource"} {
+		if !api.HasClass(name) {
+			t.Errorf("Expected the embedded API to have class %s", name)
+		}
+	}
+	// Every parent must be in the database, or lookups would stop half way up
+	for _, name := range api.ClassNames() {
+		if parent := api.Class(name).Inherits; parent != "" && !api.HasClass(parent) {
+			t.Errorf("Class %s inherits from %s, which is missing", name, parent)
+		}
+	}
+
+	if !api.IsSubclassOf("CharacterBody2D", "Node") || api.IsSubclassOf("Node", "Resource") {
+		t.Error("Unexpected inheritance for CharacterBody2D or Node")
+	}
+	if got := strings.Join(api.Ancestors("Sprite2D"), " "); got != "Node2D CanvasItem Node Object" {
+		t.Errorf("Expected Sprite2D ancestors 'Node2D CanvasItem Node Object', got %q", got)
+	}
+
+	if api.Method("CharacterBody2D", "add_child") == nil {
+		t.Error("Expected inherited method Node.add_child on CharacterBody2D")
+	}
+	if api.Method("Node", "move_and_slide") != nil {
+		t.Error("Expected no method move_and_slide on Node")
+	}
+	if p := api.Property("Sprite2D", "position"); p == nil || p.Type != "Vector2" {
+		t.Errorf("Expected property Sprite2D.position of type Vector2, got %+v", p)
+	}
+	if api.Signal("Button", "pressed") == nil {
+		t.Error("Expected inherited signal BaseButton.pressed on Button")
+	}
+}
+
+func TestVirtualMethods(t *testing.T) {
+	virtuals := Default().VirtualMethods("Node2D")
+
+	for _, name := range []string{"_ready", "_process", "_physics_process", "_draw", "_init", "_notification"} {
+		if virtuals[name] == nil {
+			t.Errorf("Expected virtual method %s on Node2D", name)
+		}
+	}
+	if virtuals["_gui_input"] != nil {
+		t.Error("Expected no virtual method _gui_input on Node2D")
+	}
+	if process := virtuals["_process"]; process != nil && process.MinArguments() != 1 {
+		t.Errorf("Expected _process to take 1 argument, got %d", process.MinArguments())
+	}
+}
+
+func TestMethodArity(t *testing.T) {
+	tests := []struct {
+		class, method string
+		min, max      int
+		returns       string
+	}{
+		{"Node", "add_child", 1, 3, "void"},
+		{"Node", "get_node", 1, 1, "Node"},
+		{"Object", "emit_signal", 1, -1, "int"},
+		{"Node", "queue_free", 0, 0, "void"},
+	}
+
+	for _, tt := range tests {
+		method := Default().Method(tt.class, tt.method)
+		if method == nil {
+			t.Errorf("Expected method %s.%s", tt.class, tt.method)
+			continue
+		}
+		if method.MinArguments() != tt.min || method.MaxArguments() != tt.max {
+			t.Errorf("%s.%s: expected %d..%d arguments, got %d..%d",
+				tt.class, tt.method, tt.min, tt.max, method.MinArguments(), method.MaxArguments())
+		}
+		if method.ReturnType() != tt.returns {
+			t.Errorf("%s.%s: expected return type %s, got %s", tt.class, tt.method, tt.returns, method.ReturnType())
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	// A fragment of a dump, with fields the database does not read
+	input := `{
+	"header": {"version_major": 4, "version_minor": 3, "version_patch": 0},
+	"builtin_classes": [],
+	"global_enums": [{"name": "Error", "is_bitfield": false, "values": [{"name": "OK", "value": 0}]}],
+	"classes": [
+		{"name": "Object", "is_refcounted": false, "api_type": "core"},
+		{"name": "Node", "inherits": "Object", "methods": [
+			{"name": "_ready", "is_const": false, "is_virtual": true, "hash": 3218959716}
+		]}
+	]
+}`
+
+	api, err := Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if api.Header.VersionMinor != 3 || len(api.Classes) != 2 {
+		t.Errorf("Expected a 4.3 API with 2 classes, got %+v", api)
+	}
+	if method := api.Method("Node", "_ready"); method == nil || !method.IsVirtual {
+		t.Errorf("Expected virtual method Node._ready, got %+v", method)
+	}
+	if !api.HasGlobalEnum("Error") || api.HasGlobalEnum("Node") {
+		t.Errorf("Expected the global enum Error only, got %v", api.GlobalEnums)
+	}
+
+	if _, err := Load(strings.NewReader("{")); err == nil {
+		t.Error("Expected an error for a truncated dump")
+	}
+}
@@ -0,0 +1,171 @@
+//go:build ignore
+
+// gen trims a JSON API dump, as written by `godot --dump-extension-api`, to
+// the fields and classes the database uses and writes api.json. Run it with
+// `go generate ./internal/core/godotapi` after copying a dump next to it, or
+// directly to keep every class:
+//
+//	go run gen.go -all -o api.json path/to/extension_api.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+// embeddedClasses are the classes kept in the embedded database unless -all is given
+var embeddedClasses = []string{
+	"Object", "RefCounted", "Resource", "Node", "MainLoop", "SceneTree", "SceneTreeTimer",
+	"Tween", "Timer", "Viewport", "Window", "AnimationMixer", "AnimationPlayer",
+	"PackedScene", "Texture", "Texture2D", "Script", "Material", "InputEvent",
+
+	"CanvasItem", "Node2D", "Sprite2D", "AnimatedSprite2D", "Camera2D", "CollisionObject2D",
+	"Area2D", "PhysicsBody2D", "StaticBody2D", "CharacterBody2D", "RigidBody2D", "CollisionShape2D",
+
+	"Node3D", "Camera3D", "VisualInstance3D", "GeometryInstance3D", "MeshInstance3D",
+	"CollisionObject3D", "Area3D", "PhysicsBody3D", "StaticBody3D", "CharacterBody3D", "RigidBody3D",
+
+	"Control", "Container", "BoxContainer", "HBoxContainer", "VBoxContainer", "Label",
+	"BaseButton", "Button", "LineEdit", "TextureRect",
+}
+
+// scriptVirtuals are the Object methods scripts override that the dump does
+// not list, because the engine dispatches them to the script instance itself
+var scriptVirtuals = []method{
+	{Name: "_get", IsVirtual: true, Arguments: []argument{{Name: "property", Type: "StringName"}}, ReturnValue: &returnValue{Type: "Variant"}},
+	{Name: "_get_property_list", IsVirtual: true, ReturnValue: &returnValue{Type: "Array"}},
+	{Name: "_init", IsVirtual: true},
+	{Name: "_notification", IsVirtual: true, Arguments: []argument{{Name: "what", Type: "int"}}},
+	{Name: "_property_can_revert", IsVirtual: true, Arguments: []argument{{Name: "property", Type: "StringName"}}, ReturnValue: &returnValue{Type: "bool"}},
+	{Name: "_property_get_revert", IsVirtual: true, Arguments: []argument{{Name: "property", Type: "StringName"}}, ReturnValue: &returnValue{Type: "Variant"}},
+	{Name: "_set", IsVirtual: true, Arguments: []argument{{Name: "property", Type: "StringName"}, {Name: "value", Type: "Variant"}}, ReturnValue: &returnValue{Type: "bool"}},
+	{Name: "_to_string", IsVirtual: true, ReturnValue: &returnValue{Type: "String"}},
+	{Name: "_validate_property", IsVirtual: true, Arguments: []argument{{Name: "property", Type: "Dictionary"}}},
+}
+
+// The types below mirror the ones in api.go, which this program cannot import
+type dump struct {
+	Header  header  `json:"header"`
+	Classes []class `json:"classes"`
+}
+
+type header struct {
+	VersionMajor int `json:"version_major"`
+	VersionMinor int `json:"version_minor"`
+}
+
+type class struct {
+	Name       string     `json:"name"`
+	Inherits   string     `json:"inherits,omitempty"`
+	Methods    []method   `json:"methods,omitempty"`
+	Properties []property `json:"properties,omitempty"`
+	Signals    []signal   `json:"signals,omitempty"`
+}
+
+type method struct {
+	Name        string       `json:"name"`
+	IsVirtual   bool         `json:"is_virtual,omitempty"`
+	IsStatic    bool         `json:"is_static,omitempty"`
+	IsVararg    bool         `json:"is_vararg,omitempty"`
+	Arguments   []argument   `json:"arguments,omitempty"`
+	ReturnValue *returnValue `json:"return_value,omitempty"`
+}
+
+type argument struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	DefaultValue string `json:"default_value,omitempty"`
+}
+
+type returnValue struct {
+	Type string `json:"type"`
+}
+
+type property struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type signal struct {
+	Name      string     `json:"name"`
+	Arguments []argument `json:"arguments,omitempty"`
+}
+
+func main() {
+	output := flag.String("o", "api.json", "output file")
+	all := flag.Bool("all", false, "keep every class of the dump")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("usage: go run gen.go [-all] [-o api.json] extension_api.json")
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var d dump
+	if err := json.Unmarshal(data, &d); err != nil {
+		log.Fatalf("%s: %v", flag.Arg(0), err)
+	}
+
+	keep := make(map[string]bool)
+	for _, name := range embeddedClasses {
+		keep[name] = true
+	}
+
+	var classes []class
+	for _, c := range d.Classes {
+		if !*all && !keep[c.Name] {
+			continue
+		}
+		if c.Name == "Object" {
+			c.Methods = addScriptVirtuals(c.Methods)
+		}
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+
+	if !*all {
+		found := make(map[string]bool)
+		for _, c := range classes {
+			found[c.Name] = true
+		}
+		for _, name := range embeddedClasses {
+			if !found[name] {
+				log.Fatalf("class %s is missing from the dump", name)
+			}
+		}
+	}
+
+	d.Classes = classes
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(d); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, out.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %d classes to %s\n", len(classes), *output)
+}
+
+// addScriptVirtuals adds the script virtuals the methods do not declare yet
+func addScriptVirtuals(methods []method) []method {
+	declared := make(map[string]bool)
+	for _, m := range methods {
+		declared[m.Name] = true
+	}
+	for _, m := range scriptVirtuals {
+		if !declared[m.Name] {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
@@ -3,15 +3,141 @@ package linter
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/godotapi"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
 )
 
 // Config represents the linter configuration
 type Config struct {
-	DisabledRules []string       `json:"disabled_rules"`
-	RuleSettings  map[string]any `json:"rule_settings"`
+	DisabledRules []string `json:"disabled_rules"`
+	// EnabledRules turns on rules that are not enabled by default
+	EnabledRules []string          `json:"enabled_rules"`
+	RuleSettings map[string]any    `json:"rule_settings"`
+	Strict       []StrictDirectory `json:"strict"`
+	// Severities overrides the severity each rule reports its problems with
+	Severities map[string]problem.Severity `json:"severity"`
+	// GodotAPI is the path of a JSON API dump to check scripts against instead
+	// of the embedded API database
+	GodotAPI string `json:"godot_api"`
+	// External declares rules implemented by executables
+	External []ExternalRuleSpec `json:"external_rules"`
+	// TabWidth makes the columns of problems count a tab up to the next
+	// multiple of it, as editors display tabs; 0 counts a tab as one column
+	TabWidth int `json:"tab_width"`
+
+	// Dir is the directory of the config file; strict paths and the API dump
+	// path are relative to it
+	Dir string `json:"-"`
+
+	api     *godotapi.API
+	apiPath string
+}
+
+// API returns the engine API database rules check scripts against: the dump
+// configured with godot_api, or the embedded database
+func (c Config) API() *godotapi.API {
+	if c.api != nil {
+		return c.api
+	}
+	return godotapi.Default()
+}
+
+// HasCompleteAPI reports whether the engine API is a full dump configured
+// with godot_api, rather than the embedded database, which only has the
+// core classes
+func (c Config) HasCompleteAPI() bool {
+	return c.api != nil
+}
+
+// StrictDirectory escalates problems found in files under a directory to errors
+type StrictDirectory struct {
+	// Path is a slash-separated glob such as "src/core" or "src/*/core/**";
+	// a file matches when the glob matches the file or one of its parent directories
+	Path string `json:"path"`
+	// Rules lists the rules to escalate; empty means every rule
+	Rules []string `json:"rules"`
+}
+
+// Severity resolves the severity of a problem found in the file at filePath:
+// the severity configured for the rule or else the one the rule reported, or
+// error when a strict directory covers the file and the rule
+func (c Config) Severity(filePath string, p problem.Problem) problem.Severity {
+	severity := p.Severity
+	if override, ok := c.Severities[p.RuleName]; ok {
+		severity = override
+	}
+	if filePath == "" || len(c.Strict) == 0 {
+		return severity
+	}
+
+	rel := filePath
+	if c.Dir != "" {
+		if r, err := filepath.Rel(c.Dir, filePath); err == nil {
+			rel = r
+		}
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+
+	for _, strict := range c.Strict {
+		if strict.covers(rel, p.RuleName) {
+			return problem.Error
+		}
+	}
+	return severity
+}
+
+// covers reports whether the strict directory applies to rule in the file at rel
+func (s StrictDirectory) covers(rel, rule string) bool {
+	if len(s.Rules) > 0 && !containsString(s.Rules, rule) {
+		return false
+	}
+
+	pattern := strings.Split(strings.Trim(path.Clean(s.Path), "/"), "/")
+	segments := strings.Split(rel, "/")
+	// The file itself or any of its parent directories may match
+	for n := len(segments); n > 0; n-- {
+		if matchSegments(pattern, segments[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against glob segments, where "**"
+// matches any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // IsRuleEnabled returns whether a rule is enabled
@@ -29,6 +155,12 @@ func (c Config) GetRuleSetting(ruleName, settingName string, defaultValue any) a
 	if settings, ok := c.RuleSettings[ruleName]; ok {
 		if settingsMap, ok := settings.(map[string]any); ok {
 			if value, ok := settingsMap[settingName]; ok {
+				// JSON numbers decode as float64; rules expect the type of their default
+				if number, ok := value.(float64); ok {
+					if _, ok := defaultValue.(int); ok {
+						return int(number)
+					}
+				}
 				return value
 			}
 		}
@@ -36,11 +168,42 @@ func (c Config) GetRuleSetting(ruleName, settingName string, defaultValue any) a
 	return defaultValue
 }
 
+// Setting returns the configured value of a setting of rule, or its default
+func (c Config) Setting(rule ConfigurableRule, name string) any {
+	var defaultValue any
+	for _, setting := range rule.Settings() {
+		if setting.Name == name {
+			defaultValue = setting.Default
+		}
+	}
+	return c.GetRuleSetting(rule.Name(), name, defaultValue)
+}
+
+// Fingerprint identifies everything in the config that affects the problems
+// found, including the API dump it loaded and the external rules it runs, so that cached results are not
+// reused once the config changes
+func (c Config) Fingerprint() string {
+	data, _ := json.Marshal(c)
+	fingerprint := string(data) + "\x00" + c.Dir
+	// The size and modification time of the API dump and of the files run by
+	// external rules stand in for their content
+	files := []string{c.apiPath}
+	for _, rule := range c.ExternalRules() {
+		files = append(files, rule.(*ExternalRule).files()...)
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && file != "" {
+			fingerprint += fmt.Sprintf("\x00%s\x00%d\x00%d", file, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return fingerprint
+}
+
 // LoadConfig loads a configuration from a file
 func LoadConfig(path string) (Config, error) {
 	var config Config
 
-	data, err := ioutil.ReadFile(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return config, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -50,6 +213,32 @@ func LoadConfig(path string) (Config, error) {
 		return config, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	for rule, severity := range config.Severities {
+		if !severity.Valid() {
+			return config, fmt.Errorf("invalid severity %q for rule %q, expected error, warning or info", severity, rule)
+		}
+	}
+
+	if config.TabWidth < 0 {
+		return config, fmt.Errorf("invalid tab_width %d, expected a positive number or 0", config.TabWidth)
+	}
+
+	if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+		config.Dir = dir
+	}
+
+	if config.GodotAPI != "" {
+		apiPath := config.GodotAPI
+		if !filepath.IsAbs(apiPath) {
+			apiPath = filepath.Join(config.Dir, apiPath)
+		}
+		config.apiPath = apiPath
+		config.api, err = godotapi.LoadFile(apiPath)
+		if err != nil {
+			return config, err
+		}
+	}
+
 	return config, nil
 }
 
@@ -65,7 +254,7 @@ func DefaultConfig() Config {
 			"max-branches":              12,
 			"max-statements":            50,
 			"max-attributes":            10,
-			"max-local-variables":       15,
+			"max-locals":                15,
 			"function-arguments-number": 10,
 		},
 	}
@@ -77,7 +266,12 @@ func FindConfigFile() string {
 	if err != nil {
 		return ""
 	}
+	return FindConfigFileFrom(dir)
+}
 
+// FindConfigFileFrom searches for a config file in dir and its parent
+// directories, so that each file is linted with the config closest to it
+func FindConfigFileFrom(dir string) string {
 	for {
 		configPath := filepath.Join(dir, "gdlintrc.json")
 		if _, err := os.Stat(configPath); err == nil {
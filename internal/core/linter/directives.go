@@ -3,8 +3,11 @@ package linter
 import (
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
 )
 
 // RuleDirective represents a linting directive found in comments
@@ -12,6 +15,14 @@ type RuleDirective struct {
 	Type  DirectiveType
 	Rules []string
 	Line  int
+	// Column is the column of the # starting the directive
+	Column int
+	// Reason is the explanation following "--", as in
+	// # gdlint:ignore=unused-argument -- required by the signal
+	Reason string
+	// EndOfLine is set for a directive following code on its line; an
+	// ignore directive then applies to that line rather than the next one
+	EndOfLine bool
 }
 
 // DirectiveType represents the type of linting directive
@@ -23,47 +34,34 @@ const (
 	DirectiveEnable
 )
 
+// directiveTypes maps the keyword of a directive to its type
+var directiveTypes = map[string]DirectiveType{
+	"ignore":  DirectiveIgnore,
+	"disable": DirectiveDisable,
+	"enable":  DirectiveEnable,
+}
+
+var directivePattern = regexp.MustCompile(`#\s*gdlint\s*:\s*(ignore|disable|enable)\s*=\s*([^#\n]+)`)
+
 // ParseDirectives extracts linting directives from source code comments
 func ParseDirectives(source string) []RuleDirective {
 	var directives []RuleDirective
 
-	// Regular expressions for different directive types
-	ignorePattern := regexp.MustCompile(`#\s*gdlint\s*:\s*ignore\s*=\s*([^#\n]+)`)
-	disablePattern := regexp.MustCompile(`#\s*gdlint\s*:\s*disable\s*=\s*([^#\n]+)`)
-	enablePattern := regexp.MustCompile(`#\s*gdlint\s*:\s*enable\s*=\s*([^#\n]+)`)
-
 	lines := strings.Split(source, "\n")
 
 	for i, line := range lines {
-		lineNum := i + 1
-
-		// Check for ignore directive
-		if matches := ignorePattern.FindStringSubmatch(line); len(matches) > 1 {
-			rules := parseRuleList(matches[1])
-			directives = append(directives, RuleDirective{
-				Type:  DirectiveIgnore,
-				Rules: rules,
-				Line:  lineNum,
-			})
-		}
-
-		// Check for disable directive
-		if matches := disablePattern.FindStringSubmatch(line); len(matches) > 1 {
-			rules := parseRuleList(matches[1])
-			directives = append(directives, RuleDirective{
-				Type:  DirectiveDisable,
-				Rules: rules,
-				Line:  lineNum,
-			})
+		if i == 0 {
+			line = strings.TrimPrefix(line, parser.ByteOrderMark)
 		}
-
-		// Check for enable directive
-		if matches := enablePattern.FindStringSubmatch(line); len(matches) > 1 {
-			rules := parseRuleList(matches[1])
+		for _, match := range directivePattern.FindAllStringSubmatchIndex(line, -1) {
+			rules, reason, _ := strings.Cut(line[match[4]:match[5]], "--")
 			directives = append(directives, RuleDirective{
-				Type:  DirectiveEnable,
-				Rules: rules,
-				Line:  lineNum,
+				Type:      directiveTypes[line[match[2]:match[3]]],
+				Rules:     parseRuleList(rules),
+				Line:      i + 1,
+				Column:    utf8.RuneCountInString(line[:match[0]]) + 1,
+				Reason:    strings.TrimSpace(reason),
+				EndOfLine: strings.TrimSpace(line[:match[0]]) != "",
 			})
 		}
 	}
@@ -86,62 +84,139 @@ func parseRuleList(ruleStr string) []string {
 	return rules
 }
 
-// RuleContext tracks which rules are enabled/disabled at different positions
+// RuleContext tracks which rules are enabled/disabled at different positions,
+// and which directives suppressed a problem
 type RuleContext struct {
-	globallyDisabled map[string]bool
-	ignoredAtLine    map[int]map[string]bool
+	disabled      map[string][]disabledRange
+	ignoredAtLine map[int]map[string]*RuleDirective
+	suppressions  []*RuleDirective
+	used          map[*RuleDirective]map[string]bool
 }
 
 // NewRuleContext creates a new rule context
 func NewRuleContext() *RuleContext {
 	return &RuleContext{
-		globallyDisabled: make(map[string]bool),
-		ignoredAtLine:    make(map[int]map[string]bool),
+		disabled:      make(map[string][]disabledRange),
+		ignoredAtLine: make(map[int]map[string]*RuleDirective),
+		used:          make(map[*RuleDirective]map[string]bool),
 	}
 }
 
 // ProcessDirectives processes all directives and updates the rule context
 func (rc *RuleContext) ProcessDirectives(directives []RuleDirective) {
-	for _, directive := range directives {
+	for i := range directives {
+		directive := &directives[i]
 		switch directive.Type {
 		case DirectiveIgnore:
-			// Mark rules as ignored for the next line
-			nextLine := directive.Line + 1
-			if rc.ignoredAtLine[nextLine] == nil {
-				rc.ignoredAtLine[nextLine] = make(map[string]bool)
+			// Mark rules as ignored for the next line, or for the line of
+			// the directive when it follows code
+			line := directive.Line + 1
+			if directive.EndOfLine {
+				line = directive.Line
+			}
+			if rc.ignoredAtLine[line] == nil {
+				rc.ignoredAtLine[line] = make(map[string]*RuleDirective)
 			}
 			for _, rule := range directive.Rules {
-				rc.ignoredAtLine[nextLine][rule] = true
+				rc.ignoredAtLine[line][rule] = directive
 			}
+			rc.suppressions = append(rc.suppressions, directive)
 
 		case DirectiveDisable:
-			// Mark rules as globally disabled
+			// Disable rules from this line to the end of the file, unless
+			// they are already disabled
 			for _, rule := range directive.Rules {
-				rc.globallyDisabled[rule] = true
+				if ranges := rc.disabled[rule]; len(ranges) == 0 || ranges[len(ranges)-1].end != 0 {
+					rc.disabled[rule] = append(ranges, disabledRange{directive: directive, start: directive.Line})
+				}
 			}
+			rc.suppressions = append(rc.suppressions, directive)
 
 		case DirectiveEnable:
-			// Mark rules as globally enabled (remove from disabled list)
+			// Enable rules again from this line
 			for _, rule := range directive.Rules {
-				delete(rc.globallyDisabled, rule)
+				if ranges := rc.disabled[rule]; len(ranges) > 0 && ranges[len(ranges)-1].end == 0 {
+					ranges[len(ranges)-1].end = directive.Line
+				}
 			}
 		}
 	}
 }
 
-// IsRuleEnabled checks if a rule is enabled at a specific position
+// IsRuleEnabled checks if a rule is enabled at a specific position. When it
+// is not, the directive suppressing the rule is marked as used.
 func (rc *RuleContext) IsRuleEnabled(ruleName string, pos ast.Position) bool {
-	// Check if rule is globally disabled
-	if rc.globallyDisabled[ruleName] {
-		return false
+	// Check if rule is disabled at this line
+	for _, disabled := range rc.disabled[ruleName] {
+		if pos.Line >= disabled.start && (disabled.end == 0 || pos.Line < disabled.end) {
+			rc.markUsed(disabled.directive, ruleName)
+			return false
+		}
 	}
 
 	// Check if rule is ignored at this line
-	if ignoredRules, exists := rc.ignoredAtLine[pos.Line]; exists {
-		if ignoredRules[ruleName] {
-			return false
-		}
+	if directive, ok := rc.ignoredAtLine[pos.Line][ruleName]; ok {
+		rc.markUsed(directive, ruleName)
+		return false
 	}
 
 	return true
 }
+
+// disabledRange is the span of lines from a gdlint:disable directive to the
+// gdlint:enable directive of the same rule, or to the end of the file when end is 0
+type disabledRange struct {
+	directive  *RuleDirective
+	start, end int
+}
+
+func (rc *RuleContext) markUsed(directive *RuleDirective, ruleName string) {
+	if rc.used[directive] == nil {
+		rc.used[directive] = make(map[string]bool)
+	}
+	rc.used[directive][ruleName] = true
+}
+
+// UnusedSuppressions calls f for every rule an ignore or disable directive
+// names without suppressing any of its problems, among the rules for which
+// checked reports true
+func (rc *RuleContext) UnusedSuppressions(checked func(ruleName string) bool, f func(directive *RuleDirective, ruleName string)) {
+	for _, directive := range rc.suppressions {
+		for _, rule := range directive.Rules {
+			if checked(rule) && !rc.used[directive][rule] {
+				f(directive, rule)
+			}
+		}
+	}
+}
+
+// String returns the keyword of the directive type, as in gdlint:ignore
+func (t DirectiveType) String() string {
+	for keyword, directiveType := range directiveTypes {
+		if directiveType == t {
+			return keyword
+		}
+	}
+	return "unknown"
+}
+
+// UnusedSuppression checks for gdlint:ignore and gdlint:disable directives
+// that suppress no problem, so that they do not outlive the code they were
+// written for. The linter reports them once every other rule has run.
+type UnusedSuppression struct{}
+
+// Name returns the name of the rule
+func (r *UnusedSuppression) Name() string {
+	return "unused-suppression"
+}
+
+// Description returns a description of the rule
+func (r *UnusedSuppression) Description() string {
+	return "Checks for gdlint:ignore and gdlint:disable comments naming a rule that reports nothing there"
+}
+
+// Check finds nothing: the linter reports unused suppressions itself, as only
+// it knows which problems the directives suppressed
+func (r *UnusedSuppression) Check(tree *ast.AbstractSyntaxTree, config Config) []problem.Problem {
+	return nil
+}
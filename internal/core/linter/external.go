@@ -0,0 +1,196 @@
+package linter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// ExternalProtocolVersion is the version of the messages exchanged with
+// external rules. Version 2 decodes the Value of string literals, spelled as
+// written in Raw.
+const ExternalProtocolVersion = 2
+
+// ExternalRuleTimeout is how long an external rule may take to check a file
+const ExternalRuleTimeout = 30 * time.Second
+
+// ExternalRuleSpec declares, in the external_rules list of a gdlintrc, a rule
+// implemented by an executable
+type ExternalRuleSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Command is the executable and its arguments; a relative executable path
+	// containing a slash is relative to the config file
+	Command []string `json:"command"`
+}
+
+// ExternalRequest is written as JSON to the standard input of an external rule
+type ExternalRequest struct {
+	Version int    `json:"version"`
+	Rule    string `json:"rule"`
+	// Path is the absolute path of the script; empty when linting code that
+	// was not read from a file
+	Path   string `json:"path"`
+	Source string `json:"source"`
+	// Settings is the rule_settings entry of the rule
+	Settings any `json:"settings"`
+	// Tree is the syntax tree of the script, encoded by ast.Encode
+	Tree any `json:"tree"`
+}
+
+// ExternalResponse is read as JSON from the standard output of an external rule
+type ExternalResponse struct {
+	Problems []ExternalProblem `json:"problems"`
+}
+
+// ExternalProblem is a problem found by an external rule
+type ExternalProblem struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+	// Severity is error, warning or info; warning when empty
+	Severity problem.Severity `json:"severity"`
+}
+
+// SourceRule is a rule that reads the path and text of the file it checks,
+// not just its syntax tree
+type SourceRule interface {
+	Rule
+	// CheckSource applies the rule to the file at filePath, whose content is source
+	CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config Config) []problem.Problem
+}
+
+// ExternalRule runs an executable to check files. The executable reads an
+// ExternalRequest from its standard input and writes an ExternalResponse to
+// its standard output. When it fails, exits with a non-zero status or writes
+// anything else, the rule reports the failure as an error.
+type ExternalRule struct {
+	spec ExternalRuleSpec
+	dir  string // directory of the config file declaring the rule
+}
+
+// ExternalRules returns the rules declared in the external_rules list
+func (c Config) ExternalRules() []Rule {
+	var rules []Rule
+	for _, spec := range c.External {
+		rules = append(rules, &ExternalRule{spec: spec, dir: c.Dir})
+	}
+	return rules
+}
+
+// Name returns the name of the rule
+func (r *ExternalRule) Name() string {
+	return r.spec.Name
+}
+
+// Description returns the description of the rule
+func (r *ExternalRule) Description() string {
+	if r.spec.Description == "" {
+		return "External rule running " + strings.Join(r.spec.Command, " ")
+	}
+	return r.spec.Description
+}
+
+// Executable returns the path of the executable the rule runs
+func (r *ExternalRule) Executable() string {
+	if len(r.spec.Command) == 0 {
+		return ""
+	}
+	executable := r.spec.Command[0]
+	if strings.ContainsAny(executable, `/\`) && !filepath.IsAbs(executable) && r.dir != "" {
+		return filepath.Join(r.dir, executable)
+	}
+	return executable
+}
+
+// files returns the paths of the executable and of the arguments of the
+// command that name files, such as the script an interpreter runs
+func (r *ExternalRule) files() []string {
+	var files []string
+	for i, arg := range r.spec.Command {
+		path := arg
+		if i == 0 {
+			path = r.Executable()
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(r.dir, path)
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Check applies the rule to a tree without its source
+func (r *ExternalRule) Check(tree *ast.AbstractSyntaxTree, config Config) []problem.Problem {
+	return r.CheckSource("", "", tree, config)
+}
+
+// CheckSource runs the executable on the file
+func (r *ExternalRule) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config Config) []problem.Problem {
+	response, err := r.run(ExternalRequest{
+		Version:  ExternalProtocolVersion,
+		Rule:     r.spec.Name,
+		Path:     filePath,
+		Source:   source,
+		Settings: config.RuleSettings[r.spec.Name],
+		Tree:     ast.Encode(tree),
+	})
+	if err != nil {
+		return []problem.Problem{problem.NewError(ast.Position{Line: 1, Column: 1},
+			fmt.Sprintf("external rule failed: %v", err), r.spec.Name)}
+	}
+
+	var problems []problem.Problem
+	for _, p := range response.Problems {
+		severity := p.Severity
+		if severity == "" {
+			severity = problem.Warning
+		}
+		if !severity.Valid() {
+			return []problem.Problem{problem.NewError(ast.Position{Line: 1, Column: 1},
+				fmt.Sprintf("external rule reported the invalid severity %q", p.Severity), r.spec.Name)}
+		}
+		problems = append(problems, problem.NewProblem(ast.Position{Line: p.Line, Column: p.Column}, p.Message, r.spec.Name, severity))
+	}
+	return problems
+}
+
+func (r *ExternalRule) run(request ExternalRequest) (*ExternalResponse, error) {
+	if len(r.spec.Command) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ExternalRuleTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, r.Executable(), r.spec.Command[1:]...)
+	cmd.Dir = r.dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%s did not finish within %v", r.spec.Command[0], ExternalRuleTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.spec.Command[0], err)
+	}
+
+	var response ExternalResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("%s wrote invalid output: %w", r.spec.Command[0], err)
+	}
+	return &response, nil
+}
@@ -2,11 +2,14 @@ package linter
 
 import (
 	"fmt"
-	"sync"
+	"time"
 
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
 	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+	"github.com/dzannotti/gdtoolkit/internal/project"
 )
 
 // Rule represents a linting rule
@@ -19,10 +22,93 @@ type Rule interface {
 	Description() string
 }
 
+// AnalysisRule is a rule built on analyses shared with other rules. The
+// linter runs the passes it requires once per file, however many rules
+// require them, and hands the results to CheckResults.
+type AnalysisRule interface {
+	Rule
+	// Requires lists the analysis passes the rule uses
+	Requires() []analysis.Pass
+	// CheckResults applies the rule to the analyzed file
+	CheckResults(results *analysis.Results, config Config) []problem.Problem
+}
+
+// StatefulRule is a rule keeping state in its fields while it checks a file.
+// A linter shares its rules between the files it checks, several at a time
+// in LintProject and LintFiles, so other rules must only read their fields:
+// a StatefulRule checks each file with a fresh instance instead.
+type StatefulRule interface {
+	Rule
+	// NewInstance returns a rule configured like this one, without state
+	NewInstance() Rule
+}
+
+// ProjectRule is a rule checking a script against the other scripts of its
+// project. CheckProject runs when the linter has a project, set with
+// SetProject, and that project has the script being linted; otherwise the
+// rule runs like any other, and rules that only make sense in a project
+// find nothing.
+type ProjectRule interface {
+	Rule
+	// CheckProject applies the rule to script, a script of p
+	CheckProject(p *project.Project, script *project.Script, config Config) []problem.Problem
+}
+
+// Setting describes a value a rule reads from its rule_settings entry
+type Setting struct {
+	Name        string
+	Default     any
+	Description string
+}
+
+// ConfigurableRule is a rule with settings users can change in rule_settings
+type ConfigurableRule interface {
+	Rule
+	// Settings lists the settings the rule reads, with their defaults
+	Settings() []Setting
+}
+
+// SeverityRule is a rule that reports problems with a severity other than warning
+type SeverityRule interface {
+	Rule
+	// DefaultSeverity returns the severity of the problems the rule reports
+	DefaultSeverity() problem.Severity
+}
+
+// DefaultSeverity returns the severity rule reports its problems with before
+// any strict directory applies: warning, unless the rule declares otherwise
+func DefaultSeverity(rule Rule) problem.Severity {
+	if r, ok := rule.(SeverityRule); ok {
+		return r.DefaultSeverity()
+	}
+	return problem.Warning
+}
+
+// RuleSettings returns the settings rule reads, or nil when it has none
+func RuleSettings(rule Rule) []Setting {
+	if r, ok := rule.(ConfigurableRule); ok {
+		return r.Settings()
+	}
+	return nil
+}
+
+// ParseError is returned when the code to lint does not parse
+type ParseError struct {
+	Errors []error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing errors: %v", e.Errors)
+}
+
 // Linter performs linting on GDScript code
 type Linter struct {
 	rules  []Rule
 	config Config
+	// profile records the time rules take, when set
+	profile *Profile
+	// project holds the scripts project rules check files against, when set
+	project *project.Project
 }
 
 // NewLinter creates a new linter with the given rules and configuration
@@ -33,15 +119,48 @@ func NewLinter(rules []Rule, config Config) *Linter {
 	}
 }
 
+// NewLinterForConfig creates a linter running the rules of registry that
+// config enables, along with its external rules
+func NewLinterForConfig(registry *Registry, config Config) *Linter {
+	return NewLinter(registry.Select(config), config)
+}
+
+// SetProfile makes the linter record the time each rule takes to check each
+// file in profile, and the time each file takes to parse and lint
+func (l *Linter) SetProfile(profile *Profile) {
+	l.profile = profile
+}
+
+// SetProject makes the linter run the project rules on the scripts of p,
+// found by the path they are linted with
+func (l *Linter) SetProject(p *project.Project) {
+	l.project = p
+}
+
 // Lint lints the given code and returns any problems found
 func (l *Linter) Lint(code string) ([]problem.Problem, error) {
+	return l.LintSource("", code)
+}
+
+// LintSource lints code read from filePath; the path decides which strict
+// directories of the configuration apply to the problems found
+func (l *Linter) LintSource(filePath, code string) ([]problem.Problem, error) {
 	// Parse the code
-	tree, errors := parser.ParseFile("", code)
+	start := time.Now()
+	tree, errors := parser.ParseFile(filePath, code)
 	if len(errors) > 0 {
-		return nil, fmt.Errorf("parsing errors: %v", errors)
+		return nil, &ParseError{Errors: errors}
 	}
+	parsed := time.Now()
 
-	return l.LintASTWithSource(tree, code), nil
+	problems := l.lint(filePath, tree, code)
+	for i := range problems {
+		problems[i].Severity = l.config.Severity(filePath, problems[i])
+	}
+	if l.profile != nil {
+		l.profile.addFile(FileTiming{Path: filePath, Parse: parsed.Sub(start), Lint: time.Since(parsed)})
+	}
+	return problems, nil
 }
 
 // LintAST lints the given AST and returns any problems found
@@ -51,6 +170,11 @@ func (l *Linter) LintAST(tree *ast.AbstractSyntaxTree) []problem.Problem {
 
 // LintASTWithSource lints the given AST with source code for directive processing
 func (l *Linter) LintASTWithSource(tree *ast.AbstractSyntaxTree, source string) []problem.Problem {
+	return l.lint("", tree, source)
+}
+
+// lint applies the rules to the tree of the file at filePath, whose content is source
+func (l *Linter) lint(filePath string, tree *ast.AbstractSyntaxTree, source string) []problem.Problem {
 	var problems []problem.Problem
 
 	// Parse directives from source if available
@@ -61,10 +185,45 @@ func (l *Linter) LintASTWithSource(tree *ast.AbstractSyntaxTree, source string)
 		ruleContext.ProcessDirectives(directives)
 	}
 
+	// Analyses shared by the rules are computed once, before any rule runs
+	start := time.Now()
+	results := analysis.NewResults(tree)
+	for _, rule := range l.rules {
+		if analyzed, ok := rule.(AnalysisRule); ok && l.config.IsRuleEnabled(rule.Name()) {
+			results.Require(analyzed.Requires()...)
+		}
+	}
+	if l.profile != nil {
+		l.profile.addRule(AnalysisTiming, filePath, time.Since(start))
+	}
+
 	// Apply each enabled rule
+	ran := make(map[string]bool)
 	for _, rule := range l.rules {
+		if stateful, ok := rule.(StatefulRule); ok {
+			rule = stateful.NewInstance()
+		}
 		if l.config.IsRuleEnabled(rule.Name()) {
-			ruleProblems := rule.Check(tree, l.config)
+			ran[rule.Name()] = true
+			start := time.Now()
+			var ruleProblems []problem.Problem
+			var script *project.Script
+			projected, ok := rule.(ProjectRule)
+			if ok {
+				script = l.projectScript(filePath)
+			}
+			if script != nil {
+				ruleProblems = projected.CheckProject(l.project, script, l.config)
+			} else if analyzed, ok := rule.(AnalysisRule); ok {
+				ruleProblems = analyzed.CheckResults(results, l.config)
+			} else if sourced, ok := rule.(SourceRule); ok {
+				ruleProblems = sourced.CheckSource(filePath, source, tree, l.config)
+			} else {
+				ruleProblems = rule.Check(tree, l.config)
+			}
+			if l.profile != nil {
+				l.profile.addRule(rule.Name(), filePath, time.Since(start))
+			}
 
 			// Filter problems based on directives if we have source code
 			if ruleContext != nil {
@@ -81,51 +240,76 @@ func (l *Linter) LintASTWithSource(tree *ast.AbstractSyntaxTree, source string)
 		}
 	}
 
-	return problems
+	// Suppressions are only known to be unused once every rule has run
+	unused := (&UnusedSuppression{}).Name()
+	if ruleContext != nil && ran[unused] {
+		checked := func(ruleName string) bool {
+			return ran[ruleName] && ruleName != unused
+		}
+		ruleContext.UnusedSuppressions(checked, func(directive *RuleDirective, ruleName string) {
+			pos := ast.Position{Line: directive.Line, Column: directive.Column}
+			if ruleContext.IsRuleEnabled(unused, pos) {
+				problems = append(problems, problem.NewWarning(
+					pos,
+					fmt.Sprintf("gdlint:%s=%s suppresses no problem", directive.Type, ruleName),
+					unused,
+				))
+			}
+		})
+	}
+
+	if source != "" {
+		resolvePositions(problems, source, l.config.TabWidth)
+	}
+	return problem.Sort(problems)
 }
 
-// LintFile lints the given file and returns any problems found
-func (l *Linter) LintFile(filePath string) ([]problem.Problem, error) {
-	// Parse the file
-	tree, errors := parser.ParseFile(filePath, "")
-	if len(errors) > 0 {
-		return nil, fmt.Errorf("parsing errors: %v", errors)
+// projectScript returns the script of the linter's project at filePath, or
+// nil when the linter has no project or the project has no such script
+func (l *Linter) projectScript(filePath string) *project.Script {
+	if l.project == nil || filePath == "" {
+		return nil
 	}
+	return l.project.Script(filePath)
+}
 
-	return l.LintAST(tree), nil
+// LintFile lints the given file and returns any problems found; files
+// larger than corpus.DefaultMaxFileSize are not read
+func (l *Linter) LintFile(filePath string) ([]problem.Problem, error) {
+	source, err := corpus.ReadFile(filePath, corpus.DefaultMaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	return l.LintSource(filePath, source)
 }
 
-// LintFiles lints the given files and returns any problems found
+// LintFiles lints the given files concurrently and returns the problems
+// found in each; a file that cannot be linted has a single internal error
 func (l *Linter) LintFiles(filePaths []string) (map[string][]problem.Problem, error) {
+	files, _ := l.lintConcurrently(func(add func(path string) error) error {
+		for _, filePath := range filePaths {
+			add(filePath)
+		}
+		return nil
+	}, func(path string) FileResult {
+		problems, err := l.LintFile(path)
+		return FileResult{Path: path, Problems: problems, Err: err}
+	})
+
 	results := make(map[string][]problem.Problem)
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	for _, filePath := range filePaths {
-		wg.Add(1)
-		go func(path string) {
-			defer wg.Done()
-
-			problems, err := l.LintFile(path)
-			mu.Lock()
-			defer mu.Unlock()
-
-			if err != nil {
-				results[path] = []problem.Problem{
-					problem.NewError(
-						ast.Position{Line: 1, Column: 1},
-						fmt.Sprintf("Failed to lint file: %v", err),
-						"internal",
-					),
-				}
-				return
+	for _, file := range files {
+		if file.Err != nil {
+			results[file.Path] = []problem.Problem{
+				problem.NewError(
+					ast.Position{Line: 1, Column: 1},
+					fmt.Sprintf("Failed to lint file: %v", file.Err),
+					"internal",
+				),
 			}
-
-			results[path] = problems
-		}(filePath)
+			continue
+		}
+		results[file.Path] = file.Problems
 	}
-
-	wg.Wait()
 	return results, nil
 }
 
@@ -0,0 +1,61 @@
+package linter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// resolvePositions completes the positions of problems found in source. Rules
+// that build a position from a line and a column leave its byte offset out,
+// which is computed here so that every problem carries one. With a tab width,
+// columns then count each tab up to the next tab stop, as editors show them,
+// instead of as one character.
+func resolvePositions(problems []problem.Problem, source string, tabWidth int) {
+	if len(problems) == 0 {
+		return
+	}
+	// Columns start after a byte order mark, as the lexer skips it
+	starts := []int{0}
+	if strings.HasPrefix(source, parser.ByteOrderMark) {
+		starts[0] = len(parser.ByteOrderMark)
+	}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+
+	for i := range problems {
+		pos := &problems[i].Position
+		if pos.Line < 1 || pos.Line > len(starts) || pos.Column < 1 {
+			continue
+		}
+		start := starts[pos.Line-1]
+		line := source[start:]
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+
+		// The byte offset and the visual column of the first Column-1 characters
+		bytes, visual := 0, 0
+		for chars := 0; chars < pos.Column-1 && bytes < len(line); chars++ {
+			r, size := utf8.DecodeRuneInString(line[bytes:])
+			bytes += size
+			if r == '\t' && tabWidth > 0 {
+				visual += tabWidth - visual%tabWidth
+			} else {
+				visual++
+			}
+		}
+
+		if pos.Offset == 0 {
+			pos.Offset = start + bytes
+		}
+		if tabWidth > 0 {
+			pos.Column = visual + 1
+		}
+	}
+}
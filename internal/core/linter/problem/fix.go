@@ -0,0 +1,67 @@
+package problem
+
+import (
+	"sort"
+)
+
+// Edit replaces the bytes of a source from Start up to End with Text
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Fix is a change of the source that resolves a problem
+type Fix struct {
+	Description string
+	Edits       []Edit
+}
+
+// ApplyFixes applies the fixes of problems to source and returns the result
+// and the number of problems fixed. A fix whose edits overlap those of a fix
+// applied before it, or fall outside the source, is skipped: linting the
+// result again reports its problem if it remains.
+func ApplyFixes(source string, problems []Problem) (string, int) {
+	var edits []Edit
+	fixed := 0
+	for _, p := range problems {
+		if p.Fix == nil || len(p.Fix.Edits) == 0 || !editsFit(source, edits, p.Fix.Edits) {
+			continue
+		}
+		edits = append(edits, p.Fix.Edits...)
+		fixed++
+	}
+
+	// Apply the edits from the end, so the offsets of the others stay valid;
+	// an insertion goes before a replacement starting at the same offset
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].Start != edits[j].Start {
+			return edits[i].Start > edits[j].Start
+		}
+		return edits[i].End > edits[j].End
+	})
+	for _, e := range edits {
+		source = source[:e.Start] + e.Text + source[e.End:]
+	}
+	return source, fixed
+}
+
+// editsFit reports whether the edits of a fix lie within source and overlap
+// neither each other nor the edits accepted so far
+func editsFit(source string, accepted, edits []Edit) bool {
+	for i, e := range edits {
+		if e.Start < 0 || e.Start > e.End || e.End > len(source) {
+			return false
+		}
+		for _, other := range append(accepted[:len(accepted):len(accepted)], edits[:i]...) {
+			if e.Start < other.End && other.Start < e.End {
+				return false
+			}
+			// Two insertions at the same offset would apply in no defined order
+			if e.Start == e.End && other.Start == other.End && e.Start == other.Start {
+				return false
+			}
+		}
+	}
+	return true
+}
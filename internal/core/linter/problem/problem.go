@@ -2,6 +2,7 @@ package problem
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 )
@@ -18,12 +19,19 @@ const (
 	Info Severity = "info"
 )
 
+// Valid reports whether s is one of the known severities
+func (s Severity) Valid() bool {
+	return s == Error || s == Warning || s == Info
+}
+
 // Problem represents a linting problem
 type Problem struct {
 	Position ast.Position
 	Message  string
 	RuleName string
 	Severity Severity
+	// Fix resolves the problem; nil when the rule cannot fix it
+	Fix *Fix `json:",omitempty"`
 }
 
 // String returns a string representation of the problem
@@ -55,3 +63,37 @@ func NewWarning(pos ast.Position, message, ruleName string) Problem {
 func NewInfo(pos ast.Position, message, ruleName string) Problem {
 	return NewProblem(pos, message, ruleName, Info)
 }
+
+// Sort orders problems by line, column, rule and message and drops repeated
+// reports of the same problem, so a file lists its problems the same way on
+// every run
+func Sort(problems []Problem) []Problem {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.Position.Line != b.Position.Line {
+			return a.Position.Line < b.Position.Line
+		}
+		if a.Position.Column != b.Position.Column {
+			return a.Position.Column < b.Position.Column
+		}
+		if a.RuleName != b.RuleName {
+			return a.RuleName < b.RuleName
+		}
+		return a.Message < b.Message
+	})
+
+	result := problems[:0]
+	for i, p := range problems {
+		if i > 0 && sameProblem(p, problems[i-1]) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// sameProblem reports whether a and b report the same problem at the same place
+func sameProblem(a, b Problem) bool {
+	return a.Position.Line == b.Position.Line && a.Position.Column == b.Position.Column &&
+		a.RuleName == b.RuleName && a.Message == b.Message
+}
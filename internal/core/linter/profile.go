@@ -0,0 +1,100 @@
+package linter
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// AnalysisTiming is the name the time of the analyses shared by rules is
+// recorded under in a Profile
+const AnalysisTiming = "(analysis)"
+
+// RuleTiming is the time a rule took to check the files of a run
+type RuleTiming struct {
+	Name  string
+	Total time.Duration
+	Files int
+	// Slowest is the longest check of a single file, SlowestFile that file
+	Slowest     time.Duration
+	SlowestFile string
+}
+
+// FileTiming is the time a file took to parse and lint
+type FileTiming struct {
+	Path  string
+	Parse time.Duration
+	Lint  time.Duration
+}
+
+// Total is the time the file took to parse and lint
+func (t FileTiming) Total() time.Duration {
+	return t.Parse + t.Lint
+}
+
+// Profile collects the time rules take to check files. Linters given the
+// same profile with SetProfile add to it, from any goroutine.
+type Profile struct {
+	mu    sync.Mutex
+	rules map[string]*RuleTiming
+	files []FileTiming
+}
+
+// NewProfile creates an empty profile
+func NewProfile() *Profile {
+	return &Profile{rules: make(map[string]*RuleTiming)}
+}
+
+// addRule records that rule took d to check the file at path
+func (p *Profile) addRule(rule, path string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timing, ok := p.rules[rule]
+	if !ok {
+		timing = &RuleTiming{Name: rule}
+		p.rules[rule] = timing
+	}
+	timing.Total += d
+	timing.Files++
+	if d > timing.Slowest {
+		timing.Slowest, timing.SlowestFile = d, path
+	}
+}
+
+// addFile records the time the file at path took to parse and lint
+func (p *Profile) addFile(timing FileTiming) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files = append(p.files, timing)
+}
+
+// Rules returns the time of each rule, the slowest first
+func (p *Profile) Rules() []RuleTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timings := make([]RuleTiming, 0, len(p.rules))
+	for _, timing := range p.rules {
+		timings = append(timings, *timing)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Total != timings[j].Total {
+			return timings[i].Total > timings[j].Total
+		}
+		return timings[i].Name < timings[j].Name
+	})
+	return timings
+}
+
+// Files returns the time of each file, the slowest first
+func (p *Profile) Files() []FileTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	timings := append([]FileTiming(nil), p.files...)
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Total() != timings[j].Total() {
+			return timings[i].Total() > timings[j].Total()
+		}
+		return timings[i].Path < timings[j].Path
+	})
+	return timings
+}
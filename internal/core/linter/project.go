@@ -0,0 +1,79 @@
+package linter
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+)
+
+// FileResult is the outcome of linting a script
+type FileResult struct {
+	Path string
+	// Source is the content of the script, empty when it could not be read
+	Source   string
+	Problems []problem.Problem
+	// Err is a *ParseError when the script does not parse, or the error
+	// reading it; Problems is empty then
+	Err error
+}
+
+// LintProject lints the scripts under root, or root itself when it is a
+// script, and returns their results in path order. Scripts are parsed and
+// linted by a worker per CPU as they are found, with the configuration of
+// the linter; scripts larger than corpus.DefaultMaxFileSize are not read.
+// The error is that of walking root, the errors of each script are in its
+// result.
+func (l *Linter) LintProject(root string) ([]FileResult, error) {
+	return l.lintConcurrently(func(add func(path string) error) error {
+		return corpus.Walk(root, add)
+	}, func(path string) FileResult {
+		source, err := corpus.ReadFile(path, corpus.DefaultMaxFileSize)
+		if err != nil {
+			return FileResult{Path: path, Err: err}
+		}
+		problems, err := l.LintSource(path, source)
+		return FileResult{Path: path, Source: source, Problems: problems, Err: err}
+	})
+}
+
+// lintConcurrently calls lintPath with every path walk adds, from a worker
+// per CPU, and returns the results in the order the paths were added along
+// with the error of walk
+func (l *Linter) lintConcurrently(walk func(add func(path string) error) error, lintPath func(path string) FileResult) ([]FileResult, error) {
+	type job struct {
+		index int
+		path  string
+	}
+	var (
+		mu      sync.Mutex
+		results []FileResult
+		wg      sync.WaitGroup
+	)
+	jobs := make(chan job)
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result := lintPath(j.path)
+				mu.Lock()
+				results[j.index] = result
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := walk(func(path string) error {
+		mu.Lock()
+		index := len(results)
+		results = append(results, FileResult{Path: path})
+		mu.Unlock()
+		jobs <- job{index, path}
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+	return results, err
+}
@@ -0,0 +1,68 @@
+package linter
+
+import "fmt"
+
+// RuleInfo describes a rule of a Registry
+type RuleInfo struct {
+	Rule Rule
+	// Category groups related rules, such as "basic", "name" or "scope"
+	Category string
+	// Default reports whether the rule runs unless disabled_rules lists it;
+	// other rules only run when enabled_rules lists them
+	Default bool
+}
+
+// Settings returns the settings the rule reads from rule_settings, or nil
+// when it has none
+func (i RuleInfo) Settings() []Setting {
+	return RuleSettings(i.Rule)
+}
+
+// Registry holds every built-in rule by name, in the order rules run
+type Registry struct {
+	rules  []RuleInfo
+	byName map[string]int
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]int)}
+}
+
+// Register adds a rule to the registry. Rule names identify rules in configs
+// and gdlint:ignore comments, so registering a name twice panics.
+func (r *Registry) Register(rule Rule, category string, enabledByDefault bool) {
+	if _, ok := r.byName[rule.Name()]; ok {
+		panic(fmt.Sprintf("rule %q is registered twice", rule.Name()))
+	}
+	r.byName[rule.Name()] = len(r.rules)
+	r.rules = append(r.rules, RuleInfo{Rule: rule, Category: category, Default: enabledByDefault})
+}
+
+// Lookup returns the rule called name
+func (r *Registry) Lookup(name string) (RuleInfo, bool) {
+	i, ok := r.byName[name]
+	if !ok {
+		return RuleInfo{}, false
+	}
+	return r.rules[i], true
+}
+
+// Rules returns every registered rule in registration order
+func (r *Registry) Rules() []RuleInfo {
+	return r.rules
+}
+
+// Select returns the rules config runs: the rules enabled by default and
+// those listed in enabled_rules, except those listed in disabled_rules,
+// followed by the external rules of config
+func (r *Registry) Select(config Config) []Rule {
+	var rules []Rule
+	for _, info := range r.rules {
+		name := info.Rule.Name()
+		if (info.Default || containsString(config.EnabledRules, name)) && config.IsRuleEnabled(name) {
+			rules = append(rules, info.Rule)
+		}
+	}
+	return append(rules, config.ExternalRules()...)
+}
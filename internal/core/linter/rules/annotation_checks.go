@@ -0,0 +1,311 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// OnreadyWithoutNodePath checks for @onready variables whose value does not
+// come from the scene tree, such as @onready var speed = 5
+type OnreadyWithoutNodePath struct{}
+
+// Name returns the name of the rule
+func (r *OnreadyWithoutNodePath) Name() string {
+	return "onready-without-node-path"
+}
+
+// Description returns a description of the rule
+func (r *OnreadyWithoutNodePath) Description() string {
+	return "Checks for @onready variables initialized with a constant value instead of a node, which @onready only delays"
+}
+
+// Check applies the rule to an AST and returns any problems found, without fixes
+func (r *OnreadyWithoutNodePath) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckSource("", "", tree, config)
+}
+
+// CheckSource applies the rule to a file, fixing each problem by dropping the
+// annotation
+func (r *OnreadyWithoutNodePath) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		for _, stmt := range class.Statements {
+			// A value the parser could not read, such as $Path, is given the
+			// benefit of the doubt
+			v, ok := stmt.(*ast.VarStatement)
+			if !ok || v.Value == nil || !isConstantValue(v.Value) {
+				continue
+			}
+			for _, annotation := range v.Annotations {
+				if annotation.Name != "onready" {
+					continue
+				}
+				p := problem.NewWarning(
+					annotation.Position(),
+					fmt.Sprintf("@onready is useless on '%s', whose value does not come from the scene tree", v.Name),
+					r.Name(),
+				)
+				p.Fix = dropAnnotation(source, annotation)
+				problems = append(problems, p)
+			}
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// isConstantValue reports whether expr is made of literals and operators only,
+// so it reads nothing from the object or the scene tree
+func isConstantValue(expr ast.Expression) bool {
+	constant := true
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.DictionaryLiteral:
+			// The keys of {name = value} are strings spelled as names
+			if n.LuaStyle {
+				for _, value := range n.Values {
+					constant = constant && isConstantValue(value)
+				}
+				return false
+			}
+			return true
+		case *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral,
+			*ast.ArrayLiteral, *ast.PrefixExpression, *ast.InfixExpression:
+			return true
+		}
+		constant = false
+		return false
+	})
+	return constant
+}
+
+// dropAnnotation returns the fix removing an annotation without arguments from
+// source, along with its line when nothing else is on it. It returns nil when
+// source is not available.
+func dropAnnotation(source string, annotation *ast.Annotation) *problem.Fix {
+	start := annotation.Pos.Offset
+	text := "@" + annotation.Name
+	if start < 0 || start > len(source) || !strings.HasPrefix(source[start:], text) {
+		return nil
+	}
+	end := start + len(text)
+	if strings.HasPrefix(source[end:], "(") {
+		return nil
+	}
+
+	// Take the blanks after the annotation, or before it when it ends the line
+	for end < len(source) && (source[end] == ' ' || source[end] == '\t') {
+		end++
+	}
+	if end == len(source) || source[end] == '\n' || source[end] == '\r' {
+		for start > 0 && (source[start-1] == ' ' || source[start-1] == '\t') {
+			start--
+		}
+		if start == 0 || source[start-1] == '\n' {
+			// The annotation is alone on its line
+			end += len(source[end:]) - len(strings.TrimPrefix(strings.TrimPrefix(source[end:], "\r"), "\n"))
+		}
+	}
+
+	return &problem.Fix{
+		Description: "Remove " + text,
+		Edits:       []problem.Edit{{Start: start, End: end}},
+	}
+}
+
+// InvalidAnnotation checks for annotations unknown to GDScript and for
+// annotations whose arguments Godot rejects, such as @export_range(1)
+type InvalidAnnotation struct{}
+
+// Name returns the name of the rule
+func (r *InvalidAnnotation) Name() string {
+	return "invalid-annotation"
+}
+
+// Description returns a description of the rule
+func (r *InvalidAnnotation) Description() string {
+	return "Checks for unknown annotations and annotations with the wrong number or kind of arguments"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *InvalidAnnotation) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// annotationArgument is the kind of value an annotation argument takes
+type annotationArgument int
+
+const (
+	argAny annotationArgument = iota
+	argNumber
+	argString
+	argRPC // an @rpc option, or the transfer channel
+)
+
+// annotationSchema describes the arguments of an annotation: the kinds of the
+// first ones, and rest for the others. max is -1 when it takes any number.
+type annotationSchema struct {
+	min, max int
+	args     []annotationArgument
+	rest     annotationArgument
+}
+
+// annotationSchemas lists the annotations of Godot 4 and their arguments
+var annotationSchemas = map[string]annotationSchema{
+	"tool":          {},
+	"icon":          {min: 1, max: 1, args: []annotationArgument{argString}},
+	"static_unload": {},
+	"abstract":      {},
+	"onready":       {},
+
+	"export":                     {},
+	"export_category":            {min: 1, max: 1, args: []annotationArgument{argString}},
+	"export_color_no_alpha":      {},
+	"export_custom":              {min: 2, max: 3, args: []annotationArgument{argAny, argString, argAny}},
+	"export_dir":                 {},
+	"export_enum":                {min: 1, max: -1, rest: argString},
+	"export_exp_easing":          {max: -1, rest: argString},
+	"export_file":                {max: -1, rest: argString},
+	"export_flags":               {min: 1, max: -1, rest: argString},
+	"export_flags_2d_navigation": {},
+	"export_flags_2d_physics":    {},
+	"export_flags_2d_render":     {},
+	"export_flags_3d_navigation": {},
+	"export_flags_3d_physics":    {},
+	"export_flags_3d_render":     {},
+	"export_flags_avoidance":     {},
+	"export_global_dir":          {},
+	"export_global_file":         {max: -1, rest: argString},
+	"export_group":               {min: 1, max: 2, args: []annotationArgument{argString, argString}},
+	"export_multiline":           {},
+	"export_node_path":           {max: -1, rest: argString},
+	"export_placeholder":         {min: 1, max: 1, args: []annotationArgument{argString}},
+	"export_range":               {min: 2, max: -1, args: []annotationArgument{argNumber, argNumber, argNumber}, rest: argString},
+	"export_storage":             {},
+	"export_subgroup":            {min: 1, max: 2, args: []annotationArgument{argString, argString}},
+	"export_tool_button":         {min: 1, max: 2, args: []annotationArgument{argString, argString}},
+
+	"rpc":                    {max: 4, rest: argRPC},
+	"warning_ignore":         {min: 1, max: -1, rest: argString},
+	"warning_ignore_start":   {min: 1, max: -1, rest: argString},
+	"warning_ignore_restore": {min: 1, max: -1, rest: argString},
+}
+
+// rpcOptions are the strings @rpc accepts, in the order Godot documents them
+var rpcOptions = []string{
+	"authority", "any_peer", "call_local", "call_remote", "unreliable", "unreliable_ordered", "reliable",
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *InvalidAnnotation) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	report := func(node ast.Node, message string) {
+		problems = append(problems, problem.NewProblem(node.Position(), message, r.Name(), r.DefaultSeverity()))
+	}
+
+	ast.Inspect(tree, func(node ast.Node) bool {
+		annotation, ok := node.(*ast.Annotation)
+		if !ok {
+			return true
+		}
+
+		schema, known := annotationSchemas[annotation.Name]
+		if !known {
+			report(annotation, fmt.Sprintf("Unknown annotation '@%s'", annotation.Name))
+			return false
+		}
+		if count := len(annotation.Args); count < schema.min || (schema.max >= 0 && count > schema.max) {
+			report(annotation, fmt.Sprintf("@%s takes %s, got %d", annotation.Name, schema.describe(), count))
+			return false
+		}
+
+		for i, arg := range annotation.Args {
+			kind := schema.rest
+			if i < len(schema.args) {
+				kind = schema.args[i]
+			}
+			if message := checkAnnotationArgument(kind, arg); message != "" {
+				report(arg, fmt.Sprintf("Argument %d of @%s %s", i+1, annotation.Name, message))
+			}
+		}
+		return false
+	})
+
+	return problems
+}
+
+// describe returns the number of arguments the schema takes, in words
+func (s annotationSchema) describe() string {
+	switch {
+	case s.max == 0:
+		return "no arguments"
+	case s.max < 0:
+		return fmt.Sprintf("at least %d %s", s.min, pluralArguments(s.min))
+	case s.min == s.max:
+		return fmt.Sprintf("%d %s", s.min, pluralArguments(s.min))
+	}
+	return fmt.Sprintf("%d to %d arguments", s.min, s.max)
+}
+
+// pluralArguments returns "argument" or "arguments" for count
+func pluralArguments(count int) string {
+	if count == 1 {
+		return "argument"
+	}
+	return "arguments"
+}
+
+// checkAnnotationArgument returns why arg is not a value of the given kind,
+// or an empty string when it is or may be. Only literals are checked, since
+// a constant can give any argument.
+func checkAnnotationArgument(kind annotationArgument, arg ast.Expression) string {
+	if prefix, ok := arg.(*ast.PrefixExpression); ok && (prefix.Operator == "-" || prefix.Operator == "+") {
+		if _, ok := prefix.Right.(*ast.NumberLiteral); ok {
+			arg = prefix.Right
+		}
+	}
+
+	switch kind {
+	case argNumber:
+		switch arg.(type) {
+		case *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral, *ast.ArrayLiteral, *ast.DictionaryLiteral:
+			return "should be a number"
+		}
+	case argString:
+		switch arg.(type) {
+		case *ast.NumberLiteral, *ast.BooleanLiteral, *ast.NullLiteral, *ast.ArrayLiteral, *ast.DictionaryLiteral:
+			return "should be a string"
+		}
+	case argRPC:
+		switch a := arg.(type) {
+		case *ast.StringLiteral:
+			for _, option := range rpcOptions {
+				if a.Value == option {
+					return ""
+				}
+			}
+			return fmt.Sprintf("'%s' should be one of %s", a.Value, strings.Join(rpcOptions, ", "))
+		case *ast.NumberLiteral:
+			if !a.IsInt {
+				return "should be an integer transfer channel"
+			}
+		case *ast.BooleanLiteral, *ast.NullLiteral, *ast.ArrayLiteral, *ast.DictionaryLiteral:
+			return "should be an @rpc option or a transfer channel"
+		}
+	}
+	return ""
+}
+
+// GetDefaultAnnotationRules returns the rules checking annotations. They have
+// no counterpart in Python gdlint and are not enabled by default.
+func GetDefaultAnnotationRules() []linter.Rule {
+	return []linter.Rule{
+		&OnreadyWithoutNodePath{},
+		&InvalidAnnotation{},
+	}
+}
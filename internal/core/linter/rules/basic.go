@@ -1,6 +1,9 @@
 package rules
 
 import (
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
@@ -60,7 +63,8 @@ func (v *expressionNotAssignedVisitor) Visit(node ast.Node) ast.Visitor {
 
 		// Only flag simple expressions that are actually not used
 		switch exprStmt.Expression.(type) {
-		case *ast.InfixExpression, *ast.NumberLiteral, *ast.BooleanLiteral, *ast.Identifier:
+		case *ast.InfixExpression, *ast.PrefixExpression, *ast.NumberLiteral, *ast.BooleanLiteral, *ast.Identifier,
+			*ast.GetNodeExpression, *ast.DotExpression:
 			*v.problems = append(*v.problems, problem.NewWarning(
 				exprStmt.Position(),
 				"Expression is not assigned to a variable or used",
@@ -89,77 +93,58 @@ func (r *UnnecessaryPass) Description() string {
 func (r *UnnecessaryPass) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
 
-	// Create a visitor to find unnecessary pass statements
-	visitor := &unnecessaryPassVisitor{
-		problems: &problems,
+	// A pass is unnecessary in any block that has other statements,
+	// however deeply the block is nested
+	report := func(block []ast.Statement, members int) {
+		if members <= 1 {
+			return
+		}
+		for _, stmt := range block {
+			if pass, ok := stmt.(*ast.PassStatement); ok {
+				problems = append(problems, problem.NewWarning(
+					pass.Position(),
+					"Unnecessary pass statement",
+					"unnecessary-pass",
+				))
+			}
+		}
 	}
 
-	// Walk the AST
-	ast.Walk(visitor, tree)
+	ast.Inspect(tree, func(node ast.Node) bool {
+		if class, ok := node.(*ast.Class); ok {
+			report(class.Statements, len(class.Statements)+len(class.Functions)+len(class.SubClasses))
+			return true
+		}
+		for _, block := range nestedBlocks(node) {
+			report(block, len(block))
+		}
+		return true
+	})
 
 	return problems
 }
 
-// unnecessaryPassVisitor is a visitor that finds unnecessary pass statements
-type unnecessaryPassVisitor struct {
-	problems *[]problem.Problem
-	inBlock  bool
-	hasStmt  bool
-}
-
-// Visit is called for each node in the AST
-func (v *unnecessaryPassVisitor) Visit(node ast.Node) ast.Visitor {
+// nestedBlocks returns the statement blocks a function or compound statement
+// contains directly
+func nestedBlocks(node ast.Node) [][]ast.Statement {
 	switch n := node.(type) {
 	case *ast.Function:
-		// Check if the function has statements other than pass
-		hasStmt := false
-		hasPass := false
-		var passStmt *ast.PassStatement
-
-		for _, stmt := range n.Statements {
-			if _, ok := stmt.(*ast.PassStatement); ok {
-				hasPass = true
-				passStmt = stmt.(*ast.PassStatement)
-			} else {
-				hasStmt = true
-			}
-		}
-
-		// If the function has both pass and other statements, the pass is unnecessary
-		if hasPass && hasStmt && passStmt != nil {
-			*v.problems = append(*v.problems, problem.NewWarning(
-				passStmt.Position(),
-				"Unnecessary pass statement",
-				"unnecessary-pass",
-			))
-		}
-
-	case *ast.Class:
-		// Check if the class has statements other than pass
-		hasStmt := false
-		hasPass := false
-		var passStmt *ast.PassStatement
-
-		for _, stmt := range n.Statements {
-			if _, ok := stmt.(*ast.PassStatement); ok {
-				hasPass = true
-				passStmt = stmt.(*ast.PassStatement)
-			} else {
-				hasStmt = true
-			}
-		}
-
-		// If the class has both pass and other statements, the pass is unnecessary
-		if hasPass && hasStmt && passStmt != nil {
-			*v.problems = append(*v.problems, problem.NewWarning(
-				passStmt.Position(),
-				"Unnecessary pass statement",
-				"unnecessary-pass",
-			))
+		return [][]ast.Statement{n.Statements}
+	case *ast.IfStatement:
+		blocks := append([][]ast.Statement{n.Consequence}, n.ElseBranches...)
+		return append(blocks, n.Alternative)
+	case *ast.ForStatement:
+		return [][]ast.Statement{n.Body}
+	case *ast.WhileStatement:
+		return [][]ast.Statement{n.Body}
+	case *ast.MatchStatement:
+		var blocks [][]ast.Statement
+		for _, branch := range n.Branches {
+			blocks = append(blocks, branch.Body)
 		}
+		return blocks
 	}
-
-	return v
+	return nil
 }
 
 // DuplicatedLoad checks for duplicated load/preload statements
@@ -175,57 +160,37 @@ func (r *DuplicatedLoad) Description() string {
 	return "Checks for duplicated load/preload statements"
 }
 
+// Requires lists the analysis passes the rule uses
+func (r *DuplicatedLoad) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassLoadCalls}
+}
+
 // Check applies the rule to an AST and returns any problems found
 func (r *DuplicatedLoad) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
-
-	// Create a visitor to find duplicated load statements
-	visitor := &duplicatedLoadVisitor{
-		problems: &problems,
-		loads:    make(map[string]ast.Position),
-	}
-
-	// Walk the AST
-	ast.Walk(visitor, tree)
-
-	return problems
+	return r.CheckResults(analysis.NewResults(tree), config)
 }
 
-// duplicatedLoadVisitor is a visitor that finds duplicated load statements
-type duplicatedLoadVisitor struct {
-	problems *[]problem.Problem
-	loads    map[string]ast.Position
-}
+// CheckResults applies the rule to an analyzed file
+func (r *DuplicatedLoad) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
 
-// Visit is called for each node in the AST
-func (v *duplicatedLoadVisitor) Visit(node ast.Node) ast.Visitor {
-	// Check for variable statements with load/preload calls
-	if varStmt, ok := node.(*ast.VarStatement); ok {
-		if callExpr, ok := varStmt.Value.(*ast.CallExpression); ok {
-			if ident, ok := callExpr.Function.(*ast.Identifier); ok {
-				if ident.Value == "load" || ident.Value == "preload" {
-					// Check if we have arguments
-					if len(callExpr.Arguments) > 0 {
-						if stringLit, ok := callExpr.Arguments[0].(*ast.StringLiteral); ok {
-							path := stringLit.Value
-							if prevPos, exists := v.loads[path]; exists {
-								*v.problems = append(*v.problems, problem.NewWarning(
-									varStmt.Position(),
-									"Duplicated load statement for '"+path+"'",
-									"duplicated-load",
-								))
-								_ = prevPos // suppress unused variable warning
-							} else {
-								v.loads[path] = varStmt.Position()
-							}
-						}
-					}
-				}
-			}
+	loaded := make(map[string]bool)
+	for _, load := range results.LoadCalls() {
+		// Only loads kept in a variable or constant can be reused
+		if load.Decl == nil {
+			continue
 		}
+		if loaded[load.Path] {
+			problems = append(problems, problem.NewWarning(
+				load.Decl.Position(),
+				"Duplicated load statement for '"+load.Path+"'",
+				"duplicated-load",
+			))
+		}
+		loaded[load.Path] = true
 	}
 
-	return v
+	return problems
 }
 
 // UnusedArgument checks for unused function arguments
@@ -241,69 +206,44 @@ func (r *UnusedArgument) Description() string {
 	return "Checks for unused function arguments"
 }
 
-// Check applies the rule to an AST and returns any problems found
-func (r *UnusedArgument) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
-
-	// Create a visitor to find unused arguments
-	visitor := &unusedArgumentVisitor{
-		problems: &problems,
-	}
-
-	// Walk the AST
-	ast.Walk(visitor, tree)
-
-	return problems
+// Requires lists the analysis passes the rule uses
+func (r *UnusedArgument) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
 }
 
-// unusedArgumentVisitor is a visitor that finds unused arguments
-type unusedArgumentVisitor struct {
-	problems *[]problem.Problem
+// Check applies the rule to an AST and returns any problems found
+func (r *UnusedArgument) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
 }
 
-// Visit is called for each node in the AST
-func (v *unusedArgumentVisitor) Visit(node ast.Node) ast.Visitor {
-	if function, ok := node.(*ast.Function); ok {
-		// Track used identifiers in the function body
-		usedIdentifiers := make(map[string]bool)
-
-		// Simple check: look for identifiers in the function body
-		bodyVisitor := &identifierCollector{used: usedIdentifiers}
-		for _, stmt := range function.Statements {
-			ast.Walk(bodyVisitor, stmt)
-		}
+// CheckResults applies the rule to an analyzed file
+func (r *UnusedArgument) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
 
-		// Check each parameter
-		for _, param := range function.Parameters {
-			// Skip parameters that start with underscore (conventional way to mark unused)
-			if len(param.Name) > 0 && param.Name[0] == '_' {
-				continue
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		used := make(map[ast.Node]bool)
+		for _, ref := range results.References(class) {
+			if ref.Kind == analysis.SymbolParameter {
+				used[ref.Decl] = true
 			}
+		}
 
-			if !usedIdentifiers[param.Name] {
-				*v.problems = append(*v.problems, problem.NewWarning(
+		for _, function := range class.Functions {
+			for _, param := range function.Parameters {
+				// Skip parameters that start with underscore (conventional way to mark unused)
+				if strings.HasPrefix(param.Name, "_") || used[param] {
+					continue
+				}
+				problems = append(problems, problem.NewWarning(
 					param.Pos,
 					"Unused argument '"+param.Name+"'",
 					"unused-argument",
 				))
 			}
 		}
-	}
-
-	return v
-}
+	}}).Walk(results.Tree)
 
-// identifierCollector collects used identifiers
-type identifierCollector struct {
-	used map[string]bool
-}
-
-// Visit is called for each node in the AST
-func (v *identifierCollector) Visit(node ast.Node) ast.Visitor {
-	if ident, ok := node.(*ast.Identifier); ok {
-		v.used[ident.Value] = true
-	}
-	return v
+	return problems
 }
 
 // ComparisonWithItself checks for comparisons of identical expressions
@@ -347,43 +287,24 @@ func (v *comparisonWithItselfVisitor) Visit(node ast.Node) ast.Visitor {
 			binExpr.Operator == "<" || binExpr.Operator == ">" ||
 			binExpr.Operator == "<=" || binExpr.Operator == ">=" {
 
-			// Check if left and right expressions are the same
-			if expressionsEqual(binExpr.Left, binExpr.Right) {
+			if analysis.SameValue(binExpr.Left, binExpr.Right) {
 				*v.problems = append(*v.problems, problem.NewWarning(
 					binExpr.Position(),
 					"Comparison of identical expressions",
 					"comparison-with-itself",
 				))
+			} else if value, ok := analysis.Fold(binExpr); ok && !analysis.IsLiteral(binExpr) {
+				// Operands that cancel out, as in x - x == 0, make the
+				// comparison constant; comparing literals, as in
+				// 1 + 1 == 2, is deliberate
+				*v.problems = append(*v.problems, problem.NewWarning(
+					binExpr.Position(),
+					"Comparison is always "+value.Source(),
+					"comparison-with-itself",
+				))
 			}
 		}
 	}
 
 	return v
 }
-
-// expressionsEqual checks if two expressions are structurally equal
-func expressionsEqual(left, right ast.Expression) bool {
-	// Simple implementation - compare string representation
-	// In a more complete implementation, this would do structural comparison
-	switch l := left.(type) {
-	case *ast.Identifier:
-		if r, ok := right.(*ast.Identifier); ok {
-			return l.Value == r.Value
-		}
-	case *ast.NumberLiteral:
-		if r, ok := right.(*ast.NumberLiteral); ok {
-			return l.Original == r.Original
-		}
-	case *ast.StringLiteral:
-		if r, ok := right.(*ast.StringLiteral); ok {
-			return l.Value == r.Value
-		}
-	case *ast.InfixExpression:
-		if r, ok := right.(*ast.InfixExpression); ok {
-			return l.Operator == r.Operator &&
-				expressionsEqual(l.Left, r.Left) &&
-				expressionsEqual(l.Right, r.Right)
-		}
-	}
-	return false
-}
@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// ForbiddenCall reports calls of the functions and methods listed in its settings
+type ForbiddenCall struct{}
+
+// Name returns the name of the rule
+func (r *ForbiddenCall) Name() string {
+	return "forbidden-call"
+}
+
+// Description returns a description of the rule
+func (r *ForbiddenCall) Description() string {
+	return "Checks for calls of forbidden functions and methods"
+}
+
+// Settings lists the settings the rule reads
+func (r *ForbiddenCall) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "calls", Default: []string{}, Description: "Calls to report: a name such as \"print\" matches the calls " +
+			"of that name on any object, a dotted name such as \"OS.execute\" the calls written that way, and " +
+			"\"get_node in _process\" only the calls inside that function"},
+	}
+}
+
+// forbiddenCall is an entry of the calls setting
+type forbiddenCall struct {
+	name     string // the call, e.g. print or OS.execute
+	function string // the function the call is forbidden in; empty for everywhere
+}
+
+// matches reports whether call is the forbidden call. inFunction reports
+// whether the call is made inside the function of the given name.
+func (f forbiddenCall) matches(call *ast.CallExpression, inFunction func(string) bool) bool {
+	if f.function != "" && !inFunction(f.function) {
+		return false
+	}
+	if !strings.Contains(f.name, ".") {
+		// A plain name matches whatever the call is made on
+		switch callee := call.Function.(type) {
+		case *ast.Identifier:
+			return callee.Value == f.name
+		case *ast.DotExpression:
+			return callee.Property == f.name
+		}
+		return false
+	}
+	callee, ok := dottedName(call.Function)
+	// Calls on self are written either way
+	return ok && strings.TrimPrefix(callee, "self.") == f.name
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *ForbiddenCall) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	calls := forbiddenCalls(config.Setting(r, "calls"))
+	if len(calls) == 0 {
+		return nil
+	}
+
+	var problems []problem.Problem
+	(&ast.TypedVisitor{VisitCall: func(call *ast.CallExpression, ancestors ast.NodeStack) {
+		inFunction := func(name string) bool {
+			for i := len(ancestors) - 1; i >= 0; i-- {
+				switch n := ancestors[i].(type) {
+				case *ast.Function:
+					if n.Name == name {
+						return true
+					}
+				case *ast.Class:
+					return false
+				}
+			}
+			return false
+		}
+		for _, forbidden := range calls {
+			if forbidden.matches(call, inFunction) {
+				message := fmt.Sprintf("Call of forbidden \"%s\"", forbidden.name)
+				if forbidden.function != "" {
+					message += " in \"" + forbidden.function + "\""
+				}
+				problems = append(problems, problem.NewWarning(call.Position(), message, r.Name()))
+				return
+			}
+		}
+	}}).Walk(tree)
+	return problems
+}
+
+// forbiddenCalls parses the calls setting, which is a list of strings in a
+// config file and a []string by default
+func forbiddenCalls(setting any) []forbiddenCall {
+	var entries []string
+	switch values := setting.(type) {
+	case []string:
+		entries = values
+	case []any:
+		for _, value := range values {
+			if entry, ok := value.(string); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	var calls []forbiddenCall
+	for _, entry := range entries {
+		name, function, _ := strings.Cut(entry, " in ")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		calls = append(calls, forbiddenCall{name: name, function: strings.TrimSpace(function)})
+	}
+	return calls
+}
+
+// dottedName returns the name of a callee made of identifiers and dots, such
+// as print or OS.execute
+func dottedName(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Value, true
+	case *ast.SelfExpression:
+		return "self", true
+	case *ast.DotExpression:
+		left, ok := dottedName(e.Left)
+		if !ok {
+			return "", false
+		}
+		return left + "." + e.Property, true
+	}
+	return "", false
+}
+
+// GetDefaultCallRules returns the rules checking calls. They report nothing
+// until configured, so they are enabled by default.
+func GetDefaultCallRules() []linter.Rule {
+	return []linter.Rule{
+		&ForbiddenCall{},
+	}
+}
@@ -47,107 +47,16 @@ func (v *classDefinitionsOrderVisitor) Visit(node ast.Node) ast.Visitor {
 	return v
 }
 
-// Expected order for class members according to gdtoolkit
-type memberType int
-
-const (
-	memberPass memberType = iota
-	memberClassName
-	memberExtends
-	memberDocstring
-	memberSignal
-	memberEnum
-	memberConst
-	memberStaticVar
-	memberExportGroup
-	memberExportVar
-	memberVar
-	memberPrivateVar
-	memberOnreadyVar
-	memberPrivateOnreadyVar
-	memberInnerClass
-	memberFunc
-	memberStaticFunc
-)
-
-// getMemberType determines the type of a class member
-func getMemberType(stmt ast.Statement) memberType {
-	switch s := stmt.(type) {
-	case *ast.PassStatement:
-		return memberPass
-	case *ast.VarStatement:
-		// Check if it's a const
-		if s.IsConst {
-			return memberConst
-		}
-
-		// Check annotations for @export, @onready, etc.
-		hasExport := false
-		hasOnready := false
-		for _, annotation := range s.Annotations {
-			if annotation.Name == "export" {
-				hasExport = true
-			} else if annotation.Name == "export_group" {
-				return memberExportGroup
-			} else if annotation.Name == "onready" {
-				hasOnready = true
-			}
-		}
-
-		// Check if variable name starts with underscore (private)
-		isPrivate := len(s.Name) > 0 && s.Name[0] == '_'
-
-		if hasExport {
-			return memberExportVar
-		} else if hasOnready {
-			if isPrivate {
-				return memberPrivateOnreadyVar
-			}
-			return memberOnreadyVar
-		} else if isPrivate {
-			return memberPrivateVar
-		}
-		return memberVar
-	case *ast.ExpressionStatement:
-		// Check for string literals (docstrings)
-		if _, ok := s.Expression.(*ast.StringLiteral); ok {
-			return memberDocstring
-		}
-	case *ast.Class:
-		return memberInnerClass
-	case *ast.Function:
-		// Check if it's a static function
-		for _, annotation := range s.Annotations {
-			if annotation.Name == "static" {
-				return memberStaticFunc
-			}
-		}
-		return memberFunc
-	}
-
-	// For other statement types, try to infer from kind
-	if stmt, ok := stmt.(*ast.BaseStatement); ok {
-		switch stmt.Kind {
-		case "signal_stmt":
-			return memberSignal
-		case "enum_stmt":
-			return memberEnum
-		}
-	}
-
-	return memberVar // Default fallback
-}
-
 // checkClassOrder validates the ordering of class members
 func (v *classDefinitionsOrderVisitor) checkClassOrder(class *ast.Class) {
 	if len(class.Statements) <= 1 {
 		return // No ordering issues with 0 or 1 statements
 	}
 
-	var lastType memberType = -1
+	var lastType ast.MemberKind = -1
 
 	for _, stmt := range class.Statements {
-		currentType := getMemberType(stmt)
+		currentType := ast.MemberKindOf(stmt)
 
 		// Check if current member type should come after the last type
 		if int(currentType) < int(lastType) {
@@ -175,6 +84,11 @@ func (r *SubClassBeforeParentClass) Description() string {
 	return "Checks for subclasses defined before their parent class"
 }
 
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *SubClassBeforeParentClass) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
 // Check applies the rule to an AST and returns any problems found
 func (r *SubClassBeforeParentClass) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
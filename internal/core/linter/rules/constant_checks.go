@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// DivisionByZero checks for divisions and modulos by a constant zero
+type DivisionByZero struct{}
+
+// Name returns the name of the rule
+func (r *DivisionByZero) Name() string {
+	return "division-by-zero"
+}
+
+// Description returns a description of the rule
+func (r *DivisionByZero) Description() string {
+	return "Checks for divisions and modulos by a divisor that is always zero, such as 0 or 1 - 1"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *DivisionByZero) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	report := func(operator string, divisor ast.Expression, pos ast.Position) {
+		value, ok := analysis.Fold(divisor)
+		if !ok || !value.IsNumber() || value.Number() != 0 {
+			return
+		}
+		message := "Division by zero"
+		if operator == "%" || operator == "%=" {
+			message = "Modulo by zero"
+		}
+		problems = append(problems, problem.NewWarning(pos, message, r.Name()))
+	}
+	ast.Inspect(tree, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.InfixExpression:
+			if n.Operator == "/" || n.Operator == "%" {
+				report(n.Operator, n.Right, n.Position())
+			}
+		case *ast.AssignmentExpression:
+			if n.Operator == "/=" || n.Operator == "%=" {
+				report(n.Operator, n.Right, n.Position())
+			}
+		}
+		return true
+	})
+
+	return problems
+}
+
+// DeadBranch checks for branches of if statements and while loops whose
+// condition makes them never run
+type DeadBranch struct{}
+
+// Name returns the name of the rule
+func (r *DeadBranch) Name() string {
+	return "dead-branch"
+}
+
+// Description returns a description of the rule
+func (r *DeadBranch) Description() string {
+	return "Checks for branches that never run because of a constant condition, such as if false: or the else of if true:"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *DeadBranch) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	ast.Inspect(tree, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.IfStatement:
+			conditions := append([]ast.Expression{n.Condition}, n.ElseCondition...)
+			for i, condition := range conditions {
+				value, ok := analysis.Fold(condition)
+				if !ok {
+					continue
+				}
+				if !value.Truthy() {
+					problems = append(problems, problem.NewWarning(
+						condition.Position(),
+						"Branch never runs, its condition is always "+value.Source(),
+						r.Name(),
+					))
+					continue
+				}
+				// The branches after one that always runs never do; only
+				// the first of them is reported
+				switch {
+				case i+1 < len(conditions):
+					problems = append(problems, problem.NewWarning(
+						conditions[i+1].Position(),
+						"Branch never runs, an earlier condition is always "+value.Source(),
+						r.Name(),
+					))
+				case len(n.Alternative) > 0:
+					problems = append(problems, problem.NewWarning(
+						n.ElsePos,
+						"Else branch never runs, an earlier condition is always "+value.Source(),
+						r.Name(),
+					))
+				}
+				break
+			}
+		case *ast.WhileStatement:
+			if value, ok := analysis.Fold(n.Condition); ok && !value.Truthy() {
+				problems = append(problems, problem.NewWarning(
+					n.Condition.Position(),
+					"Loop never runs, its condition is always "+value.Source(),
+					r.Name(),
+				))
+			}
+		}
+		return true
+	})
+
+	return problems
+}
+
+// GetDefaultConstantRules returns the rules built on the values of constant
+// expressions. They have no counterpart in Python gdlint and are not enabled
+// by default.
+func GetDefaultConstantRules() []linter.Rule {
+	return []linter.Rule{
+		&DivisionByZero{},
+		&DeadBranch{},
+	}
+}
@@ -0,0 +1,86 @@
+package rules
+
+import (
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// DebugStatement checks for breakpoint and assert statements left in code
+// that also runs outside of debug builds
+type DebugStatement struct{}
+
+// Name returns the name of the rule
+func (r *DebugStatement) Name() string {
+	return "debug-statement"
+}
+
+// Description returns a description of the rule
+func (r *DebugStatement) Description() string {
+	return "Checks for breakpoint and assert statements outside of an if OS.is_debug_build() block"
+}
+
+// Settings lists the settings the rule reads
+func (r *DebugStatement) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "assert", Default: true, Description: "Whether assert statements are reported too; Godot leaves them out of release builds"},
+	}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *DebugStatement) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	asserts, _ := config.Setting(r, "assert").(bool)
+
+	var inspect ast.Inspector
+	inspect = func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.IfStatement:
+			// Only the branches of a debug build check that run in release
+			// builds are checked
+			if isDebugBuildCheck(n.Condition) {
+				for _, branch := range n.ElseBranches {
+					for _, stmt := range branch {
+						ast.Inspect(stmt, inspect)
+					}
+				}
+				for _, stmt := range n.Alternative {
+					ast.Inspect(stmt, inspect)
+				}
+				return false
+			}
+		case *ast.BreakpointStatement:
+			problems = append(problems, problem.NewWarning(n.Position(), "Breakpoint left in the code", r.Name()))
+		case *ast.AssertStatement:
+			if asserts {
+				problems = append(problems, problem.NewWarning(n.Position(), "Assert left in the code", r.Name()))
+			}
+		}
+		return true
+	}
+	ast.Inspect(tree, inspect)
+
+	return problems
+}
+
+// isDebugBuildCheck reports whether condition is OS.is_debug_build()
+func isDebugBuildCheck(condition ast.Expression) bool {
+	call, ok := condition.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 0 {
+		return false
+	}
+	dot, ok := call.Function.(*ast.DotExpression)
+	if !ok || dot.Property != "is_debug_build" {
+		return false
+	}
+	object, ok := dot.Left.(*ast.Identifier)
+	return ok && object.Value == "OS"
+}
+
+// GetDefaultDebugRules returns the rules checking for debugging code. They
+// have no counterpart in Python gdlint and are not enabled by default.
+func GetDefaultDebugRules() []linter.Rule {
+	return []linter.Rule{
+		&DebugStatement{},
+	}
+}
@@ -1,6 +1,9 @@
 package rules
 
 import (
+	"fmt"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
@@ -17,9 +20,15 @@ func (r *MaxPublicMethods) Description() string {
 	return "Checks for too many public methods in a class"
 }
 
+func (r *MaxPublicMethods) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 20, Description: "Maximum number of public methods in a class"},
+	}
+}
+
 func (r *MaxPublicMethods) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
-	threshold := config.GetRuleSetting(r.Name(), "threshold", 20).(int)
+	threshold := config.Setting(r, "threshold").(int)
 
 	visitor := &maxPublicMethodsVisitor{
 		problems:  &problems,
@@ -73,46 +82,28 @@ func (r *MaxReturns) Description() string {
 	return "Checks for too many return statements in a function"
 }
 
-func (r *MaxReturns) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
-	threshold := config.GetRuleSetting(r.Name(), "threshold", 6).(int)
-
-	visitor := &maxReturnsVisitor{
-		problems:  &problems,
-		threshold: threshold,
+func (r *MaxReturns) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 6, Description: "Maximum number of return statements in a function"},
 	}
-
-	ast.Walk(visitor, tree)
-	return problems
 }
 
-type maxReturnsVisitor struct {
-	problems  *[]problem.Problem
-	threshold int
-}
-
-func (v *maxReturnsVisitor) Visit(node ast.Node) ast.Visitor {
-	if function, ok := node.(*ast.Function); ok {
-		returnCount := 0
-		var lastReturn *ast.ReturnStatement
-
-		// Count return statements in the function
-		for _, stmt := range function.Statements {
-			if retStmt, ok := stmt.(*ast.ReturnStatement); ok {
-				returnCount++
-				lastReturn = retStmt
-			}
-		}
+func (r *MaxReturns) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	threshold := config.Setting(r, "threshold").(int)
 
-		if returnCount > v.threshold && lastReturn != nil {
-			*v.problems = append(*v.problems, problem.NewWarning(
-				lastReturn.Position(),
-				"Function \""+function.Name+"\" has more than "+string(rune(v.threshold+48))+" return statements",
-				"max-returns",
-			))
+	// Returns inside nested blocks count too
+	return checkFunctionCount(tree, threshold, func(node ast.Node) int {
+		if _, ok := node.(*ast.ReturnStatement); ok {
+			return 1
 		}
-	}
-	return v
+		return 0
+	}, func(function *ast.Function) problem.Problem {
+		return problem.NewWarning(
+			function.Position(),
+			fmt.Sprintf("Function \"%s\" has more than %d return statements", function.Name, threshold),
+			"max-returns",
+		)
+	})
 }
 
 // FunctionArgumentsNumber checks for too many function arguments
@@ -126,9 +117,15 @@ func (r *FunctionArgumentsNumber) Description() string {
 	return "Checks for too many function arguments"
 }
 
+func (r *FunctionArgumentsNumber) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 10, Description: "Maximum number of arguments of a function"},
+	}
+}
+
 func (r *FunctionArgumentsNumber) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
-	threshold := config.GetRuleSetting(r.Name(), "threshold", 10).(int)
+	threshold := config.Setting(r, "threshold").(int)
 
 	visitor := &functionArgumentsNumberVisitor{
 		problems:  &problems,
@@ -157,6 +154,259 @@ func (v *functionArgumentsNumberVisitor) Visit(node ast.Node) ast.Visitor {
 	return v
 }
 
+// MaxLocals checks for too many local variables in a function
+type MaxLocals struct{}
+
+func (r *MaxLocals) Name() string {
+	return "max-locals"
+}
+
+func (r *MaxLocals) Description() string {
+	return "Checks for too many local variables in a function"
+}
+
+func (r *MaxLocals) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 15, Description: "Maximum number of local variables in a function"},
+	}
+}
+
+func (r *MaxLocals) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	threshold := config.Setting(r, "threshold").(int)
+
+	// Variables of nested blocks and loop variables count too
+	return checkFunctionCount(tree, threshold, func(node ast.Node) int {
+		switch n := node.(type) {
+		case *ast.VarStatement:
+			if !n.IsConst {
+				return 1
+			}
+		case *ast.ForStatement:
+			return 1
+		}
+		return 0
+	}, func(function *ast.Function) problem.Problem {
+		return problem.NewWarning(
+			function.Position(),
+			fmt.Sprintf("Function \"%s\" has more than %d local variables", function.Name, threshold),
+			"max-locals",
+		)
+	})
+}
+
+// MaxBranches checks for too many branches in a function
+type MaxBranches struct{}
+
+func (r *MaxBranches) Name() string {
+	return "max-branches"
+}
+
+func (r *MaxBranches) Description() string {
+	return "Checks for too many branches in a function"
+}
+
+func (r *MaxBranches) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 12, Description: "Maximum number of branches in a function"},
+	}
+}
+
+func (r *MaxBranches) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	threshold := config.Setting(r, "threshold").(int)
+
+	// Each if, elif and else, each loop and each match branch is a branch
+	return checkFunctionCount(tree, threshold, func(node ast.Node) int {
+		switch n := node.(type) {
+		case *ast.IfStatement:
+			branches := 1 + len(n.ElseBranches)
+			if len(n.Alternative) > 0 {
+				branches++
+			}
+			return branches
+		case *ast.ForStatement, *ast.WhileStatement:
+			return 1
+		case *ast.MatchStatement:
+			return len(n.Branches)
+		}
+		return 0
+	}, func(function *ast.Function) problem.Problem {
+		return problem.NewWarning(
+			function.Position(),
+			fmt.Sprintf("Function \"%s\" has more than %d branches", function.Name, threshold),
+			"max-branches",
+		)
+	})
+}
+
+// MaxStatements checks for too many statements in a function
+type MaxStatements struct{}
+
+func (r *MaxStatements) Name() string {
+	return "max-statements"
+}
+
+func (r *MaxStatements) Description() string {
+	return "Checks for too many statements in a function"
+}
+
+func (r *MaxStatements) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 50, Description: "Maximum number of statements in a function"},
+	}
+}
+
+func (r *MaxStatements) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	threshold := config.Setting(r, "threshold").(int)
+
+	// Compound statements count once, plus every statement of their blocks
+	return checkFunctionCount(tree, threshold, func(node ast.Node) int {
+		if _, ok := node.(ast.Statement); ok {
+			return 1
+		}
+		return 0
+	}, func(function *ast.Function) problem.Problem {
+		return problem.NewWarning(
+			function.Position(),
+			fmt.Sprintf("Function \"%s\" has more than %d statements", function.Name, threshold),
+			"max-statements",
+		)
+	})
+}
+
+// MaxNestingDepth checks for blocks nested too deep in a function
+type MaxNestingDepth struct{}
+
+func (r *MaxNestingDepth) Name() string {
+	return "max-nesting-depth"
+}
+
+func (r *MaxNestingDepth) Description() string {
+	return "Checks for if, for, while and match blocks nested too deep in a function"
+}
+
+func (r *MaxNestingDepth) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 4, Description: "Maximum nesting depth of the blocks of a function"},
+	}
+}
+
+func (r *MaxNestingDepth) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	threshold := config.Setting(r, "threshold").(int)
+
+	// Only the outermost statements nested too deep are reported, rather
+	// than every statement within them
+	(&ast.TypedVisitor{VisitFunction: func(function *ast.Function, ancestors ast.NodeStack) {
+		analysis.WalkNesting(function.Statements, func(stmt ast.Statement, depth int) {
+			if depth == threshold+1 {
+				problems = append(problems, problem.NewWarning(
+					stmt.Position(),
+					fmt.Sprintf("Block nested more than %d deep in function \"%s\"", threshold, function.Name),
+					"max-nesting-depth",
+				))
+			}
+		})
+	}}).Walk(tree)
+
+	return problems
+}
+
+// MagicNumber checks for numeric literals in function bodies that should be named constants
+type MagicNumber struct{}
+
+func (r *MagicNumber) Name() string {
+	return "magic-number"
+}
+
+func (r *MagicNumber) Description() string {
+	return "Checks for numbers used directly in function bodies instead of named constants"
+}
+
+func (r *MagicNumber) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "allowed", Default: []float64{}, Description: "Numbers allowed besides 0, 1 and -1"},
+	}
+}
+
+func (r *MagicNumber) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	allowed := map[float64]bool{0: true, 1: true, -1: true}
+	switch values := config.Setting(r, "allowed").(type) {
+	case []float64:
+		for _, value := range values {
+			allowed[value] = true
+		}
+	case []any:
+		for _, value := range values {
+			if number, ok := value.(float64); ok {
+				allowed[number] = true
+			}
+		}
+	}
+
+	var problems []problem.Problem
+	report := func(pos ast.Position, number string, function *ast.Function) {
+		problems = append(problems, problem.NewWarning(
+			pos,
+			fmt.Sprintf("Magic number %s in function \"%s\", extract it to a constant", number, function.Name),
+			"magic-number",
+		))
+	}
+
+	(&ast.TypedVisitor{VisitFunction: func(function *ast.Function, ancestors ast.NodeStack) {
+		inspectBody(function, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.VarStatement:
+				// Constants are where numbers get their names
+				return !n.IsConst
+			case *ast.PrefixExpression:
+				// A negated literal is one number
+				if number, ok := n.Right.(*ast.NumberLiteral); ok && n.Operator == "-" {
+					if !allowed[-number.Value] {
+						report(n.Position(), "-"+number.Original, function)
+					}
+					return false
+				}
+			case *ast.NumberLiteral:
+				if !allowed[n.Value] {
+					report(n.Position(), n.Original, function)
+				}
+			}
+			return true
+		})
+	}}).Walk(tree)
+
+	return problems
+}
+
+// checkFunctionCount sums count over every node of the body of each
+// function, nested blocks included, and reports the functions whose total
+// exceeds threshold
+func checkFunctionCount(tree *ast.AbstractSyntaxTree, threshold int, count func(ast.Node) int,
+	report func(*ast.Function) problem.Problem) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitFunction: func(function *ast.Function, ancestors ast.NodeStack) {
+		total := 0
+		inspectBody(function, func(node ast.Node) bool {
+			total += count(node)
+			return true
+		})
+		if total > threshold {
+			problems = append(problems, report(function))
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// inspectBody calls f for every node of the body of function, descending
+// into nested blocks, in source order
+func inspectBody(function *ast.Function, f ast.Inspector) {
+	for _, stmt := range function.Statements {
+		ast.Inspect(stmt, f)
+	}
+}
+
 // isPublicFunction checks if a function name indicates it's public
 func isPublicFunction(name string) bool {
 	return len(name) > 0 && name[0] != '_'
@@ -168,5 +418,10 @@ func GetDefaultDesignRules() []linter.Rule {
 		&MaxPublicMethods{},
 		&MaxReturns{},
 		&FunctionArgumentsNumber{},
+		&MaxLocals{},
+		&MaxBranches{},
+		&MaxStatements{},
+		&MaxNestingDepth{},
+		&MagicNumber{},
 	}
 }
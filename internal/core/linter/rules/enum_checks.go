@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// DuplicateEnumValue checks for elements of an enum that have the value of
+// an element before them
+type DuplicateEnumValue struct{}
+
+// Name returns the name of the rule
+func (r *DuplicateEnumValue) Name() string {
+	return "duplicate-enum-value"
+}
+
+// Description returns a description of the rule
+func (r *DuplicateEnumValue) Description() string {
+	return "Checks for enum elements whose value, written or implicit, is already the value of an earlier element"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *DuplicateEnumValue) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitEnum: func(enum *ast.EnumStatement, ancestors ast.NodeStack) {
+		first := make(map[int64]*ast.EnumElement)
+		for _, value := range analysis.EnumValues(enum) {
+			if !value.Known {
+				continue
+			}
+			if earlier, ok := first[value.Value]; ok {
+				problems = append(problems, problem.NewWarning(
+					value.Element.Pos,
+					fmt.Sprintf("Enum element \"%s\" has the value %d of \"%s\"", value.Element.Name, value.Value, earlier.Name),
+					r.Name(),
+				))
+				continue
+			}
+			first[value.Value] = value.Element
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// NonMonotonicEnumValue checks for written values going back below the
+// element before them in enums that also have implicit values
+type NonMonotonicEnumValue struct{}
+
+// Name returns the name of the rule
+func (r *NonMonotonicEnumValue) Name() string {
+	return "non-monotonic-enum-value"
+}
+
+// Description returns a description of the rule
+func (r *NonMonotonicEnumValue) Description() string {
+	return "Checks for enum values lower than the value before them in enums mixing written and implicit values"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *NonMonotonicEnumValue) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitEnum: func(enum *ast.EnumStatement, ancestors ast.NodeStack) {
+		// An enum whose values are all written is ordered as its author
+		// chose; the implicit values of the others follow the ones before
+		implicit := false
+		for _, element := range enum.Elements {
+			implicit = implicit || element.Value == nil
+		}
+		if !implicit {
+			return
+		}
+
+		values := analysis.EnumValues(enum)
+		for i := 1; i < len(values); i++ {
+			value, previous := values[i], values[i-1]
+			if value.Element.Value != nil && value.Known && previous.Known && value.Value < previous.Value {
+				problems = append(problems, problem.NewWarning(
+					value.Element.Pos,
+					fmt.Sprintf("Enum element \"%s\" has the value %d, lower than the value %d of \"%s\" before it",
+						value.Element.Name, value.Value, previous.Value, previous.Element.Name),
+					r.Name(),
+				))
+			}
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// GetDefaultEnumRules returns the rules checking the values of enums. They
+// have no counterpart in Python gdlint and are not enabled by default.
+func GetDefaultEnumRules() []linter.Rule {
+	return []linter.Rule{
+		&DuplicateEnumValue{},
+		&NonMonotonicEnumValue{},
+	}
+}
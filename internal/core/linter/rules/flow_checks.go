@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// UselessReturn checks for a bare return ending a function that returns nothing
+type UselessReturn struct{}
+
+// Name returns the name of the rule
+func (r *UselessReturn) Name() string {
+	return "useless-return"
+}
+
+// Description returns a description of the rule
+func (r *UselessReturn) Description() string {
+	return "Checks for a bare return as the last statement of a function that returns nothing"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UselessReturn) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitFunction: func(function *ast.Function, ancestors ast.NodeStack) {
+		// A function made of a bare return is a stub, like one made of pass
+		body := function.Statements
+		if len(body) < 2 || (function.ReturnType != "" && function.ReturnType != "void") {
+			return
+		}
+		if ret, ok := body[len(body)-1].(*ast.ReturnStatement); ok && ret.Value == nil {
+			problems = append(problems, problem.NewWarning(
+				ret.Position(),
+				"Useless \"return\" at the end of function \""+function.Name+"\"",
+				"useless-return",
+			))
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// UnreachableCode checks for statements after a return, break or continue in the same block
+type UnreachableCode struct{}
+
+// Name returns the name of the rule
+func (r *UnreachableCode) Name() string {
+	return "unreachable-code"
+}
+
+// Description returns a description of the rule
+func (r *UnreachableCode) Description() string {
+	return "Checks for statements that follow a return, break or continue in the same block"
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *UnreachableCode) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassControlFlow}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UnreachableCode) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *UnreachableCode) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitFunction: func(function *ast.Function, ancestors ast.NodeStack) {
+		flow := results.Flow(function)
+		ast.Inspect(function, func(node ast.Node) bool {
+			for _, block := range nestedBlocks(node) {
+				// Only the first unreachable statement of a block is reported
+				for i := 1; i < len(block); i++ {
+					if flow.Terminates(block[i-1]) {
+						problems = append(problems, problem.NewWarning(
+							block[i].Position(),
+							"Unreachable code",
+							"unreachable-code",
+						))
+						break
+					}
+				}
+			}
+			return true
+		})
+	}}).Walk(results.Tree)
+
+	return problems
+}
+
+// GetDefaultFlowRules returns the rules checking the control flow of
+// functions. They have no counterpart in Python gdlint and are not enabled by
+// default.
+func GetDefaultFlowRules() []linter.Rule {
+	return []linter.Rule{
+		&UselessReturn{},
+		&UnreachableCode{},
+	}
+}
@@ -1,11 +1,13 @@
 package rules
 
 import (
-	"regexp"
-	"strconv"
+	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter"
 	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
 )
@@ -21,18 +23,36 @@ func (r *MaxLineLength) Description() string {
 	return "Checks for lines that exceed the maximum length"
 }
 
+func (r *MaxLineLength) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 100, Description: "Maximum number of characters in a line"},
+	}
+}
+
+// Check applies the rule to an AST and returns any problems found, which is
+// none without the source
 func (r *MaxLineLength) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
+	return r.CheckSource("", "", tree, config)
+}
 
-	// This rule needs the original source code, not just the AST
-	// For now, we'll implement a basic check
-	threshold := config.GetRuleSetting(r.Name(), "threshold", 100).(int)
-	tabChars := config.GetRuleSetting("tab-characters", "value", 4).(int)
+// CheckSource applies the rule to a file, reporting each line longer than
+// the threshold. Like gdlint, it counts characters rather than UTF-8 bytes,
+// and a tab as tab-characters of them.
+func (r *MaxLineLength) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	threshold := config.Setting(r, "threshold").(int)
+	tab := strings.Repeat(" ", config.GetRuleSetting("tab-characters", "value", 4).(int))
 
-	// TODO: Get source code from tree context
-	// For now, return empty problems as we need source access
-	_ = threshold
-	_ = tabChars
+	for i, line := range sourceLines(source) {
+		line = strings.ReplaceAll(line, "\t", tab)
+		if utf8.RuneCountInString(line) > threshold {
+			problems = append(problems, problem.NewWarning(
+				ast.Position{Line: i + 1, Column: 1},
+				fmt.Sprintf("Max allowed line length (%d) exceeded", threshold),
+				r.Name(),
+			))
+		}
+	}
 
 	return problems
 }
@@ -48,14 +68,85 @@ func (r *MaxFileLines) Description() string {
 	return "Checks for files that exceed the maximum number of lines"
 }
 
+func (r *MaxFileLines) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 1000, Description: "Maximum number of lines in a file"},
+	}
+}
+
+// Check applies the rule to an AST and returns any problems found, which is
+// none without the source
 func (r *MaxFileLines) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckSource("", "", tree, config)
+}
+
+// CheckSource applies the rule to a file, reporting its last line when it
+// has more lines than the threshold
+func (r *MaxFileLines) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	threshold := config.Setting(r, "threshold").(int)
+	lines := len(sourceLines(source))
+	if lines <= threshold {
+		return nil
+	}
+	return []problem.Problem{problem.NewWarning(
+		ast.Position{Line: lines, Column: 1},
+		fmt.Sprintf("Max allowed file lines num (%d) exceeded", threshold),
+		r.Name(),
+	)}
+}
+
+// MaxFunctionLines checks for functions that have too many lines
+type MaxFunctionLines struct{}
+
+func (r *MaxFunctionLines) Name() string {
+	return "max-function-lines"
+}
+
+func (r *MaxFunctionLines) Description() string {
+	return "Checks for functions that exceed the maximum number of lines, blank and comment lines aside"
+}
+
+func (r *MaxFunctionLines) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 50, Description: "Maximum number of lines of code in a function"},
+	}
+}
+
+func (r *MaxFunctionLines) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
+	threshold := config.Setting(r, "threshold").(int)
 
-	threshold := config.GetRuleSetting(r.Name(), "threshold", 1000).(int)
+	// Blank lines and lines holding nothing but a comment are not counted
+	skipped := make(map[int]bool)
+	for _, line := range tree.BlankLines {
+		skipped[line] = true
+	}
+	for _, comment := range tree.Comments {
+		if !comment.Inline {
+			skipped[comment.Pos.Line] = true
+		}
+	}
 
-	// TODO: Get line count from tree context
-	// For now, return empty problems as we need source access
-	_ = threshold
+	ast.Inspect(tree, func(node ast.Node) bool {
+		function, ok := node.(*ast.Function)
+		if !ok {
+			return true
+		}
+		lines := 0
+		for line := function.Pos.Line; line <= function.End.Line; line++ {
+			if !skipped[line] {
+				lines++
+			}
+		}
+		if lines > threshold {
+			problems = append(problems, problem.NewWarning(
+				function.Position(),
+				fmt.Sprintf("Function \"%s\" has %d lines, more than %d", function.Name, lines, threshold),
+				"max-function-lines",
+			))
+		}
+		return true
+	})
 
 	return problems
 }
@@ -71,11 +162,36 @@ func (r *TrailingWhitespace) Description() string {
 	return "Checks for trailing whitespace in lines"
 }
 
+// Check applies the rule to an AST and returns any problems found, which is
+// none without the source
 func (r *TrailingWhitespace) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
+	return r.CheckSource("", "", tree, config)
+}
 
-	// TODO: Get source code from tree context
-	// For now, return empty problems as we need source access
+// CheckSource applies the rule to a file, reporting where the whitespace
+// ending each line starts, and fixing the problem by deleting it
+func (r *TrailingWhitespace) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	offset := 0
+	for i, line := range strings.Split(source, "\n") {
+		start := offset
+		offset += len(line) + 1
+		line = strings.TrimSuffix(line, "\r")
+		code := strings.TrimRightFunc(line, unicode.IsSpace)
+		if code == line {
+			continue
+		}
+		p := problem.NewWarning(
+			ast.Position{Line: i + 1, Column: utf8.RuneCountInString(code) + 1, Offset: start + len(code)},
+			"Trailing whitespace(s)",
+			r.Name(),
+		)
+		p.Fix = &problem.Fix{
+			Description: "Delete the trailing whitespace",
+			Edits:       []problem.Edit{{Start: start + len(code), End: start + len(line)}},
+		}
+		problems = append(problems, p)
+	}
 
 	return problems
 }
@@ -91,39 +207,33 @@ func (r *MixedTabsAndSpaces) Description() string {
 	return "Checks for mixed tabs and spaces in indentation"
 }
 
+// Check applies the rule to an AST and returns any problems found, which is
+// none without the source
 func (r *MixedTabsAndSpaces) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
-
-	// TODO: Get source code from tree context
-	// For now, return empty problems as we need source access
-
-	return problems
-}
-
-// FormatChecker provides source-based format checking
-type FormatChecker struct {
-	source string
-}
-
-// NewFormatChecker creates a new format checker with source code
-func NewFormatChecker(source string) *FormatChecker {
-	return &FormatChecker{source: source}
+	return r.CheckSource("", "", tree, config)
 }
 
-// CheckMaxLineLength checks for lines that exceed maximum length
-func (f *FormatChecker) CheckMaxLineLength(threshold, tabChars int) []problem.Problem {
+// CheckSource applies the rule to a file, reporting each line whose
+// indentation has both tabs and spaces, but for the lines formatter.Reindent
+// leaves as written with tabs, such as the alignment spaces past the tabs of
+// a continued line, which indentation-consistency does not fix either
+func (r *MixedTabsAndSpaces) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
-	lines := strings.Split(f.source, "\n")
-
-	for lineNum, line := range lines {
-		// Replace tabs with spaces for length calculation
-		expandedLine := strings.ReplaceAll(line, "\t", strings.Repeat(" ", tabChars))
-
-		if len(expandedLine) > threshold {
+	var reindented []string
+	for i, line := range sourceLines(source) {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !strings.Contains(indent, "\t") || !strings.Contains(indent, " ") {
+			continue
+		}
+		if reindented == nil {
+			style, _ := formatter.ParseIndentStyle("tabs")
+			reindented = sourceLines(formatter.Reindent(source, style))
+		}
+		if i >= len(reindented) || reindented[i] != line {
 			problems = append(problems, problem.NewWarning(
-				ast.Position{Line: lineNum + 1, Column: 1},
-				"Max allowed line length ("+strconv.Itoa(threshold)+") exceeded",
-				"max-line-length",
+				ast.Position{Line: i + 1, Column: 1},
+				"Mixed tabs and spaces",
+				r.Name(),
 			))
 		}
 	}
@@ -131,66 +241,96 @@ func (f *FormatChecker) CheckMaxLineLength(threshold, tabChars int) []problem.Pr
 	return problems
 }
 
-// CheckMaxFileLines checks for files that exceed maximum number of lines
-func (f *FormatChecker) CheckMaxFileLines(threshold int) []problem.Problem {
-	var problems []problem.Problem
-	lines := strings.Split(f.source, "\n")
-
-	if len(lines) > threshold {
-		problems = append(problems, problem.NewWarning(
-			ast.Position{Line: len(lines), Column: 1},
-			"Max allowed file lines num ("+strconv.Itoa(threshold)+") exceeded",
-			"max-file-lines",
-		))
-	}
+// IndentationConsistency checks that every indented line is indented in the
+// style of the project, tabs or a number of spaces
+type IndentationConsistency struct{}
 
-	return problems
+func (r *IndentationConsistency) Name() string {
+	return "indentation-consistency"
 }
 
-// CheckTrailingWhitespace checks for trailing whitespace
-func (f *FormatChecker) CheckTrailingWhitespace() []problem.Problem {
-	var problems []problem.Problem
-	lines := strings.Split(f.source, "\n")
-	trailingWSRegex := regexp.MustCompile(`\s$`)
+func (r *IndentationConsistency) Description() string {
+	return "Checks that every indented line is indented with the tabs or spaces the project uses"
+}
 
-	for lineNum, line := range lines {
-		if trailingWSRegex.MatchString(line) {
-			problems = append(problems, problem.NewWarning(
-				ast.Position{Line: lineNum + 1, Column: 1},
-				"Trailing whitespace(s)",
-				"trailing-whitespace",
-			))
-		}
+func (r *IndentationConsistency) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "style", Default: "tabs", Description: "Indentation of the project: 'tabs', or 'spaces:N' for N spaces per level"},
 	}
+}
 
-	return problems
+// Check applies the rule to an AST and returns any problems found, which is
+// none without the source
+func (r *IndentationConsistency) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckSource("", "", tree, config)
 }
 
-// CheckMixedTabsAndSpaces checks for mixed tabs and spaces in indentation
-func (f *FormatChecker) CheckMixedTabsAndSpaces() []problem.Problem {
+// CheckSource applies the rule to a file, reporting the first character of
+// each line's indentation that formatter.Reindent changes, and fixing the
+// problem with the reindented line, so that --fix reindents the file
+func (r *IndentationConsistency) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
-	lines := strings.Split(f.source, "\n")
-	mixedRegex := regexp.MustCompile(`^(\t+ +| +\t+)`)
+	style, err := formatter.ParseIndentStyle(config.Setting(r, "style").(string))
+	if err != nil {
+		style, _ = formatter.ParseIndentStyle("tabs") // fallback to default
+	}
+	message := "Indentation out of style, the project indents with tabs"
+	if !style.Tabs {
+		message = fmt.Sprintf("Indentation out of style, the project indents with %d spaces", style.Size)
+	}
 
-	for lineNum, line := range lines {
-		if mixedRegex.MatchString(line) {
-			problems = append(problems, problem.NewWarning(
-				ast.Position{Line: lineNum + 1, Column: 1},
-				"Mixed tabs and spaces",
-				"mixed-tabs-and-spaces",
-			))
+	// Lines are compared with the same lines reindented by the formatter
+	reindented := strings.Split(formatter.Reindent(source, style), "\n")
+	offset := 0
+	for i, line := range strings.Split(source, "\n") {
+		start := offset
+		offset += len(line) + 1
+		code := strings.TrimLeft(line, " \t")
+		// Blank lines are left to trailing-whitespace
+		if i >= len(reindented) || strings.TrimSpace(code) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(code)]
+		converted := reindented[i][:len(reindented[i])-len(code)]
+		if converted == indent {
+			continue
+		}
+		column := 0
+		for column < len(indent) && column < len(converted) && indent[column] == converted[column] {
+			column++
+		}
+		p := problem.NewWarning(ast.Position{Line: i + 1, Column: column + 1, Offset: start + column}, message, r.Name())
+		p.Fix = &problem.Fix{
+			Description: "Reindent the line with " + style.String(),
+			Edits:       []problem.Edit{{Start: start, End: start + len(indent), Text: converted}},
 		}
+		problems = append(problems, p)
 	}
 
 	return problems
 }
 
+// sourceLines splits source into lines the way gdlint does: without their
+// line endings, and without an empty line after the last line ending
+func sourceLines(source string) []string {
+	if source == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
 // GetDefaultFormatRules returns the default format checking rules
 func GetDefaultFormatRules() []linter.Rule {
 	return []linter.Rule{
 		&MaxLineLength{},
 		&MaxFileLines{},
+		&MaxFunctionLines{},
 		&TrailingWhitespace{},
 		&MixedTabsAndSpaces{},
+		&IndentationConsistency{},
 	}
 }
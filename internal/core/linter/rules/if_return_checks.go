@@ -18,37 +18,7 @@ func (r *NoElifReturn) Description() string {
 }
 
 func (r *NoElifReturn) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
-
-	visitor := &noElifReturnVisitor{
-		problems: &problems,
-	}
-
-	ast.Walk(visitor, tree)
-	return problems
-}
-
-type noElifReturnVisitor struct {
-	problems *[]problem.Problem
-}
-
-func (v *noElifReturnVisitor) Visit(node ast.Node) ast.Visitor {
-	if ifStmt, ok := node.(*ast.IfStatement); ok {
-		// Check if the if branch always returns
-		if v.branchAlwaysReturns(ifStmt.Consequence) {
-			// Check each elif branch
-			for i := range ifStmt.ElseBranches {
-				// Create a position for the elif branch
-				pos := ast.Position{Line: ifStmt.Position().Line + i + 1, Column: 1}
-				*v.problems = append(*v.problems, problem.NewWarning(
-					pos,
-					"Unnecessary \"elif\" after \"return\"",
-					"no-elif-return",
-				))
-			}
-		}
-	}
-	return v
+	return ifExitChecker{rule: r.Name(), keyword: "return", exits: isReturn, elif: true}.check(tree)
 }
 
 // NoElseReturn checks for unnecessary else after return
@@ -63,154 +33,123 @@ func (r *NoElseReturn) Description() string {
 }
 
 func (r *NoElseReturn) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
-	var problems []problem.Problem
+	return ifExitChecker{rule: r.Name(), keyword: "return", exits: isReturn, els: true}.check(tree)
+}
 
-	visitor := &noElseReturnVisitor{
-		problems: &problems,
-	}
+// NoElseBreak checks for unnecessary elif and else after break
+type NoElseBreak struct{}
 
-	ast.Walk(visitor, tree)
-	return problems
+func (r *NoElseBreak) Name() string {
+	return "no-else-break"
 }
 
-type noElseReturnVisitor struct {
-	problems *[]problem.Problem
+func (r *NoElseBreak) Description() string {
+	return "Checks for unnecessary elif and else after break"
 }
 
-func (v *noElseReturnVisitor) Visit(node ast.Node) ast.Visitor {
-	if ifStmt, ok := node.(*ast.IfStatement); ok {
-		// Check if all non-else branches always return
-		allNonElseBranchesReturn := true
-
-		// Check if branch
-		if !v.branchAlwaysReturns(ifStmt.Consequence) {
-			allNonElseBranchesReturn = false
-		}
+func (r *NoElseBreak) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return ifExitChecker{rule: r.Name(), keyword: "break", exits: isBreak, elif: true, els: true}.check(tree)
+}
 
-		// Check elif branches
-		for _, elifBranch := range ifStmt.ElseBranches {
-			if !v.branchAlwaysReturns(elifBranch) {
-				allNonElseBranchesReturn = false
-				break
-			}
-		}
+// NoElseContinue checks for unnecessary elif and else after continue
+type NoElseContinue struct{}
 
-		// If all non-else branches return and there's an else branch
-		if allNonElseBranchesReturn && len(ifStmt.Alternative) > 0 {
-			// Create a position for the else branch
-			pos := ast.Position{Line: ifStmt.Position().Line + len(ifStmt.ElseBranches) + 1, Column: 1}
-			*v.problems = append(*v.problems, problem.NewWarning(
-				pos,
-				"Unnecessary \"else\" after \"return\"",
-				"no-else-return",
-			))
-		}
-	}
-	return v
+func (r *NoElseContinue) Name() string {
+	return "no-else-continue"
 }
 
-// branchAlwaysReturns checks if a branch always returns
-func (v *noElifReturnVisitor) branchAlwaysReturns(statements []ast.Statement) bool {
-	return v.hasReturnStatement(statements) || v.hasIfThatAlwaysReturns(statements)
+func (r *NoElseContinue) Description() string {
+	return "Checks for unnecessary elif and else after continue"
 }
 
-// branchAlwaysReturns checks if a branch always returns (for else return visitor)
-func (v *noElseReturnVisitor) branchAlwaysReturns(statements []ast.Statement) bool {
-	return v.hasReturnStatement(statements) || v.hasIfThatAlwaysReturns(statements)
+func (r *NoElseContinue) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return ifExitChecker{rule: r.Name(), keyword: "continue", exits: isContinue, elif: true, els: true}.check(tree)
 }
 
-// hasReturnStatement checks if there's a return statement among the statements
-func (v *noElifReturnVisitor) hasReturnStatement(statements []ast.Statement) bool {
-	for _, stmt := range statements {
-		if _, ok := stmt.(*ast.ReturnStatement); ok {
-			return true
-		}
-	}
-	return false
+// ifExitChecker reports the elif and else branches that follow branches
+// always leaving the block with the statement exits accepts, and that could
+// therefore be plain if statements or follow the if statement
+type ifExitChecker struct {
+	rule    string
+	keyword string
+	exits   func(ast.Statement) bool
+	// elif reports the elif branches after an if branch that exits
+	elif bool
+	// els reports the else branch after if and elif branches that all exit
+	els bool
 }
 
-// hasReturnStatement checks if there's a return statement among the statements (for else return visitor)
-func (v *noElseReturnVisitor) hasReturnStatement(statements []ast.Statement) bool {
-	for _, stmt := range statements {
-		if _, ok := stmt.(*ast.ReturnStatement); ok {
+func (c ifExitChecker) check(tree *ast.AbstractSyntaxTree) []problem.Problem {
+	var problems []problem.Problem
+
+	ast.Inspect(tree, func(node ast.Node) bool {
+		ifStmt, ok := node.(*ast.IfStatement)
+		if !ok {
 			return true
 		}
-	}
-	return false
-}
-
-// hasIfThatAlwaysReturns checks if there's an if statement that always returns
-func (v *noElifReturnVisitor) hasIfThatAlwaysReturns(statements []ast.Statement) bool {
-	for _, stmt := range statements {
-		if ifStmt, ok := stmt.(*ast.IfStatement); ok {
-			if v.ifAlwaysReturns(ifStmt) {
-				return true
+		if c.elif && branchAlwaysExits(ifStmt.Consequence, c.exits) {
+			for _, condition := range ifStmt.ElseCondition {
+				problems = append(problems, problem.NewWarning(
+					condition.Position(),
+					"Unnecessary \"elif\" after \""+c.keyword+"\"",
+					c.rule,
+				))
 			}
 		}
-	}
-	return false
+		if c.els && len(ifStmt.Alternative) > 0 && branchesAlwaysExit(ifStmt, c.exits) {
+			problems = append(problems, problem.NewWarning(
+				ifStmt.ElsePos,
+				"Unnecessary \"else\" after \""+c.keyword+"\"",
+				c.rule,
+			))
+		}
+		return true
+	})
+
+	return problems
 }
 
-// hasIfThatAlwaysReturns checks if there's an if statement that always returns (for else return visitor)
-func (v *noElseReturnVisitor) hasIfThatAlwaysReturns(statements []ast.Statement) bool {
+// branchAlwaysExits reports whether statements contain a statement exits
+// accepts, or an if statement whose branches, else included, all do
+func branchAlwaysExits(statements []ast.Statement, exits func(ast.Statement) bool) bool {
 	for _, stmt := range statements {
-		if ifStmt, ok := stmt.(*ast.IfStatement); ok {
-			if v.ifAlwaysReturns(ifStmt) {
-				return true
-			}
+		if exits(stmt) {
+			return true
+		}
+		if ifStmt, ok := stmt.(*ast.IfStatement); ok && len(ifStmt.Alternative) > 0 &&
+			branchesAlwaysExit(ifStmt, exits) && branchAlwaysExits(ifStmt.Alternative, exits) {
+			return true
 		}
 	}
 	return false
 }
 
-// ifAlwaysReturns checks if an if statement always returns
-func (v *noElifReturnVisitor) ifAlwaysReturns(ifStmt *ast.IfStatement) bool {
-	// Must have an else branch to always return
-	if len(ifStmt.Alternative) == 0 {
+// branchesAlwaysExit reports whether the if and elif branches of ifStmt all exit
+func branchesAlwaysExit(ifStmt *ast.IfStatement, exits func(ast.Statement) bool) bool {
+	if !branchAlwaysExits(ifStmt.Consequence, exits) {
 		return false
 	}
-
-	// All branches must return
-	if !v.branchAlwaysReturns(ifStmt.Consequence) {
-		return false
-	}
-
 	for _, elifBranch := range ifStmt.ElseBranches {
-		if !v.branchAlwaysReturns(elifBranch) {
+		if !branchAlwaysExits(elifBranch, exits) {
 			return false
 		}
 	}
-
-	if !v.branchAlwaysReturns(ifStmt.Alternative) {
-		return false
-	}
-
 	return true
 }
 
-// ifAlwaysReturns checks if an if statement always returns (for else return visitor)
-func (v *noElseReturnVisitor) ifAlwaysReturns(ifStmt *ast.IfStatement) bool {
-	// Must have an else branch to always return
-	if len(ifStmt.Alternative) == 0 {
-		return false
-	}
-
-	// All branches must return
-	if !v.branchAlwaysReturns(ifStmt.Consequence) {
-		return false
-	}
-
-	for _, elifBranch := range ifStmt.ElseBranches {
-		if !v.branchAlwaysReturns(elifBranch) {
-			return false
-		}
-	}
+func isReturn(stmt ast.Statement) bool {
+	_, ok := stmt.(*ast.ReturnStatement)
+	return ok
+}
 
-	if !v.branchAlwaysReturns(ifStmt.Alternative) {
-		return false
-	}
+func isBreak(stmt ast.Statement) bool {
+	_, ok := stmt.(*ast.BreakStatement)
+	return ok
+}
 
-	return true
+func isContinue(stmt ast.Statement) bool {
+	_, ok := stmt.(*ast.ContinueStatement)
+	return ok
 }
 
 // GetDefaultIfReturnRules returns the default if-return checking rules
@@ -218,5 +157,7 @@ func GetDefaultIfReturnRules() []linter.Rule {
 	return []linter.Rule{
 		&NoElifReturn{},
 		&NoElseReturn{},
+		&NoElseBreak{},
+		&NoElseContinue{},
 	}
 }
@@ -0,0 +1,286 @@
+package rules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// RepeatedLoad checks for resources loaded with load() in several places of
+// the functions of a class, which a class-level preload constant loads once
+type RepeatedLoad struct{}
+
+// Name returns the name of the rule
+func (r *RepeatedLoad) Name() string {
+	return "repeated-load"
+}
+
+// Description returns a description of the rule
+func (r *RepeatedLoad) Description() string {
+	return "Checks for a resource loaded with load() several times in functions, instead of preloaded once into a class-level constant"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *RepeatedLoad) DefaultSeverity() problem.Severity {
+	return problem.Info
+}
+
+// Settings lists the settings the rule reads
+func (r *RepeatedLoad) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "threshold", Default: 2, Description: "Number of load() calls of a path in the functions of a class from which they are reported"},
+	}
+}
+
+// Check applies the rule to an AST and returns any problems found, without fixes
+func (r *RepeatedLoad) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckSource("", "", tree, config)
+}
+
+// CheckSource applies the rule to a file. The first call of each path is
+// fixed by replacing every call with a preload constant, declared unless the
+// class already has one.
+func (r *RepeatedLoad) CheckSource(filePath, source string, tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	threshold := config.Setting(r, "threshold").(int)
+
+	type key struct {
+		class *ast.Class
+		path  string
+	}
+	var order []key
+	calls := make(map[key][]analysis.LoadCall)
+	constants := make(map[key]*ast.VarStatement)
+	for _, load := range analysis.FindLoadCalls(tree) {
+		k := key{load.Class, load.Path}
+		switch {
+		case load.Function == nil && load.Decl != nil && load.Decl.IsConst:
+			if constants[k] == nil {
+				constants[k] = load.Decl
+			}
+		case load.Function != nil && !load.Preload:
+			if calls[k] == nil {
+				order = append(order, k)
+			}
+			calls[k] = append(calls[k], load)
+		}
+	}
+
+	var problems []problem.Problem
+	for _, k := range order {
+		loads := calls[k]
+		if len(loads) < threshold {
+			continue
+		}
+
+		message := fmt.Sprintf("'%s' is loaded %d times in functions; preload it once into a class-level constant", k.path, len(loads))
+		if constant := constants[k]; constant != nil {
+			message = fmt.Sprintf("'%s' is loaded %d times in functions; use the constant %s instead", k.path, len(loads), constant.Name)
+		}
+		for i, load := range loads {
+			p := problem.NewProblem(load.Call.Function.Position(), message, r.Name(), r.DefaultSeverity())
+			if i == 0 {
+				p.Fix = preloadConstantFix(source, tree, k.class, loads, constants[k])
+			}
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// preloadConstantFix returns the fix replacing the load calls of a path with
+// constant, or with a preload constant declared in class when constant is
+// nil. It returns nil when source is not available or a call cannot be
+// replaced safely.
+func preloadConstantFix(source string, tree *ast.AbstractSyntaxTree, class *ast.Class, loads []analysis.LoadCall, constant *ast.VarStatement) *problem.Fix {
+	if source == "" {
+		return nil
+	}
+
+	var edits []problem.Edit
+	name := ""
+	if constant != nil {
+		name = constant.Name
+	} else {
+		name = preloadConstantName(loads[0].Path)
+		if name == "" || isNameUsed(tree, name) {
+			return nil
+		}
+		insertion, ok := constantInsertion(source, class)
+		if !ok {
+			return nil
+		}
+		literal := loads[0].Call.Arguments[0].(*ast.StringLiteral).Raw
+		indent := source[insertion:memberStart(source, insertion)]
+		edits = append(edits, problem.Edit{
+			Start: insertion,
+			End:   insertion,
+			Text:  fmt.Sprintf("%sconst %s = preload(%s)\n", indent, name, literal),
+		})
+	}
+
+	for _, load := range loads {
+		start, end, ok := loadCallSpan(source, load.Call)
+		if !ok {
+			return nil
+		}
+		edits = append(edits, problem.Edit{Start: start, End: end, Text: name})
+	}
+
+	return &problem.Fix{
+		Description: fmt.Sprintf("Use the preload constant %s", name),
+		Edits:       edits,
+	}
+}
+
+// preloadConstantName derives a constant name from a resource path, e.g.
+// ENEMY_SHIP_SCENE from res://enemies/EnemyShip.tscn. It returns an empty
+// name when the file name gives none.
+func preloadConstantName(resource string) string {
+	ext := path.Ext(resource)
+	base := strings.TrimSuffix(path.Base(resource), ext)
+
+	var name strings.Builder
+	var previous rune
+	for _, c := range base {
+		switch {
+		case unicode.IsUpper(c) && (unicode.IsLower(previous) || unicode.IsDigit(previous)):
+			name.WriteRune('_')
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			c = '_'
+		}
+		if c != '_' || (name.Len() > 0 && previous != '_') {
+			name.WriteRune(unicode.ToUpper(c))
+		}
+		previous = c
+	}
+	result := strings.TrimSuffix(name.String(), "_")
+	if result == "" || unicode.IsDigit(rune(result[0])) || !isASCII(result) {
+		return ""
+	}
+
+	switch ext {
+	case ".tscn", ".scn":
+		result += "_SCENE"
+	case ".gd":
+		result += "_SCRIPT"
+	}
+	return result
+}
+
+// isASCII reports whether s is made of ASCII characters only
+func isASCII(s string) bool {
+	for _, c := range s {
+		if c > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// isNameUsed reports whether name is declared or referenced anywhere in tree
+func isNameUsed(tree *ast.AbstractSyntaxTree, name string) bool {
+	used := false
+	ast.Inspect(tree, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Identifier:
+			used = used || n.Value == name
+		case *ast.VarStatement:
+			used = used || n.Name == name
+		case *ast.Function:
+			used = used || n.Name == name
+		case *ast.Class:
+			used = used || n.Name == name
+		}
+		return !used
+	})
+	return used
+}
+
+// constantInsertion returns the offset of the line a new constant of class is
+// inserted at: that of the first member ordered after the constants
+func constantInsertion(source string, class *ast.Class) (int, bool) {
+	var members []ast.Node
+	for _, stmt := range class.Statements {
+		if ast.MemberKindOf(stmt) > ast.MemberConst {
+			members = append(members, stmt)
+		}
+	}
+	for _, function := range class.Functions {
+		members = append(members, function)
+	}
+	for _, subClass := range class.SubClasses {
+		members = append(members, subClass)
+	}
+
+	offset := -1
+	for _, member := range members {
+		start := member.Position().Offset
+		for _, annotation := range ast.AnnotationsOf(member) {
+			start = min(start, annotation.Pos.Offset)
+		}
+		if offset < 0 || start < offset {
+			offset = start
+		}
+	}
+	if offset < 0 || offset > len(source) {
+		return 0, false
+	}
+
+	lineStart := strings.LastIndexByte(source[:offset], '\n') + 1
+	if strings.TrimLeft(source[lineStart:offset], " \t") != "" {
+		return 0, false
+	}
+	return lineStart, true
+}
+
+// memberStart returns the offset of the first character after the
+// indentation of the line starting at lineStart
+func memberStart(source string, lineStart int) int {
+	end := lineStart
+	for end < len(source) && (source[end] == ' ' || source[end] == '\t') {
+		end++
+	}
+	return end
+}
+
+// loadCallSpan returns the offsets of a load call with a single string
+// argument, from the start of load to the closing parenthesis included
+func loadCallSpan(source string, call *ast.CallExpression) (int, int, bool) {
+	function, ok := call.Function.(*ast.Identifier)
+	if !ok || len(call.Arguments) != 1 {
+		return 0, 0, false
+	}
+	literal := call.Arguments[0].(*ast.StringLiteral).Raw
+
+	start := function.Pos.Offset
+	if start < 0 || start > len(source) || !strings.HasPrefix(source[start:], "load") {
+		return 0, 0, false
+	}
+	rest := strings.TrimLeft(source[start+len("load"):], " \t")
+	if !strings.HasPrefix(rest, "(") {
+		return 0, 0, false
+	}
+	rest = strings.TrimLeft(rest[1:], " \t")
+	if !strings.HasPrefix(rest, literal) {
+		return 0, 0, false
+	}
+	rest = strings.TrimLeft(rest[len(literal):], " \t")
+	if !strings.HasPrefix(rest, ")") {
+		return 0, 0, false
+	}
+	return start, len(source) - len(rest) + 1, true
+}
+
+// GetDefaultLoadRules returns the rules checking how resources are loaded.
+// They have no counterpart in Python gdlint and are not enabled by default.
+func GetDefaultLoadRules() []linter.Rule {
+	return []linter.Rule{
+		&RepeatedLoad{},
+	}
+}
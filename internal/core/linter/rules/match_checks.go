@@ -0,0 +1,215 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// DuplicateMatchPattern checks for match patterns that an earlier pattern
+// of the same match already catches
+type DuplicateMatchPattern struct{}
+
+// Name returns the name of the rule
+func (r *DuplicateMatchPattern) Name() string {
+	return "duplicate-match-pattern"
+}
+
+// Description returns a description of the rule
+func (r *DuplicateMatchPattern) Description() string {
+	return "Checks for literal and constant patterns repeated in a match, which the first of them always catches"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *DuplicateMatchPattern) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitMatch: func(match *ast.MatchStatement, ancestors ast.NodeStack) {
+		// The patterns of a guarded branch catch nothing the next branches
+		// do not see, as the guard may fail
+		var earlier []ast.Expression
+		for _, branch := range match.Branches {
+			seen := earlier
+			for _, pattern := range branch.Patterns {
+				for _, previous := range seen {
+					if samePattern(pattern, previous) {
+						problems = append(problems, problem.NewWarning(
+							pattern.Position(),
+							fmt.Sprintf("Duplicate pattern in match, already matched on line %d", previous.Position().Line),
+							r.Name(),
+						))
+						break
+					}
+				}
+				seen = append(seen[:len(seen):len(seen)], pattern)
+			}
+			if !branch.IsGuarded {
+				earlier = append(earlier, branch.Patterns...)
+			}
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// samePattern reports whether two patterns match the same value: literals
+// of the same type and value, or the same constant
+func samePattern(a, b ast.Expression) bool {
+	if x, ok := analysis.Fold(a); ok {
+		y, ok := analysis.Fold(b)
+		return ok && x == y
+	}
+	switch a.(type) {
+	case *ast.Identifier, *ast.DotExpression:
+		return analysis.SameValue(a, b)
+	}
+	return false
+}
+
+// UnreachableMatchBranch checks for match branches after one that matches
+// every value
+type UnreachableMatchBranch struct{}
+
+// Name returns the name of the rule
+func (r *UnreachableMatchBranch) Name() string {
+	return "unreachable-match-branch"
+}
+
+// Description returns a description of the rule
+func (r *UnreachableMatchBranch) Description() string {
+	return "Checks for match branches after an unguarded wildcard or binding branch, which never run"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UnreachableMatchBranch) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitMatch: func(match *ast.MatchStatement, ancestors ast.NodeStack) {
+		// Only the first unreachable branch is reported
+		for i := 1; i < len(match.Branches); i++ {
+			if previous := match.Branches[i-1]; analysis.CatchesAll(previous) {
+				problems = append(problems, problem.NewWarning(
+					match.Branches[i].Pos,
+					fmt.Sprintf("Unreachable match branch, the branch on line %d matches every value", previous.Pos.Line),
+					r.Name(),
+				))
+				break
+			}
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// IncompleteEnumMatch checks for matches over the values of an enum that
+// leave some of them out
+type IncompleteEnumMatch struct{}
+
+// Name returns the name of the rule
+func (r *IncompleteEnumMatch) Name() string {
+	return "incomplete-enum-match"
+}
+
+// Description returns a description of the rule
+func (r *IncompleteEnumMatch) Description() string {
+	return "Checks for matches whose patterns are values of an enum of the script that miss some of its values and have no wildcard branch"
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *IncompleteEnumMatch) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitMatch: func(match *ast.MatchStatement, ancestors ast.NodeStack) {
+		// Every pattern names a value of the same enum, as Enum.VALUE
+		name := ""
+		covered := make(map[string]bool)
+		for _, branch := range match.Branches {
+			if analysis.CatchesAll(branch) {
+				return
+			}
+			for _, pattern := range branch.Patterns {
+				dot, ok := pattern.(*ast.DotExpression)
+				if !ok {
+					return
+				}
+				ident, ok := dot.Left.(*ast.Identifier)
+				if !ok || (name != "" && ident.Value != name) {
+					return
+				}
+				name = ident.Value
+				if !branch.IsGuarded {
+					covered[dot.Property] = true
+				}
+			}
+		}
+		enum := enumNamed(name, ancestors)
+		if name == "" || enum == nil {
+			return
+		}
+
+		values := analysis.EnumValues(enum)
+		elements := make(map[string]bool)
+		coveredValues := make(map[int64]bool)
+		for _, value := range values {
+			elements[value.Element.Name] = true
+			if covered[value.Element.Name] && value.Known {
+				coveredValues[value.Value] = true
+			}
+		}
+		for property := range covered {
+			// A pattern naming no value of the enum is not this rule's business
+			if !elements[property] {
+				return
+			}
+		}
+
+		// A value is handled through any of the elements it is the value of
+		var missing []string
+		for _, value := range values {
+			if !covered[value.Element.Name] && !(value.Known && coveredValues[value.Value]) {
+				missing = append(missing, value.Element.Name)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, problem.NewWarning(
+				match.Position(),
+				fmt.Sprintf("Match over enum \"%s\" does not handle %s", name, strings.Join(missing, ", ")),
+				r.Name(),
+			))
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// enumNamed returns the enum called name declared in the classes enclosing
+// a node with ancestors, the innermost class first, or nil when the name is
+// not an enum there
+func enumNamed(name string, ancestors ast.NodeStack) *ast.EnumStatement {
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		class, ok := ancestors[i].(*ast.Class)
+		if !ok {
+			continue
+		}
+		if member, ok := analysis.ClassMembers(class)[name]; ok {
+			enum, _ := member.(*ast.EnumStatement)
+			return enum
+		}
+	}
+	return nil
+}
+
+// GetDefaultMatchRules returns the rules checking the branches of match
+// statements. They have no counterpart in Python gdlint and are not enabled
+// by default.
+func GetDefaultMatchRules() []linter.Rule {
+	return []linter.Rule{
+		&DuplicateMatchPattern{},
+		&UnreachableMatchBranch{},
+		&IncompleteEnumMatch{},
+	}
+}
@@ -37,12 +37,19 @@ func (r *NameCheckRule) Description() string {
 	return r.description
 }
 
+// Settings lists the settings of the rule
+func (r *NameCheckRule) Settings() []linter.Setting {
+	return []linter.Setting{
+		{Name: "pattern", Default: r.pattern, Description: "Regular expression the names must match"},
+	}
+}
+
 // Check applies the rule to an AST and returns any problems found
 func (r *NameCheckRule) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
 	var problems []problem.Problem
 
 	// Get the pattern from config if available, otherwise use default
-	pattern := config.GetRuleSetting(r.name, "pattern", r.pattern).(string)
+	pattern := config.Setting(r, "pattern").(string)
 	compiled, err := regexp.Compile("^" + pattern + "$")
 	if err != nil {
 		compiled = r.compiled // fallback to default
@@ -54,7 +61,7 @@ func (r *NameCheckRule) Check(tree *ast.AbstractSyntaxTree, config linter.Config
 		pattern:  compiled,
 	}
 
-	ast.Walk(visitor, tree)
+	visitor.typedVisitor().Walk(tree)
 	return problems
 }
 
@@ -64,53 +71,86 @@ type nameCheckVisitor struct {
 	pattern  *regexp.Regexp
 }
 
-func (v *nameCheckVisitor) Visit(node ast.Node) ast.Visitor {
-	switch n := node.(type) {
-	case *ast.Function:
-		if v.ruleName == "function-name" {
-			v.checkName(n.Name, n.Position(), "Function")
-		}
-		// Check function arguments
-		if v.ruleName == "function-argument-name" {
-			for _, param := range n.Parameters {
-				v.checkName(param.Name, param.Pos, "Function argument")
-			}
+func (v *nameCheckVisitor) typedVisitor() *ast.TypedVisitor {
+	return &ast.TypedVisitor{
+		VisitFunction: v.visitFunction,
+		VisitClass:    v.visitClass,
+		VisitFor:      v.visitFor,
+		VisitVar:      v.visitVar,
+		VisitEnum:     v.visitEnum,
+	}
+}
+
+func (v *nameCheckVisitor) visitFunction(n *ast.Function, ancestors ast.NodeStack) {
+	if v.ruleName == "function-name" {
+		v.checkName(n.Name, n.Position(), "Function")
+	}
+	// Check function arguments
+	if v.ruleName == "function-argument-name" {
+		for _, param := range n.Parameters {
+			v.checkName(param.Name, param.Pos, "Function argument")
 		}
-	case *ast.Class:
-		if v.ruleName == "sub-class-name" && n.Name != "" {
-			v.checkName(n.Name, n.Position(), "Class")
+	}
+}
+
+func (v *nameCheckVisitor) visitClass(n *ast.Class, ancestors ast.NodeStack) {
+	// The script itself is the outermost class; only inner classes are sub-classes
+	if v.ruleName == "sub-class-name" && ancestors.EnclosingClass() != nil {
+		v.checkName(n.Name, n.Position(), "Class")
+	}
+}
+
+func (v *nameCheckVisitor) visitFor(n *ast.ForStatement, ancestors ast.NodeStack) {
+	if v.ruleName == "loop-variable-name" {
+		v.checkName(n.Iterator, n.IteratorPos, "Loop variable")
+	}
+}
+
+func (v *nameCheckVisitor) visitVar(n *ast.VarStatement, ancestors ast.NodeStack) {
+	inFunction := !n.IsClassLevel
+
+	if n.IsConst {
+		if v.ruleName == "constant-name" && !v.hasLoadCall(n) {
+			v.checkName(n.Name, n.Position(), "Constant")
 		}
-	case *ast.VarStatement:
-		if v.ruleName == "function-variable-name" {
-			// Check if this is in a function scope
-			if v.isInFunctionScope(n) && !v.hasLoadCall(n) {
-				v.checkName(n.Name, n.Position(), "Function variable")
-			}
+		if v.ruleName == "load-constant-name" && v.hasLoadCall(n) {
+			v.checkName(n.Name, n.Position(), "Load constant")
 		}
-		if v.ruleName == "function-preload-variable-name" {
-			if v.isInFunctionScope(n) && v.hasPreloadCall(n) {
-				v.checkName(n.Name, n.Position(), "Function preload variable")
-			}
+		return
+	}
+
+	if v.ruleName == "function-variable-name" {
+		if inFunction && !v.hasLoadCall(n) {
+			v.checkName(n.Name, n.Position(), "Function variable")
 		}
-		if v.ruleName == "class-variable-name" {
-			if !v.isInFunctionScope(n) && !v.hasLoadCall(n) {
-				v.checkName(n.Name, n.Position(), "Class variable")
-			}
+	}
+	if v.ruleName == "function-preload-variable-name" {
+		if inFunction && v.hasPreloadCall(n) {
+			v.checkName(n.Name, n.Position(), "Function preload variable")
 		}
-		if v.ruleName == "class-load-variable-name" {
-			if !v.isInFunctionScope(n) && v.hasLoadCall(n) {
-				v.checkName(n.Name, n.Position(), "Class load variable")
-			}
+	}
+	if v.ruleName == "class-variable-name" {
+		if !inFunction && !v.hasLoadCall(n) {
+			v.checkName(n.Name, n.Position(), "Class variable")
 		}
-		// Check constants (VarStatement with IsConst = true)
-		if v.ruleName == "constant-name" && n.IsConst && !v.hasLoadCall(n) {
-			v.checkName(n.Name, n.Position(), "Constant")
+	}
+	if v.ruleName == "class-load-variable-name" {
+		if !inFunction && v.hasLoadCall(n) {
+			v.checkName(n.Name, n.Position(), "Class load variable")
 		}
-		if v.ruleName == "load-constant-name" && n.IsConst && v.hasLoadCall(n) {
-			v.checkName(n.Name, n.Position(), "Load constant")
+	}
+}
+
+func (v *nameCheckVisitor) visitEnum(n *ast.EnumStatement, ancestors ast.NodeStack) {
+	// The name of an unnamed enum is empty, and checkName skips it
+	if v.ruleName == "enum-name" {
+		v.checkName(n.Name, n.Position(), "Enum")
+	}
+	if v.ruleName == "enum-element-name" {
+		for _, element := range n.Elements {
+			v.checkName(element.Name, element.Pos, "Enum element")
 		}
 	}
-	return v
 }
 
 func (v *nameCheckVisitor) checkName(name string, pos ast.Position, context string) {
@@ -123,12 +163,6 @@ func (v *nameCheckVisitor) checkName(name string, pos ast.Position, context stri
 	}
 }
 
-func (v *nameCheckVisitor) isInFunctionScope(node ast.Node) bool {
-	// TODO: This is a simplified check. In a complete implementation,
-	// we would need to track the scope properly during AST traversal
-	return false // For now, assume global scope
-}
-
 func (v *nameCheckVisitor) hasLoadCall(node ast.Node) bool {
 	switch n := node.(type) {
 	case *ast.VarStatement:
@@ -0,0 +1,153 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/project"
+)
+
+// UnknownClass checks for type names that no class of the engine, of the
+// project or of the script declares
+type UnknownClass struct{}
+
+// Name returns the name of the rule
+func (r *UnknownClass) Name() string {
+	return "unknown-class"
+}
+
+// Description returns a description of the rule
+func (r *UnknownClass) Description() string {
+	return "Checks for types in extends clauses, type hints, is and as that are neither engine classes, class_name classes of the project, nor inner classes, enums or constants of the script (project mode, with a godot_api dump)"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *UnknownClass) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Check finds nothing: the rule needs the other scripts of the project
+func (r *UnknownClass) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return nil
+}
+
+// CheckProject applies the rule to a script of a project
+func (r *UnknownClass) CheckProject(p *project.Project, script *project.Script, config linter.Config) []problem.Problem {
+	// Without the full engine API, any unknown name might be an engine class
+	if !config.HasCompleteAPI() {
+		return nil
+	}
+	api := config.API()
+
+	var problems []problem.Problem
+	for _, ref := range script.Types {
+		name := strings.SplitN(ref.Name, ".", 2)[0]
+		if name == "void" || name == "Variant" || analysis.IsBuiltinType(name) || api.HasClass(name) ||
+			api.HasGlobalEnum(name) || p.Class(name) != nil || p.Declares(script, name) {
+			continue
+		}
+		problems = append(problems, problem.NewError(
+			ref.Pos,
+			fmt.Sprintf("Unknown class '%s'", name),
+			r.Name(),
+		))
+	}
+	return problems
+}
+
+// CyclicPreload checks for scripts that preload, or extend, scripts which
+// load them back
+type CyclicPreload struct{}
+
+// Name returns the name of the rule
+func (r *CyclicPreload) Name() string {
+	return "cyclic-preload"
+}
+
+// Description returns a description of the rule
+func (r *CyclicPreload) Description() string {
+	return "Checks for preloads, base scripts and member initializers loading scripts that load the script back, which Godot fails to load (project mode)"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *CyclicPreload) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Check finds nothing: the rule needs the other scripts of the project
+func (r *CyclicPreload) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return nil
+}
+
+// CheckProject applies the rule to a script of a project. Each load of the
+// script leading back to it is reported with the whole cycle, so that
+// whichever script of the cycle is fixed, its problem is found.
+func (r *CyclicPreload) CheckProject(p *project.Project, script *project.Script, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	for _, dep := range p.Preloads(script) {
+		cycle := []project.Link{{From: script, Dependency: dep}}
+		if target := p.Dependency(dep); target != script {
+			back := p.PreloadPath(target, script)
+			if back == nil {
+				continue
+			}
+			cycle = append(cycle, back...)
+		}
+
+		steps := make([]string, len(cycle))
+		for i, link := range cycle {
+			steps[i] = link.String()
+		}
+		problems = append(problems, problem.NewError(
+			dep.Pos,
+			"Load cycle: "+strings.Join(steps, " -> "),
+			r.Name(),
+		))
+	}
+	return problems
+}
+
+// UnusedClass checks for class_name classes that nothing in the project uses
+type UnusedClass struct{}
+
+// Name returns the name of the rule
+func (r *UnusedClass) Name() string {
+	return "unused-class"
+}
+
+// Description returns a description of the rule
+func (r *UnusedClass) Description() string {
+	return "Checks for classes registered with class_name that no other script names or loads, and no scene or resource uses (project mode)"
+}
+
+// Check finds nothing: the rule needs the other scripts of the project
+func (r *UnusedClass) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return nil
+}
+
+// CheckProject applies the rule to a script of a project
+func (r *UnusedClass) CheckProject(p *project.Project, script *project.Script, config linter.Config) []problem.Problem {
+	if script.ClassName == "" || p.IsUsed(script) {
+		return nil
+	}
+	return []problem.Problem{problem.NewWarning(
+		script.Tree.RootClass.ClassNamePos,
+		fmt.Sprintf("Class '%s' is not used anywhere in the project", script.ClassName),
+		r.Name(),
+	)}
+}
+
+// GetDefaultProjectRules returns the rules checking scripts against the rest
+// of their project. They only run in project mode, where they are enabled
+// by default.
+func GetDefaultProjectRules() []linter.Rule {
+	return []linter.Rule{
+		&UnknownClass{},
+		&CyclicPreload{},
+		&UnusedClass{},
+	}
+}
@@ -4,22 +4,98 @@ import (
 	"github.com/dzannotti/gdtoolkit/internal/core/linter"
 )
 
-// GetDefaultRules returns the default set of linting rules
-func GetDefaultRules() []linter.Rule {
-	var rules []linter.Rule
+// ruleCategories lists the rules of each category in the order they run.
+// The rules ported from Python gdlint are enabled by default, as they are
+// there. Of the others, only the rules of the categories marked enabled are:
+// the call rules, which report nothing until configured, and the project
+// rules, which only run in project mode.
+var ruleCategories = []struct {
+	name    string
+	rules   func() []linter.Rule
+	enabled bool
+}{
+	{"basic", GetDefaultBasicRules, true},
+	{"class", GetDefaultClassRules, true},
+	{"call", GetDefaultCallRules, true},
+	{"project", GetDefaultProjectRules, true},
+	{"name", GetDefaultNameRules, false},
+	{"design", GetDefaultDesignRules, false},
+	{"format", GetDefaultFormatRules, false},
+	{"if-return", GetDefaultIfReturnRules, false},
+	{"scope", GetDefaultScopeRules, false},
+	{"virtual", GetDefaultVirtualRules, false},
+	{"flow", GetDefaultFlowRules, false},
+	{"constant", GetDefaultConstantRules, false},
+	{"match", GetDefaultMatchRules, false},
+	{"enum", GetDefaultEnumRules, false},
+	{"signal", GetDefaultSignalRules, false},
+	{"annotation", GetDefaultAnnotationRules, false},
+	{"string", GetDefaultStringRules, false},
+	{"load", GetDefaultLoadRules, false},
+	{"scene", GetDefaultSceneRules, false},
+	{"debug", GetDefaultDebugRules, false},
+	{"suppression", GetDefaultSuppressionRules, false},
+}
 
-	// Basic checks
-	rules = append(rules, GetDefaultBasicRules()...)
+// pythonRules lists the rules ported from Python gdlint
+var pythonRules = map[string]bool{
+	"expression-not-assigned":        true,
+	"unnecessary-pass":               true,
+	"duplicated-load":                true,
+	"unused-argument":                true,
+	"comparison-with-itself":         true,
+	"class-definitions-order":        true,
+	"sub-class-before-parent-class":  true,
+	"function-name":                  true,
+	"sub-class-name":                 true,
+	"class-name":                     true,
+	"signal-name":                    true,
+	"enum-name":                      true,
+	"enum-element-name":              true,
+	"loop-variable-name":             true,
+	"function-argument-name":         true,
+	"function-variable-name":         true,
+	"function-preload-variable-name": true,
+	"constant-name":                  true,
+	"load-constant-name":             true,
+	"class-variable-name":            true,
+	"class-load-variable-name":       true,
+	"max-public-methods":             true,
+	"max-returns":                    true,
+	"function-arguments-number":      true,
+	"max-line-length":                true,
+	"max-file-lines":                 true,
+	"trailing-whitespace":            true,
+	"mixed-tabs-and-spaces":          true,
+	"no-elif-return":                 true,
+	"no-else-return":                 true,
+}
 
-	// Class checks
-	rules = append(rules, GetDefaultClassRules()...)
+// PortedFromPython reports whether the rule named name is ported from
+// Python gdlint, and so checks what the rule of the same name does there
+func PortedFromPython(name string) bool {
+	return pythonRules[name]
+}
 
-	// TODO: Enable these once basic rules are working correctly
-	// rules = append(rules, GetDefaultNameRules()...)
-	// rules = append(rules, GetDefaultDesignRules()...)
-	// rules = append(rules, GetDefaultFormatRules()...)
-	// rules = append(rules, GetDefaultIfReturnRules()...)
+// NewRegistry returns a registry of every built-in rule
+func NewRegistry() *linter.Registry {
+	registry := linter.NewRegistry()
+	for _, category := range ruleCategories {
+		for _, rule := range category.rules() {
+			registry.Register(rule, category.name, category.enabled || pythonRules[rule.Name()])
+		}
+	}
+	return registry
+}
 
+// GetDefaultRules returns the default set of linting rules
+func GetDefaultRules() []linter.Rule {
+	var rules []linter.Rule
+	for _, info := range NewRegistry().Rules() {
+		if info.Default {
+			rules = append(rules, info.Rule)
+		}
+	}
 	return rules
 }
 
@@ -42,18 +118,27 @@ func GetDefaultClassRules() []linter.Rule {
 	}
 }
 
-// GetAllRules returns all available linting rules
+// GetDefaultSuppressionRules returns the rules checking gdlint directives.
+// They have no counterpart in Python gdlint and are not enabled by default.
+func GetDefaultSuppressionRules() []linter.Rule {
+	return []linter.Rule{
+		&linter.UnusedSuppression{},
+	}
+}
+
+// GetAllRules returns all available linting rules, including those not yet enabled by default
 func GetAllRules() []linter.Rule {
-	return GetDefaultRules()
+	var rules []linter.Rule
+	for _, info := range NewRegistry().Rules() {
+		rules = append(rules, info.Rule)
+	}
+	return rules
 }
 
 // GetRuleByName returns a rule by its name
 func GetRuleByName(name string) linter.Rule {
-	rules := GetAllRules()
-	for _, rule := range rules {
-		if rule.Name() == name {
-			return rule
-		}
+	if info, ok := NewRegistry().Lookup(name); ok {
+		return info.Rule
 	}
 	return nil
 }
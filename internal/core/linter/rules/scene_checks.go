@@ -0,0 +1,84 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/project"
+)
+
+// UnknownNodePath checks for node paths that no scene using the script has
+type UnknownNodePath struct{}
+
+// Name returns the name of the rule
+func (r *UnknownNodePath) Name() string {
+	return "unknown-node-path"
+}
+
+// Description returns a description of the rule
+func (r *UnknownNodePath) Description() string {
+	return "Checks for $Path, %Name and get_node() paths that lead to no node in any of the scenes the script is attached to (project mode)"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *UnknownNodePath) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Check finds nothing: the rule needs the scenes of the project
+func (r *UnknownNodePath) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return nil
+}
+
+// CheckProject applies the rule to a script of a project. Nodes added at
+// run time are unknown to the scenes, so a path is only reported when it
+// leads nowhere in every scene, and never when it leaves the scene.
+func (r *UnknownNodePath) CheckProject(p *project.Project, script *project.Script, config linter.Config) []problem.Problem {
+	if script.Tree == nil {
+		return nil
+	}
+	nodes := p.NodesOf(script)
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	var problems []problem.Problem
+	for _, use := range analysis.FindNodePaths(script.Tree) {
+		// Inner classes are not attached to the nodes of the script
+		if use.Class != script.Tree.RootClass {
+			continue
+		}
+
+		var missing []string
+		for _, node := range nodes {
+			if _, status := p.FindNode(node, use.Path); status != project.NodeMissing {
+				missing = nil
+				break
+			}
+			missing = append(missing, fmt.Sprintf("%s (%s)", node.Scene.ResPath, node.Path()))
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		problems = append(problems, problem.NewError(
+			use.Expr.Position(),
+			fmt.Sprintf("Node path '%s' leads to no node in %s", use.Path, strings.Join(missing, ", ")),
+			r.Name(),
+		))
+	}
+	return problems
+}
+
+// GetDefaultSceneRules returns the rules checking scripts against the scenes
+// they are attached to. Like the project rules they only run in project
+// mode; they have no counterpart in Python gdlint and are not enabled by
+// default, as nodes added at run time are unknown to them.
+func GetDefaultSceneRules() []linter.Rule {
+	return []linter.Rule{
+		&UnknownNodePath{},
+	}
+}
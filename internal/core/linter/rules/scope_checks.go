@@ -0,0 +1,410 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/godotapi"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/project"
+)
+
+// UnusedVariable checks for function variables and private class variables that are never used
+type UnusedVariable struct{}
+
+// Name returns the name of the rule
+func (r *UnusedVariable) Name() string {
+	return "unused-variable"
+}
+
+// Description returns a description of the rule
+func (r *UnusedVariable) Description() string {
+	return "Checks for local and private class variables that are never used"
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *UnusedVariable) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UnusedVariable) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *UnusedVariable) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	visitor := &unusedVariableVisitor{
+		results:  results,
+		problems: &problems,
+	}
+	(&ast.TypedVisitor{VisitClass: visitor.visitClass}).Walk(results.Tree)
+
+	return problems
+}
+
+// unusedVariableVisitor reports declarations no reference resolves to
+type unusedVariableVisitor struct {
+	results  *analysis.Results
+	problems *[]problem.Problem
+}
+
+func (v *unusedVariableVisitor) visitClass(class *ast.Class, ancestors ast.NodeStack) {
+	// self.foo and bare foo resolve to the same declaration, so either counts as a use
+	used := make(map[ast.Node]bool)
+	for _, ref := range v.results.References(class) {
+		if ref.Decl != nil {
+			used[ref.Decl] = true
+		}
+	}
+
+	for _, stmt := range class.Statements {
+		if member, ok := stmt.(*ast.VarStatement); ok && !member.IsConst {
+			// Public members may be used from other scripts
+			if strings.HasPrefix(member.Name, "_") && !used[member] {
+				v.report(member, "Unused private class variable '"+member.Name+"'")
+			}
+		}
+	}
+
+	for _, function := range class.Functions {
+		for _, stmt := range function.Statements {
+			ast.Inspect(stmt, func(node ast.Node) bool {
+				local, ok := node.(*ast.VarStatement)
+				if ok && !strings.HasPrefix(local.Name, "_") && !used[local] {
+					v.report(local, "Unused variable '"+local.Name+"'")
+				}
+				return true
+			})
+		}
+	}
+}
+
+func (v *unusedVariableVisitor) report(stmt *ast.VarStatement, message string) {
+	*v.problems = append(*v.problems, problem.NewWarning(
+		stmt.Position(),
+		message,
+		"unused-variable",
+	))
+}
+
+// UndefinedIdentifier checks for names that are not declared in any visible
+// scope. In project mode, with a godot_api dump, it also checks capitalized
+// names, which otherwise might be any engine, autoload or global class.
+type UndefinedIdentifier struct{}
+
+// Name returns the name of the rule
+func (r *UndefinedIdentifier) Name() string {
+	return "undefined-identifier"
+}
+
+// Description returns a description of the rule
+func (r *UndefinedIdentifier) Description() string {
+	return "Checks for references to names that are not declared, including, in project mode with a godot_api dump, class names that are neither built-in types, engine classes, global classes or autoloads of the project, nor classes, enums or constants of the script"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *UndefinedIdentifier) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *UndefinedIdentifier) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UndefinedIdentifier) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *UndefinedIdentifier) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	visitor := &undefinedIdentifierVisitor{
+		results:  results,
+		problems: &problems,
+	}
+	(&ast.TypedVisitor{VisitClass: visitor.visitClass}).Walk(results.Tree)
+
+	return problems
+}
+
+// CheckProject applies the rule to a script of a project
+func (r *UndefinedIdentifier) CheckProject(p *project.Project, script *project.Script, config linter.Config) []problem.Problem {
+	if script.Tree == nil {
+		return nil
+	}
+	results := analysis.NewResults(script.Tree)
+	problems := r.CheckResults(results, config)
+	// Without the full engine API, any capitalized name might be an engine class
+	if !config.HasCompleteAPI() {
+		return problems
+	}
+	api := config.API()
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		for _, ref := range results.References(class) {
+			if ref.Kind != analysis.SymbolGlobal || !isClassName(ref.Name) {
+				continue
+			}
+			if analysis.IsBuiltinType(ref.Name) || api.HasClass(ref.Name) || api.HasGlobalEnum(ref.Name) ||
+				p.IsGlobal(ref.Name) || p.Declares(script, ref.Name) {
+				continue
+			}
+			problems = append(problems, problem.NewError(
+				ref.Pos,
+				fmt.Sprintf("Identifier '%s' is not a class, autoload or constant of the project", ref.Name),
+				r.Name(),
+			))
+		}
+	}}).Walk(script.Tree)
+
+	return problems
+}
+
+// isClassName reports whether name is spelled like a class: capitalized,
+// with lowercase letters, unlike global constants such as PI or KEY_A
+func isClassName(name string) bool {
+	return name != "" && unicode.IsUpper([]rune(name)[0]) && strings.ToUpper(name) != name
+}
+
+// undefinedIdentifierVisitor reports references that resolve to nothing
+type undefinedIdentifierVisitor struct {
+	results  *analysis.Results
+	problems *[]problem.Problem
+}
+
+func (v *undefinedIdentifierVisitor) visitClass(class *ast.Class, ancestors ast.NodeStack) {
+	// Members inherited from an explicit base class are unknown, so any
+	// unresolved name might be one of them
+	if class.Extends != "" {
+		return
+	}
+
+	for _, ref := range v.results.References(class) {
+		if ref.Kind != analysis.SymbolUnresolved {
+			continue
+		}
+
+		message := fmt.Sprintf("Identifier '%s' is not declared in the current scope", ref.Name)
+		if ref.Explicit {
+			message = fmt.Sprintf("Member '%s' is not declared in the current class", ref.Name)
+		}
+		*v.problems = append(*v.problems, problem.NewError(
+			ref.Pos,
+			message,
+			"undefined-identifier",
+		))
+	}
+}
+
+// DuplicateDefinition checks for members declared twice in the same class
+type DuplicateDefinition struct{}
+
+// Name returns the name of the rule
+func (r *DuplicateDefinition) Name() string {
+	return "duplicate-definition"
+}
+
+// Description returns a description of the rule
+func (r *DuplicateDefinition) Description() string {
+	return "Checks for functions, variables, constants, signals, enums and classes declared twice in the same class"
+}
+
+// DefaultSeverity returns error: Godot refuses to load such a script
+func (r *DuplicateDefinition) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *DuplicateDefinition) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		first := make(map[string]ast.Node)
+		for _, decl := range analysis.ClassDeclarations(class) {
+			original, ok := first[decl.Name]
+			if !ok {
+				first[decl.Name] = decl.Node
+				continue
+			}
+			problems = append(problems, problem.NewError(
+				decl.Node.Position(),
+				fmt.Sprintf("\"%s\" is already declared on line %d", decl.Name, original.Position().Line),
+				"duplicate-definition",
+			))
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// ShadowedVariable checks for parameters and local variables hiding another declaration
+type ShadowedVariable struct{}
+
+// Name returns the name of the rule
+func (r *ShadowedVariable) Name() string {
+	return "shadowed-variable"
+}
+
+// Description returns a description of the rule
+func (r *ShadowedVariable) Description() string {
+	return "Checks for parameters and local variables that shadow a local of an enclosing block, " +
+		"a class member or a property of the engine base class"
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *ShadowedVariable) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *ShadowedVariable) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *ShadowedVariable) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		v := &shadowVisitor{
+			members:  results.Members(class),
+			base:     baseClass(config.API(), class),
+			api:      config.API(),
+			problems: &problems,
+		}
+		for _, function := range class.Functions {
+			v.scopes = []map[string]ast.Node{{}}
+			for _, param := range function.Parameters {
+				v.declare(param.Name, param, param.Pos)
+			}
+			v.block(function.Statements)
+		}
+	}}).Walk(results.Tree)
+
+	return problems
+}
+
+// shadowVisitor walks the blocks of a function, keeping a scope per block
+type shadowVisitor struct {
+	members  map[string]ast.Node
+	base     string // engine class the class extends; empty when unknown
+	api      *godotapi.API
+	scopes   []map[string]ast.Node // innermost last; the first holds the parameters
+	problems *[]problem.Problem
+}
+
+func (v *shadowVisitor) block(statements []ast.Statement) {
+	v.scopes = append(v.scopes, map[string]ast.Node{})
+	for _, stmt := range statements {
+		v.statement(stmt)
+	}
+	v.scopes = v.scopes[:len(v.scopes)-1]
+}
+
+func (v *shadowVisitor) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VarStatement:
+		v.declare(s.Name, s, s.Position())
+	case *ast.ForStatement:
+		// The iterator belongs to the scope of the body
+		v.scopes = append(v.scopes, map[string]ast.Node{})
+		v.declare(s.Iterator, s, s.IteratorPos)
+		v.block(s.Body)
+		v.scopes = v.scopes[:len(v.scopes)-1]
+	case *ast.MatchStatement:
+		for _, branch := range s.Branches {
+			v.scopes = append(v.scopes, map[string]ast.Node{})
+			for _, pattern := range branch.Patterns {
+				ast.Inspect(pattern, func(node ast.Node) bool {
+					if binding, ok := node.(*ast.BindingPattern); ok {
+						v.declare(binding.Name, binding, binding.Position())
+					}
+					return true
+				})
+			}
+			v.block(branch.Body)
+			v.scopes = v.scopes[:len(v.scopes)-1]
+		}
+	default:
+		for _, block := range nestedBlocks(stmt) {
+			v.block(block)
+		}
+	}
+}
+
+// declare adds name to the innermost scope, reporting what it shadows
+func (v *shadowVisitor) declare(name string, decl ast.Node, pos ast.Position) {
+	what := "Local variable"
+	if _, ok := decl.(*ast.Parameter); ok {
+		what = "Parameter"
+	}
+	report := func(shadowed string) {
+		*v.problems = append(*v.problems, problem.NewWarning(
+			pos,
+			fmt.Sprintf("%s '%s' at %s shadows %s", what, name, pos, shadowed),
+			"shadowed-variable",
+		))
+	}
+
+	for i := len(v.scopes) - 1; i >= 0; i-- {
+		if outer, ok := v.scopes[i][name]; ok {
+			kind := "the local variable"
+			if _, ok := outer.(*ast.Parameter); ok {
+				kind = "the parameter"
+			}
+			report(fmt.Sprintf("%s declared at %s", kind, outer.Position()))
+			v.scopes[len(v.scopes)-1][name] = decl
+			return
+		}
+	}
+
+	if member, ok := v.members[name]; ok {
+		report(fmt.Sprintf("the %s declared at %s", memberKind(member), member.Position()))
+	} else if v.base != "" && v.api.Property(v.base, name) != nil {
+		report(fmt.Sprintf("the property '%s' of %s", name, v.base))
+	}
+	v.scopes[len(v.scopes)-1][name] = decl
+}
+
+// memberKind describes the declaration of a class member
+func memberKind(member ast.Node) string {
+	switch m := member.(type) {
+	case *ast.VarStatement:
+		if m.IsConst {
+			return "constant"
+		}
+		return "member variable"
+	case *ast.Function:
+		return "function"
+	case *ast.SignalStatement:
+		return "signal"
+	case *ast.EnumStatement:
+		return "enum"
+	case *ast.EnumElement:
+		return "enum value"
+	case *ast.Class:
+		return "inner class"
+	}
+	return "member"
+}
+
+// GetDefaultScopeRules returns the scope analysis rules. They have no
+// counterpart in Python gdlint and are not enabled by default.
+func GetDefaultScopeRules() []linter.Rule {
+	return []linter.Rule{
+		&UnusedVariable{},
+		&UndefinedIdentifier{},
+		&DuplicateDefinition{},
+		&ShadowedVariable{},
+	}
+}
@@ -0,0 +1,184 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// signalNameMethods are the methods of Object taking the name of a signal of
+// the object as their first argument
+var signalNameMethods = map[string]bool{
+	"emit_signal": true, "connect": true, "disconnect": true, "is_connected": true, "has_signal": true,
+}
+
+// signalNameArgument returns the string literal naming a signal of self in
+// call, such as "hit" in emit_signal("hit") or self.connect("hit", f)
+func signalNameArgument(call *ast.CallExpression) (*ast.StringLiteral, bool) {
+	var method string
+	switch callee := call.Function.(type) {
+	case *ast.Identifier:
+		method = callee.Value
+	case *ast.DotExpression:
+		if _, ok := callee.Left.(*ast.SelfExpression); !ok {
+			return nil, false
+		}
+		method = callee.Property
+	}
+	if !signalNameMethods[method] || len(call.Arguments) == 0 {
+		return nil, false
+	}
+	name, ok := call.Arguments[0].(*ast.StringLiteral)
+	return name, ok
+}
+
+// signalNames calls f for every signal named by a string in the functions
+// and initializers of class, excluding inner classes
+func signalNames(class *ast.Class, f func(name *ast.StringLiteral)) {
+	var nodes []ast.Node
+	for _, stmt := range class.Statements {
+		nodes = append(nodes, stmt)
+	}
+	for _, function := range class.Functions {
+		nodes = append(nodes, function)
+	}
+	for _, node := range nodes {
+		ast.Inspect(node, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpression); ok {
+				if name, ok := signalNameArgument(call); ok {
+					f(name)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// UnusedSignal checks for signals that the script declares but never uses
+type UnusedSignal struct{}
+
+// Name returns the name of the rule
+func (r *UnusedSignal) Name() string {
+	return "unused-signal"
+}
+
+// Description returns a description of the rule
+func (r *UnusedSignal) Description() string {
+	return "Checks for signals that are never emitted, connected or otherwise used in the script declaring them"
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *UnusedSignal) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UnusedSignal) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *UnusedSignal) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		// hit.emit() and self.hit.connect(f) resolve to a declaration, while
+		// emit_signal("hit") names it with a string. A use counts for every
+		// declaration of the name, as a signal declared twice is one signal.
+		used := make(map[string]bool)
+		for _, ref := range results.References(class) {
+			if signal, ok := ref.Decl.(*ast.SignalStatement); ok {
+				used[signal.Name] = true
+			}
+		}
+		members := results.Members(class)
+		signalNames(class, func(name *ast.StringLiteral) {
+			if signal, ok := members[name.Value].(*ast.SignalStatement); ok {
+				used[signal.Name] = true
+			}
+		})
+
+		for _, stmt := range class.Statements {
+			if signal, ok := stmt.(*ast.SignalStatement); ok && !used[signal.Name] {
+				problems = append(problems, problem.NewWarning(
+					signal.Position(),
+					fmt.Sprintf("Signal '%s' is never emitted or connected", signal.Name),
+					"unused-signal",
+				))
+			}
+		}
+	}}).Walk(results.Tree)
+
+	return problems
+}
+
+// UndefinedSignal checks for strings naming a signal the class does not have
+type UndefinedSignal struct{}
+
+// Name returns the name of the rule
+func (r *UndefinedSignal) Name() string {
+	return "undefined-signal"
+}
+
+// Description returns a description of the rule
+func (r *UndefinedSignal) Description() string {
+	return "Checks for emit_signal and connect calls on self naming a signal that is not declared"
+}
+
+// DefaultSeverity returns error: the call fails at runtime
+func (r *UndefinedSignal) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *UndefinedSignal) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *UndefinedSignal) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *UndefinedSignal) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	api := config.API()
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		// Signals inherited from a script or an unknown class cannot be checked
+		base := baseClass(api, class)
+		if base == "" {
+			return
+		}
+		members := results.Members(class)
+		signalNames(class, func(name *ast.StringLiteral) {
+			if _, ok := members[name.Value].(*ast.SignalStatement); ok {
+				return
+			}
+			if api.Signal(base, name.Value) != nil {
+				return
+			}
+			problems = append(problems, problem.NewError(
+				name.Position(),
+				fmt.Sprintf("Signal '%s' is not declared in the class or in %s", name.Value, base),
+				"undefined-signal",
+			))
+		})
+	}}).Walk(results.Tree)
+
+	return problems
+}
+
+// GetDefaultSignalRules returns the rules checking the declaration and use of
+// signals. They have no counterpart in Python gdlint and are not enabled by
+// default.
+func GetDefaultSignalRules() []linter.Rule {
+	return []linter.Rule{
+		&UnusedSignal{},
+		&UndefinedSignal{},
+	}
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+)
+
+// FormatArgumentCount checks that a literal format string used with the %
+// operator gets as many values as it has placeholders
+type FormatArgumentCount struct{}
+
+// Name returns the name of the rule
+func (r *FormatArgumentCount) Name() string {
+	return "format-argument-count"
+}
+
+// Description returns a description of the rule
+func (r *FormatArgumentCount) Description() string {
+	return "Checks that \"...\" % [...] gives as many values as the string has placeholders, when both are literals"
+}
+
+// DefaultSeverity returns error: the formatting fails at runtime
+func (r *FormatArgumentCount) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *FormatArgumentCount) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+
+	(&ast.TypedVisitor{VisitInfix: func(infix *ast.InfixExpression, ancestors ast.NodeStack) {
+		if infix.Operator != "%" {
+			return
+		}
+		format, ok := infix.Left.(*ast.StringLiteral)
+		if !ok {
+			return
+		}
+		placeholders, ok := formatPlaceholders(format.Value)
+		if !ok {
+			return
+		}
+
+		// A literal other than an array is the only value, while a variable
+		// may hold an array of any length
+		var values int
+		switch right := infix.Right.(type) {
+		case *ast.ArrayLiteral:
+			values = len(right.Elements)
+		case *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral, *ast.DictionaryLiteral:
+			values = 1
+		default:
+			return
+		}
+
+		if values != placeholders {
+			problems = append(problems, problem.NewError(
+				infix.Position(),
+				fmt.Sprintf("Format string has %s but is given %s",
+					countOf(placeholders, "placeholder"), countOf(values, "value")),
+				r.Name(),
+			))
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// formatPlaceholders returns the number of values a format string consumes:
+// one per placeholder such as %s, %5.2f or %-10d, plus one per * taking the
+// padding or precision from the values. It reports false for a string with
+// a placeholder it does not know, which the rule leaves alone.
+func formatPlaceholders(format string) (int, bool) {
+	count := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		// Flags, padding and precision
+		for i < len(format) && strings.IndexByte("+-0123456789.*", format[i]) >= 0 {
+			if format[i] == '*' {
+				count++
+			}
+			i++
+		}
+		if i == len(format) {
+			return 0, false
+		}
+		switch format[i] {
+		case '%':
+		case 's', 'c', 'd', 'o', 'x', 'X', 'f', 'v':
+			count++
+		default:
+			return 0, false
+		}
+	}
+	return count, true
+}
+
+// countOf formats a count of things, e.g. "1 value" or "2 values"
+func countOf(count int, thing string) string {
+	if count == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", count, thing)
+}
+
+// GetDefaultStringRules returns the rules checking string literals. They have
+// no counterpart in Python gdlint and are not enabled by default.
+func GetDefaultStringRules() []linter.Rule {
+	return []linter.Rule{
+		&FormatArgumentCount{},
+	}
+}
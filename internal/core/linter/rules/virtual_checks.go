@@ -0,0 +1,243 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/godotapi"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/textutil"
+)
+
+// MisnamedVirtual checks for functions whose name is a likely typo of an engine virtual function
+type MisnamedVirtual struct{}
+
+// Name returns the name of the rule
+func (r *MisnamedVirtual) Name() string {
+	return "misnamed-virtual"
+}
+
+// Description returns a description of the rule
+func (r *MisnamedVirtual) Description() string {
+	return "Checks for functions named like a misspelled engine virtual function"
+}
+
+// Requires lists the analysis passes the rule uses
+func (r *MisnamedVirtual) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassScopes}
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *MisnamedVirtual) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+
+// CheckResults applies the rule to an analyzed file
+func (r *MisnamedVirtual) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	api := config.API()
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		virtuals := baseVirtuals(api, class)
+
+		// A function the script uses itself is a helper, not a misspelled callback
+		used := make(map[ast.Node]bool)
+		for _, ref := range results.References(class) {
+			used[ref.Decl] = true
+		}
+
+		for _, function := range class.Functions {
+			if !strings.HasPrefix(function.Name, "_") || used[function] || isVirtualName(api, function.Name) {
+				continue
+			}
+			if virtual := closestVirtual(function.Name, virtuals); virtual != "" {
+				problems = append(problems, problem.NewWarning(
+					function.Position(),
+					fmt.Sprintf("Function '%s' looks like a misspelling of the virtual function '%s'", function.Name, virtual),
+					"misnamed-virtual",
+				))
+			}
+		}
+	}}).Walk(results.Tree)
+
+	return problems
+}
+
+// VirtualArity checks that overridden engine virtual functions take the arguments the engine passes
+type VirtualArity struct{}
+
+// Name returns the name of the rule
+func (r *VirtualArity) Name() string {
+	return "virtual-arity"
+}
+
+// Description returns a description of the rule
+func (r *VirtualArity) Description() string {
+	return "Checks that overridden virtual functions take the number of arguments the engine passes"
+}
+
+// DefaultSeverity returns the severity of the problems the rule reports
+func (r *VirtualArity) DefaultSeverity() problem.Severity {
+	return problem.Error
+}
+
+// Check applies the rule to an AST and returns any problems found
+func (r *VirtualArity) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	var problems []problem.Problem
+	api := config.API()
+
+	(&ast.TypedVisitor{VisitClass: func(class *ast.Class, ancestors ast.NodeStack) {
+		base := baseClass(api, class)
+		if base == "" {
+			return
+		}
+		virtuals := api.VirtualMethods(base)
+
+		for _, function := range class.Functions {
+			required := 0
+			for _, param := range function.Parameters {
+				if param.Default == nil {
+					required++
+				}
+			}
+
+			if function.Name == "_init" {
+				// Scenes and resources are instantiated by the engine, which passes no arguments
+				if required > 0 && (api.IsSubclassOf(base, "Node") || api.IsSubclassOf(base, "Resource")) {
+					problems = append(problems, problem.NewError(
+						function.Position(),
+						fmt.Sprintf("Function '_init' of a %s must not have required arguments, "+
+							"the engine instantiates it without arguments", base),
+						"virtual-arity",
+					))
+				}
+				continue
+			}
+
+			virtual := virtuals[function.Name]
+			if virtual == nil {
+				continue
+			}
+			passed := len(virtual.Arguments)
+			if passed < required || passed > len(function.Parameters) {
+				problems = append(problems, problem.NewError(
+					function.Position(),
+					fmt.Sprintf("Virtual function '%s' is called with %s, but is defined with %d",
+						function.Name, textutil.Pluralize(passed, "argument"), len(function.Parameters)),
+					"virtual-arity",
+				))
+			}
+		}
+	}}).Walk(tree)
+
+	return problems
+}
+
+// baseClass returns the engine class a class extends, or "" when it extends
+// a script or a class the API does not know
+func baseClass(api *godotapi.API, class *ast.Class) string {
+	if class.Extends == "" {
+		return "RefCounted"
+	}
+	if api.HasClass(class.Extends) {
+		return class.Extends
+	}
+	return ""
+}
+
+// baseVirtuals returns the names of the virtual functions a class can
+// override: those of its engine base class, or those of every class when the
+// base class is unknown
+func baseVirtuals(api *godotapi.API, class *ast.Class) []string {
+	var virtuals []string
+	if base := baseClass(api, class); base != "" {
+		for name := range api.VirtualMethods(base) {
+			virtuals = append(virtuals, name)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, name := range api.ClassNames() {
+			for _, method := range api.Class(name).Methods {
+				if method.IsVirtual && !seen[method.Name] {
+					seen[method.Name] = true
+					virtuals = append(virtuals, method.Name)
+				}
+			}
+		}
+	}
+	sort.Strings(virtuals)
+	return virtuals
+}
+
+// isVirtualName reports whether any engine class has a virtual function called name
+func isVirtualName(api *godotapi.API, name string) bool {
+	for _, class := range api.Classes {
+		for _, method := range class.Methods {
+			if method.IsVirtual && method.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// closestVirtual returns the virtual name that name most likely misspells,
+// or "" when none is close enough. Short names allow a single edit, so that
+// helpers such as _hide are not mistaken for _ready.
+func closestVirtual(name string, virtuals []string) string {
+	best, bestDistance := "", 0
+	for _, virtual := range virtuals {
+		allowed := 1
+		if len(virtual) > 6 {
+			allowed = 2
+		}
+		distance := editDistance(name, virtual)
+		if distance <= allowed && (best == "" || distance < bestDistance) {
+			best, bestDistance = virtual, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters that turn a into b
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of s and the first j runes of t
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// GetDefaultVirtualRules returns the rules checking overrides of engine
+// virtual functions. They have no counterpart in Python gdlint and are not
+// enabled by default.
+func GetDefaultVirtualRules() []linter.Rule {
+	return []linter.Rule{
+		&MisnamedVirtual{},
+		&VirtualArity{},
+	}
+}
@@ -0,0 +1,44 @@
+package linter
+
+import (
+	"errors"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/scene"
+)
+
+// LintScene lints the scripts embedded in the scene file at filePath, whose
+// content is content, and returns the problems found with positions in the
+// scene file. When scripts do not parse, the *ParseError holds the errors of
+// all of them, positioned in the scene file too.
+func (l *Linter) LintScene(filePath, content string) ([]problem.Problem, error) {
+	var problems []problem.Problem
+	var parseErrors []error
+	for _, script := range scene.Extract(content) {
+		found, err := l.LintSource(filePath, script.Source)
+		var parseErr *ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			for _, e := range parseErr.Errors {
+				if positioned, ok := e.(parser.Error); ok {
+					positioned.Line, positioned.Column, _ = script.Position(script.Offset(positioned.Line, positioned.Column), 0)
+					e = positioned
+				}
+				parseErrors = append(parseErrors, e)
+			}
+			continue
+		case err != nil:
+			return nil, err
+		}
+		for _, p := range found {
+			pos := &p.Position
+			pos.Line, pos.Column, pos.Offset = script.Position(pos.Offset, l.config.TabWidth)
+			problems = append(problems, p)
+		}
+	}
+	if len(parseErrors) > 0 {
+		return nil, &ParseError{Errors: parseErrors}
+	}
+	return problem.Sort(problems), nil
+}
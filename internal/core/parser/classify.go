@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"unicode"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// TokenKind is the semantic category of a token, as used for highlighting
+type TokenKind int
+
+const (
+	KindKeyword TokenKind = iota
+	KindType
+	KindFunction
+	KindSignal
+	KindConstant
+	KindAnnotation
+	KindVariable
+	KindString
+	KindNumber
+	KindComment
+	KindOperator
+)
+
+var tokenKindNames = [...]string{
+	KindKeyword:    "keyword",
+	KindType:       "type",
+	KindFunction:   "function",
+	KindSignal:     "signal",
+	KindConstant:   "constant",
+	KindAnnotation: "annotation",
+	KindVariable:   "variable",
+	KindString:     "string",
+	KindNumber:     "number",
+	KindComment:    "comment",
+	KindOperator:   "operator",
+}
+
+// String returns the name of the category, e.g. "keyword"
+func (k TokenKind) String() string {
+	return tokenKindNames[k]
+}
+
+// SemanticToken is a span of source code and its category. Offset and Length
+// are in bytes; Line and Column are those of the lexer.
+type SemanticToken struct {
+	Line   int
+	Column int
+	Offset int
+	Length int
+	Kind   TokenKind
+}
+
+// keywordTypes are the token types of the keywords
+var keywordTypes = func() map[TokenType]bool {
+	types := make(map[TokenType]bool)
+	for _, tokenType := range keywords {
+		types[tokenType] = true
+	}
+	return types
+}()
+
+// operatorTypes are the token types of the operators; brackets and other
+// punctuation are left out
+var operatorTypes = map[TokenType]bool{
+	ASSIGN: true, PLUS: true, MINUS: true, BANG: true, ASTERISK: true, SLASH: true, PERCENT: true, POWER: true,
+	LT: true, GT: true, EQ: true, NOT_EQ: true, LTE: true, GTE: true, AMPAMP: true, PIPEPIPE: true,
+	PLUSEQ: true, MINUSEQ: true, ASTERISKEQ: true, SLASHEQ: true, PERCENTEQ: true, AMPEQ: true, PIPEEQ: true,
+	CARETEQ: true, LTLTEQ: true, GTGTEQ: true, POWEREQ: true, BITAND: true, BITOR: true, BITXOR: true,
+	BITNOT: true, LTLT: true, GTGT: true, ARROW: true, COLONASSIGN: true,
+}
+
+// builtinTypeNames are the types spelled in lower case
+var builtinTypeNames = map[string]bool{"bool": true, "int": true, "float": true, "void": true}
+
+// Classify splits src into semantic tokens for syntax highlighting. Names are
+// classified by where they appear, such as after func or in a type hint, by
+// what the script declares with them, and otherwise by convention:
+// PascalCase names are types and SCREAMING_CASE names constants. Brackets and
+// punctuation are left out; code that does not parse is still classified.
+func Classify(src string) []SemanticToken {
+	tree, _ := ParseFile("", src)
+	declared := declaredNames(tree)
+
+	var tokens []Token
+	lexer := NewLexer(src)
+	for {
+		tok := lexer.NextToken()
+		if tok.Type == EOF {
+			break
+		}
+		switch tok.Type {
+		case NL, INDENT, DEDENT:
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+
+	var result []SemanticToken
+	add := func(tok Token, length int, kind TokenKind) {
+		result = append(result, SemanticToken{Line: tok.Line, Column: tok.Column, Offset: tok.Offset, Length: length, Kind: kind})
+	}
+
+	// typeDepth counts the brackets of the type hint being read, e.g. the
+	// [] of Array[int]; inType is set while a type hint is read
+	typeDepth := 0
+	inType := false
+	// paramDepth is the bracket depth of the parameter list of a function
+	// or signal being read, or 0
+	paramDepth, depth := 0, 0
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		prev := func(n int) TokenType {
+			if i-n < 0 {
+				return ""
+			}
+			return tokens[i-n].Type
+		}
+		next := func(n int) TokenType {
+			if i+n >= len(tokens) {
+				return ""
+			}
+			return tokens[i+n].Type
+		}
+
+		switch tok.Type {
+		case LPAREN, LBRACKET, LBRACE:
+			depth++
+			if tok.Type == LPAREN && prev(1) == IDENT && (prev(2) == FUNC || prev(2) == SIGNAL) {
+				paramDepth = depth
+			}
+			if inType && tok.Type == LBRACKET {
+				typeDepth++
+			}
+			continue
+		case RPAREN, RBRACKET, RBRACE:
+			if depth == paramDepth {
+				paramDepth = 0
+			}
+			depth--
+			if inType && tok.Type == RBRACKET && typeDepth > 0 {
+				typeDepth--
+			}
+			continue
+		case DOT, COMMA:
+			continue
+		}
+		// A type hint goes on through dots, brackets and their commas
+		if inType && !(tok.Type == IDENT && (typeDepth > 0 || prev(1) == DOT)) {
+			inType = false
+			typeDepth = 0
+		}
+
+		switch {
+		case tok.Type == AT && next(1) == IDENT:
+			add(tok, tokens[i+1].Offset+len(tokens[i+1].Literal)-tok.Offset, KindAnnotation)
+			i++
+		case tok.Type == COMMENT:
+			add(tok, len(tok.Literal), KindComment)
+		case tok.Type == STRING || tok.Type == RSTRING:
+			add(tok, len(tok.Literal), KindString)
+		case tok.Type == INT || tok.Type == FLOAT || tok.Type == HEX || tok.Type == BIN:
+			add(tok, len(tok.Literal), KindNumber)
+		case keywordTypes[tok.Type]:
+			add(tok, len(tok.Literal), KindKeyword)
+		case operatorTypes[tok.Type]:
+			add(tok, len(tok.Literal), KindOperator)
+		case tok.Type == IDENT:
+			inType = inType || isTypePosition(prev, paramDepth == depth && paramDepth > 0)
+			add(tok, len(tok.Literal), classifyName(tok.Literal, declared, inType, prev(1), next(1)))
+		}
+	}
+	return result
+}
+
+// isTypePosition reports whether the name after the tokens prev returns is a
+// type: after extends, class_name, class, ->, as and is, and in the type hint
+// of a variable, constant, loop variable or parameter
+func isTypePosition(prev func(int) TokenType, inParameters bool) bool {
+	switch prev(1) {
+	case EXTENDS, CLASS_NAME, CLASS, ARROW, AS, IS:
+		return true
+	case COLON:
+		if prev(2) != IDENT {
+			return false
+		}
+		switch prev(3) {
+		case VAR, CONST, FOR:
+			return true
+		case LPAREN, COMMA:
+			return inParameters
+		}
+	}
+	return false
+}
+
+// classifyName returns the category of a name, given whether it stands in a
+// type position and the tokens around it
+func classifyName(name string, declared map[string]TokenKind, typePosition bool, prev, next TokenType) TokenKind {
+	switch {
+	case typePosition || builtinTypeNames[name] && next != LPAREN:
+		return KindType
+	case prev == FUNC:
+		return KindFunction
+	case prev == SIGNAL:
+		return KindSignal
+	case prev == CONST:
+		return KindConstant
+	}
+	if kind, ok := declared[name]; ok && (kind != KindFunction || prev != DOT) {
+		return kind
+	}
+	switch {
+	case isPascalCase(name):
+		return KindType
+	case next == LPAREN:
+		return KindFunction
+	case isScreamingCase(name):
+		return KindConstant
+	}
+	return KindVariable
+}
+
+// declaredNames returns the category of the classes, enums, functions,
+// signals and constants tree declares
+func declaredNames(tree *ast.AbstractSyntaxTree) map[string]TokenKind {
+	declared := make(map[string]TokenKind)
+	if tree == nil || tree.RootClass == nil {
+		return declared
+	}
+	if tree.RootClass.ClassName != "" {
+		declared[tree.RootClass.ClassName] = KindType
+	}
+	ast.Inspect(tree.RootClass, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Class:
+			if n != tree.RootClass {
+				declared[n.Name] = KindType
+			}
+		case *ast.Function:
+			declared[n.Name] = KindFunction
+		case *ast.SignalStatement:
+			declared[n.Name] = KindSignal
+		case *ast.VarStatement:
+			if n.IsConst {
+				declared[n.Name] = KindConstant
+			}
+		case *ast.EnumStatement:
+			if n.Name != "" {
+				declared[n.Name] = KindType
+			}
+			for _, element := range n.Elements {
+				declared[element.Name] = KindConstant
+			}
+		}
+		return true
+	})
+	return declared
+}
+
+// isPascalCase reports whether name starts with an upper case letter and
+// holds a lower case one, such as Node2D
+func isPascalCase(name string) bool {
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsLower(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScreamingCase reports whether name is made of upper case letters, digits
+// and underscores, with at least one letter
+func isScreamingCase(name string) bool {
+	letter := false
+	for _, r := range name {
+		switch {
+		case unicode.IsUpper(r):
+			letter = true
+		case r != '_' && !unicode.IsDigit(r):
+			return false
+		}
+	}
+	return letter
+}
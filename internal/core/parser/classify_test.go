@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	source := `class_name Player
+extends CharacterBody2D
+
+signal died(cause: String)
+enum State { IDLE, RUNNING }
+const MAX_SPEED = 300
+@export var speed: float = 10.0
+var items: Array[Item] = []
+
+func _ready() -> void:
+	var state := State.IDLE
+	died.emit("fall") # bye
+	move(speed)
+
+func move(amount: int):
+	print(amount as float)
+`
+	want := map[string]TokenKind{
+		"class_name":      KindKeyword,
+		"Player":          KindType,
+		"CharacterBody2D": KindType,
+		"died":            KindSignal,
+		"String":          KindType,
+		"State":           KindType,
+		"IDLE":            KindConstant,
+		"MAX_SPEED":       KindConstant,
+		"300":             KindNumber,
+		"@export":         KindAnnotation,
+		"speed":           KindVariable,
+		"float":           KindType,
+		"Array":           KindType,
+		"Item":            KindType,
+		"_ready":          KindFunction,
+		"void":            KindType,
+		"\"fall\"":        KindString,
+		"# bye":           KindComment,
+		"emit":            KindFunction,
+		"move":            KindFunction,
+		"amount":          KindVariable,
+		"int":             KindType,
+		"print":           KindFunction,
+		"as":              KindKeyword,
+		"=":               KindOperator,
+		"->":              KindOperator,
+	}
+
+	got := make(map[string][]TokenKind)
+	for _, token := range Classify(source) {
+		text := source[token.Offset : token.Offset+token.Length]
+		got[text] = append(got[text], token.Kind)
+	}
+	for text, kind := range want {
+		kinds, ok := got[text]
+		if !ok {
+			t.Errorf("%q: not classified", text)
+			continue
+		}
+		for _, k := range kinds {
+			if k != kind {
+				t.Errorf("%q: got %v, want %v", text, kinds, kind)
+				break
+			}
+		}
+	}
+}
+
+func TestClassifyInvalidSource(t *testing.T) {
+	tokens := Classify("func (:\n\tvar x = \"unterminated\n")
+	if len(tokens) == 0 {
+		t.Fatal("expected tokens for source that does not parse")
+	}
+	if tokens[0].Kind != KindKeyword || tokens[0].Length != len("func") {
+		t.Errorf("got %+v, want the func keyword", tokens[0])
+	}
+}
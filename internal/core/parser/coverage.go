@@ -0,0 +1,60 @@
+package parser
+
+import "github.com/dzannotti/gdtoolkit/internal/core/ast"
+
+// UncoveredLines returns the lines of source holding code that no node of
+// tree starts on, such as statements the parser dropped. A statement written
+// over several lines, in brackets or after backslashes, is covered by a node
+// starting on any of its lines, and is reported at its first line. Blank and
+// comment lines are never reported.
+func UncoveredLines(source string, tree *ast.AbstractSyntaxTree) []int {
+	covered := make(map[int]bool)
+	ast.Inspect(tree, func(node ast.Node) bool {
+		// The global scope is placed at line 1 whatever it holds
+		if node != tree.RootClass {
+			covered[node.Position().Line] = true
+		}
+		switch n := node.(type) {
+		case *ast.Class:
+			covered[n.ExtendsPos.Line] = true
+			covered[n.ClassNamePos.Line] = true
+		case *ast.IfStatement:
+			covered[n.ElsePos.Line] = true
+		}
+		return true
+	})
+
+	var uncovered []int
+	// lines holds the lines of the tokens of the current logical line
+	var lines []int
+	lexer := NewLexer(source)
+	for {
+		tok := lexer.NextToken()
+		switch tok.Type {
+		case COMMENT, INDENT, DEDENT:
+			continue
+		case NL, EOF:
+			if len(lines) > 0 && !anyCovered(lines, covered) {
+				uncovered = append(uncovered, lines[0])
+			}
+			lines = lines[:0]
+			if tok.Type == EOF {
+				return uncovered
+			}
+		default:
+			if len(lines) == 0 || lines[len(lines)-1] != tok.Line {
+				lines = append(lines, tok.Line)
+			}
+		}
+	}
+}
+
+// anyCovered reports whether one of lines is covered
+func anyCovered(lines []int, covered map[int]bool) bool {
+	for _, line := range lines {
+		if covered[line] {
+			return true
+		}
+	}
+	return false
+}
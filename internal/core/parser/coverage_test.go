@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUncoveredLines(t *testing.T) {
+	source := `class_name Foo
+extends Node
+
+# a comment
+func f(x):
+	if x:
+		pass
+	else:
+		return [
+			1,
+		]
+	x += 1
+	return x
+`
+	tree, errors := ParseFile("test.gd", source)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	if lines := UncoveredLines(source, tree); len(lines) != 0 {
+		t.Errorf("expected every line covered, got %v", lines)
+	}
+
+	// Statements missing from the tree are reported at their first line
+	function := tree.RootClass.Functions[0]
+	function.Statements = function.Statements[1:2]
+	if lines := UncoveredLines(source, tree); !reflect.DeepEqual(lines, []int{6, 7, 8, 9, 13}) {
+		t.Errorf("expected lines 6 to 9 and 13 uncovered, got %v", lines)
+	}
+}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"sort"
+	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -12,10 +15,23 @@ type Lexer struct {
 	readPosition int     // current reading position in input (after current char)
 	ch           rune    // current char under examination
 	line         int     // current line number
-	column       int     // current column number
+	lineStart    int     // offset of the first character of the current line
+	lineExtra    int     // continuation bytes of the multi-byte characters read on the current line
 	indentStack  []int   // stack of indentation levels
 	indentLevel  int     // current indentation level
+	bracketDepth int     // nesting depth of open (), [] and {} brackets
 	tokens       []Token // tokens to be returned before continuing lexing
+	queued       int     // index of the next token of tokens to return
+	// continuations lists the lines ending with a backslash, in order
+	continuations []int
+	// indentChar is the character the first indented line is indented with,
+	// and mixedIndents lists the lines indented with both tabs and spaces,
+	// or with another character than that line, in order
+	indentChar   rune
+	mixedIndents []int
+	// unterminated lists the offsets of the strings missing their closing
+	// quotes, in order
+	unterminated []int
 }
 
 // NewLexer creates a new Lexer
@@ -23,35 +39,62 @@ func NewLexer(input string) *Lexer {
 	l := &Lexer{
 		input:       input,
 		line:        1,
-		column:      1,
 		indentStack: []int{0}, // start with 0 indentation
 		indentLevel: 0,
 	}
+	// A byte order mark is not part of the code; skipping it keeps the
+	// offsets of the tokens those of the input
+	if strings.HasPrefix(input, ByteOrderMark) {
+		l.readPosition = len(ByteOrderMark)
+		l.lineStart = l.readPosition
+	}
 	l.readChar()
 	return l
 }
 
+// ByteOrderMark is the UTF-8 byte order mark some Windows editors start files with
+const ByteOrderMark = "\uFEFF"
+
 // readChar reads the next character and advances the position in the input string
 func (l *Lexer) readChar() {
 	l.position = l.readPosition
-	if l.readPosition >= len(l.input) {
-		l.ch = 0 // EOF
-	} else {
-		r, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
-		l.ch = r
-		l.readPosition += size
+	// ASCII characters, newlines included, are read without decoding
+	if l.position < len(l.input) && l.input[l.position] < utf8.RuneSelf {
+		l.ch = rune(l.input[l.position])
+		l.readPosition++
+		if l.ch == '\n' {
+			l.line++
+			l.lineStart = l.readPosition
+			l.lineExtra = 0
+		}
+		return
 	}
+	l.readRune()
+}
 
-	if l.ch == '\n' {
-		l.line++
-		l.column = 1
-	} else {
-		l.column++
+// readRune reads a multi-byte character, which is never a newline, or the
+// end of the input
+func (l *Lexer) readRune() {
+	if l.readPosition >= len(l.input) {
+		l.ch = 0 // EOF
+		return
 	}
+	r, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = r
+	l.readPosition += size
+	l.lineExtra += size - 1
 }
 
 // peekChar returns the next character without advancing the position
 func (l *Lexer) peekChar() rune {
+	if l.readPosition < len(l.input) && l.input[l.readPosition] < utf8.RuneSelf {
+		return rune(l.input[l.readPosition])
+	}
+	return l.peekRune()
+}
+
+// peekRune decodes the next character when it is not ASCII
+func (l *Lexer) peekRune() rune {
 	if l.readPosition >= len(l.input) {
 		return 0 // EOF
 	}
@@ -62,280 +105,293 @@ func (l *Lexer) peekChar() rune {
 // NextToken returns the next token
 func (l *Lexer) NextToken() Token {
 	// If we have tokens queued up (like INDENT/DEDENT), return them first
-	if len(l.tokens) > 0 {
-		tok := l.tokens[0]
-		l.tokens = l.tokens[1:]
-		return tok
+	if l.queued < len(l.tokens) {
+		return l.dequeue()
 	}
 
-	l.skipWhitespace()
+	if l.ch == ' ' || l.ch == '\t' || l.ch == '\r' || l.ch == '\\' || l.ch == '\n' && l.bracketDepth > 0 {
+		l.skipWhitespace()
+	}
 
-	var tok Token
+	start := l.mark()
+	tokenType, literal := l.readToken()
+	return Token{
+		Type:    tokenType,
+		Literal: literal,
+		Value:   stringValue(tokenType, literal),
+		Line:    start.line,
+		Column:  start.column,
+		Offset:  start.offset,
+	}
+}
+
+// readToken reads the token at the current character and returns its type
+// and literal. Building the token itself in NextToken, once, rather than in
+// each case spares copying it.
+func (l *Lexer) readToken() (TokenType, string) {
+	var tokenType TokenType
+	start := l.position
 
 	switch l.ch {
 	case '+':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(PLUSEQ, string(ch)+"=")
+			tokenType = PLUSEQ
 		} else {
-			tok = l.newToken(PLUS, string(l.ch))
+			tokenType = PLUS
 		}
 	case '-':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(MINUSEQ, string(ch)+"=")
+			tokenType = MINUSEQ
 		} else if l.peekChar() == '>' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(ARROW, string(ch)+">")
+			tokenType = ARROW
 		} else {
-			tok = l.newToken(MINUS, string(l.ch))
+			tokenType = MINUS
 		}
 	case '*':
 		if l.peekChar() == '*' {
-			ch := l.ch
 			l.readChar()
 			if l.peekChar() == '=' {
 				l.readChar()
-				tok = l.newToken(POWEREQ, string(ch)+"*=")
+				tokenType = POWEREQ
 			} else {
-				tok = l.newToken(POWER, string(ch)+"*")
+				tokenType = POWER
 			}
 		} else if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(ASTERISKEQ, string(ch)+"=")
+			tokenType = ASTERISKEQ
 		} else {
-			tok = l.newToken(ASTERISK, string(l.ch))
+			tokenType = ASTERISK
 		}
 	case '/':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(SLASHEQ, string(ch)+"=")
+			tokenType = SLASHEQ
 		} else {
-			tok = l.newToken(SLASH, string(l.ch))
+			tokenType = SLASH
 		}
 	case '%':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(PERCENTEQ, string(ch)+"=")
+			tokenType = PERCENTEQ
 		} else {
-			tok = l.newToken(PERCENT, string(l.ch))
+			tokenType = PERCENT
 		}
 	case '&':
 		if l.peekChar() == '&' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(AMPAMP, string(ch)+"&")
+			tokenType = AMPAMP
 		} else if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(AMPEQ, string(ch)+"=")
+			tokenType = AMPEQ
 		} else {
-			tok = l.newToken(BITAND, string(l.ch))
+			tokenType = BITAND
 		}
 	case '|':
 		if l.peekChar() == '|' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(PIPEPIPE, string(ch)+"|")
+			tokenType = PIPEPIPE
 		} else if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(PIPEEQ, string(ch)+"=")
+			tokenType = PIPEEQ
 		} else {
-			tok = l.newToken(BITOR, string(l.ch))
+			tokenType = BITOR
 		}
 	case '^':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(CARETEQ, string(ch)+"=")
+			tokenType = CARETEQ
 		} else {
-			tok = l.newToken(CARET, string(l.ch))
+			tokenType = CARET
 		}
 	case '~':
-		tok = l.newToken(BITNOT, string(l.ch))
+		tokenType = BITNOT
 	case '<':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(LTE, string(ch)+"=")
+			tokenType = LTE
 		} else if l.peekChar() == '<' {
-			ch := l.ch
 			l.readChar()
 			if l.peekChar() == '=' {
 				l.readChar()
-				tok = l.newToken(LTLTEQ, string(ch)+"<=")
+				tokenType = LTLTEQ
 			} else {
-				tok = l.newToken(LTLT, string(ch)+"<")
+				tokenType = LTLT
 			}
 		} else {
-			tok = l.newToken(LT, string(l.ch))
+			tokenType = LT
 		}
 	case '>':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(GTE, string(ch)+"=")
+			tokenType = GTE
 		} else if l.peekChar() == '>' {
-			ch := l.ch
 			l.readChar()
 			if l.peekChar() == '=' {
 				l.readChar()
-				tok = l.newToken(GTGTEQ, string(ch)+">=")
+				tokenType = GTGTEQ
 			} else {
-				tok = l.newToken(GTGT, string(ch)+">")
+				tokenType = GTGT
 			}
 		} else {
-			tok = l.newToken(GT, string(l.ch))
+			tokenType = GT
 		}
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(EQ, string(ch)+"=")
+			tokenType = EQ
 		} else {
-			tok = l.newToken(ASSIGN, string(l.ch))
+			tokenType = ASSIGN
 		}
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(NOT_EQ, string(ch)+"=")
+			tokenType = NOT_EQ
 		} else {
-			tok = l.newToken(BANG, string(l.ch))
+			tokenType = BANG
 		}
 	case '.':
-		tok = l.newToken(DOT, string(l.ch))
+		tokenType = DOT
 	case ',':
-		tok = l.newToken(COMMA, string(l.ch))
+		tokenType = COMMA
 	case ':':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = l.newToken(COLONASSIGN, string(ch)+"=")
+			tokenType = COLONASSIGN
 		} else {
-			tok = l.newToken(COLON, string(l.ch))
+			tokenType = COLON
 		}
 	case ';':
-		tok = l.newToken(SEMICOLON, string(l.ch))
+		tokenType = SEMICOLON
 	case '(':
-		tok = l.newToken(LPAREN, string(l.ch))
+		l.openBracket()
+		tokenType = LPAREN
 	case ')':
-		tok = l.newToken(RPAREN, string(l.ch))
+		l.closeBracket()
+		tokenType = RPAREN
 	case '{':
-		tok = l.newToken(LBRACE, string(l.ch))
+		l.openBracket()
+		tokenType = LBRACE
 	case '}':
-		tok = l.newToken(RBRACE, string(l.ch))
+		l.closeBracket()
+		tokenType = RBRACE
 	case '[':
-		tok = l.newToken(LBRACKET, string(l.ch))
+		l.openBracket()
+		tokenType = LBRACKET
 	case ']':
-		tok = l.newToken(RBRACKET, string(l.ch))
+		l.closeBracket()
+		tokenType = RBRACKET
 	case '@':
-		tok = l.newToken(AT, string(l.ch))
+		tokenType = AT
 	case '$':
-		tok = l.newToken(DOLLAR, string(l.ch))
+		tokenType = DOLLAR
 	case '#':
-		tok.Type = COMMENT
-		tok.Line = l.line
-		tok.Column = l.column
-		tok.Offset = l.position
-		tok.Literal = l.readComment()
-		return tok
+		return COMMENT, l.readComment()
 	case '"', '\'':
-		tok.Type = STRING
-		tok.Line = l.line
-		tok.Column = l.column
-		tok.Offset = l.position
-		tok.Literal = l.readString(l.ch)
-		return tok
+		return STRING, l.readString(l.ch)
 	case 'r':
 		if l.peekChar() == '"' || l.peekChar() == '\'' {
-			startCol := l.column
-			startPos := l.position
 			l.readChar() // consume 'r'
-			quote := l.ch
-			l.readChar() // consume quote
-			str := l.readRawString(quote)
-			tok.Type = RSTRING
-			tok.Line = l.line
-			tok.Column = startCol
-			tok.Offset = startPos
-			tok.Literal = "r" + string(quote) + str + string(quote)
-			return tok
+			return RSTRING, "r" + l.readString(l.ch)
 		}
 		// If not a raw string, treat as identifier
-		tok.Line = l.line
-		tok.Column = l.column
-		tok.Offset = l.position
-		tok.Literal = l.readIdentifier()
-		tok.Type = LookupIdent(tok.Literal)
-		return tok
+		literal := l.readIdentifier()
+		return LookupIdent(literal), literal
 	case '\n':
 		// Generate NL token and handle indentation
-		tok = l.newToken(NL, "\n")
 		l.readChar()
 
 		// Count indentation after newline
 		indent := 0
-		for l.ch == ' ' || l.ch == '\t' {
-			if l.ch == ' ' {
-				indent++
-			} else if l.ch == '\t' {
-				indent += 4 // Assuming tab width of 4
+		mixed := false
+		if l.ch == ' ' || l.ch == '\t' {
+			if l.indentChar == 0 {
+				l.indentChar = l.ch
 			}
-			l.readChar()
+			end := l.position
+			for ; end < len(l.input) && (l.input[end] == ' ' || l.input[end] == '\t'); end++ {
+				mixed = mixed || rune(l.input[end]) != l.indentChar
+				if l.input[end] == ' ' {
+					indent++
+				} else {
+					indent += 4 // Assuming tab width of 4
+				}
+			}
+			l.skipTo(end)
+		}
+
+		// Blank and comment-only lines carry no indentation information. A
+		// carriage return alone is whitespace before the code of the line.
+		if l.ch == '\n' || l.ch == '\r' && (l.peekChar() == '\n' || l.peekChar() == 0) || l.ch == '#' {
+			return NL, "\n"
+		}
+		if mixed {
+			l.mixedIndents = append(l.mixedIndents, l.line)
 		}
 
 		// Handle indentation changes
 		l.handleIndentation(indent)
-		return tok
+		return NL, "\n"
 	case 0:
-		tok.Literal = ""
-		tok.Type = EOF
-		tok.Line = l.line
-		tok.Column = l.column
-		tok.Offset = l.position
-		return tok
+		return EOF, ""
 	default:
 		if isLetter(l.ch) || l.ch == '_' {
-			tok.Line = l.line
-			tok.Column = l.column
-			tok.Offset = l.position
-			tok.Literal = l.readIdentifier()
-			tok.Type = LookupIdent(tok.Literal)
-			return tok
+			literal := l.readIdentifier()
+			return LookupIdent(literal), literal
 		} else if isDigit(l.ch) {
-			tok.Line = l.line
-			tok.Column = l.column
-			tok.Offset = l.position
-			return l.readNumber()
+			tokenType = l.readNumber()
+			return tokenType, l.input[start:l.position]
 		} else {
-			tok = l.newToken(ILLEGAL, string(l.ch))
+			tokenType = ILLEGAL
 		}
 	}
 
+	// The other tokens end at the current character
 	l.readChar()
+	return tokenType, l.input[start:l.position]
+}
+
+// dequeue returns the next queued token
+func (l *Lexer) dequeue() Token {
+	tok := l.tokens[l.queued]
+	l.queued++
+	if l.queued == len(l.tokens) {
+		// Reuse the queue's storage for the next indentation change
+		l.tokens = l.tokens[:0]
+		l.queued = 0
+	}
 	return tok
 }
 
 // readIdentifier reads an identifier
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '_' {
+
+	// Skip the ASCII part of the identifier at once; it holds no newline
+	end := l.position
+	for end < len(l.input) && isASCIIIdentifierByte(l.input[end]) {
+		end++
+	}
+	l.skipTo(end)
+
+	// The ASCII characters that follow are not part of it, unless it goes on
+	// with a letter or digit that is not ASCII
+	for l.ch >= utf8.RuneSelf && (isLetter(l.ch) || isDigit(l.ch)) {
 		l.readChar()
+		end = l.position
+		for end < len(l.input) && isASCIIIdentifierByte(l.input[end]) {
+			end++
+		}
+		l.skipTo(end)
 	}
 	return l.input[position:l.position]
 }
 
-// readNumber reads a number (integer or float)
-func (l *Lexer) readNumber() Token {
-	position := l.position
+// readNumber reads a number and returns its type: an integer, a float, or
+// one written in hexadecimal or binary
+func (l *Lexer) readNumber() TokenType {
 	isFloat := false
 
 	// Check for hex or binary prefix
@@ -345,20 +401,22 @@ func (l *Lexer) readNumber() Token {
 		for isHexDigit(l.ch) || l.ch == '_' {
 			l.readChar()
 		}
-		return l.newToken(HEX, l.input[position:l.position])
+		return HEX
 	} else if l.ch == '0' && (l.peekChar() == 'b' || l.peekChar() == 'B') {
 		l.readChar() // consume '0'
 		l.readChar() // consume 'b'
 		for isBinDigit(l.ch) || l.ch == '_' {
 			l.readChar()
 		}
-		return l.newToken(BIN, l.input[position:l.position])
+		return BIN
 	}
 
 	// Regular number
-	for isDigit(l.ch) || l.ch == '_' {
-		l.readChar()
+	end := l.position
+	for end < len(l.input) && (isDigit(rune(l.input[end])) || l.input[end] == '_') {
+		end++
 	}
+	l.skipTo(end)
 
 	// Check for decimal point
 	if l.ch == '.' && isDigit(l.peekChar()) {
@@ -382,71 +440,214 @@ func (l *Lexer) readNumber() Token {
 	}
 
 	if isFloat {
-		return l.newToken(FLOAT, l.input[position:l.position])
+		return FLOAT
+	}
+	return INT
+}
+
+// skipTo reads past the characters up to the offset end, which the caller
+// scanned itself: they must all be ASCII and none of them a newline
+func (l *Lexer) skipTo(end int) {
+	if end > l.position {
+		l.readPosition = end
+		l.readChar()
 	}
-	return l.newToken(INT, l.input[position:l.position])
 }
 
-// readString reads a string literal
+// openBracket enters a bracketed expression, where newlines are not significant
+func (l *Lexer) openBracket() {
+	l.bracketDepth++
+}
+
+// closeBracket leaves a bracketed expression; an unbalanced closing bracket
+// is left for the parser to report
+func (l *Lexer) closeBracket() {
+	if l.bracketDepth > 0 {
+		l.bracketDepth--
+	}
+}
+
+// readString reads a string literal. A string in single quotes or double
+// quotes ends at the end of its line, unless a backslash escapes it; one in
+// triple quotes may span lines. A string missing its closing quotes is
+// recorded as unterminated.
 func (l *Lexer) readString(quote rune) string {
 	position := l.position
-	l.readChar() // consume opening quote
-	for {
+	delimiter := string(quote)
+	if triple := strings.Repeat(delimiter, 3); strings.HasPrefix(l.input[position:], triple) {
+		delimiter = triple
+	}
+	for range delimiter {
+		l.readChar() // consume opening quotes
+	}
+	for l.ch != 0 {
 		if l.ch == '\\' {
+			// An escaped quote never closes the string, not even a raw one
 			l.readChar() // consume backslash
 			l.readChar() // consume escaped character
 			continue
 		}
-		if l.ch == quote || l.ch == 0 {
-			break
+		if strings.HasPrefix(l.input[l.position:], delimiter) {
+			for range delimiter {
+				l.readChar() // consume closing quotes
+			}
+			return l.input[position:l.position]
 		}
-		if l.ch == '\n' {
-			l.line++
-			l.column = 1
+		if len(delimiter) == 1 && (l.ch == '\n' || l.ch == '\r') {
+			break
 		}
 		l.readChar()
 	}
-	if l.ch == quote {
-		l.readChar() // consume closing quote
-	}
+	l.unterminated = append(l.unterminated, position)
 	return l.input[position:l.position]
 }
 
-// readRawString reads a raw string literal (r"..." or r'...')
-func (l *Lexer) readRawString(quote rune) string {
-	position := l.position
-	for {
-		if l.ch == quote || l.ch == 0 {
-			break
+// Unterminated reports whether tok is a string missing its closing quotes.
+// It only knows of the tokens lexed so far.
+func (l *Lexer) Unterminated(tok Token) bool {
+	if tok.Type != STRING && tok.Type != RSTRING {
+		return false
+	}
+	offset := tok.Offset
+	if tok.Type == RSTRING {
+		offset++ // past the r
+	}
+	i := sort.SearchInts(l.unterminated, offset)
+	return i < len(l.unterminated) && l.unterminated[i] == offset
+}
+
+// decodeString returns the string a literal stands for: the text between its
+// quotes with the escape sequences decoded, or as written in a raw string.
+// An unknown escape is kept as written.
+func decodeString(literal string) string {
+	raw := strings.HasPrefix(literal, "r")
+	content := strings.TrimPrefix(literal, "r")
+	quote := content[:1]
+	if triple := strings.Repeat(quote, 3); len(content) >= 6 && strings.HasPrefix(content, triple) {
+		quote = triple
+	}
+	content = strings.TrimPrefix(content, quote)
+	// The lexer reports a string missing its closing quotes; its value is
+	// still what follows the opening ones
+	if len(content) >= len(quote) {
+		content = strings.TrimSuffix(content, quote)
+	}
+	if raw || !strings.Contains(content, "\\") {
+		return content
+	}
+
+	var value strings.Builder
+	for i := 0; i < len(content); i++ {
+		if content[i] != '\\' || i+1 == len(content) {
+			value.WriteByte(content[i])
+			continue
 		}
-		if l.ch == '\n' {
-			l.line++
-			l.column = 1
+		i++
+		switch c := content[i]; c {
+		case 'n':
+			value.WriteByte('\n')
+		case 't':
+			value.WriteByte('\t')
+		case 'r':
+			value.WriteByte('\r')
+		case 'a':
+			value.WriteByte('\a')
+		case 'b':
+			value.WriteByte('\b')
+		case 'f':
+			value.WriteByte('\f')
+		case 'v':
+			value.WriteByte('\v')
+		case '"', '\'', '\\':
+			value.WriteByte(c)
+		case '\n':
+			// A backslash at the end of a line joins it with the next one
+		case '\r':
+			if i+1 < len(content) && content[i+1] == '\n' {
+				i++
+				continue
+			}
+			value.WriteString(content[i-1 : i+1])
+		case 'u', 'U':
+			digits := 4
+			if c == 'U' {
+				digits = 6
+			}
+			code, err := strconv.ParseUint(content[i+1:min(i+1+digits, len(content))], 16, 32)
+			if err != nil || i+digits >= len(content) {
+				value.WriteString(content[i-1 : i+1])
+				continue
+			}
+			value.WriteRune(rune(code))
+			i += digits
+		default:
+			value.WriteString(content[i-1 : i+1])
 		}
-		l.readChar()
 	}
-	if l.ch == quote {
-		l.readChar() // consume closing quote
-	}
-	return l.input[position : l.position-1]
+	return value.String()
 }
 
-// readComment reads a comment
+// readComment reads a comment, up to the \n or \r\n ending its line
 func (l *Lexer) readComment() string {
 	position := l.position
-	for l.ch != '\n' && l.ch != 0 {
+	for l.ch != '\n' && l.ch != 0 && !(l.ch == '\r' && l.peekChar() == '\n') {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-// skipWhitespace skips whitespace characters (except newlines which are significant)
+// skipWhitespace skips whitespace characters, and the newlines inside
+// brackets, where lines are joined implicitly: no NL or indentation tokens.
+// The other newlines are significant.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == '\n' && l.bracketDepth > 0:
+			l.readChar()
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\r':
+			end := l.position
+			for end < len(l.input) && (l.input[end] == ' ' || l.input[end] == '\t' || l.input[end] == '\r') {
+				end++
+			}
+			l.skipTo(end)
+		case l.ch == '\\' && l.isLineContinuation():
+			// A backslash continues the statement on the next line: the newline
+			// is skipped and the indentation of the next line is ignored
+			l.continuations = append(l.continuations, l.line)
+			l.readChar() // consume '\\'
+			if l.ch == '\r' {
+				l.readChar()
+			}
+			l.readChar() // consume '\n'
+		default:
+			return
+		}
 	}
 }
 
+// isLineContinuation reports whether the current backslash ends its line
+func (l *Lexer) isLineContinuation() bool {
+	next := l.peekChar()
+	if next == '\r' && l.readPosition+1 < len(l.input) {
+		return l.input[l.readPosition+1] == '\n'
+	}
+	return next == '\n'
+}
+
+// MixedIndentation reports whether line is indented with both tabs and
+// spaces, or with another character than the first indented line of the
+// input. It only knows of the lines lexed so far.
+func (l *Lexer) MixedIndentation(line int) bool {
+	i := sort.SearchInts(l.mixedIndents, line)
+	return i < len(l.mixedIndents) && l.mixedIndents[i] == line
+}
+
+// Continuations returns the numbers of the lines that end with a backslash
+// line continuation, in order
+func (l *Lexer) Continuations() []int {
+	return l.continuations
+}
+
 // handleIndentation handles indentation changes and generates INDENT/DEDENT tokens
 func (l *Lexer) handleIndentation(indent int) {
 	currentIndent := l.indentStack[len(l.indentStack)-1]
@@ -470,24 +671,69 @@ func (l *Lexer) handleIndentation(indent int) {
 	}
 }
 
+// tokenStart is the position a token starts at
+type tokenStart struct {
+	line, column, offset int
+}
+
+// mark records the position of the current character
+func (l *Lexer) mark() tokenStart {
+	return tokenStart{line: l.line, column: l.column(), offset: l.position}
+}
+
+// column returns the 1-based column of the current character, counted in
+// characters: its offset in the line less the continuation bytes of the
+// multi-byte characters before it. The newline is read as the start of the
+// next line, so it is column 1 there.
+func (l *Lexer) column() int {
+	if l.ch == '\n' {
+		return 1
+	}
+	extra := l.lineExtra
+	if l.ch >= utf8.RuneSelf {
+		extra -= l.readPosition - l.position - 1
+	}
+	return l.position - l.lineStart - extra + 1
+}
+
+// stringValue returns the value of a token: the decoded string of a STRING or
+// RSTRING token, nothing for the others
+func stringValue(tokenType TokenType, literal string) string {
+	if tokenType != STRING && tokenType != RSTRING {
+		return ""
+	}
+	return decodeString(literal)
+}
+
 // newToken creates a new token
 func (l *Lexer) newToken(tokenType TokenType, literal string) Token {
 	return Token{
 		Type:    tokenType,
 		Literal: literal,
 		Line:    l.line,
-		Column:  l.column,
+		Column:  l.column(),
 		Offset:  l.position,
 	}
 }
 
-// Helper functions
+// isLetter, isDigit and the other helpers are small enough to be inlined in
+// the loops of the lexer. An ASCII letter is lower case with its 0x20 bit set.
 func isLetter(ch rune) bool {
-	return unicode.IsLetter(ch)
+	if ch < utf8.RuneSelf {
+		return ch|0x20 >= 'a' && ch|0x20 <= 'z'
+	}
+	return unicode.Is(unicode.Letter, ch)
 }
 
 func isDigit(ch rune) bool {
-	return unicode.IsDigit(ch)
+	if ch < utf8.RuneSelf {
+		return ch >= '0' && ch <= '9'
+	}
+	return unicode.Is(unicode.Digit, ch)
+}
+
+func isASCIIIdentifierByte(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '_'
 }
 
 func isHexDigit(ch rune) bool {
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -132,6 +133,35 @@ class MyClass extends Node:
 	}
 }
 
+func TestLexer_Strings(t *testing.T) {
+	input := `'a\'b' "x\"y" r'C:\dir' """one
+"two"
+three""" '''''' x`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{STRING, `'a\'b'`, 1},
+		{STRING, `"x\"y"`, 1},
+		{RSTRING, `r'C:\dir'`, 1},
+		{STRING, "\"\"\"one\n\"two\"\nthree\"\"\"", 1},
+		{STRING, `''''''`, 3},
+		{IDENT, "x", 3},
+		{EOF, "", 3},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral || tok.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - expected %s %q on line %d, got %s %q on line %d",
+				i, tt.expectedType, tt.expectedLiteral, tt.expectedLine, tok.Type, tok.Literal, tok.Line)
+		}
+	}
+}
+
 func TestLexer_Operators(t *testing.T) {
 	input := `+ - * / % ** += -= *= /= %= **= & | ^ ~ << >> &= |= ^= <<= >>= && || ! == != < > <= >= . , : ; ( ) { } [ ] @ $ -> as is`
 
@@ -159,3 +189,232 @@ func TestLexer_Operators(t *testing.T) {
 		}
 	}
 }
+
+func TestLexer_BlankLinesKeepIndentation(t *testing.T) {
+	input := "class X:\n\tfunc foo():\n\t\tpass\n\n\n\tfunc bar():\n\t\tpass"
+
+	expectedTokens := []TokenType{
+		CLASS, IDENT, COLON, NL, INDENT,
+		FUNC, IDENT, LPAREN, RPAREN, COLON, NL, INDENT,
+		PASS, NL, NL, NL, DEDENT,
+		FUNC, IDENT, LPAREN, RPAREN, COLON, NL, INDENT,
+		PASS,
+		EOF,
+	}
+
+	l := NewLexer(input)
+
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, expected, tok.Type)
+		}
+	}
+}
+
+func TestLexer_CommentLinesKeepIndentation(t *testing.T) {
+	input := "func foo():\n\tpass\n# 注释\n\tpass"
+
+	expectedTokens := []TokenType{
+		FUNC, IDENT, LPAREN, RPAREN, COLON, NL, INDENT,
+		PASS, NL, COMMENT, NL,
+		PASS,
+		EOF,
+	}
+
+	l := NewLexer(input)
+
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, expected, tok.Type)
+		}
+	}
+}
+
+func TestLexer_LoneCarriageReturn(t *testing.T) {
+	// A carriage return before code is whitespace, not the end of a blank line
+	input := "func foo():\n\tpass\n\rpass"
+
+	expectedTokens := []TokenType{
+		FUNC, IDENT, LPAREN, RPAREN, COLON, NL, INDENT,
+		PASS, NL, DEDENT,
+		PASS,
+		EOF,
+	}
+
+	l := NewLexer(input)
+
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, expected, tok.Type)
+		}
+	}
+}
+
+func TestLexer_BracketsJoinLines(t *testing.T) {
+	input := "func foo():\n\tbar(\n\t\t[1,\n\t2],\n\t{\"a\": (1 +\n\t\t\t2)})\n\tpass"
+
+	expectedTokens := []TokenType{
+		FUNC, IDENT, LPAREN, RPAREN, COLON, NL, INDENT,
+		IDENT, LPAREN,
+		LBRACKET, INT, COMMA, INT, RBRACKET, COMMA,
+		LBRACE, STRING, COLON, LPAREN, INT, PLUS, INT, RPAREN, RBRACE,
+		RPAREN, NL,
+		PASS,
+		EOF,
+	}
+
+	l := NewLexer(input)
+
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, expected, tok.Type)
+		}
+	}
+}
+
+func TestLexer_LineContinuation(t *testing.T) {
+	input := "func foo():\n\tvar x = 1 + \\\n2 - \\\r\n\t\t\t3\n\tpass"
+
+	expectedTokens := []TokenType{
+		FUNC, IDENT, LPAREN, RPAREN, COLON, NL, INDENT,
+		VAR, IDENT, ASSIGN, INT, PLUS, INT, MINUS, INT, NL,
+		PASS,
+		EOF,
+	}
+
+	l := NewLexer(input)
+
+	for i, expected := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Type != expected {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, expected, tok.Type)
+		}
+	}
+
+	if fmt.Sprint(l.Continuations()) != "[2 3]" {
+		t.Errorf("expected continuations on lines [2 3], got %v", l.Continuations())
+	}
+}
+
+func TestLexer_TokenPositions(t *testing.T) {
+	// Columns count characters from 1, a tab being one character
+	input := "if a <<= 1:\n\tb **= 2\nc"
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+		expectedOffset  int
+	}{
+		{IF, "if", 1, 1, 0},
+		{IDENT, "a", 1, 4, 3},
+		{LTLTEQ, "<<=", 1, 6, 5},
+		{INT, "1", 1, 10, 9},
+		{COLON, ":", 1, 11, 10},
+		{NL, "\n", 2, 1, 11},
+		{INDENT, "", 2, 2, 13},
+		{IDENT, "b", 2, 2, 13},
+		{POWEREQ, "**=", 2, 4, 15},
+		{INT, "2", 2, 8, 19},
+		{NL, "\n", 3, 1, 20},
+		{DEDENT, "", 3, 1, 21},
+		{IDENT, "c", 3, 1, 21},
+		{EOF, "", 3, 2, 22},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral ||
+			tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn || tok.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - expected %s %q at line %d column %d offset %d, got %s %q at line %d column %d offset %d",
+				i, tt.expectedType, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tt.expectedOffset,
+				tok.Type, tok.Literal, tok.Line, tok.Column, tok.Offset)
+		}
+	}
+}
+
+func TestLexer_CRLFAndByteOrderMark(t *testing.T) {
+	input := "\uFEFFfunc foo(): # comment\r\n\tvar s = \"a\"\r\n\r\n\tpass\r\n"
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{FUNC, "func", 1, 1},
+		{IDENT, "foo", 1, 6},
+		{LPAREN, "(", 1, 9},
+		{RPAREN, ")", 1, 10},
+		{COLON, ":", 1, 11},
+		{COMMENT, "# comment", 1, 13},
+		{NL, "\n", 2, 1},
+		{INDENT, "", 2, 2},
+		{VAR, "var", 2, 2},
+		{IDENT, "s", 2, 6},
+		{ASSIGN, "=", 2, 8},
+		{STRING, "\"a\"", 2, 10},
+		{NL, "\n", 3, 1},
+		{NL, "\n", 4, 1},
+		{PASS, "pass", 4, 2},
+		{NL, "\n", 5, 1},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral ||
+			tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - expected %s %q at line %d column %d, got %s %q at line %d column %d",
+				i, tt.expectedType, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn,
+				tok.Type, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestLexer_StringValues(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue string
+	}{
+		{`"plain"`, "plain"},
+		{`'single'`, "single"},
+		{`"tab\tnew\nline"`, "tab\tnew\nline"},
+		{`"say \"hi\" it\'s \\"`, `say "hi" it's \`},
+		{`"\u00e9\U01F600"`, "é😀"},
+		{`"unknown \q and short \u12"`, `unknown \q and short \u12`},
+		{"\"joined \\\nline\"", "joined line"},
+		{`"""triple "quoted" text"""`, `triple "quoted" text`},
+		{`r"C:\path\n"`, `C:\path\n`},
+		{`r'keep \' escaped'`, `keep \' escaped`},
+	}
+
+	for _, tt := range tests {
+		tok := NewLexer(tt.input).NextToken()
+		if tok.Type != STRING && tok.Type != RSTRING {
+			t.Fatalf("%s: expected a string token, got %s", tt.input, tok.Type)
+		}
+		if tok.Literal != tt.input {
+			t.Errorf("%s: expected the literal as written, got %q", tt.input, tok.Literal)
+		}
+		if tok.Value != tt.expectedValue {
+			t.Errorf("%s: expected value %q, got %q", tt.input, tt.expectedValue, tok.Value)
+		}
+	}
+}
@@ -3,6 +3,11 @@ package parser
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
 )
@@ -14,6 +19,18 @@ type Parser struct {
 	peekToken    Token
 	errors       []error
 	errorMode    ErrorMode
+	comments     []*ast.Comment
+	blankLines   []int
+	lastToken    Token // last token read from the lexer, comments included
+	// bracketed holds the comments within brackets, which never turn
+	// gdformat off or on as the expression around them is formatted whole
+	bracketed map[*ast.Comment]bool
+	// lastCode is the last token made current that is not a newline,
+	// indentation or semicolon, the end of the last statement parsed
+	lastCode Token
+	// functionDepth counts the function bodies being parsed, so declarations
+	// outside of any are class members
+	functionDepth int
 }
 
 // Error represents a parser error
@@ -21,6 +38,12 @@ type Error struct {
 	Line    int
 	Column  int
 	Message string
+	// Suggestion tells how to fix a common mistake, e.g. "did you mean '=='?";
+	// empty when the error has no known fix
+	Suggestion string
+	// Internal is set when the parser failed on the script rather than the
+	// script being invalid
+	Internal bool
 }
 
 func (e Error) Error() string {
@@ -65,7 +88,51 @@ func (p *Parser) Errors() []error {
 // nextToken advances to the next token
 func (p *Parser) nextToken() {
 	p.currentToken = p.peekToken
-	p.peekToken = p.lexer.NextToken()
+	p.peekToken = p.readToken()
+	switch p.currentToken.Type {
+	case NL, INDENT, DEDENT, SEMICOLON, EOF, "":
+	default:
+		p.lastCode = p.currentToken
+	}
+
+	// Comments are collected on the side so that the grammar never sees them
+	previous := p.currentToken
+	for p.peekToken.Type == COMMENT {
+		inline := previous.Line == p.peekToken.Line &&
+			previous.Type != NL && previous.Type != INDENT && previous.Type != DEDENT && previous.Type != ""
+		pos := ast.Position{Line: p.peekToken.Line, Column: p.peekToken.Column, Offset: p.peekToken.Offset}
+		comment := ast.NewComment(strings.TrimRight(p.peekToken.Literal, " \t\r"), pos, inline)
+		p.comments = append(p.comments, comment)
+		if p.lexer.bracketDepth > 0 {
+			if p.bracketed == nil {
+				p.bracketed = make(map[*ast.Comment]bool)
+			}
+			p.bracketed[comment] = true
+		}
+		previous = p.peekToken
+		p.peekToken = p.readToken()
+	}
+}
+
+// readToken reads the next token from the lexer, reporting unterminated
+// strings and recording empty lines: a newline right after another newline,
+// or at the start of the input
+func (p *Parser) readToken() Token {
+	tok := p.lexer.NextToken()
+	if p.lexer.Unterminated(tok) {
+		p.errors = append(p.errors, Error{
+			Line:       tok.Line,
+			Column:     tok.Column,
+			Message:    "unterminated string",
+			Suggestion: "close the string on the line it starts, or use triple quotes for a string spanning lines",
+		})
+	}
+	if tok.Type == NL && (p.lastToken.Type == NL || p.lastToken.Type == "") {
+		// The lexer has already counted the newline when it emits the token
+		p.blankLines = append(p.blankLines, tok.Line-1)
+	}
+	p.lastToken = tok
+	return tok
 }
 
 // expectPeek checks if the next token is of the expected type
@@ -103,7 +170,7 @@ func (p *Parser) synchronize() {
 
 		// Synchronize at the beginning of statements
 		switch p.currentToken.Type {
-		case CLASS, FUNC, VAR, CONST, IF, FOR, WHILE, MATCH, RETURN:
+		case CLASS, FUNC, VAR, CONST, SIGNAL, ENUM, IF, FOR, WHILE, MATCH, RETURN:
 			return
 		}
 
@@ -121,12 +188,120 @@ func (p *Parser) peekError(t TokenType) {
 	})
 }
 
-// Parse parses the input and returns an AST
-func (p *Parser) Parse() *ast.AbstractSyntaxTree {
-	tree := ast.NewAST()
+// expectColon expects the ':' ending the header of a compound statement,
+// e.g. "the if condition". A ':' missing at the end of the line is reported
+// there, and the block on the following lines is parsed as if it were written;
+// the current token is then left on the last token of the header.
+func (p *Parser) expectColon(header string) bool {
+	if p.peekToken.Type != NL && p.peekToken.Type != EOF {
+		return p.expectPeek(COLON)
+	}
+	line, column := tokenEnd(p.currentToken)
+	p.missingColon(line, column, header)
+	return true
+}
+
+// expectCurrentColon is expectColon for headers whose parser already moved
+// past their last token, e.g. function signatures: the current token is the
+// ':', or the end of the line when it is missing.
+func (p *Parser) expectCurrentColon(header string) bool {
+	switch p.currentToken.Type {
+	case COLON:
+		return true
+	case NL:
+		// The line of an NL token is the next one, its offset is the newline
+		before := p.lexer.input[:p.currentToken.Offset]
+		lineStart := strings.LastIndexByte(before, '\n') + 1
+		p.missingColon(strings.Count(before, "\n")+1, utf8.RuneCountInString(before[lineStart:])+1, header)
+		return true
+	}
+	return p.expectColon(header)
+}
+
+// missingColon reports the ':' missing at line and column after header
+func (p *Parser) missingColon(line, column int, header string) {
+	p.errors = append(p.errors, Error{
+		Line:       line,
+		Column:     column,
+		Message:    fmt.Sprintf("expected ':' after %s", header),
+		Suggestion: "add ':' at the end of the line",
+	})
+}
+
+// tokenEnd returns the line and column just after tok
+func tokenEnd(tok Token) (int, int) {
+	line, column := tok.Line, tok.Column
+	for _, r := range tok.Literal {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+// parseCondition parses the condition of an if, elif or while, the current
+// token being its first. An assignment written where a comparison is
+// expected is reported with a suggestion, and parsed as the comparison.
+func (p *Parser) parseCondition() ast.Expression {
+	condition := p.parseExpression(PREC_LOWEST)
+	if condition == nil || p.peekToken.Type != ASSIGN {
+		return condition
+	}
+	p.nextToken() // Move to '='
+	pos := ast.Position{Line: p.currentToken.Line, Column: p.currentToken.Column, Offset: p.currentToken.Offset}
+	p.errors = append(p.errors, Error{
+		Line:       pos.Line,
+		Column:     pos.Column,
+		Message:    "unexpected assignment in a condition",
+		Suggestion: "did you mean '=='? '=' assigns a value, '==' compares two",
+	})
+	p.nextToken() // Skip '='
+	right := p.parseExpression(PREC_LOWEST)
+	if right == nil {
+		return condition
+	}
+	return ast.NewInfixExpression(condition, "==", right, pos)
+}
+
+// indentationError reports a line indented wrongly, explaining the error
+// when the line mixes tabs and spaces
+func (p *Parser) indentationError(line, column int, message string) {
+	err := Error{Line: line, Column: column, Message: message}
+	if p.lexer.MixedIndentation(line) {
+		err.Message = "inconsistent indentation, the line mixes tabs and spaces"
+		err.Suggestion = "indent every line of the script with tabs only, or with spaces only"
+	}
+	p.errors = append(p.errors, err)
+}
+
+// Parse parses the input and returns an AST. A failure of the parser itself
+// is reported as an internal error at the token it stopped at, with an
+// empty tree.
+func (p *Parser) Parse() (tree *ast.AbstractSyntaxTree) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.errors = append(p.errors, Error{
+				Line:     p.currentToken.Line,
+				Column:   p.currentToken.Column,
+				Message:  fmt.Sprintf("internal parser error: %v", r),
+				Internal: true,
+			})
+			tree = ast.NewAST()
+			tree.RootClass = ast.NewClass("global scope", ast.Position{Line: 1, Column: 1})
+			tree.Classes = append(tree.Classes, tree.RootClass)
+		}
+	}()
+
+	tree = ast.NewAST()
 
 	// Parse the global scope
 	class := p.parseGlobalScope()
+	tree.Comments = p.comments
+	tree.BlankLines = p.blankLines
+	tree.LineContinuations = p.lexer.Continuations()
+	tree.Unformatted = unformattedRegions(p.lexer.input, p.comments, p.bracketed)
 	if class != nil {
 		tree.RootClass = class
 		tree.Classes = append(tree.Classes, class)
@@ -140,24 +315,115 @@ func (p *Parser) Parse() *ast.AbstractSyntaxTree {
 	return tree
 }
 
+// formatterDirective matches the comments turning gdformat off and back on
+var formatterDirective = regexp.MustCompile(`^#\s*gdformat\s*:\s*(off|on)\s*$`)
+
+// unformattedRegions returns the regions of input from each standalone
+// "# gdformat: off" comment to the line before the next "# gdformat: on"
+// comment, or to the end of input, with their text and without the blank
+// lines ending them. The "on" comment is formatted like any other, so that it
+// is placed the same whether the code before it is copied or not. Directives
+// within brackets are ignored.
+func unformattedRegions(input string, comments []*ast.Comment, bracketed map[*ast.Comment]bool) []ast.SourceRegion {
+	var regions []ast.SourceRegion
+	lines := strings.Split(input, "\n")
+	start := 0
+	for _, comment := range comments {
+		match := formatterDirective.FindStringSubmatch(strings.TrimSpace(comment.Text))
+		if match == nil || comment.Inline || bracketed[comment] {
+			continue
+		}
+		if match[1] == "off" && start == 0 {
+			start = comment.Pos.Line
+		} else if match[1] == "on" && start > 0 {
+			regions = append(regions, sourceRegion(lines, start, comment.Pos.Line-1))
+			start = 0
+		}
+	}
+	if start > 0 {
+		regions = append(regions, sourceRegion(lines, start, len(lines)))
+	}
+	return regions
+}
+
+// sourceRegion returns the region of lines from start to end, both included
+// and numbered from 1, up to its last line that is not blank, with \n line
+// endings. Carriage returns ending a line
+// are dropped whether they are part of its line ending or not, as the
+// formatter writes the line endings back.
+func sourceRegion(lines []string, start, end int) ast.SourceRegion {
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	var text []string
+	for _, line := range lines[start-1 : end] {
+		text = append(text, strings.TrimRight(line, "\r"))
+	}
+	return ast.SourceRegion{StartLine: start, EndLine: end, Text: strings.Join(text, "\n")}
+}
+
 // parseGlobalScope parses the global scope as a class
 func (p *Parser) parseGlobalScope() *ast.Class {
 	class := ast.NewClass("global scope", ast.Position{Line: 1, Column: 1})
 
 	// Parse statements until EOF
+	ended := true
 	for p.currentToken.Type != EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			// Check if this is a function and add it to both statements and functions
-			if function, ok := stmt.(*ast.Function); ok {
-				class.AddFunction(function)
-			} else if classStmt, ok := stmt.(*ast.Class); ok {
-				// Add class as a subclass to the global scope
-				class.AddSubClass(classStmt)
-			} else {
-				class.AddStatement(stmt)
+		// The script's header statements may share their line, as in
+		// "class_name Foo extends Node"
+		if p.currentToken.Type == EXTENDS && (p.peekToken.Type == IDENT || p.peekToken.Type == STRING) {
+			p.expectStatementStart(ended)
+			ended = true
+			class.ExtendsPos = ast.Position{
+				Line:   p.currentToken.Line,
+				Column: p.currentToken.Column,
+				Offset: p.currentToken.Offset,
 			}
+			p.nextToken()
+			class.Extends = p.currentToken.Literal
+			p.nextToken()
+			continue
+		}
+		if p.currentToken.Type == CLASS_NAME && p.peekToken.Type == IDENT {
+			p.expectStatementStart(ended)
+			ended = true
+			class.ClassNamePos = ast.Position{
+				Line:   p.currentToken.Line,
+				Column: p.currentToken.Column,
+				Offset: p.currentToken.Offset,
+			}
+			p.nextToken()
+			class.ClassName = p.currentToken.Literal
+			p.nextToken()
+			continue
+		}
+		if p.currentToken.Type == AT && scriptAnnotations[p.peekToken.Literal] {
+			p.expectStatementStart(ended)
+			ended = true
+			if annotation := p.parseAnnotation(); annotation != nil {
+				class.AddAnnotation(annotation)
+			}
+			p.nextToken()
+			continue
+		}
+
+		switch p.currentToken.Type {
+		case NL, SEMICOLON, DEDENT:
+		case INDENT:
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: "unexpected indentation",
+			})
+			p.parseIndentedItems(func() { p.parseClassMember(class) })
+		default:
+			p.expectStatementStart(ended)
+			errors := len(p.errors)
+			p.parseClassMember(class)
+			// A statement in error is not reported again
+			ended = len(p.errors) > errors
 		}
+		ended = ended || endsStatement(p.currentToken)
 		p.nextToken()
 	}
 
@@ -170,32 +436,65 @@ func (p *Parser) parseStatement() ast.Statement {
 	case PASS:
 		return p.parsePassStatement()
 	case RETURN:
-		return p.parseReturnStatement()
+		return statement(p.parseReturnStatement())
 	case VAR:
-		return p.parseVarStatement()
+		return statement(p.parseVarStatement())
 	case CONST:
-		return p.parseConstStatement()
+		return statement(p.parseConstStatement())
+	case STATIC:
+		return p.parseStaticStatement()
+	case SIGNAL:
+		return statement(p.parseSignalStatement())
+	case ENUM:
+		return statement(p.parseEnumStatement())
+	case AT:
+		return p.parseAnnotatedStatement()
 	case FUNC:
-		return p.parseFunctionDefinition()
+		return statement(p.parseFunctionDefinition())
 	case CLASS:
-		return p.parseClassDefinition()
+		return statement(p.parseClassDefinition())
 	case IF:
-		return p.parseIfStatement()
+		return statement(p.parseIfStatement())
 	case WHILE:
-		return p.parseWhileStatement()
+		return statement(p.parseWhileStatement())
 	case FOR:
-		return p.parseForStatement()
+		return statement(p.parseForStatement())
 	case MATCH:
-		return p.parseMatchStatement()
+		return statement(p.parseMatchStatement())
 	case BREAK:
-		return p.parseBreakStatement()
+		return statement(p.parseBreakStatement())
 	case CONTINUE:
-		return p.parseContinueStatement()
-	case IDENT, INT, FLOAT, STRING, RSTRING, TRUE, FALSE, NULL, SELF, LPAREN:
+		return statement(p.parseContinueStatement())
+	case BREAKPOINT:
+		return statement(p.parseBreakpointStatement())
+	case IDENT:
+		// assert is a statement, though written like a call
+		if p.currentToken.Literal == "assert" && p.peekToken.Type == LPAREN {
+			return statement(p.parseAssertStatement())
+		}
+		return p.parseExpressionStatement()
+	case ILLEGAL:
+		if p.currentToken.Literal == "invalid indentation" {
+			p.indentationError(p.currentToken.Line, p.currentToken.Column, "indentation does not match any outer block")
+			return nil
+		}
 		return p.parseExpressionStatement()
 	default:
+		// Anything else starts an expression, or is reported as not doing so
+		return p.parseExpressionStatement()
+	}
+}
+
+// statement returns the result of a statement parser as an ast.Statement,
+// keeping a failed parse nil rather than a nil pointer in a non-nil interface
+func statement[T any, S interface {
+	*T
+	ast.Statement
+}](stmt S) ast.Statement {
+	if stmt == nil {
 		return nil
 	}
+	return stmt
 }
 
 // parsePassStatement parses a pass statement
@@ -247,15 +546,77 @@ func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
 	return ast.NewContinueStatement(pos)
 }
 
+// parseBreakpointStatement parses a breakpoint statement
+func (p *Parser) parseBreakpointStatement() *ast.BreakpointStatement {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+	return ast.NewBreakpointStatement(pos)
+}
+
+// parseAssertStatement parses an assert statement, made of a condition and
+// an optional message in parentheses
+func (p *Parser) parseAssertStatement() *ast.AssertStatement {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	call, ok := p.parseExpression(PREC_LOWEST).(*ast.CallExpression)
+	if !ok || len(call.Arguments) < 1 || len(call.Arguments) > 2 {
+		p.errors = append(p.errors, Error{
+			Line:    pos.Line,
+			Column:  pos.Column,
+			Message: "expected assert(condition) or assert(condition, message)",
+		})
+
+		if p.errorMode == ErrorModePanic {
+			p.synchronize()
+		}
+
+		return nil
+	}
+
+	var message ast.Expression
+	if len(call.Arguments) == 2 {
+		message = call.Arguments[1]
+	}
+	stmt := ast.NewAssertStatement(pos, call.Arguments[0], message)
+
+	if p.peekToken.Type == SEMICOLON {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // parseExpressionStatement parses an expression statement
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	pos := ast.Position{
 		Line:   p.currentToken.Line,
 		Column: p.currentToken.Column,
 		Offset: p.currentToken.Offset,
 	}
 
-	stmt := ast.NewExpressionStatement(pos, p.parseExpression(PREC_LOWEST))
+	expr := p.parseExpression(PREC_LOWEST)
+	if expr == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected expression, got %s", p.currentToken.Type),
+		})
+		return nil
+	}
+	if assignmentOperators[p.peekToken.Type] {
+		expr = p.parseAssignment(expr)
+		if expr == nil {
+			return nil
+		}
+	}
+	stmt := ast.NewExpressionStatement(pos, expr)
 
 	if p.peekToken.Type == SEMICOLON {
 		p.nextToken()
@@ -264,40 +625,104 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-// Precedence levels for operators
+// assignmentOperators are the operators of assignment statements. GDScript
+// has no assignment expressions: an assignment is a whole statement.
+var assignmentOperators = map[TokenType]bool{
+	ASSIGN: true, PLUSEQ: true, MINUSEQ: true, ASTERISKEQ: true, SLASHEQ: true, PERCENTEQ: true,
+	POWEREQ: true, AMPEQ: true, PIPEEQ: true, CARETEQ: true, LTLTEQ: true, GTGTEQ: true,
+}
+
+// parseAssignment parses the operator and value of an assignment statement
+// to target; the current token is the last one of target
+func (p *Parser) parseAssignment(target ast.Expression) ast.Expression {
+	p.nextToken()
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+	operator := p.currentToken.Literal
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.DotExpression, *ast.IndexExpression:
+	default:
+		p.errors = append(p.errors, Error{
+			Line:    pos.Line,
+			Column:  pos.Column,
+			Message: fmt.Sprintf("invalid target of '%s': only variables, attributes and subscripts can be assigned", operator),
+		})
+		return nil
+	}
+
+	p.nextToken()
+	value := p.parseExpression(PREC_LOWEST)
+	if value == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected expression after '%s', got %s", operator, p.currentToken.Type),
+		})
+		return nil
+	}
+	if assignmentOperators[p.peekToken.Type] {
+		p.errors = append(p.errors, Error{
+			Line:    p.peekToken.Line,
+			Column:  p.peekToken.Column,
+			Message: "assignment is not allowed inside an expression",
+		})
+		// The rest of the chain is skipped so that it is not reported again
+		// as a statement of its own
+		for assignmentOperators[p.peekToken.Type] {
+			p.nextToken()
+			p.nextToken()
+			if p.parseExpression(PREC_LOWEST) == nil {
+				break
+			}
+		}
+		return nil
+	}
+
+	return ast.NewAssignmentExpression(target, operator, value, pos)
+}
+
+// Precedence levels for operators, from loosest to tightest as in the
+// GDScript reference
 const (
-	PREC_LOWEST     = iota
-	PREC_ASSIGN     // =, +=, -=, etc.
-	PREC_LOGICAL    // and, or
-	PREC_COMPARISON // ==, !=, <, >, <=, >=
-	PREC_BITWISE    // &, |, ^
-	PREC_SUM        // +, -
-	PREC_PRODUCT    // *, /, %
-	PREC_PREFIX     // -X, !X
-	PREC_CALL       // myFunction(X)
-	PREC_INDEX      // array[index]
-	PREC_DOT        // obj.property
+	PREC_LOWEST       = iota
+	PREC_CAST         // x as Type
+	PREC_TERNARY      // x if cond else y
+	PREC_OR           // or, ||
+	PREC_AND          // and, &&
+	PREC_NOT          // not x, !x
+	PREC_CONTENT_TEST // in, not in
+	PREC_COMPARISON   // ==, !=, <, >, <=, >=
+	PREC_BIT_OR       // |
+	PREC_BIT_XOR      // ^
+	PREC_BIT_AND      // &
+	PREC_SHIFT        // <<, >>
+	PREC_SUM          // +, -
+	PREC_PRODUCT      // *, /, %
+	PREC_SIGN         // -x, +x
+	PREC_BIT_NOT      // ~x
+	PREC_POWER        // **
+	PREC_TYPE_TEST    // x is Type, x is not Type
+	PREC_AWAIT        // await x
+	PREC_CALL         // myFunction(X)
+	PREC_INDEX        // array[index]
+	PREC_DOT          // obj.property
 )
 
-// Operator precedence map
+// Operator precedence map. NOT only appears between operands in 'not in'.
 var precedences = map[TokenType]int{
-	ASSIGN:     PREC_ASSIGN,
-	PLUSEQ:     PREC_ASSIGN,
-	MINUSEQ:    PREC_ASSIGN,
-	ASTERISKEQ: PREC_ASSIGN,
-	SLASHEQ:    PREC_ASSIGN,
-	PERCENTEQ:  PREC_ASSIGN,
-	AMPEQ:      PREC_ASSIGN,
-	PIPEEQ:     PREC_ASSIGN,
-	CARETEQ:    PREC_ASSIGN,
-	LTLTEQ:     PREC_ASSIGN,
-	GTGTEQ:     PREC_ASSIGN,
-	POWEREQ:    PREC_ASSIGN,
-
-	OR:       PREC_LOGICAL,
-	AND:      PREC_LOGICAL,
-	PIPEPIPE: PREC_LOGICAL,
-	AMPAMP:   PREC_LOGICAL,
+	AS: PREC_CAST,
+
+	OR:       PREC_OR,
+	PIPEPIPE: PREC_OR,
+	AND:      PREC_AND,
+	AMPAMP:   PREC_AND,
+
+	IN:  PREC_CONTENT_TEST,
+	NOT: PREC_CONTENT_TEST,
 
 	EQ:     PREC_COMPARISON,
 	NOT_EQ: PREC_COMPARISON,
@@ -306,11 +731,11 @@ var precedences = map[TokenType]int{
 	LTE:    PREC_COMPARISON,
 	GTE:    PREC_COMPARISON,
 
-	BITAND: PREC_BITWISE,
-	BITOR:  PREC_BITWISE,
-	BITXOR: PREC_BITWISE,
-	LTLT:   PREC_BITWISE,
-	GTGT:   PREC_BITWISE,
+	BITOR:  PREC_BIT_OR,
+	CARET:  PREC_BIT_XOR,
+	BITAND: PREC_BIT_AND,
+	LTLT:   PREC_SHIFT,
+	GTGT:   PREC_SHIFT,
 
 	PLUS:  PREC_SUM,
 	MINUS: PREC_SUM,
@@ -318,7 +743,9 @@ var precedences = map[TokenType]int{
 	ASTERISK: PREC_PRODUCT,
 	SLASH:    PREC_PRODUCT,
 	PERCENT:  PREC_PRODUCT,
-	POWER:    PREC_PRODUCT,
+
+	POWER: PREC_POWER,
+	IS:    PREC_TYPE_TEST,
 
 	LPAREN:   PREC_CALL,
 	LBRACKET: PREC_INDEX,
@@ -333,20 +760,30 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	switch p.currentToken.Type {
 	case IDENT:
 		leftExp = p.parseIdentifier()
-	case INT:
+	case INT, HEX, BIN:
 		leftExp = p.parseIntegerLiteral()
 	case FLOAT:
 		leftExp = p.parseFloatLiteral()
-	case STRING:
+	case STRING, RSTRING:
 		leftExp = p.parseStringLiteral()
 	case TRUE, FALSE:
 		leftExp = p.parseBooleanLiteral()
 	case NULL:
 		leftExp = p.parseNullLiteral()
+	case SELF:
+		leftExp = p.parseSelfExpression()
 	case LPAREN:
 		leftExp = p.parseGroupedExpression()
-	case MINUS, BANG:
+	case LBRACKET:
+		leftExp = p.parseArrayLiteral()
+	case LBRACE:
+		leftExp = p.parseDictionaryLiteral()
+	case MINUS, PLUS, BANG, NOT, BITNOT:
 		leftExp = p.parsePrefixExpression()
+	case AWAIT:
+		leftExp = p.parseAwaitExpression()
+	case DOLLAR, PERCENT:
+		leftExp = p.parseGetNodeExpression()
 	default:
 		return nil
 	}
@@ -360,9 +797,17 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		p.peekToken.Type != COLON && p.peekToken.Type != RPAREN && p.peekToken.Type != COMMA &&
 		p.peekToken.Type != RBRACE && p.peekToken.Type != RBRACKET {
 
-		// Special case: conditional expression (ternary operator)
+		// Special case: conditional expression (ternary operator). 'if' is not
+		// in the precedence table, so an operand of a tighter operator stops here
+		// and the whole operator expression becomes the ternary's true value.
 		if p.peekToken.Type == IF {
+			if precedence >= PREC_TERNARY {
+				break
+			}
 			leftExp = p.parseConditionalExpression(leftExp)
+			if leftExp == nil {
+				return nil
+			}
 			continue
 		}
 
@@ -372,31 +817,16 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 			break
 		}
 
-		// Only continue if we have a valid infix operator
-		isInfixOp := false
-		switch p.peekToken.Type {
-		case PLUS, MINUS, ASTERISK, SLASH, PERCENT, POWER:
-			isInfixOp = true
-		case EQ, NOT_EQ, LT, GT, LTE, GTE:
-			isInfixOp = true
-		case AND, OR:
-			isInfixOp = true
-		case ASSIGN, PLUSEQ, MINUSEQ, ASTERISKEQ, SLASHEQ:
-			isInfixOp = true
-		case LPAREN:
-			isInfixOp = true
-		case LBRACKET:
-			isInfixOp = true
-		case DOT:
-			isInfixOp = true
-		}
-
-		if !isInfixOp {
+		// Every token with a precedence is an infix operator
+		if _, isInfixOp := precedences[p.peekToken.Type]; !isInfixOp {
 			break
 		}
 
 		p.nextToken()
 		leftExp = p.parseInfixExpression(leftExp)
+		if leftExp == nil {
+			return nil
+		}
 	}
 
 	return leftExp
@@ -415,33 +845,164 @@ func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
 		Function: fn,
 	}
 
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments, exp.TrailingComma = p.parseExpressionList(RPAREN)
 	return exp
 }
 
-// parseCallArguments parses function call arguments
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+// parseExpressionList parses comma-separated expressions up to the closing
+// token end, allowing a trailing comma, and reports whether there is one; the
+// current token is the opening bracket
+func (p *Parser) parseExpressionList(end TokenType) (list []ast.Expression, trailingComma bool) {
+	list = []ast.Expression{}
+
+	for p.peekToken.Type != end {
+		p.nextToken()
+		expr := p.parseExpression(PREC_LOWEST)
+		if expr == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected expression, got %s", p.currentToken.Type),
+			})
+			return nil, false
+		}
+		list = append(list, expr)
+		trailingComma = false
+
+		if p.peekToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+		trailingComma = true
+	}
+
+	if !p.expectPeek(end) {
+		return nil, false
+	}
+	return list, trailingComma
+}
+
+// parseArrayLiteral parses an array literal ([1, 2, 3])
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := ast.NewArrayLiteral(ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	})
+
+	elements, trailingComma := p.parseExpressionList(RBRACKET)
+	if elements == nil {
+		return nil
+	}
+	array.TrailingComma = trailingComma
+	for _, element := range elements {
+		array.AddElement(element)
+	}
+	return array
+}
 
-	if p.peekToken.Type == RPAREN {
+// parseTypeHint parses the type starting at the current identifier, such as
+// int, SubClass.NamedEnum or Dictionary[String, Array[int]], and returns it
+// spelled the way the formatter prints it. The current token is left on the
+// last token of the type.
+func (p *Parser) parseTypeHint() (string, bool) {
+	typeHint := p.currentToken.Literal
+	for p.peekToken.Type == DOT {
 		p.nextToken()
-		return args
+		if !p.expectPeek(IDENT) {
+			return "", false
+		}
+		typeHint += "." + p.currentToken.Literal
+	}
+	if p.peekToken.Type != LBRACKET {
+		return typeHint, true
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(PREC_LOWEST))
+	var elements []string
+	for {
+		if !p.expectPeek(IDENT) {
+			return "", false
+		}
+		element, ok := p.parseTypeHint()
+		if !ok {
+			return "", false
+		}
+		elements = append(elements, element)
+		if p.peekToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(RBRACKET) {
+		return "", false
+	}
+	return typeHint + "[" + strings.Join(elements, ", ") + "]", true
+}
 
-	for p.peekToken.Type == COMMA {
+// parseDictionaryLiteral parses a dictionary literal ({"key": value} or {key = value})
+func (p *Parser) parseDictionaryLiteral() ast.Expression {
+	dict := ast.NewDictionaryLiteral(ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	})
+
+	for p.peekToken.Type != RBRACE {
+		p.nextToken()
+		key := p.parseExpression(PREC_LOWEST)
+		if key == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected dictionary key, got %s", p.currentToken.Type),
+			})
+			return nil
+		}
+		// The first pair decides between {"key": value} and {key = value}
+		_, isName := key.(*ast.Identifier)
+		if len(dict.Keys) == 0 {
+			dict.LuaStyle = isName && p.peekToken.Type == ASSIGN
+		}
+		if dict.LuaStyle {
+			if !isName {
+				p.errors = append(p.errors, Error{
+					Line:    p.currentToken.Line,
+					Column:  p.currentToken.Column,
+					Message: "expected identifier as key of a {key = value} dictionary",
+				})
+				return nil
+			}
+			if !p.expectPeek(ASSIGN) {
+				return nil
+			}
+		} else if !p.expectPeek(COLON) {
+			return nil
+		}
 		p.nextToken()
+		value := p.parseExpression(PREC_LOWEST)
+		if value == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected dictionary value, got %s", p.currentToken.Type),
+			})
+			return nil
+		}
+		dict.AddPair(key, value)
+		dict.TrailingComma = false
+
+		if p.peekToken.Type != COMMA {
+			break
+		}
 		p.nextToken()
-		args = append(args, p.parseExpression(PREC_LOWEST))
+		dict.TrailingComma = true
 	}
 
-	if !p.expectPeek(RPAREN) {
+	if !p.expectPeek(RBRACE) {
 		return nil
 	}
-
-	return args
+	return dict
 }
 
 // parseIdentifier parses an identifier expression
@@ -458,48 +1019,58 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	}
 }
 
-// parseIntegerLiteral parses an integer literal
+// parseIntegerLiteral parses a decimal, hexadecimal or binary integer literal,
+// keeping its original spelling, e.g. 0xFF_FF, for the formatter
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-	return &ast.NumberLiteral{
-		BaseExpression: ast.BaseExpression{
-			Pos: ast.Position{
-				Line:   p.currentToken.Line,
-				Column: p.currentToken.Column,
-				Offset: p.currentToken.Offset,
-			},
-		},
-		IsInt:    true,
-		Original: p.currentToken.Literal,
+	literal := p.currentToken.Literal
+	digits, base := literal, 10
+	switch p.currentToken.Type {
+	case HEX:
+		digits, base = literal[2:], 16
+	case BIN:
+		digits, base = literal[2:], 2
+	}
+	// Literals beyond the signed range wrap around, as in Godot
+	value, err := strconv.ParseUint(strings.ReplaceAll(digits, "_", ""), base, 64)
+	if err != nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("invalid integer literal %s", literal),
+		})
 	}
+	return ast.NewIntLiteral(int64(value), literal, ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	})
 }
 
 // parseFloatLiteral parses a float literal
 func (p *Parser) parseFloatLiteral() ast.Expression {
-	return &ast.NumberLiteral{
-		BaseExpression: ast.BaseExpression{
-			Pos: ast.Position{
-				Line:   p.currentToken.Line,
-				Column: p.currentToken.Column,
-				Offset: p.currentToken.Offset,
-			},
-		},
-		IsInt:    false,
-		Original: p.currentToken.Literal,
+	literal := p.currentToken.Literal
+	value, err := strconv.ParseFloat(strings.ReplaceAll(literal, "_", ""), 64)
+	if err != nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("invalid float literal %s", literal),
+		})
 	}
+	return ast.NewFloatLiteral(value, literal, ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	})
 }
 
 // parseStringLiteral parses a string literal
 func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{
-		BaseExpression: ast.BaseExpression{
-			Pos: ast.Position{
-				Line:   p.currentToken.Line,
-				Column: p.currentToken.Column,
-				Offset: p.currentToken.Offset,
-			},
-		},
-		Value: p.currentToken.Literal,
-	}
+	return ast.NewStringLiteral(p.currentToken.Literal, p.currentToken.Value, ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	})
 }
 
 // parseBooleanLiteral parses a boolean literal
@@ -529,6 +1100,15 @@ func (p *Parser) parseNullLiteral() ast.Expression {
 	}
 }
 
+// parseSelfExpression parses the 'self' keyword
+func (p *Parser) parseSelfExpression() ast.Expression {
+	return ast.NewSelfExpression(ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	})
+}
+
 // parseGroupedExpression parses a grouped expression (parentheses)
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken() // consume '('
@@ -560,11 +1140,120 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 	p.nextToken()
 
-	expression.Right = p.parseExpression(PREC_PREFIX)
+	// 'not' binds looser than comparisons: not a == b is not (a == b), while
+	// -x ** 2 is -(x ** 2)
+	precedence := PREC_SIGN
+	switch expression.Operator {
+	case "not", "!":
+		precedence = PREC_NOT
+	case "~":
+		precedence = PREC_BIT_NOT
+	}
+	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected expression after '%s', got %s", expression.Operator, p.currentToken.Type),
+		})
+		return nil
+	}
 
 	return expression
 }
 
+// parseAwaitExpression parses await x, which binds tighter than any operator
+// but calls, subscripts and attributes: await a.b() + 1 is (await a.b()) + 1
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	p.nextToken()
+	operand := p.parseExpression(PREC_AWAIT)
+	if operand == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected expression after 'await', got %s", p.currentToken.Type),
+		})
+		return nil
+	}
+
+	return ast.NewAwaitExpression(operand, pos)
+}
+
+// parseGetNodeExpression parses the get_node() shorthands $Path, $"Path" and
+// %Name. A path is made of the tokens written right after each other, so
+// $Sprite/Label is a path while $Sprite / 2 divides it.
+func (p *Parser) parseGetNodeExpression() ast.Expression {
+	start := p.currentToken
+	pos := ast.Position{
+		Line:   start.Line,
+		Column: start.Column,
+		Offset: start.Offset,
+	}
+
+	path := ""
+	if start.Type == PERCENT {
+		path = "%"
+	}
+	raw := start.Literal
+	for p.peekToken.Offset == p.currentToken.Offset+len(p.currentToken.Literal) && continuesNodePath(path, p.peekToken) {
+		p.nextToken()
+		raw += p.currentToken.Literal
+		if p.currentToken.Type == STRING {
+			path += p.currentToken.Value
+			break
+		}
+		path += p.currentToken.Literal
+	}
+
+	if path == "" || path == "%" || strings.HasSuffix(path, "/") {
+		p.errors = append(p.errors, Error{
+			Line:    p.peekToken.Line,
+			Column:  p.peekToken.Column,
+			Message: fmt.Sprintf("expected a node path after '%s', got %s", raw, p.peekToken.Type),
+		})
+		return nil
+	}
+
+	return ast.NewGetNodeExpression(raw, path, pos)
+}
+
+// continuesNodePath reports whether tok continues the node path read so far:
+// a quoted path stands alone, names, '.' and '..' start a segment and '/'
+// ends one. A scene unique name is a segment starting with '%'.
+func continuesNodePath(path string, tok Token) bool {
+	segmentStart := path == "" || strings.HasSuffix(path, "/")
+	switch {
+	case tok.Type == STRING:
+		return path == ""
+	case tok.Type == SLASH:
+		return !segmentStart && path != "%"
+	case tok.Type == PERCENT:
+		return segmentStart
+	case tok.Type == DOT:
+		return segmentStart || path == "." || strings.HasSuffix(path, "/.")
+	case tok.Type == INT || isNodeName(tok.Literal):
+		return segmentStart || strings.HasSuffix(path, "%")
+	default:
+		return false
+	}
+}
+
+// isNodeName reports whether literal is a word, as identifiers and keywords are
+func isNodeName(literal string) bool {
+	for i, c := range literal {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return literal != ""
+}
+
 // parseInfixExpression parses infix expressions like x + y and function calls
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	// Handle function calls
@@ -572,6 +1261,14 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		return p.parseCallExpression(left)
 	}
 
+	// Handle attribute access and subscripts
+	if p.currentToken.Type == DOT {
+		return p.parseDotExpression(left)
+	}
+	if p.currentToken.Type == LBRACKET {
+		return p.parseIndexExpression(left)
+	}
+
 	// Handle regular infix expressions
 	expression := &ast.InfixExpression{
 		BaseExpression: ast.BaseExpression{
@@ -586,10 +1283,91 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	switch p.currentToken.Type {
+	case NOT:
+		// 'not' between operands only starts 'not in'
+		if !p.expectPeek(IN) {
+			return nil
+		}
+		expression.Operator = "not in"
+	case IS:
+		if p.peekToken.Type == NOT {
+			p.nextToken()
+			expression.Operator = "is not"
+		}
+		// The right operand is a type, such as Node, Node2D.Thing or Array[int]
+		precedence = PREC_TYPE_TEST
+	case AS:
+		precedence = PREC_TYPE_TEST
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected expression after '%s', got %s", expression.Operator, p.currentToken.Type),
+		})
+		return nil
+	}
+
+	return expression
+}
+
+// parseDotExpression parses attribute access (obj.property)
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	p.nextToken() // Skip '.'
+
+	// Keywords such as get/set are valid property names
+	if p.currentToken.Type != IDENT && !isKeywordToken(p.currentToken) {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected property name after '.', got %s", p.currentToken.Type),
+		})
+		return nil
+	}
+
+	return ast.NewDotExpression(left, p.currentToken.Literal, pos)
+}
+
+// parseIndexExpression parses subscript expressions (array[index])
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	p.nextToken() // Skip '['
+
+	index := p.parseExpression(PREC_LOWEST)
+	if index == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: "expected index expression",
+		})
+		return nil
+	}
+
+	if !p.expectPeek(RBRACKET) {
+		return nil
+	}
 
-	return expression
+	return ast.NewIndexExpression(left, index, pos)
+}
+
+// isKeywordToken reports whether the token is a keyword spelled like an identifier
+func isKeywordToken(tok Token) bool {
+	_, ok := keywords[tok.Literal]
+	return ok
 }
 
 // peekPrecedence returns the precedence of the peek token
@@ -619,9 +1397,6 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	// Skip 'var' token
 	p.nextToken()
 
-	// Skip any newlines between 'var' and variable name
-	p.skipNewlines()
-
 	// Parse variable name
 	if p.currentToken.Type != IDENT {
 		p.errors = append(p.errors, Error{
@@ -640,6 +1415,9 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	varName := p.currentToken.Literal
 	isTyped := false
 	stmt := ast.NewVarStatement(pos, varName, isTyped)
+	if p.functionDepth == 0 {
+		stmt.SetClassLevel()
+	}
 
 	// Move past the identifier
 	p.nextToken()
@@ -663,9 +1441,12 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 			return nil
 		}
 
-		typeHint := p.currentToken.Literal
+		typeHint, ok := p.parseTypeHint()
+		if !ok {
+			return nil
+		}
 		stmt.SetTypeHint(typeHint)
-		p.nextToken() // Skip type identifier
+		p.nextToken() // Skip type
 	} else if p.currentToken.Type == COLONASSIGN {
 		// Type inference (:=)
 		isTyped = true
@@ -674,6 +1455,14 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 
 		// Parse the value expression for type inference
 		value := p.parseExpression(PREC_LOWEST)
+		if value == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected expression after ':=', got %s", p.currentToken.Type),
+			})
+			return nil
+		}
 		stmt.SetValue(value)
 		return stmt
 	}
@@ -682,15 +1471,91 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	if p.currentToken.Type == ASSIGN {
 		p.nextToken() // Skip equals sign
 		value := p.parseExpression(PREC_LOWEST)
+		if value == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected expression after '=', got %s", p.currentToken.Type),
+			})
+			return nil
+		}
 		stmt.SetValue(value)
 
 		// After parsing expression, advance past it
 		p.nextToken()
 	}
+	if !p.expectStatementEnd("variable declaration") {
+		return nil
+	}
 
 	return stmt
 }
 
+// expectStatementEnd reports the current token unless it ends the statement
+// just parsed, as what the statement declares: the end of its line or of the
+// script, or a semicolon
+func (p *Parser) expectStatementEnd(what string) bool {
+	if endsStatement(p.currentToken) {
+		return true
+	}
+	p.errors = append(p.errors, Error{
+		Line:    p.currentToken.Line,
+		Column:  p.currentToken.Column,
+		Message: fmt.Sprintf("expected end of statement after %s, got %s", what, p.currentToken.Type),
+	})
+	return false
+}
+
+// parseStaticStatement parses a static function or a static variable, which
+// belongs to the class rather than to its instances
+func (p *Parser) parseStaticStatement() ast.Statement {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	switch p.peekToken.Type {
+	case FUNC:
+		return statement(p.parseFunctionDefinition())
+	case VAR:
+		if p.functionDepth > 0 {
+			p.errors = append(p.errors, Error{
+				Line:    pos.Line,
+				Column:  pos.Column,
+				Message: "static variables can only be declared in a class body",
+			})
+
+			if p.errorMode == ErrorModePanic {
+				p.synchronize()
+			}
+
+			return nil
+		}
+
+		p.nextToken() // Skip 'static'
+		stmt := p.parseVarStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Pos = pos
+		stmt.IsStatic = true
+		return stmt
+	}
+
+	p.errors = append(p.errors, Error{
+		Line:    p.peekToken.Line,
+		Column:  p.peekToken.Column,
+		Message: fmt.Sprintf("expected 'func' or 'var' after 'static', got %s", p.peekToken.Type),
+	})
+
+	if p.errorMode == ErrorModePanic {
+		p.synchronize()
+	}
+
+	return nil
+}
+
 // parseConstStatement parses a constant declaration statement
 func (p *Parser) parseConstStatement() *ast.VarStatement {
 	pos := ast.Position{
@@ -702,9 +1567,6 @@ func (p *Parser) parseConstStatement() *ast.VarStatement {
 	// Skip 'const' token
 	p.nextToken()
 
-	// Skip any newlines between 'const' and constant name
-	p.skipNewlines()
-
 	// Parse constant name
 	if p.currentToken.Type != IDENT {
 		p.errors = append(p.errors, Error{
@@ -724,6 +1586,9 @@ func (p *Parser) parseConstStatement() *ast.VarStatement {
 	isTyped := false
 	stmt := ast.NewVarStatement(pos, constName, isTyped)
 	stmt.SetConst()
+	if p.functionDepth == 0 {
+		stmt.SetClassLevel()
+	}
 
 	// Move past the identifier
 	p.nextToken()
@@ -747,13 +1612,20 @@ func (p *Parser) parseConstStatement() *ast.VarStatement {
 			return nil
 		}
 
-		typeHint := p.currentToken.Literal
+		typeHint, ok := p.parseTypeHint()
+		if !ok {
+			return nil
+		}
 		stmt.SetTypeHint(typeHint)
-		p.nextToken() // Skip type identifier
+		p.nextToken() // Skip type
 	}
 
-	// Constants must have an assignment
-	if p.currentToken.Type != ASSIGN {
+	// Constants must have an assignment, which infers their type (:=) when
+	// they have no type hint
+	operator := p.currentToken
+	if operator.Type == COLONASSIGN && !isTyped {
+		stmt.SetInferred()
+	} else if operator.Type != ASSIGN {
 		p.errors = append(p.errors, Error{
 			Line:    p.currentToken.Line,
 			Column:  p.currentToken.Column,
@@ -767,16 +1639,208 @@ func (p *Parser) parseConstStatement() *ast.VarStatement {
 		return nil
 	}
 
-	p.nextToken() // Skip equals sign
+	p.nextToken() // Skip the assignment operator
 	value := p.parseExpression(PREC_LOWEST)
+	if value == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected expression after '%s', got %s", operator.Literal, p.currentToken.Type),
+		})
+		return nil
+	}
 	stmt.SetValue(value)
 
 	// After parsing expression, advance past it
 	p.nextToken()
+	if !p.expectStatementEnd("constant declaration") {
+		return nil
+	}
+
+	return stmt
+}
+
+// scriptAnnotations are the annotations that apply to the script itself
+// rather than to the declaration after them
+var scriptAnnotations = map[string]bool{
+	"tool":          true,
+	"icon":          true,
+	"static_unload": true,
+}
+
+// parseAnnotation parses an annotation such as @export or @export_range(0, 10),
+// leaving the current token at its last token
+func (p *Parser) parseAnnotation() *ast.Annotation {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	annotation := ast.NewAnnotation(p.currentToken.Literal, pos)
+
+	if p.peekToken.Type == LPAREN {
+		p.nextToken()
+		args, _ := p.parseExpressionList(RPAREN)
+		if args == nil {
+			return nil
+		}
+		for _, arg := range args {
+			annotation.AddArg(arg)
+		}
+	} else if p.peekToken.Type == STRING || p.peekToken.Type == INT || p.peekToken.Type == FLOAT {
+		p.parseUnparenthesizedAnnotationArgs(annotation)
+	}
+	return annotation
+}
+
+// parseUnparenthesizedAnnotationArgs parses the arguments of an annotation
+// written like a statement, as in "@export_range 0, 10", reporting the
+// missing parentheses with the annotation as it should be written
+func (p *Parser) parseUnparenthesizedAnnotationArgs(annotation *ast.Annotation) {
+	start := p.peekToken
+	for {
+		p.nextToken()
+		arg := p.parseExpression(PREC_LOWEST)
+		if arg == nil {
+			return
+		}
+		annotation.AddArg(arg)
+		if p.peekToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+	args := p.lexer.input[start.Offset : p.currentToken.Offset+len(p.currentToken.Literal)]
+	p.errors = append(p.errors, Error{
+		Line:       start.Line,
+		Column:     start.Column,
+		Message:    fmt.Sprintf("arguments of annotation '@%s' must be in parentheses", annotation.Name),
+		Suggestion: fmt.Sprintf("did you mean @%s(%s)?", annotation.Name, args),
+	})
+}
+
+// parseAnnotatedStatement parses the annotations before a statement, on its
+// line or on lines of their own, and the statement they apply to
+func (p *Parser) parseAnnotatedStatement() ast.Statement {
+	var annotations []*ast.Annotation
+	for p.currentToken.Type == AT {
+		annotation := p.parseAnnotation()
+		if annotation == nil {
+			return nil
+		}
+		if scriptAnnotations[annotation.Name] {
+			p.errors = append(p.errors, Error{
+				Line:       annotation.Pos.Line,
+				Column:     annotation.Pos.Column,
+				Message:    fmt.Sprintf("annotation '@%s' applies to the script, not to a declaration", annotation.Name),
+				Suggestion: "put it on a line of its own at the top of the script",
+			})
+		}
+		annotations = append(annotations, annotation)
+		p.nextToken()
+		p.skipNewlines()
+	}
 
+	stmt := p.parseStatement()
+	target, ok := stmt.(interface{ AddAnnotation(*ast.Annotation) })
+	if !ok {
+		p.errors = append(p.errors, Error{
+			Line:    annotations[0].Pos.Line,
+			Column:  annotations[0].Pos.Column,
+			Message: fmt.Sprintf("expected a statement after annotation '@%s'", annotations[len(annotations)-1].Name),
+		})
+		return nil
+	}
+	for _, annotation := range annotations {
+		target.AddAnnotation(annotation)
+	}
 	return stmt
 }
 
+// parseSignalStatement parses a signal declaration
+func (p *Parser) parseSignalStatement() *ast.SignalStatement {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+	signal := ast.NewSignalStatement(pos, p.currentToken.Literal)
+
+	if p.peekToken.Type == LPAREN {
+		p.nextToken()
+		params, _ := p.parseParameterList()
+		for _, param := range params {
+			signal.AddParameter(param)
+		}
+	}
+	return signal
+}
+
+// parseEnumStatement parses a named or unnamed enum declaration, leaving the
+// current token at its closing brace
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
+
+	name := ""
+	if p.peekToken.Type == IDENT {
+		p.nextToken()
+		name = p.currentToken.Literal
+	}
+	if !p.expectPeek(LBRACE) {
+		return nil
+	}
+	enum := ast.NewEnumStatement(pos, name)
+
+	for p.peekToken.Type != RBRACE {
+		if !p.expectPeek(IDENT) {
+			return nil
+		}
+		element := &ast.EnumElement{
+			Pos: ast.Position{
+				Line:   p.currentToken.Line,
+				Column: p.currentToken.Column,
+				Offset: p.currentToken.Offset,
+			},
+			Name: p.currentToken.Literal,
+		}
+		if p.peekToken.Type == ASSIGN {
+			p.nextToken() // Skip the name
+			p.nextToken() // Skip '='
+			element.Value = p.parseExpression(PREC_LOWEST)
+			if element.Value == nil {
+				p.errors = append(p.errors, Error{
+					Line:    p.currentToken.Line,
+					Column:  p.currentToken.Column,
+					Message: fmt.Sprintf("expected enum value, got %s", p.currentToken.Type),
+				})
+				return nil
+			}
+		}
+		enum.AddElement(element)
+
+		if p.peekToken.Type != COMMA {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(RBRACE) {
+		return nil
+	}
+	return enum
+}
+
 // parseFunctionDefinition parses a function definition
 func (p *Parser) parseFunctionDefinition() *ast.Function {
 	startPos := ast.Position{
@@ -830,7 +1894,11 @@ func (p *Parser) parseFunctionDefinition() *ast.Function {
 		return nil
 	}
 
-	p.parseParameterList(function)
+	params, trailingComma := p.parseParameterList()
+	for _, param := range params {
+		function.AddParameter(param)
+	}
+	function.TrailingComma = trailingComma
 
 	// Check for return type - FIXED: Check current token, not peek
 	if p.currentToken.Type == ARROW {
@@ -850,74 +1918,52 @@ func (p *Parser) parseFunctionDefinition() *ast.Function {
 			return nil
 		}
 
-		function.ReturnType = p.currentToken.Literal
-		p.nextToken() // Skip return type
-	}
-
-	// Expect colon
-	if p.currentToken.Type != COLON {
-		if !p.expectPeek(COLON) {
+		returnType, ok := p.parseTypeHint()
+		if !ok {
 			return nil
 		}
+		function.ReturnType = returnType
+		p.nextToken() // Skip return type
 	}
 
-	// Parse function body - handle flexible indentation
-	// Skip any newlines
-	for p.peekToken.Type == NL || p.peekToken.Type == INDENT {
-		p.nextToken()
-	}
-
-	// Try to expect indentation, but if we find statement tokens, proceed anyway
-	if p.peekToken.Type == INDENT {
-		p.nextToken() // consume INDENT
-	} else if p.peekToken.Type == VAR || p.peekToken.Type == RETURN || p.peekToken.Type == PASS {
-		// If we find statement tokens directly, the lexer handled indentation implicitly
-		// This is acceptable - proceed with statement parsing
-	} else if p.peekToken.Type == EOF {
-		// If we reach EOF, there's no body to parse
-		return function
-	} else {
-		p.errors = append(p.errors, Error{
-			Line:    p.peekToken.Line,
-			Column:  p.peekToken.Column,
-			Message: fmt.Sprintf("expected indentation or statement, got %s", p.peekToken.Type),
-		})
+	// Expect colon
+	if !p.expectCurrentColon("the function signature") {
 		return nil
 	}
 
-	// Parse statements until dedent
-	for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-		// Skip any newlines or indentation tokens before parsing statements
-		if p.currentToken.Type == NL {
-			p.nextToken()
-			continue
-		}
-
-		stmt := p.parseStatement()
-		if stmt != nil {
+	p.functionDepth++
+	defer func() { p.functionDepth-- }()
+	if !p.parseBlock(func() {
+		if stmt := p.parseStatement(); stmt != nil {
 			function.AddStatement(stmt)
 		}
-		p.nextToken()
+	}) {
+		return nil
 	}
+	line, column := tokenEnd(p.lastCode)
+	function.End = ast.Position{Line: line, Column: column, Offset: p.lastCode.Offset + len(p.lastCode.Literal)}
 
 	return function
 }
 
-// parseParameterList parses the parameter list of a function
-func (p *Parser) parseParameterList(function *ast.Function) {
+// parseParameterList parses the parameter list of a function or a signal,
+// leaving the current token after the closing parenthesis, and reports
+// whether the list ends with a trailing comma
+func (p *Parser) parseParameterList() (params []*ast.Parameter, trailingComma bool) {
+	params = []*ast.Parameter{}
 	p.nextToken() // Skip '('
 
 	if p.currentToken.Type == RPAREN {
-		p.nextToken() // Skip ')'
-		return        // Empty parameter list
+		p.nextToken()        // Skip ')'
+		return params, false // Empty parameter list
 	}
 
 	// Parse first parameter
 	param := p.parseParameter()
 	if param == nil {
-		return // Error already reported by parseParameter
+		return params, false // Error already reported by parseParameter
 	}
-	function.AddParameter(param)
+	params = append(params, param)
 
 	// Parse additional parameters
 	for p.currentToken.Type == COMMA {
@@ -925,14 +1971,15 @@ func (p *Parser) parseParameterList(function *ast.Function) {
 
 		// Check for trailing comma
 		if p.currentToken.Type == RPAREN {
+			trailingComma = true
 			break
 		}
 
 		param = p.parseParameter()
 		if param == nil {
-			return // Error already reported by parseParameter
+			return params, false // Error already reported by parseParameter
 		}
-		function.AddParameter(param)
+		params = append(params, param)
 	}
 
 	// Must end with right parenthesis
@@ -942,9 +1989,10 @@ func (p *Parser) parseParameterList(function *ast.Function) {
 			Column:  p.currentToken.Column,
 			Message: "expected ')' at end of parameter list",
 		})
-		return
+		return params, false
 	}
 	p.nextToken() // Skip ')'
+	return params, trailingComma
 }
 
 // parseParameter parses a single parameter
@@ -981,7 +2029,11 @@ func (p *Parser) parseParameter() *ast.Parameter {
 			})
 			return nil
 		}
-		param.TypeHint = p.currentToken.Literal
+		typeHint, ok := p.parseTypeHint()
+		if !ok {
+			return nil
+		}
+		param.TypeHint = typeHint
 		p.nextToken() // Skip type name
 	}
 
@@ -1015,9 +2067,6 @@ func (p *Parser) parseClassDefinition() *ast.Class {
 	// Skip 'class' token
 	p.nextToken()
 
-	// Skip any newlines between 'class' and class name
-	p.skipNewlines()
-
 	// Parse class name
 	if p.currentToken.Type != IDENT {
 		p.errors = append(p.errors, Error{
@@ -1041,9 +2090,14 @@ func (p *Parser) parseClassDefinition() *ast.Class {
 
 	// Check for extends
 	if p.currentToken.Type == EXTENDS {
+		class.ExtendsPos = ast.Position{
+			Line:   p.currentToken.Line,
+			Column: p.currentToken.Column,
+			Offset: p.currentToken.Offset,
+		}
 		p.nextToken() // Skip extends keyword
 
-		if p.currentToken.Type != IDENT {
+		if p.currentToken.Type != IDENT && p.currentToken.Type != STRING {
 			p.errors = append(p.errors, Error{
 				Line:    p.currentToken.Line,
 				Column:  p.currentToken.Column,
@@ -1062,62 +2116,172 @@ func (p *Parser) parseClassDefinition() *ast.Class {
 	}
 
 	// Expect colon
-	if p.currentToken.Type != COLON {
-		if !p.expectPeek(COLON) {
-			return nil
+	if !p.expectCurrentColon("the class declaration") {
+		return nil
+	}
+
+	// Parse class body statements; they are class members even when the
+	// class is nested in a function body
+	functionDepth := p.functionDepth
+	p.functionDepth = 0
+	defer func() { p.functionDepth = functionDepth }()
+	if !p.parseBlock(func() { p.parseClassMember(class) }) {
+		return nil
+	}
+
+	return class
+}
+
+// parseClassMember parses a statement of the body of class into it
+func (p *Parser) parseClassMember(class *ast.Class) {
+	switch p.currentToken.Type {
+	case EXTENDS:
+		// The parent class may also be given by a statement of the body
+		pos := ast.Position{
+			Line:   p.currentToken.Line,
+			Column: p.currentToken.Column,
+			Offset: p.currentToken.Offset,
+		}
+		if class.Extends != "" {
+			p.errors = append(p.errors, Error{
+				Line:    pos.Line,
+				Column:  pos.Column,
+				Message: fmt.Sprintf("class %s already extends %s", class.Name, class.Extends),
+			})
+		}
+		if p.peekToken.Type != IDENT && p.peekToken.Type != STRING {
+			p.errors = append(p.errors, Error{
+				Line:    p.peekToken.Line,
+				Column:  p.peekToken.Column,
+				Message: fmt.Sprintf("expected parent class name, got %s", p.peekToken.Type),
+			})
+			return
+		}
+		p.nextToken()
+		class.Extends, class.ExtendsPos = p.currentToken.Literal, pos
+	case FUNC:
+		function := p.parseFunctionDefinition()
+		if function != nil {
+			class.AddFunction(function)
+		}
+	case CLASS:
+		subClass := p.parseClassDefinition()
+		if subClass != nil {
+			class.AddSubClass(subClass)
+		}
+	default:
+		// Annotated functions and classes are parsed as statements
+		switch stmt := p.parseStatement().(type) {
+		case nil:
+		case *ast.Function:
+			class.AddFunction(stmt)
+		case *ast.Class:
+			class.AddSubClass(stmt)
+		default:
+			class.AddStatement(stmt)
+		}
+	}
+}
+
+// parseBlock parses the block of a compound statement, whose ':' is the
+// current token, calling parseItem on the first token of each statement.
+// parseItem leaves the current token on the last token it parsed. A block on
+// the following lines runs from its INDENT to the matching DEDENT, which is
+// left as the current token; a block on the line of the ':' holds the
+// statements up to the end of the line, separated by ';', and leaves the
+// current token on the newline ending it.
+func (p *Parser) parseBlock(parseItem func()) bool {
+	// The current token is the end of the line when the ':' is missing
+	if p.currentToken.Type != NL && p.peekToken.Type != NL && p.peekToken.Type != EOF {
+		p.nextToken() // Skip ':'
+		for {
+			parseItem()
+			if p.peekToken.Type == SEMICOLON {
+				p.nextToken()
+			}
+			if p.currentToken.Type != SEMICOLON || p.peekToken.Type == NL || p.peekToken.Type == EOF {
+				break
+			}
+			p.nextToken() // Skip ';'
+		}
+		// Blank lines are skipped so that the caller sees the token after them
+		for p.peekToken.Type == NL {
+			p.nextToken()
 		}
+		return true
 	}
 
-	// Parse class body - handle flexible indentation
-	// Skip any newlines
 	for p.peekToken.Type == NL {
 		p.nextToken()
 	}
-
-	// Try to expect indentation, but if we find statement tokens, proceed anyway
-	if p.peekToken.Type == INDENT {
-		p.nextToken() // consume INDENT
-	} else if p.peekToken.Type == VAR || p.peekToken.Type == FUNC || p.peekToken.Type == PASS || p.peekToken.Type == CLASS {
-		// If we find statement tokens directly, the lexer handled indentation implicitly
-		// This is acceptable - proceed with statement parsing
-	} else {
-		p.errors = append(p.errors, Error{
-			Line:    p.peekToken.Line,
-			Column:  p.peekToken.Column,
-			Message: fmt.Sprintf("expected indentation or statement, got %s", p.peekToken.Type),
-		})
-		return nil
+	if p.peekToken.Type != INDENT {
+		p.indentationError(p.peekToken.Line, p.peekToken.Column,
+			fmt.Sprintf("expected an indented block, got %s", p.peekToken.Type))
+		return false
 	}
+	p.nextToken() // Move to INDENT
+	p.nextToken() // Skip INDENT
+	// Trailing whitespace at the end of the script indents nothing
+	if p.currentToken.Type == DEDENT || p.currentToken.Type == EOF {
+		p.indentationError(p.currentToken.Line, p.currentToken.Column,
+			fmt.Sprintf("expected an indented block, got %s", p.currentToken.Type))
+		return false
+	}
+
+	p.parseItems(parseItem)
+	return true
+}
+
+// parseIndentedItems parses the statements from an unexpected INDENT, the
+// current token, to its DEDENT
+func (p *Parser) parseIndentedItems(parseItem func()) {
+	p.nextToken() // Skip INDENT
+	p.parseItems(parseItem)
+}
 
-	// Parse class body statements until dedent
+// parseItems parses the statements of a block on its own lines, from the
+// current token to the DEDENT ending the block. Over-indented lines are
+// reported and parsed as part of the block.
+func (p *Parser) parseItems(parseItem func()) {
+	ended := true
 	for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-		// Skip any newlines or indentation tokens before parsing statements
-		if p.currentToken.Type == NL || p.currentToken.Type == INDENT {
-			p.nextToken()
-			continue
-		}
-
 		switch p.currentToken.Type {
-		case FUNC:
-			function := p.parseFunctionDefinition()
-			if function != nil {
-				class.AddFunction(function)
-			}
-		case CLASS:
-			subClass := p.parseClassDefinition()
-			if subClass != nil {
-				class.AddSubClass(subClass)
-			}
+		case NL, SEMICOLON:
+		case INDENT:
+			p.indentationError(p.currentToken.Line, p.currentToken.Column, "unexpected indentation")
+			p.parseIndentedItems(parseItem)
 		default:
-			stmt := p.parseStatement()
-			if stmt != nil {
-				class.AddStatement(stmt)
-			}
+			p.expectStatementStart(ended)
+			errors := len(p.errors)
+			parseItem()
+			// A statement in error is not reported again
+			ended = len(p.errors) > errors
 		}
+		ended = ended || endsStatement(p.currentToken)
 		p.nextToken()
 	}
+}
 
-	return class
+// endsStatement reports whether tok ends a statement: statement parsers
+// leave either the last token of the statement or the one ending it current
+func endsStatement(tok Token) bool {
+	switch tok.Type {
+	case NL, SEMICOLON, EOF, DEDENT:
+		return true
+	}
+	return false
+}
+
+// expectStatementStart reports the current token, which a statement is
+// about to be parsed from, unless the statement before it ended
+func (p *Parser) expectStatementStart(ended bool) {
+	if !ended {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: fmt.Sprintf("expected end of statement, got %s", p.currentToken.Type),
+		})
+	}
 }
 
 // parseIfStatement parses an if statement
@@ -1132,7 +2296,7 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	p.nextToken()
 
 	// Parse condition
-	condition := p.parseExpression(PREC_LOWEST)
+	condition := p.parseCondition()
 	if condition == nil {
 		p.errors = append(p.errors, Error{
 			Line:    p.currentToken.Line,
@@ -1150,58 +2314,29 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	stmt := ast.NewIfStatement(pos, condition)
 
 	// Expect colon
-	if !p.expectPeek(COLON) {
-		return nil
-	}
-
-	// Parse if body - handle flexible indentation
-	// Skip any newlines
-	for p.peekToken.Type == NL {
-		p.nextToken()
-	}
-
-	// Try to expect indentation, but if we find statement tokens, proceed anyway
-	if p.peekToken.Type == INDENT {
-		p.nextToken() // consume INDENT
-	} else if p.peekToken.Type == VAR || p.peekToken.Type == RETURN || p.peekToken.Type == PASS ||
-		p.peekToken.Type == IF || p.peekToken.Type == FOR || p.peekToken.Type == WHILE {
-		// If we find statement tokens directly, the lexer handled indentation implicitly
-		// This is acceptable - proceed with statement parsing
-	} else {
-		p.errors = append(p.errors, Error{
-			Line:    p.peekToken.Line,
-			Column:  p.peekToken.Column,
-			Message: fmt.Sprintf("expected indentation or statement, got %s", p.peekToken.Type),
-		})
+	if !p.expectColon("the if condition") {
 		return nil
 	}
 
-	// Parse if body
-	for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-		// Skip any newlines or indentation tokens before parsing statements
-		if p.currentToken.Type == NL || p.currentToken.Type == INDENT {
-			p.nextToken()
-			continue
-		}
-
-		bodyStmt := p.parseStatement()
-		if bodyStmt != nil {
+	if !p.parseBlock(func() {
+		if bodyStmt := p.parseStatement(); bodyStmt != nil {
 			stmt.AddConsequenceStatement(bodyStmt)
 		}
-		p.nextToken()
-	}
-
-	// After the if body, advance past DEDENT to check for elif/else at same indentation level
-	if p.currentToken.Type == DEDENT {
-		p.nextToken() // Skip DEDENT to get to same level as 'if'
+	}) {
+		return nil
 	}
 
-	// Check for elif branches
-	for p.currentToken.Type == ELIF {
+	// An elif or else belongs to this if when it follows the end of its block
+	for p.peekToken.Type == ELIF {
+		p.nextToken() // Move to 'elif'
+		elifPos := ast.Position{
+			Line:   p.currentToken.Line,
+			Column: p.currentToken.Column,
+			Offset: p.currentToken.Offset,
+		}
 		p.nextToken() // Skip 'elif'
 
-		// Parse elif condition
-		elifCondition := p.parseExpression(PREC_LOWEST)
+		elifCondition := p.parseCondition()
 		if elifCondition == nil {
 			p.errors = append(p.errors, Error{
 				Line:    p.currentToken.Line,
@@ -1216,104 +2351,41 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 			return nil
 		}
 
-		// Expect colon
-		if !p.expectPeek(COLON) {
+		if !p.expectColon("the elif condition") {
 			return nil
 		}
 
-		// Parse elif body - handle flexible indentation
-		// Skip any newlines
-		for p.peekToken.Type == NL {
-			p.nextToken()
-		}
-
-		// Try to expect indentation, but if we find statement tokens, proceed anyway
-		if p.peekToken.Type == INDENT {
-			p.nextToken() // consume INDENT
-		}
-
-		// Parse elif body
 		elifBody := make([]ast.Statement, 0)
-		for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-			// Skip any newlines or indentation tokens before parsing statements
-			if p.currentToken.Type == NL || p.currentToken.Type == INDENT {
-				p.nextToken()
-				continue
-			}
-
-			elifStmt := p.parseStatement()
-			if elifStmt != nil {
+		if !p.parseBlock(func() {
+			if elifStmt := p.parseStatement(); elifStmt != nil {
 				elifBody = append(elifBody, elifStmt)
 			}
-			p.nextToken()
-		}
-
-		stmt.AddElseIfBranch(elifCondition, elifBody)
-
-		// After elif body, advance past DEDENT for next elif/else
-		if p.currentToken.Type == DEDENT {
-			p.nextToken()
-		}
-	}
-
-	// Check for else branch
-	if p.currentToken.Type == ELSE {
-		p.nextToken() // Skip 'else'
-
-		// Expect colon - check current token first
-		if p.currentToken.Type == COLON {
-			// Already have colon, no need to advance
-		} else if p.peekToken.Type == COLON {
-			if !p.expectPeek(COLON) {
-				return nil
-			}
-		} else {
-			p.errors = append(p.errors, Error{
-				Line:    p.currentToken.Line,
-				Column:  p.currentToken.Column,
-				Message: fmt.Sprintf("expected ':' after 'else', got %s", p.currentToken.Type),
-			})
+		}) {
 			return nil
 		}
+		stmt.AddElseIfBranch(elifPos, elifCondition, elifBody)
+	}
 
-		// Parse else body - handle flexible indentation like if body
-		// Skip any newlines
-		for p.peekToken.Type == NL {
-			p.nextToken()
+	if p.peekToken.Type == ELSE {
+		p.nextToken() // Move to 'else'
+		stmt.ElsePos = ast.Position{
+			Line:   p.currentToken.Line,
+			Column: p.currentToken.Column,
+			Offset: p.currentToken.Offset,
 		}
 
-		// Try to expect indentation, but if we find statement tokens, proceed anyway
-		if p.peekToken.Type == INDENT {
-			p.nextToken() // consume INDENT
-		} else if p.peekToken.Type == VAR || p.peekToken.Type == RETURN || p.peekToken.Type == PASS ||
-			p.peekToken.Type == IF || p.peekToken.Type == FOR || p.peekToken.Type == WHILE || p.peekToken.Type == IDENT {
-			// If we find statement tokens directly, the lexer handled indentation implicitly
-			// This is acceptable - proceed with statement parsing
-		} else {
-			p.errors = append(p.errors, Error{
-				Line:    p.peekToken.Line,
-				Column:  p.peekToken.Column,
-				Message: fmt.Sprintf("expected indentation or statement after else, got %s", p.peekToken.Type),
-			})
+		if !p.expectColon("else") {
 			return nil
 		}
 
-		// Parse else body
 		elseBody := make([]ast.Statement, 0)
-		for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-			// Skip any newlines or indentation tokens before parsing statements
-			if p.currentToken.Type == NL || p.currentToken.Type == INDENT {
-				p.nextToken()
-				continue
-			}
-
-			elseStmt := p.parseStatement()
-			if elseStmt != nil {
+		if !p.parseBlock(func() {
+			if elseStmt := p.parseStatement(); elseStmt != nil {
 				elseBody = append(elseBody, elseStmt)
 			}
-			p.nextToken()
+		}) {
+			return nil
 		}
-
 		stmt.SetAlternative(elseBody)
 	}
 
@@ -1378,8 +2450,11 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 			return nil
 		}
 
-		typeHint = p.currentToken.Literal
-		p.nextToken() // Skip type identifier
+		var ok bool
+		if typeHint, ok = p.parseTypeHint(); !ok {
+			return nil
+		}
+		p.nextToken() // Skip type
 	}
 
 	// Expect 'in' keyword
@@ -1425,45 +2500,16 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	}
 
 	// Expect colon
-	if !p.expectPeek(COLON) {
-		return nil
-	}
-
-	// Parse for body - handle flexible indentation
-	// Skip any newlines
-	for p.peekToken.Type == NL {
-		p.nextToken()
-	}
-
-	// Try to expect indentation, but if we find statement tokens, proceed anyway
-	if p.peekToken.Type == INDENT {
-		p.nextToken() // consume INDENT
-	} else if p.peekToken.Type == VAR || p.peekToken.Type == RETURN || p.peekToken.Type == PASS ||
-		p.peekToken.Type == IF || p.peekToken.Type == FOR || p.peekToken.Type == WHILE {
-		// If we find statement tokens directly, the lexer handled indentation implicitly
-		// This is acceptable - proceed with statement parsing
-	} else {
-		p.errors = append(p.errors, Error{
-			Line:    p.peekToken.Line,
-			Column:  p.peekToken.Column,
-			Message: fmt.Sprintf("expected indentation or statement, got %s", p.peekToken.Type),
-		})
+	if !p.expectColon("the for loop") {
 		return nil
 	}
 
-	// Parse for loop body
-	for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-		// Skip any newlines or indentation tokens before parsing statements
-		if p.currentToken.Type == NL || p.currentToken.Type == INDENT {
-			p.nextToken()
-			continue
-		}
-
-		bodyStmt := p.parseStatement()
-		if bodyStmt != nil {
+	if !p.parseBlock(func() {
+		if bodyStmt := p.parseStatement(); bodyStmt != nil {
 			stmt.AddBodyStatement(bodyStmt)
 		}
-		p.nextToken()
+	}) {
+		return nil
 	}
 
 	return stmt
@@ -1481,7 +2527,7 @@ func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	p.nextToken()
 
 	// Parse condition
-	condition := p.parseExpression(PREC_LOWEST)
+	condition := p.parseCondition()
 	if condition == nil {
 		p.errors = append(p.errors, Error{
 			Line:    p.currentToken.Line,
@@ -1499,45 +2545,16 @@ func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	stmt := ast.NewWhileStatement(pos, condition)
 
 	// Expect colon
-	if !p.expectPeek(COLON) {
-		return nil
-	}
-
-	// Parse while body - handle flexible indentation
-	// Skip any newlines
-	for p.peekToken.Type == NL {
-		p.nextToken()
-	}
-
-	// Try to expect indentation, but if we find statement tokens, proceed anyway
-	if p.peekToken.Type == INDENT {
-		p.nextToken() // consume INDENT
-	} else if p.peekToken.Type == VAR || p.peekToken.Type == RETURN || p.peekToken.Type == PASS ||
-		p.peekToken.Type == IF || p.peekToken.Type == FOR || p.peekToken.Type == WHILE {
-		// If we find statement tokens directly, the lexer handled indentation implicitly
-		// This is acceptable - proceed with statement parsing
-	} else {
-		p.errors = append(p.errors, Error{
-			Line:    p.peekToken.Line,
-			Column:  p.peekToken.Column,
-			Message: fmt.Sprintf("expected indentation or statement, got %s", p.peekToken.Type),
-		})
+	if !p.expectColon("the while condition") {
 		return nil
 	}
 
-	// Parse while loop body
-	for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-		// Skip any newlines or indentation tokens before parsing statements
-		if p.currentToken.Type == NL || p.currentToken.Type == INDENT {
-			p.nextToken()
-			continue
-		}
-
-		bodyStmt := p.parseStatement()
-		if bodyStmt != nil {
+	if !p.parseBlock(func() {
+		if bodyStmt := p.parseStatement(); bodyStmt != nil {
 			stmt.AddBodyStatement(bodyStmt)
 		}
-		p.nextToken()
+	}) {
+		return nil
 	}
 
 	return stmt
@@ -1573,118 +2590,242 @@ func (p *Parser) parseMatchStatement() *ast.MatchStatement {
 	stmt := ast.NewMatchStatement(pos, value)
 
 	// Expect colon
-	if !p.expectPeek(COLON) {
+	if !p.expectColon("the match value") {
 		return nil
 	}
 
-	// Parse match body - handle flexible indentation
-	// Skip any newlines
-	for p.peekToken.Type == NL {
-		p.nextToken()
+	if !p.parseBlock(func() {
+		if branch := p.parseMatchBranch(); branch != nil {
+			stmt.AddBranch(branch)
+		}
+	}) {
+		return nil
+	}
+	// A block of semicolons alone holds no branch
+	if len(stmt.Branches) == 0 {
+		p.errors = append(p.errors, Error{
+			Line:    pos.Line,
+			Column:  pos.Column,
+			Message: "expected a match branch",
+		})
+		return nil
 	}
 
-	// Try to expect indentation, but if we find statement tokens, proceed anyway
-	if p.peekToken.Type == INDENT {
-		p.nextToken() // consume INDENT
-	} else {
-		// For match statements, we can proceed directly to parsing patterns
-		// Move to the next token to start parsing the first pattern
-		p.nextToken()
+	return stmt
+}
+
+// parseMatchBranch parses a branch of a match statement: its patterns, its
+// guard and its block
+func (p *Parser) parseMatchBranch() *ast.MatchBranch {
+	branchPos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
 	}
 
-	// Parse match branches
-	for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-		// Parse pattern
-		pattern := p.parseExpression(PREC_LOWEST)
-		if pattern == nil {
+	pattern := p.parsePattern()
+	if pattern == nil {
+		p.errors = append(p.errors, Error{
+			Line:    p.currentToken.Line,
+			Column:  p.currentToken.Column,
+			Message: "expected pattern expression in match branch",
+		})
+
+		if p.errorMode == ErrorModePanic {
+			p.synchronize()
+		}
+
+		return nil
+	}
+
+	branch := ast.NewMatchBranch(branchPos, pattern)
+
+	// Parse additional comma-separated patterns (multipattern)
+	for p.peekToken.Type == COMMA {
+		p.nextToken() // Move to ','
+		p.nextToken() // Skip ','
+
+		alternative := p.parsePattern()
+		if alternative == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: "expected pattern after ',' in match branch",
+			})
+			return nil
+		}
+		branch.AddPattern(alternative)
+	}
+
+	// Check for guard condition (when)
+	if p.peekToken.Type == WHEN {
+		p.nextToken() // Move to 'when'
+		p.nextToken() // Skip 'when'
+
+		guard := p.parseExpression(PREC_LOWEST)
+		if guard == nil {
 			p.errors = append(p.errors, Error{
 				Line:    p.currentToken.Line,
 				Column:  p.currentToken.Column,
-				Message: "expected pattern expression in match branch",
+				Message: "expected guard expression after 'when'",
 			})
 
 			if p.errorMode == ErrorModePanic {
 				p.synchronize()
 			}
 
-			break
+			return nil
 		}
 
-		branch := ast.NewMatchBranch(ast.Position{
-			Line:   p.currentToken.Line,
-			Column: p.currentToken.Column,
-			Offset: p.currentToken.Offset,
-		}, pattern)
+		branch.SetGuard(guard)
+	}
 
-		// Check for guard condition (when)
-		if p.peekToken.Type == WHEN {
-			p.nextToken() // Move to 'when'
-			p.nextToken() // Skip 'when'
+	if !p.expectColon("the match pattern") {
+		return nil
+	}
 
-			guard := p.parseExpression(PREC_LOWEST)
-			if guard == nil {
-				p.errors = append(p.errors, Error{
-					Line:    p.currentToken.Line,
-					Column:  p.currentToken.Column,
-					Message: "expected guard expression after 'when'",
-				})
+	if !p.parseBlock(func() {
+		if bodyStmt := p.parseStatement(); bodyStmt != nil {
+			branch.AddBodyStatement(bodyStmt)
+		}
+	}) {
+		return nil
+	}
 
-				if p.errorMode == ErrorModePanic {
-					p.synchronize()
-				}
+	return branch
+}
 
-				break
-			}
+// parsePattern parses a single match pattern
+func (p *Parser) parsePattern() ast.Expression {
+	pos := ast.Position{
+		Line:   p.currentToken.Line,
+		Column: p.currentToken.Column,
+		Offset: p.currentToken.Offset,
+	}
 
-			branch.SetGuard(guard)
+	switch p.currentToken.Type {
+	case VAR:
+		if !p.expectPeek(IDENT) {
+			return nil
+		}
+		return ast.NewBindingPattern(p.currentToken.Literal, pos)
+	case DOT:
+		if !p.expectPeek(DOT) {
+			return nil
+		}
+		return ast.NewRestPattern(pos)
+	case LBRACKET:
+		return p.parseArrayPattern(pos)
+	case LBRACE:
+		return p.parseDictionaryPattern(pos)
+	case IDENT:
+		if p.currentToken.Literal == "_" && p.peekToken.Type != DOT {
+			return ast.NewWildcardPattern(pos)
 		}
+	}
 
-		// Expect colon
-		if !p.expectPeek(COLON) {
-			break
+	return p.parseExpression(PREC_LOWEST)
+}
+
+// parseArrayPattern parses an array pattern ([a, var b, ..])
+func (p *Parser) parseArrayPattern(pos ast.Position) ast.Expression {
+	pattern := ast.NewArrayPattern(pos)
+
+	p.nextToken() // Skip '['
+	p.skipPatternWhitespace()
+
+	for p.currentToken.Type != RBRACKET {
+		element := p.parsePattern()
+		if element == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: "expected pattern in array pattern",
+			})
+			return nil
 		}
+		pattern.AddElement(element)
 
-		// Handle branch body
-		if p.peekToken.Type == NL {
-			// Multi-line branch body
-			p.nextToken() // Skip newline
+		p.nextToken()
+		p.skipPatternWhitespace()
+		if p.currentToken.Type == COMMA {
+			p.nextToken()
+			p.skipPatternWhitespace()
+		} else if p.currentToken.Type != RBRACKET {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected ',' or ']' in array pattern, got %s", p.currentToken.Type),
+			})
+			return nil
+		}
+	}
 
-			// Expect indentation
-			if !p.expectPeek(INDENT) {
-				break
-			}
+	return pattern
+}
 
-			// Parse branch body
-			for p.currentToken.Type != DEDENT && p.currentToken.Type != EOF {
-				bodyStmt := p.parseStatement()
-				if bodyStmt != nil {
-					branch.AddBodyStatement(bodyStmt)
-				}
-				p.nextToken()
-			}
-		} else {
-			// Single-line branch body
-			p.nextToken() // Move past colon
+// parseDictionaryPattern parses a dictionary pattern ({"key": value, "other", ..})
+func (p *Parser) parseDictionaryPattern(pos ast.Position) ast.Expression {
+	pattern := ast.NewDictionaryPattern(pos)
 
-			bodyStmt := p.parseStatement()
-			if bodyStmt != nil {
-				branch.AddBodyStatement(bodyStmt)
-			}
+	p.nextToken() // Skip '{'
+	p.skipPatternWhitespace()
 
-			// Skip to next line
-			for p.currentToken.Type != NL && p.currentToken.Type != EOF {
-				p.nextToken()
-			}
+	for p.currentToken.Type != RBRACE {
+		var key ast.Expression
+		if p.currentToken.Type == DOT {
+			key = p.parsePattern()
+		} else {
+			key = p.parseExpression(PREC_LOWEST)
+		}
+		if key == nil {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: "expected key in dictionary pattern",
+			})
+			return nil
+		}
 
-			if p.currentToken.Type == NL {
-				p.nextToken() // Skip newline
+		var value ast.Expression
+		if p.peekToken.Type == COLON {
+			p.nextToken() // Move to ':'
+			p.nextToken() // Skip ':'
+			value = p.parsePattern()
+			if value == nil {
+				p.errors = append(p.errors, Error{
+					Line:    p.currentToken.Line,
+					Column:  p.currentToken.Column,
+					Message: "expected value pattern in dictionary pattern",
+				})
+				return nil
 			}
 		}
+		pattern.AddPair(key, value)
 
-		stmt.AddBranch(branch)
+		p.nextToken()
+		p.skipPatternWhitespace()
+		if p.currentToken.Type == COMMA {
+			p.nextToken()
+			p.skipPatternWhitespace()
+		} else if p.currentToken.Type != RBRACE {
+			p.errors = append(p.errors, Error{
+				Line:    p.currentToken.Line,
+				Column:  p.currentToken.Column,
+				Message: fmt.Sprintf("expected ',' or '}' in dictionary pattern, got %s", p.currentToken.Type),
+			})
+			return nil
+		}
 	}
 
-	return stmt
+	return pattern
+}
+
+// skipPatternWhitespace skips line breaks and indentation inside bracketed patterns
+func (p *Parser) skipPatternWhitespace() {
+	for p.currentToken.Type == NL || p.currentToken.Type == INDENT || p.currentToken.Type == DEDENT {
+		p.nextToken()
+	}
 }
 
 // ParseFile parses a GDScript file
@@ -1704,10 +2845,11 @@ func ParseFile(filePath string, content string) (*ast.AbstractSyntaxTree, []erro
 // parseConditionalExpression parses a conditional expression (ternary operator)
 // Format: value_if_true if condition else value_if_false
 func (p *Parser) parseConditionalExpression(valueIfTrue ast.Expression) ast.Expression {
-	p.nextToken() // consume 'if'
+	p.nextToken() // move to 'if'
+	p.nextToken() // move to the condition
 
-	// Parse the condition
-	condition := p.parseExpression(PREC_LOWEST)
+	// Parse the condition; a nested ternary here must be parenthesized
+	condition := p.parseExpression(PREC_TERNARY)
 	if condition == nil {
 		p.errors = append(p.errors, Error{
 			Line:    p.currentToken.Line,
@@ -1724,8 +2866,9 @@ func (p *Parser) parseConditionalExpression(valueIfTrue ast.Expression) ast.Expr
 
 	p.nextToken() // consume 'else' and move to value_if_false
 
-	// Parse the value if false
-	valueIfFalse := p.parseExpression(PREC_LOWEST)
+	// Parse the value if false; ternaries chain to the right, so
+	// a if b else c if d else e is a if b else (c if d else e)
+	valueIfFalse := p.parseExpression(PREC_TERNARY - 1)
 	if valueIfFalse == nil {
 		p.errors = append(p.errors, Error{
 			Line:    p.currentToken.Line,
@@ -1735,13 +2878,5 @@ func (p *Parser) parseConditionalExpression(valueIfTrue ast.Expression) ast.Expr
 		return nil
 	}
 
-	// Create a conditional expression node
-	return &ast.ConditionalExpression{
-		BaseExpression: ast.BaseExpression{
-			Pos: valueIfTrue.Position(),
-		},
-		Condition:    condition,
-		ValueIfTrue:  valueIfTrue,
-		ValueIfFalse: valueIfFalse,
-	}
+	return ast.NewConditionalExpression(condition, valueIfTrue, valueIfFalse, valueIfTrue.Position())
 }
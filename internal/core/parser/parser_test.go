@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
@@ -150,3 +152,1097 @@ func (v *testVisitor) Visit(node ast.Node) ast.Visitor {
 	v.countFunc(node)
 	return v
 }
+
+func TestParser_SelfExpression(t *testing.T) {
+	input := `extends Node
+
+func test():
+	var y = self.x
+	return self
+`
+
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	if tree.RootClass.Extends != "Node" {
+		t.Errorf("root class extends wrong. expected=%q, got=%q", "Node", tree.RootClass.Extends)
+	}
+
+	statements := tree.RootClass.Functions[0].Statements
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(statements))
+	}
+
+	dot, ok := statements[0].(*ast.VarStatement).Value.(*ast.DotExpression)
+	if !ok {
+		t.Fatalf("expected dot expression, got %T", statements[0].(*ast.VarStatement).Value)
+	}
+	if _, ok := dot.Left.(*ast.SelfExpression); !ok || dot.Property != "x" {
+		t.Errorf("expected self.x, got %T.%s", dot.Left, dot.Property)
+	}
+
+	ret := statements[1].(*ast.ReturnStatement)
+	if _, ok := ret.Value.(*ast.SelfExpression); !ok {
+		t.Errorf("expected return self, got %T", ret.Value)
+	}
+}
+
+func TestParser_ConditionalExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a if b else c", "(a if b else c)"},
+		{"a + 1 if b else c", "((a + 1) if b else c)"},
+		{"a if b and c else d or e", "(a if (b and c) else (d or e))"},
+		{"a if b else c if d else e", "(a if b else (c if d else e))"},
+		{"(a if b else c) if d else e", "((a if b else c) if d else e)"},
+		{"x + (a if b else c)", "(x + (a if b else c))"},
+		{"x = a if b else c", "(x = (a if b else c))"},
+		{"foo(a if b else c, d)", "foo((a if b else c), d)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", "func test():\n\t"+tt.input+"\n")
+			if len(errors) > 0 {
+				t.Fatalf("parser errors: %v", errors)
+			}
+
+			stmt, ok := tree.RootClass.Functions[0].Statements[0].(*ast.ExpressionStatement)
+			if !ok {
+				t.Fatalf("expected expression statement, got %T", tree.RootClass.Functions[0].Statements[0])
+			}
+			if got := parenthesize(stmt.Expression); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// parenthesize renders an expression with every operator application in parentheses
+func parenthesize(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.NumberLiteral:
+		return e.Original
+	case *ast.StringLiteral:
+		return e.Raw
+	case *ast.ArrayLiteral:
+		var elements []string
+		for _, element := range e.Elements {
+			elements = append(elements, parenthesize(element))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *ast.InfixExpression:
+		return "(" + parenthesize(e.Left) + " " + e.Operator + " " + parenthesize(e.Right) + ")"
+	case *ast.AssignmentExpression:
+		return "(" + parenthesize(e.Left) + " " + e.Operator + " " + parenthesize(e.Right) + ")"
+	case *ast.PrefixExpression:
+		if e.Operator == "not" {
+			return "(not " + parenthesize(e.Right) + ")"
+		}
+		return "(" + e.Operator + parenthesize(e.Right) + ")"
+	case *ast.DotExpression:
+		return parenthesize(e.Left) + "." + e.Property
+	case *ast.IndexExpression:
+		return parenthesize(e.Left) + "[" + parenthesize(e.Index) + "]"
+	case *ast.ConditionalExpression:
+		return "(" + parenthesize(e.ValueIfTrue) + " if " + parenthesize(e.Condition) +
+			" else " + parenthesize(e.ValueIfFalse) + ")"
+	case *ast.CallExpression:
+		var args []string
+		for _, arg := range e.Arguments {
+			args = append(args, parenthesize(arg))
+		}
+		return parenthesize(e.Function) + "(" + strings.Join(args, ", ") + ")"
+	default:
+		return fmt.Sprintf("<%T>", expr)
+	}
+}
+
+func TestParser_Comments(t *testing.T) {
+	input := `# ヘッダー
+extends Node # 継承
+
+func test(): # 関数
+	# 説明
+	var x = 1 # ✓
+	pass
+	# 終わり
+`
+
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	expected := []struct {
+		text   string
+		line   int
+		inline bool
+	}{
+		{"# ヘッダー", 1, false},
+		{"# 継承", 2, true},
+		{"# 関数", 4, true},
+		{"# 説明", 5, false},
+		{"# ✓", 6, true},
+		{"# 終わり", 8, false},
+	}
+	if len(tree.Comments) != len(expected) {
+		t.Fatalf("expected %d comments, got %d", len(expected), len(tree.Comments))
+	}
+	for i, want := range expected {
+		got := tree.Comments[i]
+		if got.Text != want.text || got.Pos.Line != want.line || got.Inline != want.inline {
+			t.Errorf("comment %d: expected %q on line %d (inline=%v), got %q on line %d (inline=%v)",
+				i, want.text, want.line, want.inline, got.Text, got.Pos.Line, got.Inline)
+		}
+	}
+
+	comments := ast.NewCommentMap(tree)
+	function := tree.RootClass.Functions[0]
+	varStmt := function.Statements[0]
+	pass := function.Statements[1]
+
+	checks := []struct {
+		name     string
+		attached []*ast.Comment
+		expected []*ast.Comment
+	}{
+		{"leading extends", comments.Leading[tree.RootClass], tree.Comments[0:1]},
+		{"inline extends", comments.Inline[tree.RootClass], tree.Comments[1:2]},
+		{"inline function", comments.Inline[function], tree.Comments[2:3]},
+		{"leading var", comments.Leading[varStmt], tree.Comments[3:4]},
+		{"inline var", comments.Inline[varStmt], tree.Comments[4:5]},
+		{"after pass", comments.After[pass], tree.Comments[5:6]},
+	}
+	for _, check := range checks {
+		if len(check.attached) != len(check.expected) || (len(check.expected) > 0 && check.attached[0] != check.expected[0]) {
+			t.Errorf("%s: expected %v, got %v", check.name, check.expected, check.attached)
+		}
+	}
+}
+
+func TestParser_BlankLines(t *testing.T) {
+	input := "\nextends Node\n\nfunc test():\n\tvar x = 1\n  \n\t# comment\n\n\r\n\tpass\n"
+
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	// Lines holding only whitespace are empty; comment lines are not
+	expected := []int{1, 3, 6, 8, 9}
+	if fmt.Sprint(tree.BlankLines) != fmt.Sprint(expected) {
+		t.Errorf("expected blank lines %v, got %v", expected, tree.BlankLines)
+	}
+}
+
+func TestParser_FunctionEnd(t *testing.T) {
+	input := "func a():\n\tvar x = 1\n\n\treturn \"\"\"a\nbc\"\"\"\n\n# comment\nfunc b(): pass\nfunc c():\n\tif x:\n\t\tfoo(1,\n\t\t\t2)  # comment\n"
+
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	// The end follows the last token of the body, comments and blank lines aside
+	expected := []string{"a 5:6", "b 8:15", "c 12:6"}
+	for i, function := range tree.RootClass.Functions {
+		if got := fmt.Sprintf("%s %d:%d", function.Name, function.End.Line, function.End.Column); got != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], got)
+		}
+	}
+}
+
+func TestParser_NumberLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+		isInt bool
+	}{
+		{"42", 42, true},
+		{"1_000_000", 1000000, true},
+		{"0xFF_FF", 0xFFFF, true},
+		{"0b1010", 10, true},
+		{"0B1_0", 2, true},
+		{"007", 7, true},
+		{"1_000.5", 1000.5, false},
+		{"2e3", 2000, false},
+	}
+
+	for _, tt := range tests {
+		tree, errors := ParseFile("test.gd", "var x = "+tt.input)
+		if len(errors) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, errors)
+		}
+		varStmt, ok := tree.RootClass.Statements[0].(*ast.VarStatement)
+		if !ok {
+			t.Fatalf("%s: expected a var statement, got %T", tt.input, tree.RootClass.Statements[0])
+		}
+		number, ok := varStmt.Value.(*ast.NumberLiteral)
+		if !ok {
+			t.Fatalf("%s: expected a number literal, got %T", tt.input, varStmt.Value)
+		}
+		if number.Value != tt.value || number.IsInt != tt.isInt || number.Original != tt.input {
+			t.Errorf("%s: expected %v (int=%v), got %v (int=%v) from %q",
+				tt.input, tt.value, tt.isInt, number.Value, number.IsInt, number.Original)
+		}
+	}
+
+	if _, errors := ParseFile("test.gd", "var x = 0x"); len(errors) == 0 {
+		t.Error("Expected an error for a hexadecimal literal without digits")
+	}
+}
+
+func TestParser_MissingOperands(t *testing.T) {
+	for _, input := range []string{
+		"func foo():\n\tbar(1) %",
+		"func foo():\n\tvar x = 1 + # comment",
+		"func foo():\n\tvar x = not",
+		"func foo():\n\t(",
+		"var x = ",
+		"func foo():\n\tvar x :=\n",
+		"const X = \n",
+		"const X :=\n",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for a missing operand in %q", input)
+		}
+	}
+}
+
+func TestParser_DeclarationEnds(t *testing.T) {
+	// Code after a declaration on its line is reported rather than dropped
+	for _, input := range []string{
+		"var a = 1 2\n",
+		"var a: int 2\n",
+		"const A = 1 2\n",
+		"var A)0\n",
+	} {
+		_, errors := ParseFile("test.gd", input)
+		if len(errors) == 0 || !strings.Contains(errors[0].Error(), "expected end of statement") {
+			t.Errorf("Expected code after the declaration in %q to be reported, got %v", input, errors)
+		}
+	}
+	// and so is a declaration without its name on its line
+	for _, input := range []string{
+		"var\na\n",
+		"const\nA = 1\n",
+		"class\nA:\n\tpass\n",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected the name missing from %q to be reported", input)
+		}
+	}
+	for _, input := range []string{
+		"var a = 1; var b = 2\n",
+		"func f(a):\n\tif a: var b = 1; pass\n",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) > 0 {
+			t.Errorf("Expected %q to parse, got %v", input, errors)
+		}
+	}
+}
+
+func TestParser_StatementEnds(t *testing.T) {
+	// Statements on a line are separated by semicolons
+	for _, input := range []string{
+		"0 a\n",
+		"func f():\n\tpass a\n",
+		"func f(): pass a\n",
+		"func f(a):\n\tif a: pass b\n",
+		"a extends B\n",
+	} {
+		_, errors := ParseFile("test.gd", input)
+		if len(errors) != 1 || !strings.Contains(errors[0].Error(), "expected end of statement") {
+			t.Errorf("Expected the second statement in %q to be reported, got %v", input, errors)
+		}
+	}
+}
+
+func TestParser_EmptyBlocks(t *testing.T) {
+	// Whitespace on the last line indents nothing
+	for _, input := range []string{
+		"func foo():",
+		"func foo():\n ",
+		"func foo():\n\tif true:\n\t\t",
+	} {
+		_, errors := ParseFile("test.gd", input)
+		if len(errors) == 0 || !strings.Contains(errors[0].Error(), "expected an indented block") {
+			t.Errorf("Expected a missing block in %q, got %v", input, errors)
+		}
+	}
+}
+
+func TestParser_MatchWithoutBranches(t *testing.T) {
+	_, errors := ParseFile("test.gd", "match x:\n\t;\n")
+	if len(errors) == 0 || !strings.Contains(errors[0].Error(), "expected a match branch") {
+		t.Errorf("Expected a missing match branch, got %v", errors)
+	}
+}
+
+func TestParser_UnterminatedStrings(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"double quotes", "var s = \"abc\nvar t = 1\n", 1, 9},
+		{"single quotes", "func f():\n\tprint('abc)\n\tpass\n", 2, 8},
+		{"escaped closing quote", "var s = \"abc\\\"\nvar t = 1\n", 1, 9},
+		{"raw string", "var s = r\"abc\nvar t = 1\n", 1, 9},
+		{"triple quotes", "var s = \"\"\"abc\nvar t = 1\n", 1, 9},
+		{"quote alone", "\"", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", tt.input)
+			found := false
+			for _, e := range errors {
+				if err, ok := e.(Error); ok && err.Message == "unterminated string" {
+					found = err.Line == tt.line && err.Column == tt.column
+				}
+			}
+			if !found {
+				t.Fatalf("Expected an unterminated string at %d:%d, got %v", tt.line, tt.column, errors)
+			}
+			// A string in single or double quotes stops at the end of its line
+			if tt.name == "double quotes" {
+				if statements := tree.RootClass.Statements; len(statements) != 2 {
+					t.Errorf("Expected the line after the string to be parsed, got %d statements", len(statements))
+				}
+			}
+		})
+	}
+
+	for _, input := range []string{
+		"var s = \"a\\\nb\"\n",
+		"var s = \"\"\"a\nb\"\"\"\n",
+		"var s = 'it\\'s'\n",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) > 0 {
+			t.Errorf("Expected %q to parse, got %v", input, errors)
+		}
+	}
+}
+
+func TestParser_Suggestions(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		line       int
+		column     int
+		suggestion string
+	}{
+		{
+			name:       "missing colon after a condition",
+			input:      "func f(a):\n\tif a > 1\n\t\tpass\n",
+			line:       2,
+			column:     10,
+			suggestion: "add ':' at the end of the line",
+		},
+		{
+			name:       "missing colon after a function signature",
+			input:      "func f(a)\n\tpass\n",
+			line:       1,
+			column:     10,
+			suggestion: "add ':' at the end of the line",
+		},
+		{
+			name:       "assignment in a condition",
+			input:      "func f(a):\n\twhile a = 1:\n\t\tpass\n",
+			line:       2,
+			column:     10,
+			suggestion: "did you mean '=='? '=' assigns a value, '==' compares two",
+		},
+		{
+			name:       "tabs and spaces mixed",
+			input:      "func f(a):\n\tif a:\n\t\tpass\n\t  pass\n",
+			line:       4,
+			column:     4,
+			suggestion: "indent every line of the script with tabs only, or with spaces only",
+		},
+		{
+			name:       "script annotation before a declaration",
+			input:      "extends Node\n@onready @tool var a = 1\n",
+			line:       2,
+			column:     10,
+			suggestion: "put it on a line of its own at the top of the script",
+		},
+		{
+			name:       "annotation arguments without parentheses",
+			input:      "@export_range 0, 10\nvar speed = 1\n",
+			line:       1,
+			column:     15,
+			suggestion: "did you mean @export_range(0, 10)?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", tt.input)
+			if len(errors) != 1 {
+				t.Fatalf("Expected one error, got %v", errors)
+			}
+			err, ok := errors[0].(Error)
+			if !ok || err.Line != tt.line || err.Column != tt.column || err.Suggestion != tt.suggestion {
+				t.Errorf("Expected the suggestion %q at %d:%d, got %+v", tt.suggestion, tt.line, tt.column, errors[0])
+			}
+			// The script parses as if it were written as suggested
+			if len(tree.RootClass.Statements)+len(tree.RootClass.Functions) == 0 {
+				t.Error("Expected the statements to be parsed")
+			}
+		})
+	}
+}
+
+func TestParser_Assignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a = 1", "(a = 1)"},
+		{"a += b + 1", "(a += (b + 1))"},
+		{"a %= 2", "(a %= 2)"},
+		{"a **= 2", "(a **= 2)"},
+		{"a <<= 1", "(a <<= 1)"},
+		{"a[0] |= b", "(a[0] |= b)"},
+		{"x.a ^= b", "(x.a ^= b)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", "func test():\n\t"+tt.input+"\n")
+			if len(errors) > 0 {
+				t.Fatalf("parser errors: %v", errors)
+			}
+
+			statements := tree.RootClass.Functions[0].Statements
+			if len(statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(statements))
+			}
+			stmt, ok := statements[0].(*ast.ExpressionStatement)
+			if !ok {
+				t.Fatalf("expected expression statement, got %T", statements[0])
+			}
+			if _, ok := stmt.Expression.(*ast.AssignmentExpression); !ok {
+				t.Fatalf("expected an assignment, got %T", stmt.Expression)
+			}
+			if got := parenthesize(stmt.Expression); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	// Assignments are statements, not expressions
+	for _, input := range []string{
+		"func foo():\n\ta = b = c",
+		"func foo():\n\tfoo(a = 1)",
+		"func foo():\n\tif a = 1:\n\t\tpass",
+		"func foo():\n\tbar() = 1",
+		"func foo():\n\t1 += a",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for the assignment in %q", input)
+		}
+	}
+
+	// A chain of assignments is reported once, and the statements after it
+	// are parsed
+	for _, input := range []string{
+		"func foo():\n\ta = b = 1\n\tprint(a)\n",
+		"func foo():\n\ta = b = c += 1\n\tprint(a)\n",
+		"func foo():\n\ta = b =\n\tprint(a)\n",
+	} {
+		tree, errors := ParseFile("test.gd", input)
+		if len(errors) != 1 || !strings.Contains(errors[0].Error(), "assignment is not allowed inside an expression") {
+			t.Errorf("Expected one error for the chained assignment in %q, got %v", input, errors)
+		}
+		if len(tree.RootClass.Functions) != 1 || len(tree.RootClass.Functions[0].Statements) != 1 {
+			t.Errorf("Expected the statement after the chained assignment in %q to be parsed", input)
+		}
+	}
+}
+
+func TestParser_UnformattedRegions(t *testing.T) {
+	input := "var a = 1\r\n# gdformat: off\r\nvar  b = 2\r\n#gdformat:on\r\nvar c = 3 # gdformat: off\r\n\t# gdformat: off\r\nvar d = 4\r\n\r\n"
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	// A region stops before its "on" comment, an inline comment does not
+	// start one, and the last one runs to the end of the script
+	expected := []ast.SourceRegion{
+		{StartLine: 2, EndLine: 3, Text: "# gdformat: off\nvar  b = 2"},
+		{StartLine: 6, EndLine: 7, Text: "\t# gdformat: off\nvar d = 4"},
+	}
+	if fmt.Sprint(tree.Unformatted) != fmt.Sprint(expected) {
+		t.Errorf("expected regions %q, got %q", expected, tree.Unformatted)
+	}
+
+	// Nor does a comment within brackets
+	tree, _ = ParseFile("test.gd", "var a = [\n\t# gdformat: off\n\t1,\n]\n")
+	if len(tree.Unformatted) != 0 {
+		t.Errorf("expected no region within brackets, got %q", tree.Unformatted)
+	}
+}
+
+func TestParser_InnerClassExtends(t *testing.T) {
+	tree, errors := ParseFile("test.gd", "class A extends Node2D:\n\tpass\nclass B:\n\textends \"res://b.gd\"\n\tvar y = 2\n")
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	a, b := tree.RootClass.SubClasses[0], tree.RootClass.SubClasses[1]
+	if a.Extends != "Node2D" || a.ExtendsPos.Line != 1 {
+		t.Errorf("expected A to extend Node2D on line 1, got %q on line %d", a.Extends, a.ExtendsPos.Line)
+	}
+	if b.Extends != `"res://b.gd"` || b.ExtendsPos.Line != 4 {
+		t.Errorf("expected B to extend \"res://b.gd\" on line 4, got %q on line %d", b.Extends, b.ExtendsPos.Line)
+	}
+	if len(b.Statements) != 1 {
+		t.Errorf("expected the extends statement to be kept out of the statements of B, got %d statements", len(b.Statements))
+	}
+
+	if _, errors := ParseFile("test.gd", "class A extends Node2D:\n\textends Node\n"); len(errors) == 0 {
+		t.Error("Expected an error for a class extending two classes")
+	}
+}
+
+func TestParser_InferredConstants(t *testing.T) {
+	input := `const SPEED := 10
+func foo():
+	const NAME := "a"
+`
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	constants := []*ast.VarStatement{
+		tree.RootClass.Statements[0].(*ast.VarStatement),
+		tree.RootClass.Functions[0].Statements[0].(*ast.VarStatement),
+	}
+	for _, constant := range constants {
+		if !constant.IsConst || !constant.IsInferred || constant.TypeHint != "" || constant.Value == nil {
+			t.Errorf("expected %s to be an inferred constant with a value, got %+v", constant.Name, constant)
+		}
+	}
+
+	if _, errors := ParseFile("test.gd", "const A: int := 1\n"); len(errors) == 0 {
+		t.Error("Expected an error for a constant with both a type hint and :=")
+	}
+}
+
+func TestParser_TypeHints(t *testing.T) {
+	input := `var a: Array[int]
+const B: Dictionary[String, Array[int]] = {}
+var c: SubClass.NamedEnum
+func foo(d: Array[SubClass.NamedEnum], e: Dictionary [ int , String ]) -> Array[int]:
+	for f: Node.Mode in []:
+		pass
+`
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	var hints []string
+	for _, stmt := range tree.RootClass.Statements {
+		hints = append(hints, stmt.(*ast.VarStatement).TypeHint)
+	}
+	function := tree.RootClass.Functions[0]
+	for _, param := range function.Parameters {
+		hints = append(hints, param.TypeHint)
+	}
+	hints = append(hints, function.ReturnType, function.Statements[0].(*ast.ForStatement).TypeHint)
+
+	expected := []string{
+		"Array[int]", "Dictionary[String, Array[int]]", "SubClass.NamedEnum",
+		"Array[SubClass.NamedEnum]", "Dictionary[int, String]", "Array[int]", "Node.Mode",
+	}
+	if strings.Join(hints, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected type hints %q, got %q", expected, hints)
+	}
+
+	for _, input := range []string{
+		"var a: Array[]",
+		"var a: Array[int",
+		"var a: Node.",
+		"func foo(a: Array[1]):\n\tpass",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for the type in %q", input)
+		}
+	}
+}
+
+func TestParser_LuaStyleDictionaries(t *testing.T) {
+	tree, errors := ParseFile("test.gd", "var stats = {name = \"a\", hp = 3}\nvar other = {\"name\": \"a\"}\n")
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	lua, ok := tree.RootClass.Statements[0].(*ast.VarStatement).Value.(*ast.DictionaryLiteral)
+	if !ok {
+		t.Fatalf("expected a dictionary, got %T", tree.RootClass.Statements[0].(*ast.VarStatement).Value)
+	}
+	if !lua.LuaStyle || len(lua.Keys) != 2 {
+		t.Fatalf("expected 2 pairs in the {key = value} style, got %d (LuaStyle %v)", len(lua.Keys), lua.LuaStyle)
+	}
+	if key, ok := lua.Keys[1].(*ast.Identifier); !ok || key.Value != "hp" {
+		t.Errorf("expected the key hp, got %#v", lua.Keys[1])
+	}
+	if other := tree.RootClass.Statements[1].(*ast.VarStatement).Value.(*ast.DictionaryLiteral); other.LuaStyle {
+		t.Error("expected {\"key\": value} not to be in the {key = value} style")
+	}
+
+	// The two styles cannot be mixed, and only names are keys of the second
+	for _, input := range []string{
+		"var d = {a = 1, \"b\": 2}",
+		"var d = {\"a\": 1, b = 2}",
+		"var d = {a = 1, \"b\" = 2}",
+		"var d = {1 = 2}",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for the dictionary in %q", input)
+		}
+	}
+}
+
+func TestParser_OperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a or b and c", "(a or (b and c))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"not a in b", "(not (a in b))"},
+		{"a not in b and c", "((a not in b) and c)"},
+		{"a in b == c", "(a in (b == c))"},
+		{"a == b | c", "(a == (b | c))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b << 1", "(a & (b << 1))"},
+		{"a << b + 1", "(a << (b + 1))"},
+		{"-a * b", "((-a) * b)"},
+		{"-a ** 2", "(-(a ** 2))"},
+		{"~a ** 2", "(~(a ** 2))"},
+		{"-~a", "(-(~a))"},
+		{"+a - b", "((+a) - b)"},
+		{"a ** b ** c", "((a ** b) ** c)"},
+		{"a ** b is int", "(a ** (b is int))"},
+		{"a is not Node and b", "((a is not Node) and b)"},
+		{"a is Array[int]", "(a is Array[int])"},
+		{"!a == b", "(!(a == b))"},
+		{"a as Node2D", "(a as Node2D)"},
+		{"a + b as int", "((a + b) as int)"},
+		{"a if b else c as int", "((a if b else c) as int)"},
+		{"(a as Node).b", "(a as Node).b"},
+		{`"%s: %d" % [a, b] + c`, `(("%s: %d" % [a, b]) + c)`},
+		{`"%d" % a * 2`, `(("%d" % a) * 2)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", "var x = "+tt.input+"\n")
+			if len(errors) > 0 {
+				t.Fatalf("parser errors: %v", errors)
+			}
+			if len(tree.RootClass.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(tree.RootClass.Statements))
+			}
+			varStmt := tree.RootClass.Statements[0].(*ast.VarStatement)
+			if got := parenthesize(varStmt.Value); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	for _, input := range []string{"var x = a not b", "var x = a is", "var x = ~"} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestParser_NotPrecedence(t *testing.T) {
+	tree, errors := ParseFile("test.gd", "var x = a and not b == c")
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	varStmt := tree.RootClass.Statements[0].(*ast.VarStatement)
+
+	// not binds looser than ==: a and (not (b == c))
+	and, ok := varStmt.Value.(*ast.InfixExpression)
+	if !ok || and.Operator != "and" {
+		t.Fatalf("expected an 'and' expression, got %#v", varStmt.Value)
+	}
+	not, ok := and.Right.(*ast.PrefixExpression)
+	if !ok || not.Operator != "not" {
+		t.Fatalf("expected a 'not' expression, got %#v", and.Right)
+	}
+	if comparison, ok := not.Right.(*ast.InfixExpression); !ok || comparison.Operator != "==" {
+		t.Errorf("expected 'not' to apply to the comparison, got %#v", not.Right)
+	}
+}
+
+func TestParser_Declarations(t *testing.T) {
+	input := `@tool
+class_name Player extends Node
+signal hit(damage: int, source)
+signal died
+enum State {IDLE, RUN = 2}
+enum {FIRST}
+@export_range(0, 10)
+var speed = 1
+
+
+@rpc("any_peer")
+func sync():
+	pass`
+
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	root := tree.RootClass
+	if root.ClassName != "Player" || root.ClassNamePos.Line != 2 || root.Extends != "Node" {
+		t.Errorf("expected class_name Player extends Node on line 2, got %q (line %d) extends %q",
+			root.ClassName, root.ClassNamePos.Line, root.Extends)
+	}
+	if len(root.Annotations) != 1 || root.Annotations[0].Name != "tool" {
+		t.Errorf("expected the script to be annotated with @tool, got %v", root.Annotations)
+	}
+	if len(root.Statements) != 5 {
+		t.Fatalf("expected 5 statements, got %d", len(root.Statements))
+	}
+
+	hit, ok := root.Statements[0].(*ast.SignalStatement)
+	if !ok || hit.Name != "hit" || len(hit.Parameters) != 2 || hit.Parameters[0].TypeHint != "int" {
+		t.Errorf("expected signal hit(damage: int, source), got %#v", root.Statements[0])
+	}
+	if died, ok := root.Statements[1].(*ast.SignalStatement); !ok || died.Name != "died" || len(died.Parameters) != 0 {
+		t.Errorf("expected signal died, got %#v", root.Statements[1])
+	}
+
+	state, ok := root.Statements[2].(*ast.EnumStatement)
+	if !ok || state.Name != "State" || len(state.Elements) != 2 {
+		t.Fatalf("expected enum State with 2 elements, got %#v", root.Statements[2])
+	}
+	if state.Elements[0].Value != nil || state.Elements[1].Name != "RUN" || state.Elements[1].Value == nil {
+		t.Errorf("expected IDLE without a value and RUN = 2, got %#v %#v", state.Elements[0], state.Elements[1])
+	}
+	if unnamed, ok := root.Statements[3].(*ast.EnumStatement); !ok || unnamed.Name != "" || len(unnamed.Elements) != 1 {
+		t.Errorf("expected an unnamed enum, got %#v", root.Statements[3])
+	}
+
+	speed, ok := root.Statements[4].(*ast.VarStatement)
+	if !ok || len(speed.Annotations) != 1 || speed.Annotations[0].Name != "export_range" || len(speed.Annotations[0].Args) != 2 {
+		t.Errorf("expected var speed annotated with @export_range(0, 10), got %#v", root.Statements[4])
+	}
+	if len(root.Functions) != 1 || len(root.Functions[0].Annotations) != 1 || root.Functions[0].Annotations[0].Name != "rpc" {
+		t.Errorf("expected func sync annotated with @rpc, got %#v", root.Functions)
+	}
+
+	if _, errors := ParseFile("test.gd", "@export\n"); len(errors) == 0 {
+		t.Error("Expected an error for an annotation without a statement")
+	}
+}
+
+func TestParser_VarStatementKinds(t *testing.T) {
+	input := `var a
+var b: int = 1
+const C = 2
+class Inner:
+	var d := 3
+func foo():
+	var e
+	var f: int = 4
+	const G = 5
+	if true:
+		var h = 6
+`
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	var kinds []string
+	ast.Inspect(tree, func(node ast.Node) bool {
+		if v, ok := node.(*ast.VarStatement); ok {
+			kinds = append(kinds, fmt.Sprintf("%s:%s:%t", v.Name, v.Kind, v.IsClassLevel))
+		}
+		return true
+	})
+
+	expected := []string{
+		"a:class_var_stmt:true", "b:class_var_typed_assgnd:true", "C:const_assigned:true",
+		"e:func_var_stmt:false", "f:func_var_typed_assgnd:false", "G:const_assigned:false",
+		"h:func_var_assigned:false", "d:class_var_typed_assgnd:true",
+	}
+	if strings.Join(kinds, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected var statements %q, got %q", expected, kinds)
+	}
+}
+
+func TestParser_StaticMembers(t *testing.T) {
+	input := `static var counter := 0
+@rpc
+static func _static_init():
+	counter = 1
+`
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	counter, ok := tree.RootClass.Statements[0].(*ast.VarStatement)
+	if !ok || counter.Name != "counter" || !counter.IsStatic || !counter.IsInferred || counter.Pos.Column != 1 {
+		t.Errorf("expected static var counter at column 1, got %#v", tree.RootClass.Statements[0])
+	}
+	if len(tree.RootClass.Functions) != 1 || !tree.RootClass.Functions[0].IsStatic || len(tree.RootClass.Functions[0].Annotations) != 1 {
+		t.Errorf("expected the annotated static func _static_init, got %#v", tree.RootClass.Functions)
+	}
+
+	for _, input := range []string{
+		"func foo():\n\tstatic var a = 1",
+		"static signal hit",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestParser_BreakpointAndAssert(t *testing.T) {
+	input := `func foo(x):
+	breakpoint
+	assert(x > 0)
+	assert(x < 10, "x is too large")
+	var assert_count = 0
+`
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	body := tree.RootClass.Functions[0].Statements
+	if len(body) != 4 {
+		t.Fatalf("expected 4 statements, got %d", len(body))
+	}
+	if _, ok := body[0].(*ast.BreakpointStatement); !ok {
+		t.Errorf("expected a breakpoint statement, got %#v", body[0])
+	}
+	if a, ok := body[1].(*ast.AssertStatement); !ok || a.Condition == nil || a.Message != nil {
+		t.Errorf("expected assert(x > 0) without a message, got %#v", body[1])
+	}
+	if a, ok := body[2].(*ast.AssertStatement); !ok || a.Message == nil || a.Position().Column != 2 {
+		t.Errorf("expected assert with a message at column 2, got %#v", body[2])
+	}
+
+	for _, input := range []string{"func foo():\n\tassert()", "func foo():\n\tassert(a, b, c)"} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestParser_Blocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "else of an outer if after a nested if",
+			input:    "func f(a, b):\n\tif a:\n\t\tif b:\n\t\t\tpass\n\telse:\n\t\treturn\n",
+			expected: "if(if(pass))else(return)",
+		},
+		{
+			name:     "elif chain",
+			input:    "func f(a):\n\tif a == 1:\n\t\tpass\n\telif a == 2:\n\t\treturn\n\telse:\n\t\tbreak\n",
+			expected: "if(pass)elif(return)else(break)",
+		},
+		{
+			name:     "statement after an if block",
+			input:    "func f(a):\n\tif a:\n\t\tpass\n\treturn\n",
+			expected: "if(pass) return",
+		},
+		{
+			name:     "single-line bodies",
+			input:    "func f(a):\n\tif a: pass\n\telse: return\n\twhile a: a -= 1; continue\n\tfor i in a: pass\n",
+			expected: "if(pass)else(return) while(expr continue) for(pass)",
+		},
+		{
+			name:     "match with inline and block branches",
+			input:    "func f(a):\n\tmatch a:\n\t\t1: return\n\t\t2:\n\t\t\tpass\n\t\t\treturn\n\treturn\n",
+			expected: "match(return)(pass return) return",
+		},
+		{
+			name:     "single-line function",
+			input:    "func f(): return 1\n",
+			expected: "return",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", tt.input)
+			if len(errors) > 0 {
+				t.Fatalf("parser errors: %v", errors)
+			}
+			if got := blockShape(tree.RootClass.Functions[0].Statements); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	// A class after a doubly dedented block belongs to the script, not to the
+	// function before it
+	tree, errors := ParseFile("test.gd", "func f(a):\n\tif a:\n\t\tpass\nclass Inner:\n\tvar b\n")
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	if len(tree.RootClass.SubClasses) != 1 || len(tree.RootClass.SubClasses[0].Statements) != 1 {
+		t.Errorf("expected the top-level class Inner with one var, got %#v", tree.RootClass.SubClasses)
+	}
+
+	for _, input := range []string{
+		"func f(a):\n\tif a:\n\treturn\n",
+		"func f(a):\n\tpass\n\t\treturn\n",
+		"func f():\n",
+		"func f(a):\n\telse:\n\t\tpass\n",
+	} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+// blockShape renders the control flow of statements compactly, e.g.
+// "if(pass)else(return)"
+func blockShape(statements []ast.Statement) string {
+	shapes := make([]string, 0, len(statements))
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case *ast.IfStatement:
+			shape := "if(" + blockShape(s.Consequence) + ")"
+			for _, branch := range s.ElseBranches {
+				shape += "elif(" + blockShape(branch) + ")"
+			}
+			if len(s.Alternative) > 0 {
+				shape += "else(" + blockShape(s.Alternative) + ")"
+			}
+			shapes = append(shapes, shape)
+		case *ast.WhileStatement:
+			shapes = append(shapes, "while("+blockShape(s.Body)+")")
+		case *ast.ForStatement:
+			shapes = append(shapes, "for("+blockShape(s.Body)+")")
+		case *ast.MatchStatement:
+			shape := "match"
+			for _, branch := range s.Branches {
+				shape += "(" + blockShape(branch.Body) + ")"
+			}
+			shapes = append(shapes, shape)
+		case *ast.PassStatement:
+			shapes = append(shapes, "pass")
+		case *ast.ReturnStatement:
+			shapes = append(shapes, "return")
+		case *ast.BreakStatement:
+			shapes = append(shapes, "break")
+		case *ast.ContinueStatement:
+			shapes = append(shapes, "continue")
+		default:
+			shapes = append(shapes, "expr")
+		}
+	}
+	return strings.Join(shapes, " ")
+}
+
+func TestParser_ExpressionStatements(t *testing.T) {
+	// Each line is a statement of its own; none may be dropped
+	input := `func f(x):
+	await get_tree().process_frame
+	not x
+	-x
+	$Sprite/Label.queue_free()
+	%Button.pressed.connect(f)
+	self.queue_free()
+	{}.clear()
+	~x
+	x.y
+`
+	tree, errors := ParseFile("test.gd", input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	body := tree.RootClass.Functions[0].Statements
+	if len(body) != strings.Count(input, "\n")-1 {
+		t.Fatalf("expected %d statements, got %d", strings.Count(input, "\n")-1, len(body))
+	}
+	for i, stmt := range body {
+		if stmt.Position().Line != i+2 {
+			t.Errorf("expected statement %d on line %d, got line %d", i, i+2, stmt.Position().Line)
+		}
+	}
+	if await, ok := body[0].(*ast.ExpressionStatement).Expression.(*ast.AwaitExpression); !ok {
+		t.Errorf("expected an await expression, got %#v", body[0])
+	} else if _, ok := await.Operand.(*ast.DotExpression); !ok {
+		t.Errorf("expected await to take get_tree().process_frame, got %#v", await.Operand)
+	}
+
+	for _, input := range []string{"func f():\n\telse\n", "func f():\n\t)\n", "func f():\n\tawait\n"} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestParser_GetNode(t *testing.T) {
+	tests := []struct {
+		input string
+		raw   string
+		path  string
+	}{
+		{"$Sprite", "$Sprite", "Sprite"},
+		{"$Sprite/Label.text", "$Sprite/Label", "Sprite/Label"},
+		{`$"../HUD"`, `$"../HUD"`, "../HUD"},
+		{"$../HUD", "$../HUD", "../HUD"},
+		{"$A/../B", "$A/../B", "A/../B"},
+		{"%Button", "%Button", "%Button"},
+		{"$%Panel/Label", "$%Panel/Label", "%Panel/Label"},
+		{"$Sprite / 2", "$Sprite", "Sprite"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tree, errors := ParseFile("test.gd", "var a = "+tt.input+"\n")
+			if len(errors) > 0 {
+				t.Fatalf("parser errors: %v", errors)
+			}
+			var node *ast.GetNodeExpression
+			ast.Inspect(tree, func(n ast.Node) bool {
+				if g, ok := n.(*ast.GetNodeExpression); ok && node == nil {
+					node = g
+				}
+				return true
+			})
+			if node == nil || node.Raw != tt.raw || node.Path != tt.path {
+				t.Errorf("expected %s looking up %s, got %#v", tt.raw, tt.path, node)
+			}
+		})
+	}
+
+	for _, input := range []string{"var a = $\n", "var a = $Sprite/\n", "var a = %\n"} {
+		if _, errors := ParseFile("test.gd", input); len(errors) == 0 {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
@@ -7,9 +7,12 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
-	Line    int
-	Column  int
-	Offset  int
+	// Value is the decoded string of a STRING or RSTRING token, whose Literal
+	// is the string as written; empty for other tokens
+	Value  string
+	Line   int
+	Column int
+	Offset int
 }
 
 // NewToken creates a new token
@@ -171,10 +174,31 @@ var keywords = map[string]TokenType{
 	"not":        NOT,
 }
 
+// keyword is a keyword and its token type
+type keyword struct {
+	literal   string
+	tokenType TokenType
+}
+
+// keywordIndex groups the keywords by length and first letter, so that most
+// identifiers are told apart from them without being hashed
+var keywordIndex = func() (index [11][26][]keyword) {
+	for literal, tokenType := range keywords {
+		bucket := &index[len(literal)][literal[0]-'a']
+		*bucket = append(*bucket, keyword{literal, tokenType})
+	}
+	return index
+}()
+
 // LookupIdent checks if the given identifier is a keyword
 func LookupIdent(ident string) TokenType {
-	if tok, ok := keywords[ident]; ok {
-		return tok
+	if len(ident) == 0 || len(ident) >= len(keywordIndex) || ident[0]-'a' >= 26 {
+		return IDENT
+	}
+	for _, keyword := range keywordIndex[len(ident)][ident[0]-'a'] {
+		if keyword.literal == ident {
+			return keyword.tokenType
+		}
 	}
 	return IDENT
 }
@@ -0,0 +1,131 @@
+// Package corpus loads the GDScript files of a project tree, to measure and
+// profile the tools on real code rather than generated scripts
+package corpus
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File is a script of a corpus
+type File struct {
+	Path   string
+	Source string
+}
+
+// Corpus is the set of scripts found under a directory
+type Corpus struct {
+	Files []File
+	// Bytes and Lines are the total size of the scripts
+	Bytes int
+	Lines int
+}
+
+// DefaultMaxFileSize is the size above which the tools refuse to read a
+// script: scripts that large are generated or not scripts at all, and would
+// take a long time and a lot of memory to process
+const DefaultMaxFileSize = 10 << 20
+
+// TooLargeError is returned when a file is larger than the limit it is read with
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("file is larger than the limit of %s, raise --max-file-size to process it", formatSize(e.Limit))
+}
+
+// formatSize formats a number of bytes, e.g. "10 MiB" or "1500 bytes"
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20 && bytes%(1<<20) == 0:
+		return fmt.Sprintf("%d MiB", bytes>>20)
+	case bytes >= 1<<10 && bytes%(1<<10) == 0:
+		return fmt.Sprintf("%d KiB", bytes>>10)
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
+
+// ReadFile reads the file at path, failing with a *TooLargeError when it is
+// larger than maxSize bytes; 0 reads files of any size. The limit holds even
+// for files whose size is unknown up front, such as named pipes.
+func ReadFile(path string, maxSize int64) (string, error) {
+	if maxSize <= 0 {
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.Size() > maxSize {
+		return "", &TooLargeError{Limit: maxSize}
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxSize {
+		return "", &TooLargeError{Limit: maxSize}
+	}
+	return string(data), nil
+}
+
+// Walk calls fn with the path of every .gd file under root as it is found,
+// so that the files of a large project are processed without listing them
+// all first. Directories are walked in lexical order, and hidden ones, such as
+// .godot and .git, are skipped. root may also be a single script, which fn is
+// called with whatever its extension. An error returned by fn stops the walk.
+func Walk(root string, fn func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".gd") || path == root {
+			return fn(path)
+		}
+		return nil
+	})
+}
+
+// Load reads every .gd file under root, in path order. Hidden directories,
+// such as .godot and .git, are skipped. root may also be a single script.
+func Load(root string) (*Corpus, error) {
+	var paths []string
+	err := Walk(root, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	c := &Corpus{}
+	for _, path := range paths {
+		source, err := ReadFile(path, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.Files = append(c.Files, File{Path: path, Source: source})
+		c.Bytes += len(source)
+		c.Lines += strings.Count(source, "\n")
+		if source != "" && !strings.HasSuffix(source, "\n") {
+			c.Lines++
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,95 @@
+package corpus
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"player.gd":                 "extends Node\n",
+		"addons/tool/plugin.gd":     "func foo():\n\tpass",
+		"addons/tool/README.md":     "not a script",
+		".godot/imported/cached.gd": "ignored",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(c.Files) != 2 {
+		t.Fatalf("Expected 2 scripts, got %d: %v", len(c.Files), c.Files)
+	}
+	if c.Files[0].Path != filepath.Join(root, "addons/tool/plugin.gd") || c.Files[1].Path != filepath.Join(root, "player.gd") {
+		t.Errorf("Expected scripts in path order, got %s and %s", c.Files[0].Path, c.Files[1].Path)
+	}
+	if c.Lines != 3 || c.Bytes != len(files["player.gd"])+len(files["addons/tool/plugin.gd"]) {
+		t.Errorf("Expected 3 lines and %d bytes, got %d lines and %d bytes",
+			len(files["player.gd"])+len(files["addons/tool/plugin.gd"]), c.Lines, c.Bytes)
+	}
+
+	single, err := Load(filepath.Join(root, "player.gd"))
+	if err != nil {
+		t.Fatalf("Load of a single script failed: %v", err)
+	}
+	if len(single.Files) != 1 || single.Lines != 1 {
+		t.Errorf("Expected the single script, got %v", single.Files)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"b.gd", "a/c.gd", "a/notes.txt", ".git/d.gd"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var found []string
+	err := Walk(root, func(path string) error {
+		found = append(found, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if len(found) != 2 || found[0] != filepath.Join(root, "a/c.gd") || found[1] != filepath.Join(root, "b.gd") {
+		t.Errorf("Expected a/c.gd and b.gd, got %v", found)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.gd")
+	if err := os.WriteFile(path, []byte("var x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int64{0, 10, 100} {
+		if source, err := ReadFile(path, limit); err != nil || source != "var x = 1\n" {
+			t.Errorf("limit %d: expected the script, got %q, %v", limit, source, err)
+		}
+	}
+
+	_, err := ReadFile(path, 9)
+	var tooLarge *TooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 9 {
+		t.Errorf("Expected a *TooLargeError, got %v", err)
+	}
+}
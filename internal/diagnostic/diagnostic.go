@@ -0,0 +1,203 @@
+// Package diagnostic prints the problems found in scripts the way compilers
+// do: the message, the offending source line and a caret under the column,
+// colorized when writing to a terminal
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+// ColorMode controls whether diagnostics are colorized
+type ColorMode int
+
+const (
+	// ColorAuto colorizes output written to a terminal, unless NO_COLOR is
+	// set or TERM is dumb
+	ColorAuto ColorMode = iota
+	// ColorAlways colorizes output, e.g. when piping it to less -R
+	ColorAlways
+	// ColorNever never colorizes output
+	ColorNever
+)
+
+// ParseColorMode parses the value of a --color flag: auto, always or never
+func ParseColorMode(s string) (ColorMode, error) {
+	switch s {
+	case "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	}
+	return ColorAuto, fmt.Errorf("invalid --color %q, expected auto, always or never", s)
+}
+
+// Enabled reports whether output written to f is colorized in mode m
+func (m ColorMode) Enabled(f *os.File) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape sequences of the styles used
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	red    = "\x1b[1;31m"
+	yellow = "\x1b[1;33m"
+	cyan   = "\x1b[1;36m"
+	blue   = "\x1b[1;34m"
+)
+
+// Diagnostic is a problem at a position of a script
+type Diagnostic struct {
+	Path   string
+	Line   int
+	Column int
+	// Offset is the byte offset of the position in the source, which places
+	// the caret when columns count tabs as several characters; -1 places it
+	// by Column, counted in characters
+	Offset   int
+	Severity string // error, warning or info
+	Message  string
+	// Rule is the name of the rule reporting the problem, empty for parse errors
+	Rule string
+	// Help suggests how to fix the problem, printed after the source line
+	Help string
+}
+
+// FromProblem returns the diagnostic of a problem found in the script at path
+func FromProblem(path string, p problem.Problem) Diagnostic {
+	return Diagnostic{
+		Path:     path,
+		Line:     p.Position.Line,
+		Column:   p.Position.Column,
+		Offset:   p.Position.Offset,
+		Severity: string(p.Severity),
+		Message:  p.Message,
+		Rule:     p.RuleName,
+	}
+}
+
+// FromParseError returns the diagnostic of an error parsing the script at
+// path, positioned when err is a parser.Error
+func FromParseError(path string, err error) Diagnostic {
+	d := Diagnostic{Path: path, Offset: -1, Severity: string(problem.Error), Message: err.Error()}
+	if positioned, ok := err.(parser.Error); ok {
+		d.Line, d.Column, d.Message = positioned.Line, positioned.Column, positioned.Message
+		d.Help = positioned.Suggestion
+	}
+	return d
+}
+
+// Write writes d to w, followed by the line of source it is on with a caret
+// under its column when source holds that line, its help, and a blank line:
+//
+//	error: unexpected assignment in a condition
+//	 --> player.gd:4:7
+//	  |
+//	4 | 	if x = 1:
+//	  | 	     ^
+//	  = help: did you mean '=='? '=' assigns a value, '==' compares two
+func Write(w io.Writer, d Diagnostic, source string, color bool) {
+	paint := func(style, text string) string {
+		if !color {
+			return text
+		}
+		return style + text + reset
+	}
+	severityStyle := map[string]string{"error": red, "warning": yellow}[d.Severity]
+	if severityStyle == "" {
+		severityStyle = cyan
+	}
+
+	header := d.Severity
+	if d.Rule != "" {
+		header += "[" + d.Rule + "]"
+	}
+	fmt.Fprintf(w, "%s%s\n", paint(severityStyle, header), paint(bold, ": "+d.Message))
+
+	gutter := " "
+	if d.Line < 1 {
+		fmt.Fprintf(w, " %s %s\n", paint(blue, "-->"), d.Path)
+	} else {
+		gutter = strings.Repeat(" ", len(strconv.Itoa(d.Line)))
+		fmt.Fprintf(w, "%s%s %s:%d:%d\n", gutter, paint(blue, "-->"), d.Path, d.Line, d.Column)
+		if line, prefix, ok := sourceLine(source, d); ok {
+			fmt.Fprintf(w, "%s %s\n", gutter, paint(blue, "|"))
+			fmt.Fprintf(w, "%s %s %s\n", paint(blue, strconv.Itoa(d.Line)), paint(blue, "|"), line)
+			fmt.Fprintf(w, "%s %s %s%s\n", gutter, paint(blue, "|"), prefix, paint(severityStyle, "^"))
+		}
+	}
+	if d.Help != "" {
+		fmt.Fprintf(w, "%s %s %s\n", gutter, paint(blue, "="), paint(bold, "help:")+" "+d.Help)
+	}
+	fmt.Fprintln(w)
+}
+
+// sourceLine returns the line of source d is on and the text to print
+// before its caret: the tabs of the line up to the column and a space for
+// every other character, so the caret lines up whatever the tab width
+func sourceLine(source string, d Diagnostic) (string, string, bool) {
+	start := 0
+	for line := 1; line < d.Line; line++ {
+		end := strings.IndexByte(source[start:], '\n')
+		if end < 0 {
+			return "", "", false
+		}
+		start += end + 1
+	}
+	if start == 0 && strings.HasPrefix(source, parser.ByteOrderMark) {
+		start = len(parser.ByteOrderMark)
+	}
+	line := source[start:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	line = strings.TrimSuffix(line, "\r")
+	if start == len(source) && line == "" {
+		return "", "", false
+	}
+
+	var before string
+	if d.Offset >= start && d.Offset <= start+len(line) {
+		before = line[:d.Offset-start]
+	} else {
+		before = line
+		for i, column := 0, 1; i < len(line); column++ {
+			if column == d.Column {
+				before = line[:i]
+				break
+			}
+			_, size := utf8.DecodeRuneInString(line[i:])
+			i += size
+		}
+	}
+
+	var prefix strings.Builder
+	for _, r := range before {
+		if r == '\t' {
+			prefix.WriteRune('\t')
+		} else {
+			prefix.WriteRune(' ')
+		}
+	}
+	return line, prefix.String(), true
+}
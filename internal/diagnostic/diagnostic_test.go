@@ -0,0 +1,96 @@
+package diagnostic
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func TestWrite(t *testing.T) {
+	source := "extends Node\n\nfunc foo(x):\n\tvar é = 1\n"
+	tests := []struct {
+		name     string
+		d        Diagnostic
+		expected string
+	}{
+		{
+			"problem",
+			FromProblem("player.gd", problem.NewWarning(ast.Position{Line: 3, Column: 10, Offset: 23}, "unused argument", "unused-argument")),
+			"warning[unused-argument]: unused argument\n" +
+				" --> player.gd:3:10\n" +
+				"  |\n" +
+				"3 | func foo(x):\n" +
+				"  |          ^\n\n",
+		},
+		{
+			"caret after a tab and a wide character",
+			FromParseError("player.gd", parser.Error{Line: 4, Column: 8, Message: "unexpected token"}),
+			"error: unexpected token\n" +
+				" --> player.gd:4:8\n" +
+				"  |\n" +
+				"4 | \tvar é = 1\n" +
+				"  | \t      ^\n\n",
+		},
+		{
+			"suggestion",
+			FromParseError("player.gd", parser.Error{Line: 3, Column: 12, Message: "expected ':'", Suggestion: "add ':' at the end of the line"}),
+			"error: expected ':'\n" +
+				" --> player.gd:3:12\n" +
+				"  |\n" +
+				"3 | func foo(x):\n" +
+				"  |            ^\n" +
+				"  = help: add ':' at the end of the line\n\n",
+		},
+		{
+			"line outside the source",
+			Diagnostic{Path: "player.gd", Line: 12, Column: 1, Offset: -1, Severity: "info", Message: "note"},
+			"info: note\n" +
+				"  --> player.gd:12:1\n\n",
+		},
+		{
+			"no position",
+			FromParseError("player.gd", errors.New("parser failed")),
+			"error: parser failed\n" +
+				" --> player.gd\n\n",
+		},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Write(&out, tt.d, source, false)
+		if out.String() != tt.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, tt.expected, out.String())
+		}
+	}
+}
+
+func TestWriteColor(t *testing.T) {
+	d := Diagnostic{Path: "a.gd", Line: 1, Column: 1, Offset: 0, Severity: "error", Message: "bad", Rule: "rule"}
+	var out bytes.Buffer
+	Write(&out, d, "pass\n", true)
+	if !strings.Contains(out.String(), red+"error[rule]"+reset) {
+		t.Errorf("expected a red severity, got %q", out.String())
+	}
+
+	out.Reset()
+	Write(&out, d, "pass\n", false)
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("expected no escape sequences, got %q", out.String())
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	for s, expected := range map[string]ColorMode{"auto": ColorAuto, "always": ColorAlways, "never": ColorNever} {
+		mode, err := ParseColorMode(s)
+		if err != nil || mode != expected {
+			t.Errorf("%s: got %v, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseColorMode("sometimes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,349 @@
+// Package doc extracts the reference documentation of scripts: their
+// signals, enums, constants, exported properties and functions, described by
+// the ## comments written above them or at the end of their line
+package doc
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
+)
+
+// Script is the documentation of a script
+type Script struct {
+	Path string `json:"path"`
+	Class
+}
+
+// Title returns the name the script is known by: its class_name, or its file name
+func (s *Script) Title() string {
+	if s.ClassName != "" {
+		return s.ClassName
+	}
+	return filepath.Base(s.Path)
+}
+
+// Class is the documentation of a script or an inner class. Members whose
+// name starts with an underscore are private and left out, and so are the
+// variables without an @export annotation.
+type Class struct {
+	// Name is the name of an inner class; ClassName the class_name of a script
+	Name        string     `json:"name,omitempty"`
+	ClassName   string     `json:"class_name,omitempty"`
+	Extends     string     `json:"extends,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Signals     []Signal   `json:"signals,omitempty"`
+	Enums       []Enum     `json:"enums,omitempty"`
+	Constants   []Constant `json:"constants,omitempty"`
+	Properties  []Property `json:"properties,omitempty"`
+	Functions   []Function `json:"functions,omitempty"`
+	Classes     []Class    `json:"classes,omitempty"`
+}
+
+// Parameter is a parameter of a signal or a function
+type Parameter struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+// Signal is a documented signal
+type Signal struct {
+	Name        string      `json:"name"`
+	Parameters  []Parameter `json:"parameters,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// Enum is a documented enum; Name is empty for an unnamed enum
+type Enum struct {
+	Name        string        `json:"name,omitempty"`
+	Elements    []EnumElement `json:"elements"`
+	Description string        `json:"description,omitempty"`
+}
+
+// EnumElement is an element of an enum; Value is empty when it is implicit
+type EnumElement struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Constant is a documented constant
+type Constant struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// Property is an exported variable
+type Property struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Annotations []string `json:"annotations"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Function is a documented function
+type Function struct {
+	Name        string      `json:"name"`
+	Parameters  []Parameter `json:"parameters,omitempty"`
+	ReturnType  string      `json:"return_type,omitempty"`
+	Static      bool        `json:"static,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// Extract returns the documentation of the script at path, parsed into tree
+func Extract(path string, tree *ast.AbstractSyntaxTree) *Script {
+	e := &extractor{comments: make(map[int]*ast.Comment)}
+	for _, comment := range tree.Comments {
+		if strings.HasPrefix(comment.Text, "##") {
+			e.comments[comment.Pos.Line] = comment
+			e.lines = append(e.lines, comment.Pos.Line)
+		}
+	}
+
+	script := &Script{Path: path}
+	if tree.RootClass != nil {
+		script.Class = e.class(tree.RootClass, 0)
+		script.Name = ""
+		script.ClassName = tree.RootClass.ClassName
+	}
+	return script
+}
+
+// extractor builds the documentation of the classes of a script
+type extractor struct {
+	// comments holds the ## comments of the script by line, and lines the
+	// lines they are on in order
+	comments map[int]*ast.Comment
+	lines    []int
+}
+
+// class documents class, whose body starts after the line start
+func (e *extractor) class(class *ast.Class, start int) Class {
+	doc := Class{
+		Name:        class.Name,
+		Extends:     class.Extends,
+		Description: e.classDescription(class, start),
+	}
+
+	for _, stmt := range class.Statements {
+		switch s := stmt.(type) {
+		case *ast.SignalStatement:
+			if private(s.Name) {
+				continue
+			}
+			doc.Signals = append(doc.Signals, Signal{
+				Name:        s.Name,
+				Parameters:  parameters(s.Parameters),
+				Description: e.describe(s),
+			})
+		case *ast.EnumStatement:
+			if private(s.Name) {
+				continue
+			}
+			enum := Enum{Name: s.Name, Elements: []EnumElement{}, Description: e.describe(s)}
+			for _, element := range s.Elements {
+				enum.Elements = append(enum.Elements, EnumElement{
+					Name:        element.Name,
+					Value:       expression(element.Value),
+					Description: e.describeLine(element.Pos.Line, element.Pos.Line),
+				})
+			}
+			doc.Enums = append(doc.Enums, enum)
+		case *ast.VarStatement:
+			if private(s.Name) {
+				continue
+			}
+			if s.IsConst {
+				doc.Constants = append(doc.Constants, Constant{
+					Name:        s.Name,
+					Type:        s.TypeHint,
+					Value:       expression(s.Value),
+					Description: e.describe(s),
+				})
+			} else if exported(s) {
+				property := Property{
+					Name:        s.Name,
+					Type:        s.TypeHint,
+					Default:     expression(s.Value),
+					Annotations: []string{},
+					Description: e.describe(s),
+				}
+				for _, annotation := range s.Annotations {
+					property.Annotations = append(property.Annotations, annotationText(annotation))
+				}
+				doc.Properties = append(doc.Properties, property)
+			}
+		}
+	}
+
+	for _, function := range class.Functions {
+		if private(function.Name) {
+			continue
+		}
+		doc.Functions = append(doc.Functions, Function{
+			Name:        function.Name,
+			Parameters:  parameters(function.Parameters),
+			ReturnType:  function.ReturnType,
+			Static:      function.IsStatic,
+			Description: e.describe(function),
+		})
+	}
+
+	for _, subClass := range class.SubClasses {
+		if private(subClass.Name) {
+			continue
+		}
+		inner := e.class(subClass, subClass.Pos.Line)
+		// Inner classes are described above their declaration, or at the top of their body
+		if description := e.describe(subClass); description != "" {
+			inner.Description = description
+		}
+		doc.Classes = append(doc.Classes, inner)
+	}
+	return doc
+}
+
+// classDescription returns the standalone ## comments after the line start
+// and before the first member of class, apart from those describing that
+// member. A script without members is described by all of them.
+func (e *extractor) classDescription(class *ast.Class, start int) string {
+	end := 0
+	for _, member := range members(class) {
+		if line := firstLine(member); end == 0 || line < end {
+			end = line
+		}
+	}
+	if end == 0 && start > 0 {
+		// An inner class without members has nothing but pass in its body
+		return ""
+	}
+	if end > 0 {
+		end -= len(e.linesAbove(end))
+	}
+
+	var lines []string
+	for _, line := range e.lines {
+		comment := e.comments[line]
+		if line > start && (end == 0 || line < end) && !comment.Inline {
+			lines = append(lines, docText(comment))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// describe returns the ## comments describing a declaration: those on the
+// lines right above it and its annotations, or the one at the end of its line
+func (e *extractor) describe(node ast.Node) string {
+	return e.describeLine(firstLine(node), node.Position().Line)
+}
+
+// describeLine returns the ## comments on the lines right above first, or the
+// one ending line
+func (e *extractor) describeLine(first, line int) string {
+	if above := e.linesAbove(first); len(above) > 0 {
+		return strings.TrimSpace(strings.Join(above, "\n"))
+	}
+	if comment, ok := e.comments[line]; ok && comment.Inline {
+		return docText(comment)
+	}
+	return ""
+}
+
+// linesAbove returns the text of the standalone ## comments on the lines
+// right above line, in source order
+func (e *extractor) linesAbove(line int) []string {
+	var lines []string
+	for l := line - 1; l > 0; l-- {
+		comment, ok := e.comments[l]
+		if !ok || comment.Inline {
+			break
+		}
+		lines = append([]string{docText(comment)}, lines...)
+	}
+	return lines
+}
+
+// members returns the declarations of class in no particular order
+func members(class *ast.Class) []ast.Node {
+	var nodes []ast.Node
+	for _, stmt := range class.Statements {
+		nodes = append(nodes, stmt)
+	}
+	for _, function := range class.Functions {
+		nodes = append(nodes, function)
+	}
+	for _, subClass := range class.SubClasses {
+		nodes = append(nodes, subClass)
+	}
+	return nodes
+}
+
+// firstLine returns the line a declaration starts on, annotations included
+func firstLine(node ast.Node) int {
+	line := node.Position().Line
+	for _, annotation := range ast.AnnotationsOf(node) {
+		if annotation.Pos.Line < line {
+			line = annotation.Pos.Line
+		}
+	}
+	return line
+}
+
+// docText returns the text of a ## comment without its marker and the space after it
+func docText(comment *ast.Comment) string {
+	text := strings.TrimPrefix(comment.Text, "##")
+	return strings.TrimPrefix(text, " ")
+}
+
+// private reports whether a name follows the convention for private members
+func private(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// exported reports whether a variable has an @export annotation
+func exported(v *ast.VarStatement) bool {
+	for _, annotation := range v.Annotations {
+		if annotation.Name == "export" || strings.HasPrefix(annotation.Name, "export_") {
+			return true
+		}
+	}
+	return false
+}
+
+func parameters(params []*ast.Parameter) []Parameter {
+	var result []Parameter
+	for _, param := range params {
+		result = append(result, Parameter{
+			Name:    param.Name,
+			Type:    param.TypeHint,
+			Default: expression(param.Default),
+		})
+	}
+	return result
+}
+
+// expression formats an expression as gdformat writes it; "" for nil
+func expression(expr ast.Expression) string {
+	if expr == nil {
+		return ""
+	}
+	return formatter.FormatExpression(expr, nil)
+}
+
+// annotationText formats an annotation as written in the source, e.g. @export_range(0, 10)
+func annotationText(annotation *ast.Annotation) string {
+	if len(annotation.Args) == 0 {
+		return "@" + annotation.Name
+	}
+	var args []string
+	for _, arg := range annotation.Args {
+		args = append(args, expression(arg))
+	}
+	return "@" + annotation.Name + "(" + strings.Join(args, ", ") + ")"
+}
@@ -0,0 +1,151 @@
+package doc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+const playerScript = `## A controllable player.
+##
+## Moves and jumps.
+class_name Player
+extends CharacterBody2D
+
+## Emitted when the player is hit.
+signal hit(damage: int)
+signal _internal
+
+## Movement states.
+enum State {
+	IDLE,  ## Standing still.
+	RUN = 2,
+}
+
+## Top speed.
+const MAX_SPEED = 300
+
+## Walking speed.
+@export_range(0, 100)
+var speed: float = 10.0
+@export var jump = 2  ## Jump height.
+var not_exported = 2
+
+
+## Moves the player.
+func move(direction: Vector2, boost = 1.5) -> void:
+	pass
+
+
+func _ready():
+	pass
+
+
+## A hitbox.
+class Hitbox:
+	## Radius of the box.
+	const RADIUS = 4
+`
+
+func extract(t *testing.T, source string) *Script {
+	t.Helper()
+	tree, errors := parser.ParseFile("player.gd", source)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	return Extract("player.gd", tree)
+}
+
+func TestExtract(t *testing.T) {
+	script := extract(t, playerScript)
+
+	if script.Title() != "Player" || script.Extends != "CharacterBody2D" || script.Name != "" {
+		t.Errorf("Expected Player extending CharacterBody2D, got %q (%q) extending %q",
+			script.Title(), script.Name, script.Extends)
+	}
+	if script.Description != "A controllable player.\n\nMoves and jumps." {
+		t.Errorf("Unexpected class description %q", script.Description)
+	}
+
+	if len(script.Signals) != 1 || script.Signals[0].Description != "Emitted when the player is hit." ||
+		script.Signals[0].Parameters[0] != (Parameter{Name: "damage", Type: "int"}) {
+		t.Errorf("Expected the public signal hit(damage: int), got %+v", script.Signals)
+	}
+
+	if len(script.Enums) != 1 || len(script.Enums[0].Elements) != 2 {
+		t.Fatalf("Expected enum State with 2 elements, got %+v", script.Enums)
+	}
+	elements := script.Enums[0].Elements
+	if elements[0].Description != "Standing still." || elements[1].Value != "2" || elements[1].Description != "" {
+		t.Errorf("Unexpected enum elements %+v", elements)
+	}
+
+	if len(script.Constants) != 1 || script.Constants[0].Value != "300" || script.Constants[0].Description != "Top speed." {
+		t.Errorf("Unexpected constants %+v", script.Constants)
+	}
+
+	if len(script.Properties) != 2 {
+		t.Fatalf("Expected the 2 exported variables, got %+v", script.Properties)
+	}
+	speed, jump := script.Properties[0], script.Properties[1]
+	if speed.Type != "float" || speed.Default != "10.0" || strings.Join(speed.Annotations, " ") != "@export_range(0, 100)" ||
+		speed.Description != "Walking speed." {
+		t.Errorf("Unexpected property %+v", speed)
+	}
+	if jump.Description != "Jump height." {
+		t.Errorf("Expected the inline description of jump, got %+v", jump)
+	}
+
+	if len(script.Functions) != 1 || script.Functions[0].Name != "move" || script.Functions[0].ReturnType != "void" ||
+		script.Functions[0].Parameters[1].Default != "1.5" || script.Functions[0].Description != "Moves the player." {
+		t.Errorf("Expected only the public function move, got %+v", script.Functions)
+	}
+
+	if len(script.Classes) != 1 || script.Classes[0].Description != "A hitbox." ||
+		len(script.Classes[0].Constants) != 1 || script.Classes[0].Constants[0].Description != "Radius of the box." {
+		t.Errorf("Unexpected inner classes %+v", script.Classes)
+	}
+}
+
+func TestExtract_ClassDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		description string
+	}{
+		{"no_members", "extends Node\n## Only a description.\n", "Only a description."},
+		{"first_member_documented", "## The script.\n\n## The signal.\nsignal hit\n", "The script."},
+		{"plain_comments_ignored", "# License header\nextends Node\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if script := extract(t, tt.source); script.Description != tt.description {
+				t.Errorf("Expected description %q, got %q", tt.description, script.Description)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	page := Markdown(extract(t, playerScript))
+
+	for _, expected := range []string{
+		"# Player\n\n**Extends:** `CharacterBody2D`\n\nA controllable player.\n\nMoves and jumps.\n",
+		"## Signals\n\n### hit\n\n`signal hit(damage: int)`\n\nEmitted when the player is hit.\n",
+		"### State\n\n`enum State`\n\nMovement states.\n\n- `IDLE`: Standing still.\n- `RUN = 2`\n",
+		"`const MAX_SPEED = 300`",
+		"`@export_range(0, 100) var speed: float = 10.0`",
+		"`func move(direction: Vector2, boost = 1.5) -> void`",
+		"## class Hitbox\n\nA hitbox.\n\n### Constants\n\n#### RADIUS\n",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the page to contain:\n%s\nPage:\n%s", expected, page)
+		}
+	}
+	for _, private := range []string{"_internal", "_ready", "not_exported"} {
+		if strings.Contains(page, private) {
+			t.Errorf("Expected %s to be left out of the page", private)
+		}
+	}
+}
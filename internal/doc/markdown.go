@@ -0,0 +1,135 @@
+package doc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the documentation of a script as a Markdown reference page
+func Markdown(script *Script) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", script.Title())
+	writeClass(&b, &script.Class, 2)
+	return b.String()
+}
+
+// writeClass writes the description and the members of class, with its
+// sections at the heading level given
+func writeClass(b *strings.Builder, class *Class, level int) {
+	section := strings.Repeat("#", level)
+	member := section + "#"
+
+	if class.Extends != "" {
+		fmt.Fprintf(b, "\n**Extends:** `%s`\n", class.Extends)
+	}
+	writeDescription(b, class.Description)
+
+	if len(class.Signals) > 0 {
+		fmt.Fprintf(b, "\n%s Signals\n", section)
+		for _, signal := range class.Signals {
+			declaration := "signal " + signal.Name
+			if len(signal.Parameters) > 0 {
+				declaration += formatParameters(signal.Parameters)
+			}
+			writeMember(b, member, signal.Name, declaration, signal.Description)
+		}
+	}
+
+	if len(class.Enums) > 0 {
+		fmt.Fprintf(b, "\n%s Enums\n", section)
+		for _, enum := range class.Enums {
+			name, declaration := enum.Name, "enum "+enum.Name
+			if name == "" {
+				name, declaration = "(unnamed)", "enum"
+			}
+			writeMember(b, member, name, declaration, enum.Description)
+			b.WriteString("\n")
+			for _, element := range enum.Elements {
+				text := element.Name
+				if element.Value != "" {
+					text += " = " + element.Value
+				}
+				fmt.Fprintf(b, "- `%s`", text)
+				if element.Description != "" {
+					b.WriteString(": " + strings.ReplaceAll(element.Description, "\n", " "))
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	if len(class.Constants) > 0 {
+		fmt.Fprintf(b, "\n%s Constants\n", section)
+		for _, constant := range class.Constants {
+			declaration := "const " + constant.Name
+			if constant.Type != "" {
+				declaration += ": " + constant.Type
+			}
+			declaration += " = " + constant.Value
+			writeMember(b, member, constant.Name, declaration, constant.Description)
+		}
+	}
+
+	if len(class.Properties) > 0 {
+		fmt.Fprintf(b, "\n%s Properties\n", section)
+		for _, property := range class.Properties {
+			declaration := strings.Join(append(property.Annotations, "var "+property.Name), " ")
+			if property.Type != "" {
+				declaration += ": " + property.Type
+			}
+			if property.Default != "" {
+				declaration += " = " + property.Default
+			}
+			writeMember(b, member, property.Name, declaration, property.Description)
+		}
+	}
+
+	if len(class.Functions) > 0 {
+		fmt.Fprintf(b, "\n%s Functions\n", section)
+		for _, function := range class.Functions {
+			declaration := "func " + function.Name + formatParameters(function.Parameters)
+			if function.Static {
+				declaration = "static " + declaration
+			}
+			if function.ReturnType != "" {
+				declaration += " -> " + function.ReturnType
+			}
+			writeMember(b, member, function.Name, declaration, function.Description)
+		}
+	}
+
+	for i := range class.Classes {
+		inner := &class.Classes[i]
+		fmt.Fprintf(b, "\n%s class %s\n", section, inner.Name)
+		writeClass(b, inner, level+1)
+	}
+}
+
+// writeMember writes the heading, the declaration and the description of a member
+func writeMember(b *strings.Builder, heading, name, declaration, description string) {
+	fmt.Fprintf(b, "\n%s %s\n\n`%s`\n", heading, name, declaration)
+	writeDescription(b, description)
+}
+
+// writeDescription writes a description as a paragraph of its own
+func writeDescription(b *strings.Builder, description string) {
+	if description != "" {
+		fmt.Fprintf(b, "\n%s\n", description)
+	}
+}
+
+// formatParameters formats a parenthesized parameter list
+func formatParameters(params []Parameter) string {
+	var parts []string
+	for _, param := range params {
+		part := param.Name
+		if param.Type != "" {
+			part += ": " + param.Type
+		}
+		if param.Default != "" {
+			part += " = " + param.Default
+		}
+		parts = append(parts, part)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,462 @@
+// Package doctor diagnoses the setup of a project: its gdlintrc, its Godot
+// project file and its scripts, reporting each problem with a way to fix it
+package doctor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// Status is the outcome of a check
+type Status int
+
+const (
+	// StatusOK means the check passed
+	StatusOK Status = iota
+	// StatusWarning means the setup works but probably not as intended
+	StatusWarning
+	// StatusError means the setup is broken
+	StatusError
+)
+
+// String returns a string representation of the status
+func (s Status) String() string {
+	switch s {
+	case StatusWarning:
+		return "warning"
+	case StatusError:
+		return "error"
+	default:
+		return "ok"
+	}
+}
+
+// Finding is the result of one check
+type Finding struct {
+	Check   string // area checked: "config", "project" or "files"
+	Status  Status
+	Message string
+	Fix     string // remediation step; empty for passed checks
+}
+
+// String returns a string representation of the finding
+func (f Finding) String() string {
+	s := fmt.Sprintf("[%s] %s: %s", f.Status, f.Check, f.Message)
+	if f.Fix != "" {
+		s += "\n    fix: " + f.Fix
+	}
+	return s
+}
+
+// LargeFileBytes is the size above which a script is reported as suspiciously large
+const LargeFileBytes = 1 << 20
+
+// configFileNames are the names gdlint looks for, in order of precedence
+var configFileNames = []string{"gdlintrc.json", ".gdlintrc.json", "gdlintrc", ".gdlintrc"}
+
+// configKeys are the top-level keys of a gdlintrc, the JSON names of the
+// fields of linter.Config
+var configKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	config := reflect.TypeOf(linter.Config{})
+	for i := 0; i < config.NumField(); i++ {
+		if name, _, _ := strings.Cut(config.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
+// Diagnose checks the setup of the project containing dir. Rule names in
+// the config are checked against ruleNames.
+func Diagnose(dir string, ruleNames []string) []Finding {
+	d := &diagnosis{rules: make(map[string]bool), external: make(map[string]bool)}
+	for _, name := range ruleNames {
+		d.rules[name] = true
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err == nil {
+		_, err = os.Stat(dir)
+	}
+	if err != nil {
+		d.report("files", StatusError, fmt.Sprintf("cannot access %s: %v", dir, err),
+			"check the path and its permissions")
+		return d.findings
+	}
+
+	config := d.checkConfig(dir)
+	d.checkProject(dir, config)
+	d.checkFiles(dir)
+	return d.findings
+}
+
+// Run prints the findings for the project containing dir, checking rule
+// names against every registered rule, and returns the exit status: 1 when
+// a finding is an error
+func Run(w io.Writer, dir string) int {
+	var ruleNames []string
+	for _, rule := range rules.GetAllRules() {
+		ruleNames = append(ruleNames, rule.Name())
+	}
+
+	findings := Diagnose(dir, ruleNames)
+	for _, finding := range findings {
+		fmt.Fprintln(w, finding)
+	}
+	if HasErrors(findings) {
+		return 1
+	}
+	return 0
+}
+
+// HasErrors reports whether any finding is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Status == StatusError {
+			return true
+		}
+	}
+	return false
+}
+
+type diagnosis struct {
+	rules    map[string]bool
+	external map[string]bool // rules declared in external_rules
+	findings []Finding
+}
+
+func (d *diagnosis) report(check string, status Status, message, fix string) {
+	d.findings = append(d.findings, Finding{Check: check, Status: status, Message: message, Fix: fix})
+}
+
+// checkConfig validates the gdlintrc used for files in dir and returns the
+// configuration gdlint will use
+func (d *diagnosis) checkConfig(dir string) linter.Config {
+	path := linter.FindConfigFileFrom(dir)
+	if path == "" {
+		d.report("config", StatusOK, "no gdlintrc found, using the default configuration", "")
+		return linter.DefaultConfig()
+	}
+
+	var shadowed []string
+	for _, name := range configFileNames {
+		other := filepath.Join(filepath.Dir(path), name)
+		if other != path {
+			if _, err := os.Stat(other); err == nil {
+				shadowed = append(shadowed, name)
+			}
+		}
+	}
+	if len(shadowed) > 0 {
+		d.report("config", StatusWarning,
+			fmt.Sprintf("%s is used and %s in the same directory is ignored", filepath.Base(path), strings.Join(shadowed, ", ")),
+			"merge the settings into one file and delete the others")
+	}
+
+	config, err := linter.LoadConfig(path)
+	if err != nil {
+		d.report("config", StatusError, fmt.Sprintf("%s: %v", path, err),
+			"fix the file so that it is valid JSON and every path it names exists")
+		return linter.DefaultConfig()
+	}
+
+	problems := len(d.findings)
+	d.checkConfigKeys(path)
+	d.checkExternalRules(config)
+	d.checkRuleNames(config)
+	d.checkRuleSettings(config)
+	d.checkStrictPaths(config)
+	if len(d.findings) == problems {
+		d.report("config", StatusOK, "using "+path, "")
+	}
+	return config
+}
+
+// checkConfigKeys reports top-level keys gdlint does not read, which are usually typos
+func (d *diagnosis) checkConfigKeys(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+	for _, key := range sortedKeys(raw) {
+		if !configKeys[key] {
+			d.report("config", StatusWarning, fmt.Sprintf("unknown setting %q is ignored", key),
+				"use one of "+strings.Join(sortedKeys(configKeys), ", "))
+		}
+	}
+}
+
+// checkExternalRules reports external rules without a name or whose
+// executable cannot be found, and makes the others known rules
+func (d *diagnosis) checkExternalRules(config linter.Config) {
+	for i, rule := range config.ExternalRules() {
+		if rule.Name() == "" {
+			d.report("config", StatusError, fmt.Sprintf("external rule %d has no name", i+1),
+				`give it a "name", used to disable it or configure it like other rules`)
+			continue
+		}
+		if d.rules[rule.Name()] && !d.external[rule.Name()] {
+			d.report("config", StatusError, fmt.Sprintf("external rule %q has the name of a built-in rule", rule.Name()),
+				"rename the external rule")
+		}
+		d.rules[rule.Name()] = true
+		d.external[rule.Name()] = true
+
+		executable := rule.(*linter.ExternalRule).Executable()
+		if executable == "" {
+			d.report("config", StatusError, fmt.Sprintf("external rule %q has no command", rule.Name()),
+				`set "command" to the executable and its arguments`)
+		} else if _, err := exec.LookPath(executable); err != nil {
+			d.report("config", StatusError, fmt.Sprintf("the executable of external rule %q cannot be run: %v", rule.Name(), err),
+				"relative paths are relative to the gdlintrc; check the path and that the file is executable")
+		}
+	}
+}
+
+// checkRuleNames reports rules that do not exist and settings for disabled rules
+func (d *diagnosis) checkRuleNames(config linter.Config) {
+	unknown := func(name, where string) {
+		if !d.rules[name] {
+			d.report("config", StatusWarning, fmt.Sprintf("unknown rule %q in %s", name, where),
+				"check the rule name for typos, or remove the entry")
+		}
+	}
+
+	for _, name := range config.DisabledRules {
+		unknown(name, "disabled_rules")
+	}
+	for _, name := range config.EnabledRules {
+		unknown(name, "enabled_rules")
+		if !config.IsRuleEnabled(name) {
+			d.report("config", StatusWarning, fmt.Sprintf("rule %q is in both enabled_rules and disabled_rules, so it does not run", name),
+				"remove the rule from one of the two lists")
+		}
+	}
+	for _, name := range sortedKeys(config.RuleSettings) {
+		unknown(name, "rule_settings")
+		if !config.IsRuleEnabled(name) {
+			d.report("config", StatusWarning, fmt.Sprintf("rule %q is disabled, so its settings have no effect", name),
+				"remove the rule from disabled_rules or its entry from rule_settings")
+		}
+	}
+	for _, name := range sortedKeys(config.Severities) {
+		unknown(name, "severity")
+	}
+	for _, strict := range config.Strict {
+		for _, name := range strict.Rules {
+			unknown(name, "strict")
+			if !config.IsRuleEnabled(name) {
+				d.report("config", StatusWarning,
+					fmt.Sprintf("rule %q is disabled, so making it strict under %s has no effect", name, strict.Path),
+					"remove the rule from disabled_rules or from the strict entry")
+			}
+		}
+	}
+}
+
+// checkRuleSettings reports settings of the wrong type
+func (d *diagnosis) checkRuleSettings(config linter.Config) {
+	for _, name := range sortedKeys(config.RuleSettings) {
+		if d.external[name] {
+			// External rules read settings of any type
+			continue
+		}
+		settings, ok := config.RuleSettings[name].(map[string]any)
+		if !ok {
+			d.report("config", StatusError, fmt.Sprintf("settings of rule %q must be an object", name),
+				fmt.Sprintf(`write them as "%s": {"threshold": <number>}`, name))
+			continue
+		}
+		for _, key := range sortedKeys(settings) {
+			switch value := settings[key].(type) {
+			case float64:
+				if key == "pattern" {
+					d.report("config", StatusError, fmt.Sprintf("%s.pattern must be a string", name),
+						"quote the regular expression")
+				}
+			case string:
+				if key != "pattern" {
+					d.report("config", StatusError, fmt.Sprintf("%s.%s must be a number, not %q", name, key, value),
+						"remove the quotes around the number")
+				} else if _, err := regexp.Compile(value); err != nil {
+					d.report("config", StatusError, fmt.Sprintf("%s.pattern is not a valid regular expression: %v", name, err),
+						"fix the regular expression; backslashes must be doubled in JSON")
+				}
+			}
+		}
+	}
+}
+
+// checkStrictPaths reports strict directories that match nothing on disk
+func (d *diagnosis) checkStrictPaths(config linter.Config) {
+	for _, strict := range config.Strict {
+		pattern := filepath.Join(config.Dir, filepath.FromSlash(strings.TrimSuffix(strict.Path, "/**")))
+		if matches, err := filepath.Glob(pattern); err != nil || len(matches) == 0 {
+			d.report("config", StatusWarning, fmt.Sprintf("strict path %q matches nothing under %s", strict.Path, config.Dir),
+				"strict paths are relative to the gdlintrc; fix or remove the entry")
+		}
+	}
+}
+
+// checkProject finds project.godot and compares its engine version with the
+// grammar and API gdtoolkit uses
+func (d *diagnosis) checkProject(dir string, config linter.Config) {
+	path := findUp(dir, "project.godot")
+	if path == "" {
+		d.report("project", StatusWarning, "no project.godot found in "+dir+" or its parents",
+			"run doctor from inside a Godot project so that res:// paths can be resolved")
+		return
+	}
+
+	configVersion, features, err := readProject(path)
+	if err != nil {
+		d.report("project", StatusError, fmt.Sprintf("cannot read %s: %v", path, err), "check the file permissions")
+		return
+	}
+	if configVersion != 0 && configVersion < 5 {
+		d.report("project", StatusError, fmt.Sprintf("%s is a Godot 3 project (config_version=%d)", path, configVersion),
+			"gdtoolkit parses Godot 4 GDScript; use the Python gdtoolkit 3.x for Godot 3 projects")
+		return
+	}
+
+	api := config.API().Header
+	major, minor, ok := engineVersion(features)
+	switch {
+	case !ok:
+		d.report("project", StatusOK, "using "+path, "")
+	case major != api.VersionMajor || minor > api.VersionMinor:
+		d.report("project", StatusWarning,
+			fmt.Sprintf("project targets Godot %d.%d but the API database is for Godot %d.%d",
+				major, minor, api.VersionMajor, api.VersionMinor),
+			"dump the API with `godot --headless --dump-extension-api` and set godot_api in gdlintrc")
+	default:
+		d.report("project", StatusOK, fmt.Sprintf("using %s (Godot %d.%d)", path, major, minor), "")
+	}
+}
+
+// checkFiles reports scripts that cannot be read or are suspiciously large
+func (d *diagnosis) checkFiles(dir string) {
+	root := dir
+	if project := findUp(dir, "project.godot"); project != "" {
+		root = filepath.Dir(project)
+	}
+
+	count, problems := 0, len(d.findings)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			d.report("files", StatusError, fmt.Sprintf("cannot access %s: %v", path, err), "check the permissions of the path")
+			return nil
+		}
+		if info.IsDir() {
+			// Godot's import cache is not part of the sources
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".gd" {
+			return nil
+		}
+
+		count++
+		file, err := os.Open(path)
+		if err != nil {
+			d.report("files", StatusError, fmt.Sprintf("cannot read %s: %v", path, err), "check the permissions of the file")
+			return nil
+		}
+		file.Close()
+		if info.Size() > LargeFileBytes {
+			d.report("files", StatusWarning, fmt.Sprintf("%s is %d KiB, which is unusually large for a script", path, info.Size()>>10),
+				"if the file is generated, exclude it from linting and formatting")
+		}
+		return nil
+	})
+	if err != nil {
+		d.report("files", StatusError, fmt.Sprintf("cannot walk %s: %v", root, err), "check the permissions of the directory")
+		return
+	}
+	if len(d.findings) == problems {
+		d.report("files", StatusOK, fmt.Sprintf("%d scripts under %s", count, root), "")
+	}
+}
+
+// findUp looks for a file named name in dir and its parents
+func findUp(dir, name string) string {
+	for {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readProject reads the config_version and config/features entries of a project.godot
+func readProject(path string) (configVersion int, features string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "config_version":
+			configVersion, _ = strconv.Atoi(value)
+		case "config/features":
+			features = value
+		}
+	}
+	return configVersion, features, scanner.Err()
+}
+
+// featureVersion matches the engine version among the features of a project,
+// e.g. "4.2" in PackedStringArray("4.2", "Forward Plus")
+var featureVersion = regexp.MustCompile(`"(\d+)\.(\d+)"`)
+
+// engineVersion returns the engine version listed in the features of a project
+func engineVersion(features string) (major, minor int, ok bool) {
+	match := featureVersion.FindStringSubmatch(features)
+	if match == nil {
+		return 0, 0, false
+	}
+	major, _ = strconv.Atoi(match[1])
+	minor, _ = strconv.Atoi(match[2])
+	return major, minor, true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
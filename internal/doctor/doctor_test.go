@@ -0,0 +1,213 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var ruleNames = []string{"unused-argument", "max-line-length", "function-name"}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// messages returns the messages of the findings with the given status
+func messages(findings []Finding, status Status) []string {
+	var result []string
+	for _, f := range findings {
+		if f.Status == status {
+			result = append(result, f.Message)
+		}
+	}
+	return result
+}
+
+func TestDiagnoseHealthyProject(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"project.godot":   "config_version=5\n\n[application]\nconfig/features=PackedStringArray(\"4.2\", \"Forward Plus\")\n",
+		"gdlintrc.json":   `{"enabled_rules": ["max-line-length"], "rule_settings": {"max-line-length": {"threshold": 120}}, "strict": [{"path": "src/core/**"}], "tab_width": 4}`,
+		"src/core/a.gd":   "func a():\n\tpass\n",
+		".godot/cache.gd": "",
+	})
+
+	findings := Diagnose(filepath.Join(dir, "src"), ruleNames)
+	for _, f := range findings {
+		if f.Status != StatusOK {
+			t.Errorf("Unexpected finding: %s", f)
+		}
+	}
+	if len(findings) != 3 {
+		t.Fatalf("Expected one finding per check, got %v", findings)
+	}
+	if !strings.Contains(findings[2].Message, "1 scripts") {
+		t.Errorf("Expected hidden directories to be skipped, got %q", findings[2].Message)
+	}
+}
+
+func TestDiagnoseConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gdlintrc.json": `{
+	"disabled_rules": ["unused-argument", "unused-arguments"],
+	"enabled_rules": ["unused-argument", "unused-signals"],
+	"rule_settings": {
+		"unused-argument": {"threshold": 3},
+		"max-line-length": {"threshold": "120"},
+		"function-name": {"pattern": "[a-z"}
+	},
+	"strict": [{"path": "missing", "rules": ["unused-argument"]}],
+	"disable_rules": []
+}`,
+		".gdlintrc": `{}`,
+	})
+
+	findings := Diagnose(dir, ruleNames)
+	warnings := strings.Join(messages(findings, StatusWarning), "\n")
+	for _, expected := range []string{
+		".gdlintrc in the same directory is ignored",
+		`unknown setting "disable_rules"`,
+		`unknown rule "unused-arguments" in disabled_rules`,
+		`rule "unused-argument" is disabled, so its settings have no effect`,
+		`unknown rule "unused-signals" in enabled_rules`,
+		`rule "unused-argument" is in both enabled_rules and disabled_rules`,
+		`making it strict under missing has no effect`,
+		`strict path "missing" matches nothing`,
+		"no project.godot found",
+	} {
+		if !strings.Contains(warnings, expected) {
+			t.Errorf("Expected a warning containing %q, got:\n%s", expected, warnings)
+		}
+	}
+
+	errors := strings.Join(messages(findings, StatusError), "\n")
+	for _, expected := range []string{
+		`max-line-length.threshold must be a number`,
+		`function-name.pattern is not a valid regular expression`,
+	} {
+		if !strings.Contains(errors, expected) {
+			t.Errorf("Expected an error containing %q, got:\n%s", expected, errors)
+		}
+	}
+	if !HasErrors(findings) {
+		t.Error("Expected HasErrors to report the errors")
+	}
+}
+
+func TestDiagnoseExternalRules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gdlintrc.json": `{
+	"disabled_rules": ["banned-calls"],
+	"rule_settings": {"banned-calls": {"calls": ["OS.execute"]}},
+	"external_rules": [
+		{"name": "banned-calls", "command": ["sh", "rules/banned.sh"]},
+		{"name": "missing", "command": ["./rules/missing.sh"]},
+		{"name": "unused-argument", "command": ["sh"]},
+		{"command": ["sh"]}
+	]
+}`,
+	})
+
+	findings := Diagnose(dir, ruleNames)
+	errors := strings.Join(messages(findings, StatusError), "\n")
+	for _, expected := range []string{
+		`the executable of external rule "missing" cannot be run`,
+		`external rule "unused-argument" has the name of a built-in rule`,
+		"external rule 4 has no name",
+	} {
+		if !strings.Contains(errors, expected) {
+			t.Errorf("Expected an error containing %q, got:\n%s", expected, errors)
+		}
+	}
+	warnings := strings.Join(messages(findings, StatusWarning), "\n")
+	if strings.Contains(warnings, "unknown") || strings.Contains(errors, "calls") {
+		t.Errorf("Expected the external rule and its settings to be known, got:\n%s\n%s", warnings, errors)
+	}
+}
+
+func TestDiagnoseInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"gdlintrc.json": `{"disabled_rules": [`})
+
+	errors := messages(Diagnose(dir, ruleNames), StatusError)
+	if len(errors) != 1 || !strings.Contains(errors[0], "failed to parse config file") {
+		t.Errorf("Expected a parse error, got %v", errors)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"gdlintrc.json": `{"enabled_rules": ["unused-argument"], "tab_width": 2}`})
+
+	var out strings.Builder
+	if status := Run(&out, dir); status != 0 {
+		t.Errorf("Expected status 0, got %d:\n%s", status, out.String())
+	}
+	if strings.Contains(out.String(), "unknown") {
+		t.Errorf("Expected every setting and rule to be known, got:\n%s", out.String())
+	}
+
+	writeFiles(t, dir, map[string]string{"gdlintrc.json": `{"disabled_rules": [`})
+	out.Reset()
+	if status := Run(&out, dir); status != 1 {
+		t.Errorf("Expected status 1 for an invalid config, got %d:\n%s", status, out.String())
+	}
+}
+
+func TestDiagnoseProjectVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		status  Status
+	}{
+		{"godot 3", "config_version=4\n", StatusError},
+		{"newer godot 4", "config_version=5\nconfig/features=PackedStringArray(\"4.9\")\n", StatusWarning},
+		{"godot 5", "config_version=6\nconfig/features=PackedStringArray(\"5.0\")\n", StatusWarning},
+		{"no features", "config_version=5\n", StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"project.godot": tt.project})
+
+			for _, f := range Diagnose(dir, ruleNames) {
+				if f.Check == "project" && f.Status != tt.status {
+					t.Errorf("Expected status %s, got %s", tt.status, f)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnoseLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"project.godot": "config_version=5\n",
+		"generated.gd":  strings.Repeat("var x = 1\n", LargeFileBytes/10+1),
+	})
+
+	warnings := messages(Diagnose(dir, ruleNames), StatusWarning)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "generated.gd") {
+		t.Errorf("Expected a warning about generated.gd, got %v", warnings)
+	}
+}
+
+func TestDiagnoseMissingDirectory(t *testing.T) {
+	findings := Diagnose(filepath.Join(t.TempDir(), "missing"), ruleNames)
+	if len(findings) != 1 || findings[0].Status != StatusError {
+		t.Errorf("Expected a single error for a missing directory, got %v", findings)
+	}
+}
@@ -0,0 +1,146 @@
+// Package metrics measures the size and complexity of scripts from their
+// syntax trees: lines of code and comments, classes, functions with their
+// length and nesting depth, and the totals of a set of scripts.
+package metrics
+
+import (
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// Function is the measure of a function
+type Function struct {
+	Name string `json:"name"`
+	Line int    `json:"line"`
+	// Lines is the number of lines from the header to the last line of the
+	// body, including the blank and comment lines within it
+	Lines int `json:"lines"`
+	// NestingDepth is the deepest nesting of if, for, while and match blocks
+	NestingDepth int `json:"nesting_depth"`
+}
+
+// Summary is the measure of a script, or the total of several
+type Summary struct {
+	// Path is the path of the script, or "total"
+	Path string `json:"path"`
+	// Lines counts every line; a line with code and a comment counts as a
+	// code line and as a comment line
+	Lines        int `json:"lines"`
+	CodeLines    int `json:"code_lines"`
+	CommentLines int `json:"comment_lines"`
+	BlankLines   int `json:"blank_lines"`
+	// Classes counts the script classes and their inner classes
+	Classes   int `json:"classes"`
+	Functions int `json:"functions"`
+	// AverageFunctionLines is the average of the function lengths
+	AverageFunctionLines float64 `json:"average_function_lines"`
+	// LongestFunction is the name of the longest function, prefixed with
+	// the path of its script in totals, and "" when there are no functions
+	LongestFunction      string `json:"longest_function"`
+	LongestFunctionLines int    `json:"longest_function_lines"`
+	MaxNestingDepth      int    `json:"max_nesting_depth"`
+	// CommentDensity is the share of the non-blank lines with a comment
+	CommentDensity float64 `json:"comment_density"`
+
+	// functionLines is the sum of the function lengths
+	functionLines int
+}
+
+// Measure measures the script at path, parsed from source into tree, and
+// returns its summary with the measure of each function in source order
+func Measure(path, source string, tree *ast.AbstractSyntaxTree) (Summary, []Function) {
+	summary := Summary{Path: path}
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	if source == "" {
+		lines = nil
+	}
+
+	// Comments alone on their line, or after code
+	comments := make(map[int]*ast.Comment)
+	for _, comment := range tree.Comments {
+		comments[comment.Pos.Line] = comment
+	}
+	for i, line := range lines {
+		comment := comments[i+1]
+		switch {
+		case strings.TrimSpace(line) == "":
+			summary.BlankLines++
+		case comment != nil && !comment.Inline:
+			summary.CommentLines++
+		default:
+			summary.CodeLines++
+			if comment != nil {
+				summary.CommentLines++
+			}
+		}
+	}
+	summary.Lines = len(lines)
+
+	var functions []Function
+	ast.Inspect(tree, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Class:
+			summary.Classes++
+		case *ast.Function:
+			depth, _ := analysis.NestingDepth(n.Statements)
+			start := n.Position().Line
+			functions = append(functions, Function{
+				Name:         n.Name,
+				Line:         start,
+				Lines:        n.End.Line - start + 1,
+				NestingDepth: depth,
+			})
+		}
+		return true
+	})
+
+	for _, function := range functions {
+		summary.add(function, function.Name)
+	}
+	summary.finish()
+	return summary, functions
+}
+
+// add counts function in the summary, naming it name when it is the longest
+func (s *Summary) add(function Function, name string) {
+	s.Functions++
+	s.functionLines += function.Lines
+	if function.Lines > s.LongestFunctionLines {
+		s.LongestFunction, s.LongestFunctionLines = name, function.Lines
+	}
+	if function.NestingDepth > s.MaxNestingDepth {
+		s.MaxNestingDepth = function.NestingDepth
+	}
+}
+
+// finish computes the averages and ratios of the summary
+func (s *Summary) finish() {
+	s.AverageFunctionLines = 0
+	if s.Functions > 0 {
+		s.AverageFunctionLines = float64(s.functionLines) / float64(s.Functions)
+	}
+	s.CommentDensity = 0
+	if nonBlank := s.Lines - s.BlankLines; nonBlank > 0 {
+		s.CommentDensity = float64(s.CommentLines) / float64(nonBlank)
+	}
+}
+
+// Total returns the total of the summaries of scripts, measured along with
+// their functions, as a summary whose path is "total"
+func Total(summaries []Summary, functions [][]Function) Summary {
+	total := Summary{Path: "total"}
+	for i, summary := range summaries {
+		total.Lines += summary.Lines
+		total.CodeLines += summary.CodeLines
+		total.CommentLines += summary.CommentLines
+		total.BlankLines += summary.BlankLines
+		total.Classes += summary.Classes
+		for _, function := range functions[i] {
+			total.add(function, summary.Path+":"+function.Name)
+		}
+	}
+	total.finish()
+	return total
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+const testScript = `extends Node
+# The player
+
+var hp = 3 # health
+
+
+func hit(damage):
+	# Nested three blocks deep
+	for i in damage:
+		if hp > 0:
+			while hp > 10:
+				hp -= 1
+		elif hp < 0:
+			pass
+
+	var d = {
+		"a": 1,
+	}
+
+
+class Inner:
+	func f():
+		match 1:
+			1:
+				if true:
+					pass
+`
+
+func TestMeasure(t *testing.T) {
+	tree, errors := parser.ParseFile("player.gd", testScript)
+	if len(errors) > 0 {
+		t.Fatal(errors)
+	}
+	summary, functions := Measure("player.gd", testScript, tree)
+
+	expected := Summary{
+		Path:                 "player.gd",
+		Lines:                26,
+		CodeLines:            18,
+		CommentLines:         3,
+		BlankLines:           6,
+		Classes:              2,
+		Functions:            2,
+		AverageFunctionLines: 8.5,
+		LongestFunction:      "hit",
+		LongestFunctionLines: 12,
+		MaxNestingDepth:      3,
+		CommentDensity:       0.15,
+		functionLines:        17,
+	}
+	if summary != expected {
+		t.Errorf("Expected %+v, got %+v", expected, summary)
+	}
+	if len(functions) != 2 || functions[0] != (Function{Name: "hit", Line: 7, Lines: 12, NestingDepth: 3}) ||
+		functions[1] != (Function{Name: "f", Line: 22, Lines: 5, NestingDepth: 2}) {
+		t.Errorf("Unexpected functions %+v", functions)
+	}
+
+	total := Total([]Summary{summary, summary}, [][]Function{functions, functions})
+	if total.Lines != 52 || total.Functions != 4 || total.LongestFunction != "player.gd:hit" ||
+		total.AverageFunctionLines != 8.5 || total.CommentDensity != 0.15 {
+		t.Errorf("Unexpected total %+v", total)
+	}
+}
+
+func TestMeasureEmpty(t *testing.T) {
+	tree, _ := parser.ParseFile("empty.gd", "")
+	summary, functions := Measure("empty.gd", "", tree)
+	if summary.Lines != 0 || summary.Functions != 0 || summary.CommentDensity != 0 || len(functions) != 0 {
+		t.Errorf("Expected an empty summary, got %+v", summary)
+	}
+}
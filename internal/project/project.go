@@ -0,0 +1,585 @@
+// Package project loads every script of a Godot project into a graph of the
+// global classes they register with class_name and of the scripts they
+// depend on, along with the autoloads of project.godot and the node trees of
+// its scenes, so that rules can check a script against the rest of its
+// project: the classes it names exist, its preloads do not form cycles, the
+// classes it declares are used somewhere, and the nodes it looks up exist.
+package project
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+)
+
+// FileName is the file marking the root directory of a Godot project
+const FileName = "project.godot"
+
+// DependencyKind is the way a script depends on another file
+type DependencyKind int
+
+const (
+	// DependencyPreload is a preload() call, resolved when the script loads
+	DependencyPreload DependencyKind = iota
+	// DependencyLoad is a load() call, resolved when it runs
+	DependencyLoad
+	// DependencyExtends is the base script of a class, by path or class name
+	DependencyExtends
+)
+
+// String returns the name of the kind
+func (k DependencyKind) String() string {
+	switch k {
+	case DependencyPreload:
+		return "preload"
+	case DependencyLoad:
+		return "load"
+	default:
+		return "extends"
+	}
+}
+
+// Dependency is a file a script refers to
+type Dependency struct {
+	Kind DependencyKind
+	// ResPath is the res:// path of the file, with relative paths resolved
+	// against the directory of the script
+	ResPath string
+	// Pos is the position of the call or of the extends clause
+	Pos ast.Position
+	// Constant is the name of the class-level constant a preload or load
+	// initializes, if any
+	Constant string
+	// Member is set for the calls initializing class members, which run
+	// when the script, or an instance of it, is loaded
+	Member bool
+}
+
+// String describes the dependency, e.g. "preloads res://weapon.gd"
+func (d Dependency) String() string {
+	if d.Kind == DependencyExtends {
+		return "extends " + d.ResPath
+	}
+	return d.Kind.String() + "s " + d.ResPath
+}
+
+// Link is a dependency of a script on another script of the project
+type Link struct {
+	From *Script
+	Dependency
+}
+
+// String describes the link with the position of the dependency, e.g.
+// "res://player.gd:3 preloads res://weapon.gd"
+func (l Link) String() string {
+	return fmt.Sprintf("%s:%d %s", l.From.ResPath, l.Pos.Line, l.Dependency)
+}
+
+// TypeReference is a name a script uses as a type: in an extends clause, a
+// type hint, or after is or as
+type TypeReference struct {
+	// Name is the name as written, e.g. "Weapon" or "Weapon.Kind"; the
+	// element types of typed arrays and dictionaries are references of their own
+	Name string
+	Pos  ast.Position
+}
+
+// Autoload is a script or scene project.godot adds to the scene tree at startup
+type Autoload struct {
+	Name    string
+	ResPath string
+	// Singleton is set for the autoloads scripts can refer to by name,
+	// marked with a * in project.godot
+	Singleton bool
+}
+
+// Script is a script of a project
+type Script struct {
+	// Path is the absolute path of the script, and ResPath its res:// path
+	Path    string
+	ResPath string
+	// Tree is the syntax tree of the script, nil when it could not be read;
+	// a script that does not parse has the tree parsed up to the errors
+	Tree *ast.AbstractSyntaxTree
+	// ClassName is the global class the script registers with class_name
+	ClassName string
+	// Dependencies are the files the script refers to, in source order
+	Dependencies []Dependency
+	// Types are the names the script uses as types, in source order
+	Types []TypeReference
+
+	// names are the identifiers and type names the script uses
+	names map[string]bool
+}
+
+// Uses reports whether the script refers to name, as an identifier or as a type
+func (s *Script) Uses(name string) bool {
+	return s.names[name]
+}
+
+// Project is the set of scripts of a Godot project
+type Project struct {
+	// Root is the directory of project.godot, or the directory loaded when
+	// it is not part of a project
+	Root string
+	// Scripts are the scripts of the project in path order
+	Scripts []*Script
+	// Autoloads are the autoloads of project.godot in file order
+	Autoloads []Autoload
+	// Scenes are the scenes of the project in path order
+	Scenes []*Scene
+
+	byPath  map[string]*Script
+	byRes   map[string]*Script
+	classes map[string]*Script
+	scenes  map[string]*Scene
+	// globals are the names of the autoload singletons, and of the classes
+	// project.godot registers, which Godot 3 lists there
+	globals map[string]bool
+	// resources are the res:// paths the scenes, resources and project.godot refer to
+	resources map[string]bool
+}
+
+// FindRoot returns the directory containing project.godot among dir and its
+// parents, or "" when there is none
+func FindRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, FileName)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load loads the project dir belongs to: every script under the directory
+// of its project.godot, or under dir when there is no project.godot. Hidden
+// directories, such as .godot, are skipped like corpus.Walk does. Scripts
+// larger than corpus.DefaultMaxFileSize are left out. Scripts are parsed by
+// a worker per CPU; the error is that of walking the project.
+func Load(dir string) (*Project, error) {
+	root := FindRoot(dir)
+	if root == "" {
+		var err error
+		if root, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+	}
+	p := &Project{
+		Root:      root,
+		byPath:    make(map[string]*Script),
+		byRes:     make(map[string]*Script),
+		classes:   make(map[string]*Script),
+		scenes:    make(map[string]*Scene),
+		globals:   make(map[string]bool),
+		resources: make(map[string]bool),
+	}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".gd":
+			paths = append(paths, path)
+		case ".tscn":
+			p.addResourceReferences(path)
+			if scene := p.loadScene(path); scene != nil {
+				p.Scenes = append(p.Scenes, scene)
+				p.scenes[scene.ResPath] = scene
+			}
+		case ".tres", ".godot":
+			p.addResourceReferences(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.Scripts = make([]*Script, len(paths))
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				p.Scripts[j] = p.loadScript(paths[j])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, script := range p.Scripts {
+		p.byPath[script.Path] = script
+		p.byRes[script.ResPath] = script
+		// The first script registering a name wins, as Godot then reports the others
+		if script.ClassName != "" && p.classes[script.ClassName] == nil {
+			p.classes[script.ClassName] = script
+		}
+	}
+	p.loadSettings(filepath.Join(root, FileName))
+
+	// Scripts extending a global class depend on the script declaring it
+	for _, script := range p.Scripts {
+		for i, dep := range script.Dependencies {
+			if dep.Kind == DependencyExtends && !strings.HasPrefix(dep.ResPath, "res://") {
+				if base := p.classes[dep.ResPath]; base != nil {
+					script.Dependencies[i].ResPath = base.ResPath
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// resourcePath matches the res:// paths quoted in scenes, resources and project.godot
+var resourcePath = regexp.MustCompile(`"(res://[^"]+)"`)
+
+// addResourceReferences records the res:// paths the file at path refers to
+func (p *Project) addResourceReferences(path string) {
+	content, err := corpus.ReadFile(path, corpus.DefaultMaxFileSize)
+	if err != nil {
+		return
+	}
+	for _, match := range resourcePath.FindAllStringSubmatch(content, -1) {
+		p.resources[match[1]] = true
+	}
+}
+
+var (
+	// settingsSection matches the section headers of project.godot
+	settingsSection = regexp.MustCompile(`^\[(\w+)\]$`)
+	// autoloadEntry matches the entries of the autoload section, such as
+	// Music="*res://music.tscn"
+	autoloadEntry = regexp.MustCompile(`^(\w+)\s*=\s*"(\*?)(res://[^"]+)"`)
+	// globalClass matches the entries of the _global_script_classes setting
+	// of Godot 3 projects, whose keys are sorted
+	globalClass = regexp.MustCompile(`\{[^{}]*"class":\s*"(\w+)"[^{}]*"path":\s*"(res://[^"]+)"[^{}]*\}`)
+)
+
+// loadSettings reads the autoloads and the global classes the project.godot
+// at path registers, if there is one. The classes registered to scripts
+// that do not declare them, such as scripts that failed to parse, are known
+// by name only.
+func (p *Project) loadSettings(path string) {
+	content, err := corpus.ReadFile(path, corpus.DefaultMaxFileSize)
+	if err != nil {
+		return
+	}
+
+	section := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if match := settingsSection.FindStringSubmatch(line); match != nil {
+			section = match[1]
+		} else if match := autoloadEntry.FindStringSubmatch(line); match != nil && section == "autoload" {
+			autoload := Autoload{Name: match[1], ResPath: match[3], Singleton: match[2] == "*"}
+			p.Autoloads = append(p.Autoloads, autoload)
+			if autoload.Singleton {
+				p.globals[autoload.Name] = true
+			}
+		}
+	}
+
+	for _, match := range globalClass.FindAllStringSubmatch(content, -1) {
+		name, resPath := match[1], match[2]
+		p.globals[name] = true
+		if script := p.byRes[resPath]; script != nil && p.classes[name] == nil {
+			p.classes[name] = script
+		}
+	}
+}
+
+// loadScript reads and parses the script at path
+func (p *Project) loadScript(path string) *Script {
+	script := &Script{Path: path, ResPath: p.ResPath(path), names: make(map[string]bool)}
+	source, err := corpus.ReadFile(path, corpus.DefaultMaxFileSize)
+	if err != nil {
+		return script
+	}
+	script.Tree, _ = parser.ParseFile(path, source)
+	if script.Tree == nil || script.Tree.RootClass == nil {
+		return script
+	}
+	script.ClassName = script.Tree.RootClass.ClassName
+	script.collect()
+	return script
+}
+
+// collect records the dependencies, type references and names of the script
+func (s *Script) collect() {
+	for _, load := range analysis.FindLoadCalls(s.Tree) {
+		dep := Dependency{Kind: DependencyLoad, ResPath: resolve(s.ResPath, load.Path), Pos: load.Call.Position()}
+		if load.Preload {
+			dep.Kind = DependencyPreload
+		}
+		if load.Function == nil {
+			dep.Member = true
+			if load.Decl != nil {
+				dep.Constant = load.Decl.Name
+			}
+		}
+		s.Dependencies = append(s.Dependencies, dep)
+	}
+
+	ast.Inspect(s.Tree, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Class:
+			if quoted := strings.Trim(n.Extends, `"'`); quoted != n.Extends {
+				s.Dependencies = append(s.Dependencies, Dependency{
+					Kind: DependencyExtends, ResPath: resolve(s.ResPath, quoted), Pos: n.ExtendsPos,
+				})
+			} else if n.Extends != "" {
+				// Resolved to the path of the class once every script is loaded
+				s.Dependencies = append(s.Dependencies, Dependency{
+					Kind: DependencyExtends, ResPath: n.Extends, Pos: n.ExtendsPos,
+				})
+				s.addType(n.Extends, n.ExtendsPos)
+			}
+		case *ast.VarStatement:
+			s.addType(n.TypeHint, n.Position())
+		case *ast.Function:
+			for _, param := range n.Parameters {
+				s.addType(param.TypeHint, param.Position())
+			}
+			s.addType(n.ReturnType, n.Position())
+		case *ast.ForStatement:
+			s.addType(n.TypeHint, n.Position())
+		case *ast.InfixExpression:
+			if n.Operator == "is" || n.Operator == "as" {
+				if name := typeName(n.Right); name != "" {
+					s.addType(name, n.Right.Position())
+				}
+			}
+		case *ast.Identifier:
+			s.names[n.Value] = true
+		}
+		return true
+	})
+	sort.SliceStable(s.Dependencies, func(i, j int) bool {
+		return s.Dependencies[i].Pos.Offset < s.Dependencies[j].Pos.Offset
+	})
+}
+
+// addType records the types named in hint, such as Weapon, Array[Weapon]
+// or Dictionary[String, Weapon.Kind]
+func (s *Script) addType(hint string, pos ast.Position) {
+	for _, name := range strings.FieldsFunc(hint, func(r rune) bool {
+		return r == '[' || r == ']' || r == ',' || r == ' '
+	}) {
+		s.Types = append(s.Types, TypeReference{Name: name, Pos: pos})
+		s.names[strings.SplitN(name, ".", 2)[0]] = true
+	}
+}
+
+// typeName returns the dotted name expr spells, e.g. "Weapon.Kind", or ""
+func typeName(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.DotExpression:
+		if left := typeName(e.Left); left != "" {
+			return left + "." + e.Property
+		}
+	}
+	return ""
+}
+
+// resolve returns the res:// path of the file at target, as written in the
+// script at from
+func resolve(from, target string) string {
+	if strings.HasPrefix(target, "res://") || strings.Contains(target, "://") {
+		return target
+	}
+	dir := path.Dir(strings.TrimPrefix(from, "res://"))
+	return "res://" + strings.TrimPrefix(path.Join(dir, target), "/")
+}
+
+// ResPath returns the res:// path of the file at path, an absolute path or
+// one relative to the working directory
+func (p *Project) ResPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	rel, err := filepath.Rel(p.Root, abs)
+	if err != nil {
+		rel = filepath.Base(abs)
+	}
+	return "res://" + filepath.ToSlash(rel)
+}
+
+// Script returns the script at path, a res:// path or a path on disk, or nil
+// when the project has no such script
+func (p *Project) Script(path string) *Script {
+	if strings.HasPrefix(path, "res://") {
+		return p.byRes[path]
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return p.byPath[path]
+}
+
+// Class returns the script registering the global class name, or nil
+func (p *Project) Class(name string) *Script {
+	return p.classes[name]
+}
+
+// Autoload returns the autoload named name, or nil
+func (p *Project) Autoload(name string) *Autoload {
+	for i := range p.Autoloads {
+		if p.Autoloads[i].Name == name {
+			return &p.Autoloads[i]
+		}
+	}
+	return nil
+}
+
+// IsGlobal reports whether every script of the project can refer to name:
+// a global class, or an autoload singleton
+func (p *Project) IsGlobal(name string) bool {
+	return p.classes[name] != nil || p.globals[name]
+}
+
+// Dependency returns the script a dependency refers to, or nil when it
+// refers to a resource or to a file outside the project
+func (p *Project) Dependency(dep Dependency) *Script {
+	return p.byRes[dep.ResPath]
+}
+
+// Preloads returns the dependencies of script on other scripts that are
+// loaded along with it: its preloads, its base scripts, and the scripts its
+// members are initialized with load()
+func (p *Project) Preloads(script *Script) []Dependency {
+	var preloads []Dependency
+	for _, dep := range script.Dependencies {
+		if (dep.Kind != DependencyLoad || dep.Member) && p.Dependency(dep) != nil {
+			preloads = append(preloads, dep)
+		}
+	}
+	return preloads
+}
+
+// PreloadPath returns the shortest chain of preloads through which loading
+// from loads target along with it, or nil when it does not
+func (p *Project) PreloadPath(from, target *Script) []Link {
+	parents := map[*Script]Link{from: {}}
+	queue := []*Script{from}
+	for len(queue) > 0 {
+		script := queue[0]
+		queue = queue[1:]
+		for _, dep := range p.Preloads(script) {
+			next := p.Dependency(dep)
+			if next == target {
+				path := []Link{{script, dep}}
+				for s := script; s != from; s = parents[s].From {
+					path = append([]Link{parents[s]}, path...)
+				}
+				return path
+			}
+			if _, seen := parents[next]; !seen {
+				parents[next] = Link{script, dep}
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// PreloadsReach reports whether loading from loads target along with it
+func (p *Project) PreloadsReach(from, target *Script) bool {
+	return p.PreloadPath(from, target) != nil
+}
+
+// IsUsed reports whether other files of the project refer to script: other
+// scripts by its class name or its path, or scenes, resources and
+// project.godot by its path
+func (p *Project) IsUsed(script *Script) bool {
+	if p.resources[script.ResPath] {
+		return true
+	}
+	for _, other := range p.Scripts {
+		if other == script {
+			continue
+		}
+		if script.ClassName != "" && other.Uses(script.ClassName) {
+			return true
+		}
+		for _, dep := range other.Dependencies {
+			if dep.ResPath == script.ResPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Declares reports whether script declares name as a type of its own: an
+// inner class, an enum or a constant, such as a preloaded script, of any of
+// its classes or of the project scripts it extends
+func (p *Project) Declares(script *Script, name string) bool {
+	seen := make(map[*Script]bool)
+	for script != nil && !seen[script] && script.Tree != nil {
+		seen[script] = true
+		found := false
+		ast.Inspect(script.Tree, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.Class:
+				found = found || (n != script.Tree.RootClass && n.Name == name)
+			case *ast.EnumStatement:
+				found = found || n.Name == name
+			case *ast.VarStatement:
+				found = found || (n.IsConst && n.Name == name)
+			case *ast.Function:
+				return false
+			}
+			return !found
+		})
+		if found {
+			return true
+		}
+
+		// Types are inherited from the base script
+		var base *Script
+		for _, dep := range script.Dependencies {
+			if dep.Kind == DependencyExtends && dep.Pos == script.Tree.RootClass.ExtendsPos {
+				base = p.Dependency(dep)
+			}
+		}
+		script = base
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files, by path relative to root, under a new root
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"project.godot":        "config_version=5\n",
+		"player/player.gd":     "extends Actor\nclass_name Player\nconst Gun = preload(\"gun.gd\")\nvar target: Array[Enemy]\nfunc hit(by: Gun) -> void:\n\tvar fx = load(\"res://fx/hit.tscn\")\n",
+		"player/gun.gd":        "extends \"res://actor.gd\"\nenum Kind { PISTOL, RIFLE }\n",
+		"actor.gd":             "extends Node\nclass_name Actor\nclass Stats:\n\tvar hp = 1\n",
+		"enemy.gd":             "extends Node\nclass_name Enemy\n",
+		"unused.gd":            "extends Node\nclass_name Unused\n",
+		"main.tscn":            "[ext_resource type=\"Script\" path=\"res://enemy.gd\" id=\"1\"]\n",
+		".godot/cache/skip.gd": "class_name Skipped\n",
+	})
+
+	// Loading any directory of the project loads all of it
+	p, err := Load(filepath.Join(root, "player"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p.Root != root || len(p.Scripts) != 5 {
+		t.Fatalf("Expected the 5 scripts under %s, got %d under %s", root, len(p.Scripts), p.Root)
+	}
+
+	player := p.Script(filepath.Join(root, "player", "player.gd"))
+	if player == nil || player.ResPath != "res://player/player.gd" || player.ClassName != "Player" {
+		t.Fatalf("Expected res://player/player.gd registering Player, got %+v", player)
+	}
+	if p.Class("Player") != player || p.Class("Skipped") != nil || p.Script("res://player/player.gd") != player {
+		t.Error("Expected Player by class name and res:// path, and the hidden script left out")
+	}
+
+	expected := []Dependency{
+		{Kind: DependencyExtends, ResPath: "res://actor.gd"},
+		{Kind: DependencyPreload, ResPath: "res://player/gun.gd", Constant: "Gun", Member: true},
+		{Kind: DependencyLoad, ResPath: "res://fx/hit.tscn"},
+	}
+	if len(player.Dependencies) != len(expected) {
+		t.Fatalf("Expected %d dependencies, got %+v", len(expected), player.Dependencies)
+	}
+	for i, dep := range player.Dependencies {
+		dep.Pos = expected[i].Pos
+		if dep != expected[i] {
+			t.Errorf("Dependency %d: expected %+v, got %+v", i, expected[i], dep)
+		}
+	}
+	var types []string
+	for _, ref := range player.Types {
+		types = append(types, ref.Name)
+	}
+	if len(types) != 5 || types[0] != "Actor" || types[1] != "Array" || types[2] != "Enemy" || types[3] != "Gun" || types[4] != "void" {
+		t.Errorf("Expected the types Actor, Array, Enemy, Gun and void, got %v", types)
+	}
+
+	gun := p.Script("res://player/gun.gd")
+	actor := p.Class("Actor")
+	if !p.PreloadsReach(player, actor) || p.PreloadsReach(actor, player) {
+		t.Error("Expected player.gd to load actor.gd through gun.gd, and not the other way around")
+	}
+	// The shortest path is the extends clause, rather than the preload of gun.gd
+	if path := p.PreloadPath(player, actor); len(path) != 1 || path[0].String() != "res://player/player.gd:1 extends res://actor.gd" {
+		t.Errorf("Expected player.gd to extend actor.gd, got %v", path)
+	}
+	if !p.Declares(gun, "Kind") || !p.Declares(gun, "Stats") || !p.Declares(player, "Gun") || p.Declares(player, "Kind") {
+		t.Error("Expected gun.gd to declare Kind and inherit Stats, and player.gd to declare Gun only")
+	}
+
+	for name, used := range map[string]bool{"Actor": true, "Enemy": true, "Player": false, "Unused": false} {
+		if got := p.IsUsed(p.Class(name)); got != used {
+			t.Errorf("%s: expected used %v, got %v", name, used, got)
+		}
+	}
+	if !p.IsUsed(gun) {
+		t.Error("Expected the preloaded gun.gd to be used")
+	}
+}
+
+func TestLoadWithoutProjectFile(t *testing.T) {
+	root := writeFiles(t, map[string]string{"scripts/a.gd": "class_name A\n"})
+	if FindRoot(root) != "" {
+		t.Skip("a project.godot above the temporary directory")
+	}
+	p, err := Load(filepath.Join(root, "scripts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Root != filepath.Join(root, "scripts") || p.Script("res://a.gd") == nil {
+		t.Errorf("Expected the directory loaded to be the root, got %s with %v", p.Root, p.Scripts)
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"project.godot": `config_version=4
+
+_global_script_classes=[ {
+"base": "Node",
+"class": "Enemy",
+"language": "GDScript",
+"path": "res://enemy.gd"
+}, {
+"base": "Node",
+"class": "Broken",
+"language": "GDScript",
+"path": "res://broken.gd"
+} ]
+
+[autoload]
+
+Music="*res://music.tscn"
+Loader="res://loader.gd"
+`,
+		"enemy.gd": "extends Node\n",
+	})
+
+	p, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Autoload{
+		{Name: "Music", ResPath: "res://music.tscn", Singleton: true},
+		{Name: "Loader", ResPath: "res://loader.gd"},
+	}
+	if len(p.Autoloads) != len(expected) || p.Autoloads[0] != expected[0] || p.Autoloads[1] != expected[1] {
+		t.Errorf("Expected the autoloads %+v, got %+v", expected, p.Autoloads)
+	}
+	if p.Autoload("Loader") == nil || p.Autoload("Enemy") != nil {
+		t.Error("Expected Loader to be an autoload, and Enemy not to be one")
+	}
+	if p.Class("Enemy") != p.Script("res://enemy.gd") || p.Class("Broken") != nil {
+		t.Error("Expected Enemy registered to enemy.gd, and Broken to no script")
+	}
+	for name, global := range map[string]bool{"Music": true, "Loader": false, "Enemy": true, "Broken": true, "Missing": false} {
+		if got := p.IsGlobal(name); got != global {
+			t.Errorf("%s: expected global %v, got %v", name, global, got)
+		}
+	}
+}
+
+func TestLoadScenes(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"project.godot": "config_version=5\n",
+		"player.gd":     "extends Node\n",
+		"hud.gd":        "extends Node\n",
+		"player.tscn": `[gd_scene load_steps=3 format=3 uid="uid://abc"]
+
+[ext_resource type="Script" path="res://player.gd" id="1_pl"]
+
+[node name="Player" type="CharacterBody2D" groups=["actors"]]
+script = ExtResource("1_pl")
+
+[node name="Sprite" type="Sprite2D" parent="."]
+
+[node name="Health" type="Label" parent="Sprite"]
+unique_name_in_owner = true
+`,
+		"level.tscn": `[gd_scene format=2]
+
+[ext_resource path="res://player.tscn" type="PackedScene" id=1]
+[ext_resource path="res://hud.gd" type="Script" id=2]
+
+[node name="Level" type="Node"]
+
+[node name="Player" parent="." instance=ExtResource( 1 )]
+
+[node name="Weapon" type="Node" parent="Player/Sprite"]
+
+[node name="HUD" type="Node" parent="."]
+script = ExtResource( 2 )
+`,
+	})
+
+	p, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Scenes) != 2 {
+		t.Fatalf("Expected 2 scenes, got %d", len(p.Scenes))
+	}
+
+	// player.gd runs on the root of player.tscn, and on its instance in level.tscn
+	player := p.NodesOf(p.Script("res://player.gd"))
+	if len(player) != 2 || player[0].Scene.ResPath != "res://level.tscn" || player[0].Path() != "Player" ||
+		player[1].Scene.ResPath != "res://player.tscn" || player[1].Line != 5 {
+		t.Fatalf("Expected the Player nodes of level.tscn and player.tscn, got %+v", player)
+	}
+	hud := p.NodesOf(p.Script("res://hud.gd"))
+	if len(hud) != 1 || hud[0].Path() != "HUD" {
+		t.Fatalf("Expected the HUD node of level.tscn, got %+v", hud)
+	}
+
+	tests := []struct {
+		from   *SceneNode
+		path   string
+		status NodeStatus
+	}{
+		{player[1], "Sprite/Health", NodeFound},
+		{player[1], "%Health:text", NodeFound},
+		{player[1], "Sprite/Weapon", NodeMissing},
+		{player[1], "..", NodeUnknown},
+		{player[0], "Sprite/Weapon", NodeFound},
+		{player[0], "Sprite/Health", NodeFound},
+		{player[0], "%Health", NodeFound},
+		{player[0], "../HUD", NodeFound},
+		{player[0], "Gun", NodeMissing},
+		{hud[0], "/root/Level", NodeUnknown},
+		{hud[0], "../Player/Sprite", NodeFound},
+		{hud[0], "%Health", NodeMissing},
+	}
+	for _, tt := range tests {
+		if _, status := p.FindNode(tt.from, tt.path); status != tt.status {
+			t.Errorf("%s from %s: expected status %d, got %d", tt.path, tt.from.Path(), tt.status, status)
+		}
+	}
+}
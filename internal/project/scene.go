@@ -0,0 +1,307 @@
+package project
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
+)
+
+// Scene is a scene of a project, as saved in a .tscn file
+type Scene struct {
+	ResPath string
+	// Root is the root node of the scene, nil when the file has no nodes
+	Root *SceneNode
+	// Nodes are the nodes of the file in file order, including the nodes
+	// that only name the parents of other nodes
+	Nodes []*SceneNode
+}
+
+// SceneNode is a node of a scene
+type SceneNode struct {
+	Name     string
+	Scene    *Scene
+	Parent   *SceneNode
+	Children []*SceneNode
+	// Script is the res:// path of the script attached to the node, if any
+	Script string
+	// Instance is the res:// path of the scene the node instances, if any:
+	// the children of the node then also include those of that scene's root
+	Instance string
+	// Unique is set for the nodes the scene calls by %Name
+	Unique bool
+	// Line is the line of the node's header in the file, or 0 for the nodes
+	// that only name the parents of others, such as the children of an
+	// instance with editable children
+	Line int
+}
+
+// Path returns the path of the node from the root of its scene, e.g. "HUD/Label"
+func (n *SceneNode) Path() string {
+	if n.Parent == nil {
+		return "."
+	}
+	if n.Parent.Parent == nil {
+		return n.Name
+	}
+	return n.Parent.Path() + "/" + n.Name
+}
+
+// child returns the child node named name that the scene declares, or nil
+func (n *SceneNode) child(name string) *SceneNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+var (
+	// sceneHeader matches the headers of the sections of a .tscn file, such
+	// as [node name="HUD" parent="."]
+	sceneHeader = regexp.MustCompile(`^\[(\w+)(\s.*)?\]$`)
+	// sceneAttribute matches the attributes of a section header: strings,
+	// constructors such as ExtResource("1_abcde"), arrays and plain values
+	sceneAttribute = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|\w+\([^)]*\)|\[[^\]]*\]|[^\s\]]+)`)
+	// sceneProperty matches the properties of a section, such as
+	// script = ExtResource("1_abcde")
+	sceneProperty = regexp.MustCompile(`^(\w+)\s*=\s*(.*)$`)
+	// extResource matches references to external resources, ExtResource("1_abcde")
+	// in Godot 4 and ExtResource( 1 ) in Godot 3
+	extResource = regexp.MustCompile(`^ExtResource\(\s*"?([^")\s]+)"?\s*\)$`)
+)
+
+// loadScene reads and parses the scene at path, returning nil when it
+// cannot be read
+func (p *Project) loadScene(path string) *Scene {
+	content, err := corpus.ReadFile(path, corpus.DefaultMaxFileSize)
+	if err != nil {
+		return nil
+	}
+	return parseScene(p.ResPath(path), content)
+}
+
+// parseScene parses the .tscn file content saved at resPath. Lines it does
+// not understand, such as the continuation lines of multi-line values, are
+// skipped.
+func parseScene(resPath, content string) *Scene {
+	scene := &Scene{ResPath: resPath}
+	resources := make(map[string]string)
+	var node *SceneNode
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if match := sceneHeader.FindStringSubmatch(line); match != nil {
+			attributes := make(map[string]string)
+			for _, attribute := range sceneAttribute.FindAllStringSubmatch(match[2], -1) {
+				attributes[attribute[1]] = strings.Trim(attribute[2], `"`)
+			}
+
+			node = nil
+			switch match[1] {
+			case "ext_resource":
+				resources[attributes["id"]] = resolve(resPath, attributes["path"])
+			case "node":
+				node = scene.addNode(attributes["name"], attributes["parent"], i+1)
+				if node != nil {
+					node.Instance = externalPath(attributes["instance"], resources)
+				}
+			}
+			continue
+		}
+
+		if node == nil {
+			continue
+		}
+		if match := sceneProperty.FindStringSubmatch(line); match != nil {
+			switch match[1] {
+			case "script":
+				node.Script = externalPath(match[2], resources)
+			case "unique_name_in_owner":
+				node.Unique = match[2] == "true"
+			}
+		}
+	}
+	return scene
+}
+
+// externalPath returns the res:// path of the external resource value
+// refers to, or ""
+func externalPath(value string, resources map[string]string) string {
+	if match := extResource.FindStringSubmatch(value); match != nil {
+		return resources[match[1]]
+	}
+	return ""
+}
+
+// addNode adds the node declared on line with the name and parent path
+// of its header, returning nil when the file declares no root yet
+func (s *Scene) addNode(name, parent string, line int) *SceneNode {
+	if parent == "" && s.Root == nil {
+		s.Root = &SceneNode{Name: name, Scene: s, Line: line}
+		s.Nodes = append(s.Nodes, s.Root)
+		return s.Root
+	}
+	if s.Root == nil {
+		return nil
+	}
+
+	// The parents of the children of instances are only named by path
+	at := s.Root
+	for _, segment := range strings.Split(parent, "/") {
+		if segment == "." || segment == "" {
+			continue
+		}
+		next := at.child(segment)
+		if next == nil {
+			next = &SceneNode{Name: segment, Scene: s, Parent: at}
+			at.Children = append(at.Children, next)
+			s.Nodes = append(s.Nodes, next)
+		}
+		at = next
+	}
+
+	node := at.child(name)
+	if node == nil {
+		node = &SceneNode{Name: name, Scene: s, Parent: at}
+		at.Children = append(at.Children, node)
+		s.Nodes = append(s.Nodes, node)
+	}
+	node.Line = line
+	return node
+}
+
+// NodeStatus is the result of looking up a node path in a scene
+type NodeStatus int
+
+const (
+	// NodeFound means the path leads to a node of the scene
+	NodeFound NodeStatus = iota
+	// NodeMissing means the scene has no node at the path
+	NodeMissing
+	// NodeUnknown means the path leaves what the scene knows of the tree:
+	// an absolute path, a path going above the root of the scene, or a path
+	// through an instance of a scene that is not part of the project
+	NodeUnknown
+)
+
+// FindNode looks up path from the node from, as get_node would on the node
+// once the scene is instanced. Property subnames are ignored.
+func (p *Project) FindNode(from *SceneNode, path string) (*SceneNode, NodeStatus) {
+	path, _, _ = strings.Cut(path, ":")
+	if strings.HasPrefix(path, "/") {
+		return nil, NodeUnknown
+	}
+
+	node := from
+	for _, segment := range strings.Split(path, "/") {
+		switch {
+		case segment == "" || segment == ".":
+			continue
+		case segment == "..":
+			if node.Parent == nil {
+				return nil, NodeUnknown
+			}
+			node = node.Parent
+		case strings.HasPrefix(segment, "%"):
+			next, status := p.unique(node, segment[1:])
+			if next == nil {
+				return nil, status
+			}
+			node = next
+		default:
+			next, status := p.child(node, segment, 0)
+			if next == nil {
+				return nil, status
+			}
+			node = next
+		}
+	}
+	return node, NodeFound
+}
+
+// maxInstanceDepth bounds the instances looked through for a child, which
+// a scene instancing itself would make endless
+const maxInstanceDepth = 16
+
+// child returns the child of node named name, declared by its scene or, for
+// the nodes of instances and their children, by the instanced scene
+func (p *Project) child(node *SceneNode, name string, depth int) (*SceneNode, NodeStatus) {
+	if child := node.child(name); child != nil {
+		return child, NodeFound
+	}
+
+	// Look for the instance node belongs to
+	var names []string
+	instance := node
+	for instance != nil && instance.Instance == "" {
+		names = append([]string{instance.Name}, names...)
+		instance = instance.Parent
+	}
+	if instance == nil {
+		return nil, NodeMissing
+	}
+	instanced := p.scenes[instance.Instance]
+	if instanced == nil || instanced.Root == nil || depth >= maxInstanceDepth {
+		return nil, NodeUnknown
+	}
+
+	// and for the counterpart of node in the instanced scene
+	counterpart := instanced.Root
+	for _, name := range names {
+		next, status := p.child(counterpart, name, depth+1)
+		if next == nil {
+			return nil, status
+		}
+		counterpart = next
+	}
+	return p.child(counterpart, name, depth+1)
+}
+
+// unique returns the node that node calls by %name: a node of the scene
+// node instances, or else of the scene node belongs to
+func (p *Project) unique(node *SceneNode, name string) (*SceneNode, NodeStatus) {
+	scene := node.Scene
+	if node.Instance != "" {
+		if scene = p.scenes[node.Instance]; scene == nil {
+			return nil, NodeUnknown
+		}
+	}
+	for _, node := range scene.Nodes {
+		if node.Unique && node.Name == name {
+			return node, NodeFound
+		}
+	}
+	return nil, NodeMissing
+}
+
+// NodeScript returns the res:// path of the script of node: the script
+// attached to it, or that of the root of the scene it instances
+func (p *Project) NodeScript(node *SceneNode) string {
+	for depth := 0; node != nil && depth < maxInstanceDepth; depth++ {
+		if node.Script != "" || node.Instance == "" {
+			return node.Script
+		}
+		instanced := p.scenes[node.Instance]
+		if instanced == nil {
+			return ""
+		}
+		node = instanced.Root
+	}
+	return ""
+}
+
+// NodesOf returns the nodes of the scenes of the project that script is
+// attached to, directly or as the root of an instanced scene
+func (p *Project) NodesOf(script *Script) []*SceneNode {
+	var nodes []*SceneNode
+	for _, scene := range p.Scenes {
+		for _, node := range scene.Nodes {
+			if p.NodeScript(node) == script.ResPath {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,215 @@
+// Package scene finds the GDScript embedded in Godot scene (.tscn) and
+// resource (.tres) files, so that the tools check and format it in place.
+// Godot saves a built-in script as a GDScript sub-resource whose source is a
+// string spanning many lines:
+//
+//	[sub_resource type="GDScript" id="GDScript_k2x4d"]
+//	script/source = "extends Node
+//
+//	func _ready():
+//		print(\"ready\")
+//	"
+package scene
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// IsScene reports whether path is a scene or resource file that may embed scripts
+func IsScene(path string) bool {
+	switch filepath.Ext(path) {
+	case ".tscn", ".tres":
+		return true
+	}
+	return false
+}
+
+// Script is a script embedded in a scene file
+type Script struct {
+	// ID is the id of the sub-resource holding the script
+	ID string
+	// Source is the source code of the script, unescaped
+	Source string
+	// Start and End are the byte offsets in the scene file of the first
+	// character of the source and of the quote closing it
+	Start, End int
+
+	// escapes are the escape sequences of the source, in order
+	escapes []escape
+	// content is the scene file
+	content string
+}
+
+// escape is an escape sequence of an embedded source
+type escape struct {
+	// offset is the byte offset in Source of the character it stands for
+	offset int
+	// extra is the number of bytes the escape sequences up to this one,
+	// included, are longer than the characters they stand for
+	extra int
+}
+
+var (
+	headerPattern = regexp.MustCompile(`^\[sub_resource\b.*\btype="GDScript".*\]\s*$`)
+	idPattern     = regexp.MustCompile(`\bid="?([^"\s\]]+)"?`)
+)
+
+// sourceKey starts the line of an embedded source, up to its opening quote
+const sourceKey = `script/source = "`
+
+// Extract returns the scripts embedded in the scene file content, in the
+// order they appear
+func Extract(content string) []Script {
+	var scripts []Script
+	inScript := false
+	id := ""
+	for offset := 0; offset < len(content); {
+		end := strings.IndexByte(content[offset:], '\n')
+		if end < 0 {
+			end = len(content) - offset
+		}
+		line := strings.TrimSuffix(content[offset:offset+end], "\r")
+		next := offset + end + 1
+
+		switch {
+		case strings.HasPrefix(line, "["):
+			inScript = headerPattern.MatchString(line)
+			id = ""
+			if m := idPattern.FindStringSubmatch(line); m != nil {
+				id = m[1]
+			}
+		case inScript && strings.HasPrefix(line, sourceKey):
+			if script, ok := parseSource(content, offset+len(sourceKey)); ok {
+				script.ID = id
+				scripts = append(scripts, script)
+				next = script.End + 1
+			}
+		}
+		offset = next
+	}
+	return scripts
+}
+
+// parseSource reads the string whose first character is at start in content,
+// reporting false when it is not terminated
+func parseSource(content string, start int) (Script, bool) {
+	script := Script{Start: start, content: content}
+	var source strings.Builder
+	extra := 0
+	for i := start; i < len(content); {
+		switch content[i] {
+		case '"':
+			script.Source = source.String()
+			script.End = i
+			return script, true
+		case '\\':
+			r, size := unescape(content[i:])
+			script.escapes = append(script.escapes, escape{offset: source.Len(), extra: extra + size - utf8.RuneLen(r)})
+			extra += size - utf8.RuneLen(r)
+			source.WriteRune(r)
+			i += size
+		default:
+			source.WriteByte(content[i])
+			i++
+		}
+	}
+	return Script{}, false
+}
+
+// unescape returns the character the escape sequence s starts with stands
+// for and the length of the sequence
+func unescape(s string) (rune, int) {
+	if len(s) < 2 {
+		return '\\', 1
+	}
+	switch s[1] {
+	case 'n':
+		return '\n', 2
+	case 't':
+		return '\t', 2
+	case 'r':
+		return '\r', 2
+	case 'b':
+		return '\b', 2
+	case 'f':
+		return '\f', 2
+	case 'u':
+		if len(s) >= 6 {
+			if code, err := strconv.ParseUint(s[2:6], 16, 32); err == nil {
+				return rune(code), 6
+			}
+		}
+	}
+	r, size := utf8.DecodeRuneInString(s[1:])
+	return r, 1 + size
+}
+
+// Escape escapes source to be written between the quotes of an embedded
+// source, as Godot does: only backslashes and quotes are escaped
+func Escape(source string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(source)
+}
+
+// Offset returns the byte offset in the source of line and column, counted
+// in characters from 1; positions past the end of a line are at its end
+func (s *Script) Offset(line, column int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		end := strings.IndexByte(s.Source[offset:], '\n')
+		if end < 0 {
+			return len(s.Source)
+		}
+		offset += end + 1
+	}
+	for ; column > 1 && offset < len(s.Source) && s.Source[offset] != '\n'; column-- {
+		_, size := utf8.DecodeRuneInString(s.Source[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// Position returns the line and column in the scene file of the byte at
+// offset in the source, and its byte offset in the file. Columns count
+// characters from 1, or count a tab up to the next multiple of tabWidth when
+// it is not 0.
+func (s *Script) Position(offset, tabWidth int) (line, column, fileOffset int) {
+	// The escape sequences before offset lengthen the file
+	i := sort.Search(len(s.escapes), func(i int) bool { return s.escapes[i].offset >= offset })
+	fileOffset = s.Start + offset
+	if i > 0 {
+		fileOffset += s.escapes[i-1].extra
+	}
+
+	before := s.content[:fileOffset]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	line = strings.Count(before, "\n") + 1
+	column = 1
+	for _, r := range before[lineStart:] {
+		if r == '\t' && tabWidth > 0 {
+			column += tabWidth - (column-1)%tabWidth
+		} else {
+			column++
+		}
+	}
+	return line, column, fileOffset
+}
+
+// Replace returns content, the scene file scripts were extracted from, with
+// the source of each script replaced by the source at the same index
+func Replace(content string, scripts []Script, sources []string) string {
+	var out strings.Builder
+	out.Grow(len(content))
+	last := 0
+	for i, script := range scripts {
+		out.WriteString(content[last:script.Start])
+		out.WriteString(Escape(sources[i]))
+		last = script.End
+	}
+	out.WriteString(content[last:])
+	return out.String()
+}
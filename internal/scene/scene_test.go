@@ -0,0 +1,93 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+)
+
+const testScene = `[gd_scene load_steps=3 format=3 uid="uid://b1"]
+
+[sub_resource type="GDScript" id="GDScript_a1"]
+script/source = "extends Node
+
+func _ready():
+	print(\"ready\", \"\\\\\")
+	var x=1
+"
+
+[sub_resource type="RectangleShape2D" id="RectangleShape2D_b2"]
+size = Vector2(10, 10)
+
+[sub_resource type="GDScript" id=2]
+script/source = "extends Node2D
+"
+
+[node name="Root" type="Node"]
+script = SubResource("GDScript_a1")
+`
+
+func TestExtract(t *testing.T) {
+	scripts := Extract(testScene)
+	if len(scripts) != 2 {
+		t.Fatalf("Expected 2 scripts, got %d", len(scripts))
+	}
+	if scripts[0].ID != "GDScript_a1" || scripts[1].ID != "2" {
+		t.Errorf("Expected the ids GDScript_a1 and 2, got %s and %s", scripts[0].ID, scripts[1].ID)
+	}
+	expected := "extends Node\n\nfunc _ready():\n\tprint(\"ready\", \"\\\\\")\n\tvar x=1\n"
+	if scripts[0].Source != expected {
+		t.Errorf("Expected the unescaped source %q, got %q", expected, scripts[0].Source)
+	}
+	if scripts[1].Source != "extends Node2D\n" {
+		t.Errorf("Expected the second script, got %q", scripts[1].Source)
+	}
+	if testScene[scripts[0].End] != '"' || !strings.HasPrefix(testScene[scripts[0].Start:], "extends Node\n") {
+		t.Errorf("Expected Start and End around the source, got %d and %d", scripts[0].Start, scripts[0].End)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	script := Extract(testScene)[0]
+	tests := []struct {
+		line, column      int
+		fileLine, fileCol int
+		text              string
+	}{
+		{1, 1, 4, 18, "extends"},
+		{3, 6, 6, 6, "_ready"},
+		// After the escaped quotes and backslashes of the line before
+		{5, 6, 8, 6, "x=1"},
+		{4, 17, 7, 19, `\"\\\\\")`},
+	}
+	for _, tt := range tests {
+		line, column, offset := script.Position(script.Offset(tt.line, tt.column), 0)
+		if line != tt.fileLine || column != tt.fileCol || !strings.HasPrefix(testScene[offset:], tt.text) {
+			t.Errorf("%d:%d: expected %d:%d at %q, got %d:%d at %q", tt.line, tt.column,
+				tt.fileLine, tt.fileCol, tt.text, line, column, testScene[offset:offset+10])
+		}
+	}
+
+	if _, column, _ := script.Position(script.Offset(5, 2), 4); column != 5 {
+		t.Errorf("Expected a tab to count up to column 5, got %d", column)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	scripts := Extract(testScene)
+	sources := []string{strings.Replace(scripts[0].Source, "x=1", "x = 1", 1), scripts[1].Source}
+	replaced := Replace(testScene, scripts, sources)
+	if replaced != strings.Replace(testScene, "x=1", "x = 1", 1) {
+		t.Errorf("Expected only the changed line to differ, got:\n%s", replaced)
+	}
+	if again := Extract(replaced); again[0].Source != sources[0] {
+		t.Errorf("Expected the escaped source to round-trip, got %q", again[0].Source)
+	}
+}
+
+func TestIsScene(t *testing.T) {
+	for path, expected := range map[string]bool{"main.tscn": true, "theme.tres": true, "player.gd": false} {
+		if IsScene(path) != expected {
+			t.Errorf("%s: expected %v", path, expected)
+		}
+	}
+}
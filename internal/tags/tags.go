@@ -0,0 +1,196 @@
+// Package tags builds ctags and etags indexes of the declarations of
+// scripts, so that editors without a language server can jump to definitions
+package tags
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// Kind is the kind of a declaration, as the letter universal-ctags uses for GDScript
+type Kind byte
+
+// Declaration kinds
+const (
+	KindClass      Kind = 'c'
+	KindFunction   Kind = 'm'
+	KindVariable   Kind = 'v'
+	KindConstant   Kind = 'C'
+	KindSignal     Kind = 's'
+	KindEnum       Kind = 'g'
+	KindEnumerator Kind = 'e'
+)
+
+// Tag locates a declaration
+type Tag struct {
+	Name string
+	Path string
+	Kind Kind
+	Line int
+	// Offset is the byte offset of the start of the line, and Text its content
+	Offset int
+	Text   string
+	// ScopeKind is "class" or "enum" for members of an inner class or an
+	// enum, and Scope the dotted name of that class or enum
+	ScopeKind string
+	Scope     string
+}
+
+// Extract returns the tags of the declarations of the script at path, whose
+// content is source, in source order. Local variables are not tagged.
+func Extract(path, source string, tree *ast.AbstractSyntaxTree) []Tag {
+	e := &extractor{path: path, source: source, lineStarts: []int{0}}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			e.lineStarts = append(e.lineStarts, i+1)
+		}
+	}
+
+	if root := tree.RootClass; root != nil {
+		if root.ClassName != "" {
+			e.add(root.ClassName, KindClass, root.ClassNamePos.Line, "", "")
+		}
+		e.class(root, "")
+	}
+	sort.SliceStable(e.tags, func(i, j int) bool {
+		return e.tags[i].Line < e.tags[j].Line
+	})
+	return e.tags
+}
+
+type extractor struct {
+	path       string
+	source     string
+	lineStarts []int // byte offset of the start of each line
+	tags       []Tag
+}
+
+// class tags the members of class, whose dotted name is scope; "" for the script
+func (e *extractor) class(class *ast.Class, scope string) {
+	scopeKind := ""
+	if scope != "" {
+		scopeKind = "class"
+	}
+
+	for _, stmt := range class.Statements {
+		switch s := stmt.(type) {
+		case *ast.VarStatement:
+			kind := KindVariable
+			if s.IsConst {
+				kind = KindConstant
+			}
+			e.add(s.Name, kind, s.Position().Line, scopeKind, scope)
+		case *ast.SignalStatement:
+			e.add(s.Name, KindSignal, s.Position().Line, scopeKind, scope)
+		case *ast.EnumStatement:
+			// The elements of an unnamed enum belong to the class
+			elementScopeKind, elementScope := scopeKind, scope
+			if s.Name != "" {
+				e.add(s.Name, KindEnum, s.Position().Line, scopeKind, scope)
+				elementScopeKind, elementScope = "enum", join(scope, s.Name)
+			}
+			for _, element := range s.Elements {
+				e.add(element.Name, KindEnumerator, element.Pos.Line, elementScopeKind, elementScope)
+			}
+		}
+	}
+	for _, function := range class.Functions {
+		e.add(function.Name, KindFunction, function.Position().Line, scopeKind, scope)
+	}
+	for _, subClass := range class.SubClasses {
+		e.add(subClass.Name, KindClass, subClass.Position().Line, scopeKind, scope)
+		e.class(subClass, join(scope, subClass.Name))
+	}
+}
+
+func (e *extractor) add(name string, kind Kind, line int, scopeKind, scope string) {
+	if line < 1 || line > len(e.lineStarts) {
+		return
+	}
+	start := e.lineStarts[line-1]
+	end := len(e.source)
+	if line < len(e.lineStarts) {
+		end = e.lineStarts[line] - 1
+	}
+	e.tags = append(e.tags, Tag{
+		Name:      name,
+		Path:      e.path,
+		Kind:      kind,
+		Line:      line,
+		Offset:    start,
+		Text:      strings.TrimRight(e.source[start:end], "\r"),
+		ScopeKind: scopeKind,
+		Scope:     scope,
+	})
+}
+
+func join(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// WriteCtags writes tags in the extended ctags format, sorted by name so
+// that editors can search them with a binary search
+func WriteCtags(w io.Writer, tags []Tag) error {
+	sorted := append([]Tag(nil), tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+
+	var b strings.Builder
+	b.WriteString("!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/\n")
+	b.WriteString("!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/\n")
+	for _, tag := range sorted {
+		// The search pattern matches the whole line, with / and \ escaped
+		pattern := strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(tag.Text)
+		fmt.Fprintf(&b, "%s\t%s\t/^%s$/;\"\t%c\tline:%d", tag.Name, tag.Path, pattern, tag.Kind, tag.Line)
+		if tag.Scope != "" {
+			fmt.Fprintf(&b, "\t%s:%s", tag.ScopeKind, tag.Scope)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteEtags writes tags in the Emacs etags format: a section per file, in
+// the order the files first appear in tags
+func WriteEtags(w io.Writer, tags []Tag) error {
+	var paths []string
+	sections := make(map[string]*strings.Builder)
+	for _, tag := range tags {
+		section, ok := sections[tag.Path]
+		if !ok {
+			section = &strings.Builder{}
+			sections[tag.Path] = section
+			paths = append(paths, tag.Path)
+		}
+		// The text searched for is the line up to the end of the name
+		text := tag.Text
+		if i := strings.Index(text, tag.Name); i >= 0 {
+			text = text[:i+len(tag.Name)]
+		}
+		fmt.Fprintf(section, "%s\x7f%s\x01%d,%d\n", text, tag.Name, tag.Line, tag.Offset)
+	}
+
+	var b strings.Builder
+	for _, path := range paths {
+		section := sections[path].String()
+		fmt.Fprintf(&b, "\x0c\n%s,%d\n%s", path, len(section), section)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
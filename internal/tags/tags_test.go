@@ -0,0 +1,110 @@
+package tags
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+const script = `class_name Player
+signal hit(damage)
+enum State {IDLE, RUN}
+const SPEED = 10
+var path = "a/b"
+
+
+func move():
+	var local = 1
+
+
+class Hitbox:
+	enum {NARROW}
+
+	func area():
+		pass
+`
+
+func extract(t *testing.T) []Tag {
+	t.Helper()
+	tree, errors := parser.ParseFile("player.gd", script)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	return Extract("src/player.gd", script, tree)
+}
+
+func TestExtract(t *testing.T) {
+	var got []string
+	for _, tag := range extract(t) {
+		entry := string(tag.Kind) + " " + tag.Name
+		if tag.Scope != "" {
+			entry += " " + tag.ScopeKind + ":" + tag.Scope
+		}
+		got = append(got, entry)
+	}
+
+	expected := []string{
+		"c Player",
+		"s hit",
+		"g State",
+		"e IDLE enum:State",
+		"e RUN enum:State",
+		"C SPEED",
+		"v path",
+		"m move",
+		"c Hitbox",
+		"e NARROW class:Hitbox",
+		"m area class:Hitbox",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected tags:\n%s\nExpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestWriteCtags(t *testing.T) {
+	var out strings.Builder
+	if err := WriteCtags(&out, extract(t)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+
+	if !strings.HasPrefix(lines[0], "!_TAG_FILE_FORMAT\t2\t") || !strings.HasPrefix(lines[1], "!_TAG_FILE_SORTED\t1\t") {
+		t.Errorf("Expected the ctags header, got:\n%s", out.String())
+	}
+	for i := 3; i < len(lines); i++ {
+		if lines[i] < lines[i-1] {
+			t.Errorf("Expected tags sorted by name, got %q after %q", lines[i], lines[i-1])
+		}
+	}
+	for _, expected := range []string{
+		"area\tsrc/player.gd\t/^\tfunc area():$/;\"\tm\tline:15\tclass:Hitbox\n",
+		"path\tsrc/player.gd\t/^var path = \"a\\/b\"$/;\"\tv\tline:5\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected the entry %q, got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "local") {
+		t.Error("Expected local variables to be left out")
+	}
+}
+
+func TestWriteEtags(t *testing.T) {
+	var out strings.Builder
+	if err := WriteEtags(&out, extract(t)); err != nil {
+		t.Fatal(err)
+	}
+	header, section, ok := strings.Cut(strings.TrimPrefix(out.String(), "\x0c\n"), "\n")
+	if !ok || !strings.HasPrefix(out.String(), "\x0c\n") {
+		t.Fatalf("Expected a file section, got %q", out.String())
+	}
+	if expected := "src/player.gd," + strconv.Itoa(len(section)); header != expected {
+		t.Errorf("Expected section header %q, got %q", expected, header)
+	}
+	// The offset of line 2 is the length of line 1 and its newline
+	if !strings.Contains(section, "signal hit\x7fhit\x012,18\n") {
+		t.Errorf("Expected the entry of signal hit, got %q", section)
+	}
+}
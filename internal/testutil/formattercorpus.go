@@ -0,0 +1,43 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FormatterCorpusDir is the location of the formatter corpus relative to the tests directories
+const FormatterCorpusDir = "../../testdata/formatter"
+
+// FormatterCase is an input script and its expected gdformat output
+type FormatterCase struct {
+	Name     string
+	Input    string
+	Expected string
+}
+
+// LoadFormatterCorpus reads every <name>.in.gd file in dir together with its
+// <name>.out.gd counterpart
+func LoadFormatterCorpus(dir string) ([]FormatterCase, error) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.in.gd"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(inputs)
+
+	var cases []FormatterCase
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".in.gd")
+		in, err := os.ReadFile(input)
+		if err != nil {
+			return nil, err
+		}
+		out, err := os.ReadFile(filepath.Join(dir, name+".out.gd"))
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, FormatterCase{Name: name, Input: string(in), Expected: string(out)})
+	}
+	return cases, nil
+}
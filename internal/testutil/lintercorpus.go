@@ -0,0 +1,168 @@
+package testutil
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LinterCorpusDir is the location of the linter parity corpus relative to the tests directories
+const LinterCorpusDir = "../../testdata/linter"
+
+// LinterCase is a single snippet from the linter parity corpus
+type LinterCase struct {
+	// ID is "<file>/<name>", e.g. "basic_checks/unused_argument"
+	ID            string
+	Code          string
+	OK            bool
+	ExpectedRule  string
+	ExpectedLine  int
+	DisabledRules []string
+	// Rule is the rule the case exercises: the expected rule of a nok case,
+	// the rule= option of an ok case
+	Rule string
+}
+
+// LoadLinterCorpus reads every *.txt case file in dir.
+//
+// A case file is a list of snippets, each introduced by a header line:
+//
+//	== ok <name> [rule=<rule>] [disable=<rule>,...]
+//	== nok <name> <rule>:<line> [disable=<rule>,...]
+//
+// The snippet runs until the next header; trailing blank lines are dropped.
+// Lines before the first header are treated as file comments.
+func LoadLinterCorpus(dir string) ([]LinterCase, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var cases []LinterCase
+	for _, file := range files {
+		if filepath.Base(file) == "known_gaps.txt" {
+			continue
+		}
+		fileCases, err := loadLinterCaseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, fileCases...)
+	}
+	return cases, nil
+}
+
+func loadLinterCaseFile(path string) ([]LinterCase, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSuffix(filepath.Base(path), ".txt")
+	var cases []LinterCase
+	var current *LinterCase
+	var body []string
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+			body = body[:len(body)-1]
+		}
+		current.Code = strings.Join(body, "\n") + "\n"
+		cases = append(cases, *current)
+		current = nil
+		body = nil
+	}
+
+	for i, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "== ") {
+			if current != nil {
+				body = append(body, line)
+			}
+			continue
+		}
+
+		flush()
+		c, err := parseLinterCaseHeader(strings.Fields(line[3:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		c.ID = prefix + "/" + c.ID
+		current = &c
+	}
+	flush()
+
+	return cases, nil
+}
+
+func parseLinterCaseHeader(fields []string) (LinterCase, error) {
+	var c LinterCase
+	if len(fields) < 2 {
+		return c, fmt.Errorf("case header needs a kind and a name")
+	}
+
+	c.ID = fields[1]
+	rest := fields[2:]
+	switch fields[0] {
+	case "ok":
+		c.OK = true
+	case "nok":
+		if len(rest) == 0 {
+			return c, fmt.Errorf("nok case %s needs a <rule>:<line> expectation", c.ID)
+		}
+		rule, line, found := strings.Cut(rest[0], ":")
+		if !found {
+			return c, fmt.Errorf("malformed expectation %q", rest[0])
+		}
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return c, fmt.Errorf("malformed expectation line %q: %w", line, err)
+		}
+		c.Rule = rule
+		c.ExpectedRule = rule
+		c.ExpectedLine = n
+		rest = rest[1:]
+	default:
+		return c, fmt.Errorf("unknown case kind %q", fields[0])
+	}
+
+	for _, option := range rest {
+		if value, found := strings.CutPrefix(option, "rule="); found && c.OK {
+			c.Rule = value
+			continue
+		}
+		value, found := strings.CutPrefix(option, "disable=")
+		if !found {
+			return c, fmt.Errorf("unknown case option %q", option)
+		}
+		c.DisabledRules = append(c.DisabledRules, strings.Split(value, ",")...)
+	}
+	return c, nil
+}
+
+// LoadKnownGaps reads an allowlist of case IDs, one per line. Blank lines and
+// '#' comments (including trailing ones) are ignored.
+func LoadKnownGaps(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gaps := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if id := strings.TrimSpace(line); id != "" {
+			gaps[id] = true
+		}
+	}
+	return gaps, scanner.Err()
+}
@@ -0,0 +1,66 @@
+// Command portlinter regenerates the linter parity corpus from the Python
+// gdlint tests: every gdtoolkit/tests/linter/test_<name>.py file becomes
+// <name>.txt in the corpus directory.
+//
+//	go run ./internal/testutil/portlinter gdtoolkit/tests/linter testdata/linter
+//
+// Regenerated case names follow the Python test functions, so known_gaps.txt
+// may need updating afterwards.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/testutil"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Println("Usage: portlinter <python-tests-dir> <corpus-dir>")
+		os.Exit(1)
+	}
+	if err := port(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// port writes a case file in corpusDir for every Python test file of testsDir
+func port(testsDir, corpusDir string) error {
+	files, err := filepath.Glob(filepath.Join(testsDir, "test_*.py"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no test_*.py files in %s", testsDir)
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		cases, skipped, err := testutil.PortPythonLinterTests(string(source))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, name := range skipped {
+			fmt.Printf("%s: skipped %s, it does not call simple_ok_check or simple_nok_check\n", file, name)
+		}
+		if len(cases) == 0 {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "test_"), ".py")
+		origin := "tests/linter/" + filepath.Base(file)
+		output := filepath.Join(corpusDir, name+".txt")
+		if err := os.WriteFile(output, []byte(testutil.FormatLinterCorpus(origin, cases)), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d cases\n", output, len(cases))
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+package testutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pythonTestDef matches the definition of a pytest test function
+var pythonTestDef = regexp.MustCompile(`(?m)^def (test_\w+)\(`)
+
+// PortPythonLinterTests extracts the snippets of a Python gdlint test file
+// (gdtoolkit/tests/linter/test_*.py) as parity cases.
+//
+// Every test function calling simple_ok_check or simple_nok_check yields one
+// case per @pytest.mark.parametrize value, named after the function and the
+// index of the value, e.g. "unused_argument_nok_2". Snippets lose the blank
+// line the Python triple-quoted strings start with. Test functions written
+// any other way are returned in skipped.
+func PortPythonLinterTests(source string) (cases []LinterCase, skipped []string, err error) {
+	defs := pythonTestDef.FindAllStringSubmatchIndex(source, -1)
+	previous := 0
+	for i, def := range defs {
+		name := source[def[2]:def[3]]
+		end := len(source)
+		if i+1 < len(defs) {
+			end = defs[i+1][0]
+		}
+
+		params, err := pythonParameters(source[previous:def[0]])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		previous = def[1]
+
+		check, args, ok := pythonCheckCall(source[def[1]:end])
+		if !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		base := strings.TrimPrefix(name, "test_")
+		for n, values := range params {
+			c, err := pythonCase(check, args, values)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			c.ID = base
+			if values != nil {
+				c.ID += "_" + strconv.Itoa(n+1)
+			}
+			if c.OK {
+				// test_unused_argument_ok exercises unused-argument
+				c.Rule = strings.ReplaceAll(strings.TrimSuffix(base, "_ok"), "_", "-")
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases, skipped, nil
+}
+
+// pythonParameters returns the values the test function whose decorators end
+// text is called with: a map per @pytest.mark.parametrize value, or a single nil map
+func pythonParameters(text string) ([]map[string]pyValue, error) {
+	at := strings.LastIndex(text, "@pytest.mark.parametrize(")
+	if at < 0 {
+		return []map[string]pyValue{nil}, nil
+	}
+	s := &pyScanner{src: text, pos: at + len("@pytest.mark.parametrize(")}
+	args, err := s.arguments()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 || args[0].kind != pyString || args[1].kind != pyList {
+		return nil, fmt.Errorf("parametrize needs parameter names and a list of values")
+	}
+
+	var names []string
+	for _, name := range strings.Split(args[0].str, ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	var params []map[string]pyValue
+	for _, item := range args[1].items {
+		values := []pyValue{item}
+		if len(names) > 1 {
+			values = item.items
+		}
+		if len(values) != len(names) {
+			return nil, fmt.Errorf("parametrize value with %d fields for %d names", len(values), len(names))
+		}
+		param := make(map[string]pyValue)
+		for i, name := range names {
+			param[name] = values[i]
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// pythonCheckCall finds the simple_ok_check or simple_nok_check call of a test
+// function body
+func pythonCheckCall(body string) (string, []pyValue, bool) {
+	for _, check := range []string{"simple_ok_check(", "simple_nok_check("} {
+		at := strings.Index(body, check)
+		if at < 0 {
+			continue
+		}
+		s := &pyScanner{src: body, pos: at + len(check)}
+		args, err := s.arguments()
+		if err != nil {
+			return "", nil, false
+		}
+		return strings.TrimSuffix(check, "("), args, true
+	}
+	return "", nil, false
+}
+
+// pythonCase evaluates the arguments of a check call with the values of one
+// parametrized run
+func pythonCase(check string, args []pyValue, params map[string]pyValue) (LinterCase, error) {
+	c := LinterCase{OK: check == "simple_ok_check", ExpectedLine: 2}
+	var positional []pyValue
+	for _, arg := range args {
+		value := arg
+		if arg.kind == pyKeyword {
+			value = *arg.value
+		}
+		if value.kind == pyIdent {
+			resolved, ok := params[value.str]
+			if !ok {
+				return c, fmt.Errorf("unknown name %s", value.str)
+			}
+			value = resolved
+		}
+
+		switch {
+		case arg.kind != pyKeyword:
+			positional = append(positional, value)
+		case arg.str == "line" && value.kind == pyInt:
+			c.ExpectedLine = value.num
+		case arg.str == "disable" && value.kind == pyString:
+			c.DisabledRules = append(c.DisabledRules, value.str)
+		case arg.str == "disable" && value.kind == pyList:
+			for _, rule := range value.items {
+				c.DisabledRules = append(c.DisabledRules, rule.str)
+			}
+		default:
+			return c, fmt.Errorf("unsupported argument %s", arg.str)
+		}
+	}
+
+	if len(positional) == 0 || positional[0].kind != pyString {
+		return c, fmt.Errorf("%s needs the code of the snippet", check)
+	}
+	c.Code = positional[0].str
+	if !c.OK {
+		if len(positional) < 2 || positional[1].kind != pyString {
+			return c, fmt.Errorf("%s needs the expected rule", check)
+		}
+		c.Rule = positional[1].str
+		c.ExpectedRule = positional[1].str
+	}
+	if strings.HasPrefix(c.Code, "\n") {
+		c.Code = c.Code[1:]
+		c.ExpectedLine--
+	}
+	if !strings.HasSuffix(c.Code, "\n") {
+		c.Code += "\n"
+	}
+	if c.OK {
+		c.ExpectedLine = 0
+	}
+	return c, nil
+}
+
+// FormatLinterCorpus writes cases in the case file format LoadLinterCorpus
+// reads, under a comment naming the Python file they were ported from
+func FormatLinterCorpus(origin string, cases []LinterCase) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Ported from gdtoolkit %s\n", origin)
+	b.WriteString("#\n")
+	b.WriteString("# Each case starts with a header line:\n")
+	b.WriteString("#   == ok <name> rule=<rule> [disable=<rule>,...]\n")
+	b.WriteString("#   == nok <name> <rule>:<line> [disable=<rule>,...]\n")
+	b.WriteString("# and runs until the next header. Lines starting with '#' before the first\n")
+	b.WriteString("# header are comments.\n")
+	for _, c := range cases {
+		b.WriteString("\n== ")
+		if c.OK {
+			fmt.Fprintf(&b, "ok %s rule=%s", c.ID, c.Rule)
+		} else {
+			fmt.Fprintf(&b, "nok %s %s:%d", c.ID, c.ExpectedRule, c.ExpectedLine)
+		}
+		if len(c.DisabledRules) > 0 {
+			fmt.Fprintf(&b, " disable=%s", strings.Join(c.DisabledRules, ","))
+		}
+		b.WriteString("\n")
+		b.WriteString(c.Code)
+	}
+	return b.String()
+}
+
+type pyKind int
+
+const (
+	pyString pyKind = iota
+	pyInt
+	pyIdent
+	pyList
+	pyKeyword
+)
+
+// pyValue is a Python literal, a name, or a keyword argument of a call
+type pyValue struct {
+	kind  pyKind
+	str   string // string value, name, or keyword
+	num   int
+	items []pyValue // list or tuple items
+	value *pyValue  // keyword argument value
+}
+
+// pyScanner reads the Python literals pytest test files are written with
+type pyScanner struct {
+	src string
+	pos int
+}
+
+// arguments reads call arguments up to the closing parenthesis
+func (s *pyScanner) arguments() ([]pyValue, error) {
+	var args []pyValue
+	for {
+		s.skipSpace()
+		if s.peek() == ')' {
+			s.pos++
+			return args, nil
+		}
+		arg, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		if arg.kind == pyIdent && s.peek() == '=' {
+			s.pos++
+			s.skipSpace()
+			value, err := s.value()
+			if err != nil {
+				return nil, err
+			}
+			arg = pyValue{kind: pyKeyword, str: arg.str, value: &value}
+		}
+		args = append(args, arg)
+		if err := s.separator(')'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the comma after an item, or checks for the closing bracket
+func (s *pyScanner) separator(closing byte) error {
+	s.skipSpace()
+	switch s.peek() {
+	case ',':
+		s.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fmt.Errorf("expected ',' or '%c' at offset %d", closing, s.pos)
+}
+
+func (s *pyScanner) value() (pyValue, error) {
+	s.skipSpace()
+	c := s.peek()
+	switch {
+	case c == '[' || c == '(':
+		closing := byte(']')
+		if c == '(' {
+			closing = ')'
+		}
+		s.pos++
+		var items []pyValue
+		for {
+			s.skipSpace()
+			if s.peek() == closing {
+				s.pos++
+				return pyValue{kind: pyList, items: items}, nil
+			}
+			item, err := s.value()
+			if err != nil {
+				return pyValue{}, err
+			}
+			items = append(items, item)
+			if err := s.separator(closing); err != nil {
+				return pyValue{}, err
+			}
+		}
+	case c >= '0' && c <= '9' || c == '-':
+		start := s.pos
+		s.pos++
+		for s.pos < len(s.src) && s.src[s.pos] >= '0' && s.src[s.pos] <= '9' {
+			s.pos++
+		}
+		n, err := strconv.Atoi(s.src[start:s.pos])
+		return pyValue{kind: pyInt, num: n}, err
+	case c == '"' || c == '\'' || (c == 'r' || c == 'R') && s.pos+1 < len(s.src) && (s.src[s.pos+1] == '"' || s.src[s.pos+1] == '\''):
+		// Adjacent string literals are concatenated
+		var value strings.Builder
+		for {
+			str, err := s.stringLiteral()
+			if err != nil {
+				return pyValue{}, err
+			}
+			value.WriteString(str)
+			s.skipSpace()
+			if next := s.peek(); next != '"' && next != '\'' && next != 'r' && next != 'R' {
+				return pyValue{kind: pyString, str: value.String()}, nil
+			}
+		}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := s.pos
+		for s.pos < len(s.src) && (s.src[s.pos] == '_' || s.src[s.pos] == '.' ||
+			s.src[s.pos] >= 'a' && s.src[s.pos] <= 'z' || s.src[s.pos] >= 'A' && s.src[s.pos] <= 'Z' ||
+			s.src[s.pos] >= '0' && s.src[s.pos] <= '9') {
+			s.pos++
+		}
+		return pyValue{kind: pyIdent, str: s.src[start:s.pos]}, nil
+	}
+	return pyValue{}, fmt.Errorf("unexpected %q at offset %d", c, s.pos)
+}
+
+// stringLiteral reads a single, triple-quoted or raw string literal
+func (s *pyScanner) stringLiteral() (string, error) {
+	raw := false
+	if c := s.peek(); c == 'r' || c == 'R' {
+		raw = true
+		s.pos++
+	}
+	quote := s.src[s.pos : s.pos+1]
+	if strings.HasPrefix(s.src[s.pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	s.pos += len(quote)
+
+	var value strings.Builder
+	for {
+		if s.pos >= len(s.src) {
+			return "", fmt.Errorf("unterminated string literal")
+		}
+		if strings.HasPrefix(s.src[s.pos:], quote) {
+			s.pos += len(quote)
+			return value.String(), nil
+		}
+		c := s.src[s.pos]
+		if c != '\\' || s.pos+1 >= len(s.src) {
+			value.WriteByte(c)
+			s.pos++
+			continue
+		}
+		escaped := s.src[s.pos+1]
+		s.pos += 2
+		switch {
+		case raw:
+			value.WriteByte('\\')
+			value.WriteByte(escaped)
+		case escaped == 'n':
+			value.WriteByte('\n')
+		case escaped == 't':
+			value.WriteByte('\t')
+		case escaped == 'r':
+			value.WriteByte('\r')
+		case escaped == '\n':
+			// A backslash at the end of a line continues the string
+		case escaped == '\\' || escaped == '\'' || escaped == '"':
+			value.WriteByte(escaped)
+		default:
+			value.WriteByte('\\')
+			value.WriteByte(escaped)
+		}
+	}
+}
+
+// skipSpace skips whitespace, newlines and comments
+func (s *pyScanner) skipSpace() {
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		case '#':
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+				s.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *pyScanner) peek() byte {
+	if s.pos < len(s.src) {
+		return s.src[s.pos]
+	}
+	return 0
+}
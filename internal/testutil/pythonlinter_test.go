@@ -0,0 +1,88 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const pythonLinterTests = `import pytest
+
+from .common import simple_ok_check, simple_nok_check
+
+
+# fmt: off
+@pytest.mark.parametrize('code', [
+"""
+func foo(_x):
+    pass
+""",
+])
+def test_unused_argument_ok(code):
+    simple_ok_check(code)
+
+
+@pytest.mark.parametrize('code,expected_line', [
+("""
+func foo(x):
+    pass
+""", 2),
+("""
+func foo(a,
+\tx):
+    pass
+""", 3),
+])
+def test_unused_argument_nok(code, expected_line):
+    simple_nok_check(code, 'unused-argument', line=expected_line)
+
+
+def test_pass_before_expression():
+    simple_nok_check(r"""
+func foo():
+    pass
+    1
+""", "unnecessary-pass", disable=["expression-not-assigned"])
+
+
+def test_config():
+    assert True
+`
+
+func TestPortPythonLinterTests(t *testing.T) {
+	cases, skipped, err := PortPythonLinterTests(pythonLinterTests)
+	if err != nil {
+		t.Fatalf("PortPythonLinterTests failed: %v", err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"test_config"}) {
+		t.Errorf("Expected test_config skipped, got %v", skipped)
+	}
+
+	expected := []LinterCase{
+		{ID: "unused_argument_ok_1", Code: "func foo(_x):\n    pass\n", OK: true, Rule: "unused-argument"},
+		{ID: "unused_argument_nok_1", Code: "func foo(x):\n    pass\n", ExpectedRule: "unused-argument", ExpectedLine: 1, Rule: "unused-argument"},
+		{ID: "unused_argument_nok_2", Code: "func foo(a,\n\tx):\n    pass\n", ExpectedRule: "unused-argument", ExpectedLine: 2, Rule: "unused-argument"},
+		{ID: "pass_before_expression", Code: "func foo():\n    pass\n    1\n", ExpectedRule: "unnecessary-pass", ExpectedLine: 1,
+			DisabledRules: []string{"expression-not-assigned"}, Rule: "unnecessary-pass"},
+	}
+	if !reflect.DeepEqual(cases, expected) {
+		t.Fatalf("Expected cases:\n%+v\ngot:\n%+v", expected, cases)
+	}
+
+	// The generated case file reads back as the same cases, with the file name as prefix
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "basic.txt"), []byte(FormatLinterCorpus("tests/linter/test_basic.py", cases)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLinterCorpus(dir)
+	if err != nil {
+		t.Fatalf("LoadLinterCorpus failed: %v", err)
+	}
+	for i := range expected {
+		expected[i].ID = "basic/" + expected[i].ID
+	}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("Expected the case file to load as:\n%+v\ngot:\n%+v", expected, loaded)
+	}
+}
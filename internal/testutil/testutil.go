@@ -139,18 +139,19 @@ func SimpleNOKCheck(t *testing.T, code string, expectedRule string, expectedLine
 	}
 }
 
-// CompareParsedASTs compares two ASTs for structural equality (for validating parser compatibility)
+// CompareParsedASTs fails the test when two ASTs differ in anything but
+// positions, whitespace, comments and literal spelling, as ast.Compare does
 func CompareParsedASTs(t *testing.T, ast1, ast2 *ast.AbstractSyntaxTree) {
-	// This is a placeholder for AST comparison logic
-	// In a full implementation, this would recursively compare AST nodes
-	// For now, we'll just check that both are non-nil
+	t.Helper()
 	if ast1 == nil && ast2 == nil {
 		return
 	}
 	if ast1 == nil || ast2 == nil {
 		t.Fatalf("AST comparison failed: one AST is nil")
 	}
-	// TODO: Implement detailed AST comparison
+	for _, difference := range ast.Compare(ast1, ast2) {
+		t.Errorf("ASTs differ at %s (%s / %s)", difference, difference.A, difference.B)
+	}
 }
 
 // TestParserOnValidFiles tests the parser against all valid GDScript files
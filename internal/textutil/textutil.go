@@ -0,0 +1,12 @@
+// Package textutil formats the text the tools print
+package textutil
+
+import "fmt"
+
+// Pluralize formats a count of things, e.g. "1 error" or "2 errors"
+func Pluralize(count int, thing string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", thing)
+	}
+	return fmt.Sprintf("%d %ss", count, thing)
+}
@@ -0,0 +1,11 @@
+package textutil
+
+import "testing"
+
+func TestPluralize(t *testing.T) {
+	for count, expected := range map[int]string{0: "0 errors", 1: "1 error", 2: "2 errors"} {
+		if got := Pluralize(count, "error"); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package translation extracts the strings scripts translate with tr(),
+// tr_n(), atr() and atr_n(), and writes them as the POT and CSV files of
+// Godot's localization workflow.
+package translation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+)
+
+// Source is the place a message is translated
+type Source struct {
+	// Path is the path of the script, as given to Catalog.Extract
+	Path string
+	Line int
+}
+
+// String returns the source as path:line
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.Path, s.Line)
+}
+
+// Message is a string to translate
+type Message struct {
+	// Context tells apart the messages with the same ID, as the context
+	// argument of tr() does
+	Context string
+	ID      string
+	// Plural is the plural form of the ID for tr_n() and atr_n(), if any
+	Plural string
+	// Sources are the places the message is translated, in extraction order
+	Sources []Source
+}
+
+// translationCall describes the arguments of a translation function
+type translationCall struct {
+	// plural is the index of the plural argument, or -1
+	plural int
+	// context is the index of the context argument
+	context int
+}
+
+// translationCalls are the methods of Object translating strings
+var translationCalls = map[string]translationCall{
+	"tr":    {plural: -1, context: 1},
+	"atr":   {plural: -1, context: 1},
+	"tr_n":  {plural: 1, context: 3},
+	"atr_n": {plural: 1, context: 3},
+}
+
+// Catalog collects the messages of scripts, merging the same message
+// translated in several places
+type Catalog struct {
+	// Messages are the messages in the order they were first found
+	Messages []*Message
+
+	byKey map[[2]string]*Message
+}
+
+// NewCatalog returns an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{byKey: make(map[[2]string]*Message)}
+}
+
+// Extract adds the messages of the script at path to the catalog: the
+// string literals passed to tr(), tr_n(), atr() and atr_n(), called on
+// any object. Calls translating computed strings are left out.
+func (c *Catalog) Extract(path string, tree *ast.AbstractSyntaxTree) {
+	ast.Inspect(tree, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		var name string
+		switch function := call.Function.(type) {
+		case *ast.Identifier:
+			name = function.Value
+		case *ast.DotExpression:
+			name = function.Property
+		}
+		kind, ok := translationCalls[name]
+		if !ok {
+			return true
+		}
+
+		id, ok := stringArgument(call, 0)
+		if !ok {
+			return true
+		}
+		context, _ := stringArgument(call, kind.context)
+		plural := ""
+		if kind.plural >= 0 {
+			plural, _ = stringArgument(call, kind.plural)
+		}
+		c.add(context, id, plural, Source{Path: path, Line: call.Position().Line})
+		return true
+	})
+}
+
+// stringArgument returns the argument i of call when it is a string literal
+func stringArgument(call *ast.CallExpression, i int) (string, bool) {
+	if i >= len(call.Arguments) {
+		return "", false
+	}
+	literal, ok := call.Arguments[i].(*ast.StringLiteral)
+	if !ok {
+		return "", false
+	}
+	return literal.Value, true
+}
+
+// add records a message translated at source
+func (c *Catalog) add(context, id, plural string, source Source) {
+	key := [2]string{context, id}
+	message := c.byKey[key]
+	if message == nil {
+		message = &Message{Context: context, ID: id}
+		c.byKey[key] = message
+		c.Messages = append(c.Messages, message)
+	}
+	if message.Plural == "" {
+		message.Plural = plural
+	}
+	message.Sources = append(message.Sources, source)
+}
+
+// WritePOT writes the messages as a gettext template, each preceded by the
+// sources it is translated at, as Godot's POT generation does
+func WritePOT(w io.Writer, messages []*Message) error {
+	var b strings.Builder
+	b.WriteString("# LANGUAGE translation.\n")
+	b.WriteString("#\n")
+	b.WriteString("#, fuzzy\n")
+	b.WriteString("msgid \"\"\n")
+	b.WriteString("msgstr \"\"\n")
+	b.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	b.WriteString("\"Content-Transfer-Encoding: 8-bit\\n\"\n")
+
+	for _, message := range messages {
+		b.WriteString("\n")
+		for _, source := range message.Sources {
+			fmt.Fprintf(&b, "#: %s\n", source)
+		}
+		if message.Context != "" {
+			fmt.Fprintf(&b, "msgctxt %s\n", quotePO(message.Context))
+		}
+		fmt.Fprintf(&b, "msgid %s\n", quotePO(message.ID))
+		if message.Plural != "" {
+			fmt.Fprintf(&b, "msgid_plural %s\n", quotePO(message.Plural))
+			b.WriteString("msgstr[0] \"\"\n")
+			b.WriteString("msgstr[1] \"\"\n")
+		} else {
+			b.WriteString("msgstr \"\"\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quotePO quotes s as a PO string, splitting it after its line breaks
+func quotePO(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`, "\r", `\r`).Replace(s)
+	lines := strings.SplitAfter(escaped, "\n")
+	if len(lines) == 1 {
+		return `"` + escaped + `"`
+	}
+
+	parts := []string{`""`}
+	for _, line := range lines {
+		if line != "" {
+			parts = append(parts, `"`+strings.ReplaceAll(line, "\n", `\n`)+`"`)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// WriteCSV writes the messages as a translation CSV with the keys column,
+// the locale column holding the messages as written, which Godot imports,
+// and a _sources column with the sources of each message, which it skips.
+// CSV translations have no contexts or plurals: messages differing only by
+// context share a row, and plural forms get rows of their own.
+func WriteCSV(w io.Writer, messages []*Message, locale string) error {
+	sources := make(map[string][]string)
+	var keys []string
+	addKey := func(key string, message *Message) {
+		if _, ok := sources[key]; !ok {
+			keys = append(keys, key)
+		}
+		for _, source := range message.Sources {
+			sources[key] = append(sources[key], source.String())
+		}
+	}
+	for _, message := range messages {
+		addKey(message.ID, message)
+		if message.Plural != "" {
+			addKey(message.Plural, message)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"keys", locale, "_sources"}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := writer.Write([]string{key, key, strings.Join(distinct(sources[key]), " ")}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// distinct returns the strings of values without repetitions, in order
+func distinct(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
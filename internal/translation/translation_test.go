@@ -0,0 +1,97 @@
+package translation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+)
+
+func extract(t *testing.T, scripts map[string]string, order ...string) *Catalog {
+	t.Helper()
+	catalog := NewCatalog()
+	for _, path := range order {
+		tree, errors := parser.ParseFile(path, scripts[path])
+		if len(errors) > 0 {
+			t.Fatalf("%s: %v", path, errors)
+		}
+		catalog.Extract(path, tree)
+	}
+	return catalog
+}
+
+func TestExtract(t *testing.T) {
+	scripts := map[string]string{
+		"res://hud.gd": `extends Control
+func _ready():
+	$Label.text = tr("Hello")
+	title = self.tr("Open", "menu")
+	var n = atr_n("apple", "apples", count)
+	print(tr(name), tr_n("%d life", "%d lives", 3, "hud"))
+`,
+		"res://menu.gd": "extends Node\nfunc _ready():\n\tprint(tr(\"Hello\"), atr(\"Open\", \"door\"))\n",
+	}
+	catalog := extract(t, scripts, "res://hud.gd", "res://menu.gd")
+
+	var got []string
+	for _, m := range catalog.Messages {
+		var sources []string
+		for _, source := range m.Sources {
+			sources = append(sources, source.String())
+		}
+		got = append(got, m.Context+"|"+m.ID+"|"+m.Plural+"|"+strings.Join(sources, " "))
+	}
+	expected := []string{
+		"|Hello||res://hud.gd:3 res://menu.gd:3",
+		"menu|Open||res://hud.gd:4",
+		"|apple|apples|res://hud.gd:5",
+		"hud|%d life|%d lives|res://hud.gd:6",
+		"door|Open||res://menu.gd:3",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestWritePOT(t *testing.T) {
+	catalog := extract(t, map[string]string{
+		"a.gd": "func f():\n\ttr(\"Say \\\"hi\\\"\\nnow\", \"ui\")\n\ttr_n(\"one\", \"many\", 2)\n",
+	}, "a.gd")
+
+	var out strings.Builder
+	if err := WritePOT(&out, catalog.Messages); err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+#: a.gd:2
+msgctxt "ui"
+msgid ""
+"Say \"hi\"\n"
+"now"
+msgstr ""
+
+#: a.gd:3
+msgid "one"
+msgid_plural "many"
+msgstr[0] ""
+msgstr[1] ""
+`
+	if !strings.HasPrefix(out.String(), "# LANGUAGE translation.\n") || !strings.HasSuffix(out.String(), expected) {
+		t.Errorf("Unexpected template:\n%s", out.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	catalog := extract(t, map[string]string{
+		"a.gd": "func f():\n\ttr(\"Open\", \"menu\")\n\ttr(\"Open\", \"door\")\n\ttr_n(\"a, b\", \"c\", 2)\n",
+	}, "a.gd")
+
+	var out strings.Builder
+	if err := WriteCSV(&out, catalog.Messages, "fr"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "keys,fr,_sources\nOpen,Open,a.gd:2 a.gd:3\n\"a, b\",\"a, b\",a.gd:4\nc,c,a.gd:4\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
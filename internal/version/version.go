@@ -0,0 +1,63 @@
+// Package version identifies the release the tools were built from. Release
+// builds set Version and Commit with the linker:
+//
+//	go build -ldflags "-X github.com/dzannotti/gdtoolkit/internal/version.Version=1.2.0 \
+//		-X github.com/dzannotti/gdtoolkit/internal/version.Commit=$(git rev-parse --short HEAD)" ./cmd/...
+//
+// which make build does. Other builds report the module version and VCS
+// revision Go embeds, when there are any.
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// Version is the semantic version of the release, set by the linker
+var Version = ""
+
+// Commit is the git commit the tools were built from, set by the linker
+var Commit = ""
+
+// Grammar is the version of GDScript the parser accepts: GDScript 2.0, the
+// language of Godot 4
+const Grammar = "2.0"
+
+// Info describes the build of a tool, as written in the metadata of reports
+type Info struct {
+	Tool    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Grammar string `json:"gdscript_grammar"`
+}
+
+// Get returns the build of tool, e.g. "gdlint". The version is "dev" when
+// neither the linker nor Go set one.
+func Get(tool string) Info {
+	info := Info{Tool: tool, Version: Version, Commit: Commit, Grammar: Grammar}
+	build, ok := debug.ReadBuildInfo()
+	if info.Version == "" && ok && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = strings.TrimPrefix(build.Main.Version, "v")
+	}
+	if info.Commit == "" && ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				info.Commit = setting.Value[:12]
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
+
+// String returns the line --version prints, e.g.
+// "gdlint 1.2.0 (commit 4f2a9c1, GDScript 2.0)"
+func (i Info) String() string {
+	if i.Commit == "" {
+		return fmt.Sprintf("%s %s (GDScript %s)", i.Tool, i.Version, i.Grammar)
+	}
+	return fmt.Sprintf("%s %s (commit %s, GDScript %s)", i.Tool, i.Version, i.Commit, i.Grammar)
+}
@@ -0,0 +1,25 @@
+package version
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+
+	Version, Commit = "1.2.0", "4f2a9c1"
+	info := Get("gdlint")
+	if info != (Info{Tool: "gdlint", Version: "1.2.0", Commit: "4f2a9c1", Grammar: Grammar}) {
+		t.Errorf("Expected the version set by the linker, got %+v", info)
+	}
+	if info.String() != "gdlint 1.2.0 (commit 4f2a9c1, GDScript 2.0)" {
+		t.Errorf("Unexpected version line %q", info.String())
+	}
+
+	// Test binaries have no module version
+	Version, Commit = "", ""
+	if info := Get("gdformat"); info.Version != "dev" {
+		t.Errorf("Expected a dev version, got %+v", info)
+	}
+	if s := (Info{Tool: "gdformat", Version: "dev", Grammar: "2.0"}).String(); s != "gdformat dev (GDScript 2.0)" {
+		t.Errorf("Unexpected version line %q", s)
+	}
+}
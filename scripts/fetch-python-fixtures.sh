@@ -0,0 +1,50 @@
+#!/bin/sh
+# Fetches the test fixtures of Python gdtoolkit into gdtoolkit/tests, where
+# the tests comparing the Go tools with Python gdtoolkit look for them:
+# valid-gd-scripts, invalid-gd-scripts, potential-godot-bugs and the
+# formatter input-output-pairs.
+#
+# Usage: scripts/fetch-python-fixtures.sh [ref]
+#
+# ref is the branch, tag or commit of github.com/Scony/godot-gdscript-toolkit
+# to check out, the commit pinned in testdata/formatter_python/ref by default.
+# The skip lists in testdata are kept for the pinned commit, so checking out
+# another ref pins the commit it resolves to; regenerate
+# testdata/formatter_python/skip.txt with `make python-skip-list` and commit
+# both files then.
+set -eu
+
+repo=${PYTHON_GDTOOLKIT_REPO:-https://github.com/Scony/godot-gdscript-toolkit.git}
+root=$(cd "$(dirname "$0")/.." && pwd)
+dest=$root/gdtoolkit
+pin=$root/testdata/formatter_python/ref
+pinned=$(sed '/^#/d' "$pin" | head -n 1)
+ref=${1:-$pinned}
+if [ -z "$ref" ]; then
+	echo "No commit of Python gdtoolkit is pinned in $pin, pass the ref to check out and pin" >&2
+	exit 1
+fi
+
+if [ -d "$dest/.git" ]; then
+	git -C "$dest" fetch --depth 1 origin "$ref"
+elif [ -e "$dest" ]; then
+	echo "$dest exists and is not a checkout of Python gdtoolkit, remove it first" >&2
+	exit 1
+else
+	git init --quiet "$dest"
+	git -C "$dest" remote add origin "$repo"
+	git -C "$dest" sparse-checkout set tests
+	git -C "$dest" fetch --depth 1 origin "$ref"
+fi
+git -C "$dest" checkout --quiet --force FETCH_HEAD
+commit=$(git -C "$dest" rev-parse HEAD)
+echo "Python gdtoolkit fixtures at $commit in $dest/tests"
+
+if [ "$commit" != "$pinned" ]; then
+	{
+		sed -n '/^#/p' "$pin"
+		echo "$commit"
+	} >"$pin.tmp"
+	mv "$pin.tmp" "$pin"
+	echo "Pinned $commit in $pin, regenerate the skip list with make python-skip-list and commit both"
+fi
@@ -0,0 +1,17 @@
+@tool
+class_name Player extends CharacterBody2D
+## Emitted when hit.
+signal hit( damage:int,source )
+signal died
+enum State {IDLE, RUN = 2,
+	JUMP}
+enum {FIRST, SECOND}
+enum LongNames {A_VERY_LONG_ELEMENT_NAME, ANOTHER_VERY_LONG_ELEMENT_NAME, YET_ANOTHER_LONG_ELEMENT_NAME}
+@export_range(0,10) var speed = 1  # inline
+@onready
+var label = get_node("Label")
+
+
+@rpc("any_peer")
+func sync():
+	pass
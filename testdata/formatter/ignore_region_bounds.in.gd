@@ -0,0 +1,20 @@
+extends Node
+# Kept above the region
+# gdformat: off
+var   a=1
+# gdformat: on
+
+
+func foo( x ):
+	# gdformat: off
+	var m = [ 1,  2,
+	          3,  4 ]
+# gdformat: on
+	return  x
+
+
+# gdformat: off
+func cut( ):
+	# gdformat: on
+	return  1
+var   after=2
@@ -0,0 +1,25 @@
+extends Node
+var a  =  1
+# gdformat: off
+const TABLE = [
+	[1,   0,   0],
+	[0,   1,   0],
+	[0,   0,   1],
+]
+var   b=2   # aligned
+# gdformat: on
+var c  =  3
+
+
+func foo( x ):
+	var y   = x
+	# gdformat: off
+	var m = [ 1,  2,
+	          3,  4 ]
+	# gdformat: on
+	return  y + m[0]
+# gdformat: off
+# just a comment
+# gdformat: on
+func bar():
+	pass
@@ -0,0 +1,9 @@
+var numbers = [1, 2, 3]
+var table = {"a": 1, "b": [2]}
+
+
+func foo():
+	print(numbers, table)
+	var total = 1 + 2
+	if total > 0:
+		return [total, total * 2]
@@ -0,0 +1,25 @@
+extends Node
+var a:Array[int]
+var b : Dictionary[String,Array[int]] = {}
+const C:Array[int]=[1]
+const E:=5
+var d:Node.ProcessMode
+
+
+func foo(e:Array[ Node ],f:Node.ProcessMode=PROCESS_MODE_INHERIT)->Array[int]:
+	for g:int in e.size():
+		pass
+	return [1]
+
+
+func bar()->void:
+	const F :=  "f"
+	print(F)
+
+
+func baz(  )  ->  Dictionary [String, int]:
+	return {}
+
+
+func qux()->Node.ProcessMode:
+	return d
@@ -0,0 +1,13 @@
+# スクリプトの説明
+extends Node  # 継承
+
+# 体力の初期値
+var 体力 = 100  # ポイント
+
+
+# 关于这个函数的注释
+func 攻撃(目標):  # 攻撃する
+	# 伤害计算
+	var ダメージ = 体力 * 2  # 两倍
+	目標.受ける(ダメージ)
+	# 終わり
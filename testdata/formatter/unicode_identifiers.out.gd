@@ -0,0 +1,6 @@
+var café = 1
+var naïve_größe: int = 2
+
+
+func ñandú(señal, über = café):
+	return señal + über * naïve_größe
@@ -0,0 +1,4 @@
+func greet():
+	print("¡Hola! 👋🏽 こんにちは 你好 🎉")
+	var label = get_node("UI/ラベル").get_child(0).set_text("絵文字🎉🎉🎉とテキストを表示する")
+	var flag = "🇯🇵" if true else "🇫🇷"
@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestOnreadyWithoutNodePath checks the rule and the fixes dropping the annotation
+func TestOnreadyWithoutNodePath(t *testing.T) {
+	testCases := []struct {
+		name  string
+		code  string
+		fixed string // the code with the fixes applied; empty when nothing is reported
+	}{
+		{
+			name: "values from the scene tree",
+			code: "extends Node\n@onready var a = get_node(\"A\")\n@onready var b = get_parent()\n" +
+				"@onready var c = $C\n@onready var d = speed * 2\n",
+		},
+		{
+			name:  "annotation on the line of the variable",
+			code:  "extends Node\n@onready var speed = 5\n",
+			fixed: "extends Node\nvar speed = 5\n",
+		},
+		{
+			name:  "annotation on its own line",
+			code:  "extends Node\n@onready\nvar names = [\"a\", \"b\"]\nvar other = 1\n",
+			fixed: "extends Node\nvar names = [\"a\", \"b\"]\nvar other = 1\n",
+		},
+		{
+			name:  "annotation after another one",
+			code:  "extends Node\n@export @onready var limit = -1\n",
+			fixed: "extends Node\n@export var limit = -1\n",
+		},
+		{
+			name: "several variables",
+			code: "extends Node\n@onready var a = 1\n@onready var b = \"b\" + \"c\"\n" +
+				"class Inner:\n\t@onready var c = null\n",
+			fixed: "extends Node\nvar a = 1\nvar b = \"b\" + \"c\"\n" +
+				"class Inner:\n\tvar c = null\n",
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultAnnotationRules(), linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			if tc.fixed == "" {
+				if len(problems) != 0 {
+					t.Errorf("Expected no problems, got %v", problems)
+				}
+				return
+			}
+			if len(problems) == 0 {
+				t.Fatalf("Expected problems, got none")
+			}
+			for _, p := range problems {
+				if p.RuleName != "onready-without-node-path" || p.Fix == nil {
+					t.Errorf("Expected a fixable onready-without-node-path problem, got %v", p)
+				}
+			}
+
+			fixed, count := problem.ApplyFixes(tc.code, problems)
+			if count != len(problems) {
+				t.Errorf("Expected %d fixes applied, got %d", len(problems), count)
+			}
+			if fixed != tc.fixed {
+				t.Errorf("Fixed code mismatch\nexpected:\n%s\ngot:\n%s", tc.fixed, fixed)
+			}
+			if problems, err := l.Lint(fixed); err != nil || len(problems) != 0 {
+				t.Errorf("Expected the fixed code to lint clean, got %v (%v)", problems, err)
+			}
+		})
+	}
+}
+
+// TestApplyFixesSkipsOverlappingEdits checks that only the first of two
+// conflicting fixes is applied
+func TestApplyFixesSkipsOverlappingEdits(t *testing.T) {
+	problems := []problem.Problem{
+		{Fix: &problem.Fix{Edits: []problem.Edit{{Start: 0, End: 3, Text: "abc"}}}},
+		{Fix: &problem.Fix{Edits: []problem.Edit{{Start: 2, End: 4, Text: "x"}}}},
+		{Fix: &problem.Fix{Edits: []problem.Edit{{Start: 6, End: 6, Text: "!"}}}},
+		{Fix: &problem.Fix{Edits: []problem.Edit{{Start: 5, End: 99}}}},
+		{},
+	}
+
+	fixed, count := problem.ApplyFixes("foo bar", problems)
+	if fixed != "abc ba!r" || count != 2 {
+		t.Errorf("Expected \"abc ba!r\" with 2 fixes, got %q with %d", fixed, count)
+	}
+}
+
+// TestInvalidAnnotation checks the annotation names and arguments the rule reports
+func TestInvalidAnnotation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // the messages reported, in order
+	}{
+		{
+			name: "valid annotations",
+			code: "@tool\n@icon(\"res://icon.svg\")\nextends Node\n" +
+				"@export_range(1, 10) var a = 1\n@export_range(-1.5, 1.5, 0.1, \"or_greater\") var b = 0.0\n" +
+				"@export_enum(\"Warrior\", \"Magician\") var c = 0\n@export_group(\"Stats\", \"stat_\")\n" +
+				"@export_range(0, MAX_SPEED) var d = 0\n@onready var e = $E\n" +
+				"@rpc(\"any_peer\", \"call_local\", \"reliable\", 1)\nfunc f():\n\tpass\n" +
+				"@warning_ignore(\"unused_parameter\")\nfunc g(x):\n\tpass\n",
+		},
+		{
+			name:     "unknown annotation",
+			code:     "@exprot var a = 1\n",
+			expected: []string{"Unknown annotation '@exprot'"},
+		},
+		{
+			name: "wrong number of arguments",
+			code: "@export_range(1) var a = 1\n@onready(1) var b = 2\n@export_enum var c = 0\n" +
+				"@export_group(\"A\", \"a_\", \"b\")\nvar d = 0\n",
+			expected: []string{
+				"@export_range takes at least 2 arguments, got 1",
+				"@onready takes no arguments, got 1",
+				"@export_enum takes at least 1 argument, got 0",
+				"@export_group takes 1 to 2 arguments, got 3",
+			},
+		},
+		{
+			name: "wrong kind of arguments",
+			code: "@export_range(\"1\", 10, 1, 2) var a = 1\n@export_enum(\"A\", 2) var b = 0\n" +
+				"@rpc(\"any_pear\", 1.5)\nfunc f():\n\tpass\n",
+			expected: []string{
+				"Argument 1 of @export_range should be a number",
+				"Argument 4 of @export_range should be a string",
+				"Argument 2 of @export_enum should be a string",
+				"Argument 1 of @rpc 'any_pear' should be one of authority, any_peer, call_local, call_remote, unreliable, unreliable_ordered, reliable",
+				"Argument 2 of @rpc should be an integer transfer channel",
+			},
+		},
+	}
+
+	l := linter.NewLinter([]linter.Rule{&rules.InvalidAnnotation{}}, linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			var messages []string
+			for _, p := range problems {
+				if p.Severity != problem.Error {
+					t.Errorf("Expected an error, got %v", p)
+				}
+				messages = append(messages, p.Message)
+			}
+			if strings.Join(messages, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(tc.expected, "\n"), strings.Join(messages, "\n"))
+			}
+		})
+	}
+}
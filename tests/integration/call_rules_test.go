@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestForbiddenCall checks the calls reported with the calls setting of a gdlintrc
+func TestForbiddenCall(t *testing.T) {
+	var config linter.Config
+	rc := `{"rule_settings": {"forbidden-call": {"calls": ["print", "OS.execute", "get_node in _process"]}}}`
+	if err := json.Unmarshal([]byte(rc), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name  string
+		code  string
+		lines []int // lines of the expected problems
+	}{
+		{
+			name: "plain name on any object",
+			code: `extends Node
+func _ready():
+	print("a")
+	self.print("b")
+	logger.print("c")
+	prints("d")
+`,
+			lines: []int{3, 4, 5},
+		},
+		{
+			name: "dotted name",
+			code: `extends Node
+func run():
+	OS.execute("ls", [])
+	var os = OS
+	os.execute("ls", [])
+	OS.get_name()
+`,
+			lines: []int{3},
+		},
+		{
+			name: "only in a function",
+			code: `extends Node
+func _ready():
+	var a = get_node("A")
+func _process(delta):
+	var b = get_node("B")
+	var c = get_tree().get_node("C")
+`,
+			lines: []int{5, 6},
+		},
+	}
+
+	l := linter.NewLinter([]linter.Rule{&rules.ForbiddenCall{}}, config)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tc.lines) {
+				t.Fatalf("Expected problems on lines %v, got %v", tc.lines, problems)
+			}
+			for i, line := range tc.lines {
+				if problems[i].Position.Line != line || problems[i].RuleName != "forbidden-call" {
+					t.Errorf("Expected a forbidden-call problem on line %d, got %v", line, problems[i])
+				}
+			}
+		})
+	}
+
+	// Without calls configured, nothing is forbidden
+	problems, err := linter.NewLinter([]linter.Rule{&rules.ForbiddenCall{}}, linter.DefaultConfig()).Lint("func f():\n\tprint(1)\n")
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected no problems by default, got %v (%v)", problems, err)
+	}
+}
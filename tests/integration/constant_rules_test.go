@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestConstantRules checks the rules built on constant expressions
+func TestConstantRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // "line: message" of the expected problems
+	}{
+		{
+			name: "division and modulo by zero",
+			code: `
+func foo(x):
+	var a = x / 0
+	var b = x % (2 - 2)
+	x /= 0.0
+	var c = x / (x - x)
+	var d = x / 2
+	var e = x / y
+`,
+			expected: []string{"3: Division by zero", "4: Modulo by zero", "5: Division by zero", "6: Division by zero"},
+		},
+		{
+			name: "if branches with constant conditions",
+			code: `
+func foo(x):
+	if false:
+		print(x)
+	if x:
+		pass
+	elif 0:
+		pass
+	if true:
+		pass
+	elif x:
+		pass
+	else:
+		pass
+	if x:
+		pass
+	elif 1 + 1 == 2:
+		pass
+	else:
+		pass
+`,
+			expected: []string{
+				"3: Branch never runs, its condition is always false",
+				"7: Branch never runs, its condition is always 0",
+				"11: Branch never runs, an earlier condition is always true",
+				"19: Else branch never runs, an earlier condition is always true",
+			},
+		},
+		{
+			name: "loops with constant conditions",
+			code: `
+func foo(x):
+	while true:
+		break
+	while false:
+		pass
+	while x:
+		break
+`,
+			expected: []string{"5: Loop never runs, its condition is always false"},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultConstantRules(), linter.DefaultConfig())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, problems)
+			}
+			for i, expected := range tc.expected {
+				if got := fmt.Sprintf("%d: %s", problems[i].Position.Line, problems[i].Message); got != expected {
+					t.Errorf("Expected %q, got %q", expected, got)
+				}
+			}
+		})
+	}
+}
+
+// TestComparisonWithItselfFolding checks that comparisons made constant by
+// operands that cancel out are reported, unlike comparisons of literals
+func TestComparisonWithItselfFolding(t *testing.T) {
+	code := `
+func foo(x):
+	if 1 + 1 == 2:
+		pass
+	if x - x == 0:
+		pass
+	if self.hp == self.hp:
+		pass
+	if x - 1 == 0:
+		pass
+`
+	l := linter.NewLinter([]linter.Rule{rules.GetRuleByName("comparison-with-itself")}, linter.DefaultConfig())
+	problems, err := l.Lint(code)
+	if err != nil {
+		t.Fatalf("Linting failed: %v", err)
+	}
+	if len(problems) != 2 || problems[0].Position.Line != 5 || problems[0].Message != "Comparison is always true" ||
+		problems[1].Position.Line != 7 {
+		t.Errorf("Expected problems on lines 5 and 7, got %v", problems)
+	}
+}
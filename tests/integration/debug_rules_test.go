@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestDebugStatement checks the breakpoint and assert statements reported
+func TestDebugStatement(t *testing.T) {
+	code := `func foo(x):
+	breakpoint
+	assert(x > 0)
+	if OS.is_debug_build():
+		breakpoint
+		assert(x < 10)
+	elif x:
+		breakpoint
+	else:
+		assert(x)
+`
+	testCases := []struct {
+		name     string
+		settings map[string]any
+		expected []int // the lines reported
+	}{
+		{name: "defaults", expected: []int{2, 3, 8, 10}},
+		{name: "asserts allowed", settings: map[string]any{"assert": false}, expected: []int{2, 8}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := linter.DefaultConfig()
+			if tc.settings != nil {
+				config.RuleSettings = map[string]any{"debug-statement": tc.settings}
+			}
+			l := linter.NewLinter(rules.GetDefaultDebugRules(), config)
+
+			problems, err := l.Lint(code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			var lines []int
+			for _, p := range problems {
+				lines = append(lines, p.Position.Line)
+			}
+			if len(lines) != len(tc.expected) {
+				t.Fatalf("Expected problems on lines %v, got %v", tc.expected, problems)
+			}
+			for i := range lines {
+				if lines[i] != tc.expected[i] {
+					t.Errorf("Expected problems on lines %v, got %v", tc.expected, problems)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestDesignRulesCountNestedBlocks checks that the per-function design rules
+// count what is declared in nested blocks, not only at the top of the body
+func TestDesignRulesCountNestedBlocks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     string
+		code     string
+		expected int // Expected number of problems
+	}{
+		{
+			name: "locals in nested blocks",
+			rule: "max-locals",
+			code: `
+func foo(items):
+	var a = 1
+	for item in items:
+		var b = item
+		if b:
+			var c = b
+`,
+			expected: 1,
+		},
+		{
+			name: "constants are not locals",
+			rule: "max-locals",
+			code: `
+func foo():
+	const A = 1
+	const B = 2
+	var c = A + B
+`,
+			expected: 0,
+		},
+		{
+			name: "nested branches",
+			rule: "max-branches",
+			code: `
+func foo(x):
+	if x:
+		while x:
+			x -= 1
+	else:
+		match x:
+			1:
+				pass
+`,
+			expected: 1,
+		},
+		{
+			name: "branches at the limit",
+			rule: "max-branches",
+			code: `
+func foo(x):
+	if x:
+		pass
+	else:
+		pass
+	for i in x:
+		pass
+`,
+			expected: 0,
+		},
+		{
+			name: "statements in nested blocks",
+			rule: "max-statements",
+			code: `
+func foo(x):
+	if x:
+		print(x)
+		while x:
+			x -= 1
+`,
+			expected: 1,
+		},
+		{
+			name: "statements at the limit",
+			rule: "max-statements",
+			code: `
+func foo(x):
+	print(x)
+	print(x)
+	return x
+func bar():
+	pass
+`,
+			expected: 0,
+		},
+		{
+			name: "returns in nested blocks",
+			rule: "max-returns",
+			code: `
+func foo(x):
+	if x:
+		return 1
+	for i in x:
+		if i:
+			return i
+	return 0
+`,
+			expected: 1,
+		},
+		{
+			name: "returns at the limit",
+			rule: "max-returns",
+			code: `
+func foo(x):
+	if x:
+		return 1
+	return 0
+`,
+			expected: 0,
+		},
+	}
+
+	config := linter.DefaultConfig()
+	config.RuleSettings = map[string]any{
+		"max-locals":     map[string]any{"threshold": 2},
+		"max-branches":   map[string]any{"threshold": 3},
+		"max-statements": map[string]any{"threshold": 3},
+		"max-returns":    map[string]any{"threshold": 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := linter.NewLinter([]linter.Rule{rules.GetRuleByName(tc.rule)}, config)
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			if len(problems) != tc.expected {
+				t.Errorf("Expected %d problems, got %d", tc.expected, len(problems))
+				for i, problem := range problems {
+					t.Logf("Problem %d: %s", i, problem.String())
+				}
+			}
+			for _, problem := range problems {
+				if problem.RuleName != tc.rule {
+					t.Errorf("Expected rule %s, got %s", tc.rule, problem.RuleName)
+				}
+			}
+		})
+	}
+}
+
+// TestMagicNumber checks which numbers of function bodies are reported
+func TestMagicNumber(t *testing.T) {
+	code := `const SPEED = 300
+enum State {IDLE = 5}
+
+
+func move(delta, scale = 2.5):
+	const MAX = 10
+	var distance = SPEED * delta * 60
+	var back = -1
+	var offset = -8
+	var half = size / 2.0
+	return distance + 0 + 1.0 + offset
+`
+	tests := []struct {
+		name     string
+		rc       string
+		expected []string // the numbers reported, in source order
+	}{
+		{"defaults", `{}`, []string{"60", "-8", "2.0"}},
+		{"allow_list", `{"rule_settings": {"magic-number": {"allowed": [2, 60]}}}`, []string{"-8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config linter.Config
+			if err := json.Unmarshal([]byte(tt.rc), &config); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := linter.NewLinter([]linter.Rule{&rules.MagicNumber{}}, config).Lint(code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tt.expected) {
+				t.Fatalf("Expected magic numbers %v, got %v", tt.expected, problems)
+			}
+			for i, number := range tt.expected {
+				if !strings.Contains(problems[i].Message, "Magic number "+number+" ") {
+					t.Errorf("Expected magic number %s, got %q", number, problems[i].Message)
+				}
+			}
+		})
+	}
+}
+
+// TestMaxNestingDepth checks that the outermost blocks nested deeper than
+// the threshold are reported, once each
+func TestMaxNestingDepth(t *testing.T) {
+	code := `func foo(items):
+	for item in items:
+		if item:
+			while item:
+				match item:
+					1:
+						if true:
+							pass
+					2:
+						for i in item:
+							pass
+				item -= 1
+		elif items:
+			if item:
+				pass
+func bar(x):
+	if x:
+		if x:
+			if x:
+				pass
+`
+	tests := []struct {
+		name     string
+		rc       string
+		expected []string // the line:column of the problems
+	}{
+		{"default", `{}`, []string{"7:7", "10:7"}},
+		{"threshold", `{"rule_settings": {"max-nesting-depth": {"threshold": 2}}}`, []string{"4:4", "14:4", "19:4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config linter.Config
+			if err := json.Unmarshal([]byte(tt.rc), &config); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := linter.NewLinter([]linter.Rule{&rules.MaxNestingDepth{}}, config).Lint(code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			var found []string
+			for _, p := range problems {
+				found = append(found, fmt.Sprintf("%d:%d", p.Position.Line, p.Position.Column))
+			}
+			if strings.Join(found, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected problems at %v, got %v", tt.expected, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestEnumRules checks the rules on the values of enums
+func TestEnumRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // "line: message" of the expected problems
+	}{
+		{
+			name: "duplicate values",
+			code: `
+enum State {IDLE, RUNNING, STOPPED = 1, PAUSED = 1 + 1}
+enum {A = 4, B = 2, C, D}
+enum Flags {ONE = 1, TWO = 2, FOUR = 4}
+`,
+			expected: []string{
+				"2: Enum element \"STOPPED\" has the value 1 of \"RUNNING\"",
+				"3: Enum element \"B\" has the value 2, lower than the value 4 of \"A\" before it",
+				"3: Enum element \"D\" has the value 4 of \"A\"",
+			},
+		},
+		{
+			name: "written values going back",
+			code: `
+enum State {IDLE, RUNNING, STOPPED = 0, PAUSED}
+enum {A = 10, B, C = 5}
+enum Order {HIGH = 3, LOW = 1}
+enum Unknown {X = SIZE, Y, Z = 0}
+`,
+			expected: []string{
+				"2: Enum element \"STOPPED\" has the value 0 of \"IDLE\"",
+				"2: Enum element \"STOPPED\" has the value 0, lower than the value 1 of \"RUNNING\" before it",
+				"2: Enum element \"PAUSED\" has the value 1 of \"RUNNING\"",
+				"3: Enum element \"C\" has the value 5, lower than the value 11 of \"B\" before it",
+			},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultEnumRules(), linter.DefaultConfig())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, problems)
+			}
+			for i, expected := range tc.expected {
+				if got := fmt.Sprintf("%d: %s", problems[i].Position.Line, problems[i].Message); got != expected {
+					t.Errorf("Expected %q, got %q", expected, got)
+				}
+			}
+		})
+	}
+}
+
+// TestEnumNames checks that the names of enums, named or not, and of their
+// elements are checked
+func TestEnumNames(t *testing.T) {
+	code := `
+enum some_enum {A, b}
+enum {C, some_d}
+
+class Inner:
+	enum Mode {on}
+`
+	l := linter.NewLinter([]linter.Rule{rules.GetRuleByName("enum-name"), rules.GetRuleByName("enum-element-name")}, linter.DefaultConfig())
+	problems, err := l.Lint(code)
+	if err != nil {
+		t.Fatalf("Linting failed: %v", err)
+	}
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d: %s", p.Position.Line, p.Message))
+	}
+	expected := []string{
+		"2: Enum name \"some_enum\" is not valid",
+		"2: Enum element name \"b\" is not valid",
+		"3: Enum element name \"some_d\" is not valid",
+		"6: Enum element name \"on\" is not valid",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestFlowRules checks the rules built on the control flow of functions
+func TestFlowRules(t *testing.T) {
+	testCases := []struct {
+		name  string
+		code  string
+		lines []int // lines of the expected problems
+	}{
+		{
+			name: "bare return ends a void function",
+			code: `
+func foo(x):
+	print(x)
+	return
+`,
+			lines: []int{4},
+		},
+		{
+			name: "returns that are needed",
+			code: `
+func stub():
+	return
+func value(x) -> int:
+	return x
+func typed(x) -> void:
+	print(x)
+	return x
+`,
+			lines: []int{},
+		},
+		{
+			name: "statements after return",
+			code: `
+func foo(x):
+	return x
+	print(x)
+	print(x)
+`,
+			lines: []int{4},
+		},
+		{
+			name: "statements after break and continue in loops",
+			code: `
+func foo(items):
+	for item in items:
+		continue
+		print(item)
+	while items:
+		break
+		items.pop_back()
+`,
+			lines: []int{5, 8},
+		},
+		{
+			name: "statement after an if whose branches all return",
+			code: `
+func foo(x):
+	if x:
+		return 1
+	else:
+		return 2
+	print(x)
+`,
+			lines: []int{7},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultFlowRules(), linter.DefaultConfig())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tc.lines) {
+				t.Fatalf("Expected problems on lines %v, got %v", tc.lines, problems)
+			}
+			for i, line := range tc.lines {
+				if problems[i].Position.Line != line {
+					t.Errorf("Expected a problem on line %d, got %v", line, problems[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestMaxFunctionLines checks that functions are measured from their header
+// to their last line, blank and comment lines aside
+func TestMaxFunctionLines(t *testing.T) {
+	code := `func short():
+	var x = 1
+
+	# Comments and blank lines are not counted
+
+	return x
+func long(items):
+	for item in items:
+		print(item,
+			"continued")  # the comment does not hide the code
+
+	var text = """first
+second"""
+	return text
+# The comment after the body is not part of the function
+func single(): pass
+`
+	tests := []struct {
+		name     string
+		rc       string
+		expected []string // the functions reported
+	}{
+		{"default", `{}`, nil},
+		{"threshold", `{"rule_settings": {"max-function-lines": {"threshold": 3}}}`, []string{"long"}},
+		{"exact", `{"rule_settings": {"max-function-lines": {"threshold": 7}}}`, nil},
+		{"everything", `{"rule_settings": {"max-function-lines": {"threshold": 0}}}`, []string{"short", "long", "single"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config linter.Config
+			if err := json.Unmarshal([]byte(tt.rc), &config); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := linter.NewLinter([]linter.Rule{&rules.MaxFunctionLines{}}, config).Lint(code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tt.expected) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.expected), problems)
+			}
+			for i, p := range problems {
+				if !strings.Contains(p.Message, `"`+tt.expected[i]+`"`) {
+					t.Errorf("Expected a problem with %q, got %q", tt.expected[i], p.Message)
+				}
+			}
+		})
+	}
+}
+
+// TestMaxLineLength checks that lines are measured in characters rather
+// than bytes, with tabs as wide as tab-characters
+func TestMaxLineLength(t *testing.T) {
+	code := "var a = \"éé\"\nvar bb = \"éé\"\nfunc f():\n\tvar c = 1\n"
+	tests := []struct {
+		name     string
+		rc       string
+		expected []int // the lines reported
+	}{
+		{"default", `{}`, nil},
+		{"threshold", `{"rule_settings": {"max-line-length": {"threshold": 12}}}`, []int{2, 4}},
+		{"tab characters", `{"rule_settings": {"max-line-length": {"threshold": 12}, "tab-characters": {"value": 1}}}`, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config linter.Config
+			if err := json.Unmarshal([]byte(tt.rc), &config); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := linter.NewLinter([]linter.Rule{&rules.MaxLineLength{}}, config).LintSource("test.gd", code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			var lines []int
+			for _, p := range problems {
+				lines = append(lines, p.Position.Line)
+			}
+			if fmt.Sprint(lines) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected lines %v to be reported, got %v", tt.expected, problems)
+			}
+		})
+	}
+}
+
+// TestMaxFileLines checks that a line ending the file does not start
+// another line
+func TestMaxFileLines(t *testing.T) {
+	config := linter.DefaultConfig()
+	config.RuleSettings = map[string]any{"max-file-lines": map[string]any{"threshold": 2}}
+	l := linter.NewLinter([]linter.Rule{&rules.MaxFileLines{}}, config)
+	for code, expected := range map[string]string{
+		"var a\nvar b\n":      "[]",
+		"var a\r\nvar b\r\n":  "[]",
+		"var a\nvar b\nvar c": "[3]",
+		"var a\n\nvar b\n\n":  "[4]",
+	} {
+		problems, err := l.LintSource("test.gd", code)
+		if err != nil {
+			t.Fatalf("Linting failed: %v", err)
+		}
+		var lines []int
+		for _, p := range problems {
+			lines = append(lines, p.Position.Line)
+		}
+		if got := fmt.Sprint(lines); got != expected {
+			t.Errorf("Expected lines %s of %q to be reported, got %v", expected, code, problems)
+		}
+	}
+}
+
+// TestWhitespaceRules checks where trailing whitespace and indentation
+// mixing tabs and spaces are reported, and that the fixes delete the
+// trailing whitespace
+func TestWhitespaceRules(t *testing.T) {
+	code := "func foo(): \r\n\t  var a = \"é\"\t\r\n  \tpass\r\n\t\r\n"
+	problems, err := linter.NewLinter([]linter.Rule{&rules.TrailingWhitespace{}, &rules.MixedTabsAndSpaces{}}, linter.DefaultConfig()).LintSource("test.gd", code)
+	if err != nil {
+		t.Fatalf("Linting failed: %v", err)
+	}
+	var found []string
+	for _, p := range problems {
+		found = append(found, fmt.Sprintf("%s %d:%d", p.RuleName, p.Position.Line, p.Position.Column))
+	}
+	expected := []string{
+		"trailing-whitespace 1:12",
+		"mixed-tabs-and-spaces 2:1",
+		"trailing-whitespace 2:15",
+		"mixed-tabs-and-spaces 3:1",
+		"trailing-whitespace 4:1",
+	}
+	if strings.Join(found, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, found)
+	}
+	fixed := "func foo():\r\n\t  var a = \"é\"\r\n  \tpass\r\n\r\n"
+	if got, _ := problem.ApplyFixes(code, problems); got != fixed {
+		t.Errorf("Expected the fixes to give %q, got %q", fixed, got)
+	}
+}
+
+// TestIndentationConsistency checks that the first character of each
+// indentation out of style is reported, and that the fixes reindent the lines
+func TestIndentationConsistency(t *testing.T) {
+	code := "func foo():\n\tif true:\n\t    print(1,\n\t\t\t2)\n    # comment\n\n  \n\tvar s = \"\"\"a\n  b\"\"\"\n"
+	tests := []struct {
+		name     string
+		rc       string
+		expected []string // the line:column of the problems
+		fixed    string
+	}{
+		{"tabs", `{}`, []string{"3:2", "5:1"},
+			"func foo():\n\tif true:\n\t\tprint(1,\n\t\t\t2)\n\t# comment\n\n  \n\tvar s = \"\"\"a\n  b\"\"\"\n"},
+		{"spaces", `{"rule_settings": {"indentation-consistency": {"style": "spaces:4"}}}`, []string{"2:1", "3:1", "4:1", "8:1"},
+			"func foo():\n    if true:\n        print(1,\n            2)\n    # comment\n\n  \n    var s = \"\"\"a\n  b\"\"\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config linter.Config
+			if err := json.Unmarshal([]byte(tt.rc), &config); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := linter.NewLinter([]linter.Rule{&rules.IndentationConsistency{}}, config).LintSource("test.gd", code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			var found []string
+			for _, p := range problems {
+				found = append(found, fmt.Sprintf("%d:%d", p.Position.Line, p.Position.Column))
+			}
+			if strings.Join(found, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected problems at %v, got %v", tt.expected, problems)
+			}
+			if fixed, _ := problem.ApplyFixes(code, problems); fixed != tt.fixed {
+				t.Errorf("Expected the fixes to give %q, got %q", tt.fixed, fixed)
+			}
+		})
+	}
+}
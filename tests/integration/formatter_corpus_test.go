@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/testutil"
+)
+
+// TestFormatterCorpus formats every input of the formatter corpus, compares it
+// with the expected output and checks that formatting the output changes nothing
+func TestFormatterCorpus(t *testing.T) {
+	cases, err := testutil.LoadFormatterCorpus(testutil.FormatterCorpusDir)
+	if err != nil {
+		t.Fatalf("Failed to load formatter corpus: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("Formatter corpus is empty")
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			result := formatCorpusScript(t, tc.Input)
+			if !utf8.ValidString(result) {
+				t.Fatalf("Formatted output is not valid UTF-8:\n%q", result)
+			}
+			if result != tc.Expected {
+				t.Errorf("Formatting mismatch:\nExpected:\n%s\nActual:\n%s", tc.Expected, result)
+			}
+
+			if again := formatCorpusScript(t, tc.Expected); again != tc.Expected {
+				t.Errorf("Formatting is not idempotent:\nFirst:\n%s\nSecond:\n%s", tc.Expected, again)
+			}
+		})
+	}
+}
+
+// formatCorpusScript formats code the way gdformat writes it, with a final newline
+func formatCorpusScript(t *testing.T, code string) string {
+	t.Helper()
+
+	tree, errors := parser.ParseFile("test.gd", code)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	result, err := formatter.FormatCode(tree, formatter.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Format error: %v", err)
+	}
+	if !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return result
+}
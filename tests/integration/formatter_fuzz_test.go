@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/testutil"
+)
+
+// FuzzFormatterIdempotency formats arbitrary scripts that parse cleanly and
+// checks that the output parses again and that formatting it changes nothing.
+// The formatter corpus seeds it; run it with
+//
+//	go test ./tests/integration -run '^$' -fuzz FuzzFormatterIdempotency
+func FuzzFormatterIdempotency(f *testing.F) {
+	cases, err := testutil.LoadFormatterCorpus(testutil.FormatterCorpusDir)
+	if err != nil {
+		f.Fatalf("Failed to load formatter corpus: %v", err)
+	}
+	for _, tc := range cases {
+		f.Add(tc.Input)
+	}
+	for _, seed := range []string{
+		"var a = {\"b\": 1, \"c\": [2, 3], 4: null}\n",
+		"func foo(a, b = 1):\n\treturn a if b else -a * (b + 1)\n",
+		"class X:\n\tvar y = 'z'\n\n\tfunc f():\n\t\tfor i in y:\n\t\t\tprint(i)\n",
+		"func foo():\n\tvar x = 1 + \\\n\t\t2\n\tmatch x:\n\t\t1, 2:\n\t\t\tpass\n",
+		"# comment\nextends Node # inline\nvar v := 0x1F + 0b10\n",
+		"\"",
+		"func a():\n\t\t#\n\tpass\nfunc b():\n\tpass\n",
+		"const A = ",
+		"func a():\n ",
+		"@a()0#0\n",
+		"func a(): b\n(c)\n #d\n",
+		"func a():\n#gdformat:on\n 0\n#gdformat:off\n0\n",
+		"func a():\n#gdformat:off\n\t[]\n#gdformat:on\n\t0\n#gdformat:off\n0\n",
+		"func a():\n#gdformat:off\n\t[]\n#gdformat:on\n\n\t0\n",
+		"func a():\n#gdformat:off\n [\n#gdformat:on\n]\n",
+		"func a():\n 0\n#gdformat:off\n 0\n",
+		"func a():\n#gdformat:off\n\t[]\n#gdformat:on\n#gdformat:off\n\n\t0\n",
+		"func a():0\n#gdformat:off\n\r0\n",
+		"var\n#gdformat:off\na: b 0\n",
+		"0\n#gdformat:off\nfunc A(): \n 0",
+		"(#\n\"\\\n\")",
+		"func A():\n#gdformat:off\n\t[\r\r\n]",
+		"#\n#gdformat:off\nfunc A(): \n []",
+		"#gdformat:off\nfunc A(): \n []\n#gdformat:on\n0",
+		"#gdformat:off\n0func A(): \n []\n#gdformat:on",
+		"match 0:\n ;",
+		"#gdformat:off\nfunc A():\n []\n #gdformat:on",
+		"var\n#gdformat:off\nA)0",
+		"var\n#gdformat:off\nA:A;0",
+		"func A():\n#gdformat:off\n\t0\n#gdformat:on\nfunc A():00A",
+		"func A(): \n [] \n #gdformat:off\nfunc A(): \n 0",
+		"@A@tool A extends A",
+		"extends A@A@tool A",
+		"#gdformat:off\nfunc A():#0\n\tva#0\n#gdformat:on\n #",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tree, errors := parser.ParseFile("fuzz.gd", input)
+		if len(errors) > 0 {
+			return
+		}
+		first, err := formatter.FormatCode(tree, formatter.DefaultConfig())
+		if err != nil {
+			t.Fatalf("Format error: %v", err)
+		}
+
+		formatted, errors := parser.ParseFile("fuzz.gd", first)
+		if len(errors) > 0 {
+			t.Fatalf("Formatted output does not parse: %v\nInput:\n%q\nOutput:\n%q", errors, input, first)
+		}
+		second, err := formatter.FormatCode(formatted, formatter.DefaultConfig())
+		if err != nil {
+			t.Fatalf("Format error on formatted output: %v", err)
+		}
+		if second != first {
+			t.Fatalf("Formatting is not idempotent\nInput:\n%q\nFirst:\n%q\nSecond:\n%q", input, first, second)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package integration
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/testutil"
+)
+
+// compatReport is the file the Markdown compatibility matrix is written to, see
+// `make compat-report`
+var compatReport = flag.String("compat-report", "", "Write the Python formatter compatibility matrix to this Markdown file")
+
+// updateSkipList rewrites the skip list with the fixtures that fail, see
+// `make python-skip-list`
+var updateSkipList = flag.Bool("update-skip-list", false, "Rewrite the Python formatter skip list with the fixtures that fail")
+
+// pythonSkipList lists the Python fixtures the Go formatter does not reproduce yet
+const pythonSkipList = "../../testdata/formatter_python/skip.txt"
+
+// pythonFixturesRef pins the commit of Python gdtoolkit the skip list is kept for
+const pythonFixturesRef = "../../testdata/formatter_python/ref"
+
+// pythonCheckout is where scripts/fetch-python-fixtures.sh checks Python
+// gdtoolkit out
+const pythonCheckout = "../../gdtoolkit"
+
+// pythonCaseResult is the outcome of formatting one Python fixture
+type pythonCaseResult struct {
+	name    string
+	passed  bool
+	skipped bool
+	detail  string
+}
+
+// TestFormatterPythonCorpus formats every input-output pair of the Python
+// gdformat test suite and reports how many the Go formatter reproduces. Cases
+// listed in the skip list may fail without failing the test; once one starts
+// passing it must be removed from the list.
+//
+// The fixtures are fetched by scripts/fetch-python-fixtures.sh. Without them
+// the test is skipped, except in CI where it fails.
+func TestFormatterPythonCorpus(t *testing.T) {
+	dir := testutil.GetTestFixtures().FormatterPairs
+	if _, err := os.Stat(dir); err != nil {
+		if *compatReport != "" || *updateSkipList || os.Getenv("CI") != "" {
+			t.Fatalf("Python formatter fixtures not available, run scripts/fetch-python-fixtures.sh: %v", err)
+		}
+		t.Skipf("Python formatter fixtures not available, run scripts/fetch-python-fixtures.sh: %v", err)
+	}
+
+	if err := checkPythonFixturesRef(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases, err := testutil.LoadFormatterCorpus(dir)
+	if err != nil {
+		t.Fatalf("Failed to load Python formatter fixtures: %v", err)
+	}
+	skips, err := testutil.LoadKnownGaps(pythonSkipList)
+	if err != nil {
+		t.Fatalf("Failed to load skip list: %v", err)
+	}
+
+	var results []pythonCaseResult
+	passed := 0
+	for _, tc := range cases {
+		err := checkPythonCase(tc)
+		result := pythonCaseResult{name: tc.Name, passed: err == nil, skipped: skips[tc.Name]}
+		if err != nil {
+			result.detail = err.Error()
+		} else {
+			passed++
+		}
+		results = append(results, result)
+
+		if *updateSkipList {
+			continue
+		}
+		t.Run(tc.Name, func(t *testing.T) {
+			switch {
+			case err == nil && skips[tc.Name]:
+				t.Errorf("Case now passes, remove it from %s", filepath.Base(pythonSkipList))
+			case err != nil && skips[tc.Name]:
+				t.Skipf("Skipped: %v", err)
+			case err != nil:
+				t.Error(err)
+			}
+		})
+	}
+
+	for name := range skips {
+		if !*updateSkipList && !hasFormatterCase(cases, name) {
+			t.Errorf("%s lists unknown case %s", filepath.Base(pythonSkipList), name)
+		}
+	}
+
+	if len(cases) > 0 {
+		t.Logf("Formatter compatibility: %d/%d (%.1f%%) Python fixtures reproduced",
+			passed, len(cases), float64(passed)/float64(len(cases))*100)
+	}
+
+	if *updateSkipList {
+		if err := writeSkipList(pythonSkipList, results); err != nil {
+			t.Fatalf("Failed to write skip list: %v", err)
+		}
+	}
+	if *compatReport != "" {
+		if err := os.WriteFile(*compatReport, []byte(compatMatrix(results, passed)), 0644); err != nil {
+			t.Fatalf("Failed to write compatibility report: %v", err)
+		}
+	}
+}
+
+// checkPythonFixturesRef checks that the fixtures checked out are those of the
+// commit the skip list is kept for. Fixtures not checked out by
+// scripts/fetch-python-fixtures.sh are not checked.
+func checkPythonFixturesRef() error {
+	head, err := os.ReadFile(filepath.Join(pythonCheckout, ".git", "HEAD"))
+	if err != nil {
+		return nil
+	}
+	content, err := os.ReadFile(pythonFixturesRef)
+	if err != nil {
+		return fmt.Errorf("failed to read the pinned commit: %v", err)
+	}
+	pinned := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			pinned = strings.TrimSpace(line)
+			break
+		}
+	}
+	if commit := strings.TrimSpace(string(head)); commit != pinned {
+		return fmt.Errorf("fixtures checked out at %s, but %s pins %q, run scripts/fetch-python-fixtures.sh",
+			commit, filepath.Base(pythonFixturesRef), pinned)
+	}
+	return nil
+}
+
+// writeSkipList rewrites the skip list at path with the fixtures that fail,
+// keeping the comments at its top
+func writeSkipList(path string, results []pythonCaseResult) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		b.WriteString(line)
+	}
+	for _, result := range results {
+		if !result.passed {
+			b.WriteString(result.name + "\n")
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// checkPythonCase formats a Python fixture and compares the result with the
+// output of Python gdformat, then checks that formatting it changes nothing
+func checkPythonCase(tc testutil.FormatterCase) error {
+	result, err := formatPythonScript(tc.Input)
+	if err != nil {
+		return err
+	}
+	if result != tc.Expected {
+		return fmt.Errorf("output differs from Python gdformat %s", firstDifference(tc.Expected, result))
+	}
+	again, err := formatPythonScript(tc.Expected)
+	if err != nil {
+		return fmt.Errorf("expected output: %v", err)
+	}
+	if again != tc.Expected {
+		return fmt.Errorf("expected output is reformatted %s", firstDifference(tc.Expected, again))
+	}
+	return nil
+}
+
+// formatPythonScript formats code the way gdformat writes it, with a final newline
+func formatPythonScript(code string) (string, error) {
+	tree, errors := parser.ParseFile("test.gd", code)
+	if len(errors) > 0 {
+		return "", fmt.Errorf("parse error: %v", errors[0])
+	}
+	result, err := formatter.FormatCode(tree, formatter.DefaultConfig())
+	if err != nil {
+		return "", fmt.Errorf("format error: %v", err)
+	}
+	if !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// firstDifference describes the first line where actual differs from expected
+func firstDifference(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var want, got string
+		if i < len(expectedLines) {
+			want = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if want != got {
+			return fmt.Sprintf("at line %d: expected %q, got %q", i+1, want, got)
+		}
+	}
+	return ""
+}
+
+// compatMatrix renders the results as a Markdown table, one row per fixture
+func compatMatrix(results []pythonCaseResult, passed int) string {
+	var b strings.Builder
+	b.WriteString("# Formatter compatibility with Python gdformat\n\n")
+	if len(results) > 0 {
+		fmt.Fprintf(&b, "%d/%d (%.1f%%) fixtures reproduced.\n\n",
+			passed, len(results), float64(passed)/float64(len(results))*100)
+	}
+	b.WriteString("| Fixture | Status | Detail |\n")
+	b.WriteString("|---|---|---|\n")
+	for _, result := range results {
+		status := "✅ pass"
+		switch {
+		case !result.passed && result.skipped:
+			status = "⏭️ skipped"
+		case !result.passed:
+			status = "❌ fail"
+		}
+		detail := strings.ReplaceAll(result.detail, "|", "\\|")
+		detail = strings.ReplaceAll(detail, "\n", " ")
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", result.name, status, detail)
+	}
+	return b.String()
+}
+
+func hasFormatterCase(cases []testutil.FormatterCase, name string) bool {
+	for _, tc := range cases {
+		if tc.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestIfReturnRules checks the rules reporting elif and else branches after
+// branches that always return, break or continue
+func TestIfReturnRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // "line:column rule" of the expected problems
+	}{
+		{
+			name: "elif and else after return",
+			code: `
+func foo(x):
+	if x > 1:
+		print(x)
+		return 1
+	elif x > 0:
+		return 2
+	else:
+		return 3
+`,
+			expected: []string{"6:9 no-elif-return", "8:2 no-else-return"},
+		},
+		{
+			name: "else after an if whose branches all return",
+			code: `
+func foo(x):
+	if x:
+		if x > 1:
+			return 1
+		else:
+			return 2
+	else:
+		return 3
+`,
+			expected: []string{"6:3 no-else-return", "8:2 no-else-return"},
+		},
+		{
+			name: "branches that fall through",
+			code: `
+func foo(x):
+	if x:
+		if x > 1:
+			return 1
+	elif x < 0:
+		return 2
+	else:
+		pass
+`,
+			expected: []string{},
+		},
+		{
+			name: "elif and else after break and continue",
+			code: `
+func foo(items):
+	for item in items:
+		if item == 0:
+			continue
+		elif item < 0:
+			break
+		else:
+			print(item)
+	while items:
+		if items[0]:
+			break
+		else:
+			items.pop_front()
+`,
+			expected: []string{"6:13 no-else-continue", "13:3 no-else-break"},
+		},
+		{
+			name: "break of a nested loop does not leave the branch",
+			code: `
+func foo(items):
+	for item in items:
+		if item:
+			for other in items:
+				break
+		else:
+			print(item)
+`,
+			expected: []string{},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultIfReturnRules(), linter.DefaultConfig())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, problems)
+			}
+			for i, expected := range tc.expected {
+				p := problems[i]
+				if got := fmt.Sprintf("%d:%d %s", p.Position.Line, p.Position.Column, p.RuleName); got != expected {
+					t.Errorf("Expected %q, got %q (%s)", expected, got, p.Message)
+				}
+			}
+		})
+	}
+}
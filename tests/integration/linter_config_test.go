@@ -0,0 +1,346 @@
+package integration
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+func TestStrictDirectories(t *testing.T) {
+	dir := t.TempDir()
+	rc := `{
+	"strict": [
+		{"path": "src/core/**", "rules": ["unused-argument"]},
+		{"path": "addons/*/api"}
+	]
+}`
+	if err := os.WriteFile(filepath.Join(dir, "gdlintrc.json"), []byte(rc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := filepath.Join(dir, "src", "core", "combat")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := linter.FindConfigFileFrom(nested)
+	if configPath != filepath.Join(dir, "gdlintrc.json") {
+		t.Fatalf("Expected config from %s, found %q", dir, configPath)
+	}
+	config, err := linter.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	t.Run("lint", func(t *testing.T) {
+		code := "func foo(unused):\n\tpass\n"
+		lint := linter.NewLinter(rules.GetDefaultRules(), config)
+
+		expected := map[string]problem.Severity{
+			filepath.Join(dir, "src", "core", "combat", "hit.gd"): problem.Error,
+			filepath.Join(dir, "src", "ui", "menu.gd"):            problem.Warning,
+		}
+		for path, severity := range expected {
+			problems, err := lint.LintSource(path, code)
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+			if len(problems) != 1 || problems[0].RuleName != "unused-argument" {
+				t.Fatalf("%s: expected one unused-argument problem, got %v", path, problems)
+			}
+			if problems[0].Severity != severity {
+				t.Errorf("%s: expected severity %s, got %s", path, severity, problems[0].Severity)
+			}
+		}
+	})
+
+	t.Run("resolution", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			rule     string
+			expected problem.Severity
+		}{
+			{"src/core/player.gd", "unused-argument", problem.Error},
+			{"src/core/player.gd", "unnecessary-pass", problem.Warning},
+			{"src/core_utils/player.gd", "unused-argument", problem.Warning},
+			{"addons/inventory/api/items.gd", "unnecessary-pass", problem.Error},
+			{"addons/inventory/api/v2/items.gd", "max-returns", problem.Error},
+			{"addons/inventory/ui/items.gd", "unnecessary-pass", problem.Warning},
+			{"", "unused-argument", problem.Warning},
+		}
+		for _, tt := range tests {
+			path := tt.path
+			if path != "" {
+				path = filepath.Join(dir, filepath.FromSlash(path))
+			}
+			p := problem.NewWarning(ast.Position{Line: 1, Column: 1}, "", tt.rule)
+			if got := config.Severity(path, p); got != tt.expected {
+				t.Errorf("%s (%s): expected %s, got %s", tt.path, tt.rule, tt.expected, got)
+			}
+		}
+	})
+}
+
+func TestRuleRegistry(t *testing.T) {
+	registry := rules.NewRegistry()
+
+	// Every rule of every category is registered once, under its own name
+	seen := make(map[string]bool)
+	for _, info := range registry.Rules() {
+		name := info.Rule.Name()
+		if name == "" || info.Rule.Description() == "" || info.Category == "" {
+			t.Errorf("Rule %q lacks a name, description or category", name)
+		}
+		if seen[name] {
+			t.Errorf("Rule %q is registered twice", name)
+		}
+		seen[name] = true
+		if found, ok := registry.Lookup(name); !ok || found.Rule != info.Rule {
+			t.Errorf("Lookup(%q) does not return the registered rule", name)
+		}
+	}
+	for _, rule := range rules.GetDefaultScopeRules() {
+		if info, ok := registry.Lookup(rule.Name()); !ok || info.Default || info.Category != "scope" {
+			t.Errorf("Expected %s to be registered as a scope rule disabled by default, got %+v", rule.Name(), info)
+		}
+	}
+	// The rules ported from Python gdlint are on by default, as they are there,
+	// even in categories that are not
+	for _, name := range []string{"function-name", "max-returns", "trailing-whitespace", "no-else-return"} {
+		if info, ok := registry.Lookup(name); !ok || !info.Default || !rules.PortedFromPython(name) {
+			t.Errorf("Expected %s to be enabled by default, got %+v", name, info)
+		}
+	}
+	if info, ok := registry.Lookup("magic-number"); !ok || info.Default {
+		t.Errorf("Expected magic-number, in the same category as max-returns, to be disabled by default, got %+v", info)
+	}
+	if len(rules.GetAllRules()) != len(registry.Rules()) {
+		t.Errorf("Expected GetAllRules to return the %d registered rules, got %d", len(registry.Rules()), len(rules.GetAllRules()))
+	}
+
+	// enabled_rules turns on rules that are off by default, and
+	// disabled_rules wins over it
+	code := "signal hit\nfunc foo(unused):\n\tpass\n"
+	tests := []struct {
+		name     string
+		enabled  []string
+		disabled []string
+		expected []string
+	}{
+		{"defaults", nil, nil, []string{"unused-argument"}},
+		{"enabled", []string{"unused-signal"}, nil, []string{"unused-signal", "unused-argument"}},
+		{"enabled and disabled", []string{"unused-signal"}, []string{"unused-signal", "unused-argument"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := linter.DefaultConfig()
+			config.EnabledRules = tt.enabled
+			config.DisabledRules = tt.disabled
+			problems, err := linter.NewLinterForConfig(registry, config).Lint(code)
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+			var names []string
+			for _, p := range problems {
+				names = append(names, p.RuleName)
+			}
+			if strings.Join(names, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected problems of %v, got %v", tt.expected, problems)
+			}
+		})
+	}
+}
+
+func TestGodotAPIConfig(t *testing.T) {
+	dir := t.TempDir()
+	dump := `{"header": {"version_major": 4, "version_minor": 3}, "classes": [
+	{"name": "Object"},
+	{"name": "Node", "inherits": "Object"},
+	{"name": "CustomEngineNode", "inherits": "Node"}
+]}`
+	if err := os.WriteFile(filepath.Join(dir, "extension_api.json"), []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rc := `{"godot_api": "extension_api.json"}`
+	if err := os.WriteFile(filepath.Join(dir, "gdlintrc.json"), []byte(rc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := linter.LoadConfig(filepath.Join(dir, "gdlintrc.json"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !config.API().HasClass("CustomEngineNode") || config.API().HasClass("Node2D") {
+		t.Error("Expected the API dump configured with godot_api")
+	}
+
+	if !linter.DefaultConfig().API().HasClass("Node2D") {
+		t.Error("Expected the default config to use the embedded API")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "gdlintrc.json"), []byte(`{"godot_api": "missing.json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linter.LoadConfig(filepath.Join(dir, "gdlintrc.json")); err == nil {
+		t.Error("Expected an error for a missing API dump")
+	}
+}
+
+func TestSeverityOverrides(t *testing.T) {
+	dir := t.TempDir()
+	rc := `{
+	"severity": {"unused-argument": "error", "sub-class-before-parent-class": "warning", "unnecessary-pass": "info"},
+	"strict": [{"path": "src", "rules": ["unnecessary-pass"]}]
+}`
+	if err := os.WriteFile(filepath.Join(dir, "gdlintrc.json"), []byte(rc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := linter.LoadConfig(filepath.Join(dir, "gdlintrc.json"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		problem  problem.Problem
+		expected problem.Severity
+	}{
+		{"main.gd", problem.NewWarning(ast.Position{}, "", "unused-argument"), problem.Error},
+		{"main.gd", problem.NewError(ast.Position{}, "", "sub-class-before-parent-class"), problem.Warning},
+		{"main.gd", problem.NewWarning(ast.Position{}, "", "unnecessary-pass"), problem.Info},
+		{"main.gd", problem.NewWarning(ast.Position{}, "", "max-returns"), problem.Warning},
+		// Strict directories still escalate overridden rules
+		{"src/main.gd", problem.NewWarning(ast.Position{}, "", "unnecessary-pass"), problem.Error},
+	}
+	for _, tt := range tests {
+		if got := config.Severity(filepath.Join(dir, tt.path), tt.problem); got != tt.expected {
+			t.Errorf("%s (%s): expected %s, got %s", tt.path, tt.problem.RuleName, tt.expected, got)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "gdlintrc.json"), []byte(`{"severity": {"max-returns": "fatal"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linter.LoadConfig(filepath.Join(dir, "gdlintrc.json")); err == nil {
+		t.Error("Expected an error for an invalid severity")
+	}
+}
+
+func TestExternalRules(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The external rules of this test are shell scripts")
+	}
+	dir := t.TempDir()
+	// The rule saves its request and reports a problem at the position of the
+	// first function
+	script := `#!/bin/sh
+cat > request.json
+echo '{"problems": [{"line": 3, "column": 1, "message": "banned call"}, {"line": 1, "message": "style", "severity": "info"}]}'
+`
+	files := map[string]string{
+		"rules/banned.sh": script,
+		"rules/broken.sh": "#!/bin/sh\necho 'not json'\n",
+		"gdlintrc.json": `{
+	"disabled_rules": ["unused-argument"],
+	"rule_settings": {"banned-calls": {"calls": ["OS.execute"]}},
+	"external_rules": [
+		{"name": "banned-calls", "command": ["./rules/banned.sh"]},
+		{"name": "broken", "command": ["rules/broken.sh"]}
+	]
+}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := linter.LoadConfig(filepath.Join(dir, "gdlintrc.json"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	lint := linter.NewLinter(append(rules.GetDefaultRules(), config.ExternalRules()...), config)
+	path := filepath.Join(dir, "player.gd")
+	problems, err := lint.LintSource(path, "extends Node\n\nfunc foo(a):\n\tprint(a)\n")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	if len(problems) != 3 {
+		t.Fatalf("Expected 2 problems from banned-calls and the failure of broken, got %v", problems)
+	}
+	// Problems are ordered by position, whichever rule reports them
+	if p := problems[0]; p.Severity != problem.Info {
+		t.Errorf("Expected the severity reported by the rule, got %v", p)
+	}
+	if p := problems[1]; p.RuleName != "broken" || p.Severity != problem.Error || !strings.Contains(p.Message, "invalid output") {
+		t.Errorf("Expected the failure of the broken rule, got %v", p)
+	}
+	if p := problems[2]; p.RuleName != "banned-calls" || p.Position.Line != 3 || p.Message != "banned call" || p.Severity != problem.Warning {
+		t.Errorf("Unexpected problem %v", p)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	if err != nil {
+		t.Fatalf("Expected the rule to run in the directory of the config: %v", err)
+	}
+	var request linter.ExternalRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := request.Tree.(map[string]any)
+	if request.Version != linter.ExternalProtocolVersion || request.Rule != "banned-calls" || request.Path != path ||
+		!strings.HasPrefix(request.Source, "extends Node") || tree["node"] != "AbstractSyntaxTree" {
+		t.Errorf("Unexpected request %+v", request)
+	}
+	if settings, _ := request.Settings.(map[string]any); settings["calls"] == nil {
+		t.Errorf("Expected the settings of the rule, got %v", request.Settings)
+	}
+}
+
+// TestProblemPositions checks that problems carry byte offsets and that
+// tab_width turns their columns into the columns editors display
+func TestProblemPositions(t *testing.T) {
+	code := "func f(a):\n\tvar é = a\n\tif a:\n\t\tvar unused = 2 # gdlint:ignore=unused-argument\n"
+	ruleset := []linter.Rule{&rules.UnusedVariable{}, &rules.UnusedArgument{}, &linter.UnusedSuppression{}}
+
+	tests := []struct {
+		tabWidth int
+		columns  []int
+	}{
+		{0, []int{2, 3, 18}},
+		{4, []int{5, 9, 24}},
+		{8, []int{9, 17, 32}},
+	}
+	// The suppression is positioned from its line and column only, and
+	// still gets the offset of its #
+	offsets := []int{12, 32, 47}
+	for _, tt := range tests {
+		config := linter.DefaultConfig()
+		config.TabWidth = tt.tabWidth
+		problems, err := linter.NewLinter(ruleset, config).Lint(code)
+		if err != nil {
+			t.Fatalf("Linting failed: %v", err)
+		}
+		if len(problems) != len(tt.columns) {
+			t.Fatalf("tab_width %d: expected %d problems, got %v", tt.tabWidth, len(tt.columns), problems)
+		}
+		for i, p := range problems {
+			if p.Position.Column != tt.columns[i] || p.Position.Offset != offsets[i] {
+				t.Errorf("tab_width %d: expected %s at column %d offset %d, got column %d offset %d",
+					tt.tabWidth, p.RuleName, tt.columns[i], offsets[i], p.Position.Column, p.Position.Offset)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// writeProject writes scripts of n functions each, script_00.gd and on, and
+// a script that does not parse, broken.gd, under a new directory
+func writeProject(t *testing.T, n int) string {
+	t.Helper()
+	root := t.TempDir()
+	for i := 0; i < n; i++ {
+		path := filepath.Join(root, "scenes", fmt.Sprintf("script_%02d.gd", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		source := fmt.Sprintf("extends Node\n\nvar health_%d = 10\n\n\nfunc hit_%d(amount, unused):\n\thealth_%[1]d -= amount\n\tif health_%[1]d == health_%[1]d:\n\t\tpass\n", i, i)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "broken.gd"), []byte("func (:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestLintProject(t *testing.T) {
+	root := writeProject(t, 30)
+	lint := linter.NewLinter(rules.GetDefaultRules(), linter.DefaultConfig())
+	results, err := lint.LintProject(root)
+	if err != nil {
+		t.Fatalf("LintProject failed: %v", err)
+	}
+	if len(results) != 31 {
+		t.Fatalf("Expected 31 results, got %d", len(results))
+	}
+
+	var parseErr *linter.ParseError
+	if results[0].Path != filepath.Join(root, "broken.gd") || !errors.As(results[0].Err, &parseErr) {
+		t.Errorf("Expected a parse error for broken.gd first, got %+v", results[0])
+	}
+	for i, result := range results[1:] {
+		if expected := filepath.Join(root, "scenes", fmt.Sprintf("script_%02d.gd", i)); result.Path != expected {
+			t.Errorf("Expected %s in path order, got %s", expected, result.Path)
+		}
+		if result.Err != nil || result.Source == "" {
+			t.Errorf("%s: expected the script to be linted, got %v", result.Path, result.Err)
+		}
+		names := map[string]bool{}
+		for _, p := range result.Problems {
+			names[p.RuleName] = true
+		}
+		if !names["unused-argument"] || !names["comparison-with-itself"] {
+			t.Errorf("%s: expected unused-argument and comparison-with-itself, got %v", result.Path, result.Problems)
+		}
+	}
+}
+
+// TestLintProjectAllRules lints a project concurrently with every rule; run
+// with -race (make test-race) it checks that rules share no mutable state
+func TestLintProjectAllRules(t *testing.T) {
+	root := writeProject(t, 16)
+	lint := linter.NewLinter(rules.GetAllRules(), linter.DefaultConfig())
+
+	results, err := lint.LintProject(root)
+	if err != nil {
+		t.Fatalf("LintProject failed: %v", err)
+	}
+	for _, result := range results[1:] {
+		sequential, err := lint.LintSource(result.Path, result.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(sequential) != fmt.Sprint(result.Problems) {
+			t.Errorf("%s: expected the problems found linting it alone\n%v\ngot\n%v", result.Path, sequential, result.Problems)
+		}
+	}
+}
+
+// fileCounter is a stateful rule counting the files it checks
+type fileCounter struct {
+	files     int
+	instances chan *fileCounter
+}
+
+func (r *fileCounter) Name() string        { return "file-counter" }
+func (r *fileCounter) Description() string { return "Counts the files it checks" }
+func (r *fileCounter) NewInstance() linter.Rule {
+	instance := &fileCounter{instances: r.instances}
+	r.instances <- instance
+	return instance
+}
+func (r *fileCounter) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	r.files++
+	return nil
+}
+
+func TestStatefulRulesCheckFilesWithFreshInstances(t *testing.T) {
+	root := writeProject(t, 10)
+	counter := &fileCounter{instances: make(chan *fileCounter, 100)}
+	lint := linter.NewLinter([]linter.Rule{counter}, linter.DefaultConfig())
+	if _, err := lint.LintProject(root); err != nil {
+		t.Fatalf("LintProject failed: %v", err)
+	}
+	close(counter.instances)
+
+	instances := 0
+	for instance := range counter.instances {
+		instances++
+		if instance.files != 1 {
+			t.Errorf("Expected each instance to check one file, got %d", instance.files)
+		}
+	}
+	if instances != 10 || counter.files != 0 {
+		t.Errorf("Expected 10 instances and the shared rule unused, got %d instances and %d files", instances, counter.files)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	root := writeProject(t, 5)
+	profile := linter.NewProfile()
+	lint := linter.NewLinter(rules.GetDefaultRules(), linter.DefaultConfig())
+	lint.SetProfile(profile)
+	if _, err := lint.LintProject(root); err != nil {
+		t.Fatalf("LintProject failed: %v", err)
+	}
+
+	timings := profile.Rules()
+	if len(timings) != len(rules.GetDefaultRules())+1 {
+		t.Fatalf("Expected a timing for each rule and the analyses, got %+v", timings)
+	}
+	for i, timing := range timings {
+		if timing.Files != 5 || timing.SlowestFile == "" {
+			t.Errorf("%s: expected the 5 scripts that parse to be timed, got %+v", timing.Name, timing)
+		}
+		if i > 0 && timing.Total > timings[i-1].Total {
+			t.Errorf("Expected the slowest rules first, got %s after %s", timing.Name, timings[i-1].Name)
+		}
+	}
+
+	files := profile.Files()
+	if len(files) != 5 {
+		t.Fatalf("Expected the 5 scripts that parse, got %+v", files)
+	}
+	for i := 1; i < len(files); i++ {
+		if files[i].Total() > files[i-1].Total() {
+			t.Errorf("Expected the slowest files first, got %+v", files)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package integration
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/linter"
@@ -19,6 +21,33 @@ func TestBasicLintingRules(t *testing.T) {
 func foo():
 	1 + 1
 	true
+`,
+			expected: []string{"expression-not-assigned", "expression-not-assigned"},
+		},
+		{
+			name: "expression-not-assigned should trigger for unary expressions",
+			code: `
+func foo(x, ready):
+	-x
+	not ready
+`,
+			expected: []string{"expression-not-assigned", "expression-not-assigned"},
+		},
+		{
+			name: "expression-not-assigned should trigger for node paths",
+			code: `
+func foo():
+	$Player
+	%HUD
+`,
+			expected: []string{"expression-not-assigned", "expression-not-assigned"},
+		},
+		{
+			name: "expression-not-assigned should trigger for properties",
+			code: `
+func foo(x):
+	x.position
+	self.visible
 `,
 			expected: []string{"expression-not-assigned", "expression-not-assigned"},
 		},
@@ -29,6 +58,21 @@ func foo():
 	bar()
 	x.baz()
 	await something()
+	$Player.queue_free()
+	%HUD.show()
+`,
+			expected: []string{},
+		},
+		{
+			name: "expression-not-assigned should not trigger for assignments",
+			code: `
+func foo(x):
+	var a
+	a = 1
+	a += 2
+	a %= 2
+	x[0] -= 1
+	self.a = x.b
 `,
 			expected: []string{},
 		},
@@ -49,11 +93,34 @@ func foo():
 `,
 			expected: []string{},
 		},
+		{
+			name: "unnecessary-pass should trigger in nested blocks",
+			code: `
+func foo(x):
+	for i in x:
+		print(i)
+		pass
+	while x:
+		pass
+	if x:
+		pass
+		print(x)
+`,
+			expected: []string{"unnecessary-pass", "unnecessary-pass"},
+		},
 		{
 			name: "duplicated-load should trigger",
 			code: `
 var A = load("res://scene.tscn")
 var B = preload("res://scene.tscn")
+`,
+			expected: []string{"duplicated-load"},
+		},
+		{
+			name: "duplicated-load compares paths whatever their quotes",
+			code: `
+var A = load("res://scene.tscn")
+var B = load('res://scene.tscn')
 `,
 			expected: []string{"duplicated-load"},
 		},
@@ -102,6 +169,25 @@ class Test:
 	var x = 1
 	func foo():
 		pass
+`,
+			expected: []string{},
+		},
+		{
+			name: "class-definitions-order should trigger for a static variable after variables",
+			code: `
+var x = 1
+static var count = 0
+`,
+			expected: []string{"class-definitions-order"},
+		},
+		{
+			name: "class-definitions-order should not trigger for a static variable after constants",
+			code: `
+const X = 1
+static var count = 0
+var x = 1
+static func _static_init():
+	pass
 `,
 			expected: []string{},
 		},
@@ -283,3 +369,82 @@ class SubClass:
 		}
 	}
 }
+
+func TestSuppressionDirectives(t *testing.T) {
+	config := linter.DefaultConfig()
+	config.EnabledRules = []string{"unused-suppression"}
+	l := linter.NewLinterForConfig(rules.NewRegistry(), config)
+
+	tests := []struct {
+		name     string
+		code     string
+		expected []string
+	}{
+		{
+			name: "reasons are not rule names",
+			code: `func foo(unused):
+	# gdlint:ignore=expression-not-assigned -- kept for the side effect
+	1 + unused
+`,
+			expected: nil,
+		},
+		{
+			name: "end of line ignore",
+			code: `func foo(unused):  # gdlint:ignore=unused-argument -- required by the signal
+	1 + 1  # gdlint:ignore = expression-not-assigned
+`,
+			expected: nil,
+		},
+		{
+			name: "end of line ignore does not reach the next line",
+			code: `func foo():
+	print("a")  # gdlint:ignore=expression-not-assigned
+	1 + 1
+`,
+			expected: []string{
+				"unused-suppression at line 2: gdlint:ignore=expression-not-assigned suppresses no problem",
+				"expression-not-assigned at line 3",
+			},
+		},
+		{
+			name: "unused ignore and disable",
+			code: `# gdlint:disable=unnecessary-pass,unused-argument
+func foo(unused):
+	# gdlint:ignore=expression-not-assigned
+	print("a")
+`,
+			expected: []string{
+				"unused-suppression at line 1: gdlint:disable=unnecessary-pass suppresses no problem",
+				"unused-suppression at line 3: gdlint:ignore=expression-not-assigned suppresses no problem",
+			},
+		},
+		{
+			name: "rules that do not run are not judged",
+			code: `# gdlint:ignore=function-name,no-such-rule
+func Foo():
+	pass
+`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := l.Lint(tt.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			var got []string
+			for _, p := range problems {
+				description := fmt.Sprintf("%s at line %d", p.RuleName, p.Position.Line)
+				if p.RuleName == "unused-suppression" {
+					description += ": " + p.Message
+				}
+				got = append(got, description)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
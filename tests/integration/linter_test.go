@@ -1,8 +1,17 @@
 package integration
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/dzannotti/gdtoolkit/internal/core/analysis"
+	"github.com/dzannotti/gdtoolkit/internal/core/ast"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
 	"github.com/dzannotti/gdtoolkit/internal/testutil"
 )
 
@@ -180,3 +189,110 @@ func foo():
 `, "comparison-with-itself", 2)
 	})
 }
+
+// resultsRecorder is an analysis rule that records the results it is given
+type resultsRecorder struct {
+	name    string
+	results *analysis.Results
+}
+
+func (r *resultsRecorder) Name() string        { return r.name }
+func (r *resultsRecorder) Description() string { return "records analysis results" }
+func (r *resultsRecorder) Requires() []analysis.Pass {
+	return []analysis.Pass{analysis.PassTypes}
+}
+func (r *resultsRecorder) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return r.CheckResults(analysis.NewResults(tree), config)
+}
+func (r *resultsRecorder) CheckResults(results *analysis.Results, config linter.Config) []problem.Problem {
+	r.results = results
+	return nil
+}
+
+func TestAnalysisRulesShareResults(t *testing.T) {
+	first := &resultsRecorder{name: "first"}
+	second := &resultsRecorder{name: "second"}
+	disabled := &resultsRecorder{name: "disabled"}
+	config := linter.DefaultConfig()
+	config.DisabledRules = []string{"disabled"}
+
+	lint := linter.NewLinter([]linter.Rule{first, &rules.UnusedArgument{}, second, disabled}, config)
+	problems, err := lint.Lint("func foo(unused):\n\tpass\n")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(problems) != 1 || problems[0].RuleName != "unused-argument" {
+		t.Errorf("Expected one unused-argument problem, got %v", problems)
+	}
+
+	if first.results == nil || first.results != second.results {
+		t.Fatal("Expected analysis rules to be given the same results")
+	}
+	if disabled.results != nil {
+		t.Error("Expected disabled rules not to run")
+	}
+}
+
+// fixedProblems is a rule reporting the same problems whatever the code
+type fixedProblems struct {
+	name     string
+	problems []problem.Problem
+}
+
+func (r *fixedProblems) Name() string        { return r.name }
+func (r *fixedProblems) Description() string { return "Reports fixed problems" }
+func (r *fixedProblems) Check(tree *ast.AbstractSyntaxTree, config linter.Config) []problem.Problem {
+	return append([]problem.Problem(nil), r.problems...)
+}
+
+func TestProblemsSortedAndDeduplicated(t *testing.T) {
+	at := func(line, column int, rule string) problem.Problem {
+		return problem.NewWarning(ast.Position{Line: line, Column: column}, "problem", rule)
+	}
+	first := &fixedProblems{name: "b-rule", problems: []problem.Problem{at(3, 1, "b-rule"), at(1, 5, "b-rule"), at(3, 1, "b-rule")}}
+	second := &fixedProblems{name: "a-rule", problems: []problem.Problem{at(3, 1, "a-rule"), at(1, 2, "a-rule")}}
+
+	lint := linter.NewLinter([]linter.Rule{first, second}, linter.DefaultConfig())
+	problems, err := lint.Lint("var a = 1\nvar b = 2\nvar c = 3\n")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d:%d %s", p.Position.Line, p.Position.Column, p.RuleName))
+	}
+	expected := []string{"1:2 a-rule", "1:5 b-rule", "3:1 a-rule", "3:1 b-rule"}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+// TestLintFiles checks that files are read from disk and linted with their
+// source, and that a file that cannot be read gets an internal error
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "player.gd")
+	if err := os.WriteFile(path, []byte("func Move(): \n\tpass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.gd")
+
+	lint := linter.NewLinter([]linter.Rule{rules.GetRuleByName("function-name"), rules.GetRuleByName("trailing-whitespace")}, linter.DefaultConfig())
+	results, err := lint.LintFiles([]string{path, missing})
+	if err != nil {
+		t.Fatalf("LintFiles failed: %v", err)
+	}
+
+	var got []string
+	for _, p := range results[path] {
+		got = append(got, fmt.Sprintf("%d:%d %s", p.Position.Line, p.Position.Column, p.RuleName))
+	}
+	expected := []string{"1:1 function-name", "1:13 trailing-whitespace"}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if problems := results[missing]; len(problems) != 1 || problems[0].RuleName != "internal" {
+		t.Errorf("Expected an internal error for the missing file, got %v", problems)
+	}
+}
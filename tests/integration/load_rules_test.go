@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/problem"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestRepeatedLoad checks the rule and the fix preloading the resource into a constant
+func TestRepeatedLoad(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		problems int
+		fixed    string // the code with the fixes applied; empty when no fix is expected
+	}{
+		{
+			name: "single load and preloads",
+			code: "extends Node\nfunc a():\n\tload(\"res://enemy.tscn\")\n" +
+				"func b():\n\tpreload(\"res://bullet.tscn\")\n\tpreload(\"res://bullet.tscn\")\n",
+		},
+		{
+			name: "loads in functions",
+			code: "extends Node\nsignal hit\nconst SPEED = 2\nvar health = 3\n" +
+				"func a():\n\tvar e = load(\"res://enemies/EnemyShip.tscn\").instantiate()\n" +
+				"func b():\n\tadd_child(load( \"res://enemies/EnemyShip.tscn\" ).instantiate())\n",
+			problems: 2,
+			fixed: "extends Node\nsignal hit\nconst SPEED = 2\nconst ENEMY_SHIP_SCENE = preload(\"res://enemies/EnemyShip.tscn\")\nvar health = 3\n" +
+				"func a():\n\tvar e = ENEMY_SHIP_SCENE.instantiate()\n" +
+				"func b():\n\tadd_child(ENEMY_SHIP_SCENE.instantiate())\n",
+		},
+		{
+			name: "existing constant",
+			code: "extends Node\nconst Enemy = preload(\"res://enemy.tscn\")\n" +
+				"func a():\n\treturn load(\"res://enemy.tscn\")\nfunc b():\n\treturn load(\"res://enemy.tscn\")\n",
+			problems: 2,
+			fixed: "extends Node\nconst Enemy = preload(\"res://enemy.tscn\")\n" +
+				"func a():\n\treturn Enemy\nfunc b():\n\treturn Enemy\n",
+		},
+		{
+			name:     "inner class",
+			code:     "extends Node\nclass Inner:\n\t@export var x = 1\n\tfunc a():\n\t\tload('res://data/item_db.gd')\n\t\tload('res://data/item_db.gd')\n",
+			problems: 2,
+			fixed: "extends Node\nclass Inner:\n\tconst ITEM_DB_SCRIPT = preload('res://data/item_db.gd')\n\t@export var x = 1\n" +
+				"\tfunc a():\n\t\tITEM_DB_SCRIPT\n\t\tITEM_DB_SCRIPT\n",
+		},
+		{
+			name:     "name already taken",
+			code:     "extends Node\nvar ENEMY_SCENE\nfunc a():\n\tload(\"res://enemy.tscn\")\n\tload(\"res://enemy.tscn\")\n",
+			problems: 2,
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultLoadRules(), linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != tc.problems {
+				t.Fatalf("Expected %d problems, got %v", tc.problems, problems)
+			}
+			for _, p := range problems {
+				if p.RuleName != "repeated-load" || p.Severity != problem.Info {
+					t.Errorf("Expected a repeated-load info, got %v", p)
+				}
+			}
+
+			fixed, count := problem.ApplyFixes(tc.code, problems)
+			if tc.fixed == "" {
+				if count != 0 {
+					t.Errorf("Expected no fix, got:\n%s", fixed)
+				}
+				return
+			}
+			if count != 1 {
+				t.Errorf("Expected one fix applied, got %d", count)
+			}
+			if fixed != tc.fixed {
+				t.Errorf("Fixed code mismatch\nexpected:\n%s\ngot:\n%s", tc.fixed, fixed)
+			}
+			if problems, err := l.Lint(fixed); err != nil || len(problems) != 0 {
+				t.Errorf("Expected the fixed code to lint clean, got %v (%v)", problems, err)
+			}
+		})
+	}
+
+	config := linter.DefaultConfig()
+	config.RuleSettings = map[string]any{"repeated-load": map[string]any{"threshold": 3}}
+	problems, err := linter.NewLinter(rules.GetDefaultLoadRules(), config).Lint(testCases[1].code)
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected two loads to be within a threshold of 3, got %v (%v)", problems, err)
+	}
+}
@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestMatchRules checks the rules on the branches of match statements
+func TestMatchRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // "line: message" of the expected problems
+	}{
+		{
+			name: "duplicate literal and constant patterns",
+			code: `
+const LIMIT = 3
+
+func foo(x):
+	match x:
+		1, "a":
+			pass
+		2, 1:
+			pass
+		LIMIT:
+			pass
+		"a", 1.0:
+			pass
+		LIMIT:
+			pass
+`,
+			expected: []string{
+				"8: Duplicate pattern in match, already matched on line 6",
+				"12: Duplicate pattern in match, already matched on line 6",
+				"14: Duplicate pattern in match, already matched on line 10",
+			},
+		},
+		{
+			name: "patterns repeated after a guarded branch",
+			code: `
+func foo(x, y):
+	match x:
+		1 when y:
+			pass
+		1:
+			pass
+		2, 2:
+			pass
+`,
+			expected: []string{"8: Duplicate pattern in match, already matched on line 8"},
+		},
+		{
+			name: "branches after a wildcard or a binding",
+			code: `
+func foo(x):
+	match x:
+		1:
+			pass
+		_:
+			pass
+		2:
+			pass
+		3:
+			pass
+	match x:
+		var value when value > 0:
+			pass
+		var value:
+			pass
+		[1]:
+			pass
+`,
+			expected: []string{
+				"8: Unreachable match branch, the branch on line 6 matches every value",
+				"17: Unreachable match branch, the branch on line 15 matches every value",
+			},
+		},
+		{
+			name: "matches over enum values",
+			code: `
+enum State {IDLE, RUNNING, JUMPING, FALLING = 2}
+
+func foo(state: State, x):
+	match state:
+		State.IDLE:
+			pass
+		State.RUNNING when x:
+			pass
+	match state:
+		State.IDLE, State.RUNNING:
+			pass
+		State.FALLING:
+			pass
+	match state:
+		State.IDLE:
+			pass
+		_:
+			pass
+	match state:
+		State.IDLE:
+			pass
+		State.SWIMMING:
+			pass
+	match state:
+		Other.IDLE:
+			pass
+`,
+			expected: []string{"5: Match over enum \"State\" does not handle RUNNING, JUMPING, FALLING"},
+		},
+		{
+			name: "enum of an enclosing class",
+			code: `
+enum Mode {ON, OFF}
+
+class Inner:
+	func foo(mode):
+		match mode:
+			Mode.ON:
+				pass
+`,
+			expected: []string{"6: Match over enum \"Mode\" does not handle OFF"},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultMatchRules(), linter.DefaultConfig())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+			if len(problems) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, problems)
+			}
+			for i, expected := range tc.expected {
+				if got := fmt.Sprintf("%d: %s", problems[i].Position.Line, problems[i].Message); got != expected {
+					t.Errorf("Expected %q, got %q", expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/formatter"
+	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/testutil"
+)
+
+// FuzzParserAndFormatterDoNotCrash feeds arbitrary input, valid or not, to
+// the parser, and the trees that parse to the formatter, and checks that
+// neither fails internally. The formatter corpus and truncated scripts seed
+// it; run it with
+//
+//	go test ./tests/integration -run '^$' -fuzz FuzzParserAndFormatterDoNotCrash
+func FuzzParserAndFormatterDoNotCrash(f *testing.F) {
+	cases, err := testutil.LoadFormatterCorpus(testutil.FormatterCorpusDir)
+	if err != nil {
+		f.Fatalf("Failed to load formatter corpus: %v", err)
+	}
+	for _, tc := range cases {
+		f.Add(tc.Input)
+		f.Add(tc.Input[:len(tc.Input)/2])
+	}
+	for _, seed := range []string{
+		"var a: Array[",
+		"func foo(a = , b):\n\treturn",
+		"class X extends:\n",
+		"match x:\n\t{\"a\": var b, ..}:\n",
+		"var d = {a = 1, \"b\": 2}\n",
+		"@export_range(0, 1 var a\n",
+		"if a:\nelif b\n\telse:\n",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tree, errs := parser.ParseFile("fuzz.gd", input)
+		for _, err := range errs {
+			var parseError parser.Error
+			if errors.As(err, &parseError) && parseError.Internal {
+				t.Fatalf("Parser failed: %v\nInput:\n%q", err, input)
+			}
+		}
+		if len(errs) > 0 {
+			return
+		}
+
+		var internal *formatter.InternalError
+		if _, err := formatter.FormatCode(tree, formatter.DefaultConfig()); errors.As(err, &internal) {
+			t.Fatalf("Formatter failed: %v\nInput:\n%q", err, input)
+		}
+	})
+}
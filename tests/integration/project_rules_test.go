@@ -0,0 +1,239 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+	"github.com/dzannotti/gdtoolkit/internal/project"
+)
+
+// testAPI is a fragment of an API dump, which makes the engine API complete
+// as far as the project rules know
+const testAPI = `{
+	"header": {"version_major": 4, "version_minor": 3},
+	"global_enums": [{"name": "Error"}],
+	"classes": [{"name": "Object"}, {"name": "Node", "inherits": "Object"}, {"name": "Resource", "inherits": "Object"}]
+}`
+
+// lintProjectFiles writes files under a new project directory, with a
+// project.godot unless files has one and a config using testAPI, and lints
+// every script with ruleset, returning "file:line rule" for each problem
+func lintProjectFiles(t *testing.T, ruleset []linter.Rule, files map[string]string) []string {
+	t.Helper()
+	root := t.TempDir()
+	if _, ok := files["project.godot"]; !ok {
+		files["project.godot"] = "config_version=5\n"
+	}
+	files["api.json"] = testAPI
+	files["gdlintrc.json"] = `{"godot_api": "api.json"}`
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := linter.LoadConfig(filepath.Join(root, "gdlintrc.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := project.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := linter.NewLinter(ruleset, config)
+	l.SetProject(p)
+	var found []string
+	for _, script := range p.Scripts {
+		source, err := os.ReadFile(script.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		problems, err := l.LintSource(script.Path, string(source))
+		if err != nil {
+			t.Fatalf("%s: %v", script.ResPath, err)
+		}
+		for _, p := range problems {
+			found = append(found, fmt.Sprintf("%s:%d %s", strings.TrimPrefix(script.ResPath, "res://"), p.Position.Line, p.RuleName))
+		}
+	}
+	return found
+}
+
+func TestProjectRules(t *testing.T) {
+	found := lintProjectFiles(t, rules.GetDefaultProjectRules(), map[string]string{
+		"actor.gd": "extends Node\nclass_name Actor\n",
+		"player.gd": `extends Actor
+class_name Player
+const Weapon = preload("weapon.gd")
+enum State { IDLE, RUN }
+var weapon: Weapon
+var state: State
+var target: Array[Enemy]
+var missing: Array[Missing]
+func hit(by: Actor) -> Error:
+	if by is Projectile:
+		return OK
+	return by as Node
+`,
+		"weapon.gd":  "extends Resource\nconst Owner = preload(\"res://player.gd\")\n",
+		"enemy.gd":   "extends Actor\nclass_name Enemy\n",
+		"helper.gd":  "extends Node\nclass_name Helper\n",
+		"self.gd":    "extends Node\nconst Me = preload(\"self.gd\")\n",
+		"level.tscn": "[ext_resource type=\"Script\" path=\"res://enemy.gd\" id=\"1\"]\n",
+	})
+
+	expected := []string{
+		"helper.gd:2 unused-class",
+		"player.gd:3 cyclic-preload",
+		"player.gd:8 unknown-class",
+		"player.gd:10 unknown-class",
+		"self.gd:2 cyclic-preload",
+		"weapon.gd:2 cyclic-preload",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
+
+func TestProjectRulesNeedProject(t *testing.T) {
+	l := linter.NewLinter(rules.GetDefaultProjectRules(), linter.DefaultConfig())
+	problems, err := l.Lint("extends Missing\nclass_name Unused\nconst Me = preload(\"res://a.gd\")\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems outside project mode, got %v", problems)
+	}
+}
+
+func TestCyclicPreloadPath(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"project.godot": "config_version=5\n",
+		"a.gd":          "extends Node\nconst B = preload(\"b.gd\")\n",
+		"b.gd":          "extends \"c.gd\"\n",
+		"c.gd":          "extends Node\nvar a = load(\"res://a.gd\")\nfunc reload():\n\treturn load(\"res://b.gd\")\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := project.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := linter.NewLinter([]linter.Rule{&rules.CyclicPreload{}}, linter.DefaultConfig())
+	l.SetProject(p)
+	expected := map[string]string{
+		// Loads inside functions run after the script is loaded, and close no cycle
+		"a.gd": "2:18 Load cycle: res://a.gd:2 preloads res://b.gd -> res://b.gd:1 extends res://c.gd -> res://c.gd:2 loads res://a.gd",
+		"b.gd": "1:1 Load cycle: res://b.gd:1 extends res://c.gd -> res://c.gd:2 loads res://a.gd -> res://a.gd:2 preloads res://b.gd",
+		"c.gd": "2:13 Load cycle: res://c.gd:2 loads res://a.gd -> res://a.gd:2 preloads res://b.gd -> res://b.gd:1 extends res://c.gd",
+	}
+	for name, want := range expected {
+		problems, err := l.LintSource(filepath.Join(root, name), files[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range problems {
+			got = append(got, fmt.Sprintf("%d:%d %s", p.Position.Line, p.Position.Column, p.Message))
+		}
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestUndefinedIdentifierInProject(t *testing.T) {
+	found := lintProjectFiles(t, []linter.Rule{&rules.UndefinedIdentifier{}}, map[string]string{
+		"project.godot": `config_version=5
+
+[autoload]
+
+Music="*res://music.gd"
+Loader="res://loader.gd"
+`,
+		"music.gd":  "extends Node\n",
+		"loader.gd": "extends Node\n",
+		"enemy.gd":  "extends Node\nclass_name Enemy\n",
+		"player.gd": `extends Node
+const Gun = preload("gun.gd")
+enum State { IDLE }
+class Stats:
+	var hp = 1
+func _ready():
+	Music.play()
+	Loader.load_all()
+	var e = Enemy.new()
+	var g = Gun.new()
+	var s = Stats.new()
+	var v = Vector2(PI, State.IDLE)
+	Node.new()
+	Missing.new()
+	print(missing)
+`,
+		"gun.gd": "extends Node\n",
+	})
+
+	// Loader is not a singleton; missing might be a member inherited from Node
+	expected := []string{
+		"player.gd:8 undefined-identifier",
+		"player.gd:14 undefined-identifier",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
+
+func TestUnknownNodePath(t *testing.T) {
+	found := lintProjectFiles(t, []linter.Rule{&rules.UnknownNodePath{}}, map[string]string{
+		"player.gd": `extends Node
+@onready var sprite = $Sprite
+@onready var health = %Health
+@onready var gun = $"Sprite/Gun"
+func _ready():
+	get_node("Sprite/Health").text = "1"
+	self.get_node("Missing").queue_free()
+	$Sprite.get_node("Anything").show()
+	get_node("/root/Game").start()
+	get_node("../Level").start()
+class Helper:
+	func help():
+		return $Elsewhere
+`,
+		"unused.gd": "extends Node\nfunc _ready():\n\t$Missing.show()\n",
+		"player.tscn": `[gd_scene format=3]
+
+[ext_resource type="Script" path="res://player.gd" id="1"]
+
+[node name="Player" type="Node2D"]
+script = ExtResource("1")
+
+[node name="Sprite" type="Sprite2D" parent="."]
+
+[node name="Health" type="Label" parent="Sprite"]
+unique_name_in_owner = true
+`,
+	})
+
+	// Paths from other nodes, leaving the scene or in scripts no scene uses are not checked
+	expected := []string{
+		"player.gd:4 unknown-node-path",
+		"player.gd:7 unknown-node-path",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
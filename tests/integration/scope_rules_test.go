@@ -0,0 +1,196 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestScopeRules checks that explicit (self.foo) and implicit (foo) member
+// access are treated the same by the scope analysis rules
+func TestScopeRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // Expected rule names that should trigger
+	}{
+		{
+			name: "private member used implicitly",
+			code: `
+var _count = 0
+func bump():
+	_count += 1
+`,
+			expected: []string{},
+		},
+		{
+			name: "private member used explicitly",
+			code: `
+var _count = 0
+func bump():
+	self._count += 1
+`,
+			expected: []string{},
+		},
+		{
+			name: "private member used by another initializer",
+			code: `
+var _base = 1
+var total = _base + 1
+`,
+			expected: []string{},
+		},
+		{
+			name: "private member never used",
+			code: `
+var _count = 0
+func bump():
+	pass
+`,
+			expected: []string{"unused-variable"},
+		},
+		{
+			name: "local variable never used",
+			code: `
+func foo():
+	var x = 1
+	var _ignored = 2
+`,
+			expected: []string{"unused-variable"},
+		},
+		{
+			name: "local shadowing a member does not use the member",
+			code: `
+var _count = 0
+func bump():
+	var _count = 1
+	print(_count)
+`,
+			expected: []string{"unused-variable", "shadowed-variable"},
+		},
+		{
+			name: "undefined names through self and bare access",
+			code: `
+func foo():
+	print(missing)
+	print(self.missing)
+`,
+			expected: []string{"undefined-identifier", "undefined-identifier"},
+		},
+		{
+			name: "keys of {key = value} dictionaries are not names",
+			code: `
+func foo():
+	print({name = "a", hp = missing})
+`,
+			expected: []string{"undefined-identifier"},
+		},
+		{
+			name: "members resolve through self and bare access",
+			code: `
+var speed = 1
+func foo():
+	print(speed, self.speed, bar(), self.bar())
+func bar():
+	return Vector2.ZERO
+`,
+			expected: []string{},
+		},
+		{
+			name: "inherited members are not reported",
+			code: `
+extends Node
+func foo():
+	print(position, self.position)
+`,
+			expected: []string{},
+		},
+		{
+			name: "shadowed declarations",
+			code: `
+extends Node2D
+var speed = 1
+func move(speed, delta):
+	var position = Vector2()
+	for i in range(3):
+		var delta = i
+		for i in range(2):
+			print(i, delta, position)
+`,
+			expected: []string{"shadowed-variable", "shadowed-variable", "shadowed-variable", "shadowed-variable"},
+		},
+		{
+			name: "members declared twice",
+			code: `
+signal hit
+var speed = 1
+enum {IDLE}
+const IDLE = 2
+func hit():
+	pass
+func move():
+	pass
+func move():
+	pass
+class Inner:
+	var speed = 2
+`,
+			expected: []string{"duplicate-definition", "duplicate-definition", "duplicate-definition"},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultScopeRules(), linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			if len(problems) != len(tc.expected) {
+				t.Errorf("Expected %d problems, got %d", len(tc.expected), len(problems))
+				for i, problem := range problems {
+					t.Logf("Problem %d: %s", i, problem.String())
+				}
+				return
+			}
+
+			for i, expectedRule := range tc.expected {
+				if problems[i].RuleName != expectedRule {
+					t.Errorf("Expected rule %s, got %s", expectedRule, problems[i].RuleName)
+				}
+			}
+		})
+	}
+}
+
+// TestShadowedVariableMessages checks that shadowed-variable names both declarations
+func TestShadowedVariableMessages(t *testing.T) {
+	code := `extends Node2D
+var speed = 1
+func move(speed):
+	var position = Vector2()
+	for i in range(3):
+		var speed = i
+`
+	problems, err := linter.NewLinter([]linter.Rule{&rules.ShadowedVariable{}}, linter.DefaultConfig()).Lint(code)
+	if err != nil {
+		t.Fatalf("Linting failed: %v", err)
+	}
+	expected := []string{
+		"Parameter 'speed' at line 3, column 11 shadows the member variable declared at line 2",
+		"Local variable 'position' at line 4, column 2 shadows the property 'position' of Node2D",
+		"Local variable 'speed' at line 6, column 3 shadows the parameter declared at line 3, column 11",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), problems)
+	}
+	for i, message := range expected {
+		if !strings.HasPrefix(problems[i].Message, message) {
+			t.Errorf("Expected a message starting with %q, got %q", message, problems[i].Message)
+		}
+	}
+}
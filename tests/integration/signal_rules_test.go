@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestSignalRules checks the rules on the declaration and use of signals
+func TestSignalRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // Expected rule names that should trigger
+	}{
+		{
+			name: "signals used in every way",
+			code: `
+extends Node
+signal hit
+signal died
+signal healed
+signal moved
+func _ready():
+	hit.emit()
+	self.died.connect(_on_died)
+	emit_signal("healed")
+	self.connect("moved", _on_died)
+func _on_died():
+	pass
+`,
+			expected: []string{},
+		},
+		{
+			name: "signal never used",
+			code: `
+extends Node
+signal hit
+signal unused
+func _ready():
+	hit.emit()
+`,
+			expected: []string{"unused-signal"},
+		},
+		{
+			name: "signal declared twice and emitted",
+			code: `
+extends Node
+signal hit
+signal hit
+func _ready():
+	hit.emit()
+	emit_signal("hit")
+`,
+			expected: []string{},
+		},
+		{
+			name: "misspelled signal names",
+			code: `
+extends Node
+signal hit
+func _ready():
+	emit_signal("hti")
+	emit_signal("hit")
+	emit_signal("ready")
+	connect("tree_exited", _ready)
+	other.emit_signal("anything")
+`,
+			expected: []string{"undefined-signal"},
+		},
+		{
+			name: "signals of an unknown base class are not checked",
+			code: `
+extends "res://base.gd"
+func _ready():
+	emit_signal("from_base")
+`,
+			expected: []string{},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultSignalRules(), linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			if len(problems) != len(tc.expected) {
+				t.Errorf("Expected %d problems, got %d", len(tc.expected), len(problems))
+				for i, problem := range problems {
+					t.Logf("Problem %d: %s", i, problem.String())
+				}
+				return
+			}
+
+			for i, expectedRule := range tc.expected {
+				if problems[i].RuleName != expectedRule {
+					t.Errorf("Expected rule %s, got %s", expectedRule, problems[i].RuleName)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestStringRules checks the rules on string literals
+func TestStringRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // Expected messages
+	}{
+		{
+			name: "matching counts",
+			code: `
+func foo(name, score, values):
+	print("%s: %d" % [name, score])
+	print("%d%%" % 50)
+	print("%-10s|%5.2f|%+d" % ["a", 1.5, 2])
+	print("%*d" % [5, score])
+	print("%s %s" % values)
+	print("100%" % [])
+`,
+			expected: []string{},
+		},
+		{
+			name: "too few and too many values",
+			code: `
+func foo(name, score):
+	print("%s: %d" % [name])
+	print("%s" % [name, score])
+	print("%s and %s" % name)
+	print("%s and %s" % "a")
+	print("%*.*f" % [5, 2])
+`,
+			expected: []string{
+				"Format string has 2 placeholders but is given 1 value",
+				"Format string has 1 placeholder but is given 2 values",
+				"Format string has 2 placeholders but is given 1 value",
+				"Format string has 3 placeholders but is given 2 values",
+			},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultStringRules(), linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			if len(problems) != len(tc.expected) {
+				t.Errorf("Expected %d problems, got %d", len(tc.expected), len(problems))
+				for i, problem := range problems {
+					t.Logf("Problem %d: %s", i, problem.String())
+				}
+				return
+			}
+
+			for i, expected := range tc.expected {
+				if problems[i].Message != expected || problems[i].RuleName != "format-argument-count" {
+					t.Errorf("Expected %q, got %s", expected, problems[i].String())
+				}
+			}
+		})
+	}
+}
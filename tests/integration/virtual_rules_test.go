@@ -0,0 +1,124 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+)
+
+// TestVirtualRules checks the rules comparing overridden functions against
+// the virtual functions of the engine API
+func TestVirtualRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected []string // Expected rule names that should trigger
+	}{
+		{
+			name: "well named virtuals",
+			code: `
+extends Node2D
+func _ready():
+	pass
+func _process(delta):
+	pass
+func _draw():
+	pass
+`,
+			expected: []string{},
+		},
+		{
+			name: "transposed and doubled letters",
+			code: `
+extends Node
+func _raedy():
+	pass
+func _procces(delta):
+	pass
+`,
+			expected: []string{"misnamed-virtual", "misnamed-virtual"},
+		},
+		{
+			name: "virtual of another class",
+			code: `
+extends Node
+func _gui_input(event):
+	pass
+`,
+			expected: []string{},
+		},
+		{
+			name: "private helpers",
+			code: `
+extends Node
+func _ready():
+	_reade()
+func _reade():
+	pass
+func _spawn():
+	pass
+`,
+			expected: []string{},
+		},
+		{
+			name: "unknown base class is checked against every virtual",
+			code: `
+extends "res://base.gd"
+func _phisics_process(delta):
+	pass
+`,
+			expected: []string{"misnamed-virtual"},
+		},
+		{
+			name: "wrong arity",
+			code: `
+extends Node
+func _ready(extra):
+	pass
+func _process():
+	pass
+func _physics_process(delta, extra = 1):
+	pass
+`,
+			expected: []string{"virtual-arity", "virtual-arity"},
+		},
+		{
+			name: "required init arguments",
+			code: `
+extends Resource
+func _init(value):
+	pass
+class Helper:
+	func _init(value):
+		pass
+`,
+			expected: []string{"virtual-arity"},
+		},
+	}
+
+	l := linter.NewLinter(rules.GetDefaultVirtualRules(), linter.DefaultConfig())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := l.Lint(tc.code)
+			if err != nil {
+				t.Fatalf("Linting failed: %v", err)
+			}
+
+			if len(problems) != len(tc.expected) {
+				t.Errorf("Expected %d problems, got %d", len(tc.expected), len(problems))
+				for i, problem := range problems {
+					t.Logf("Problem %d: %s", i, problem.String())
+				}
+				return
+			}
+
+			for i, expectedRule := range tc.expected {
+				if problems[i].RuleName != expectedRule {
+					t.Errorf("Expected rule %s, got %s", expectedRule, problems[i].RuleName)
+				}
+			}
+		})
+	}
+}
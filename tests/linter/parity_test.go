@@ -0,0 +1,132 @@
+package linter
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/dzannotti/gdtoolkit/internal/core/linter"
+	"github.com/dzannotti/gdtoolkit/internal/core/linter/rules"
+	"github.com/dzannotti/gdtoolkit/internal/testutil"
+)
+
+//go:generate go run ../../internal/testutil/portlinter ../../gdtoolkit/tests/linter ../../testdata/linter
+
+// TestLinterParity runs the snippet corpus ported from the Python gdlint tests
+// and reports how many expectations the Go linter reproduces. Cases listed in
+// known_gaps.txt may fail without failing the test; once one starts passing it
+// must be removed from the allowlist.
+func TestLinterParity(t *testing.T) {
+	cases, err := testutil.LoadLinterCorpus(testutil.LinterCorpusDir)
+	if err != nil {
+		t.Fatalf("Failed to load linter corpus: %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("No cases found in %s", testutil.LinterCorpusDir)
+	}
+
+	knownGaps, err := testutil.LoadKnownGaps(filepath.Join(testutil.LinterCorpusDir, "known_gaps.txt"))
+	if err != nil {
+		t.Fatalf("Failed to load known gaps: %v", err)
+	}
+
+	// Cases are grouped by the rule they exercise, so the parity of a single
+	// rule can be checked with -run 'TestLinterParity/<rule>'
+	var order []string
+	byRule := make(map[string][]testutil.LinterCase)
+	for _, c := range cases {
+		rule := c.Rule
+		if rule == "" {
+			rule = "unspecified"
+		}
+		if _, ok := byRule[rule]; !ok {
+			order = append(order, rule)
+		}
+		byRule[rule] = append(byRule[rule], c)
+	}
+	sort.Strings(order)
+
+	passed := 0
+	for _, rule := range order {
+		t.Run(rule, func(t *testing.T) {
+			rulePassed := 0
+			for _, c := range byRule[rule] {
+				c := c
+				err := checkLinterCase(c)
+				if err == nil {
+					rulePassed++
+				}
+
+				t.Run(c.ID, func(t *testing.T) {
+					switch {
+					case err == nil && knownGaps[c.ID]:
+						t.Errorf("Case now passes, remove it from known_gaps.txt")
+					case err != nil && knownGaps[c.ID]:
+						t.Skipf("Known gap: %v", err)
+					case err != nil:
+						t.Error(err)
+					}
+				})
+			}
+			passed += rulePassed
+			t.Logf("%s: %d/%d cases match Python gdlint", rule, rulePassed, len(byRule[rule]))
+		})
+	}
+
+	for id := range knownGaps {
+		if !hasLinterCase(cases, id) {
+			t.Errorf("known_gaps.txt lists unknown case %s", id)
+		}
+	}
+
+	t.Logf("Linter parity: %d/%d (%.1f%%) cases match Python gdlint",
+		passed, len(cases), float64(passed)/float64(len(cases))*100)
+}
+
+// checkLinterCase lints a corpus snippet with every rule ported from Python and
+// compares the result with the Python expectation
+func checkLinterCase(c testutil.LinterCase) error {
+	config := linter.DefaultConfig()
+	config.DisabledRules = append(config.DisabledRules, c.DisabledRules...)
+
+	// Only the rules ported from Python have expectations in the corpus; the
+	// rules added on top of them, such as magic-number, must not run on it
+	var ported []linter.Rule
+	for _, rule := range rules.GetAllRules() {
+		if rules.PortedFromPython(rule.Name()) {
+			ported = append(ported, rule)
+		}
+	}
+
+	problems, err := linter.NewLinter(ported, config).Lint(c.Code)
+	if err != nil {
+		return fmt.Errorf("linting failed: %v", err)
+	}
+
+	if c.OK {
+		if len(problems) > 0 {
+			return fmt.Errorf("expected no problems, but found %d: %v", len(problems), problems)
+		}
+		return nil
+	}
+
+	if len(problems) != 1 {
+		return fmt.Errorf("expected exactly 1 problem (%s:%d), but found %d: %v",
+			c.ExpectedRule, c.ExpectedLine, len(problems), problems)
+	}
+	if problems[0].RuleName != c.ExpectedRule || problems[0].Position.Line != c.ExpectedLine {
+		return fmt.Errorf("expected %s:%d, but got %s:%d", c.ExpectedRule, c.ExpectedLine,
+			problems[0].RuleName, problems[0].Position.Line)
+	}
+	return nil
+}
+
+func hasLinterCase(cases []testutil.LinterCase, id string) bool {
+	for _, c := range cases {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@
 package validation
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/ast"
@@ -394,8 +395,8 @@ else:
 
 	for _, tc := range controlFlowTests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Wrap in a function
-			input := "func test():\n\t" + tc.input
+			// Wrap in a function, indenting every line into its body
+			input := "func test():\n\t" + strings.ReplaceAll(tc.input, "\n", "\n\t")
 
 			p := parser.NewParser(input)
 			tree := p.Parse()
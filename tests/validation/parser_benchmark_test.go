@@ -2,10 +2,12 @@
 package validation
 
 import (
+	"os"
 	"testing"
 	"time"
 
 	"github.com/dzannotti/gdtoolkit/internal/core/parser"
+	"github.com/dzannotti/gdtoolkit/internal/corpus"
 	"github.com/dzannotti/gdtoolkit/internal/testutil"
 )
 
@@ -157,6 +159,65 @@ func BenchmarkFixtureParsing(t *testing.B) {
 	}
 }
 
+// BenchmarkLexerScalability benchmarks tokenizing the 5,000 line script of
+// TestParserScalability on its own
+func BenchmarkLexerScalability(b *testing.B) {
+	script := generateGDScriptWithLines(5000)
+	b.SetBytes(int64(len(script)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := parser.NewLexer(script)
+		for tok := l.NextToken(); tok.Type != parser.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+// BenchmarkParserScalability benchmarks parsing the 5,000 line script of
+// TestParserScalability
+func BenchmarkParserScalability(b *testing.B) {
+	script := generateGDScriptWithLines(5000)
+	b.SetBytes(int64(len(script)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := parser.NewParser(script)
+		if p.Parse() == nil || len(p.Errors()) > 0 {
+			b.Fatalf("Parsing failed: %v", p.Errors())
+		}
+	}
+}
+
+// BenchmarkProjectCorpus benchmarks parsing every script of a real project.
+// It is opt-in: point GDTOOLKIT_CORPUS at a project directory, and add
+// -cpuprofile or -memprofile to capture profiles, e.g.
+//
+//	GDTOOLKIT_CORPUS=~/my-game go test ./tests/validation -run '^$' \
+//		-bench ProjectCorpus -cpuprofile cpu.out
+func BenchmarkProjectCorpus(b *testing.B) {
+	root := os.Getenv("GDTOOLKIT_CORPUS")
+	if root == "" {
+		b.Skip("GDTOOLKIT_CORPUS is not set")
+	}
+	scripts, err := corpus.Load(root)
+	if err != nil {
+		b.Fatalf("Failed to load corpus: %v", err)
+	}
+	if len(scripts.Files) == 0 {
+		b.Fatalf("No scripts found under %s", root)
+	}
+	b.Logf("Corpus: %d files, %d lines", len(scripts.Files), scripts.Lines)
+
+	b.SetBytes(int64(scripts.Bytes))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, file := range scripts.Files {
+			parser.ParseFile(file.Path, file.Source)
+		}
+	}
+}
+
 // TestParserMemoryUsage tests parser memory efficiency
 func TestParserMemoryUsage(t *testing.T) {
 	// Large GDScript sample to test memory usage